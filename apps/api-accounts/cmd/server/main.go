@@ -10,11 +10,18 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/credentials"
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/events"
 	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/features"
 	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/handlers"
-	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/middleware"
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/notify"
 	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/repository"
 	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/services"
+	"github.com/CB-AccountStack/AccountStack/pkg/auth"
+	"github.com/CB-AccountStack/AccountStack/pkg/health"
+	"github.com/CB-AccountStack/AccountStack/pkg/middleware"
+	"github.com/CB-AccountStack/AccountStack/pkg/problem"
+	"github.com/CB-AccountStack/AccountStack/pkg/requestid"
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 )
@@ -50,6 +57,57 @@ func main() {
 		dataPath = filepath.Join("..", "..", "data", "seed")
 	}
 
+	// Storage backend: "json" (in-memory, seeded on every start) or "sqlite" (durable)
+	storageBackend := os.Getenv("STORAGE_BACKEND")
+	if storageBackend == "" {
+		storageBackend = repository.BackendJSON
+	}
+
+	sqlitePath := os.Getenv("SQLITE_PATH")
+	if sqlitePath == "" {
+		sqlitePath = "accountstack.db"
+	}
+
+	// Notifier: "log" (default) or "file", which appends messages to NOTIFIER_FILE
+	notifierFile := os.Getenv("NOTIFIER_FILE")
+	if notifierFile == "" {
+		notifierFile = "outbox.jsonl"
+	}
+
+	// Token signing keys: every *.pem in JWT_KEYS_DIR is published in the JWKS,
+	// and JWT_SIGNING_KID (default: the last by name) signs new tokens
+	keysDir := os.Getenv("JWT_KEYS_DIR")
+
+	// Access tokens are short-lived and renewed with refresh tokens
+	accessTokenTTL := envDuration("ACCESS_TOKEN_TTL", 15*time.Minute, logger)
+	refreshTokenTTL := envDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour, logger)
+
+	// Revoked token IDs: "memory" (this process only) or "sqlite", shared by
+	// every service that opens the same REVOCATION_SQLITE_PATH
+	revocationSQLitePath := os.Getenv("REVOCATION_SQLITE_PATH")
+	if revocationSQLitePath == "" {
+		revocationSQLitePath = sqlitePath
+	}
+
+	// Personal API keys: "memory" (this process only) or "sqlite", accepted by
+	// every service that opens the same API_KEY_SQLITE_PATH
+	apiKeySQLitePath := os.Getenv("API_KEY_SQLITE_PATH")
+	if apiKeySQLitePath == "" {
+		apiKeySQLitePath = sqlitePath
+	}
+
+	// Failed sign-in counts and security events: "memory"/"log" (this process
+	// only) or "sqlite", shared by every replica and, for security events, by
+	// api-insights, which shows them as alerts
+	loginAttemptsSQLitePath := os.Getenv("LOGIN_ATTEMPTS_SQLITE_PATH")
+	if loginAttemptsSQLitePath == "" {
+		loginAttemptsSQLitePath = sqlitePath
+	}
+	securityEventsSQLitePath := os.Getenv("SECURITY_EVENTS_SQLITE_PATH")
+	if securityEventsSQLitePath == "" {
+		securityEventsSQLitePath = sqlitePath
+	}
+
 	cloudBeesAPIKey := os.Getenv("CLOUDBEES_FM_API_KEY")
 	if cloudBeesAPIKey == "" {
 		logger.Warn("CLOUDBEES_FM_API_KEY not set, feature flags will use defaults")
@@ -65,40 +123,163 @@ func main() {
 	defer features.Shutdown()
 
 	// Initialize repository
-	repo, err := repository.NewRepository(dataPath, logger)
+	repo, err := repository.NewStore(repository.Config{
+		Backend:    storageBackend,
+		DataPath:   dataPath,
+		SQLitePath: sqlitePath,
+	}, logger)
 	if err != nil {
 		logger.WithError(err).Fatal("Failed to initialize repository")
 	}
+	defer repo.Close()
+
+	var signingKeys []*auth.SigningKey
+	if keysDir == "" {
+		key, err := auth.GenerateSigningKey()
+		if err != nil {
+			logger.WithError(err).Fatal("Failed to generate signing key")
+		}
+		logger.Warn("JWT_KEYS_DIR not set, signing with a temporary key (tokens stop working on restart)")
+		signingKeys = []*auth.SigningKey{key}
+	} else {
+		signingKeys, err = auth.LoadSigningKeys(keysDir)
+		if err != nil {
+			logger.WithError(err).Fatal("Failed to load signing keys")
+		}
+	}
+	jwtManager, err := auth.NewJWTManager(signingKeys, os.Getenv("JWT_SIGNING_KID"), accessTokenTTL)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize token signing")
+	}
+
+	revocations, err := auth.NewRevocationList(os.Getenv("REVOCATION_STORE"), revocationSQLitePath)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize revocation list")
+	}
+	defer revocations.Close()
+
+	apiKeys, err := auth.NewAPIKeyStore(os.Getenv("API_KEY_STORE"), apiKeySQLitePath)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize API keys")
+	}
+	defer apiKeys.Close()
+
+	loginAttempts, err := credentials.NewAttemptCounter(os.Getenv("LOGIN_ATTEMPTS_STORE"), loginAttemptsSQLitePath)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize login attempt counter")
+	}
+	defer loginAttempts.Close()
+
+	securityEvents, err := events.New(os.Getenv("SECURITY_EVENTS_STORE"), securityEventsSQLitePath, logger)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize security events")
+	}
+	defer securityEvents.Close()
 
 	// Initialize services
 	userService := services.NewUserService(repo, logger)
 	accountService := services.NewAccountService(repo, flags, logger)
+	statementService := services.NewStatementService(repo, flags, logger)
+	balanceHistoryService := services.NewBalanceHistoryService(repo, flags, logger)
+
+	// Seeded users sign in with AUTH_PASSWORD until they set their own password
+	seedPassword := os.Getenv("AUTH_PASSWORD")
+	if seedPassword == "" {
+		seedPassword = "demo123"
+	}
+	notifier, err := notify.New(os.Getenv("NOTIFIER"), notifierFile, logger)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize notifier")
+	}
+	credentialService, err := services.NewCredentialService(repo, notifier, services.CredentialConfig{
+		SeedPassword: seedPassword,
+		ResetURL:     os.Getenv("PASSWORD_RESET_URL"),
+	}, logger)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize credentials")
+	}
+	tokenService := services.NewTokenService(repo, jwtManager, revocations, refreshTokenTTL, logger)
+	mfaService := services.NewMFAService(repo, logger)
+	loginThrottle := services.NewLoginThrottle(repo, loginAttempts, securityEvents, logger)
+	adminService := services.NewAdminService(repo, accountService, tokenService, jwtManager, logger)
+	sharingService := services.NewSharingService(repo, accountService, notifier, logger)
+	apiKeyService := services.NewAPIKeyService(apiKeys, logger)
 
 	// Initialize handlers
-	healthHandler := handlers.NewHealthHandler()
+	healthHandler := health.NewHandler("api-accounts")
+	jwksHandler := handlers.NewJWKSHandler(jwtManager, logger)
 	userHandler := handlers.NewUserHandler(userService, logger)
 	accountHandler := handlers.NewAccountHandler(accountService, logger)
-	authHandler := handlers.NewAuthHandler(repo, logger)
+	statementHandler := handlers.NewStatementHandler(statementService, logger)
+	balanceHistoryHandler := handlers.NewBalanceHistoryHandler(balanceHistoryService, logger)
+	authHandler := handlers.NewAuthHandler(credentialService, tokenService, mfaService, loginThrottle, logger)
+	passwordHandler := handlers.NewPasswordHandler(credentialService, tokenService, logger)
+	mfaHandler := handlers.NewMFAHandler(mfaService, logger)
+	adminHandler := handlers.NewAdminHandler(adminService, logger)
+	sharingHandler := handlers.NewSharingHandler(sharingService, logger)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService, logger)
 
 	// Setup router
 	router := mux.NewRouter()
+	router.NotFoundHandler = problem.NotFoundHandler()
+	router.MethodNotAllowedHandler = problem.MethodNotAllowedHandler()
 
 	// Apply global middleware
+	if os.Getenv("TRUST_FORWARDED_FOR") == "true" {
+		router.Use(middleware.RealIPMiddleware())
+	}
 	router.Use(middleware.LoggingMiddleware(logger))
-	router.Use(middleware.AuthMiddleware(logger))
+	router.Use(middleware.AuthMiddleware(jwtManager, revocations, apiKeys, logger, handlers.PublicPaths...))
 
 	// Setup CORS
 	corsHandler := middleware.NewCORS()
 
 	// Register routes
 	router.Handle("/healthz", healthHandler).Methods("GET")
+	router.Handle("/.well-known/jwks.json", jwksHandler).Methods("GET")
 	router.HandleFunc("/login", authHandler.Login).Methods("POST")
-	router.HandleFunc("/me", userHandler.GetMe).Methods("GET")
-	router.HandleFunc("/accounts", accountHandler.GetAccounts).Methods("GET")
-	router.HandleFunc("/accounts/{id}", accountHandler.GetAccountByID).Methods("GET")
+	router.HandleFunc("/login/mfa", authHandler.LoginMFA).Methods("POST")
+	router.HandleFunc("/register", authHandler.Register).Methods("POST")
+	router.HandleFunc("/token/refresh", authHandler.Refresh).Methods("POST")
+	router.HandleFunc("/logout", authHandler.Logout).Methods("POST")
+	router.HandleFunc("/password/forgot", passwordHandler.ForgotPassword).Methods("POST")
+	router.HandleFunc("/password/reset", passwordHandler.ResetPassword).Methods("POST")
+	// Signed-in routes need the scope matching what they do; /logout works with any token
+	scoped := func(scope string, h http.HandlerFunc) http.Handler {
+		return middleware.RequireScope(scope, logger)(h)
+	}
+	router.Handle("/me", scoped(auth.ScopeAccountsRead, userHandler.GetMe)).Methods("GET")
+	router.Handle("/me/password", scoped(auth.ScopeAccountsWrite, passwordHandler.ChangePassword)).Methods("POST")
+	router.Handle("/me/mfa", scoped(auth.ScopeAccountsRead, mfaHandler.GetStatus)).Methods("GET")
+	router.Handle("/me/mfa/totp", scoped(auth.ScopeAccountsWrite, mfaHandler.EnrollTOTP)).Methods("POST")
+	router.Handle("/me/mfa/totp", scoped(auth.ScopeAccountsWrite, mfaHandler.DisableTOTP)).Methods("DELETE")
+	router.Handle("/me/mfa/totp/verify", scoped(auth.ScopeAccountsWrite, mfaHandler.ConfirmTOTP)).Methods("POST")
+	router.Handle("/me/mfa/recovery-codes", scoped(auth.ScopeAccountsWrite, mfaHandler.RegenerateRecoveryCodes)).Methods("POST")
+	router.Handle("/me/api-keys", scoped(auth.ScopeAccountsRead, apiKeyHandler.ListAPIKeys)).Methods("GET")
+	router.Handle("/me/api-keys", scoped(auth.ScopeAccountsWrite, apiKeyHandler.CreateAPIKey)).Methods("POST")
+	router.Handle("/me/api-keys/{id}", scoped(auth.ScopeAccountsWrite, apiKeyHandler.RevokeAPIKey)).Methods("DELETE")
+	router.Handle("/me/invitations", scoped(auth.ScopeAccountsRead, sharingHandler.ListInvitations)).Methods("GET")
+	router.Handle("/me/invitations/{id}/accept", scoped(auth.ScopeAccountsWrite, sharingHandler.AcceptInvitation)).Methods("POST")
+	router.Handle("/me/invitations/{id}/decline", scoped(auth.ScopeAccountsWrite, sharingHandler.DeclineInvitation)).Methods("POST")
+	router.Handle("/accounts", scoped(auth.ScopeAccountsRead, accountHandler.GetAccounts)).Methods("GET")
+	router.Handle("/accounts/{id}", scoped(auth.ScopeAccountsRead, accountHandler.GetAccountByID)).Methods("GET")
+	router.Handle("/accounts/{id}/balance-history", scoped(auth.ScopeAccountsRead, balanceHistoryHandler.GetBalanceHistory)).Methods("GET")
+	router.Handle("/accounts/{id}/statements", scoped(auth.ScopeAccountsRead, statementHandler.GetStatements)).Methods("GET")
+	router.Handle("/accounts/{id}/statements/{period}", scoped(auth.ScopeAccountsRead, statementHandler.GetStatement)).Methods("GET")
+	router.Handle("/accounts/{id}/access", scoped(auth.ScopeAccountsRead, sharingHandler.GetAccess)).Methods("GET")
+	router.Handle("/accounts/{id}/access/{userId}", scoped(auth.ScopeAccountsWrite, sharingHandler.RemoveAccess)).Methods("DELETE")
+	router.Handle("/accounts/{id}/invitations", scoped(auth.ScopeAccountsWrite, sharingHandler.Invite)).Methods("POST")
+	router.Handle("/accounts/{id}/invitations/{invitationId}", scoped(auth.ScopeAccountsWrite, sharingHandler.CancelInvitation)).Methods("DELETE")
+	router.Handle("/admin/users", scoped(auth.ScopeAdminRead, adminHandler.ListUsers)).Methods("GET")
+	router.Handle("/admin/users/{id}", scoped(auth.ScopeAdminRead, adminHandler.GetUser)).Methods("GET")
+	router.Handle("/admin/users/{id}/accounts", scoped(auth.ScopeAdminRead, adminHandler.GetUserAccounts)).Methods("GET")
+	router.Handle("/admin/users/{id}/role", scoped(auth.ScopeAdminWrite, adminHandler.SetRole)).Methods("PUT")
+	router.Handle("/admin/accounts/{id}", scoped(auth.ScopeAdminRead, adminHandler.GetAccount)).Methods("GET")
+	router.Handle("/admin/impersonate", scoped(auth.ScopeAdminImpersonate, adminHandler.Impersonate)).Methods("POST")
+	router.Handle("/admin/audit", scoped(auth.ScopeAdminRead, adminHandler.GetAuditLog)).Methods("GET")
 
-	// Wrap router with CORS
-	handler := corsHandler.Handler(router)
+	// Wrap router with request IDs, outside it so unmatched routes get one too, and CORS
+	handler := corsHandler.Handler(requestid.Middleware(router))
 
 	// Create HTTP server
 	server := &http.Server{
@@ -114,10 +295,43 @@ func main() {
 		logger.Infof("Server listening on port %s", port)
 		logger.Info("API Endpoints:")
 		logger.Info("  GET  /healthz - Health check")
+		logger.Info("  GET  /.well-known/jwks.json - Public keys that verify access tokens")
 		logger.Info("  POST /login - User login")
+		logger.Info("  POST /login/mfa - Complete login with a TOTP or recovery code")
+		logger.Info("  POST /register - Create a user")
+		logger.Info("  POST /token/refresh - Exchange a refresh token for new tokens")
+		logger.Info("  POST /logout - Revoke the current session")
+		logger.Info("  POST /password/forgot - Send a password reset token")
+		logger.Info("  POST /password/reset - Set a new password with a reset token")
 		logger.Info("  GET  /me - Current user info")
-		logger.Info("  GET  /accounts - List user accounts")
+		logger.Info("  POST /me/password - Change password")
+		logger.Info("  GET  /me/mfa - Two-factor authentication status")
+		logger.Info("  POST /me/mfa/totp - Start TOTP enrollment")
+		logger.Info("  DELETE /me/mfa/totp - Disable TOTP")
+		logger.Info("  POST /me/mfa/totp/verify - Confirm TOTP enrollment")
+		logger.Info("  POST /me/mfa/recovery-codes - Replace recovery codes")
+		logger.Info("  GET  /me/api-keys - List personal API keys")
+		logger.Info("  POST /me/api-keys - Create a personal API key")
+		logger.Info("  DELETE /me/api-keys/{id} - Revoke a personal API key")
+		logger.Info("  GET  /me/invitations - Pending invitations to shared accounts")
+		logger.Info("  POST /me/invitations/{id}/accept - Accept an invitation with its token")
+		logger.Info("  POST /me/invitations/{id}/decline - Decline an invitation")
+		logger.Info("  GET  /accounts - List owned and shared accounts")
 		logger.Info("  GET  /accounts/{id} - Get account by ID")
+		logger.Info("  GET  /accounts/{id}/balance-history - End-of-day balance series")
+		logger.Info("  GET  /accounts/{id}/statements - List monthly statements")
+		logger.Info("  GET  /accounts/{id}/statements/{period} - Get a statement (JSON or PDF)")
+		logger.Info("  GET  /accounts/{id}/access - Who can see an account (owner, joint)")
+		logger.Info("  DELETE /accounts/{id}/access/{userId} - Remove someone's access, or leave")
+		logger.Info("  POST /accounts/{id}/invitations - Invite a joint holder or viewer (owner)")
+		logger.Info("  DELETE /accounts/{id}/invitations/{invitationId} - Cancel an invitation (owner)")
+		logger.Info("  GET  /admin/users - List users (support, admin)")
+		logger.Info("  GET  /admin/users/{id} - Get any user (support, admin)")
+		logger.Info("  GET  /admin/users/{id}/accounts - List any user's accounts (support, admin)")
+		logger.Info("  PUT  /admin/users/{id}/role - Change a user's role (admin)")
+		logger.Info("  GET  /admin/accounts/{id} - Get any account (support, admin)")
+		logger.Info("  POST /admin/impersonate - Read-only token for a customer (support, admin)")
+		logger.Info("  GET  /admin/audit - Role changes and impersonations (support, admin)")
 
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			logger.WithError(err).Fatal("Server failed to start")
@@ -141,3 +355,18 @@ func main() {
 
 	logger.Info("Server stopped gracefully")
 }
+
+// envDuration reads a duration such as "15m" from the environment, falling
+// back to def when it is unset or invalid
+func envDuration(name string, def time.Duration, logger *logrus.Logger) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		logger.Warnf("Invalid %s '%s', defaulting to %s", name, value, def)
+		return def
+	}
+	return d
+}
@@ -0,0 +1,175 @@
+package credentials
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite" // Pure Go SQLite driver, keeps CGO_ENABLED=0 builds working
+)
+
+// Attempt counter stores selectable via the LOGIN_ATTEMPTS_STORE environment variable
+const (
+	AttemptsMemory = "memory"
+	AttemptsSQLite = "sqlite"
+)
+
+// AttemptRecord is the failed sign-in count for one key, such as a username
+// or a client IP
+type AttemptRecord struct {
+	Failures    int
+	LastFailure time.Time
+}
+
+// AttemptCounter counts failed sign-ins per key. Replicas of api-accounts
+// enforce the same limits when they share a store.
+type AttemptCounter interface {
+	// Get returns the record for key, or a zero record if it has none
+	Get(key string) (AttemptRecord, error)
+
+	// RecordFailure adds a failure at now and returns the updated record.
+	// Counting starts again when the previous failure is older than window.
+	RecordFailure(key string, now time.Time, window time.Duration) (AttemptRecord, error)
+
+	// Reset forgets the failures for key
+	Reset(key string) error
+
+	Close() error
+}
+
+// NewAttemptCounter creates the attempt counter for the configured store.
+// path is the database file used by the sqlite store.
+func NewAttemptCounter(store, path string) (AttemptCounter, error) {
+	switch store {
+	case "", AttemptsMemory:
+		return NewMemoryAttemptCounter(), nil
+	case AttemptsSQLite:
+		return NewSQLiteAttemptCounter(path)
+	default:
+		return nil, fmt.Errorf("unknown login attempts store %q", store)
+	}
+}
+
+// MemoryAttemptCounter keeps counts in process. Each replica counts on its
+// own and counts are lost on restart.
+type MemoryAttemptCounter struct {
+	records map[string]AttemptRecord
+	mu      sync.Mutex
+}
+
+// NewMemoryAttemptCounter creates an empty in-memory attempt counter
+func NewMemoryAttemptCounter() *MemoryAttemptCounter {
+	return &MemoryAttemptCounter{records: make(map[string]AttemptRecord)}
+}
+
+// Get returns the record for key
+func (c *MemoryAttemptCounter) Get(key string) (AttemptRecord, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.records[key], nil
+}
+
+// RecordFailure adds a failure for key and drops records that have gone quiet
+func (c *MemoryAttemptCounter) RecordFailure(key string, now time.Time, window time.Duration) (AttemptRecord, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for k, record := range c.records {
+		if now.Sub(record.LastFailure) > window {
+			delete(c.records, k)
+		}
+	}
+	record := c.records[key]
+	record.Failures++
+	record.LastFailure = now
+	c.records[key] = record
+	return record, nil
+}
+
+// Reset forgets the failures for key
+func (c *MemoryAttemptCounter) Reset(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.records, key)
+	return nil
+}
+
+// Close is a no-op for the in-memory counter
+func (c *MemoryAttemptCounter) Close() error {
+	return nil
+}
+
+// SQLiteAttemptCounter keeps counts in a SQLite file shared by every replica
+type SQLiteAttemptCounter struct {
+	db *sql.DB
+}
+
+// NewSQLiteAttemptCounter opens (or creates) the login_failures table in the
+// database at dbPath
+func NewSQLiteAttemptCounter(dbPath string) (*SQLiteAttemptCounter, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", dbPath)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open login attempts database: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS login_failures (
+		key          TEXT PRIMARY KEY,
+		failures     INTEGER NOT NULL,
+		last_failure INTEGER NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_login_failures_last_failure ON login_failures (last_failure)`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create login_failures: %w", err)
+	}
+
+	return &SQLiteAttemptCounter{db: db}, nil
+}
+
+// Get returns the record for key
+func (c *SQLiteAttemptCounter) Get(key string) (AttemptRecord, error) {
+	var failures int
+	var lastFailure int64
+	err := c.db.QueryRow(`SELECT failures, last_failure FROM login_failures WHERE key = ?`, key).Scan(&failures, &lastFailure)
+	if errors.Is(err, sql.ErrNoRows) {
+		return AttemptRecord{}, nil
+	}
+	if err != nil {
+		return AttemptRecord{}, err
+	}
+	return AttemptRecord{Failures: failures, LastFailure: time.UnixMilli(lastFailure)}, nil
+}
+
+// RecordFailure adds a failure for key in a single statement, so concurrent
+// replicas never lose a count, and drops records that have gone quiet
+func (c *SQLiteAttemptCounter) RecordFailure(key string, now time.Time, window time.Duration) (AttemptRecord, error) {
+	cutoff := now.Add(-window).UnixMilli()
+	if _, err := c.db.Exec(`DELETE FROM login_failures WHERE last_failure < ?`, cutoff); err != nil {
+		return AttemptRecord{}, err
+	}
+
+	var failures int
+	err := c.db.QueryRow(`INSERT INTO login_failures (key, failures, last_failure) VALUES (?, 1, ?)
+		ON CONFLICT (key) DO UPDATE SET failures = failures + 1, last_failure = excluded.last_failure
+		RETURNING failures`, key, now.UnixMilli()).Scan(&failures)
+	if err != nil {
+		return AttemptRecord{}, err
+	}
+	return AttemptRecord{Failures: failures, LastFailure: time.UnixMilli(now.UnixMilli())}, nil
+}
+
+// Reset forgets the failures for key
+func (c *SQLiteAttemptCounter) Reset(key string) error {
+	_, err := c.db.Exec(`DELETE FROM login_failures WHERE key = ?`, key)
+	return err
+}
+
+// Close closes the underlying database
+func (c *SQLiteAttemptCounter) Close() error {
+	return c.db.Close()
+}
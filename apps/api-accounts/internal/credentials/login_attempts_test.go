@@ -0,0 +1,68 @@
+package credentials
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAttemptCounters(t *testing.T) {
+	sqliteCounter, err := NewSQLiteAttemptCounter(filepath.Join(t.TempDir(), "attempts.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteAttemptCounter failed: %v", err)
+	}
+	defer sqliteCounter.Close()
+
+	tests := []struct {
+		name    string
+		counter AttemptCounter
+	}{
+		{"memory", NewMemoryAttemptCounter()},
+		{"sqlite", sqliteCounter},
+	}
+
+	now := time.Date(2024, 12, 1, 12, 0, 0, 0, time.UTC)
+	window := 15 * time.Minute
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if record, err := tt.counter.Get("user:a"); err != nil || record.Failures != 0 {
+				t.Fatalf("Expected no failures, got %+v (%v)", record, err)
+			}
+
+			for i := 1; i <= 3; i++ {
+				record, err := tt.counter.RecordFailure("user:a", now.Add(time.Duration(i)*time.Second), window)
+				if err != nil {
+					t.Fatalf("RecordFailure failed: %v", err)
+				}
+				if record.Failures != i {
+					t.Errorf("Expected %d failures, got %d", i, record.Failures)
+				}
+			}
+			if _, err := tt.counter.RecordFailure("ip:10.0.0.1", now, window); err != nil {
+				t.Fatalf("RecordFailure failed: %v", err)
+			}
+
+			record, err := tt.counter.Get("user:a")
+			if err != nil || record.Failures != 3 || !record.LastFailure.Equal(now.Add(3*time.Second)) {
+				t.Errorf("Unexpected record %+v (%v)", record, err)
+			}
+
+			// A failure after a quiet window starts counting again
+			record, err = tt.counter.RecordFailure("user:a", now.Add(window+time.Minute), window)
+			if err != nil || record.Failures != 1 {
+				t.Errorf("Expected the count to restart, got %+v (%v)", record, err)
+			}
+			if record, _ := tt.counter.Get("ip:10.0.0.1"); record.Failures != 0 {
+				t.Errorf("Expected the stale record to be dropped, got %+v", record)
+			}
+
+			if err := tt.counter.Reset("user:a"); err != nil {
+				t.Fatalf("Reset failed: %v", err)
+			}
+			if record, _ := tt.counter.Get("user:a"); record.Failures != 0 {
+				t.Errorf("Expected no failures after reset, got %+v", record)
+			}
+		})
+	}
+}
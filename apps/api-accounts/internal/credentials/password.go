@@ -1,4 +1,6 @@
-package auth
+// Package credentials checks what users sign in with: password hashes, TOTP
+// codes and the failed attempts that throttle sign-in.
+package credentials
 
 import (
 	"golang.org/x/crypto/bcrypt"
@@ -0,0 +1,120 @@
+// Package events publishes security events, such as an account being locked
+// after repeated failed sign-ins. With the sqlite publisher the events land in
+// a table api-insights reads and shows to the user as alerts.
+package events
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	_ "modernc.org/sqlite" // Pure Go SQLite driver, keeps CGO_ENABLED=0 builds working
+)
+
+// Publishers selectable via the SECURITY_EVENTS_STORE environment variable
+const (
+	PublisherLog    = "log"
+	PublisherSQLite = "sqlite"
+)
+
+// Event types
+const (
+	TypeAccountLocked = "account_locked"
+)
+
+// Event is a security event concerning a user
+type Event struct {
+	ID        string
+	UserID    string
+	Type      string
+	Message   string
+	CreatedAt time.Time
+}
+
+// Publisher records security events
+type Publisher interface {
+	Publish(event *Event) error
+	Close() error
+}
+
+// New creates the publisher for the configured store. path is the database
+// file used by the sqlite publisher.
+func New(store, path string, logger *logrus.Logger) (Publisher, error) {
+	switch store {
+	case "", PublisherLog:
+		return NewLogPublisher(logger), nil
+	case PublisherSQLite:
+		return NewSQLitePublisher(path)
+	default:
+		return nil, fmt.Errorf("unknown security events store %q", store)
+	}
+}
+
+// LogPublisher writes events to the service log only
+type LogPublisher struct {
+	logger *logrus.Logger
+}
+
+// NewLogPublisher creates a publisher that logs every event
+func NewLogPublisher(logger *logrus.Logger) *LogPublisher {
+	return &LogPublisher{logger: logger}
+}
+
+// Publish logs the event
+func (p *LogPublisher) Publish(event *Event) error {
+	p.logger.WithFields(logrus.Fields{
+		"userId":    event.UserID,
+		"eventType": event.Type,
+	}).Warn("Security event: " + event.Message)
+	return nil
+}
+
+// Close is a no-op for the log publisher
+func (p *LogPublisher) Close() error {
+	return nil
+}
+
+// SQLitePublisher appends events to the security_events table of a SQLite
+// file that api-insights also opens, such as the shared accountstack.db volume
+type SQLitePublisher struct {
+	db *sql.DB
+}
+
+// NewSQLitePublisher opens (or creates) the security_events table in the
+// database at dbPath. api-insights creates the same table, so whichever
+// service starts first can open it.
+func NewSQLitePublisher(dbPath string) (*SQLitePublisher, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", dbPath)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open security events database: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS security_events (
+		id         TEXT PRIMARY KEY,
+		user_id    TEXT NOT NULL,
+		type       TEXT NOT NULL,
+		message    TEXT NOT NULL,
+		created_at INTEGER NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_security_events_user_id ON security_events (user_id)`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create security_events: %w", err)
+	}
+
+	return &SQLitePublisher{db: db}, nil
+}
+
+// Publish stores the event
+func (p *SQLitePublisher) Publish(event *Event) error {
+	_, err := p.db.Exec(`INSERT INTO security_events (id, user_id, type, message, created_at) VALUES (?, ?, ?, ?, ?)`,
+		event.ID, event.UserID, event.Type, event.Message, event.CreatedAt.Unix())
+	return err
+}
+
+// Close closes the underlying database
+func (p *SQLitePublisher) Close() error {
+	return p.db.Close()
+}
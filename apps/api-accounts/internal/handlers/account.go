@@ -4,8 +4,9 @@ import (
 	"encoding/json"
 	"net/http"
 
-	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/middleware"
 	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/services"
+	"github.com/CB-AccountStack/AccountStack/pkg/middleware"
+	"github.com/CB-AccountStack/AccountStack/pkg/problem"
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 )
@@ -30,13 +31,7 @@ func (h *AccountHandler) GetAccounts(w http.ResponseWriter, r *http.Request) {
 
 	accounts, err := h.accountService.GetAccountsByUserID(userID)
 	if err != nil {
-		h.logger.WithError(err).WithField("userId", userID).Error("Failed to get accounts")
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(ErrorResponse{
-			Error:   "internal_error",
-			Message: "Failed to retrieve accounts",
-		})
+		writeError(w, r, h.logger, err, "Failed to retrieve accounts")
 		return
 	}
 
@@ -52,38 +47,13 @@ func (h *AccountHandler) GetAccountByID(w http.ResponseWriter, r *http.Request)
 	accountID := vars["id"]
 
 	if accountID == "" {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{
-			Error:   "bad_request",
-			Message: "Account ID is required",
-		})
+		problem.Write(w, r, http.StatusBadRequest, "Account ID is required")
 		return
 	}
 
 	account, err := h.accountService.GetAccountByID(accountID, userID)
 	if err != nil {
-		if err.Error() == "unauthorized" {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusForbidden)
-			json.NewEncoder(w).Encode(ErrorResponse{
-				Error:   "forbidden",
-				Message: "You do not have access to this account",
-			})
-			return
-		}
-
-		h.logger.WithError(err).WithFields(logrus.Fields{
-			"userId":    userID,
-			"accountId": accountID,
-		}).Error("Failed to get account")
-
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(ErrorResponse{
-			Error:   "not_found",
-			Message: "Account not found",
-		})
+		writeError(w, r, h.logger, err, "Failed to retrieve account")
 		return
 	}
 
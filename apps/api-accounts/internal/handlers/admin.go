@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/services"
+	"github.com/CB-AccountStack/AccountStack/pkg/middleware"
+	"github.com/CB-AccountStack/AccountStack/pkg/problem"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 500
+)
+
+// AdminHandler handles the support and admin endpoints under /admin
+type AdminHandler struct {
+	admin  *services.AdminService
+	logger *logrus.Logger
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(admin *services.AdminService, logger *logrus.Logger) *AdminHandler {
+	return &AdminHandler{
+		admin:  admin,
+		logger: logger,
+	}
+}
+
+// RoleRequest sets a user's role
+type RoleRequest struct {
+	Role string `json:"role"`
+}
+
+// ImpersonateRequest names the customer to impersonate and why
+type ImpersonateRequest struct {
+	UserID string `json:"userId"`
+	Reason string `json:"reason"`
+}
+
+// ImpersonateResponse carries a read-only access token for the customer
+type ImpersonateResponse struct {
+	Token     string `json:"token"`
+	ExpiresIn int    `json:"expiresIn"` // seconds
+	User      User   `json:"user"`
+}
+
+// ListUsers handles GET /admin/users - lists every user
+func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := h.admin.ListUsers()
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to list users")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(users)
+}
+
+// GetUser handles GET /admin/users/{id} - returns any user
+func (h *AdminHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	user, err := h.admin.GetUser(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to retrieve user")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(user)
+}
+
+// GetUserAccounts handles GET /admin/users/{id}/accounts - lists any user's accounts
+func (h *AdminHandler) GetUserAccounts(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["id"]
+
+	accounts, err := h.admin.GetUserAccounts(userID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to retrieve accounts")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(accounts)
+}
+
+// GetAccount handles GET /admin/accounts/{id} - returns any account
+func (h *AdminHandler) GetAccount(w http.ResponseWriter, r *http.Request) {
+	account, err := h.admin.GetAccount(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to retrieve account")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(account)
+}
+
+// SetRole handles PUT /admin/users/{id}/role - changes a user's role
+func (h *AdminHandler) SetRole(w http.ResponseWriter, r *http.Request) {
+	var req RoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Role == "" {
+		problem.Write(w, r, http.StatusBadRequest, "role is required")
+		return
+	}
+
+	user, err := h.admin.ChangeRole(middleware.GetClaims(r), mux.Vars(r)["id"], req.Role)
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to change role")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(user)
+}
+
+// Impersonate handles POST /admin/impersonate - issues a read-only access
+// token for a customer. The request is audited with the given reason.
+func (h *AdminHandler) Impersonate(w http.ResponseWriter, r *http.Request) {
+	var req ImpersonateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.UserID == "" {
+		problem.Write(w, r, http.StatusBadRequest, "userId and reason are required")
+		return
+	}
+
+	impersonation, err := h.admin.Impersonate(middleware.GetClaims(r), req.UserID, req.Reason)
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to impersonate user")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ImpersonateResponse{
+		Token:     impersonation.AccessToken,
+		ExpiresIn: secondsUntil(impersonation.ExpiresAt, time.Now()),
+		User: User{
+			ID:    impersonation.User.ID,
+			Email: impersonation.User.Email,
+			Name:  impersonation.User.Name,
+		},
+	})
+}
+
+// GetAuditLog handles GET /admin/audit - lists recent role changes and
+// impersonations, newest first. ?limit= defaults to 50.
+func (h *AdminHandler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	limit := defaultAuditLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxAuditLimit {
+			problem.Write(w, r, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxAuditLimit))
+			return
+		}
+		limit = n
+	}
+
+	entries, err := h.admin.AuditLog(limit)
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to read audit log")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(entries)
+}
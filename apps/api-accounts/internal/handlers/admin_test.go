@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/models"
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/repository"
+	"github.com/gorilla/mux"
+)
+
+// bearer sends a request with an optional JSON body authenticated with an access token
+func bearer(router *mux.Router, method, path, body, accessToken string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+// auditLog returns the audit log as seen by accessToken
+func auditLog(t *testing.T, router *mux.Router, accessToken string) []models.AuditEntry {
+	t.Helper()
+	rec := bearer(router, "GET", "/admin/audit", "", accessToken)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected the audit log, got %d", rec.Code)
+	}
+	var entries []models.AuditEntry
+	if err := json.NewDecoder(rec.Body).Decode(&entries); err != nil {
+		t.Fatalf("Failed to decode audit log: %v", err)
+	}
+	return entries
+}
+
+func TestAdminScopes(t *testing.T) {
+	router, _ := newCredentialsRouter(t, repository.BackendJSON)
+	customer := signIn(t, router, "demo@accountstack.com").Token
+	support := signIn(t, router, "support@accountstack.com").Token
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		token  string
+		want   int
+	}{
+		{"customer cannot list users", "GET", "/admin/users", "", customer, http.StatusForbidden},
+		{"customer cannot impersonate", "POST", "/admin/impersonate", `{"userId":"user-002","reason":"test"}`, customer, http.StatusForbidden},
+		{"support lists users", "GET", "/admin/users", "", support, http.StatusOK},
+		{"support reads any account", "GET", "/admin/accounts/acc-004", "", support, http.StatusOK},
+		{"support lists any user's accounts", "GET", "/admin/users/user-002/accounts", "", support, http.StatusOK},
+		{"support cannot change roles", "PUT", "/admin/users/user-002/role", `{"role":"admin"}`, support, http.StatusForbidden},
+		{"unknown account", "GET", "/admin/accounts/acc-999", "", support, http.StatusNotFound},
+		{"unknown user", "GET", "/admin/users/user-999/accounts", "", support, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := bearer(router, tt.method, tt.path, tt.body, tt.token); rec.Code != tt.want {
+				t.Errorf("Expected status %d, got %d: %s", tt.want, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestImpersonation(t *testing.T) {
+	for _, backend := range []string{repository.BackendJSON, repository.BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			router, _ := newCredentialsRouter(t, backend)
+			support := signIn(t, router, "support@accountstack.com").Token
+
+			// A reason is required, and staff cannot be impersonated
+			if rec := bearer(router, "POST", "/admin/impersonate", `{"userId":"user-001"}`, support); rec.Code != http.StatusBadRequest {
+				t.Errorf("Expected 400 without a reason, got %d", rec.Code)
+			}
+			if rec := bearer(router, "POST", "/admin/impersonate", `{"userId":"user-901","reason":"test"}`, support); rec.Code != http.StatusForbidden {
+				t.Errorf("Expected 403 impersonating an admin, got %d", rec.Code)
+			}
+
+			rec := bearer(router, "POST", "/admin/impersonate", `{"userId":"user-001","reason":"Ticket 4411"}`, support)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+			var resp ImpersonateResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if resp.User.ID != "user-001" || resp.ExpiresIn <= 0 || resp.ExpiresIn > 15*60 {
+				t.Errorf("Unexpected impersonation response: %+v", resp)
+			}
+
+			// The token sees the customer's data but cannot change anything
+			me := bearer(router, "GET", "/me", "", resp.Token)
+			if me.Code != http.StatusOK || !strings.Contains(me.Body.String(), `"id":"user-001"`) {
+				t.Errorf("Expected the customer's profile, got %d: %s", me.Code, me.Body.String())
+			}
+			if code := withToken(router, "GET", "/accounts", resp.Token); code != http.StatusOK {
+				t.Errorf("Expected read access to accounts, got %d", code)
+			}
+			if rec := bearer(router, "POST", "/me/password", `{"currentPassword":"x","newPassword":"another password"}`, resp.Token); rec.Code != http.StatusForbidden {
+				t.Errorf("Expected writes to be forbidden, got %d", rec.Code)
+			}
+			if code := withToken(router, "GET", "/admin/users", resp.Token); code != http.StatusForbidden {
+				t.Errorf("Expected admin endpoints to be forbidden, got %d", code)
+			}
+			// Nor read the customer's credentials or sharing
+			for _, path := range []string{"/me/api-keys", "/me/mfa", "/me/invitations", "/accounts/acc-001/access"} {
+				if code := withToken(router, "GET", path, resp.Token); code != http.StatusForbidden {
+					t.Errorf("Expected GET %s to be forbidden, got %d", path, code)
+				}
+			}
+
+			entries := auditLog(t, router, support)
+			if len(entries) != 1 {
+				t.Fatalf("Expected one audit entry, got %+v", entries)
+			}
+			entry := entries[0]
+			if entry.Action != models.AuditImpersonate || entry.ActorID != "user-900" || entry.TargetUserID != "user-001" ||
+				entry.Detail != "Ticket 4411" || entry.TokenID == "" {
+				t.Errorf("Unexpected audit entry: %+v", entry)
+			}
+		})
+	}
+}
+
+func TestSetRole(t *testing.T) {
+	router, _ := newCredentialsRouter(t, repository.BackendSQLite)
+	admin := signIn(t, router, "admin@accountstack.com").Token
+
+	if rec := bearer(router, "PUT", "/admin/users/user-002/role", `{"role":"owner"}`, admin); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown role, got %d", rec.Code)
+	}
+	if rec := bearer(router, "PUT", "/admin/users/user-901/role", `{"role":"user"}`, admin); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 changing one's own role, got %d", rec.Code)
+	}
+	if rec := bearer(router, "PUT", "/admin/users/user-999/role", `{"role":"support"}`, admin); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown user, got %d", rec.Code)
+	}
+
+	before := signIn(t, router, "sarah.chen@accountstack.com").Token
+	rec := bearer(router, "PUT", "/admin/users/user-002/role", `{"role":"support"}`, admin)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"role":"support"`) {
+		t.Fatalf("Expected the role to change, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// The new role applies to tokens issued after the change
+	if code := withToken(router, "GET", "/admin/users", before); code != http.StatusForbidden {
+		t.Errorf("Expected the earlier token to keep its scopes, got %d", code)
+	}
+	promoted := signIn(t, router, "sarah.chen@accountstack.com")
+	if code := withToken(router, "GET", "/admin/users", promoted.Token); code != http.StatusOK {
+		t.Errorf("Expected a new token to carry the support scopes, got %d", code)
+	}
+
+	// Lowering the role signs the user out everywhere
+	if rec := bearer(router, "PUT", "/admin/users/user-002/role", `{"role":"user"}`, admin); rec.Code != http.StatusOK {
+		t.Fatalf("Expected the role to change, got %d: %s", rec.Code, rec.Body.String())
+	}
+	for _, path := range []string{"/admin/users", "/me"} {
+		if code := withToken(router, "GET", path, promoted.Token); code != http.StatusUnauthorized {
+			t.Errorf("Expected the demoted user's token to be rejected on %s, got %d", path, code)
+		}
+	}
+	if rec := refresh(t, router, promoted.RefreshToken); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the demoted user's refresh token to be rejected, got %d", rec.Code)
+	}
+	if code := withToken(router, "GET", "/admin/users", signIn(t, router, "sarah.chen@accountstack.com").Token); code != http.StatusForbidden {
+		t.Errorf("Expected a new token to carry only the customer scopes, got %d", code)
+	}
+
+	entries := auditLog(t, router, admin)
+	if len(entries) != 2 || entries[0].Action != models.AuditRoleChange || entries[0].Detail != "support -> user" ||
+		entries[1].Detail != "user -> support" {
+		t.Errorf("Unexpected audit log: %+v", entries)
+	}
+}
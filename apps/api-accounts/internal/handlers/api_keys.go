@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/services"
+	"github.com/CB-AccountStack/AccountStack/pkg/middleware"
+	"github.com/CB-AccountStack/AccountStack/pkg/problem"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// APIKeyHandler handles the personal API key endpoints under /me/api-keys
+type APIKeyHandler struct {
+	apiKeys *services.APIKeyService
+	logger  *logrus.Logger
+}
+
+// NewAPIKeyHandler creates a new API key handler
+func NewAPIKeyHandler(apiKeys *services.APIKeyService, logger *logrus.Logger) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeys: apiKeys,
+		logger:  logger,
+	}
+}
+
+// CreateAPIKeyRequest names a new key and optionally limits it
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes,omitempty"`    // Defaults to every scope a key can carry
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"` // Never expires when unset
+}
+
+// CreateAPIKey handles POST /me/api-keys - the key is only returned in this
+// response
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	if !requireSession(w, r, h.logger) {
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	created, err := h.apiKeys.Create(middleware.GetClaims(r), req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to create API key")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// ListAPIKeys handles GET /me/api-keys - lists the current user's keys with
+// when they were last used
+func (h *APIKeyHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	if !requireSession(w, r, h.logger) {
+		return
+	}
+
+	keys, err := h.apiKeys.List(middleware.GetUserID(r))
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to list API keys")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(keys)
+}
+
+// RevokeAPIKey handles DELETE /me/api-keys/{id}
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	if !requireSession(w, r, h.logger) {
+		return
+	}
+
+	if err := h.apiKeys.Revoke(middleware.GetUserID(r), mux.Vars(r)["id"]); err != nil {
+		writeError(w, r, h.logger, err, "Failed to revoke API key")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/repository"
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/services"
+	"github.com/CB-AccountStack/AccountStack/pkg/auth"
+	"github.com/gorilla/mux"
+)
+
+// withAPIKey sends a request authenticated with a personal API key
+func withAPIKey(router *mux.Router, method, path, body, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "ApiKey "+key)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+// createAPIKey creates a key as accessToken and returns it
+func createAPIKey(t *testing.T, router *mux.Router, body, accessToken string) services.CreatedAPIKey {
+	t.Helper()
+	rec := bearer(router, "POST", "/me/api-keys", body, accessToken)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected the key to be created, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created services.CreatedAPIKey
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode key: %v", err)
+	}
+	return created
+}
+
+func TestAPIKeys(t *testing.T) {
+	for _, backend := range []string{repository.BackendJSON, repository.BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			router, _ := newCredentialsRouter(t, backend)
+			accessToken := signIn(t, router, "demo@accountstack.com").Token
+
+			full := createAPIKey(t, router, `{"name":"Budget sync"}`, accessToken)
+			if !strings.HasPrefix(full.Key, "ask_") || !strings.HasPrefix(full.Key, full.Prefix) || full.UserID != "user-001" {
+				t.Errorf("Unexpected key: %+v", full)
+			}
+			readOnly := createAPIKey(t, router, `{"name":"Reporting","scopes":["accounts:read"],"expiresAt":"2999-01-01T00:00:00Z"}`, accessToken)
+			if readOnly.Scope != "accounts:read" || readOnly.ExpiresAt == nil {
+				t.Errorf("Unexpected key: %+v", readOnly)
+			}
+			writer := createAPIKey(t, router, `{"name":"Writer","scopes":["accounts:write"]}`, accessToken)
+
+			tests := []struct {
+				name   string
+				method string
+				path   string
+				body   string
+				key    string
+				want   int
+			}{
+				{"reads with a key", "GET", "/accounts/acc-001", "", full.Key, http.StatusOK},
+				{"stays within the user's data", "GET", "/accounts/acc-004", "", full.Key, http.StatusNotFound},
+				{"read-only key reads", "GET", "/accounts", "", readOnly.Key, http.StatusOK},
+				{"read-only key cannot write", "POST", "/me/api-keys", `{"name":"More"}`, readOnly.Key, http.StatusForbidden},
+				{"keys cannot mint keys", "POST", "/me/api-keys", `{"name":"More","scopes":["accounts:read"]}`, full.Key, http.StatusForbidden},
+				{"keys cannot list keys", "GET", "/me/api-keys", "", full.Key, http.StatusForbidden},
+				{"keys cannot revoke keys", "DELETE", "/me/api-keys/" + readOnly.ID, "", writer.Key, http.StatusForbidden},
+				{"keys cannot change the password", "POST", "/me/password", `{"currentPassword":"` + testSeedPassword + `","newPassword":"a new password"}`, full.Key, http.StatusForbidden},
+				{"keys cannot enroll MFA", "POST", "/me/mfa/totp", "", full.Key, http.StatusForbidden},
+				{"keys cannot share accounts", "POST", "/accounts/acc-001/invitations", `{"email":"sarah.chen@accountstack.com","access":"viewer"}`, full.Key, http.StatusForbidden},
+				{"keys cannot see who has access", "GET", "/accounts/acc-001/access", "", full.Key, http.StatusForbidden},
+				{"unknown key", "GET", "/accounts", "", "ask_unknown", http.StatusUnauthorized},
+				{"access token sent as a key", "GET", "/accounts", "", accessToken, http.StatusUnauthorized},
+				{"keys do not log out", "POST", "/logout", "", full.Key, http.StatusBadRequest},
+			}
+			for _, tt := range tests {
+				if rec := withAPIKey(router, tt.method, tt.path, tt.body, tt.key); rec.Code != tt.want {
+					t.Errorf("%s: expected status %d, got %d: %s", tt.name, tt.want, rec.Code, rec.Body.String())
+				}
+			}
+
+			// The list shows when each key was used but never the key itself
+			rec := bearer(router, "GET", "/me/api-keys", "", accessToken)
+			if strings.Contains(rec.Body.String(), full.Key) {
+				t.Fatalf("Listing leaked a key: %s", rec.Body.String())
+			}
+			var keys []auth.APIKey
+			if err := json.NewDecoder(rec.Body).Decode(&keys); err != nil {
+				t.Fatalf("Failed to decode keys: %v", err)
+			}
+			if len(keys) != 3 || keys[0].ID != full.ID || keys[0].LastUsedAt == nil {
+				t.Errorf("Expected three keys, the first one used, got %+v", keys)
+			}
+
+			// Revoking takes effect immediately
+			if rec := bearer(router, "DELETE", "/me/api-keys/"+full.ID, "", signIn(t, router, "sarah.chen@accountstack.com").Token); rec.Code != http.StatusNotFound {
+				t.Errorf("Expected another user's key not to be found, got %d", rec.Code)
+			}
+			if rec := bearer(router, "DELETE", "/me/api-keys/"+full.ID, "", accessToken); rec.Code != http.StatusNoContent {
+				t.Fatalf("Expected the key to be revoked, got %d: %s", rec.Code, rec.Body.String())
+			}
+			if rec := withAPIKey(router, "GET", "/accounts", "", full.Key); rec.Code != http.StatusUnauthorized {
+				t.Errorf("Expected a revoked key to be refused, got %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestAPIKeyValidation(t *testing.T) {
+	router, _ := newCredentialsRouter(t, repository.BackendJSON)
+	accessToken := signIn(t, router, "demo@accountstack.com").Token
+	adminToken := signIn(t, router, "admin@accountstack.com").Token
+
+	tests := []struct {
+		name  string
+		body  string
+		token string
+		want  int
+	}{
+		{"missing name", `{"scopes":["accounts:read"]}`, accessToken, http.StatusBadRequest},
+		{"unknown scope", `{"name":"Key","scopes":["accounts:delete"]}`, accessToken, http.StatusBadRequest},
+		{"admin scopes are never given to keys", `{"name":"Key","scopes":["admin:read"]}`, adminToken, http.StatusBadRequest},
+		{"expiry in the past", `{"name":"Key","expiresAt":"2001-01-01T00:00:00Z"}`, accessToken, http.StatusBadRequest},
+		{"malformed body", `{"name":`, accessToken, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if rec := bearer(router, "POST", "/me/api-keys", tt.body, tt.token); rec.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.name, tt.want, rec.Code, rec.Body.String())
+		}
+	}
+
+	// An admin's key gets the customer scopes only
+	key := createAPIKey(t, router, `{"name":"Admin script"}`, adminToken)
+	if strings.Contains(key.Scope, "admin:") {
+		t.Errorf("Expected no admin scopes, got %q", key.Scope)
+	}
+	if rec := withAPIKey(router, "GET", "/admin/users", "", key.Key); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected admin routes to refuse keys, got %d", rec.Code)
+	}
+}
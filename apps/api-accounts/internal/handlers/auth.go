@@ -2,49 +2,48 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
-	"os"
+	"strconv"
 	"time"
 
-	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/auth"
-	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/repository"
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/models"
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/services"
+	"github.com/CB-AccountStack/AccountStack/pkg/middleware"
+	"github.com/CB-AccountStack/AccountStack/pkg/problem"
 	"github.com/sirupsen/logrus"
 )
 
+// PublicPaths are served without an access token: signing in, the
+// signed-out credential endpoints and the JWKS other services verify with
+var PublicPaths = []string{
+	"/login",
+	"/login/mfa",
+	"/register",
+	"/password/forgot",
+	"/password/reset",
+	"/token/refresh",
+	"/.well-known/jwks.json",
+}
+
 // AuthHandler handles authentication requests
 type AuthHandler struct {
-	jwtManager   *auth.JWTManager
-	logger       *logrus.Logger
-	repo         *repository.Repository
-	demoPassword string // Hashed password - same for all users in demo mode
+	credentials *services.CredentialService
+	tokens      *services.TokenService
+	mfa         *services.MFAService
+	throttle    *services.LoginThrottle
+	logger      *logrus.Logger
 }
 
 // NewAuthHandler creates a new auth handler
-func NewAuthHandler(repo *repository.Repository, logger *logrus.Logger) *AuthHandler {
-	// Get JWT secret from environment
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = "dev-secret-key-change-in-production"
-		logger.Warn("JWT_SECRET not set, using default (not secure for production)")
-	}
-
-	// Get demo password from environment (all users use this in demo mode)
-	password := os.Getenv("AUTH_PASSWORD")
-	if password == "" {
-		password = "demo123"
-	}
-
-	// Hash the password for comparison
-	hashedPassword, err := auth.HashPassword(password)
-	if err != nil {
-		logger.WithError(err).Fatal("Failed to hash password")
-	}
-
+func NewAuthHandler(credentials *services.CredentialService, tokens *services.TokenService, mfa *services.MFAService, throttle *services.LoginThrottle, logger *logrus.Logger) *AuthHandler {
 	return &AuthHandler{
-		jwtManager:   auth.NewJWTManager(jwtSecret, 24*time.Hour),
-		logger:       logger,
-		repo:         repo,
-		demoPassword: hashedPassword,
+		credentials: credentials,
+		tokens:      tokens,
+		mfa:         mfa,
+		throttle:    throttle,
+		logger:      logger,
 	}
 }
 
@@ -56,9 +55,32 @@ type LoginRequest struct {
 
 // LoginResponse represents a login response
 type LoginResponse struct {
-	Token     string `json:"token"`
-	ExpiresIn int    `json:"expiresIn"` // seconds
-	User      User   `json:"user"`
+	Token            string `json:"token"`
+	ExpiresIn        int    `json:"expiresIn"` // seconds
+	RefreshToken     string `json:"refreshToken"`
+	RefreshExpiresIn int    `json:"refreshExpiresIn"` // seconds
+	User             User   `json:"user"`
+}
+
+// MFAChallengeResponse is returned by /login instead of tokens when the user
+// has two-factor authentication enabled
+type MFAChallengeResponse struct {
+	MFARequired bool     `json:"mfaRequired"`
+	MFAToken    string   `json:"mfaToken"`
+	ExpiresIn   int      `json:"expiresIn"` // seconds
+	Methods     []string `json:"methods"`
+}
+
+// LoginMFARequest completes a login with the MFA token and a TOTP or recovery code
+type LoginMFARequest struct {
+	MFAToken     string `json:"mfaToken"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recoveryCode,omitempty"`
+}
+
+// RefreshRequest represents a token refresh request
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
 }
 
 // User represents basic user info
@@ -71,43 +93,212 @@ type User struct {
 // Login handles user login
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		problem.Write(w, r, http.StatusMethodNotAllowed, "Use POST to sign in")
 		return
 	}
 
 	var req LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.logger.WithError(err).Error("Failed to decode login request")
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+		problem.Write(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Refuse attempts for a username or client that failed too often recently
+	ip := middleware.ClientIP(r)
+	if h.throttled(w, r, req.Username, ip) {
+		return
+	}
+
+	// Look up the user and check their password
+	user, err := h.credentials.Authenticate(req.Username, req.Password)
+	if err != nil {
+		h.throttle.Failed(req.Username, ip)
+		problem.Write(w, r, http.StatusUnauthorized, "Invalid credentials")
+		return
+	}
+
+	// Users with MFA get a challenge to complete at /login/mfa instead of
+	// tokens. Their failures are only cleared once the second factor is
+	// accepted, so starting new challenges gives no more guesses at it.
+	required, err := h.mfa.Required(user.ID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to check MFA enrollment")
+		return
+	}
+	if required {
+		h.respondWithChallenge(w, r, user.ID)
+		return
+	}
+
+	h.throttle.Succeeded(req.Username)
+	h.respondWithToken(w, r, http.StatusOK, user)
+	h.logger.WithField("username", req.Username).Info("User logged in successfully")
+}
+
+// LoginMFA handles POST /login/mfa - the second step of signing in for users
+// with MFA enabled. The MFA token from /login is exchanged, together with a
+// TOTP code or a recovery code, for access and refresh tokens.
+func (h *AuthHandler) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	var req LoginMFARequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.MFAToken == "" || (req.Code == "" && req.RecoveryCode == "") {
+		problem.Write(w, r, http.StatusBadRequest, "mfaToken and code or recoveryCode are required")
+		return
+	}
+
+	// Wrong codes count against the username and client like wrong passwords
+	challenged, err := h.mfa.ChallengeUser(req.MFAToken)
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to verify code")
+		return
+	}
+	ip := middleware.ClientIP(r)
+	if h.throttled(w, r, challenged.Email, ip) {
+		return
+	}
+
+	user, err := h.mfa.CompleteChallenge(req.MFAToken, req.Code, req.RecoveryCode)
+	if err != nil {
+		// A wrong code means signing in failed, rather than a signed-in user being refused
+		if errors.Is(err, services.ErrInvalidMFACode) {
+			h.throttle.Failed(challenged.Email, ip)
+			problem.Write(w, r, http.StatusUnauthorized, "The verification code is incorrect or already used")
+			return
+		}
+		writeError(w, r, h.logger, err, "Failed to verify code")
 		return
 	}
 
-	// Look up user by email in the repository
-	user, err := h.repo.GetUserByEmail(req.Username)
+	h.throttle.Succeeded(user.Email)
+	h.respondWithToken(w, r, http.StatusOK, user)
+	h.logger.WithField("userId", user.ID).Info("User logged in successfully with MFA")
+}
+
+// throttled answers 429 and returns true when sign-in for username from ip
+// must wait after recent failures
+func (h *AuthHandler) throttled(w http.ResponseWriter, r *http.Request, username, ip string) bool {
+	var throttled *services.ThrottledError
+	if err := h.throttle.Check(username, ip); !errors.As(err, &throttled) {
+		return false
+	}
+	retryAfter := int(math.Ceil(throttled.RetryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	problem.Write(w, r, http.StatusTooManyRequests, "Too many failed sign-in attempts, try again later")
+	return true
+}
+
+// Register handles POST /register - creates a user with their own password
+// and signs them in
+func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
+	var req services.RegisterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	user, err := h.credentials.Register(&req)
 	if err != nil {
-		h.logger.WithField("username", req.Username).Warn("User not found")
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		writeError(w, r, h.logger, err, "Failed to register")
 		return
 	}
 
-	// Validate password (all users use demo password in demo mode)
-	if err := auth.VerifyPassword(h.demoPassword, req.Password); err != nil {
-		h.logger.WithField("username", req.Username).Warn("Invalid password")
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+	h.respondWithToken(w, r, http.StatusCreated, user)
+}
+
+// Refresh handles POST /token/refresh - exchanges a refresh token for a new
+// access token and refresh token. The presented refresh token stops working.
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		problem.Write(w, r, http.StatusBadRequest, "refreshToken is required")
 		return
 	}
 
-	// Generate JWT token
-	token, err := h.jwtManager.Generate(user.ID, req.Username)
+	pair, user, err := h.tokens.Refresh(req.RefreshToken)
 	if err != nil {
-		h.logger.WithError(err).Error("Failed to generate token")
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, r, h.logger, err, "Failed to refresh token")
+		return
+	}
+
+	h.writeTokens(w, http.StatusOK, pair, user)
+}
+
+// Logout handles POST /logout - revokes the access token used for the request
+// and the refresh tokens of its session
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r)
+	if claims == nil {
+		problem.Write(w, r, http.StatusUnauthorized, "Not signed in")
+		return
+	}
+	if claims.APIKeyID != "" {
+		problem.Write(w, r, http.StatusBadRequest, "API keys are revoked with DELETE /me/api-keys/{id}")
+		return
+	}
+
+	if err := h.tokens.Logout(claims); err != nil {
+		writeError(w, r, h.logger, err, "Failed to log out")
 		return
 	}
 
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requireSession answers 403 unless the request was made with an access
+// token the user got by signing in themselves, as credentials and sharing
+// cannot be managed with an API key or an impersonation token. It reports
+// whether the handler may go on.
+func requireSession(w http.ResponseWriter, r *http.Request, logger *logrus.Logger) bool {
+	claims := middleware.GetClaims(r)
+	if claims == nil {
+		problem.Write(w, r, http.StatusUnauthorized, "Not signed in")
+		return false
+	}
+	if err := services.CheckSession(claims); err != nil {
+		writeError(w, r, logger, err, "Failed to check the session")
+		return false
+	}
+	return true
+}
+
+// respondWithToken starts a session for user and writes a LoginResponse
+func (h *AuthHandler) respondWithToken(w http.ResponseWriter, r *http.Request, status int, user *models.User) {
+	pair, err := h.tokens.Issue(user)
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to generate token")
+		return
+	}
+
+	h.writeTokens(w, status, pair, user)
+}
+
+// respondWithChallenge starts an MFA challenge for a user whose password was
+// accepted and writes an MFAChallengeResponse
+func (h *AuthHandler) respondWithChallenge(w http.ResponseWriter, r *http.Request, userID string) {
+	token, expiresAt, err := h.mfa.StartChallenge(userID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to start MFA challenge")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(MFAChallengeResponse{
+		MFARequired: true,
+		MFAToken:    token,
+		ExpiresIn:   secondsUntil(expiresAt, time.Now()),
+		Methods:     []string{"totp", "recovery_code"},
+	})
+}
+
+// writeTokens writes a token pair and the user it belongs to as a LoginResponse
+func (h *AuthHandler) writeTokens(w http.ResponseWriter, status int, pair *services.TokenPair, user *models.User) {
+	now := time.Now()
 	response := LoginResponse{
-		Token:     token,
-		ExpiresIn: 86400, // 24 hours in seconds
+		Token:            pair.AccessToken,
+		ExpiresIn:        secondsUntil(pair.AccessExpiresAt, now),
+		RefreshToken:     pair.RefreshToken,
+		RefreshExpiresIn: secondsUntil(pair.RefreshExpiresAt, now),
 		User: User{
 			ID:    user.ID,
 			Email: user.Email,
@@ -116,7 +307,12 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(response)
+}
 
-	h.logger.WithField("username", req.Username).Info("User logged in successfully")
+// secondsUntil returns the whole seconds from now until t, rounded up since
+// token expiry times are truncated to the second
+func secondsUntil(t, now time.Time) int {
+	return int(math.Ceil(t.Sub(now).Seconds()))
 }
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/models"
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/services"
+	"github.com/CB-AccountStack/AccountStack/pkg/middleware"
+	"github.com/CB-AccountStack/AccountStack/pkg/problem"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// BalanceHistoryHandler handles balance history requests
+type BalanceHistoryHandler struct {
+	balanceHistoryService *services.BalanceHistoryService
+	logger                *logrus.Logger
+}
+
+// NewBalanceHistoryHandler creates a new balance history handler
+func NewBalanceHistoryHandler(balanceHistoryService *services.BalanceHistoryService, logger *logrus.Logger) *BalanceHistoryHandler {
+	return &BalanceHistoryHandler{
+		balanceHistoryService: balanceHistoryService,
+		logger:                logger,
+	}
+}
+
+// GetBalanceHistory handles GET /accounts/{id}/balance-history - returns
+// end-of-day balances for an account. Supports query parameters:
+// - from, to: YYYY-MM-DD, inclusive (default: a range ending today sized for the interval)
+// - interval: day (default), week or month
+func (h *BalanceHistoryHandler) GetBalanceHistory(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	accountID := mux.Vars(r)["id"]
+	query := r.URL.Query()
+
+	req := &services.BalanceHistoryRequest{Interval: query.Get("interval")}
+	for _, param := range []struct {
+		name string
+		dest *time.Time
+	}{{"from", &req.From}, {"to", &req.To}} {
+		value := query.Get(param.name)
+		if value == "" {
+			continue
+		}
+		date, err := time.Parse(models.DateLayout, value)
+		if err != nil {
+			problem.Write(w, r, http.StatusBadRequest, param.name+" must be a date in YYYY-MM-DD format")
+			return
+		}
+		*param.dest = date
+	}
+
+	history, err := h.balanceHistoryService.GetBalanceHistory(accountID, userID, req)
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to retrieve balance history")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(history)
+}
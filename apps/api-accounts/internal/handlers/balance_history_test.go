@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/models"
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/repository"
+)
+
+func TestGetBalanceHistory(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []models.BalancePointResponse
+	}{
+		{
+			name:  "daily",
+			query: "from=2024-11-30&to=2024-12-02",
+			want: []models.BalancePointResponse{
+				{Date: "2024-11-30", Balance: 2652.79},
+				{Date: "2024-12-01", Balance: 5852.79},
+				{Date: "2024-12-02", Balance: 5852.79},
+			},
+		},
+		{
+			name:  "monthly",
+			query: "from=2024-10-01&to=2024-12-31&interval=month",
+			want: []models.BalancePointResponse{
+				{Date: "2024-10-31", Balance: 2773.34},
+				{Date: "2024-11-30", Balance: 2652.79},
+				{Date: "2024-12-31", Balance: 5847.32},
+			},
+		},
+	}
+
+	for _, backend := range []string{repository.BackendJSON, repository.BackendSQLite} {
+		router := newTestRouter(t, backend)
+		for _, tt := range tests {
+			t.Run(backend+"/"+tt.name, func(t *testing.T) {
+				// The second request is served from the cache and must match
+				for i := 0; i < 2; i++ {
+					rec := doRequest(t, router, "/accounts/acc-001/balance-history?"+tt.query, "user-001")
+					if rec.Code != http.StatusOK {
+						t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+					}
+
+					var history models.BalanceHistoryResponse
+					if err := json.NewDecoder(rec.Body).Decode(&history); err != nil {
+						t.Fatalf("Failed to decode response: %v", err)
+					}
+					if len(history.Points) != len(tt.want) {
+						t.Fatalf("Expected %d points, got %+v", len(tt.want), history.Points)
+					}
+					for j, p := range history.Points {
+						if p != tt.want[j] {
+							t.Errorf("Point %d: expected %+v, got %+v", j, tt.want[j], p)
+						}
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestGetBalanceHistoryErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		userID     string
+		path       string
+		wantStatus int
+	}{
+		{"defaults", "user-001", "/accounts/acc-001/balance-history", http.StatusOK},
+		{"other user's account", "user-001", "/accounts/acc-004/balance-history", http.StatusNotFound},
+		{"other user's account reversed", "user-002", "/accounts/acc-001/balance-history", http.StatusNotFound},
+		{"missing account", "user-001", "/accounts/acc-999/balance-history", http.StatusNotFound},
+		{"anonymous", "", "/accounts/acc-001/balance-history", http.StatusUnauthorized},
+		{"invalid interval", "user-001", "/accounts/acc-001/balance-history?interval=hour", http.StatusBadRequest},
+		{"invalid date", "user-001", "/accounts/acc-001/balance-history?from=12/01/2024", http.StatusBadRequest},
+		{"from after to", "user-001", "/accounts/acc-001/balance-history?from=2024-12-02&to=2024-12-01", http.StatusBadRequest},
+		{"range too long", "user-001", "/accounts/acc-001/balance-history?from=2000-01-01&to=2024-12-01", http.StatusBadRequest},
+	}
+
+	router := newTestRouter(t, repository.BackendJSON)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(t, router, tt.path, tt.userID)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
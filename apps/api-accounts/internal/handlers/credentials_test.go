@@ -0,0 +1,332 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/credentials"
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/events"
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/notify"
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/repository"
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/services"
+	"github.com/CB-AccountStack/AccountStack/pkg/auth"
+	"github.com/CB-AccountStack/AccountStack/pkg/middleware"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+const testSeedPassword = "seed-password"
+
+// newCredentialsRouter wires the credential and token endpoints with a file
+// notifier and returns the router and the notifier's outbox path
+func newCredentialsRouter(t *testing.T, backend string) (*mux.Router, string) {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	repo, err := repository.NewStore(repository.Config{
+		Backend:    backend,
+		DataPath:   "testdata",
+		SQLitePath: filepath.Join(t.TempDir(), "test.db"),
+	}, logger)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+
+	outbox := filepath.Join(t.TempDir(), "outbox.jsonl")
+	credentialService, err := services.NewCredentialService(repo, notify.NewFileNotifier(outbox), services.CredentialConfig{
+		SeedPassword: testSeedPassword,
+		ResetURL:     "https://app.example.com/reset",
+	}, logger)
+	if err != nil {
+		t.Fatalf("Failed to create credential service: %v", err)
+	}
+
+	jwtManager := newTestJWTManager(t, 15*time.Minute)
+	revocations := auth.NewMemoryRevocationList()
+	apiKeys := auth.NewMemoryAPIKeyStore()
+	tokens := services.NewTokenService(repo, jwtManager, revocations, time.Hour, logger)
+
+	mfaService := services.NewMFAService(repo, logger)
+	throttle := services.NewLoginThrottle(repo, credentials.NewMemoryAttemptCounter(), events.NewLogPublisher(logger), logger)
+	authHandler := NewAuthHandler(credentialService, tokens, mfaService, throttle, logger)
+	mfaHandler := NewMFAHandler(mfaService, logger)
+	passwordHandler := NewPasswordHandler(credentialService, tokens, logger)
+	userHandler := NewUserHandler(services.NewUserService(repo, logger), logger)
+	accountService := services.NewAccountService(repo, nil, logger)
+	accountHandler := NewAccountHandler(accountService, logger)
+	adminHandler := NewAdminHandler(services.NewAdminService(repo, accountService, tokens, jwtManager, logger), logger)
+	apiKeyHandler := NewAPIKeyHandler(services.NewAPIKeyService(apiKeys, logger), logger)
+	sharingHandler := NewSharingHandler(services.NewSharingService(repo, accountService, notify.NewFileNotifier(outbox), logger), logger)
+	scoped := func(scope string, h http.HandlerFunc) http.Handler {
+		return middleware.RequireScope(scope, logger)(h)
+	}
+
+	router := mux.NewRouter()
+	router.Use(middleware.AuthMiddleware(jwtManager, revocations, apiKeys, logger, PublicPaths...))
+	router.Handle("/.well-known/jwks.json", NewJWKSHandler(jwtManager, logger)).Methods("GET")
+	router.HandleFunc("/login", authHandler.Login).Methods("POST")
+	router.HandleFunc("/login/mfa", authHandler.LoginMFA).Methods("POST")
+	router.HandleFunc("/register", authHandler.Register).Methods("POST")
+	router.HandleFunc("/token/refresh", authHandler.Refresh).Methods("POST")
+	router.HandleFunc("/logout", authHandler.Logout).Methods("POST")
+	router.HandleFunc("/password/forgot", passwordHandler.ForgotPassword).Methods("POST")
+	router.HandleFunc("/password/reset", passwordHandler.ResetPassword).Methods("POST")
+	router.Handle("/me", scoped(auth.ScopeAccountsRead, userHandler.GetMe)).Methods("GET")
+	router.Handle("/me/password", scoped(auth.ScopeAccountsWrite, passwordHandler.ChangePassword)).Methods("POST")
+	router.Handle("/me/mfa", scoped(auth.ScopeAccountsRead, mfaHandler.GetStatus)).Methods("GET")
+	router.Handle("/me/mfa/totp", scoped(auth.ScopeAccountsWrite, mfaHandler.EnrollTOTP)).Methods("POST")
+	router.Handle("/me/mfa/totp", scoped(auth.ScopeAccountsWrite, mfaHandler.DisableTOTP)).Methods("DELETE")
+	router.Handle("/me/mfa/totp/verify", scoped(auth.ScopeAccountsWrite, mfaHandler.ConfirmTOTP)).Methods("POST")
+	router.Handle("/me/mfa/recovery-codes", scoped(auth.ScopeAccountsWrite, mfaHandler.RegenerateRecoveryCodes)).Methods("POST")
+	router.Handle("/me/api-keys", scoped(auth.ScopeAccountsRead, apiKeyHandler.ListAPIKeys)).Methods("GET")
+	router.Handle("/me/api-keys", scoped(auth.ScopeAccountsWrite, apiKeyHandler.CreateAPIKey)).Methods("POST")
+	router.Handle("/me/api-keys/{id}", scoped(auth.ScopeAccountsWrite, apiKeyHandler.RevokeAPIKey)).Methods("DELETE")
+	router.Handle("/me/invitations", scoped(auth.ScopeAccountsRead, sharingHandler.ListInvitations)).Methods("GET")
+	router.Handle("/me/invitations/{id}/accept", scoped(auth.ScopeAccountsWrite, sharingHandler.AcceptInvitation)).Methods("POST")
+	router.Handle("/me/invitations/{id}/decline", scoped(auth.ScopeAccountsWrite, sharingHandler.DeclineInvitation)).Methods("POST")
+	router.Handle("/accounts", scoped(auth.ScopeAccountsRead, accountHandler.GetAccounts)).Methods("GET")
+	router.Handle("/accounts/{id}", scoped(auth.ScopeAccountsRead, accountHandler.GetAccountByID)).Methods("GET")
+	router.Handle("/accounts/{id}/access", scoped(auth.ScopeAccountsRead, sharingHandler.GetAccess)).Methods("GET")
+	router.Handle("/accounts/{id}/access/{userId}", scoped(auth.ScopeAccountsWrite, sharingHandler.RemoveAccess)).Methods("DELETE")
+	router.Handle("/accounts/{id}/invitations", scoped(auth.ScopeAccountsWrite, sharingHandler.Invite)).Methods("POST")
+	router.Handle("/accounts/{id}/invitations/{invitationId}", scoped(auth.ScopeAccountsWrite, sharingHandler.CancelInvitation)).Methods("DELETE")
+	router.Handle("/admin/users", scoped(auth.ScopeAdminRead, adminHandler.ListUsers)).Methods("GET")
+	router.Handle("/admin/users/{id}/accounts", scoped(auth.ScopeAdminRead, adminHandler.GetUserAccounts)).Methods("GET")
+	router.Handle("/admin/users/{id}/role", scoped(auth.ScopeAdminWrite, adminHandler.SetRole)).Methods("PUT")
+	router.Handle("/admin/accounts/{id}", scoped(auth.ScopeAdminRead, adminHandler.GetAccount)).Methods("GET")
+	router.Handle("/admin/impersonate", scoped(auth.ScopeAdminImpersonate, adminHandler.Impersonate)).Methods("POST")
+	router.Handle("/admin/audit", scoped(auth.ScopeAdminRead, adminHandler.GetAuditLog)).Methods("GET")
+	return router, outbox
+}
+
+// post sends a JSON body as userID, or anonymously when userID is empty
+func post(t *testing.T, router http.Handler, path, body, userID string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest("POST", path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return serve(t, router, req, userID)
+}
+
+// login returns the status of a login attempt
+func login(t *testing.T, router http.Handler, email, password string) int {
+	t.Helper()
+	body, _ := json.Marshal(LoginRequest{Username: email, Password: password})
+	return post(t, router, "/login", string(body), "").Code
+}
+
+func TestRegister(t *testing.T) {
+	for _, backend := range []string{repository.BackendJSON, repository.BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			router, _ := newCredentialsRouter(t, backend)
+
+			rec := post(t, router, "/register",
+				`{"email":" New.User@Example.com ","password":"correct horse","firstName":"New","lastName":"User","country":"fr"}`, "")
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+			}
+			var resp LoginResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if resp.Token == "" || resp.User.Email != "new.user@example.com" || resp.User.Name != "New User" {
+				t.Errorf("Unexpected registration response: %+v", resp)
+			}
+
+			// The returned token works, and the profile never includes the hash
+			req := httptest.NewRequest("GET", "/me", nil)
+			req.Header.Set("Authorization", "Bearer "+resp.Token)
+			me := httptest.NewRecorder()
+			router.ServeHTTP(me, req)
+			if me.Code != http.StatusOK || !strings.Contains(me.Body.String(), `"country":"FR"`) {
+				t.Errorf("Expected the new user's profile, got %d: %s", me.Code, me.Body.String())
+			}
+			if strings.Contains(strings.ToLower(me.Body.String()), "password") {
+				t.Errorf("Profile leaked the password hash: %s", me.Body.String())
+			}
+
+			if code := login(t, router, "new.user@example.com", "correct horse"); code != http.StatusOK {
+				t.Errorf("Expected the new password to sign in, got %d", code)
+			}
+			if code := login(t, router, "new.user@example.com", testSeedPassword); code != http.StatusUnauthorized {
+				t.Errorf("Expected the seed password to be rejected for a registered user, got %d", code)
+			}
+		})
+	}
+}
+
+func TestRegisterErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"email taken", `{"email":"DEMO@accountstack.com","password":"long enough","firstName":"A","lastName":"B"}`, http.StatusConflict},
+		{"short password", `{"email":"a@example.com","password":"short","firstName":"A","lastName":"B"}`, http.StatusBadRequest},
+		{"invalid email", `{"email":"not an email","password":"long enough","firstName":"A","lastName":"B"}`, http.StatusBadRequest},
+		{"missing name", `{"email":"a@example.com","password":"long enough","firstName":"A"}`, http.StatusBadRequest},
+		{"malformed body", `{`, http.StatusBadRequest},
+	}
+
+	router, _ := newCredentialsRouter(t, repository.BackendJSON)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := post(t, router, "/register", tt.body, ""); rec.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	for _, backend := range []string{repository.BackendJSON, repository.BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			router, _ := newCredentialsRouter(t, backend)
+
+			if code := login(t, router, "demo@accountstack.com", testSeedPassword); code != http.StatusOK {
+				t.Fatalf("Expected seeded users to sign in with the seed password, got %d", code)
+			}
+
+			rec := post(t, router, "/me/password", `{"currentPassword":"wrong","newPassword":"a new password"}`, "user-001")
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("Expected status 403 for a wrong current password, got %d", rec.Code)
+			}
+			rec = post(t, router, "/me/password", `{"currentPassword":"`+testSeedPassword+`","newPassword":"a new password"}`, "")
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("Expected status 401 without a token, got %d", rec.Code)
+			}
+			rec = post(t, router, "/me/password", `{"currentPassword":"`+testSeedPassword+`","newPassword":"a new password"}`, "user-001")
+			if rec.Code != http.StatusNoContent {
+				t.Fatalf("Expected status 204, got %d: %s", rec.Code, rec.Body.String())
+			}
+
+			if code := login(t, router, "demo@accountstack.com", "a new password"); code != http.StatusOK {
+				t.Errorf("Expected the new password to sign in, got %d", code)
+			}
+			if code := login(t, router, "demo@accountstack.com", testSeedPassword); code != http.StatusUnauthorized {
+				t.Errorf("Expected the old password to be rejected, got %d", code)
+			}
+			// Other seeded users are unaffected
+			if code := login(t, router, "sarah.chen@accountstack.com", testSeedPassword); code != http.StatusOK {
+				t.Errorf("Expected another seeded user to keep the seed password, got %d", code)
+			}
+		})
+	}
+}
+
+func TestPasswordReset(t *testing.T) {
+	for _, backend := range []string{repository.BackendJSON, repository.BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			router, outbox := newCredentialsRouter(t, backend)
+
+			// Unknown emails get the same response and no message
+			if rec := post(t, router, "/password/forgot", `{"email":"nobody@example.com"}`, ""); rec.Code != http.StatusAccepted {
+				t.Errorf("Expected status 202 for an unknown email, got %d", rec.Code)
+			}
+			if _, err := os.Stat(outbox); !os.IsNotExist(err) {
+				t.Error("Expected no message for an unknown email")
+			}
+
+			if rec := post(t, router, "/password/forgot", `{"email":"sarah.chen@accountstack.com"}`, ""); rec.Code != http.StatusAccepted {
+				t.Fatalf("Expected status 202, got %d", rec.Code)
+			}
+			if rec := post(t, router, "/password/forgot", `{"email":"sarah.chen@accountstack.com"}`, ""); rec.Code != http.StatusAccepted {
+				t.Fatalf("Expected status 202, got %d", rec.Code)
+			}
+			tokens := readResetTokens(t, outbox, "sarah.chen@accountstack.com")
+			if len(tokens) != 2 || tokens[0] == tokens[1] {
+				t.Fatalf("Expected two distinct reset tokens, got %v", tokens)
+			}
+
+			reset := func(token, password string) int {
+				body, _ := json.Marshal(ResetPasswordRequest{Token: token, NewPassword: password})
+				return post(t, router, "/password/reset", string(body), "").Code
+			}
+
+			if code := reset(tokens[1], "short"); code != http.StatusBadRequest {
+				t.Errorf("Expected a weak password to be rejected, got %d", code)
+			}
+			if code := reset(tokens[1], "reset password"); code != http.StatusNoContent {
+				t.Fatalf("Expected status 204, got %d", code)
+			}
+			if code := login(t, router, "sarah.chen@accountstack.com", "reset password"); code != http.StatusOK {
+				t.Errorf("Expected the reset password to sign in, got %d", code)
+			}
+
+			// Tokens are single use, and redeeming one voids the user's others
+			if code := reset(tokens[1], "another password"); code != http.StatusBadRequest {
+				t.Errorf("Expected a used token to be rejected, got %d", code)
+			}
+			if code := reset(tokens[0], "another password"); code != http.StatusBadRequest {
+				t.Errorf("Expected an older token to be voided, got %d", code)
+			}
+			if code := reset("made-up-token", "another password"); code != http.StatusBadRequest {
+				t.Errorf("Expected an unknown token to be rejected, got %d", code)
+			}
+		})
+	}
+}
+
+// readResetTokens returns the reset tokens sent to an address, oldest first
+func readResetTokens(t *testing.T, outbox, to string) []string {
+	t.Helper()
+	file, err := os.Open(outbox)
+	if err != nil {
+		t.Fatalf("Failed to open outbox: %v", err)
+	}
+	defer file.Close()
+
+	var tokens []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var msg notify.Message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			t.Fatalf("Invalid outbox line: %v", err)
+		}
+		if msg.To != to {
+			continue
+		}
+		_, token, found := strings.Cut(msg.Body, "https://app.example.com/reset?token=")
+		if !found {
+			t.Fatalf("Expected a reset link in %q", msg.Body)
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+func TestLoginThrottling(t *testing.T) {
+	router, _ := newCredentialsRouter(t, repository.BackendJSON)
+
+	// Unknown and known usernames fail identically until they back off
+	for _, email := range []string{"demo@accountstack.com", "nobody@accountstack.com"} {
+		for i := 0; i < 5; i++ {
+			if code := login(t, router, email, "wrong-password"); code != http.StatusUnauthorized {
+				t.Fatalf("Expected status 401 for %s, got %d", email, code)
+			}
+		}
+
+		body, _ := json.Marshal(LoginRequest{Username: email, Password: testSeedPassword})
+		rec := post(t, router, "/login", string(body), "")
+		if rec.Code != http.StatusTooManyRequests {
+			t.Fatalf("Expected status 429 for %s, got %d", email, rec.Code)
+		}
+		if rec.Header().Get("Retry-After") != "1" {
+			t.Errorf("Expected Retry-After: 1, got %q", rec.Header().Get("Retry-After"))
+		}
+	}
+
+	// Other users are unaffected, even from the same address
+	if code := login(t, router, "sarah.chen@accountstack.com", testSeedPassword); code != http.StatusOK {
+		t.Errorf("Expected another user to sign in, got %d", code)
+	}
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/repository"
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/services"
+	"github.com/CB-AccountStack/AccountStack/pkg/problem"
+	"github.com/sirupsen/logrus"
+)
+
+// errorStatus is how an error returned by a service is answered
+type errorStatus struct {
+	err    error
+	status int
+	detail string // sent instead of the error's message when set
+}
+
+// errorStatuses maps the errors the services return to the status of the
+// response. Errors not listed are internal and answered with 500.
+var errorStatuses = []errorStatus{
+	// Accounts, statements and balance history
+	{services.ErrAccountNotFound, http.StatusNotFound, "Account not found"},
+	{services.ErrAccountAccessDenied, http.StatusNotFound, "Account not found"},
+	{services.ErrInvalidPeriod, http.StatusBadRequest, ""},
+	{services.ErrStatementNotAvailable, http.StatusNotFound, ""},
+	{services.ErrInvalidInterval, http.StatusBadRequest, ""},
+	{services.ErrInvalidRange, http.StatusBadRequest, ""},
+
+	// Sharing
+	{services.ErrInvalidAccess, http.StatusBadRequest, ""},
+	{services.ErrInvalidInvitee, http.StatusBadRequest, ""},
+	{services.ErrOwnerAccess, http.StatusBadRequest, ""},
+	{services.ErrNotAccountOwner, http.StatusForbidden, ""},
+	{services.ErrViewerAccess, http.StatusForbidden, ""},
+	{services.ErrGrantNotFound, http.StatusNotFound, "That user has no access to this account"},
+	{services.ErrInvitationNotFound, http.StatusNotFound, "Invitation not found"},
+	{services.ErrInvitationTokenInvalid, http.StatusForbidden, ""},
+	{services.ErrAlreadyShared, http.StatusConflict, ""},
+	{services.ErrInvitationPending, http.StatusConflict, ""},
+	{services.ErrInvitationClosed, http.StatusConflict, ""},
+
+	// Registration and passwords
+	{services.ErrInvalidRegistration, http.StatusBadRequest, ""},
+	{services.ErrWeakPassword, http.StatusBadRequest, ""},
+	{services.ErrInvalidCredentials, http.StatusForbidden, "Current password is incorrect"},
+	{repository.ErrEmailTaken, http.StatusConflict, "An account with this email already exists"},
+	{repository.ErrResetTokenInvalid, http.StatusBadRequest, "The reset token is invalid, expired or already used"},
+
+	// Tokens and two-factor authentication
+	{services.ErrRefreshTokenInvalid, http.StatusUnauthorized, "Refresh token is invalid, expired or revoked"},
+	{services.ErrRefreshTokenReused, http.StatusUnauthorized, "Refresh token is invalid, expired or revoked"},
+	{services.ErrSessionRequired, http.StatusForbidden, ""},
+	{services.ErrMFAChallengeInvalid, http.StatusUnauthorized, "MFA token is invalid or expired, sign in again"},
+	{services.ErrInvalidMFACode, http.StatusForbidden, "The verification code is incorrect or already used"},
+	{services.ErrMFAAlreadyEnabled, http.StatusConflict, ""},
+	{services.ErrMFANotEnabled, http.StatusConflict, ""},
+	{services.ErrMFANotEnrolled, http.StatusConflict, ""},
+
+	// API keys
+	{services.ErrInvalidAPIKeyName, http.StatusBadRequest, ""},
+	{services.ErrInvalidAPIKeyScope, http.StatusBadRequest, ""},
+	{services.ErrInvalidAPIKeyExpiry, http.StatusBadRequest, ""},
+	{services.ErrTooManyAPIKeys, http.StatusConflict, ""},
+	{services.ErrAPIKeyNotFound, http.StatusNotFound, "API key not found"},
+
+	// Administration
+	{services.ErrUserNotFound, http.StatusNotFound, "User not found"},
+	{services.ErrInvalidRole, http.StatusBadRequest, ""},
+	{services.ErrOwnRole, http.StatusBadRequest, ""},
+	{services.ErrReasonRequired, http.StatusBadRequest, ""},
+	{services.ErrImpersonationNotAllowed, http.StatusForbidden, ""},
+}
+
+// writeError answers a failed service call with a problem response. Errors in
+// errorStatuses get their status; anything else is logged and answered with
+// 500 and fallback as the detail, so internal errors are not exposed.
+func writeError(w http.ResponseWriter, r *http.Request, logger *logrus.Logger, err error, fallback string) {
+	for _, known := range errorStatuses {
+		if errors.Is(err, known.err) {
+			detail := known.detail
+			if detail == "" {
+				detail = err.Error()
+			}
+			problem.Write(w, r, known.status, detail)
+			return
+		}
+	}
+
+	logger.WithError(err).WithField("path", r.URL.Path).Error(fallback)
+	problem.Write(w, r, http.StatusInternalServerError, fallback)
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/repository"
+	"github.com/CB-AccountStack/AccountStack/pkg/problem"
+	"github.com/CB-AccountStack/AccountStack/pkg/requestid"
+)
+
+func TestErrorsAreProblems(t *testing.T) {
+	router := requestid.Middleware(newTestRouter(t, repository.BackendSQLite))
+
+	tests := []struct {
+		name       string
+		path       string
+		userID     string
+		wantStatus int
+		wantDetail string
+	}{
+		{"no token", "/accounts/acc-001", "", http.StatusUnauthorized, "A valid access token or API key is required"},
+		{"other user's account", "/accounts/acc-004", "user-001", http.StatusNotFound, "Account not found"},
+		{"missing account", "/accounts/acc-999", "user-001", http.StatusNotFound, "Account not found"},
+		{"invalid query", "/accounts/acc-001/balance-history?from=yesterday", "user-001", http.StatusBadRequest, "from must be a date in YYYY-MM-DD format"},
+		{"invalid interval", "/accounts/acc-001/balance-history?interval=hour", "user-001", http.StatusBadRequest, "interval must be day, week or month"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			req.Header.Set(requestid.Header, "req-42")
+			rec := serve(t, router, req, tt.userID)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != problem.ContentType {
+				t.Errorf("Expected a problem response, got %q", ct)
+			}
+			var body problem.Details
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("Failed to decode problem: %v", err)
+			}
+			if body.Status != tt.wantStatus || body.Detail != tt.wantDetail || body.Title != http.StatusText(tt.wantStatus) {
+				t.Errorf("Unexpected problem %+v", body)
+			}
+			if body.Instance != "urn:accountstack:request:req-42" || body.RequestID != "req-42" {
+				t.Errorf("Expected the instance and request ID to name req-42, got %+v", body)
+			}
+		})
+	}
+}
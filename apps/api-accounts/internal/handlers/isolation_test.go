@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/repository"
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/services"
+	"github.com/CB-AccountStack/AccountStack/pkg/auth"
+	"github.com/CB-AccountStack/AccountStack/pkg/middleware"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// testSigningKey signs every token in the handler tests
+var testSigningKey = func() *auth.SigningKey {
+	key, err := auth.GenerateSigningKey()
+	if err != nil {
+		panic(err)
+	}
+	return key
+}()
+
+// newTestJWTManager creates a manager that signs and verifies with testSigningKey
+func newTestJWTManager(t *testing.T, tokenDuration time.Duration) *auth.JWTManager {
+	t.Helper()
+	manager, err := auth.NewJWTManager([]*auth.SigningKey{testSigningKey}, "", tokenDuration)
+	if err != nil {
+		t.Fatalf("Failed to create JWT manager: %v", err)
+	}
+	return manager
+}
+
+// newTestRouter wires the real handlers and auth middleware over the test fixtures
+func newTestRouter(t *testing.T, backend string) *mux.Router {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	repo, err := repository.NewStore(repository.Config{
+		Backend:    backend,
+		DataPath:   "testdata",
+		SQLitePath: filepath.Join(t.TempDir(), "test.db"),
+	}, logger)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+
+	accountHandler := NewAccountHandler(services.NewAccountService(repo, nil, logger), logger)
+	userHandler := NewUserHandler(services.NewUserService(repo, logger), logger)
+	statementHandler := NewStatementHandler(services.NewStatementService(repo, nil, logger), logger)
+	balanceHistoryHandler := NewBalanceHistoryHandler(services.NewBalanceHistoryService(repo, nil, logger), logger)
+
+	router := mux.NewRouter()
+	router.Use(middleware.AuthMiddleware(newTestJWTManager(t, time.Hour), auth.NewMemoryRevocationList(), auth.NewMemoryAPIKeyStore(), logger, PublicPaths...))
+	router.HandleFunc("/me", userHandler.GetMe).Methods("GET")
+	router.HandleFunc("/accounts", accountHandler.GetAccounts).Methods("GET")
+	router.HandleFunc("/accounts/{id}", accountHandler.GetAccountByID).Methods("GET")
+	router.HandleFunc("/accounts/{id}/balance-history", balanceHistoryHandler.GetBalanceHistory).Methods("GET")
+	router.HandleFunc("/accounts/{id}/statements", statementHandler.GetStatements).Methods("GET")
+	router.HandleFunc("/accounts/{id}/statements/{period}", statementHandler.GetStatement).Methods("GET")
+	return router
+}
+
+// doRequest issues a GET as userID, or anonymously when userID is empty
+func doRequest(t *testing.T, router http.Handler, path, userID string) *httptest.ResponseRecorder {
+	t.Helper()
+	return serve(t, router, httptest.NewRequest("GET", path, nil), userID)
+}
+
+// serve signs req as userID, unless it is empty, and records the response
+func serve(t *testing.T, router http.Handler, req *http.Request, userID string) *httptest.ResponseRecorder {
+	t.Helper()
+	if userID != "" {
+		token, err := newTestJWTManager(t, time.Hour).Generate(userID, userID+"@example.com")
+		if err != nil {
+			t.Fatalf("Failed to generate token: %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGetAccountByIDIsolation(t *testing.T) {
+	tests := []struct {
+		name       string
+		userID     string
+		accountID  string
+		wantStatus int
+	}{
+		{"owner can read", "user-001", "acc-001", http.StatusOK},
+		{"second owner can read", "user-002", "acc-004", http.StatusOK},
+		{"other user's account", "user-001", "acc-004", http.StatusNotFound},
+		{"other user's account reversed", "user-002", "acc-003", http.StatusNotFound},
+		{"unknown user", "user-999", "acc-001", http.StatusNotFound},
+		{"missing account", "user-001", "acc-999", http.StatusNotFound},
+		{"no token", "", "acc-001", http.StatusUnauthorized},
+	}
+
+	for _, backend := range []string{repository.BackendJSON, repository.BackendSQLite} {
+		router := newTestRouter(t, backend)
+		for _, tt := range tests {
+			t.Run(backend+"/"+tt.name, func(t *testing.T) {
+				rec := doRequest(t, router, "/accounts/"+tt.accountID, tt.userID)
+				if rec.Code != tt.wantStatus {
+					t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+				}
+				if rec.Code != http.StatusOK && strings.Contains(rec.Body.String(), "balance") {
+					t.Errorf("Response leaked account data: %s", rec.Body.String())
+				}
+			})
+		}
+	}
+}
+
+func TestGetAccountsIsolation(t *testing.T) {
+	for _, backend := range []string{repository.BackendJSON, repository.BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			router := newTestRouter(t, backend)
+
+			rec := doRequest(t, router, "/accounts", "user-002")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", rec.Code)
+			}
+
+			var accounts []map[string]any
+			if err := json.NewDecoder(rec.Body).Decode(&accounts); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if len(accounts) != 1 || accounts[0]["id"] != "acc-004" {
+				t.Errorf("Expected only acc-004 for user-002, got %v", accounts)
+			}
+		})
+	}
+}
+
+func TestGetMeIsolation(t *testing.T) {
+	router := newTestRouter(t, repository.BackendJSON)
+
+	rec := doRequest(t, router, "/me", "user-002")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+
+	var user map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&user); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if user["id"] != "user-002" {
+		t.Errorf("Expected user-002, got %v", user["id"])
+	}
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/CB-AccountStack/AccountStack/pkg/auth"
+	"github.com/CB-AccountStack/AccountStack/pkg/problem"
+	"github.com/sirupsen/logrus"
+)
+
+// jwksCacheControl lets clients cache the key set for five minutes. Verifiers
+// refetch early when they see an unknown kid, so a new key is picked up regardless.
+const jwksCacheControl = "public, max-age=300"
+
+// JWKSHandler publishes the public keys that verify access tokens
+type JWKSHandler struct {
+	jwtManager *auth.JWTManager
+	logger     *logrus.Logger
+}
+
+// NewJWKSHandler creates a new JWKS handler
+func NewJWKSHandler(jwtManager *auth.JWTManager, logger *logrus.Logger) *JWKSHandler {
+	return &JWKSHandler{
+		jwtManager: jwtManager,
+		logger:     logger,
+	}
+}
+
+// ServeHTTP handles GET /.well-known/jwks.json
+func (h *JWKSHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	set, err := h.jwtManager.JWKS()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to build JWKS")
+		problem.Write(w, r, http.StatusInternalServerError, "Failed to load signing keys")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", jwksCacheControl)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(set)
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/services"
+	"github.com/CB-AccountStack/AccountStack/pkg/middleware"
+	"github.com/CB-AccountStack/AccountStack/pkg/problem"
+	"github.com/sirupsen/logrus"
+)
+
+// MFAHandler handles TOTP enrollment and recovery code requests
+type MFAHandler struct {
+	mfa    *services.MFAService
+	logger *logrus.Logger
+}
+
+// NewMFAHandler creates a new MFA handler
+func NewMFAHandler(mfa *services.MFAService, logger *logrus.Logger) *MFAHandler {
+	return &MFAHandler{
+		mfa:    mfa,
+		logger: logger,
+	}
+}
+
+// MFACodeRequest carries a TOTP code, or a recovery code where one is accepted
+type MFACodeRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recoveryCode,omitempty"`
+}
+
+// RecoveryCodesResponse lists freshly issued recovery codes. They are only
+// ever shown once.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// GetStatus handles GET /me/mfa - reports the current user's second factors
+func (h *MFAHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	if !requireSession(w, r, h.logger) {
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+
+	status, err := h.mfa.Status(userID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to get MFA status")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(status)
+}
+
+// EnrollTOTP handles POST /me/mfa/totp - generates a TOTP secret, returned
+// with its otpauth URI and a QR code PNG. TOTP is not required at sign-in
+// until the enrollment is confirmed.
+func (h *MFAHandler) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	if !requireSession(w, r, h.logger) {
+		return
+	}
+
+	setup, err := h.mfa.EnrollTOTP(middleware.GetUserID(r))
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to start TOTP enrollment")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(setup)
+}
+
+// ConfirmTOTP handles POST /me/mfa/totp/verify - enables TOTP with a first
+// code from the authenticator and returns recovery codes
+func (h *MFAHandler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	if !requireSession(w, r, h.logger) {
+		return
+	}
+
+	var req MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		problem.Write(w, r, http.StatusBadRequest, "code is required")
+		return
+	}
+
+	codes, err := h.mfa.ConfirmTOTP(middleware.GetUserID(r), req.Code)
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to enable TOTP")
+		return
+	}
+
+	h.writeRecoveryCodes(w, codes)
+}
+
+// DisableTOTP handles DELETE /me/mfa/totp - turns off TOTP after checking a
+// TOTP or recovery code
+func (h *MFAHandler) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	if !requireSession(w, r, h.logger) {
+		return
+	}
+
+	var req MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || (req.Code == "" && req.RecoveryCode == "") {
+		problem.Write(w, r, http.StatusBadRequest, "code or recoveryCode is required")
+		return
+	}
+
+	if err := h.mfa.DisableTOTP(middleware.GetUserID(r), req.Code, req.RecoveryCode); err != nil {
+		writeError(w, r, h.logger, err, "Failed to disable TOTP")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes handles POST /me/mfa/recovery-codes - replaces the
+// recovery codes after checking a TOTP code
+func (h *MFAHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	if !requireSession(w, r, h.logger) {
+		return
+	}
+
+	var req MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		problem.Write(w, r, http.StatusBadRequest, "code is required")
+		return
+	}
+
+	codes, err := h.mfa.RegenerateRecoveryCodes(middleware.GetUserID(r), req.Code)
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to regenerate recovery codes")
+		return
+	}
+
+	h.writeRecoveryCodes(w, codes)
+}
+
+// writeRecoveryCodes writes a RecoveryCodesResponse that must not be cached
+func (h *MFAHandler) writeRecoveryCodes(w http.ResponseWriter, codes []string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(RecoveryCodesResponse{RecoveryCodes: codes})
+}
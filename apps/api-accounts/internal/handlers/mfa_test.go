@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/credentials"
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/repository"
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/services"
+)
+
+// totpCode returns the code for secret offset steps from now
+func totpCode(t *testing.T, secret string, offset int64) string {
+	t.Helper()
+	code, err := credentials.TOTPCode(secret, credentials.TOTPStep(time.Now())+offset)
+	if err != nil {
+		t.Fatalf("TOTPCode failed: %v", err)
+	}
+	return code
+}
+
+// enableTOTP enrolls and confirms TOTP for userID and returns the secret, the
+// recovery codes and the code that confirmed the enrollment
+func enableTOTP(t *testing.T, router http.Handler, userID string) (string, []string, string) {
+	t.Helper()
+	rec := post(t, router, "/me/mfa/totp", "", userID)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var setup services.TOTPSetup
+	if err := json.NewDecoder(rec.Body).Decode(&setup); err != nil {
+		t.Fatalf("Failed to decode enrollment: %v", err)
+	}
+	if !strings.HasPrefix(setup.OTPAuthURI, "otpauth://totp/") || !strings.Contains(setup.OTPAuthURI, setup.Secret) {
+		t.Errorf("Unexpected otpauth URI %q", setup.OTPAuthURI)
+	}
+	if len(setup.QRCode) < 8 || string(setup.QRCode[1:4]) != "PNG" {
+		t.Error("Expected a PNG QR code")
+	}
+
+	confirmCode := totpCode(t, setup.Secret, 0)
+	rec = post(t, router, "/me/mfa/totp/verify", `{"code":"`+confirmCode+`"}`, userID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp RecoveryCodesResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode recovery codes: %v", err)
+	}
+	if len(resp.RecoveryCodes) != 10 {
+		t.Fatalf("Expected 10 recovery codes, got %d", len(resp.RecoveryCodes))
+	}
+	return setup.Secret, resp.RecoveryCodes, confirmCode
+}
+
+// startLogin signs in with a password and returns the MFA challenge
+func startLogin(t *testing.T, router http.Handler, email string) MFAChallengeResponse {
+	t.Helper()
+	body, _ := json.Marshal(LoginRequest{Username: email, Password: testSeedPassword})
+	rec := post(t, router, "/login", string(body), "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var challenge MFAChallengeResponse
+	if err := json.NewDecoder(rec.Body).Decode(&challenge); err != nil {
+		t.Fatalf("Failed to decode login response: %v", err)
+	}
+	if !challenge.MFARequired || challenge.MFAToken == "" || strings.Contains(rec.Body.String(), `"token"`) {
+		t.Fatalf("Expected an MFA challenge without tokens, got %s", rec.Body.String())
+	}
+	return challenge
+}
+
+// completeLogin sends the second step of a login
+func completeLogin(t *testing.T, router http.Handler, req LoginMFARequest) *httptest.ResponseRecorder {
+	t.Helper()
+	body, _ := json.Marshal(req)
+	return post(t, router, "/login/mfa", string(body), "")
+}
+
+func TestTOTPLogin(t *testing.T) {
+	for _, backend := range []string{repository.BackendJSON, repository.BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			router, _ := newCredentialsRouter(t, backend)
+
+			// Enrolling alone does not change sign-in
+			if rec := post(t, router, "/me/mfa/totp", "", "user-001"); rec.Code != http.StatusCreated {
+				t.Fatalf("Expected status 201, got %d", rec.Code)
+			}
+			signIn(t, router, "demo@accountstack.com")
+
+			secret, recoveryCodes, confirmCode := enableTOTP(t, router, "user-001")
+			if rec := post(t, router, "/me/mfa/totp", "", "user-001"); rec.Code != http.StatusConflict {
+				t.Errorf("Expected status 409 when already enabled, got %d", rec.Code)
+			}
+
+			// The code that confirmed the enrollment cannot be replayed
+			challenge := startLogin(t, router, "demo@accountstack.com")
+			if rec := completeLogin(t, router, LoginMFARequest{MFAToken: challenge.MFAToken, Code: confirmCode}); rec.Code != http.StatusUnauthorized {
+				t.Errorf("Expected a replayed code to be rejected, got %d", rec.Code)
+			}
+			rec := completeLogin(t, router, LoginMFARequest{MFAToken: challenge.MFAToken, Code: totpCode(t, secret, 1)})
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+			var resp LoginResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || resp.Token == "" || resp.RefreshToken == "" {
+				t.Fatalf("Expected tokens, got %s", rec.Body.String())
+			}
+
+			// MFA tokens are single use and are not access tokens
+			if rec := completeLogin(t, router, LoginMFARequest{MFAToken: challenge.MFAToken, RecoveryCode: recoveryCodes[0]}); rec.Code != http.StatusUnauthorized {
+				t.Errorf("Expected a completed MFA token to be rejected, got %d", rec.Code)
+			}
+			if code := withToken(router, "GET", "/me", challenge.MFAToken); code != http.StatusUnauthorized {
+				t.Errorf("Expected the MFA token to be refused as an access token, got %d", code)
+			}
+
+			// Recovery codes work once, with or without the separator
+			challenge = startLogin(t, router, "demo@accountstack.com")
+			code := strings.ToUpper(strings.ReplaceAll(recoveryCodes[0], "-", ""))
+			if rec := completeLogin(t, router, LoginMFARequest{MFAToken: challenge.MFAToken, RecoveryCode: code}); rec.Code != http.StatusOK {
+				t.Fatalf("Expected a recovery code to sign in, got %d: %s", rec.Code, rec.Body.String())
+			}
+			challenge = startLogin(t, router, "demo@accountstack.com")
+			if rec := completeLogin(t, router, LoginMFARequest{MFAToken: challenge.MFAToken, RecoveryCode: recoveryCodes[0]}); rec.Code != http.StatusUnauthorized {
+				t.Errorf("Expected a used recovery code to be rejected, got %d", rec.Code)
+			}
+
+			status := doRequest(t, router, "/me/mfa", "user-001")
+			if !strings.Contains(status.Body.String(), `"totpEnabled":true`) || !strings.Contains(status.Body.String(), `"recoveryCodesRemaining":9`) {
+				t.Errorf("Unexpected MFA status: %s", status.Body.String())
+			}
+
+			// Disabling needs a valid second factor
+			req := httptest.NewRequest("DELETE", "/me/mfa/totp", strings.NewReader(`{"code":"000000"}`))
+			if rec := serve(t, router, req, "user-001"); rec.Code != http.StatusForbidden {
+				t.Errorf("Expected a wrong code to be refused, got %d", rec.Code)
+			}
+			req = httptest.NewRequest("DELETE", "/me/mfa/totp", strings.NewReader(`{"recoveryCode":"`+recoveryCodes[1]+`"}`))
+			if rec := serve(t, router, req, "user-001"); rec.Code != http.StatusNoContent {
+				t.Fatalf("Expected status 204, got %d: %s", rec.Code, rec.Body.String())
+			}
+			signIn(t, router, "demo@accountstack.com")
+		})
+	}
+}
+
+func TestTOTPLoginLockout(t *testing.T) {
+	router, _ := newCredentialsRouter(t, repository.BackendJSON)
+	secret, _, _ := enableTOTP(t, router, "user-002")
+
+	challenge := startLogin(t, router, "sarah.chen@accountstack.com")
+	for i := 0; i < 5; i++ {
+		if rec := completeLogin(t, router, LoginMFARequest{MFAToken: challenge.MFAToken, Code: "000000"}); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected status 401 for a wrong code, got %d", rec.Code)
+		}
+	}
+
+	// The wrong codes count against the user, who has to wait before trying again
+	rec := completeLogin(t, router, LoginMFARequest{MFAToken: challenge.MFAToken, Code: totpCode(t, secret, 1)})
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "1" {
+		t.Fatalf("Expected the user to be throttled for a second, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	time.Sleep(time.Second)
+
+	// After too many wrong codes even the right one needs a new password check
+	rec = completeLogin(t, router, LoginMFARequest{MFAToken: challenge.MFAToken, Code: totpCode(t, secret, 1)})
+	if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), "MFA token is invalid") {
+		t.Errorf("Expected the challenge to be locked, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := completeLogin(t, router, LoginMFARequest{MFAToken: "made-up", Code: "123456"}); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected an unknown MFA token to be rejected, got %d", rec.Code)
+	}
+	if rec := post(t, router, "/login/mfa", `{"mfaToken":"x"}`, ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without a code, got %d", rec.Code)
+	}
+}
+
+func TestTOTPLoginThrottling(t *testing.T) {
+	router, _ := newCredentialsRouter(t, repository.BackendJSON)
+	enableTOTP(t, router, "user-002")
+
+	// Each challenge allows a few wrong codes, but they count against the
+	// user across challenges, and knowing the password does not reset them
+	first := startLogin(t, router, "sarah.chen@accountstack.com")
+	second := startLogin(t, router, "sarah.chen@accountstack.com")
+	third := startLogin(t, router, "sarah.chen@accountstack.com")
+	for _, challenge := range []MFAChallengeResponse{first, second} {
+		for i := 0; i < 3; i++ {
+			rec := completeLogin(t, router, LoginMFARequest{MFAToken: challenge.MFAToken, Code: "000000"})
+			if rec.Code == http.StatusTooManyRequests {
+				break
+			}
+			if rec.Code != http.StatusUnauthorized {
+				t.Fatalf("Expected status 401 for a wrong code, got %d", rec.Code)
+			}
+		}
+	}
+
+	rec := completeLogin(t, router, LoginMFARequest{MFAToken: third.MFAToken, Code: "000000"})
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected wrong codes across challenges to be throttled, got %d", rec.Code)
+	}
+	body, _ := json.Marshal(LoginRequest{Username: "sarah.chen@accountstack.com", Password: testSeedPassword})
+	if rec := post(t, router, "/login", string(body), ""); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected a new challenge to be refused too, got %d", rec.Code)
+	}
+}
+
+func TestRegenerateRecoveryCodes(t *testing.T) {
+	router, _ := newCredentialsRouter(t, repository.BackendSQLite)
+
+	if rec := post(t, router, "/me/mfa/recovery-codes", `{"code":"123456"}`, "user-001"); rec.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 without TOTP enabled, got %d", rec.Code)
+	}
+
+	secret, oldCodes, _ := enableTOTP(t, router, "user-001")
+	rec := post(t, router, "/me/mfa/recovery-codes", `{"code":"`+totpCode(t, secret, 1)+`"}`, "user-001")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp RecoveryCodesResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || len(resp.RecoveryCodes) != 10 {
+		t.Fatalf("Expected 10 new recovery codes, got %s", rec.Body.String())
+	}
+
+	challenge := startLogin(t, router, "demo@accountstack.com")
+	if rec := completeLogin(t, router, LoginMFARequest{MFAToken: challenge.MFAToken, RecoveryCode: oldCodes[0]}); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected replaced recovery codes to stop working, got %d", rec.Code)
+	}
+	if rec := completeLogin(t, router, LoginMFARequest{MFAToken: challenge.MFAToken, RecoveryCode: resp.RecoveryCodes[0]}); rec.Code != http.StatusOK {
+		t.Errorf("Expected a new recovery code to sign in, got %d", rec.Code)
+	}
+}
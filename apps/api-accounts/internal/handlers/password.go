@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/services"
+	"github.com/CB-AccountStack/AccountStack/pkg/middleware"
+	"github.com/CB-AccountStack/AccountStack/pkg/problem"
+	"github.com/sirupsen/logrus"
+)
+
+// PasswordHandler handles password change and reset requests. A new
+// password ends every session of the user.
+type PasswordHandler struct {
+	credentials *services.CredentialService
+	tokens      *services.TokenService
+	logger      *logrus.Logger
+}
+
+// NewPasswordHandler creates a new password handler
+func NewPasswordHandler(credentials *services.CredentialService, tokens *services.TokenService, logger *logrus.Logger) *PasswordHandler {
+	return &PasswordHandler{
+		credentials: credentials,
+		tokens:      tokens,
+		logger:      logger,
+	}
+}
+
+// ChangePasswordRequest represents a password change request
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+// ForgotPasswordRequest represents a password reset request
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest represents the redemption of a password reset token
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"newPassword"`
+}
+
+// ChangePassword handles POST /me/password - replaces the current user's
+// password and signs out all of their sessions, this one included
+func (h *PasswordHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	if !requireSession(w, r, h.logger) {
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+
+	var req ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.credentials.ChangePassword(userID, req.CurrentPassword, req.NewPassword); err != nil {
+		writeError(w, r, h.logger, err, "Failed to change password")
+		return
+	}
+	if err := h.tokens.RevokeAllForUser(userID); err != nil {
+		writeError(w, r, h.logger, err, "Failed to sign out existing sessions")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ForgotPassword handles POST /password/forgot - sends a reset token to the
+// email address if it is registered. The response is the same either way.
+func (h *PasswordHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		problem.Write(w, r, http.StatusBadRequest, "email is required")
+		return
+	}
+
+	if err := h.credentials.RequestPasswordReset(req.Email); err != nil {
+		writeError(w, r, h.logger, err, "Failed to request a password reset")
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ResetPassword handles POST /password/reset - sets a new password using a
+// reset token and signs out all of the user's sessions
+func (h *PasswordHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		problem.Write(w, r, http.StatusBadRequest, "token and newPassword are required")
+		return
+	}
+
+	userID, err := h.credentials.ResetPassword(req.Token, req.NewPassword)
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to reset password")
+		return
+	}
+	if err := h.tokens.RevokeAllForUser(userID); err != nil {
+		writeError(w, r, h.logger, err, "Failed to sign out existing sessions")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
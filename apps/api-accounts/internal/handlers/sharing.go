@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/services"
+	"github.com/CB-AccountStack/AccountStack/pkg/middleware"
+	"github.com/CB-AccountStack/AccountStack/pkg/problem"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// SharingHandler handles account invitations and shared access
+type SharingHandler struct {
+	sharing *services.SharingService
+	logger  *logrus.Logger
+}
+
+// NewSharingHandler creates a new sharing handler
+func NewSharingHandler(sharing *services.SharingService, logger *logrus.Logger) *SharingHandler {
+	return &SharingHandler{
+		sharing: sharing,
+		logger:  logger,
+	}
+}
+
+// InvitationRequest invites someone to an account by email
+type InvitationRequest struct {
+	Email  string `json:"email"`
+	Access string `json:"access"` // joint or viewer
+}
+
+// Invite handles POST /accounts/{id}/invitations - invites someone to an
+// account the current user owns
+func (h *SharingHandler) Invite(w http.ResponseWriter, r *http.Request) {
+	if !requireSession(w, r, h.logger) {
+		return
+	}
+
+	var req InvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" || req.Access == "" {
+		problem.Write(w, r, http.StatusBadRequest, "email and access are required")
+		return
+	}
+
+	invitation, err := h.sharing.Invite(middleware.GetUserID(r), mux.Vars(r)["id"], req.Email, req.Access)
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to send invitation")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(invitation)
+}
+
+// GetAccess handles GET /accounts/{id}/access - lists who has access to an
+// account and the pending invitations
+func (h *SharingHandler) GetAccess(w http.ResponseWriter, r *http.Request) {
+	if !requireSession(w, r, h.logger) {
+		return
+	}
+
+	access, err := h.sharing.ListAccess(middleware.GetUserID(r), mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to list account access")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(access)
+}
+
+// RemoveAccess handles DELETE /accounts/{id}/access/{userId} - the owner
+// removes someone, or a joint holder or viewer leaves
+func (h *SharingHandler) RemoveAccess(w http.ResponseWriter, r *http.Request) {
+	if !requireSession(w, r, h.logger) {
+		return
+	}
+
+	vars := mux.Vars(r)
+	if err := h.sharing.RemoveAccess(middleware.GetUserID(r), vars["id"], vars["userId"]); err != nil {
+		writeError(w, r, h.logger, err, "Failed to remove access")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CancelInvitation handles DELETE /accounts/{id}/invitations/{invitationId}
+func (h *SharingHandler) CancelInvitation(w http.ResponseWriter, r *http.Request) {
+	if !requireSession(w, r, h.logger) {
+		return
+	}
+
+	vars := mux.Vars(r)
+	if err := h.sharing.CancelInvitation(middleware.GetUserID(r), vars["id"], vars["invitationId"]); err != nil {
+		writeError(w, r, h.logger, err, "Failed to cancel invitation")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListInvitations handles GET /me/invitations - lists pending invitations
+// to the current user's email
+func (h *SharingHandler) ListInvitations(w http.ResponseWriter, r *http.Request) {
+	if !requireSession(w, r, h.logger) {
+		return
+	}
+
+	invitations, err := h.sharing.ListInvitations(middleware.GetUserID(r))
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to list invitations")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(invitations)
+}
+
+// AcceptInvitationRequest carries the token sent to the invitee's email
+type AcceptInvitationRequest struct {
+	Token string `json:"token"`
+}
+
+// AcceptInvitation handles POST /me/invitations/{id}/accept - returns the
+// account that is now shared with the current user
+func (h *SharingHandler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	if !requireSession(w, r, h.logger) {
+		return
+	}
+
+	var req AcceptInvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		problem.Write(w, r, http.StatusBadRequest, "token is required")
+		return
+	}
+
+	account, err := h.sharing.AcceptInvitation(middleware.GetUserID(r), mux.Vars(r)["id"], req.Token)
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to accept invitation")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(account)
+}
+
+// DeclineInvitation handles POST /me/invitations/{id}/decline
+func (h *SharingHandler) DeclineInvitation(w http.ResponseWriter, r *http.Request) {
+	if !requireSession(w, r, h.logger) {
+		return
+	}
+
+	if err := h.sharing.DeclineInvitation(middleware.GetUserID(r), mux.Vars(r)["id"]); err != nil {
+		writeError(w, r, h.logger, err, "Failed to decline invitation")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/models"
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/notify"
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/repository"
+	"github.com/gorilla/mux"
+)
+
+// invite sends an invitation as accessToken and returns it
+func invite(t *testing.T, router *mux.Router, accountID, body, accessToken string) models.AccountInvitation {
+	t.Helper()
+	rec := bearer(router, "POST", "/accounts/"+accountID+"/invitations", body, accessToken)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected the invitation to be sent, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var invitation models.AccountInvitation
+	if err := json.NewDecoder(rec.Body).Decode(&invitation); err != nil {
+		t.Fatalf("Failed to decode invitation: %v", err)
+	}
+	return invitation
+}
+
+// invitationToken returns the token in the latest invitation sent to an
+// address, which ends the message
+func invitationToken(t *testing.T, outbox, to string) string {
+	t.Helper()
+	file, err := os.Open(outbox)
+	if err != nil {
+		t.Fatalf("Failed to open outbox: %v", err)
+	}
+	defer file.Close()
+
+	token := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var msg notify.Message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			t.Fatalf("Invalid outbox line: %v", err)
+		}
+		if msg.To == to && strings.Contains(msg.Subject, "invited") {
+			fields := strings.Fields(msg.Body)
+			token = fields[len(fields)-1]
+		}
+	}
+	if token == "" {
+		t.Fatalf("Expected an invitation to %s in the outbox", to)
+	}
+	return token
+}
+
+// acceptBody is the body accepting an invitation with token
+func acceptBody(token string) string {
+	return `{"token":"` + token + `"}`
+}
+
+// countMessages returns how many outbox messages were sent to an address
+func countMessages(t *testing.T, outbox, to string) int {
+	t.Helper()
+	file, err := os.Open(outbox)
+	if err != nil {
+		t.Fatalf("Failed to open outbox: %v", err)
+	}
+	defer file.Close()
+
+	count := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var msg notify.Message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			t.Fatalf("Invalid outbox line: %v", err)
+		}
+		if msg.To == to {
+			count++
+		}
+	}
+	return count
+}
+
+func TestAccountSharing(t *testing.T) {
+	for _, backend := range []string{repository.BackendJSON, repository.BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			router, outbox := newCredentialsRouter(t, backend)
+			owner := signIn(t, router, "demo@accountstack.com").Token
+			advisor := signIn(t, router, "sarah.chen@accountstack.com").Token
+
+			invitation := invite(t, router, "acc-001", `{"email":"Sarah.Chen@AccountStack.com","access":"viewer"}`, owner)
+			if invitation.Email != "sarah.chen@accountstack.com" || invitation.Status != models.InvitationPending {
+				t.Errorf("Unexpected invitation: %+v", invitation)
+			}
+			if n := countMessages(t, outbox, "sarah.chen@accountstack.com"); n != 1 {
+				t.Errorf("Expected the invitee to be notified once, got %d messages", n)
+			}
+			token := invitationToken(t, outbox, "sarah.chen@accountstack.com")
+
+			tests := []struct {
+				name   string
+				method string
+				path   string
+				body   string
+				token  string
+				want   int
+			}{
+				{"no access before accepting", "GET", "/accounts/acc-001", "", advisor, http.StatusNotFound},
+				{"unknown access level", "POST", "/accounts/acc-001/invitations", `{"email":"a@example.com","access":"owner"}`, owner, http.StatusBadRequest},
+				{"inviting oneself", "POST", "/accounts/acc-001/invitations", `{"email":"demo@accountstack.com","access":"joint"}`, owner, http.StatusBadRequest},
+				{"invalid email", "POST", "/accounts/acc-001/invitations", `{"email":"not an email","access":"joint"}`, owner, http.StatusBadRequest},
+				{"already invited", "POST", "/accounts/acc-001/invitations", `{"email":"sarah.chen@accountstack.com","access":"joint"}`, owner, http.StatusConflict},
+				{"inviting to someone else's account", "POST", "/accounts/acc-001/invitations", `{"email":"a@example.com","access":"viewer"}`, advisor, http.StatusNotFound},
+				{"accepting someone else's invitation", "POST", "/me/invitations/" + invitation.ID + "/accept", acceptBody(token), owner, http.StatusNotFound},
+				{"accepting without the token", "POST", "/me/invitations/" + invitation.ID + "/accept", "", advisor, http.StatusBadRequest},
+				{"accepting with a wrong token", "POST", "/me/invitations/" + invitation.ID + "/accept", acceptBody("guess"), advisor, http.StatusForbidden},
+			}
+			for _, tt := range tests {
+				if rec := bearer(router, tt.method, tt.path, tt.body, tt.token); rec.Code != tt.want {
+					t.Errorf("%s: expected status %d, got %d: %s", tt.name, tt.want, rec.Code, rec.Body.String())
+				}
+			}
+
+			rec := bearer(router, "GET", "/me/invitations", "", advisor)
+			if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), invitation.ID) {
+				t.Fatalf("Expected the invitee to see the invitation, got %d: %s", rec.Code, rec.Body.String())
+			}
+
+			rec = bearer(router, "POST", "/me/invitations/"+invitation.ID+"/accept", acceptBody(token), advisor)
+			if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"access":"viewer"`) {
+				t.Fatalf("Expected the shared account, got %d: %s", rec.Code, rec.Body.String())
+			}
+			if rec := bearer(router, "POST", "/me/invitations/"+invitation.ID+"/accept", acceptBody(token), advisor); rec.Code != http.StatusConflict {
+				t.Errorf("Expected an accepted invitation to be closed, got %d", rec.Code)
+			}
+
+			// The viewer sees the account next to their own but cannot manage it
+			rec = bearer(router, "GET", "/accounts", "", advisor)
+			var accounts []models.AccountResponse
+			if err := json.NewDecoder(rec.Body).Decode(&accounts); err != nil {
+				t.Fatalf("Failed to decode accounts: %v", err)
+			}
+			if len(accounts) != 2 || accounts[0].ID != "acc-004" || accounts[0].Access != models.AccessOwner ||
+				accounts[1].ID != "acc-001" || accounts[1].Access != models.AccessViewer {
+				t.Errorf("Expected acc-004 owned and acc-001 shared, got %+v", accounts)
+			}
+			if code := withToken(router, "GET", "/accounts/acc-001", advisor); code != http.StatusOK {
+				t.Errorf("Expected the viewer to read the account, got %d", code)
+			}
+			if code := withToken(router, "GET", "/accounts/acc-003", advisor); code != http.StatusNotFound {
+				t.Errorf("Expected the owner's other accounts to stay private, got %d", code)
+			}
+			if code := withToken(router, "GET", "/accounts/acc-001/access", advisor); code != http.StatusForbidden {
+				t.Errorf("Expected viewers not to list access, got %d", code)
+			}
+			if rec := bearer(router, "DELETE", "/accounts/acc-001/access/user-001", "", advisor); rec.Code != http.StatusBadRequest {
+				t.Errorf("Expected the owner not to be removable, got %d", rec.Code)
+			}
+
+			rec = bearer(router, "GET", "/accounts/acc-001/access", "", owner)
+			var access models.AccountAccess
+			if err := json.NewDecoder(rec.Body).Decode(&access); err != nil {
+				t.Fatalf("Failed to decode access: %v", err)
+			}
+			if len(access.Holders) != 2 || access.Holders[0].Access != models.AccessOwner ||
+				access.Holders[1].UserID != "user-002" || access.Holders[1].Access != models.AccessViewer || len(access.Invitations) != 0 {
+				t.Errorf("Unexpected access list: %+v", access)
+			}
+
+			// Revoking takes effect immediately
+			if rec := bearer(router, "DELETE", "/accounts/acc-001/access/user-002", "", owner); rec.Code != http.StatusNoContent {
+				t.Fatalf("Expected access to be removed, got %d: %s", rec.Code, rec.Body.String())
+			}
+			if code := withToken(router, "GET", "/accounts/acc-001", advisor); code != http.StatusNotFound {
+				t.Errorf("Expected revoked access to be refused, got %d", code)
+			}
+			if rec := bearer(router, "DELETE", "/accounts/acc-001/access/user-002", "", owner); rec.Code != http.StatusNotFound {
+				t.Errorf("Expected 404 removing access twice, got %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestAccountInvitationResponses(t *testing.T) {
+	router, outbox := newCredentialsRouter(t, repository.BackendSQLite)
+	owner := signIn(t, router, "demo@accountstack.com").Token
+	invitee := signIn(t, router, "sarah.chen@accountstack.com").Token
+
+	// A cancelled invitation can no longer be accepted
+	cancelled := invite(t, router, "acc-003", `{"email":"sarah.chen@accountstack.com","access":"joint"}`, owner)
+	if rec := bearer(router, "DELETE", "/accounts/acc-003/invitations/"+cancelled.ID, "", invitee); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected only the owner to cancel, got %d", rec.Code)
+	}
+	if rec := bearer(router, "DELETE", "/accounts/acc-003/invitations/"+cancelled.ID, "", owner); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected the invitation to be cancelled, got %d: %s", rec.Code, rec.Body.String())
+	}
+	token := invitationToken(t, outbox, "sarah.chen@accountstack.com")
+	if rec := bearer(router, "POST", "/me/invitations/"+cancelled.ID+"/accept", acceptBody(token), invitee); rec.Code != http.StatusConflict {
+		t.Errorf("Expected a cancelled invitation to be closed, got %d", rec.Code)
+	}
+
+	// Declining leaves the account private and allows a new invitation
+	declined := invite(t, router, "acc-003", `{"email":"sarah.chen@accountstack.com","access":"joint"}`, owner)
+	if rec := bearer(router, "POST", "/me/invitations/"+declined.ID+"/decline", "", invitee); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected the invitation to be declined, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if code := withToken(router, "GET", "/accounts/acc-003", invitee); code != http.StatusNotFound {
+		t.Errorf("Expected no access after declining, got %d", code)
+	}
+	rec := bearer(router, "GET", "/me/invitations", "", invitee)
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != "[]" {
+		t.Errorf("Expected no pending invitations, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// A joint holder can leave on their own
+	joint := invite(t, router, "acc-003", `{"email":"sarah.chen@accountstack.com","access":"joint"}`, owner)
+	token = invitationToken(t, outbox, "sarah.chen@accountstack.com")
+	if rec := bearer(router, "POST", "/me/invitations/"+joint.ID+"/accept", acceptBody(token), invitee); rec.Code != http.StatusOK {
+		t.Fatalf("Expected the invitation to be accepted, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if code := withToken(router, "GET", "/accounts/acc-003/access", invitee); code != http.StatusOK {
+		t.Errorf("Expected joint holders to list access, got %d", code)
+	}
+	if rec := bearer(router, "POST", "/accounts/acc-003/invitations", `{"email":"a@example.com","access":"viewer"}`, invitee); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected only the owner to invite, got %d", rec.Code)
+	}
+	if rec := bearer(router, "DELETE", "/accounts/acc-003/access/user-002", "", invitee); rec.Code != http.StatusNoContent {
+		t.Errorf("Expected a joint holder to leave, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if code := withToken(router, "GET", "/accounts/acc-003", invitee); code != http.StatusNotFound {
+		t.Errorf("Expected no access after leaving, got %d", code)
+	}
+}
+
+func TestInvitationToUnregisteredEmail(t *testing.T) {
+	for _, backend := range []string{repository.BackendJSON, repository.BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			router, outbox := newCredentialsRouter(t, backend)
+			owner := signIn(t, router, "demo@accountstack.com").Token
+			invitation := invite(t, router, "acc-001", `{"email":"new.holder@example.com","access":"joint"}`, owner)
+
+			// Anyone can register the invited address, but that does not prove they own it
+			rec := post(t, router, "/register",
+				`{"email":"new.holder@example.com","password":"correct horse","firstName":"New","lastName":"Holder","country":"gb"}`, "")
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+			}
+			var registered LoginResponse
+			if err := json.NewDecoder(rec.Body).Decode(&registered); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if rec := bearer(router, "POST", "/me/invitations/"+invitation.ID+"/accept", acceptBody("guess"), registered.Token); rec.Code != http.StatusForbidden {
+				t.Errorf("Expected the invitation to need its token, got %d: %s", rec.Code, rec.Body.String())
+			}
+			if code := withToken(router, "GET", "/accounts/acc-001", registered.Token); code != http.StatusNotFound {
+				t.Errorf("Expected no access without the token, got %d", code)
+			}
+
+			// Whoever reads the invitation email can accept it
+			token := invitationToken(t, outbox, "new.holder@example.com")
+			rec = bearer(router, "POST", "/me/invitations/"+invitation.ID+"/accept", acceptBody(token), registered.Token)
+			if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"access":"joint"`) {
+				t.Errorf("Expected the shared account, got %d: %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/pdf"
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/services"
+	"github.com/CB-AccountStack/AccountStack/pkg/middleware"
+	"github.com/CB-AccountStack/AccountStack/pkg/problem"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// StatementHandler handles account statement requests
+type StatementHandler struct {
+	statementService *services.StatementService
+	logger           *logrus.Logger
+}
+
+// NewStatementHandler creates a new statement handler
+func NewStatementHandler(statementService *services.StatementService, logger *logrus.Logger) *StatementHandler {
+	return &StatementHandler{
+		statementService: statementService,
+		logger:           logger,
+	}
+}
+
+// GetStatements handles GET /accounts/{id}/statements - lists the available
+// monthly statements for an account, newest first
+func (h *StatementHandler) GetStatements(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	accountID := mux.Vars(r)["id"]
+
+	statements, err := h.statementService.ListStatements(accountID, userID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to retrieve statements")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(statements)
+}
+
+// GetStatement handles GET /accounts/{id}/statements/{period} - returns the
+// statement for a YYYY-MM period as JSON, or as a PDF when the Accept header
+// prefers application/pdf
+func (h *StatementHandler) GetStatement(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	vars := mux.Vars(r)
+	accountID := vars["id"]
+	period := vars["period"]
+
+	w.Header().Set("Vary", "Accept")
+
+	statement, err := h.statementService.GetStatement(accountID, userID, period)
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to retrieve statement")
+		return
+	}
+
+	if !prefersPDF(r.Header.Get("Accept")) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(statement.ToResponse())
+		return
+	}
+
+	// Render fully before writing so a failure can still be reported as an error
+	var buf bytes.Buffer
+	if err := pdf.RenderStatement(&buf, statement); err != nil {
+		h.logger.WithError(err).WithFields(logrus.Fields{
+			"userId":    userID,
+			"accountId": accountID,
+			"period":    period,
+		}).Error("Failed to render statement PDF")
+		problem.Write(w, r, http.StatusInternalServerError, "Failed to render statement")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="statement-%s-%s.pdf"`, statement.AccountID, statement.Period))
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(http.StatusOK)
+	buf.WriteTo(w)
+}
+
+// prefersPDF reports whether an Accept header ranks application/pdf above
+// JSON, which stays the default. An explicit application/pdf wins a tie with a
+// wildcard but not with application/json.
+func prefersPDF(accept string) bool {
+	pdfQ, jsonQ, wildcardQ := 0.0, 0.0, 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		switch mediaType {
+		case "application/pdf":
+			pdfQ = max(pdfQ, q)
+		case "application/json":
+			jsonQ = max(jsonQ, q)
+		case "application/*", "*/*":
+			wildcardQ = max(wildcardQ, q)
+		}
+	}
+	return pdfQ > 0 && pdfQ > jsonQ && pdfQ >= wildcardQ
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/repository"
+)
+
+func TestGetStatement(t *testing.T) {
+	for _, backend := range []string{repository.BackendJSON, repository.BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			router := newTestRouter(t, backend)
+
+			rec := doRequest(t, router, "/accounts/acc-001/statements/2024-12", "user-001")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Expected JSON by default, got %q", ct)
+			}
+
+			var statement struct {
+				OpeningBalance float64 `json:"openingBalance"`
+				ClosingBalance float64 `json:"closingBalance"`
+				Postings       []struct {
+					TransactionID string `json:"transactionId"`
+				} `json:"postings"`
+				CategoryTotals []struct {
+					Category string `json:"category"`
+				} `json:"categoryTotals"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&statement); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+
+			// The pending txn-003 is not posted; November's txn-004 is before the period
+			if len(statement.Postings) != 2 || statement.Postings[0].TransactionID != "txn-002" || statement.Postings[1].TransactionID != "txn-001" {
+				t.Errorf("Expected postings txn-002 and txn-001, got %+v", statement.Postings)
+			}
+			if statement.ClosingBalance != 5847.32 || statement.OpeningBalance != 2652.79 {
+				t.Errorf("Expected opening 2652.79 and closing 5847.32, got %v and %v",
+					statement.OpeningBalance, statement.ClosingBalance)
+			}
+			if len(statement.CategoryTotals) != 2 || statement.CategoryTotals[0].Category != "food_dining" {
+				t.Errorf("Expected sorted totals for food_dining and income, got %+v", statement.CategoryTotals)
+			}
+		})
+	}
+}
+
+func TestGetStatementPDF(t *testing.T) {
+	router := newTestRouter(t, repository.BackendJSON)
+
+	tests := []struct {
+		accept  string
+		wantPDF bool
+	}{
+		{"application/pdf", true},
+		{"application/pdf, */*", true},
+		{"application/json;q=0.5, application/pdf", true},
+		{"application/pdf;q=0.5, application/json", false},
+		{"*/*", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/accounts/acc-001/statements/2024-12", nil)
+			req.Header.Set("Accept", tt.accept)
+			rec := serve(t, router, req, "user-001")
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", rec.Code)
+			}
+			isPDF := rec.Header().Get("Content-Type") == "application/pdf"
+			if isPDF != tt.wantPDF {
+				t.Fatalf("Expected PDF=%v, got Content-Type %q", tt.wantPDF, rec.Header().Get("Content-Type"))
+			}
+			if rec.Header().Get("Vary") != "Accept" {
+				t.Error("Expected Vary: Accept")
+			}
+			if !isPDF {
+				return
+			}
+
+			if !bytes.HasPrefix(rec.Body.Bytes(), []byte("%PDF-")) {
+				t.Errorf("Expected a PDF document, got %q", rec.Body.Bytes()[:min(rec.Body.Len(), 16)])
+			}
+			if got, want := rec.Header().Get("Content-Disposition"), `attachment; filename="statement-acc-001-2024-12.pdf"`; got != want {
+				t.Errorf("Expected Content-Disposition %q, got %q", want, got)
+			}
+		})
+	}
+}
+
+func TestStatementsIsolation(t *testing.T) {
+	tests := []struct {
+		name       string
+		userID     string
+		path       string
+		wantStatus int
+	}{
+		{"owner lists statements", "user-001", "/accounts/acc-001/statements", http.StatusOK},
+		{"other user's statements", "user-001", "/accounts/acc-004/statements", http.StatusNotFound},
+		{"other user's statement", "user-002", "/accounts/acc-001/statements/2024-12", http.StatusNotFound},
+		{"other user's statement as PDF", "user-001", "/accounts/acc-004/statements/2024-12", http.StatusNotFound},
+		{"missing account", "user-001", "/accounts/acc-999/statements", http.StatusNotFound},
+		{"anonymous", "", "/accounts/acc-001/statements", http.StatusUnauthorized},
+		{"invalid period", "user-001", "/accounts/acc-001/statements/2024-13", http.StatusBadRequest},
+		{"before the account opened", "user-001", "/accounts/acc-001/statements/2020-01", http.StatusNotFound},
+		{"future period", "user-001", "/accounts/acc-001/statements/2999-01", http.StatusNotFound},
+	}
+
+	for _, backend := range []string{repository.BackendJSON, repository.BackendSQLite} {
+		router := newTestRouter(t, backend)
+		for _, tt := range tests {
+			t.Run(backend+"/"+tt.name, func(t *testing.T) {
+				req := httptest.NewRequest("GET", tt.path, nil)
+				req.Header.Set("Accept", "application/pdf")
+				rec := serve(t, router, req, tt.userID)
+				if rec.Code != tt.wantStatus {
+					t.Errorf("Expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+				}
+				if rec.Code != http.StatusOK && bytes.Contains(rec.Body.Bytes(), []byte("Client Payment")) {
+					t.Errorf("Response leaked transactions: %s", rec.Body.String())
+				}
+			})
+		}
+	}
+}
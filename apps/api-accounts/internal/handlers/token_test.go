@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/repository"
+	"github.com/CB-AccountStack/AccountStack/pkg/auth"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
+)
+
+// signIn logs in with the seed password and returns the issued tokens
+func signIn(t *testing.T, router *mux.Router, email string) LoginResponse {
+	t.Helper()
+	body, _ := json.Marshal(LoginRequest{Username: email, Password: testSeedPassword})
+	rec := post(t, router, "/login", string(body), "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected login to succeed, got %d", rec.Code)
+	}
+	var resp LoginResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode login response: %v", err)
+	}
+	return resp
+}
+
+// refresh exchanges a refresh token and returns the recorded response
+func refresh(t *testing.T, router *mux.Router, refreshToken string) *httptest.ResponseRecorder {
+	t.Helper()
+	body, _ := json.Marshal(RefreshRequest{RefreshToken: refreshToken})
+	return post(t, router, "/token/refresh", string(body), "")
+}
+
+// withToken sends a request authenticated with an access token
+func withToken(router *mux.Router, method, path, accessToken string) int {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestLoginIssuesShortLivedTokens(t *testing.T) {
+	router, _ := newCredentialsRouter(t, repository.BackendJSON)
+
+	resp := signIn(t, router, "demo@accountstack.com")
+	if resp.Token == "" || resp.RefreshToken == "" {
+		t.Fatalf("Expected an access and a refresh token, got %+v", resp)
+	}
+	if resp.ExpiresIn != 900 {
+		t.Errorf("Expected the access token to last 900 seconds, got %d", resp.ExpiresIn)
+	}
+	if resp.RefreshExpiresIn != 3600 {
+		t.Errorf("Expected the refresh token to last 3600 seconds, got %d", resp.RefreshExpiresIn)
+	}
+	if strings.Count(resp.RefreshToken, ".") != 0 {
+		t.Errorf("Expected an opaque refresh token, got %q", resp.RefreshToken)
+	}
+}
+
+func TestTokenRefreshRotation(t *testing.T) {
+	for _, backend := range []string{repository.BackendJSON, repository.BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			router, _ := newCredentialsRouter(t, backend)
+			first := signIn(t, router, "demo@accountstack.com")
+
+			rec := refresh(t, router, first.RefreshToken)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+			var second LoginResponse
+			if err := json.NewDecoder(rec.Body).Decode(&second); err != nil {
+				t.Fatalf("Failed to decode refresh response: %v", err)
+			}
+			if second.RefreshToken == first.RefreshToken || second.Token == first.Token {
+				t.Fatal("Expected refresh to rotate both tokens")
+			}
+			if second.User.ID != "user-001" {
+				t.Errorf("Expected tokens for user-001, got %s", second.User.ID)
+			}
+			if code := withToken(router, "GET", "/me", second.Token); code != http.StatusOK {
+				t.Errorf("Expected the new access token to work, got %d", code)
+			}
+
+			// Presenting the exchanged token again revokes the whole family,
+			// including the tokens that were issued in exchange for it
+			if rec := refresh(t, router, first.RefreshToken); rec.Code != http.StatusUnauthorized {
+				t.Errorf("Expected a reused refresh token to be rejected, got %d", rec.Code)
+			}
+			if rec := refresh(t, router, second.RefreshToken); rec.Code != http.StatusUnauthorized {
+				t.Errorf("Expected the family's latest refresh token to be revoked, got %d", rec.Code)
+			}
+			if code := withToken(router, "GET", "/me", second.Token); code != http.StatusUnauthorized {
+				t.Errorf("Expected the family's access token to be revoked, got %d", code)
+			}
+		})
+	}
+}
+
+func TestTokenRefreshErrors(t *testing.T) {
+	router, _ := newCredentialsRouter(t, repository.BackendJSON)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"missing token", `{}`, http.StatusBadRequest},
+		{"malformed body", `{`, http.StatusBadRequest},
+		{"unknown token", `{"refreshToken":"made-up-token"}`, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := post(t, router, "/token/refresh", tt.body, ""); rec.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+		})
+	}
+}
+
+func TestLogout(t *testing.T) {
+	for _, backend := range []string{repository.BackendJSON, repository.BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			router, _ := newCredentialsRouter(t, backend)
+			session := signIn(t, router, "demo@accountstack.com")
+			other := signIn(t, router, "demo@accountstack.com")
+
+			if code := withToken(router, "POST", "/logout", session.Token); code != http.StatusNoContent {
+				t.Fatalf("Expected status 204, got %d", code)
+			}
+
+			if code := withToken(router, "GET", "/me", session.Token); code != http.StatusUnauthorized {
+				t.Errorf("Expected the logged out access token to be rejected, got %d", code)
+			}
+			if rec := refresh(t, router, session.RefreshToken); rec.Code != http.StatusUnauthorized {
+				t.Errorf("Expected the logged out refresh token to be rejected, got %d", rec.Code)
+			}
+
+			// Other sessions of the same user are unaffected
+			if code := withToken(router, "GET", "/me", other.Token); code != http.StatusOK {
+				t.Errorf("Expected another session to keep working, got %d", code)
+			}
+			if rec := refresh(t, router, other.RefreshToken); rec.Code != http.StatusOK {
+				t.Errorf("Expected another session to refresh, got %d", rec.Code)
+			}
+
+			if rec := post(t, router, "/logout", "", ""); rec.Code != http.StatusUnauthorized {
+				t.Errorf("Expected status 401 without a token, got %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestNewPasswordEndsSessions(t *testing.T) {
+	for _, backend := range []string{repository.BackendJSON, repository.BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			router, outbox := newCredentialsRouter(t, backend)
+
+			// Changing the password signs out every session, the current one too
+			session := signIn(t, router, "demo@accountstack.com")
+			other := signIn(t, router, "demo@accountstack.com")
+			rec := bearer(router, "POST", "/me/password", `{"currentPassword":"`+testSeedPassword+`","newPassword":"a new password"}`, session.Token)
+			if rec.Code != http.StatusNoContent {
+				t.Fatalf("Expected status 204, got %d: %s", rec.Code, rec.Body.String())
+			}
+			for _, s := range []LoginResponse{session, other} {
+				if rec := refresh(t, router, s.RefreshToken); rec.Code != http.StatusUnauthorized {
+					t.Errorf("Expected the refresh token to be revoked after a password change, got %d", rec.Code)
+				}
+				if code := withToken(router, "GET", "/me", s.Token); code != http.StatusUnauthorized {
+					t.Errorf("Expected the access token to be revoked after a password change, got %d", code)
+				}
+			}
+
+			// So does resetting it, and other users keep their sessions
+			reset := signIn(t, router, "sarah.chen@accountstack.com")
+			bystander := signIn(t, router, "support@accountstack.com")
+			if rec := post(t, router, "/password/forgot", `{"email":"sarah.chen@accountstack.com"}`, ""); rec.Code != http.StatusAccepted {
+				t.Fatalf("Expected status 202, got %d", rec.Code)
+			}
+			body, _ := json.Marshal(ResetPasswordRequest{Token: readResetTokens(t, outbox, "sarah.chen@accountstack.com")[0], NewPassword: "reset password"})
+			if rec := post(t, router, "/password/reset", string(body), ""); rec.Code != http.StatusNoContent {
+				t.Fatalf("Expected status 204, got %d", rec.Code)
+			}
+			if rec := refresh(t, router, reset.RefreshToken); rec.Code != http.StatusUnauthorized {
+				t.Errorf("Expected the refresh token to be revoked after a reset, got %d", rec.Code)
+			}
+			if code := withToken(router, "GET", "/me", reset.Token); code != http.StatusUnauthorized {
+				t.Errorf("Expected the access token to be revoked after a reset, got %d", code)
+			}
+			if rec := refresh(t, router, bystander.RefreshToken); rec.Code != http.StatusOK {
+				t.Errorf("Expected another user's session to refresh, got %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	router, _ := newCredentialsRouter(t, repository.BackendJSON)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/.well-known/jwks.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected the JWKS to be public, got %d", rec.Code)
+	}
+	if rec.Header().Get("Cache-Control") == "" {
+		t.Error("Expected the JWKS to be cacheable")
+	}
+	var set auth.JWKS
+	if err := json.NewDecoder(rec.Body).Decode(&set); err != nil {
+		t.Fatalf("Failed to decode JWKS: %v", err)
+	}
+	if len(set.Keys) != 1 || set.Keys[0].Kid != testSigningKey.ID || set.Keys[0].Alg != "EdDSA" {
+		t.Fatalf("Expected the test key, got %+v", set.Keys)
+	}
+	if strings.Contains(rec.Body.String(), `"d"`) {
+		t.Error("JWKS leaked private key material")
+	}
+
+	// Issued tokens name the published key
+	resp := signIn(t, router, "demo@accountstack.com")
+	token, _, err := jwt.NewParser().ParseUnverified(resp.Token, &auth.Claims{})
+	if err != nil {
+		t.Fatalf("Failed to parse token: %v", err)
+	}
+	if token.Header["kid"] != set.Keys[0].Kid || token.Method.Alg() != "EdDSA" {
+		t.Errorf("Unexpected token header: %v", token.Header)
+	}
+}
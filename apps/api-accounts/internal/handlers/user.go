@@ -4,8 +4,9 @@ import (
 	"encoding/json"
 	"net/http"
 
-	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/middleware"
 	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/services"
+	"github.com/CB-AccountStack/AccountStack/pkg/middleware"
+	"github.com/CB-AccountStack/AccountStack/pkg/problem"
 	"github.com/sirupsen/logrus"
 )
 
@@ -23,12 +24,6 @@ func NewUserHandler(userService *services.UserService, logger *logrus.Logger) *U
 	}
 }
 
-// ErrorResponse represents an error response
-type ErrorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message,omitempty"`
-}
-
 // GetMe handles GET /me - returns current user info
 func (h *UserHandler) GetMe(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r)
@@ -36,12 +31,7 @@ func (h *UserHandler) GetMe(w http.ResponseWriter, r *http.Request) {
 	user, err := h.userService.GetUserByID(userID)
 	if err != nil {
 		h.logger.WithError(err).WithField("userId", userID).Error("Failed to get user")
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(ErrorResponse{
-			Error:   "not_found",
-			Message: "User not found",
-		})
+		problem.Write(w, r, http.StatusNotFound, "User not found")
 		return
 	}
 
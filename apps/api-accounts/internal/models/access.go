@@ -0,0 +1,76 @@
+package models
+
+import "time"
+
+// Levels of access a user can have to an account. The owner is the account's
+// UserID; joint holders and viewers are granted access by the owner.
+const (
+	AccessOwner  = "owner"
+	AccessJoint  = "joint"  // Reads the account and moves money like the owner
+	AccessViewer = "viewer" // Read-only, such as a financial advisor
+)
+
+// Invitation states
+const (
+	InvitationPending   = "pending"
+	InvitationAccepted  = "accepted"
+	InvitationDeclined  = "declined"
+	InvitationCancelled = "cancelled"
+)
+
+// ValidGrantAccess reports whether access can be granted to another user
+func ValidGrantAccess(access string) bool {
+	return access == AccessJoint || access == AccessViewer
+}
+
+// CanWrite reports whether access allows changing the account, such as
+// posting transactions or managing who else has access
+func CanWrite(access string) bool {
+	return access == AccessOwner || access == AccessJoint
+}
+
+// AccountGrant gives a user other than the owner access to an account
+type AccountGrant struct {
+	AccountID string    `json:"accountId"`
+	UserID    string    `json:"userId"`
+	Access    string    `json:"access"`
+	GrantedBy string    `json:"grantedBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// AccountInvitation offers access to an account to whoever holds Email. It
+// becomes an AccountGrant when the invitee accepts it with the token sent to
+// that address, which is stored only as TokenHash.
+type AccountInvitation struct {
+	ID          string     `json:"id"`
+	TokenHash   string     `json:"-"`
+	AccountID   string     `json:"accountId"`
+	Email       string     `json:"email"`
+	Access      string     `json:"access"`
+	InvitedBy   string     `json:"invitedBy"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	ExpiresAt   time.Time  `json:"expiresAt"`
+	RespondedAt *time.Time `json:"respondedAt,omitempty"`
+}
+
+// Pending reports whether the invitation can still be accepted at now
+func (i *AccountInvitation) Pending(now time.Time) bool {
+	return i.Status == InvitationPending && now.Before(i.ExpiresAt)
+}
+
+// AccessHolder is someone with access to an account, as listed to its holders
+type AccessHolder struct {
+	UserID    string     `json:"userId"`
+	Name      string     `json:"name"`
+	Email     string     `json:"email"`
+	Access    string     `json:"access"`
+	GrantedAt *time.Time `json:"grantedAt,omitempty"` // Unset for the owner
+}
+
+// AccountAccess lists who can see an account and who has been invited to
+type AccountAccess struct {
+	AccountID   string               `json:"accountId"`
+	Holders     []*AccessHolder      `json:"holders"`     // The owner first
+	Invitations []*AccountInvitation `json:"invitations"` // Pending only
+}
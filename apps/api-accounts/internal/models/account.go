@@ -2,6 +2,9 @@ package models
 
 import "time"
 
+// MaskedAmount replaces amounts when the maskAmounts flag is enabled
+const MaskedAmount = "***.**"
+
 // Account represents a bank account in the system
 type Account struct {
 	ID            string    `json:"id"`
@@ -30,6 +33,7 @@ type AccountResponse struct {
 	Status        string    `json:"status"`
 	OpenedDate    time.Time `json:"openedDate"`
 	LastActivity  time.Time `json:"lastActivity"`
+	Access        string    `json:"access,omitempty"` // The caller's access: owner, joint or viewer
 }
 
 // ToResponse converts an Account to AccountResponse with optional masking and currency override
@@ -47,9 +51,9 @@ func (a *Account) ToResponse(maskAmounts bool, currency string) AccountResponse
 	}
 
 	if maskAmounts {
-		resp.Balance = "***.**"
+		resp.Balance = MaskedAmount
 		if a.CreditLimit != nil {
-			resp.CreditLimit = "***.**"
+			resp.CreditLimit = MaskedAmount
 		}
 	} else {
 		resp.Balance = a.Balance
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Audited actions
+const (
+	AuditImpersonate = "impersonate"
+	AuditRoleChange  = "role_change"
+)
+
+// AuditEntry records a privileged action taken by a support or admin user
+type AuditEntry struct {
+	ID           string    `json:"id"`
+	ActorID      string    `json:"actorId"`
+	Action       string    `json:"action"`
+	TargetUserID string    `json:"targetUserId"`
+	Detail       string    `json:"detail,omitempty"`  // The stated reason or the change made
+	TokenID      string    `json:"tokenId,omitempty"` // jti of an impersonation token
+	CreatedAt    time.Time `json:"createdAt"`
+}
@@ -0,0 +1,126 @@
+package models
+
+import "time"
+
+// Balance history intervals
+const (
+	IntervalDay   = "day"
+	IntervalWeek  = "week"
+	IntervalMonth = "month"
+)
+
+// DateLayout is the format of balance history dates
+const DateLayout = "2006-01-02"
+
+// BalancePoint is an end-of-day balance
+type BalancePoint struct {
+	Date    time.Time `json:"date"`
+	Balance float64   `json:"balance"`
+}
+
+// BalanceHistoryResponse represents a balance series in API responses with optional masking
+type BalanceHistoryResponse struct {
+	AccountID string                 `json:"accountId"`
+	Currency  string                 `json:"currency"`
+	Interval  string                 `json:"interval"`
+	From      string                 `json:"from"`
+	To        string                 `json:"to"`
+	Points    []BalancePointResponse `json:"points"`
+}
+
+// BalancePointResponse represents a balance point in API responses with optional masking
+type BalancePointResponse struct {
+	Date    string `json:"date"`
+	Balance any    `json:"balance"` // Can be float64 or string (masked)
+}
+
+// ValidInterval reports whether interval is a supported balance history interval
+func ValidInterval(interval string) bool {
+	return interval == IntervalDay || interval == IntervalWeek || interval == IntervalMonth
+}
+
+// StartOfDay returns midnight UTC on the day containing t
+func StartOfDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// FirstBalanceDate returns the first day with a meaningful balance: the day
+// the account was opened, or of its first posting if that is earlier
+func FirstBalanceDate(account *Account, transactions []*Transaction) time.Time {
+	first := account.OpenedDate
+	for _, txn := range transactions {
+		if txn.Status == StatusCompleted && (first.IsZero() || txn.Date.Before(first)) {
+			first = txn.Date
+		}
+	}
+	return StartOfDay(first)
+}
+
+// BuildBalanceHistory reconstructs end-of-day balances for the days from
+// from to to inclusive, both midnight UTC, by walking the completed
+// transactions back from the account's current balance. For week (Monday to
+// Sunday) and month intervals there is one point per interval, holding the
+// balance on its last day, or on to for the final partial interval.
+func BuildBalanceHistory(account *Account, transactions []*Transaction, from, to time.Time, interval string) []BalancePoint {
+	points := []BalancePoint{}
+	if from.After(to) {
+		return points
+	}
+
+	// Net movement per day in the range, and the balance at the end of to
+	end := to.AddDate(0, 0, 1)
+	daily := make(map[time.Time]float64)
+	balance := account.Balance
+	for _, txn := range completedPostings(transactions) {
+		if !txn.Date.Before(end) {
+			balance -= txn.Amount
+		} else if !txn.Date.Before(from) {
+			daily[StartOfDay(txn.Date)] += txn.Amount
+		}
+	}
+
+	// Walk back one day at a time, then reverse into date order
+	for day := to; !day.Before(from); day = day.AddDate(0, 0, -1) {
+		if day.Equal(to) || isLastDayOf(day, interval) {
+			points = append(points, BalancePoint{Date: day, Balance: roundCents(balance)})
+		}
+		balance -= daily[day]
+	}
+	for i, j := 0, len(points)-1; i < j; i, j = i+1, j-1 {
+		points[i], points[j] = points[j], points[i]
+	}
+
+	return points
+}
+
+// isLastDayOf reports whether day closes an interval
+func isLastDayOf(day time.Time, interval string) bool {
+	switch interval {
+	case IntervalWeek:
+		return day.Weekday() == time.Sunday
+	case IntervalMonth:
+		return day.AddDate(0, 0, 1).Day() == 1
+	default:
+		return true
+	}
+}
+
+// ToBalanceHistoryResponse converts a balance series to BalanceHistoryResponse with optional masking
+func ToBalanceHistoryResponse(accountID, currency, interval string, from, to time.Time, points []BalancePoint, maskAmounts bool) BalanceHistoryResponse {
+	resp := BalanceHistoryResponse{
+		AccountID: accountID,
+		Currency:  currency,
+		Interval:  interval,
+		From:      from.Format(DateLayout),
+		To:        to.Format(DateLayout),
+		Points:    make([]BalancePointResponse, len(points)),
+	}
+	for i, p := range points {
+		resp.Points[i] = BalancePointResponse{
+			Date:    p.Date.Format(DateLayout),
+			Balance: maskAmount(p.Balance, maskAmounts),
+		}
+	}
+	return resp
+}
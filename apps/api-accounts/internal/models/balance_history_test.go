@@ -0,0 +1,84 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildBalanceHistory(t *testing.T) {
+	account, transactions := statementFixture()
+	day := func(s string) time.Time {
+		d, _ := time.Parse(DateLayout, s)
+		return d
+	}
+
+	tests := []struct {
+		name     string
+		from, to string
+		interval string
+		want     []BalancePoint
+	}{
+		{
+			name: "daily around a posting",
+			from: "2024-11-29", to: "2024-12-01", interval: IntervalDay,
+			want: []BalancePoint{
+				{day("2024-11-29"), 540},
+				{day("2024-11-30"), 640},
+				{day("2024-12-01"), 1140},
+			},
+		},
+		{
+			// 2024-12-16 and 2024-12-22 are Mondays, the range ends mid-week
+			name: "weekly ends on Sundays and on to",
+			from: "2024-12-16", to: "2024-12-25", interval: IntervalWeek,
+			want: []BalancePoint{
+				{day("2024-12-22"), 1040},
+				{day("2024-12-25"), 1040},
+			},
+		},
+		{
+			name: "monthly ends on the last day of each month",
+			from: "2024-10-15", to: "2025-01-05", interval: IntervalMonth,
+			want: []BalancePoint{
+				{day("2024-10-31"), 540},
+				{day("2024-11-30"), 640},
+				{day("2024-12-31"), 1040},
+				{day("2025-01-05"), 1000},
+			},
+		},
+		{
+			name: "empty when from is after to",
+			from: "2024-12-02", to: "2024-12-01", interval: IntervalDay,
+			want: []BalancePoint{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := BuildBalanceHistory(account, transactions, day(tt.from), day(tt.to), tt.interval)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %d points, got %+v", len(tt.want), got)
+			}
+			for i := range got {
+				if !got[i].Date.Equal(tt.want[i].Date) || got[i].Balance != tt.want[i].Balance {
+					t.Errorf("Point %d: expected %+v, got %+v", i, tt.want[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBalanceHistoryMatchesStatements(t *testing.T) {
+	account, transactions := statementFixture()
+	now := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+
+	points := BuildBalanceHistory(account, transactions,
+		time.Date(2024, 10, 15, 0, 0, 0, 0, time.UTC), time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC), IntervalMonth)
+	for _, p := range points {
+		s := BuildStatement(account, transactions, periodStart(p.Date), now)
+		if s.ClosingBalance != p.Balance {
+			t.Errorf("%s: balance history %v differs from statement closing balance %v",
+				p.Date.Format(DateLayout), p.Balance, s.ClosingBalance)
+		}
+	}
+}
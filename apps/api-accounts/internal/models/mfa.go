@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// TOTPEnrollment is a user's time-based one-time password authenticator. It
+// only protects sign-in once confirmed with a first code.
+type TOTPEnrollment struct {
+	UserID      string     `json:"userId"`
+	Secret      string     `json:"-"` // Base32 shared secret held by the authenticator app
+	CreatedAt   time.Time  `json:"createdAt"`
+	ConfirmedAt *time.Time `json:"confirmedAt,omitempty"`
+	// LastUsedStep is the time step of the last accepted code; codes from it
+	// or earlier steps are refused so they cannot be replayed
+	LastUsedStep int64 `json:"-"`
+}
+
+// Enabled reports whether the enrollment has been confirmed
+func (e *TOTPEnrollment) Enabled() bool {
+	return e.ConfirmedAt != nil
+}
+
+// RecoveryCode is a single-use code that stands in for a TOTP code when the
+// authenticator is lost. Only a SHA-256 hash of the code is stored.
+type RecoveryCode struct {
+	CodeHash  string     `json:"-"`
+	UserID    string     `json:"userId"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UsedAt    *time.Time `json:"usedAt,omitempty"`
+}
+
+// MFAChallenge is issued when a password check succeeds for a user with MFA
+// enabled. Its token is exchanged, together with a second factor, for access
+// and refresh tokens. Only a SHA-256 hash of the token is stored.
+type MFAChallenge struct {
+	TokenHash string     `json:"-"`
+	UserID    string     `json:"userId"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	Attempts  int        `json:"attempts"` // Failed second-factor attempts
+	UsedAt    *time.Time `json:"usedAt,omitempty"`
+}
+
+// Usable reports whether the challenge can still be completed at now
+func (c *MFAChallenge) Usable(now time.Time) bool {
+	return c.UsedAt == nil && now.Before(c.ExpiresAt)
+}
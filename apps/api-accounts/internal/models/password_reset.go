@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// PasswordResetToken is an issued password reset token. Only a SHA-256 hash
+// of the token is stored; the token itself is sent to the user and never kept.
+type PasswordResetToken struct {
+	TokenHash string     `json:"-"`
+	UserID    string     `json:"userId"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	UsedAt    *time.Time `json:"usedAt,omitempty"`
+}
+
+// Usable reports whether the token can still be redeemed at now
+func (t *PasswordResetToken) Usable(now time.Time) bool {
+	return t.UsedAt == nil && now.Before(t.ExpiresAt)
+}
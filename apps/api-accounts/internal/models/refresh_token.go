@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// RefreshToken is an issued refresh token. Only a SHA-256 hash of the token is
+// stored. Each refresh exchanges the token for a new one in the same family;
+// a token presented again after it was exchanged has been copied, and the
+// whole family is revoked.
+type RefreshToken struct {
+	TokenHash       string     `json:"-"`
+	FamilyID        string     `json:"familyId"`
+	UserID          string     `json:"userId"`
+	AccessTokenID   string     `json:"accessTokenId"` // jti of the access token issued with it
+	AccessExpiresAt time.Time  `json:"accessExpiresAt"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	ExpiresAt       time.Time  `json:"expiresAt"`
+	UsedAt          *time.Time `json:"usedAt,omitempty"`    // When it was exchanged for a new token
+	RevokedAt       *time.Time `json:"revokedAt,omitempty"` // When its family was revoked
+}
+
+// Usable reports whether the token can still be exchanged at now
+func (t *RefreshToken) Usable(now time.Time) bool {
+	return t.UsedAt == nil && t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
@@ -0,0 +1,345 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// PeriodLayout is the format of a statement period, one calendar month in UTC
+const PeriodLayout = "2006-01"
+
+// Statement is an account statement for one period. Balances are derived by
+// walking back from the account's current balance, so the closing balance of
+// one period is always the opening balance of the next.
+type Statement struct {
+	AccountID      string          `json:"accountId"`
+	AccountName    string          `json:"accountName"`
+	AccountNumber  string          `json:"accountNumber"`
+	AccountType    string          `json:"accountType"`
+	Currency       string          `json:"currency"`
+	Period         string          `json:"period"`
+	PeriodStart    time.Time       `json:"periodStart"`
+	PeriodEnd      time.Time       `json:"periodEnd"` // Exclusive
+	Complete       bool            `json:"complete"`  // False while the period is still in progress
+	OpeningBalance float64         `json:"openingBalance"`
+	ClosingBalance float64         `json:"closingBalance"`
+	TotalCredits   float64         `json:"totalCredits"`
+	TotalDebits    float64         `json:"totalDebits"`
+	Postings       []Posting       `json:"postings"`
+	CategoryTotals []CategoryTotal `json:"categoryTotals"`
+	GeneratedAt    time.Time       `json:"generatedAt"`
+	MaskAmounts    bool            `json:"-"` // Render every amount as MaskedAmount
+}
+
+// Posting is a completed transaction on a statement with the balance after it
+type Posting struct {
+	TransactionID string    `json:"transactionId"`
+	Date          time.Time `json:"date"`
+	Description   string    `json:"description"`
+	Merchant      string    `json:"merchant"`
+	Category      string    `json:"category"`
+	Amount        float64   `json:"amount"`
+	Balance       float64   `json:"balance"`
+}
+
+// CategoryTotal sums the postings of one category
+type CategoryTotal struct {
+	Category string  `json:"category"`
+	Count    int     `json:"count"`
+	Total    float64 `json:"total"`
+}
+
+// StatementSummary lists an available statement without its postings
+type StatementSummary struct {
+	Period         string    `json:"period"`
+	PeriodStart    time.Time `json:"periodStart"`
+	PeriodEnd      time.Time `json:"periodEnd"`
+	Complete       bool      `json:"complete"`
+	OpeningBalance float64   `json:"openingBalance"`
+	ClosingBalance float64   `json:"closingBalance"`
+	PostingCount   int       `json:"postingCount"`
+}
+
+// ParsePeriod parses a YYYY-MM period and returns its first instant in UTC
+func ParsePeriod(period string) (time.Time, error) {
+	start, err := time.Parse(PeriodLayout, period)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("period must be in YYYY-MM format")
+	}
+	return start, nil
+}
+
+// periodStart returns the first instant of the month containing t, in UTC
+func periodStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// FirstPeriod returns the start of the earliest period with a statement: the
+// month the account was opened, or of its first posting if that is earlier
+func FirstPeriod(account *Account, transactions []*Transaction) time.Time {
+	return periodStart(FirstBalanceDate(account, transactions))
+}
+
+// BuildStatement computes the statement for the period starting at start.
+// transactions are all of the account's transactions, in any order.
+func BuildStatement(account *Account, transactions []*Transaction, start, now time.Time) *Statement {
+	end := start.AddDate(0, 1, 0)
+	postings := completedPostings(transactions)
+
+	// Work back from the current balance to the balance at the end of the period
+	closing := account.Balance
+	for _, txn := range postings {
+		if !txn.Date.Before(end) {
+			closing -= txn.Amount
+		}
+	}
+
+	statement := &Statement{
+		AccountID:      account.ID,
+		AccountName:    account.AccountName,
+		AccountNumber:  account.AccountNumber,
+		AccountType:    account.AccountType,
+		Currency:       account.Currency,
+		Period:         start.Format(PeriodLayout),
+		PeriodStart:    start,
+		PeriodEnd:      end,
+		Complete:       !now.Before(end),
+		ClosingBalance: roundCents(closing),
+		Postings:       []Posting{},
+		CategoryTotals: []CategoryTotal{},
+		GeneratedAt:    now,
+	}
+
+	var inPeriod []*Transaction
+	sum := 0.0
+	for _, txn := range postings {
+		if !txn.Date.Before(start) && txn.Date.Before(end) {
+			inPeriod = append(inPeriod, txn)
+			sum += txn.Amount
+		}
+	}
+	statement.OpeningBalance = roundCents(closing - sum)
+
+	byCategory := make(map[string]*CategoryTotal)
+	balance := statement.OpeningBalance
+	for _, txn := range inPeriod {
+		balance += txn.Amount
+		statement.Postings = append(statement.Postings, Posting{
+			TransactionID: txn.ID,
+			Date:          txn.Date,
+			Description:   txn.Description,
+			Merchant:      txn.Merchant,
+			Category:      txn.Category,
+			Amount:        txn.Amount,
+			Balance:       roundCents(balance),
+		})
+
+		if txn.Amount > 0 {
+			statement.TotalCredits += txn.Amount
+		} else {
+			statement.TotalDebits += txn.Amount
+		}
+
+		total, exists := byCategory[txn.Category]
+		if !exists {
+			total = &CategoryTotal{Category: txn.Category}
+			byCategory[txn.Category] = total
+		}
+		total.Count++
+		total.Total += txn.Amount
+	}
+	statement.TotalCredits = roundCents(statement.TotalCredits)
+	statement.TotalDebits = roundCents(statement.TotalDebits)
+
+	for _, total := range byCategory {
+		total.Total = roundCents(total.Total)
+		statement.CategoryTotals = append(statement.CategoryTotals, *total)
+	}
+	sort.Slice(statement.CategoryTotals, func(i, j int) bool {
+		return statement.CategoryTotals[i].Category < statement.CategoryTotals[j].Category
+	})
+
+	return statement
+}
+
+// BuildStatementSummaries lists every period from the first period up to the
+// one containing now, newest first
+func BuildStatementSummaries(account *Account, transactions []*Transaction, now time.Time) []StatementSummary {
+	postings := completedPostings(transactions)
+	current := periodStart(now)
+	first := FirstPeriod(account, transactions)
+	if first.After(current) {
+		first = current
+	}
+
+	// Net movement and posting count per period, plus anything dated after the current period
+	sums := make(map[time.Time]float64)
+	counts := make(map[time.Time]int)
+	later := 0.0
+	for _, txn := range postings {
+		period := periodStart(txn.Date)
+		if period.After(current) {
+			later += txn.Amount
+			continue
+		}
+		sums[period] += txn.Amount
+		counts[period]++
+	}
+
+	summaries := []StatementSummary{}
+	closing := account.Balance - later
+	for start := current; !start.Before(first); start = start.AddDate(0, -1, 0) {
+		end := start.AddDate(0, 1, 0)
+		opening := closing - sums[start]
+		summaries = append(summaries, StatementSummary{
+			Period:         start.Format(PeriodLayout),
+			PeriodStart:    start,
+			PeriodEnd:      end,
+			Complete:       !now.Before(end),
+			OpeningBalance: roundCents(opening),
+			ClosingBalance: roundCents(closing),
+			PostingCount:   counts[start],
+		})
+		closing = opening
+	}
+
+	return summaries
+}
+
+// completedPostings returns the completed transactions in posting order
+func completedPostings(transactions []*Transaction) []*Transaction {
+	var postings []*Transaction
+	for _, txn := range transactions {
+		if txn.Status == StatusCompleted {
+			postings = append(postings, txn)
+		}
+	}
+	sort.Slice(postings, func(i, j int) bool {
+		if !postings[i].Date.Equal(postings[j].Date) {
+			return postings[i].Date.Before(postings[j].Date)
+		}
+		return postings[i].ID < postings[j].ID
+	})
+	return postings
+}
+
+// roundCents rounds an amount to the nearest cent
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// StatementResponse represents a statement in API responses with optional masking
+type StatementResponse struct {
+	AccountID      string                  `json:"accountId"`
+	AccountName    string                  `json:"accountName"`
+	AccountNumber  string                  `json:"accountNumber"`
+	AccountType    string                  `json:"accountType"`
+	Currency       string                  `json:"currency"`
+	Period         string                  `json:"period"`
+	PeriodStart    time.Time               `json:"periodStart"`
+	PeriodEnd      time.Time               `json:"periodEnd"`
+	Complete       bool                    `json:"complete"`
+	OpeningBalance any                     `json:"openingBalance"` // Can be float64 or string (masked)
+	ClosingBalance any                     `json:"closingBalance"`
+	TotalCredits   any                     `json:"totalCredits"`
+	TotalDebits    any                     `json:"totalDebits"`
+	Postings       []PostingResponse       `json:"postings"`
+	CategoryTotals []CategoryTotalResponse `json:"categoryTotals"`
+	GeneratedAt    time.Time               `json:"generatedAt"`
+}
+
+// PostingResponse represents a posting in API responses with optional masking
+type PostingResponse struct {
+	TransactionID string    `json:"transactionId"`
+	Date          time.Time `json:"date"`
+	Description   string    `json:"description"`
+	Merchant      string    `json:"merchant"`
+	Category      string    `json:"category"`
+	Amount        any       `json:"amount"`
+	Balance       any       `json:"balance"`
+}
+
+// CategoryTotalResponse represents a category total in API responses with optional masking
+type CategoryTotalResponse struct {
+	Category string `json:"category"`
+	Count    int    `json:"count"`
+	Total    any    `json:"total"`
+}
+
+// StatementSummaryResponse represents a statement summary in API responses with optional masking
+type StatementSummaryResponse struct {
+	Period         string    `json:"period"`
+	PeriodStart    time.Time `json:"periodStart"`
+	PeriodEnd      time.Time `json:"periodEnd"`
+	Complete       bool      `json:"complete"`
+	OpeningBalance any       `json:"openingBalance"`
+	ClosingBalance any       `json:"closingBalance"`
+	PostingCount   int       `json:"postingCount"`
+}
+
+// ToResponse converts a Statement to StatementResponse, masking amounts if MaskAmounts is set
+func (s *Statement) ToResponse() StatementResponse {
+	resp := StatementResponse{
+		AccountID:      s.AccountID,
+		AccountName:    s.AccountName,
+		AccountNumber:  s.AccountNumber,
+		AccountType:    s.AccountType,
+		Currency:       s.Currency,
+		Period:         s.Period,
+		PeriodStart:    s.PeriodStart,
+		PeriodEnd:      s.PeriodEnd,
+		Complete:       s.Complete,
+		OpeningBalance: maskAmount(s.OpeningBalance, s.MaskAmounts),
+		ClosingBalance: maskAmount(s.ClosingBalance, s.MaskAmounts),
+		TotalCredits:   maskAmount(s.TotalCredits, s.MaskAmounts),
+		TotalDebits:    maskAmount(s.TotalDebits, s.MaskAmounts),
+		Postings:       make([]PostingResponse, len(s.Postings)),
+		CategoryTotals: make([]CategoryTotalResponse, len(s.CategoryTotals)),
+		GeneratedAt:    s.GeneratedAt,
+	}
+
+	for i, p := range s.Postings {
+		resp.Postings[i] = PostingResponse{
+			TransactionID: p.TransactionID,
+			Date:          p.Date,
+			Description:   p.Description,
+			Merchant:      p.Merchant,
+			Category:      p.Category,
+			Amount:        maskAmount(p.Amount, s.MaskAmounts),
+			Balance:       maskAmount(p.Balance, s.MaskAmounts),
+		}
+	}
+	for i, c := range s.CategoryTotals {
+		resp.CategoryTotals[i] = CategoryTotalResponse{
+			Category: c.Category,
+			Count:    c.Count,
+			Total:    maskAmount(c.Total, s.MaskAmounts),
+		}
+	}
+
+	return resp
+}
+
+// ToResponse converts a StatementSummary to StatementSummaryResponse with optional masking
+func (s *StatementSummary) ToResponse(maskAmounts bool) StatementSummaryResponse {
+	return StatementSummaryResponse{
+		Period:         s.Period,
+		PeriodStart:    s.PeriodStart,
+		PeriodEnd:      s.PeriodEnd,
+		Complete:       s.Complete,
+		OpeningBalance: maskAmount(s.OpeningBalance, maskAmounts),
+		ClosingBalance: maskAmount(s.ClosingBalance, maskAmounts),
+		PostingCount:   s.PostingCount,
+	}
+}
+
+// maskAmount returns the amount, or MaskedAmount when masking is enabled
+func maskAmount(amount float64, mask bool) any {
+	if mask {
+		return MaskedAmount
+	}
+	return amount
+}
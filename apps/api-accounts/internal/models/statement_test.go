@@ -0,0 +1,166 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+// statementFixture is an account opened in October 2024 with a current
+// balance of 1000 and postings in November, December and January
+func statementFixture() (*Account, []*Transaction) {
+	account := &Account{
+		ID:          "acc-001",
+		AccountName: "Personal Checking",
+		Balance:     1000,
+		OpenedDate:  time.Date(2024, 10, 15, 0, 0, 0, 0, time.UTC),
+	}
+	txn := func(id, date string, amount float64, category, status string) *Transaction {
+		d, _ := time.Parse(time.RFC3339, date)
+		return &Transaction{ID: id, AccountID: "acc-001", Date: d, Amount: amount, Category: category, Status: status}
+	}
+	transactions := []*Transaction{
+		txn("txn-005", "2025-01-03T10:00:00Z", -40, "groceries", StatusCompleted),
+		txn("txn-002", "2024-12-20T10:00:00Z", -75.25, "groceries", StatusCompleted),
+		txn("txn-001", "2024-12-01T00:00:00Z", 500, "income", StatusCompleted),
+		txn("txn-003", "2024-12-20T10:00:00Z", -24.75, "dining", StatusCompleted),
+		txn("txn-004", "2024-12-31T23:59:59Z", -300, "rent", "pending"),
+		txn("txn-000", "2024-11-30T23:59:59Z", 100, "income", StatusCompleted),
+	}
+	return account, transactions
+}
+
+func TestBuildStatement(t *testing.T) {
+	account, transactions := statementFixture()
+	now := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+
+	s := BuildStatement(account, transactions, time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC), now)
+
+	// Closing is the current balance less January's -40; opening removes December's +400
+	if s.ClosingBalance != 1040 || s.OpeningBalance != 640 {
+		t.Errorf("Expected opening 640 and closing 1040, got %v and %v", s.OpeningBalance, s.ClosingBalance)
+	}
+	if s.TotalCredits != 500 || s.TotalDebits != -100 {
+		t.Errorf("Expected credits 500 and debits -100, got %v and %v", s.TotalCredits, s.TotalDebits)
+	}
+	if !s.Complete || s.Period != "2024-12" {
+		t.Errorf("Expected complete period 2024-12, got %q complete=%v", s.Period, s.Complete)
+	}
+
+	// Pending transactions are left out, ties on date are ordered by ID
+	wantIDs := []string{"txn-001", "txn-002", "txn-003"}
+	wantBalances := []float64{1140, 1064.75, 1040}
+	if len(s.Postings) != len(wantIDs) {
+		t.Fatalf("Expected %d postings, got %d", len(wantIDs), len(s.Postings))
+	}
+	for i, p := range s.Postings {
+		if p.TransactionID != wantIDs[i] || p.Balance != wantBalances[i] {
+			t.Errorf("Posting %d: expected %s with balance %v, got %s with %v",
+				i, wantIDs[i], wantBalances[i], p.TransactionID, p.Balance)
+		}
+	}
+
+	wantTotals := []CategoryTotal{
+		{Category: "dining", Count: 1, Total: -24.75},
+		{Category: "groceries", Count: 1, Total: -75.25},
+		{Category: "income", Count: 1, Total: 500},
+	}
+	if len(s.CategoryTotals) != len(wantTotals) {
+		t.Fatalf("Expected %d category totals, got %+v", len(wantTotals), s.CategoryTotals)
+	}
+	for i, total := range s.CategoryTotals {
+		if total != wantTotals[i] {
+			t.Errorf("Category total %d: expected %+v, got %+v", i, wantTotals[i], total)
+		}
+	}
+}
+
+func TestBuildStatementCurrentPeriod(t *testing.T) {
+	account, transactions := statementFixture()
+	now := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+
+	s := BuildStatement(account, transactions, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), now)
+	if s.Complete {
+		t.Error("Expected the current period to be incomplete")
+	}
+	if s.ClosingBalance != account.Balance || s.OpeningBalance != 1040 {
+		t.Errorf("Expected opening 1040 and closing %v, got %v and %v", account.Balance, s.OpeningBalance, s.ClosingBalance)
+	}
+}
+
+func TestBuildStatementSummaries(t *testing.T) {
+	account, transactions := statementFixture()
+	now := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+
+	summaries := BuildStatementSummaries(account, transactions, now)
+
+	want := []struct {
+		period           string
+		opening, closing float64
+		postings         int
+	}{
+		{"2025-01", 1040, 1000, 1},
+		{"2024-12", 640, 1040, 3},
+		{"2024-11", 540, 640, 1},
+		{"2024-10", 540, 540, 0},
+	}
+	if len(summaries) != len(want) {
+		t.Fatalf("Expected %d summaries, got %d", len(want), len(summaries))
+	}
+	for i, s := range summaries {
+		w := want[i]
+		if s.Period != w.period || s.OpeningBalance != w.opening || s.ClosingBalance != w.closing || s.PostingCount != w.postings {
+			t.Errorf("Summary %d: expected %+v, got %+v", i, w, s)
+		}
+	}
+
+	// The summaries agree with the full statements
+	for _, summary := range summaries {
+		s := BuildStatement(account, transactions, summary.PeriodStart, now)
+		if s.OpeningBalance != summary.OpeningBalance || s.ClosingBalance != summary.ClosingBalance {
+			t.Errorf("Period %s: summary %v/%v differs from statement %v/%v", summary.Period,
+				summary.OpeningBalance, summary.ClosingBalance, s.OpeningBalance, s.ClosingBalance)
+		}
+	}
+}
+
+func TestParsePeriod(t *testing.T) {
+	tests := []struct {
+		period  string
+		want    time.Time
+		wantErr bool
+	}{
+		{"2024-12", time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC), false},
+		{"2024-1", time.Time{}, true},
+		{"2024-13", time.Time{}, true},
+		{"december", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.period, func(t *testing.T) {
+			got, err := ParsePeriod(tt.period)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePeriod() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestStatementToResponseMasking(t *testing.T) {
+	account, transactions := statementFixture()
+	s := BuildStatement(account, transactions, time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC), time.Now())
+	s.MaskAmounts = true
+
+	resp := s.ToResponse()
+	if resp.OpeningBalance != MaskedAmount || resp.ClosingBalance != MaskedAmount || resp.TotalDebits != MaskedAmount {
+		t.Errorf("Expected masked balances, got %+v", resp)
+	}
+	if resp.Postings[0].Amount != MaskedAmount || resp.Postings[0].Balance != MaskedAmount {
+		t.Errorf("Expected masked posting, got %+v", resp.Postings[0])
+	}
+	if resp.CategoryTotals[0].Total != MaskedAmount || resp.CategoryTotals[0].Count != 1 {
+		t.Errorf("Expected masked total with count, got %+v", resp.CategoryTotals[0])
+	}
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Transaction is a posting read from the transactions data owned by
+// api-transactions, used to build statements
+type Transaction struct {
+	ID          string    `json:"id"`
+	AccountID   string    `json:"accountId"`
+	Date        time.Time `json:"date"`
+	Description string    `json:"description"`
+	Amount      float64   `json:"amount"`
+	Category    string    `json:"category"`
+	Merchant    string    `json:"merchant"`
+	Status      string    `json:"status"`
+	Type        string    `json:"type"`
+}
+
+// StatusCompleted marks a transaction that has been posted to the account.
+// Pending and failed transactions do not appear on statements.
+const StatusCompleted = "completed"
@@ -1,6 +1,27 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/pkg/auth"
+)
+
+// Roles a user can hold. They are defined with the token scopes each one
+// grants, see auth.ScopesForRole.
+const (
+	RoleUser    = auth.RoleUser
+	RoleSupport = auth.RoleSupport
+	RoleAdmin   = auth.RoleAdmin
+)
+
+// ValidRole reports whether role is one of the known roles
+func ValidRole(role string) bool {
+	switch role {
+	case RoleUser, RoleSupport, RoleAdmin:
+		return true
+	}
+	return false
+}
 
 // User represents a user in the system
 type User struct {
@@ -12,4 +33,9 @@ type User struct {
 	Country   string    `json:"country"` // ISO 3166-1 alpha-2 country code (US, UK, FR, etc.)
 	CreatedAt time.Time `json:"createdAt"`
 	LastLogin time.Time `json:"lastLogin"`
+	Role      string    `json:"role"` // RoleUser, RoleSupport or RoleAdmin
+
+	// PasswordHash is the user's bcrypt hash. It is never serialised; seeded
+	// users have none until they set a password and sign in with AUTH_PASSWORD.
+	PasswordHash string `json:"-"`
 }
@@ -0,0 +1,99 @@
+// Package notify delivers messages such as password reset links to users.
+// The log and file notifiers are local stand-ins for an email or SMS provider;
+// a real provider only has to implement Notifier.
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Notifier backends selectable via the NOTIFIER environment variable
+const (
+	BackendLog  = "log"
+	BackendFile = "file"
+)
+
+// Message is a notification addressed to a user
+type Message struct {
+	To      string    `json:"to"`
+	Subject string    `json:"subject"`
+	Body    string    `json:"body"`
+	SentAt  time.Time `json:"sentAt"`
+}
+
+// Notifier delivers messages to users
+type Notifier interface {
+	Send(msg *Message) error
+}
+
+// New creates the notifier for the configured backend. path is the outbox
+// file used by the file backend.
+func New(backend, path string, logger *logrus.Logger) (Notifier, error) {
+	switch backend {
+	case "", BackendLog:
+		return NewLogNotifier(logger), nil
+	case BackendFile:
+		return NewFileNotifier(path), nil
+	default:
+		return nil, fmt.Errorf("unknown notifier %q", backend)
+	}
+}
+
+// LogNotifier writes messages to the service log. Message bodies can carry
+// secrets such as reset tokens, so it is only suitable for development.
+type LogNotifier struct {
+	logger *logrus.Logger
+}
+
+// NewLogNotifier creates a notifier that logs every message
+func NewLogNotifier(logger *logrus.Logger) *LogNotifier {
+	return &LogNotifier{logger: logger}
+}
+
+// Send logs the message
+func (n *LogNotifier) Send(msg *Message) error {
+	n.logger.WithFields(logrus.Fields{
+		"to":      msg.To,
+		"subject": msg.Subject,
+	}).Info("Notification: " + msg.Body)
+	return nil
+}
+
+// FileNotifier appends messages as JSON lines to an outbox file, which tests
+// and local tooling can read back
+type FileNotifier struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileNotifier creates a notifier that appends to the file at path
+func NewFileNotifier(path string) *FileNotifier {
+	return &FileNotifier{path: path}
+}
+
+// Send appends the message to the outbox
+func (n *FileNotifier) Send(msg *Message) error {
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	file, err := os.OpenFile(n.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open outbox: %w", err)
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write outbox: %w", err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,219 @@
+// Package pdf renders account statements as PDF documents using a pure Go
+// library, so the service keeps building with CGO_ENABLED=0.
+package pdf
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/models"
+	"github.com/go-pdf/fpdf"
+)
+
+const (
+	margin     = 15.0
+	lineHeight = 6.0
+	fontFamily = "Helvetica"
+)
+
+// postingColumns are the postings table columns and their widths in mm
+var postingColumns = []struct {
+	title string
+	width float64
+	align string
+}{
+	{"Date", 24, "L"},
+	{"Description", 62, "L"},
+	{"Category", 32, "L"},
+	{"Amount", 31, "R"},
+	{"Balance", 31, "R"},
+}
+
+// RenderStatement writes the statement to w as a PDF. The output depends only
+// on the statement, so rendering the same statement twice gives the same bytes.
+func RenderStatement(w io.Writer, s *models.Statement) error {
+	doc := fpdf.New("P", "mm", "A4", "")
+	doc.SetMargins(margin, margin, margin)
+	doc.SetAutoPageBreak(true, margin+5)
+	doc.AliasNbPages("")
+	doc.SetCreationDate(s.GeneratedAt)
+	doc.SetModificationDate(s.GeneratedAt)
+	doc.SetCatalogSort(true)
+	doc.SetTitle(fmt.Sprintf("Statement %s %s", s.AccountNumber, s.Period), true)
+	doc.SetCreator("AccountStack", true)
+
+	// The core fonts use cp1252, so UTF-8 text from the data is translated first
+	tr := doc.UnicodeTranslatorFromDescriptor("")
+
+	// Repeat the postings table header on every page the table spans
+	inPostings := false
+	doc.SetHeaderFunc(func() {
+		doc.SetFont(fontFamily, "B", 14)
+		doc.CellFormat(0, 8, "AccountStack", "", 0, "L", false, 0, "")
+		doc.SetFont(fontFamily, "", 10)
+		doc.CellFormat(0, 8, tr(fmt.Sprintf("Statement %s - %s", s.AccountNumber, s.Period)), "", 1, "R", false, 0, "")
+		doc.Line(margin, doc.GetY(), 210-margin, doc.GetY())
+		doc.Ln(4)
+		if inPostings {
+			postingsHeader(doc)
+		}
+	})
+	doc.SetFooterFunc(func() {
+		doc.SetY(-margin)
+		doc.SetFont(fontFamily, "", 8)
+		doc.SetTextColor(110, 110, 110)
+		doc.CellFormat(0, 5, "Generated "+s.GeneratedAt.UTC().Format("2 Jan 2006 15:04 MST"), "", 0, "L", false, 0, "")
+		doc.CellFormat(0, 5, fmt.Sprintf("Page %d of {nb}", doc.PageNo()), "", 0, "R", false, 0, "")
+		doc.SetTextColor(0, 0, 0)
+	})
+
+	doc.AddPage()
+
+	// Account details
+	last := s.PeriodEnd.AddDate(0, 0, -1)
+	doc.SetFont(fontFamily, "B", 12)
+	doc.CellFormat(0, 7, tr(s.AccountName), "", 1, "L", false, 0, "")
+	doc.SetFont(fontFamily, "", 10)
+	doc.CellFormat(0, lineHeight, tr(fmt.Sprintf("%s account %s", titleCase(s.AccountType), s.AccountNumber)), "", 1, "L", false, 0, "")
+	doc.CellFormat(0, lineHeight, fmt.Sprintf("Period: %s to %s", s.PeriodStart.Format("2 January 2006"), last.Format("2 January 2006")), "", 1, "L", false, 0, "")
+	doc.CellFormat(0, lineHeight, "Currency: "+s.Currency, "", 1, "L", false, 0, "")
+	if !s.Complete {
+		doc.SetFont(fontFamily, "I", 9)
+		doc.CellFormat(0, lineHeight, "This period is still in progress. Balances include postings to date.", "", 1, "L", false, 0, "")
+	}
+	doc.Ln(4)
+
+	// Summary box
+	summary := []struct {
+		label  string
+		amount float64
+	}{
+		{"Opening balance", s.OpeningBalance},
+		{"Total credits", s.TotalCredits},
+		{"Total debits", s.TotalDebits},
+		{"Closing balance", s.ClosingBalance},
+	}
+	width := (210 - 2*margin) / float64(len(summary))
+	doc.SetFillColor(240, 243, 247)
+	doc.SetFont(fontFamily, "", 9)
+	for _, item := range summary {
+		doc.CellFormat(width, lineHeight, item.label, "LTR", 0, "C", true, 0, "")
+	}
+	doc.Ln(-1)
+	doc.SetFont(fontFamily, "B", 11)
+	for _, item := range summary {
+		doc.CellFormat(width, 8, formatAmount(item.amount, s.MaskAmounts), "LBR", 0, "C", true, 0, "")
+	}
+	doc.Ln(12)
+
+	// Postings
+	sectionTitle(doc, fmt.Sprintf("Transactions (%d)", len(s.Postings)))
+	inPostings = true
+	postingsHeader(doc)
+	doc.SetFont(fontFamily, "", 9)
+	if len(s.Postings) == 0 {
+		doc.CellFormat(0, lineHeight, "No transactions were posted in this period.", "B", 1, "L", false, 0, "")
+	}
+	for _, p := range s.Postings {
+		description := p.Description
+		if p.Merchant != "" && p.Merchant != p.Description {
+			description += " - " + p.Merchant
+		}
+		cells := []string{
+			p.Date.UTC().Format("02 Jan 2006"),
+			fit(doc, tr(description), postingColumns[1].width),
+			fit(doc, tr(p.Category), postingColumns[2].width),
+			formatAmount(p.Amount, s.MaskAmounts),
+			formatAmount(p.Balance, s.MaskAmounts),
+		}
+		for i, col := range postingColumns {
+			doc.CellFormat(col.width, lineHeight, cells[i], "B", 0, col.align, false, 0, "")
+		}
+		doc.Ln(-1)
+	}
+	inPostings = false
+	doc.Ln(8)
+
+	// Category totals
+	sectionTitle(doc, "Totals by category")
+	doc.SetFont(fontFamily, "B", 9)
+	doc.SetFillColor(225, 230, 238)
+	doc.CellFormat(110, lineHeight, "Category", "", 0, "L", true, 0, "")
+	doc.CellFormat(30, lineHeight, "Count", "", 0, "R", true, 0, "")
+	doc.CellFormat(40, lineHeight, "Total", "", 1, "R", true, 0, "")
+	doc.SetFont(fontFamily, "", 9)
+	for _, c := range s.CategoryTotals {
+		doc.CellFormat(110, lineHeight, tr(c.Category), "B", 0, "L", false, 0, "")
+		doc.CellFormat(30, lineHeight, strconv.Itoa(c.Count), "B", 0, "R", false, 0, "")
+		doc.CellFormat(40, lineHeight, formatAmount(c.Total, s.MaskAmounts), "B", 1, "R", false, 0, "")
+	}
+
+	return doc.Output(w)
+}
+
+// sectionTitle writes a heading, starting a new page first if it would be orphaned
+func sectionTitle(doc *fpdf.Fpdf, title string) {
+	_, pageHeight := doc.GetPageSize()
+	if doc.GetY()+4*lineHeight > pageHeight-margin-5 {
+		doc.AddPage()
+	}
+	doc.SetFont(fontFamily, "B", 11)
+	doc.CellFormat(0, 7, title, "", 1, "L", false, 0, "")
+}
+
+// postingsHeader writes the column headings of the postings table
+func postingsHeader(doc *fpdf.Fpdf) {
+	doc.SetFont(fontFamily, "B", 9)
+	doc.SetFillColor(225, 230, 238)
+	for _, col := range postingColumns {
+		doc.CellFormat(col.width, lineHeight, col.title, "", 0, col.align, true, 0, "")
+	}
+	doc.Ln(-1)
+	doc.SetFont(fontFamily, "", 9)
+}
+
+// fit shortens text with an ellipsis so that it fits in a cell of the given width
+func fit(doc *fpdf.Fpdf, text string, width float64) string {
+	width -= 2 // Cell padding
+	if doc.GetStringWidth(text) <= width {
+		return text
+	}
+	for len(text) > 0 && doc.GetStringWidth(text+"...") > width {
+		text = text[:len(text)-1]
+	}
+	return text + "..."
+}
+
+// formatAmount formats an amount with thousands separators, or masks it
+func formatAmount(amount float64, mask bool) string {
+	if mask {
+		return models.MaskedAmount
+	}
+
+	cents := int64(math.Round(math.Abs(amount) * 100))
+	digits := strconv.FormatInt(cents/100, 10)
+	var grouped strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(d)
+	}
+
+	sign := ""
+	if amount < 0 && cents > 0 {
+		sign = "-"
+	}
+	return fmt.Sprintf("%s%s.%02d", sign, grouped.String(), cents%100)
+}
+
+// titleCase capitalises the first letter of an account type such as "checking"
+func titleCase(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
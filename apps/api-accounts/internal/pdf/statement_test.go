@@ -0,0 +1,85 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/models"
+)
+
+func testStatement(postings int) *models.Statement {
+	start := time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)
+	s := &models.Statement{
+		AccountID:      "acc-001",
+		AccountName:    "Personal Checking – Café",
+		AccountNumber:  "****1234",
+		AccountType:    "checking",
+		Currency:       "USD",
+		Period:         "2024-12",
+		PeriodStart:    start,
+		PeriodEnd:      start.AddDate(0, 1, 0),
+		Complete:       true,
+		OpeningBalance: 1000,
+		GeneratedAt:    time.Date(2025, 1, 2, 9, 0, 0, 0, time.UTC),
+	}
+	balance := s.OpeningBalance
+	for i := 0; i < postings; i++ {
+		balance -= 12.5
+		s.Postings = append(s.Postings, models.Posting{
+			TransactionID: fmt.Sprintf("txn-%03d", i),
+			Date:          start.AddDate(0, 0, i%31),
+			Description:   "A rather long description that will not fit in its column",
+			Merchant:      "Merchant",
+			Category:      "shopping",
+			Amount:        -12.5,
+			Balance:       balance,
+		})
+	}
+	s.ClosingBalance = balance
+	s.CategoryTotals = []models.CategoryTotal{{Category: "shopping", Count: postings, Total: balance - 1000}}
+	return s
+}
+
+func TestRenderStatement(t *testing.T) {
+	var first, second bytes.Buffer
+	if err := RenderStatement(&first, testStatement(100)); err != nil {
+		t.Fatalf("RenderStatement failed: %v", err)
+	}
+	if err := RenderStatement(&second, testStatement(100)); err != nil {
+		t.Fatalf("RenderStatement failed: %v", err)
+	}
+
+	if !bytes.HasPrefix(first.Bytes(), []byte("%PDF-")) {
+		t.Fatalf("Expected a PDF document")
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Error("Expected identical output for the same statement")
+	}
+	// 100 postings do not fit on one page
+	if bytes.Contains(first.Bytes(), []byte("/Count 1\n")) {
+		t.Error("Expected the postings table to span several pages")
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	tests := []struct {
+		amount float64
+		mask   bool
+		want   string
+	}{
+		{0, false, "0.00"},
+		{5.4, false, "5.40"},
+		{-1234.567, false, "-1,234.57"},
+		{1234567.89, false, "1,234,567.89"},
+		{-0.001, false, "0.00"},
+		{99.99, true, models.MaskedAmount},
+	}
+
+	for _, tt := range tests {
+		if got := formatAmount(tt.amount, tt.mask); got != tt.want {
+			t.Errorf("formatAmount(%v, %v) = %q, want %q", tt.amount, tt.mask, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,740 @@
+package repository
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// JSONStore is an in-memory Store loaded from the seed JSON files.
+// Changes are not persisted across restarts.
+type JSONStore struct {
+	users         map[string]*models.User
+	accounts      map[string]*models.Account
+	transactions  map[string][]*models.Transaction // Keyed by account ID
+	resetTokens   map[string]*models.PasswordResetToken
+	refreshTokens map[string]*models.RefreshToken
+	totp          map[string]*models.TOTPEnrollment // Keyed by user ID
+	recoveryCodes map[string][]*models.RecoveryCode // Keyed by user ID
+	mfaChallenges map[string]*models.MFAChallenge
+	grants        []*models.AccountGrant      // In the order they were granted
+	invitations   []*models.AccountInvitation // In the order they were sent
+	auditLog      []*models.AuditEntry        // In the order they were recorded
+	mu            sync.RWMutex
+	logger        *logrus.Logger
+}
+
+// NewJSONStore creates a new store and loads data from JSON files
+func NewJSONStore(dataPath string, logger *logrus.Logger) (*JSONStore, error) {
+	repo := &JSONStore{
+		users:         make(map[string]*models.User),
+		accounts:      make(map[string]*models.Account),
+		transactions:  make(map[string][]*models.Transaction),
+		resetTokens:   make(map[string]*models.PasswordResetToken),
+		refreshTokens: make(map[string]*models.RefreshToken),
+		totp:          make(map[string]*models.TOTPEnrollment),
+		recoveryCodes: make(map[string][]*models.RecoveryCode),
+		mfaChallenges: make(map[string]*models.MFAChallenge),
+		logger:        logger,
+	}
+
+	// Load users
+	if err := repo.loadUsers(filepath.Join(dataPath, "users.json")); err != nil {
+		return nil, fmt.Errorf("failed to load users: %w", err)
+	}
+
+	// Load accounts
+	if err := repo.loadAccounts(filepath.Join(dataPath, "accounts.json")); err != nil {
+		return nil, fmt.Errorf("failed to load accounts: %w", err)
+	}
+
+	// Load transactions, a read-only snapshot of the api-transactions seed
+	if err := repo.loadTransactions(filepath.Join(dataPath, "transactions.json")); err != nil {
+		return nil, fmt.Errorf("failed to load transactions: %w", err)
+	}
+
+	logger.Infof("Loaded %d users and %d accounts from %s", len(repo.users), len(repo.accounts), dataPath)
+
+	return repo, nil
+}
+
+// loadUsers loads users from a JSON file
+func (r *JSONStore) loadUsers(filePath string) error {
+	var users []*models.User
+	if err := readJSONFile(filePath, &users); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, user := range users {
+		if user.Role == "" {
+			user.Role = models.RoleUser
+		}
+		r.users[user.ID] = user
+	}
+
+	return nil
+}
+
+// loadAccounts loads accounts from a JSON file
+func (r *JSONStore) loadAccounts(filePath string) error {
+	var accounts []*models.Account
+	if err := readJSONFile(filePath, &accounts); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, account := range accounts {
+		r.accounts[account.ID] = account
+	}
+
+	return nil
+}
+
+// loadTransactions loads transactions from a JSON file
+func (r *JSONStore) loadTransactions(filePath string) error {
+	var transactions []*models.Transaction
+	if err := readJSONFile(filePath, &transactions); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, txn := range transactions {
+		r.transactions[txn.AccountID] = append(r.transactions[txn.AccountID], txn)
+	}
+
+	return nil
+}
+
+// GetUserByID retrieves a user by ID
+func (r *JSONStore) GetUserByID(userID string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, exists := r.users[userID]
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+// GetUserByEmail retrieves a user by email address
+func (r *JSONStore) GetUserByEmail(email string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+
+	return nil, ErrUserNotFound
+}
+
+// GetAccountByID retrieves an account by ID
+func (r *JSONStore) GetAccountByID(accountID string) (*models.Account, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	account, exists := r.accounts[accountID]
+	if !exists {
+		return nil, ErrAccountNotFound
+	}
+
+	return account, nil
+}
+
+// GetAccountsByUserID retrieves all accounts for a specific user
+func (r *JSONStore) GetAccountsByUserID(userID string) ([]*models.Account, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var userAccounts []*models.Account
+	for _, account := range r.accounts {
+		if account.UserID == userID {
+			userAccounts = append(userAccounts, account)
+		}
+	}
+
+	return userAccounts, nil
+}
+
+// GetAllUsers returns all users (for testing/admin purposes)
+func (r *JSONStore) GetAllUsers() ([]*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := make([]*models.User, 0, len(r.users))
+	for _, user := range r.users {
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
+// CreateUser adds a new user
+func (r *JSONStore) CreateUser(user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.users {
+		if existing.Email == user.Email {
+			return ErrEmailTaken
+		}
+	}
+	if _, exists := r.users[user.ID]; exists {
+		return fmt.Errorf("user %s already exists", user.ID)
+	}
+
+	stored := *user
+	r.users[user.ID] = &stored
+	return nil
+}
+
+// UpdatePasswordHash replaces a user's password hash
+func (r *JSONStore) UpdatePasswordHash(userID, passwordHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, exists := r.users[userID]
+	if !exists {
+		return ErrUserNotFound
+	}
+
+	// Replace rather than mutate, since readers hold the old pointer without the lock
+	updated := *user
+	updated.PasswordHash = passwordHash
+	r.users[userID] = &updated
+	return nil
+}
+
+// UpdateUserRole replaces a user's role
+func (r *JSONStore) UpdateUserRole(userID, role string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, exists := r.users[userID]
+	if !exists {
+		return ErrUserNotFound
+	}
+
+	updated := *user
+	updated.Role = role
+	r.users[userID] = &updated
+	return nil
+}
+
+// CreatePasswordResetToken stores a newly issued reset token
+func (r *JSONStore) CreatePasswordResetToken(token *models.PasswordResetToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *token
+	r.resetTokens[token.TokenHash] = &stored
+	return nil
+}
+
+// ConsumePasswordResetToken marks a usable token and the user's other outstanding tokens as used
+func (r *JSONStore) ConsumePasswordResetToken(tokenHash string, now time.Time) (*models.PasswordResetToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, exists := r.resetTokens[tokenHash]
+	if !exists || !token.Usable(now) {
+		return nil, ErrResetTokenInvalid
+	}
+
+	for _, t := range r.resetTokens {
+		if t.UserID == token.UserID && t.UsedAt == nil {
+			usedAt := now
+			t.UsedAt = &usedAt
+		}
+	}
+
+	consumed := *token
+	return &consumed, nil
+}
+
+// CreateRefreshToken stores a newly issued refresh token
+func (r *JSONStore) CreateRefreshToken(token *models.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *token
+	r.refreshTokens[token.TokenHash] = &stored
+	return nil
+}
+
+// GetRefreshToken retrieves a refresh token by its hash
+func (r *JSONStore) GetRefreshToken(tokenHash string) (*models.RefreshToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	token, exists := r.refreshTokens[tokenHash]
+	if !exists {
+		return nil, ErrRefreshTokenNotFound
+	}
+	found := *token
+	return &found, nil
+}
+
+// GetRefreshTokenByAccessTokenID retrieves the refresh token issued with an access token
+func (r *JSONStore) GetRefreshTokenByAccessTokenID(accessTokenID string) (*models.RefreshToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, token := range r.refreshTokens {
+		if token.AccessTokenID == accessTokenID {
+			found := *token
+			return &found, nil
+		}
+	}
+	return nil, ErrRefreshTokenNotFound
+}
+
+// RotateRefreshToken marks an unused, unrevoked token as used and stores next
+func (r *JSONStore) RotateRefreshToken(tokenHash string, next *models.RefreshToken, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, exists := r.refreshTokens[tokenHash]
+	if !exists {
+		return ErrRefreshTokenNotFound
+	}
+	if token.UsedAt != nil || token.RevokedAt != nil {
+		return ErrRefreshTokenUsed
+	}
+
+	usedAt := now
+	token.UsedAt = &usedAt
+	stored := *next
+	r.refreshTokens[next.TokenHash] = &stored
+	return nil
+}
+
+// RevokeRefreshTokenFamily revokes every token in the family and returns them
+func (r *JSONStore) RevokeRefreshTokenFamily(familyID string, now time.Time) ([]*models.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var family []*models.RefreshToken
+	for _, token := range r.refreshTokens {
+		if token.FamilyID != familyID {
+			continue
+		}
+		if token.RevokedAt == nil {
+			revokedAt := now
+			token.RevokedAt = &revokedAt
+		}
+		revoked := *token
+		family = append(family, &revoked)
+	}
+	return family, nil
+}
+
+// RevokeUserRefreshTokens revokes every token of the user and returns those
+// whose access token has not expired by now
+func (r *JSONStore) RevokeUserRefreshTokens(userID string, now time.Time) ([]*models.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var live []*models.RefreshToken
+	for _, token := range r.refreshTokens {
+		if token.UserID != userID {
+			continue
+		}
+		if token.RevokedAt == nil {
+			revokedAt := now
+			token.RevokedAt = &revokedAt
+		}
+		if token.AccessExpiresAt.After(now) {
+			revoked := *token
+			live = append(live, &revoked)
+		}
+	}
+	return live, nil
+}
+
+// SaveTOTPEnrollment stores an unconfirmed enrollment, replacing any earlier unconfirmed one
+func (r *JSONStore) SaveTOTPEnrollment(enrollment *models.TOTPEnrollment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, exists := r.totp[enrollment.UserID]; exists && existing.Enabled() {
+		return ErrTOTPAlreadyEnabled
+	}
+	stored := *enrollment
+	r.totp[enrollment.UserID] = &stored
+	return nil
+}
+
+// GetTOTPEnrollment retrieves a user's TOTP enrollment
+func (r *JSONStore) GetTOTPEnrollment(userID string) (*models.TOTPEnrollment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	enrollment, exists := r.totp[userID]
+	if !exists {
+		return nil, ErrTOTPNotEnrolled
+	}
+	found := *enrollment
+	return &found, nil
+}
+
+// ConfirmTOTPEnrollment enables an enrollment and replaces the user's recovery codes
+func (r *JSONStore) ConfirmTOTPEnrollment(userID string, step int64, codes []*models.RecoveryCode, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	enrollment, exists := r.totp[userID]
+	if !exists {
+		return ErrTOTPNotEnrolled
+	}
+	if enrollment.Enabled() {
+		return ErrTOTPAlreadyEnabled
+	}
+
+	confirmedAt := now
+	enrollment.ConfirmedAt = &confirmedAt
+	enrollment.LastUsedStep = step
+	r.recoveryCodes[userID] = copyRecoveryCodes(codes)
+	return nil
+}
+
+// UseTOTPStep records step as the last used one if it is later than it
+func (r *JSONStore) UseTOTPStep(userID string, step int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	enrollment, exists := r.totp[userID]
+	if !exists {
+		return ErrTOTPNotEnrolled
+	}
+	if step <= enrollment.LastUsedStep {
+		return ErrTOTPStepUsed
+	}
+	enrollment.LastUsedStep = step
+	return nil
+}
+
+// DeleteTOTPEnrollment removes the user's enrollment and recovery codes
+func (r *JSONStore) DeleteTOTPEnrollment(userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.totp[userID]; !exists {
+		return ErrTOTPNotEnrolled
+	}
+	delete(r.totp, userID)
+	delete(r.recoveryCodes, userID)
+	return nil
+}
+
+// ReplaceRecoveryCodes discards the user's recovery codes and stores codes instead
+func (r *JSONStore) ReplaceRecoveryCodes(userID string, codes []*models.RecoveryCode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.recoveryCodes[userID] = copyRecoveryCodes(codes)
+	return nil
+}
+
+// ConsumeRecoveryCode marks one of the user's unused recovery codes as used
+func (r *JSONStore) ConsumeRecoveryCode(userID, codeHash string, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, code := range r.recoveryCodes[userID] {
+		if code.CodeHash == codeHash && code.UsedAt == nil {
+			usedAt := now
+			code.UsedAt = &usedAt
+			return nil
+		}
+	}
+	return ErrRecoveryCodeInvalid
+}
+
+// CountRecoveryCodes returns how many of the user's recovery codes are unused
+func (r *JSONStore) CountRecoveryCodes(userID string) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	count := 0
+	for _, code := range r.recoveryCodes[userID] {
+		if code.UsedAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// CreateMFAChallenge stores a newly issued MFA challenge
+func (r *JSONStore) CreateMFAChallenge(challenge *models.MFAChallenge) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *challenge
+	r.mfaChallenges[challenge.TokenHash] = &stored
+	return nil
+}
+
+// GetMFAChallenge retrieves an MFA challenge by its hash
+func (r *JSONStore) GetMFAChallenge(tokenHash string) (*models.MFAChallenge, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	challenge, exists := r.mfaChallenges[tokenHash]
+	if !exists {
+		return nil, ErrMFAChallengeInvalid
+	}
+	found := *challenge
+	return &found, nil
+}
+
+// RecordMFAChallengeFailure counts a failed attempt and returns the new total
+func (r *JSONStore) RecordMFAChallengeFailure(tokenHash string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	challenge, exists := r.mfaChallenges[tokenHash]
+	if !exists {
+		return 0, ErrMFAChallengeInvalid
+	}
+	challenge.Attempts++
+	return challenge.Attempts, nil
+}
+
+// ConsumeMFAChallenge marks a usable challenge as used
+func (r *JSONStore) ConsumeMFAChallenge(tokenHash string, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	challenge, exists := r.mfaChallenges[tokenHash]
+	if !exists || !challenge.Usable(now) {
+		return ErrMFAChallengeInvalid
+	}
+	usedAt := now
+	challenge.UsedAt = &usedAt
+	return nil
+}
+
+// copyRecoveryCodes copies codes so callers cannot modify the stored ones
+func copyRecoveryCodes(codes []*models.RecoveryCode) []*models.RecoveryCode {
+	copied := make([]*models.RecoveryCode, 0, len(codes))
+	for _, code := range codes {
+		stored := *code
+		copied = append(copied, &stored)
+	}
+	return copied
+}
+
+// GetAccountGrant returns the user's grant on the account
+func (r *JSONStore) GetAccountGrant(accountID, userID string) (*models.AccountGrant, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, grant := range r.grants {
+		if grant.AccountID == accountID && grant.UserID == userID {
+			g := *grant
+			return &g, nil
+		}
+	}
+	return nil, ErrGrantNotFound
+}
+
+// GetAccountGrantsByAccountID returns every grant on the account, oldest first
+func (r *JSONStore) GetAccountGrantsByAccountID(accountID string) ([]*models.AccountGrant, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	grants := []*models.AccountGrant{}
+	for _, grant := range r.grants {
+		if grant.AccountID == accountID {
+			g := *grant
+			grants = append(grants, &g)
+		}
+	}
+	return grants, nil
+}
+
+// GetAccountGrantsByUserID returns every grant the user holds, ordered by account
+func (r *JSONStore) GetAccountGrantsByUserID(userID string) ([]*models.AccountGrant, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	grants := []*models.AccountGrant{}
+	for _, grant := range r.grants {
+		if grant.UserID == userID {
+			g := *grant
+			grants = append(grants, &g)
+		}
+	}
+	sort.Slice(grants, func(i, j int) bool { return grants[i].AccountID < grants[j].AccountID })
+	return grants, nil
+}
+
+// DeleteAccountGrant removes the user's grant on the account
+func (r *JSONStore) DeleteAccountGrant(accountID, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, grant := range r.grants {
+		if grant.AccountID == accountID && grant.UserID == userID {
+			r.grants = append(r.grants[:i], r.grants[i+1:]...)
+			return nil
+		}
+	}
+	return ErrGrantNotFound
+}
+
+// CreateAccountInvitation stores a new invitation
+func (r *JSONStore) CreateAccountInvitation(invitation *models.AccountInvitation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *invitation
+	r.invitations = append(r.invitations, &stored)
+	return nil
+}
+
+// GetAccountInvitation retrieves an invitation by ID
+func (r *JSONStore) GetAccountInvitation(invitationID string) (*models.AccountInvitation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	invitation := r.findInvitation(invitationID)
+	if invitation == nil {
+		return nil, ErrInvitationNotFound
+	}
+	i := *invitation
+	return &i, nil
+}
+
+// GetAccountInvitationsByAccountID returns every invitation to the account, oldest first
+func (r *JSONStore) GetAccountInvitationsByAccountID(accountID string) ([]*models.AccountInvitation, error) {
+	return r.filterInvitations(func(i *models.AccountInvitation) bool { return i.AccountID == accountID }), nil
+}
+
+// GetAccountInvitationsByEmail returns every invitation sent to email, oldest first
+func (r *JSONStore) GetAccountInvitationsByEmail(email string) ([]*models.AccountInvitation, error) {
+	return r.filterInvitations(func(i *models.AccountInvitation) bool { return i.Email == email }), nil
+}
+
+// AcceptAccountInvitation marks a pending invitation as accepted and stores grant
+func (r *JSONStore) AcceptAccountInvitation(invitationID string, grant *models.AccountGrant, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	invitation := r.findInvitation(invitationID)
+	if invitation == nil {
+		return ErrInvitationNotFound
+	}
+	if !invitation.Pending(now) {
+		return ErrInvitationClosed
+	}
+	invitation.Status = models.InvitationAccepted
+	invitation.RespondedAt = &now
+
+	stored := *grant
+	for i, existing := range r.grants {
+		if existing.AccountID == grant.AccountID && existing.UserID == grant.UserID {
+			stored.CreatedAt = existing.CreatedAt
+			r.grants[i] = &stored
+			return nil
+		}
+	}
+	r.grants = append(r.grants, &stored)
+	return nil
+}
+
+// CloseAccountInvitation moves a pending invitation to status
+func (r *JSONStore) CloseAccountInvitation(invitationID, status string, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	invitation := r.findInvitation(invitationID)
+	if invitation == nil {
+		return ErrInvitationNotFound
+	}
+	if invitation.Status != models.InvitationPending {
+		return ErrInvitationClosed
+	}
+	invitation.Status = status
+	invitation.RespondedAt = &now
+	return nil
+}
+
+// findInvitation returns the stored invitation with the given ID, or nil.
+// The caller must hold the lock.
+func (r *JSONStore) findInvitation(invitationID string) *models.AccountInvitation {
+	for _, invitation := range r.invitations {
+		if invitation.ID == invitationID {
+			return invitation
+		}
+	}
+	return nil
+}
+
+// filterInvitations returns copies of the invitations matching keep, oldest first
+func (r *JSONStore) filterInvitations(keep func(*models.AccountInvitation) bool) []*models.AccountInvitation {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	invitations := []*models.AccountInvitation{}
+	for _, invitation := range r.invitations {
+		if keep(invitation) {
+			i := *invitation
+			invitations = append(invitations, &i)
+		}
+	}
+	return invitations
+}
+
+// CreateAuditEntry appends an entry to the audit log
+func (r *JSONStore) CreateAuditEntry(entry *models.AuditEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *entry
+	r.auditLog = append(r.auditLog, &stored)
+	return nil
+}
+
+// ListAuditEntries returns up to limit audit entries, newest first
+func (r *JSONStore) ListAuditEntries(limit int) ([]*models.AuditEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := []*models.AuditEntry{}
+	for i := len(r.auditLog) - 1; i >= 0 && len(entries) < limit; i-- {
+		entry := *r.auditLog[i]
+		entries = append(entries, &entry)
+	}
+	return entries, nil
+}
+
+// GetTransactionsByAccountID retrieves all transactions for an account
+func (r *JSONStore) GetTransactionsByAccountID(accountID string) ([]*models.Transaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]*models.Transaction(nil), r.transactions[accountID]...), nil
+}
+
+// Close is a no-op for the in-memory store
+func (r *JSONStore) Close() error {
+	return nil
+}
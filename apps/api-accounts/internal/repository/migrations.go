@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// migration is a single forward-only schema change. IDs are prefixed with the
+// owning service so several services can share one database file.
+type migration struct {
+	id  string
+	sql string
+}
+
+// migrations lists the accounts schema in the order it must be applied
+var migrations = []migration{
+	{
+		id: "accounts_0001_create_users",
+		sql: `CREATE TABLE IF NOT EXISTS users (
+			id         TEXT PRIMARY KEY,
+			email      TEXT NOT NULL UNIQUE,
+			name       TEXT NOT NULL,
+			first_name TEXT NOT NULL,
+			last_name  TEXT NOT NULL,
+			country    TEXT NOT NULL,
+			created_at TEXT NOT NULL,
+			last_login TEXT NOT NULL
+		)`,
+	},
+	{
+		id: "accounts_0002_create_accounts",
+		sql: `CREATE TABLE IF NOT EXISTS accounts (
+			id             TEXT PRIMARY KEY,
+			user_id        TEXT NOT NULL,
+			account_number TEXT NOT NULL,
+			account_type   TEXT NOT NULL,
+			account_name   TEXT NOT NULL,
+			balance        REAL NOT NULL,
+			currency       TEXT NOT NULL,
+			credit_limit   REAL,
+			status         TEXT NOT NULL,
+			opened_date    TEXT NOT NULL,
+			last_activity  TEXT NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_accounts_user_id ON accounts (user_id)`,
+	},
+	{
+		// Owned by api-transactions; created here with the same base columns so
+		// statements can be read from a shared file or a standalone database
+		id: "accounts_0003_create_transactions",
+		sql: `CREATE TABLE IF NOT EXISTS transactions (
+			id          TEXT PRIMARY KEY,
+			account_id  TEXT NOT NULL,
+			date        TEXT NOT NULL,
+			description TEXT NOT NULL,
+			amount      REAL NOT NULL,
+			category    TEXT NOT NULL,
+			merchant    TEXT NOT NULL,
+			status      TEXT NOT NULL,
+			type        TEXT NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_transactions_account_date_id ON transactions (account_id, date, id)`,
+	},
+	{
+		id:  "accounts_0004_add_password_hash",
+		sql: `ALTER TABLE users ADD COLUMN password_hash TEXT`,
+	},
+	{
+		id: "accounts_0005_create_password_reset_tokens",
+		sql: `CREATE TABLE IF NOT EXISTS password_reset_tokens (
+			token_hash TEXT PRIMARY KEY,
+			user_id    TEXT NOT NULL,
+			created_at TEXT NOT NULL,
+			expires_at TEXT NOT NULL,
+			used_at    TEXT
+		);
+		CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user_id ON password_reset_tokens (user_id)`,
+	},
+	{
+		id: "accounts_0006_create_refresh_tokens",
+		sql: `CREATE TABLE IF NOT EXISTS refresh_tokens (
+			token_hash        TEXT PRIMARY KEY,
+			family_id         TEXT NOT NULL,
+			user_id           TEXT NOT NULL,
+			access_token_id   TEXT NOT NULL,
+			access_expires_at TEXT NOT NULL,
+			created_at        TEXT NOT NULL,
+			expires_at        TEXT NOT NULL,
+			used_at           TEXT,
+			revoked_at        TEXT
+		);
+		CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+		CREATE INDEX IF NOT EXISTS idx_refresh_tokens_access_token_id ON refresh_tokens (access_token_id)`,
+	},
+	{
+		id: "accounts_0007_create_mfa",
+		sql: `CREATE TABLE IF NOT EXISTS totp_enrollments (
+			user_id        TEXT PRIMARY KEY,
+			secret         TEXT NOT NULL,
+			created_at     TEXT NOT NULL,
+			confirmed_at   TEXT,
+			last_used_step INTEGER NOT NULL DEFAULT 0
+		);
+		CREATE TABLE IF NOT EXISTS recovery_codes (
+			user_id    TEXT NOT NULL,
+			code_hash  TEXT NOT NULL,
+			created_at TEXT NOT NULL,
+			used_at    TEXT,
+			PRIMARY KEY (user_id, code_hash)
+		);
+		CREATE TABLE IF NOT EXISTS mfa_challenges (
+			token_hash TEXT PRIMARY KEY,
+			user_id    TEXT NOT NULL,
+			created_at TEXT NOT NULL,
+			expires_at TEXT NOT NULL,
+			attempts   INTEGER NOT NULL DEFAULT 0,
+			used_at    TEXT
+		)`,
+	},
+	{
+		id: "accounts_0008_add_roles_and_audit_log",
+		sql: `ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user';
+		CREATE TABLE IF NOT EXISTS audit_log (
+			id             TEXT PRIMARY KEY,
+			actor_id       TEXT NOT NULL,
+			action         TEXT NOT NULL,
+			target_user_id TEXT NOT NULL,
+			detail         TEXT NOT NULL DEFAULT '',
+			token_id       TEXT NOT NULL DEFAULT '',
+			created_at     TEXT NOT NULL
+		)`,
+	},
+	{
+		// account_grants is read by api-transactions and api-insights too
+		id: "accounts_0009_create_account_sharing",
+		sql: `CREATE TABLE IF NOT EXISTS account_grants (
+			account_id TEXT NOT NULL,
+			user_id    TEXT NOT NULL,
+			access     TEXT NOT NULL,
+			granted_by TEXT NOT NULL,
+			created_at TEXT NOT NULL,
+			PRIMARY KEY (account_id, user_id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_account_grants_user_id ON account_grants (user_id);
+		CREATE TABLE IF NOT EXISTS account_invitations (
+			id           TEXT PRIMARY KEY,
+			account_id   TEXT NOT NULL,
+			email        TEXT NOT NULL,
+			access       TEXT NOT NULL,
+			invited_by   TEXT NOT NULL,
+			status       TEXT NOT NULL,
+			created_at   TEXT NOT NULL,
+			expires_at   TEXT NOT NULL,
+			responded_at TEXT
+		);
+		CREATE INDEX IF NOT EXISTS idx_account_invitations_account_id ON account_invitations (account_id);
+		CREATE INDEX IF NOT EXISTS idx_account_invitations_email ON account_invitations (email)`,
+	},
+	{
+		id:  "accounts_0010_index_refresh_tokens_user_id",
+		sql: `CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id)`,
+	},
+	{
+		// Invitations sent before this have no token and can no longer be accepted
+		id:  "accounts_0011_add_invitation_token_hash",
+		sql: `ALTER TABLE account_invitations ADD COLUMN token_hash TEXT NOT NULL DEFAULT ''`,
+	},
+}
+
+// migrate applies any migrations that have not yet been recorded
+func migrate(db *sql.DB, migrations []migration) (int, error) {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		id         TEXT PRIMARY KEY,
+		applied_at TEXT NOT NULL
+	)`); err != nil {
+		return 0, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	applied := 0
+	for _, m := range migrations {
+		var exists int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM schema_migrations WHERE id = ?`, m.id).Scan(&exists); err != nil {
+			return applied, err
+		}
+		if exists > 0 {
+			continue
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			return applied, err
+		}
+		if _, err := tx.Exec(m.sql); err != nil {
+			tx.Rollback()
+			return applied, fmt.Errorf("migration %s failed: %w", m.id, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (id, applied_at) VALUES (?, ?)`,
+			m.id, time.Now().UTC().Format(time.RFC3339)); err != nil {
+			tx.Rollback()
+			return applied, err
+		}
+		if err := tx.Commit(); err != nil {
+			return applied, err
+		}
+		applied++
+	}
+
+	return applied, nil
+}
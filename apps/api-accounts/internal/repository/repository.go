@@ -2,154 +2,167 @@ package repository
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
-	"sync"
+	"time"
 
 	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/models"
 	"github.com/sirupsen/logrus"
 )
 
-// Repository provides data access for users and accounts
-type Repository struct {
-	users    map[string]*models.User
-	accounts map[string]*models.Account
-	mu       sync.RWMutex
-	logger   *logrus.Logger
-}
-
-// NewRepository creates a new repository and loads data from JSON files
-func NewRepository(dataPath string, logger *logrus.Logger) (*Repository, error) {
-	repo := &Repository{
-		users:    make(map[string]*models.User),
-		accounts: make(map[string]*models.Account),
-		logger:   logger,
-	}
-
-	// Load users
-	if err := repo.loadUsers(filepath.Join(dataPath, "users.json")); err != nil {
-		return nil, fmt.Errorf("failed to load users: %w", err)
-	}
-
-	// Load accounts
-	if err := repo.loadAccounts(filepath.Join(dataPath, "accounts.json")); err != nil {
-		return nil, fmt.Errorf("failed to load accounts: %w", err)
-	}
-
-	logger.Infof("Loaded %d users and %d accounts from %s", len(repo.users), len(repo.accounts), dataPath)
-
-	return repo, nil
-}
-
-// loadUsers loads users from a JSON file
-func (r *Repository) loadUsers(filePath string) error {
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		return err
-	}
+// Storage backends selectable via the STORAGE_BACKEND environment variable
+const (
+	BackendJSON   = "json"
+	BackendSQLite = "sqlite"
+)
 
-	var users []*models.User
-	if err := json.Unmarshal(data, &users); err != nil {
-		return err
-	}
+var (
+	// ErrUserNotFound is returned for a user that does not exist
+	ErrUserNotFound = errors.New("user not found")
 
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	// ErrAccountNotFound is returned for an account that does not exist
+	ErrAccountNotFound = errors.New("account not found")
 
-	for _, user := range users {
-		r.users[user.ID] = user
-	}
+	// ErrEmailTaken is returned when creating a user whose email is already registered
+	ErrEmailTaken = errors.New("email already registered")
 
-	return nil
-}
+	// ErrResetTokenInvalid is returned for a password reset token that is
+	// unknown, already used or expired
+	ErrResetTokenInvalid = errors.New("invalid or expired reset token")
 
-// loadAccounts loads accounts from a JSON file
-func (r *Repository) loadAccounts(filePath string) error {
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		return err
-	}
+	// ErrRefreshTokenNotFound is returned for a refresh token that was never issued
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
 
-	var accounts []*models.Account
-	if err := json.Unmarshal(data, &accounts); err != nil {
-		return err
-	}
+	// ErrRefreshTokenUsed is returned when rotating a refresh token that was
+	// already exchanged or revoked
+	ErrRefreshTokenUsed = errors.New("refresh token already used")
 
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	// ErrTOTPNotEnrolled is returned when a user has no TOTP enrollment
+	ErrTOTPNotEnrolled = errors.New("TOTP not enrolled")
 
-	for _, account := range accounts {
-		r.accounts[account.ID] = account
-	}
+	// ErrTOTPAlreadyEnabled is returned when enrolling a user whose TOTP
+	// enrollment is already confirmed
+	ErrTOTPAlreadyEnabled = errors.New("TOTP already enabled")
 
-	return nil
-}
+	// ErrTOTPStepUsed is returned when a TOTP code's time step is not later
+	// than the last one accepted, meaning the code is being replayed
+	ErrTOTPStepUsed = errors.New("TOTP code already used")
 
-// GetUserByID retrieves a user by ID
-func (r *Repository) GetUserByID(userID string) (*models.User, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+	// ErrRecoveryCodeInvalid is returned for a recovery code that is unknown or already used
+	ErrRecoveryCodeInvalid = errors.New("invalid or used recovery code")
 
-	user, exists := r.users[userID]
-	if !exists {
-		return nil, fmt.Errorf("user not found")
-	}
+	// ErrMFAChallengeInvalid is returned for an MFA challenge that is
+	// unknown, already completed or expired
+	ErrMFAChallengeInvalid = errors.New("invalid or expired MFA challenge")
 
-	return user, nil
-}
+	// ErrGrantNotFound is returned when a user holds no grant on an account
+	ErrGrantNotFound = errors.New("account grant not found")
 
-// GetUserByEmail retrieves a user by email address
-func (r *Repository) GetUserByEmail(email string) (*models.User, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+	// ErrInvitationNotFound is returned for an account invitation that was never sent
+	ErrInvitationNotFound = errors.New("invitation not found")
 
-	for _, user := range r.users {
-		if user.Email == email {
-			return user, nil
-		}
-	}
+	// ErrInvitationClosed is returned when responding to an invitation that
+	// was already accepted, declined or cancelled, or has expired
+	ErrInvitationClosed = errors.New("invitation is no longer pending")
+)
 
-	return nil, fmt.Errorf("user not found")
+// Store provides data access for users and accounts, plus read access to the
+// transactions owned by api-transactions for building statements
+type Store interface {
+	GetUserByID(userID string) (*models.User, error)
+	GetUserByEmail(email string) (*models.User, error)
+	GetAccountByID(accountID string) (*models.Account, error)
+	GetAccountsByUserID(userID string) ([]*models.Account, error)
+	GetAllUsers() ([]*models.User, error)
+	CreateUser(user *models.User) error
+	UpdatePasswordHash(userID, passwordHash string) error
+	UpdateUserRole(userID, role string) error
+	CreatePasswordResetToken(token *models.PasswordResetToken) error
+	// ConsumePasswordResetToken marks a usable token as used, together with
+	// every other outstanding token of the same user, and returns it
+	ConsumePasswordResetToken(tokenHash string, now time.Time) (*models.PasswordResetToken, error)
+	CreateRefreshToken(token *models.RefreshToken) error
+	GetRefreshToken(tokenHash string) (*models.RefreshToken, error)
+	GetRefreshTokenByAccessTokenID(accessTokenID string) (*models.RefreshToken, error)
+	// RotateRefreshToken marks an unused, unrevoked token as used and stores
+	// next in one step, so a token can only be exchanged once
+	RotateRefreshToken(tokenHash string, next *models.RefreshToken, now time.Time) error
+	// RevokeRefreshTokenFamily revokes every token in the family and returns them
+	RevokeRefreshTokenFamily(familyID string, now time.Time) ([]*models.RefreshToken, error)
+	// RevokeUserRefreshTokens revokes every token of the user and returns
+	// those whose access token has not expired by now
+	RevokeUserRefreshTokens(userID string, now time.Time) ([]*models.RefreshToken, error)
+	// SaveTOTPEnrollment stores an unconfirmed enrollment, replacing any earlier
+	// unconfirmed one, and fails with ErrTOTPAlreadyEnabled once one is confirmed
+	SaveTOTPEnrollment(enrollment *models.TOTPEnrollment) error
+	GetTOTPEnrollment(userID string) (*models.TOTPEnrollment, error)
+	// ConfirmTOTPEnrollment enables the user's enrollment, records the step of
+	// the code that confirmed it and replaces their recovery codes
+	ConfirmTOTPEnrollment(userID string, step int64, codes []*models.RecoveryCode, now time.Time) error
+	// UseTOTPStep records step as the last used one if it is later than it
+	UseTOTPStep(userID string, step int64) error
+	// DeleteTOTPEnrollment removes the user's enrollment and recovery codes
+	DeleteTOTPEnrollment(userID string) error
+	ReplaceRecoveryCodes(userID string, codes []*models.RecoveryCode) error
+	// ConsumeRecoveryCode marks one of the user's unused recovery codes as used
+	ConsumeRecoveryCode(userID, codeHash string, now time.Time) error
+	// CountRecoveryCodes returns how many of the user's recovery codes are unused
+	CountRecoveryCodes(userID string) (int, error)
+	CreateMFAChallenge(challenge *models.MFAChallenge) error
+	GetMFAChallenge(tokenHash string) (*models.MFAChallenge, error)
+	// RecordMFAChallengeFailure counts a failed attempt and returns the new total
+	RecordMFAChallengeFailure(tokenHash string) (int, error)
+	// ConsumeMFAChallenge marks a usable challenge as used
+	ConsumeMFAChallenge(tokenHash string, now time.Time) error
+	// GetAccountGrant returns the user's grant on the account, or ErrGrantNotFound
+	GetAccountGrant(accountID, userID string) (*models.AccountGrant, error)
+	GetAccountGrantsByAccountID(accountID string) ([]*models.AccountGrant, error)
+	GetAccountGrantsByUserID(userID string) ([]*models.AccountGrant, error)
+	// DeleteAccountGrant removes the user's grant on the account, or fails
+	// with ErrGrantNotFound
+	DeleteAccountGrant(accountID, userID string) error
+	CreateAccountInvitation(invitation *models.AccountInvitation) error
+	GetAccountInvitation(invitationID string) (*models.AccountInvitation, error)
+	GetAccountInvitationsByAccountID(accountID string) ([]*models.AccountInvitation, error)
+	GetAccountInvitationsByEmail(email string) ([]*models.AccountInvitation, error)
+	// AcceptAccountInvitation marks a pending, unexpired invitation as accepted
+	// and stores grant in one step, failing with ErrInvitationClosed otherwise
+	AcceptAccountInvitation(invitationID string, grant *models.AccountGrant, now time.Time) error
+	// CloseAccountInvitation moves a pending invitation to status, declined or
+	// cancelled, failing with ErrInvitationClosed if it is no longer pending
+	CloseAccountInvitation(invitationID, status string, now time.Time) error
+	CreateAuditEntry(entry *models.AuditEntry) error
+	// ListAuditEntries returns up to limit audit entries, newest first
+	ListAuditEntries(limit int) ([]*models.AuditEntry, error)
+	GetTransactionsByAccountID(accountID string) ([]*models.Transaction, error)
+	Close() error
 }
 
-// GetAccountByID retrieves an account by ID
-func (r *Repository) GetAccountByID(accountID string) (*models.Account, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-
-	account, exists := r.accounts[accountID]
-	if !exists {
-		return nil, fmt.Errorf("account not found")
-	}
-
-	return account, nil
+// Config selects and configures the storage backend
+type Config struct {
+	Backend    string // json (default) or sqlite
+	DataPath   string // Directory containing the seed JSON files
+	SQLitePath string // Database file used by the sqlite backend
 }
 
-// GetAccountsByUserID retrieves all accounts for a specific user
-func (r *Repository) GetAccountsByUserID(userID string) ([]*models.Account, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-
-	var userAccounts []*models.Account
-	for _, account := range r.accounts {
-		if account.UserID == userID {
-			userAccounts = append(userAccounts, account)
-		}
+// NewStore creates the store for the configured backend
+func NewStore(cfg Config, logger *logrus.Logger) (Store, error) {
+	switch cfg.Backend {
+	case "", BackendJSON:
+		return NewJSONStore(cfg.DataPath, logger)
+	case BackendSQLite:
+		return NewSQLiteStore(cfg.SQLitePath, cfg.DataPath, logger)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
 	}
-
-	return userAccounts, nil
 }
 
-// GetAllUsers returns all users (for testing/admin purposes)
-func (r *Repository) GetAllUsers() []*models.User {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-
-	users := make([]*models.User, 0, len(r.users))
-	for _, user := range r.users {
-		users = append(users, user)
+// readJSONFile decodes a seed file into v
+func readJSONFile(filePath string, v any) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
 	}
-
-	return users
+	return json.Unmarshal(data, v)
 }
@@ -0,0 +1,1031 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/models"
+	"github.com/sirupsen/logrus"
+	_ "modernc.org/sqlite" // Pure Go SQLite driver, keeps CGO_ENABLED=0 builds working
+)
+
+const (
+	userColumns    = `id, email, name, first_name, last_name, country, created_at, last_login, password_hash, role`
+	accountColumns = `id, user_id, account_number, account_type, account_name, balance, currency,
+		credit_limit, status, opened_date, last_activity`
+	transactionColumns  = `id, account_id, date, description, amount, category, merchant, status, type`
+	refreshTokenColumns = `token_hash, family_id, user_id, access_token_id, access_expires_at,
+		created_at, expires_at, used_at, revoked_at`
+	totpColumns         = `user_id, secret, created_at, confirmed_at, last_used_step`
+	mfaChallengeColumns = `token_hash, user_id, created_at, expires_at, attempts, used_at`
+	auditColumns        = `id, actor_id, action, target_user_id, detail, token_id, created_at`
+	grantColumns        = `account_id, user_id, access, granted_by, created_at`
+	invitationColumns   = `id, account_id, email, access, invited_by, status, created_at, expires_at, responded_at, token_hash`
+
+	// transactionTimeLayout matches the fixed-width dates api-transactions writes,
+	// which sort chronologically as text
+	transactionTimeLayout = "2006-01-02T15:04:05.000000000Z07:00"
+)
+
+// SQLiteStore is a durable Store backed by a SQLite database file
+type SQLiteStore struct {
+	db     *sql.DB
+	logger *logrus.Logger
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// NewSQLiteStore opens (or creates) the database at dbPath, applies pending
+// migrations and imports the seed data from dataPath when the database is empty
+func NewSQLiteStore(dbPath, dataPath string, logger *logrus.Logger) (*SQLiteStore, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", dbPath)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	// A single connection serialises writers within the process and avoids SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+
+	store := &SQLiteStore{
+		db:     db,
+		logger: logger,
+	}
+
+	applied, err := migrate(db, migrations)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+	if applied > 0 {
+		logger.Infof("Applied %d schema migrations to %s", applied, dbPath)
+	}
+
+	if err := store.importSeed(dataPath); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to import seed data: %w", err)
+	}
+
+	if err := store.importTransactionSeed(dataPath); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to import seed transactions: %w", err)
+	}
+
+	logger.Infof("Using SQLite storage at %s", dbPath)
+
+	return store, nil
+}
+
+// importSeed loads users and accounts from the seed files if the users table is empty
+func (s *SQLiteStore) importSeed(dataPath string) error {
+	var count int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	var users []*models.User
+	if err := readJSONFile(filepath.Join(dataPath, "users.json"), &users); err != nil {
+		return fmt.Errorf("failed to load users: %w", err)
+	}
+
+	var accounts []*models.Account
+	if err := readJSONFile(filepath.Join(dataPath, "accounts.json"), &accounts); err != nil {
+		return fmt.Errorf("failed to load accounts: %w", err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, u := range users {
+		if err := insertUser(tx, u, "INSERT OR IGNORE"); err != nil {
+			return err
+		}
+	}
+
+	for _, a := range accounts {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO accounts (`+accountColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			a.ID, a.UserID, a.AccountNumber, a.AccountType, a.AccountName, a.Balance, a.Currency,
+			a.CreditLimit, a.Status, formatTime(a.OpenedDate), formatTime(a.LastActivity)); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.logger.Infof("Imported %d users and %d accounts from %s", len(users), len(accounts), dataPath)
+	return nil
+}
+
+// importTransactionSeed loads transactions from the seed file if the
+// transactions table is empty, which it is until either service seeds it
+func (s *SQLiteStore) importTransactionSeed(dataPath string) error {
+	var count int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM transactions`).Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	var transactions []*models.Transaction
+	if err := readJSONFile(filepath.Join(dataPath, "transactions.json"), &transactions); err != nil {
+		return fmt.Errorf("failed to load transactions: %w", err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, t := range transactions {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO transactions (`+transactionColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			t.ID, t.AccountID, t.Date.UTC().Format(transactionTimeLayout), t.Description, t.Amount,
+			t.Category, t.Merchant, t.Status, t.Type); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.logger.Infof("Imported %d transactions from %s", len(transactions), dataPath)
+	return nil
+}
+
+// GetUserByID retrieves a user by ID
+func (s *SQLiteStore) GetUserByID(userID string) (*models.User, error) {
+	row := s.db.QueryRow(`SELECT `+userColumns+` FROM users WHERE id = ?`, userID)
+	user, err := scanUser(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	return user, err
+}
+
+// GetUserByEmail retrieves a user by email address
+func (s *SQLiteStore) GetUserByEmail(email string) (*models.User, error) {
+	row := s.db.QueryRow(`SELECT `+userColumns+` FROM users WHERE email = ?`, email)
+	user, err := scanUser(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	return user, err
+}
+
+// GetAccountByID retrieves an account by ID
+func (s *SQLiteStore) GetAccountByID(accountID string) (*models.Account, error) {
+	row := s.db.QueryRow(`SELECT `+accountColumns+` FROM accounts WHERE id = ?`, accountID)
+	account, err := scanAccount(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAccountNotFound
+	}
+	return account, err
+}
+
+// GetAccountsByUserID retrieves all accounts for a specific user
+func (s *SQLiteStore) GetAccountsByUserID(userID string) ([]*models.Account, error) {
+	rows, err := s.db.Query(`SELECT `+accountColumns+` FROM accounts WHERE user_id = ? ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userAccounts []*models.Account
+	for rows.Next() {
+		account, err := scanAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		userAccounts = append(userAccounts, account)
+	}
+
+	return userAccounts, rows.Err()
+}
+
+// GetAllUsers returns all users (for testing/admin purposes)
+func (s *SQLiteStore) GetAllUsers() ([]*models.User, error) {
+	rows, err := s.db.Query(`SELECT ` + userColumns + ` FROM users ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []*models.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// CreateUser adds a new user
+func (s *SQLiteStore) CreateUser(user *models.User) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM users WHERE email = ?`, user.Email).Scan(&exists); err != nil {
+		return err
+	}
+	if exists > 0 {
+		return ErrEmailTaken
+	}
+	if err := insertUser(tx, user, "INSERT"); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdatePasswordHash replaces a user's password hash
+func (s *SQLiteStore) UpdatePasswordHash(userID, passwordHash string) error {
+	res, err := s.db.Exec(`UPDATE users SET password_hash = ? WHERE id = ?`, passwordHash, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// UpdateUserRole replaces a user's role
+func (s *SQLiteStore) UpdateUserRole(userID, role string) error {
+	res, err := s.db.Exec(`UPDATE users SET role = ? WHERE id = ?`, role, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// CreatePasswordResetToken stores a newly issued reset token
+func (s *SQLiteStore) CreatePasswordResetToken(token *models.PasswordResetToken) error {
+	_, err := s.db.Exec(`INSERT INTO password_reset_tokens (token_hash, user_id, created_at, expires_at) VALUES (?, ?, ?, ?)`,
+		token.TokenHash, token.UserID, formatTime(token.CreatedAt), formatTime(token.ExpiresAt))
+	return err
+}
+
+// ConsumePasswordResetToken marks a usable token and the user's other outstanding tokens as used
+func (s *SQLiteStore) ConsumePasswordResetToken(tokenHash string, now time.Time) (*models.PasswordResetToken, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var token models.PasswordResetToken
+	var createdAt, expiresAt string
+	var usedAt sql.NullString
+	err = tx.QueryRow(`SELECT token_hash, user_id, created_at, expires_at, used_at FROM password_reset_tokens WHERE token_hash = ?`,
+		tokenHash).Scan(&token.TokenHash, &token.UserID, &createdAt, &expiresAt, &usedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrResetTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	token.CreatedAt = parseTime(createdAt)
+	token.ExpiresAt = parseTime(expiresAt)
+	token.UsedAt = parseOptionalTime(usedAt)
+	if !token.Usable(now) {
+		return nil, ErrResetTokenInvalid
+	}
+
+	if _, err := tx.Exec(`UPDATE password_reset_tokens SET used_at = ? WHERE user_id = ? AND used_at IS NULL`,
+		formatTime(now), token.UserID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// CreateRefreshToken stores a newly issued refresh token
+func (s *SQLiteStore) CreateRefreshToken(token *models.RefreshToken) error {
+	return insertRefreshToken(s.db, token)
+}
+
+// GetRefreshToken retrieves a refresh token by its hash
+func (s *SQLiteStore) GetRefreshToken(tokenHash string) (*models.RefreshToken, error) {
+	row := s.db.QueryRow(`SELECT `+refreshTokenColumns+` FROM refresh_tokens WHERE token_hash = ?`, tokenHash)
+	token, err := scanRefreshToken(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRefreshTokenNotFound
+	}
+	return token, err
+}
+
+// GetRefreshTokenByAccessTokenID retrieves the refresh token issued with an access token
+func (s *SQLiteStore) GetRefreshTokenByAccessTokenID(accessTokenID string) (*models.RefreshToken, error) {
+	row := s.db.QueryRow(`SELECT `+refreshTokenColumns+` FROM refresh_tokens WHERE access_token_id = ?`, accessTokenID)
+	token, err := scanRefreshToken(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRefreshTokenNotFound
+	}
+	return token, err
+}
+
+// RotateRefreshToken marks an unused, unrevoked token as used and stores next
+func (s *SQLiteStore) RotateRefreshToken(tokenHash string, next *models.RefreshToken, now time.Time) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`UPDATE refresh_tokens SET used_at = ?
+		WHERE token_hash = ? AND used_at IS NULL AND revoked_at IS NULL`, formatTime(now), tokenHash)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		var exists int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM refresh_tokens WHERE token_hash = ?`, tokenHash).Scan(&exists); err != nil {
+			return err
+		}
+		if exists == 0 {
+			return ErrRefreshTokenNotFound
+		}
+		return ErrRefreshTokenUsed
+	}
+
+	if err := insertRefreshToken(tx, next); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RevokeRefreshTokenFamily revokes every token in the family and returns them
+func (s *SQLiteStore) RevokeRefreshTokenFamily(familyID string, now time.Time) ([]*models.RefreshToken, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE refresh_tokens SET revoked_at = ? WHERE family_id = ? AND revoked_at IS NULL`,
+		formatTime(now), familyID); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(`SELECT `+refreshTokenColumns+` FROM refresh_tokens WHERE family_id = ? ORDER BY created_at`, familyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var family []*models.RefreshToken
+	for rows.Next() {
+		token, err := scanRefreshToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		family = append(family, token)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return family, nil
+}
+
+// RevokeUserRefreshTokens revokes every token of the user and returns those
+// whose access token has not expired by now
+func (s *SQLiteStore) RevokeUserRefreshTokens(userID string, now time.Time) ([]*models.RefreshToken, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE refresh_tokens SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL`,
+		formatTime(now), userID); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(`SELECT `+refreshTokenColumns+` FROM refresh_tokens WHERE user_id = ? ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var live []*models.RefreshToken
+	for rows.Next() {
+		token, err := scanRefreshToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		if token.AccessExpiresAt.After(now) {
+			live = append(live, token)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return live, nil
+}
+
+// SaveTOTPEnrollment stores an unconfirmed enrollment, replacing any earlier unconfirmed one
+func (s *SQLiteStore) SaveTOTPEnrollment(enrollment *models.TOTPEnrollment) error {
+	res, err := s.db.Exec(`INSERT INTO totp_enrollments (`+totpColumns+`) VALUES (?, ?, ?, NULL, 0)
+		ON CONFLICT (user_id) DO UPDATE SET secret = excluded.secret, created_at = excluded.created_at
+		WHERE totp_enrollments.confirmed_at IS NULL`,
+		enrollment.UserID, enrollment.Secret, formatTime(enrollment.CreatedAt))
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrTOTPAlreadyEnabled
+	}
+	return nil
+}
+
+// GetTOTPEnrollment retrieves a user's TOTP enrollment
+func (s *SQLiteStore) GetTOTPEnrollment(userID string) (*models.TOTPEnrollment, error) {
+	var e models.TOTPEnrollment
+	var createdAt string
+	var confirmedAt sql.NullString
+	err := s.db.QueryRow(`SELECT `+totpColumns+` FROM totp_enrollments WHERE user_id = ?`, userID).
+		Scan(&e.UserID, &e.Secret, &createdAt, &confirmedAt, &e.LastUsedStep)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTOTPNotEnrolled
+	}
+	if err != nil {
+		return nil, err
+	}
+	e.CreatedAt = parseTime(createdAt)
+	e.ConfirmedAt = parseOptionalTime(confirmedAt)
+	return &e, nil
+}
+
+// ConfirmTOTPEnrollment enables an enrollment and replaces the user's recovery codes
+func (s *SQLiteStore) ConfirmTOTPEnrollment(userID string, step int64, codes []*models.RecoveryCode, now time.Time) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`UPDATE totp_enrollments SET confirmed_at = ?, last_used_step = ?
+		WHERE user_id = ? AND confirmed_at IS NULL`, formatTime(now), step, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		var exists int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM totp_enrollments WHERE user_id = ?`, userID).Scan(&exists); err != nil {
+			return err
+		}
+		if exists == 0 {
+			return ErrTOTPNotEnrolled
+		}
+		return ErrTOTPAlreadyEnabled
+	}
+
+	if err := replaceRecoveryCodes(tx, userID, codes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UseTOTPStep records step as the last used one if it is later than it
+func (s *SQLiteStore) UseTOTPStep(userID string, step int64) error {
+	res, err := s.db.Exec(`UPDATE totp_enrollments SET last_used_step = ? WHERE user_id = ? AND last_used_step < ?`,
+		step, userID, step)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		var exists int
+		if err := s.db.QueryRow(`SELECT COUNT(*) FROM totp_enrollments WHERE user_id = ?`, userID).Scan(&exists); err != nil {
+			return err
+		}
+		if exists == 0 {
+			return ErrTOTPNotEnrolled
+		}
+		return ErrTOTPStepUsed
+	}
+	return nil
+}
+
+// DeleteTOTPEnrollment removes the user's enrollment and recovery codes
+func (s *SQLiteStore) DeleteTOTPEnrollment(userID string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`DELETE FROM totp_enrollments WHERE user_id = ?`, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrTOTPNotEnrolled
+	}
+	if _, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ReplaceRecoveryCodes discards the user's recovery codes and stores codes instead
+func (s *SQLiteStore) ReplaceRecoveryCodes(userID string, codes []*models.RecoveryCode) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(tx, userID, codes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ConsumeRecoveryCode marks one of the user's unused recovery codes as used
+func (s *SQLiteStore) ConsumeRecoveryCode(userID, codeHash string, now time.Time) error {
+	res, err := s.db.Exec(`UPDATE recovery_codes SET used_at = ? WHERE user_id = ? AND code_hash = ? AND used_at IS NULL`,
+		formatTime(now), userID, codeHash)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrRecoveryCodeInvalid
+	}
+	return nil
+}
+
+// CountRecoveryCodes returns how many of the user's recovery codes are unused
+func (s *SQLiteStore) CountRecoveryCodes(userID string) (int, error) {
+	var count int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM recovery_codes WHERE user_id = ? AND used_at IS NULL`, userID).Scan(&count)
+	return count, err
+}
+
+// CreateMFAChallenge stores a newly issued MFA challenge
+func (s *SQLiteStore) CreateMFAChallenge(challenge *models.MFAChallenge) error {
+	_, err := s.db.Exec(`INSERT INTO mfa_challenges (`+mfaChallengeColumns+`) VALUES (?, ?, ?, ?, ?, ?)`,
+		challenge.TokenHash, challenge.UserID, formatTime(challenge.CreatedAt), formatTime(challenge.ExpiresAt),
+		challenge.Attempts, formatOptionalTime(challenge.UsedAt))
+	return err
+}
+
+// GetMFAChallenge retrieves an MFA challenge by its hash
+func (s *SQLiteStore) GetMFAChallenge(tokenHash string) (*models.MFAChallenge, error) {
+	var c models.MFAChallenge
+	var createdAt, expiresAt string
+	var usedAt sql.NullString
+	err := s.db.QueryRow(`SELECT `+mfaChallengeColumns+` FROM mfa_challenges WHERE token_hash = ?`, tokenHash).
+		Scan(&c.TokenHash, &c.UserID, &createdAt, &expiresAt, &c.Attempts, &usedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMFAChallengeInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.CreatedAt = parseTime(createdAt)
+	c.ExpiresAt = parseTime(expiresAt)
+	c.UsedAt = parseOptionalTime(usedAt)
+	return &c, nil
+}
+
+// RecordMFAChallengeFailure counts a failed attempt and returns the new total
+func (s *SQLiteStore) RecordMFAChallengeFailure(tokenHash string) (int, error) {
+	var attempts int
+	err := s.db.QueryRow(`UPDATE mfa_challenges SET attempts = attempts + 1 WHERE token_hash = ? RETURNING attempts`,
+		tokenHash).Scan(&attempts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrMFAChallengeInvalid
+	}
+	return attempts, err
+}
+
+// ConsumeMFAChallenge marks a usable challenge as used
+func (s *SQLiteStore) ConsumeMFAChallenge(tokenHash string, now time.Time) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var expiresAt string
+	var usedAt sql.NullString
+	err = tx.QueryRow(`SELECT expires_at, used_at FROM mfa_challenges WHERE token_hash = ?`, tokenHash).Scan(&expiresAt, &usedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrMFAChallengeInvalid
+	}
+	if err != nil {
+		return err
+	}
+	challenge := models.MFAChallenge{ExpiresAt: parseTime(expiresAt), UsedAt: parseOptionalTime(usedAt)}
+	if !challenge.Usable(now) {
+		return ErrMFAChallengeInvalid
+	}
+
+	if _, err := tx.Exec(`UPDATE mfa_challenges SET used_at = ? WHERE token_hash = ?`, formatTime(now), tokenHash); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetTransactionsByAccountID retrieves all transactions for an account, oldest first
+func (s *SQLiteStore) GetTransactionsByAccountID(accountID string) ([]*models.Transaction, error) {
+	rows, err := s.db.Query(`SELECT `+transactionColumns+` FROM transactions WHERE account_id = ? ORDER BY date, id`, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []*models.Transaction
+	for rows.Next() {
+		var t models.Transaction
+		var date string
+		if err := rows.Scan(&t.ID, &t.AccountID, &date, &t.Description, &t.Amount,
+			&t.Category, &t.Merchant, &t.Status, &t.Type); err != nil {
+			return nil, err
+		}
+		t.Date = parseTime(date)
+		transactions = append(transactions, &t)
+	}
+
+	return transactions, rows.Err()
+}
+
+// GetAccountGrant returns the user's grant on the account
+func (s *SQLiteStore) GetAccountGrant(accountID, userID string) (*models.AccountGrant, error) {
+	row := s.db.QueryRow(`SELECT `+grantColumns+` FROM account_grants WHERE account_id = ? AND user_id = ?`, accountID, userID)
+	grant, err := scanGrant(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrGrantNotFound
+	}
+	return grant, err
+}
+
+// GetAccountGrantsByAccountID returns every grant on the account, oldest first
+func (s *SQLiteStore) GetAccountGrantsByAccountID(accountID string) ([]*models.AccountGrant, error) {
+	return s.queryGrants(`SELECT `+grantColumns+` FROM account_grants WHERE account_id = ? ORDER BY rowid`, accountID)
+}
+
+// GetAccountGrantsByUserID returns every grant the user holds, ordered by account
+func (s *SQLiteStore) GetAccountGrantsByUserID(userID string) ([]*models.AccountGrant, error) {
+	return s.queryGrants(`SELECT `+grantColumns+` FROM account_grants WHERE user_id = ? ORDER BY account_id`, userID)
+}
+
+// DeleteAccountGrant removes the user's grant on the account
+func (s *SQLiteStore) DeleteAccountGrant(accountID, userID string) error {
+	res, err := s.db.Exec(`DELETE FROM account_grants WHERE account_id = ? AND user_id = ?`, accountID, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrGrantNotFound
+	}
+	return nil
+}
+
+// CreateAccountInvitation stores a new invitation
+func (s *SQLiteStore) CreateAccountInvitation(invitation *models.AccountInvitation) error {
+	_, err := s.db.Exec(`INSERT INTO account_invitations (`+invitationColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		invitation.ID, invitation.AccountID, invitation.Email, invitation.Access, invitation.InvitedBy, invitation.Status,
+		formatTime(invitation.CreatedAt), formatTime(invitation.ExpiresAt), formatOptionalTime(invitation.RespondedAt),
+		invitation.TokenHash)
+	return err
+}
+
+// GetAccountInvitation retrieves an invitation by ID
+func (s *SQLiteStore) GetAccountInvitation(invitationID string) (*models.AccountInvitation, error) {
+	row := s.db.QueryRow(`SELECT `+invitationColumns+` FROM account_invitations WHERE id = ?`, invitationID)
+	invitation, err := scanInvitation(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvitationNotFound
+	}
+	return invitation, err
+}
+
+// GetAccountInvitationsByAccountID returns every invitation to the account, oldest first
+func (s *SQLiteStore) GetAccountInvitationsByAccountID(accountID string) ([]*models.AccountInvitation, error) {
+	return s.queryInvitations(`SELECT `+invitationColumns+` FROM account_invitations WHERE account_id = ? ORDER BY rowid`, accountID)
+}
+
+// GetAccountInvitationsByEmail returns every invitation sent to email, oldest first
+func (s *SQLiteStore) GetAccountInvitationsByEmail(email string) ([]*models.AccountInvitation, error) {
+	return s.queryInvitations(`SELECT `+invitationColumns+` FROM account_invitations WHERE email = ? ORDER BY rowid`, email)
+}
+
+// AcceptAccountInvitation marks a pending invitation as accepted and stores grant
+func (s *SQLiteStore) AcceptAccountInvitation(invitationID string, grant *models.AccountGrant, now time.Time) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	invitation, err := scanInvitation(tx.QueryRow(`SELECT `+invitationColumns+` FROM account_invitations WHERE id = ?`, invitationID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvitationNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if !invitation.Pending(now) {
+		return ErrInvitationClosed
+	}
+
+	if _, err := tx.Exec(`UPDATE account_invitations SET status = ?, responded_at = ? WHERE id = ?`,
+		models.InvitationAccepted, formatTime(now), invitationID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO account_grants (`+grantColumns+`) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (account_id, user_id) DO UPDATE SET access = excluded.access, granted_by = excluded.granted_by`,
+		grant.AccountID, grant.UserID, grant.Access, grant.GrantedBy, formatTime(grant.CreatedAt)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CloseAccountInvitation moves a pending invitation to status
+func (s *SQLiteStore) CloseAccountInvitation(invitationID, status string, now time.Time) error {
+	res, err := s.db.Exec(`UPDATE account_invitations SET status = ?, responded_at = ? WHERE id = ? AND status = ?`,
+		status, formatTime(now), invitationID, models.InvitationPending)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		if _, err := s.GetAccountInvitation(invitationID); err != nil {
+			return err
+		}
+		return ErrInvitationClosed
+	}
+	return nil
+}
+
+// queryGrants runs a query selecting grantColumns
+func (s *SQLiteStore) queryGrants(query string, args ...any) ([]*models.AccountGrant, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	grants := []*models.AccountGrant{}
+	for rows.Next() {
+		grant, err := scanGrant(rows)
+		if err != nil {
+			return nil, err
+		}
+		grants = append(grants, grant)
+	}
+
+	return grants, rows.Err()
+}
+
+// queryInvitations runs a query selecting invitationColumns
+func (s *SQLiteStore) queryInvitations(query string, args ...any) ([]*models.AccountInvitation, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []*models.AccountInvitation{}
+	for rows.Next() {
+		invitation, err := scanInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, invitation)
+	}
+
+	return invitations, rows.Err()
+}
+
+// CreateAuditEntry appends an entry to the audit log
+func (s *SQLiteStore) CreateAuditEntry(entry *models.AuditEntry) error {
+	_, err := s.db.Exec(`INSERT INTO audit_log (`+auditColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		entry.ID, entry.ActorID, entry.Action, entry.TargetUserID, entry.Detail, entry.TokenID, formatTime(entry.CreatedAt))
+	return err
+}
+
+// ListAuditEntries returns up to limit audit entries, newest first. Rows are
+// ordered by rowid, which follows insertion order.
+func (s *SQLiteStore) ListAuditEntries(limit int) ([]*models.AuditEntry, error) {
+	rows, err := s.db.Query(`SELECT `+auditColumns+` FROM audit_log ORDER BY rowid DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*models.AuditEntry{}
+	for rows.Next() {
+		var e models.AuditEntry
+		var createdAt string
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.Action, &e.TargetUserID, &e.Detail, &e.TokenID, &createdAt); err != nil {
+			return nil, err
+		}
+		e.CreatedAt = parseTime(createdAt)
+		entries = append(entries, &e)
+	}
+
+	return entries, rows.Err()
+}
+
+// Close closes the underlying database
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// insertUser writes a users row with the given verb, INSERT or INSERT OR IGNORE
+func insertUser(db execer, u *models.User, verb string) error {
+	var passwordHash sql.NullString
+	if u.PasswordHash != "" {
+		passwordHash = sql.NullString{String: u.PasswordHash, Valid: true}
+	}
+	role := u.Role
+	if role == "" {
+		role = models.RoleUser
+	}
+	_, err := db.Exec(verb+` INTO users (`+userColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		u.ID, u.Email, u.Name, u.FirstName, u.LastName, u.Country,
+		formatTime(u.CreatedAt), formatTime(u.LastLogin), passwordHash, role)
+	return err
+}
+
+// insertRefreshToken writes a new refresh_tokens row
+func insertRefreshToken(db execer, t *models.RefreshToken) error {
+	_, err := db.Exec(`INSERT INTO refresh_tokens (`+refreshTokenColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		t.TokenHash, t.FamilyID, t.UserID, t.AccessTokenID, formatTime(t.AccessExpiresAt),
+		formatTime(t.CreatedAt), formatTime(t.ExpiresAt), formatOptionalTime(t.UsedAt), formatOptionalTime(t.RevokedAt))
+	return err
+}
+
+// replaceRecoveryCodes deletes the user's recovery codes and inserts codes
+func replaceRecoveryCodes(db execer, userID string, codes []*models.RecoveryCode) error {
+	if _, err := db.Exec(`DELETE FROM recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	for _, code := range codes {
+		if _, err := db.Exec(`INSERT INTO recovery_codes (user_id, code_hash, created_at, used_at) VALUES (?, ?, ?, ?)`,
+			userID, code.CodeHash, formatTime(code.CreatedAt), formatOptionalTime(code.UsedAt)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanRefreshToken reads a refresh_tokens row selected with refreshTokenColumns
+func scanRefreshToken(row rowScanner) (*models.RefreshToken, error) {
+	var t models.RefreshToken
+	var accessExpiresAt, createdAt, expiresAt string
+	var usedAt, revokedAt sql.NullString
+	if err := row.Scan(&t.TokenHash, &t.FamilyID, &t.UserID, &t.AccessTokenID, &accessExpiresAt,
+		&createdAt, &expiresAt, &usedAt, &revokedAt); err != nil {
+		return nil, err
+	}
+	t.AccessExpiresAt = parseTime(accessExpiresAt)
+	t.CreatedAt = parseTime(createdAt)
+	t.ExpiresAt = parseTime(expiresAt)
+	t.UsedAt = parseOptionalTime(usedAt)
+	t.RevokedAt = parseOptionalTime(revokedAt)
+	return &t, nil
+}
+
+// scanUser reads a users row selected with userColumns
+func scanUser(row rowScanner) (*models.User, error) {
+	var u models.User
+	var createdAt, lastLogin string
+	var passwordHash sql.NullString
+	if err := row.Scan(&u.ID, &u.Email, &u.Name, &u.FirstName, &u.LastName, &u.Country, &createdAt, &lastLogin,
+		&passwordHash, &u.Role); err != nil {
+		return nil, err
+	}
+	u.CreatedAt = parseTime(createdAt)
+	u.LastLogin = parseTime(lastLogin)
+	u.PasswordHash = passwordHash.String
+	return &u, nil
+}
+
+// scanAccount reads an accounts row selected with accountColumns
+func scanAccount(row rowScanner) (*models.Account, error) {
+	var a models.Account
+	var creditLimit sql.NullFloat64
+	var openedDate, lastActivity string
+	if err := row.Scan(&a.ID, &a.UserID, &a.AccountNumber, &a.AccountType, &a.AccountName, &a.Balance,
+		&a.Currency, &creditLimit, &a.Status, &openedDate, &lastActivity); err != nil {
+		return nil, err
+	}
+	if creditLimit.Valid {
+		a.CreditLimit = &creditLimit.Float64
+	}
+	a.OpenedDate = parseTime(openedDate)
+	a.LastActivity = parseTime(lastActivity)
+	return &a, nil
+}
+
+// scanGrant reads an account_grants row selected with grantColumns
+func scanGrant(row rowScanner) (*models.AccountGrant, error) {
+	var g models.AccountGrant
+	var createdAt string
+	if err := row.Scan(&g.AccountID, &g.UserID, &g.Access, &g.GrantedBy, &createdAt); err != nil {
+		return nil, err
+	}
+	g.CreatedAt = parseTime(createdAt)
+	return &g, nil
+}
+
+// scanInvitation reads an account_invitations row selected with invitationColumns
+func scanInvitation(row rowScanner) (*models.AccountInvitation, error) {
+	var i models.AccountInvitation
+	var createdAt, expiresAt string
+	var respondedAt sql.NullString
+	if err := row.Scan(&i.ID, &i.AccountID, &i.Email, &i.Access, &i.InvitedBy, &i.Status,
+		&createdAt, &expiresAt, &respondedAt, &i.TokenHash); err != nil {
+		return nil, err
+	}
+	i.CreatedAt = parseTime(createdAt)
+	i.ExpiresAt = parseTime(expiresAt)
+	i.RespondedAt = parseOptionalTime(respondedAt)
+	return &i, nil
+}
+
+// formatTime encodes timestamps as UTC RFC 3339 text
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// parseTime decodes a timestamp written by formatTime
+func parseTime(s string) time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, s)
+	return t
+}
+
+// formatOptionalTime encodes a timestamp that may be unset as nullable text
+func formatOptionalTime(t *time.Time) sql.NullString {
+	if t == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: formatTime(*t), Valid: true}
+}
+
+// parseOptionalTime decodes a nullable timestamp written by formatOptionalTime
+func parseOptionalTime(s sql.NullString) *time.Time {
+	if !s.Valid {
+		return nil
+	}
+	t := parseTime(s.String)
+	return &t
+}
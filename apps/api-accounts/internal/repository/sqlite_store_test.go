@@ -0,0 +1,644 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+const testUsersJSON = `[
+  {"id": "user-001", "email": "demo@accountstack.com", "name": "Demo User", "firstName": "Demo", "lastName": "User", "country": "US", "createdAt": "2024-01-15T10:00:00Z", "lastLogin": "2024-12-13T08:30:00Z"},
+  {"id": "user-002", "email": "sarah.chen@accountstack.com", "name": "Sarah Chen", "firstName": "Sarah", "lastName": "Chen", "country": "UK", "createdAt": "2023-06-20T14:22:00Z", "lastLogin": "2024-12-12T18:45:00Z"}
+]`
+
+const testAccountsJSON = `[
+  {"id": "acc-001", "userId": "user-001", "accountNumber": "****1234", "accountType": "checking", "accountName": "Personal Checking", "balance": 5847.32, "currency": "USD", "status": "active", "openedDate": "2023-03-15T00:00:00Z", "lastActivity": "2024-12-12T15:30:00Z"},
+  {"id": "acc-003", "userId": "user-001", "accountNumber": "****9012", "accountType": "credit", "accountName": "Rewards Credit Card", "balance": -2134.56, "currency": "USD", "creditLimit": 10000.00, "status": "active", "openedDate": "2023-07-22T00:00:00Z", "lastActivity": "2024-12-13T07:20:00Z"},
+  {"id": "acc-004", "userId": "user-002", "accountNumber": "****3456", "accountType": "checking", "accountName": "Business Checking", "balance": 45123.67, "currency": "USD", "status": "active", "openedDate": "2023-06-20T00:00:00Z", "lastActivity": "2024-12-13T06:00:00Z"}
+]`
+
+const testTransactionsJSON = `[
+  {"id": "txn-001", "accountId": "acc-001", "date": "2024-12-13T07:20:00Z", "description": "Starbucks Coffee", "amount": -5.47, "category": "food_dining", "merchant": "Starbucks", "status": "completed", "type": "debit"},
+  {"id": "txn-002", "accountId": "acc-001", "date": "2024-12-01T09:00:00Z", "description": "Payroll Deposit", "amount": 3200.00, "category": "income", "merchant": "Employer", "status": "completed", "type": "credit"},
+  {"id": "txn-003", "accountId": "acc-004", "date": "2024-12-10T12:00:00Z", "description": "Client Payment", "amount": 8500.00, "category": "income", "merchant": "Acme Corp", "status": "completed", "type": "credit"}
+]`
+
+func newTestLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+func writeSeed(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "users.json"), []byte(testUsersJSON), 0o644); err != nil {
+		t.Fatalf("Failed to write users seed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "accounts.json"), []byte(testAccountsJSON), 0o644); err != nil {
+		t.Fatalf("Failed to write accounts seed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "transactions.json"), []byte(testTransactionsJSON), 0o644); err != nil {
+		t.Fatalf("Failed to write transactions seed: %v", err)
+	}
+	return dir
+}
+
+func newTestSQLiteStore(t *testing.T) *SQLiteStore {
+	t.Helper()
+	store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"), writeSeed(t), newTestLogger())
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestNewStoreBackends(t *testing.T) {
+	dataPath := writeSeed(t)
+
+	tests := []struct {
+		name    string
+		backend string
+		wantErr bool
+	}{
+		{"default is json", "", false},
+		{"json", BackendJSON, false},
+		{"sqlite", BackendSQLite, false},
+		{"unknown", "postgres", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := NewStore(Config{
+				Backend:    tt.backend,
+				DataPath:   dataPath,
+				SQLitePath: filepath.Join(t.TempDir(), "test.db"),
+			}, newTestLogger())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewStore() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			defer store.Close()
+
+			user, err := store.GetUserByEmail("demo@accountstack.com")
+			if err != nil {
+				t.Fatalf("GetUserByEmail failed: %v", err)
+			}
+			if user.ID != "user-001" {
+				t.Errorf("Expected user-001, got %s", user.ID)
+			}
+		})
+	}
+}
+
+func TestSQLiteStoreSeedImport(t *testing.T) {
+	store := newTestSQLiteStore(t)
+
+	users, err := store.GetAllUsers()
+	if err != nil {
+		t.Fatalf("GetAllUsers failed: %v", err)
+	}
+	if len(users) != 2 {
+		t.Errorf("Expected 2 users, got %d", len(users))
+	}
+
+	user, err := store.GetUserByID("user-002")
+	if err != nil {
+		t.Fatalf("GetUserByID failed: %v", err)
+	}
+	if user.Country != "UK" || user.CreatedAt.IsZero() {
+		t.Errorf("User not round-tripped correctly: %+v", user)
+	}
+
+	if _, err := store.GetUserByID("user-999"); err == nil {
+		t.Error("Expected error for missing user")
+	}
+}
+
+func TestSQLiteStoreAccounts(t *testing.T) {
+	store := newTestSQLiteStore(t)
+
+	account, err := store.GetAccountByID("acc-003")
+	if err != nil {
+		t.Fatalf("GetAccountByID failed: %v", err)
+	}
+	if account.CreditLimit == nil || *account.CreditLimit != 10000.00 {
+		t.Errorf("Expected credit limit 10000, got %v", account.CreditLimit)
+	}
+	if account.Balance != -2134.56 {
+		t.Errorf("Expected balance -2134.56, got %v", account.Balance)
+	}
+
+	checking, err := store.GetAccountByID("acc-001")
+	if err != nil {
+		t.Fatalf("GetAccountByID failed: %v", err)
+	}
+	if checking.CreditLimit != nil {
+		t.Errorf("Expected nil credit limit, got %v", *checking.CreditLimit)
+	}
+
+	accounts, err := store.GetAccountsByUserID("user-001")
+	if err != nil {
+		t.Fatalf("GetAccountsByUserID failed: %v", err)
+	}
+	if len(accounts) != 2 {
+		t.Errorf("Expected 2 accounts for user-001, got %d", len(accounts))
+	}
+
+	if _, err := store.GetAccountByID("acc-999"); err == nil {
+		t.Error("Expected error for missing account")
+	}
+}
+
+func TestSQLiteStoreReopen(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	dataPath := writeSeed(t)
+
+	first, err := NewSQLiteStore(dbPath, dataPath, newTestLogger())
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	if _, err := first.db.Exec(`UPDATE accounts SET balance = 1.23 WHERE id = 'acc-001'`); err != nil {
+		t.Fatalf("Failed to update balance: %v", err)
+	}
+	first.Close()
+
+	// Reopening must not re-run migrations or overwrite existing rows with seed data
+	second, err := NewSQLiteStore(dbPath, dataPath, newTestLogger())
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	defer second.Close()
+
+	account, err := second.GetAccountByID("acc-001")
+	if err != nil {
+		t.Fatalf("GetAccountByID failed: %v", err)
+	}
+	if account.Balance != 1.23 {
+		t.Errorf("Expected persisted balance 1.23, got %v", account.Balance)
+	}
+}
+
+func TestGetTransactionsByAccountID(t *testing.T) {
+	dataPath := writeSeed(t)
+
+	for _, backend := range []string{BackendJSON, BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			store, err := NewStore(Config{
+				Backend:    backend,
+				DataPath:   dataPath,
+				SQLitePath: filepath.Join(t.TempDir(), "test.db"),
+			}, newTestLogger())
+			if err != nil {
+				t.Fatalf("NewStore failed: %v", err)
+			}
+			defer store.Close()
+
+			txns, err := store.GetTransactionsByAccountID("acc-001")
+			if err != nil {
+				t.Fatalf("GetTransactionsByAccountID failed: %v", err)
+			}
+			if len(txns) != 2 {
+				t.Fatalf("Expected 2 transactions for acc-001, got %d", len(txns))
+			}
+			for _, txn := range txns {
+				if txn.AccountID != "acc-001" || txn.Date.IsZero() {
+					t.Errorf("Unexpected transaction: %+v", txn)
+				}
+			}
+
+			none, err := store.GetTransactionsByAccountID("acc-999")
+			if err != nil || len(none) != 0 {
+				t.Errorf("Expected no transactions for a missing account, got %d (%v)", len(none), err)
+			}
+		})
+	}
+}
+
+func TestSQLiteStoreSharedTransactionsTable(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	// Simulate a file api-transactions has already migrated and written to
+	db, err := NewSQLiteStore(dbPath, writeSeed(t), newTestLogger())
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	if _, err := db.db.Exec(`ALTER TABLE transactions ADD COLUMN transfer_id TEXT;
+		DELETE FROM transactions;
+		INSERT INTO transactions (id, account_id, date, description, amount, category, merchant, status, type, transfer_id)
+		VALUES ('txn-900', 'acc-001', '2024-12-05T00:00:00.000000000Z', 'Transfer', -50, 'transfer', 'AccountStack', 'completed', 'debit', 'trf-1')`); err != nil {
+		t.Fatalf("Failed to prepare shared table: %v", err)
+	}
+	db.Close()
+
+	// Reopening must keep the other service's rows and not seed over them
+	store, err := NewSQLiteStore(dbPath, writeSeed(t), newTestLogger())
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	defer store.Close()
+
+	txns, err := store.GetTransactionsByAccountID("acc-001")
+	if err != nil {
+		t.Fatalf("GetTransactionsByAccountID failed: %v", err)
+	}
+	if len(txns) != 1 || txns[0].ID != "txn-900" {
+		t.Errorf("Expected only the shared txn-900, got %+v", txns)
+	}
+}
+
+func TestCredentialStorage(t *testing.T) {
+	dataPath := writeSeed(t)
+	now := time.Date(2024, 12, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, backend := range []string{BackendJSON, BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			store, err := NewStore(Config{
+				Backend:    backend,
+				DataPath:   dataPath,
+				SQLitePath: filepath.Join(t.TempDir(), "test.db"),
+			}, newTestLogger())
+			if err != nil {
+				t.Fatalf("NewStore failed: %v", err)
+			}
+			defer store.Close()
+
+			user := &models.User{ID: "user-100", Email: "new@example.com", Name: "New User", PasswordHash: "hash-1"}
+			if err := store.CreateUser(user); err != nil {
+				t.Fatalf("CreateUser failed: %v", err)
+			}
+			if err := store.CreateUser(&models.User{ID: "user-101", Email: "new@example.com"}); !errors.Is(err, ErrEmailTaken) {
+				t.Errorf("Expected ErrEmailTaken, got %v", err)
+			}
+
+			if err := store.UpdatePasswordHash("user-100", "hash-2"); err != nil {
+				t.Fatalf("UpdatePasswordHash failed: %v", err)
+			}
+			stored, err := store.GetUserByEmail("new@example.com")
+			if err != nil || stored.PasswordHash != "hash-2" {
+				t.Errorf("Expected the updated hash, got %+v (%v)", stored, err)
+			}
+			if err := store.UpdatePasswordHash("user-999", "hash"); err == nil {
+				t.Error("Expected an error for a missing user")
+			}
+
+			for _, token := range []*models.PasswordResetToken{
+				{TokenHash: "expired", UserID: "user-001", CreatedAt: now.Add(-time.Hour), ExpiresAt: now.Add(-time.Minute)},
+				{TokenHash: "first", UserID: "user-001", CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+				{TokenHash: "second", UserID: "user-001", CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+				{TokenHash: "other-user", UserID: "user-002", CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+			} {
+				if err := store.CreatePasswordResetToken(token); err != nil {
+					t.Fatalf("CreatePasswordResetToken failed: %v", err)
+				}
+			}
+
+			if _, err := store.ConsumePasswordResetToken("expired", now); !errors.Is(err, ErrResetTokenInvalid) {
+				t.Errorf("Expected an expired token to be rejected, got %v", err)
+			}
+			token, err := store.ConsumePasswordResetToken("second", now)
+			if err != nil || token.UserID != "user-001" {
+				t.Fatalf("Expected to consume the token for user-001, got %+v (%v)", token, err)
+			}
+			if _, err := store.ConsumePasswordResetToken("second", now); !errors.Is(err, ErrResetTokenInvalid) {
+				t.Errorf("Expected a used token to be rejected, got %v", err)
+			}
+			if _, err := store.ConsumePasswordResetToken("first", now); !errors.Is(err, ErrResetTokenInvalid) {
+				t.Errorf("Expected the user's other token to be voided, got %v", err)
+			}
+			if _, err := store.ConsumePasswordResetToken("other-user", now); err != nil {
+				t.Errorf("Expected another user's token to stay usable, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRefreshTokenStorage(t *testing.T) {
+	dataPath := writeSeed(t)
+	now := time.Date(2024, 12, 1, 12, 0, 0, 0, time.UTC)
+
+	newToken := func(hash, family, accessID string) *models.RefreshToken {
+		return &models.RefreshToken{
+			TokenHash:       hash,
+			FamilyID:        family,
+			UserID:          "user-001",
+			AccessTokenID:   accessID,
+			AccessExpiresAt: now.Add(15 * time.Minute),
+			CreatedAt:       now,
+			ExpiresAt:       now.Add(time.Hour),
+		}
+	}
+
+	for _, backend := range []string{BackendJSON, BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			store, err := NewStore(Config{
+				Backend:    backend,
+				DataPath:   dataPath,
+				SQLitePath: filepath.Join(t.TempDir(), "test.db"),
+			}, newTestLogger())
+			if err != nil {
+				t.Fatalf("NewStore failed: %v", err)
+			}
+			defer store.Close()
+
+			if err := store.CreateRefreshToken(newToken("a", "family-1", "jti-a")); err != nil {
+				t.Fatalf("CreateRefreshToken failed: %v", err)
+			}
+			if err := store.CreateRefreshToken(newToken("other", "family-2", "jti-other")); err != nil {
+				t.Fatalf("CreateRefreshToken failed: %v", err)
+			}
+
+			if err := store.RotateRefreshToken("a", newToken("b", "family-1", "jti-b"), now); err != nil {
+				t.Fatalf("RotateRefreshToken failed: %v", err)
+			}
+			if err := store.RotateRefreshToken("a", newToken("c", "family-1", "jti-c"), now); !errors.Is(err, ErrRefreshTokenUsed) {
+				t.Errorf("Expected ErrRefreshTokenUsed rotating a used token, got %v", err)
+			}
+			if err := store.RotateRefreshToken("missing", newToken("d", "family-1", "jti-d"), now); !errors.Is(err, ErrRefreshTokenNotFound) {
+				t.Errorf("Expected ErrRefreshTokenNotFound, got %v", err)
+			}
+			if _, err := store.GetRefreshToken("c"); !errors.Is(err, ErrRefreshTokenNotFound) {
+				t.Errorf("Expected a failed rotation not to store its token, got %v", err)
+			}
+
+			used, err := store.GetRefreshToken("a")
+			if err != nil || used.UsedAt == nil || used.Usable(now) {
+				t.Errorf("Expected token a to be used, got %+v (%v)", used, err)
+			}
+			current, err := store.GetRefreshTokenByAccessTokenID("jti-b")
+			if err != nil || current.TokenHash != "b" || !current.Usable(now) || !current.AccessExpiresAt.Equal(now.Add(15*time.Minute)) {
+				t.Errorf("Expected usable token b, got %+v (%v)", current, err)
+			}
+
+			family, err := store.RevokeRefreshTokenFamily("family-1", now)
+			if err != nil {
+				t.Fatalf("RevokeRefreshTokenFamily failed: %v", err)
+			}
+			if len(family) != 2 {
+				t.Fatalf("Expected 2 tokens in the family, got %d", len(family))
+			}
+			for _, token := range family {
+				if token.RevokedAt == nil {
+					t.Errorf("Expected token %s to be revoked", token.TokenHash)
+				}
+			}
+			if err := store.RotateRefreshToken("b", newToken("e", "family-1", "jti-e"), now); !errors.Is(err, ErrRefreshTokenUsed) {
+				t.Errorf("Expected a revoked token not to rotate, got %v", err)
+			}
+
+			other, err := store.GetRefreshToken("other")
+			if err != nil || !other.Usable(now) {
+				t.Errorf("Expected another family to be unaffected, got %+v (%v)", other, err)
+			}
+		})
+	}
+}
+
+func TestMFAStorage(t *testing.T) {
+	dataPath := writeSeed(t)
+	now := time.Date(2024, 12, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, backend := range []string{BackendJSON, BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			store, err := NewStore(Config{
+				Backend:    backend,
+				DataPath:   dataPath,
+				SQLitePath: filepath.Join(t.TempDir(), "test.db"),
+			}, newTestLogger())
+			if err != nil {
+				t.Fatalf("NewStore failed: %v", err)
+			}
+			defer store.Close()
+
+			if _, err := store.GetTOTPEnrollment("user-001"); !errors.Is(err, ErrTOTPNotEnrolled) {
+				t.Errorf("Expected ErrTOTPNotEnrolled, got %v", err)
+			}
+
+			// Unconfirmed enrollments can be replaced
+			for _, secret := range []string{"FIRST", "SECOND"} {
+				if err := store.SaveTOTPEnrollment(&models.TOTPEnrollment{UserID: "user-001", Secret: secret, CreatedAt: now}); err != nil {
+					t.Fatalf("SaveTOTPEnrollment failed: %v", err)
+				}
+			}
+			codes := []*models.RecoveryCode{
+				{CodeHash: "code-1", UserID: "user-001", CreatedAt: now},
+				{CodeHash: "code-2", UserID: "user-001", CreatedAt: now},
+			}
+			if err := store.ConfirmTOTPEnrollment("user-001", 100, codes, now); err != nil {
+				t.Fatalf("ConfirmTOTPEnrollment failed: %v", err)
+			}
+			enrollment, err := store.GetTOTPEnrollment("user-001")
+			if err != nil || enrollment.Secret != "SECOND" || !enrollment.Enabled() || enrollment.LastUsedStep != 100 {
+				t.Fatalf("Expected the confirmed second enrollment, got %+v (%v)", enrollment, err)
+			}
+			if err := store.SaveTOTPEnrollment(&models.TOTPEnrollment{UserID: "user-001", Secret: "THIRD", CreatedAt: now}); !errors.Is(err, ErrTOTPAlreadyEnabled) {
+				t.Errorf("Expected ErrTOTPAlreadyEnabled, got %v", err)
+			}
+
+			// Steps only move forward
+			if err := store.UseTOTPStep("user-001", 100); !errors.Is(err, ErrTOTPStepUsed) {
+				t.Errorf("Expected ErrTOTPStepUsed for a used step, got %v", err)
+			}
+			if err := store.UseTOTPStep("user-001", 101); err != nil {
+				t.Errorf("UseTOTPStep failed: %v", err)
+			}
+
+			if err := store.ConsumeRecoveryCode("user-001", "code-1", now); err != nil {
+				t.Fatalf("ConsumeRecoveryCode failed: %v", err)
+			}
+			if err := store.ConsumeRecoveryCode("user-001", "code-1", now); !errors.Is(err, ErrRecoveryCodeInvalid) {
+				t.Errorf("Expected a used code to be rejected, got %v", err)
+			}
+			if err := store.ConsumeRecoveryCode("user-002", "code-2", now); !errors.Is(err, ErrRecoveryCodeInvalid) {
+				t.Errorf("Expected another user's code to be rejected, got %v", err)
+			}
+			if count, err := store.CountRecoveryCodes("user-001"); err != nil || count != 1 {
+				t.Errorf("Expected 1 unused recovery code, got %d (%v)", count, err)
+			}
+
+			if err := store.DeleteTOTPEnrollment("user-001"); err != nil {
+				t.Fatalf("DeleteTOTPEnrollment failed: %v", err)
+			}
+			if count, err := store.CountRecoveryCodes("user-001"); err != nil || count != 0 {
+				t.Errorf("Expected recovery codes to be deleted, got %d (%v)", count, err)
+			}
+
+			if err := store.CreateMFAChallenge(&models.MFAChallenge{
+				TokenHash: "challenge", UserID: "user-002", CreatedAt: now, ExpiresAt: now.Add(5 * time.Minute),
+			}); err != nil {
+				t.Fatalf("CreateMFAChallenge failed: %v", err)
+			}
+			if attempts, err := store.RecordMFAChallengeFailure("challenge"); err != nil || attempts != 1 {
+				t.Errorf("Expected 1 failed attempt, got %d (%v)", attempts, err)
+			}
+			if err := store.ConsumeMFAChallenge("challenge", now.Add(6*time.Minute)); !errors.Is(err, ErrMFAChallengeInvalid) {
+				t.Errorf("Expected an expired challenge to be rejected, got %v", err)
+			}
+			if err := store.ConsumeMFAChallenge("challenge", now); err != nil {
+				t.Fatalf("ConsumeMFAChallenge failed: %v", err)
+			}
+			if err := store.ConsumeMFAChallenge("challenge", now); !errors.Is(err, ErrMFAChallengeInvalid) {
+				t.Errorf("Expected a used challenge to be rejected, got %v", err)
+			}
+			challenge, err := store.GetMFAChallenge("challenge")
+			if err != nil || challenge.Attempts != 1 || challenge.UsedAt == nil {
+				t.Errorf("Unexpected challenge %+v (%v)", challenge, err)
+			}
+		})
+	}
+}
+
+func TestRolesAndAuditLog(t *testing.T) {
+	dataPath := writeSeed(t)
+	now := time.Date(2024, 12, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, backend := range []string{BackendJSON, BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			store, err := NewStore(Config{
+				Backend:    backend,
+				DataPath:   dataPath,
+				SQLitePath: filepath.Join(t.TempDir(), "test.db"),
+			}, newTestLogger())
+			if err != nil {
+				t.Fatalf("NewStore failed: %v", err)
+			}
+			defer store.Close()
+
+			// Seeded users without a role are customers
+			user, err := store.GetUserByID("user-002")
+			if err != nil {
+				t.Fatalf("GetUserByID failed: %v", err)
+			}
+			if user.Role != models.RoleUser {
+				t.Errorf("Expected the user role, got %q", user.Role)
+			}
+
+			if err := store.UpdateUserRole("user-002", models.RoleSupport); err != nil {
+				t.Fatalf("UpdateUserRole failed: %v", err)
+			}
+			if user, _ := store.GetUserByEmail("sarah.chen@accountstack.com"); user.Role != models.RoleSupport {
+				t.Errorf("Expected the support role, got %q", user.Role)
+			}
+			if err := store.UpdateUserRole("user-999", models.RoleAdmin); err == nil {
+				t.Error("Expected an error for a missing user")
+			}
+
+			for i, action := range []string{models.AuditRoleChange, models.AuditImpersonate, models.AuditImpersonate} {
+				if err := store.CreateAuditEntry(&models.AuditEntry{
+					ID:           fmt.Sprintf("audit-%d", i),
+					ActorID:      "user-002",
+					Action:       action,
+					TargetUserID: "user-001",
+					CreatedAt:    now,
+				}); err != nil {
+					t.Fatalf("CreateAuditEntry failed: %v", err)
+				}
+			}
+			entries, err := store.ListAuditEntries(2)
+			if err != nil {
+				t.Fatalf("ListAuditEntries failed: %v", err)
+			}
+			if len(entries) != 2 || entries[0].ID != "audit-2" || entries[1].ID != "audit-1" {
+				t.Fatalf("Expected the two newest entries first, got %+v", entries)
+			}
+			if !entries[0].CreatedAt.Equal(now) || entries[0].Action != models.AuditImpersonate {
+				t.Errorf("Entry not round-tripped correctly: %+v", entries[0])
+			}
+		})
+	}
+}
+
+func TestAccountInvitationsAndGrants(t *testing.T) {
+	dataPath := writeSeed(t)
+	now := time.Date(2024, 12, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, backend := range []string{BackendJSON, BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			store, err := NewStore(Config{
+				Backend:    backend,
+				DataPath:   dataPath,
+				SQLitePath: filepath.Join(t.TempDir(), "test.db"),
+			}, newTestLogger())
+			if err != nil {
+				t.Fatalf("NewStore failed: %v", err)
+			}
+			defer store.Close()
+
+			for _, id := range []string{"inv-1", "inv-2"} {
+				if err := store.CreateAccountInvitation(&models.AccountInvitation{
+					ID:        id,
+					AccountID: "acc-001",
+					Email:     "sarah.chen@accountstack.com",
+					Access:    models.AccessViewer,
+					InvitedBy: "user-001",
+					Status:    models.InvitationPending,
+					CreatedAt: now,
+					ExpiresAt: now.Add(time.Hour),
+				}); err != nil {
+					t.Fatalf("CreateAccountInvitation failed: %v", err)
+				}
+			}
+			grant := &models.AccountGrant{AccountID: "acc-001", UserID: "user-002", Access: models.AccessViewer, GrantedBy: "user-001", CreatedAt: now}
+
+			// Expired invitations cannot be accepted
+			if err := store.AcceptAccountInvitation("inv-1", grant, now.Add(2*time.Hour)); !errors.Is(err, ErrInvitationClosed) {
+				t.Errorf("Expected an expired invitation to be closed, got %v", err)
+			}
+			if err := store.AcceptAccountInvitation("inv-1", grant, now); err != nil {
+				t.Fatalf("AcceptAccountInvitation failed: %v", err)
+			}
+			if err := store.AcceptAccountInvitation("inv-1", grant, now); !errors.Is(err, ErrInvitationClosed) {
+				t.Errorf("Expected an accepted invitation to be closed, got %v", err)
+			}
+			if err := store.AcceptAccountInvitation("inv-9", grant, now); !errors.Is(err, ErrInvitationNotFound) {
+				t.Errorf("Expected an unknown invitation to be missing, got %v", err)
+			}
+
+			if err := store.CloseAccountInvitation("inv-2", models.InvitationDeclined, now); err != nil {
+				t.Fatalf("CloseAccountInvitation failed: %v", err)
+			}
+			if err := store.CloseAccountInvitation("inv-2", models.InvitationCancelled, now); !errors.Is(err, ErrInvitationClosed) {
+				t.Errorf("Expected a declined invitation to be closed, got %v", err)
+			}
+			invitations, err := store.GetAccountInvitationsByEmail("sarah.chen@accountstack.com")
+			if err != nil {
+				t.Fatalf("GetAccountInvitationsByEmail failed: %v", err)
+			}
+			if len(invitations) != 2 || invitations[0].Status != models.InvitationAccepted ||
+				invitations[1].Status != models.InvitationDeclined || invitations[1].RespondedAt == nil {
+				t.Errorf("Unexpected invitations: %+v", invitations)
+			}
+
+			stored, err := store.GetAccountGrant("acc-001", "user-002")
+			if err != nil {
+				t.Fatalf("GetAccountGrant failed: %v", err)
+			}
+			if stored.Access != models.AccessViewer || !stored.CreatedAt.Equal(now) {
+				t.Errorf("Grant not round-tripped correctly: %+v", stored)
+			}
+			if grants, _ := store.GetAccountGrantsByUserID("user-002"); len(grants) != 1 || grants[0].AccountID != "acc-001" {
+				t.Errorf("Expected one grant for user-002, got %+v", grants)
+			}
+
+			if err := store.DeleteAccountGrant("acc-001", "user-002"); err != nil {
+				t.Fatalf("DeleteAccountGrant failed: %v", err)
+			}
+			if _, err := store.GetAccountGrant("acc-001", "user-002"); !errors.Is(err, ErrGrantNotFound) {
+				t.Errorf("Expected the grant to be gone, got %v", err)
+			}
+			if err := store.DeleteAccountGrant("acc-001", "user-002"); !errors.Is(err, ErrGrantNotFound) {
+				t.Errorf("Expected ErrGrantNotFound deleting twice, got %v", err)
+			}
+		})
+	}
+}
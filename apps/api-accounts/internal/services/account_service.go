@@ -1,7 +1,7 @@
 package services
 
 import (
-	"fmt"
+	"errors"
 
 	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/features"
 	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/models"
@@ -9,15 +9,25 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+var (
+	// ErrAccountNotFound is returned for an account that does not exist
+	ErrAccountNotFound = errors.New("account not found")
+
+	// ErrAccountAccessDenied is returned when a user neither owns an account
+	// nor was granted access to it. It is answered like ErrAccountNotFound so
+	// that other users' accounts cannot be told apart from missing ones.
+	ErrAccountAccessDenied = errors.New("you do not have access to this account")
+)
+
 // AccountService handles business logic for accounts
 type AccountService struct {
-	repo   *repository.Repository
+	repo   repository.Store
 	flags  *features.Flags
 	logger *logrus.Logger
 }
 
 // NewAccountService creates a new account service
-func NewAccountService(repo *repository.Repository, flags *features.Flags, logger *logrus.Logger) *AccountService {
+func NewAccountService(repo repository.Store, flags *features.Flags, logger *logrus.Logger) *AccountService {
 	return &AccountService{
 		repo:   repo,
 		flags:  flags,
@@ -25,27 +35,14 @@ func NewAccountService(repo *repository.Repository, flags *features.Flags, logge
 	}
 }
 
-// GetAccountByID retrieves an account by ID and applies masking if needed
+// GetAccountByID retrieves an account the user owns or was granted access to
+// and applies masking if needed
 func (s *AccountService) GetAccountByID(accountID string, userID string) (*models.AccountResponse, error) {
-	account, err := s.repo.GetAccountByID(accountID)
+	account, access, err := accessibleAccount(s.repo, s.logger, accountID, userID)
 	if err != nil {
-		s.logger.WithFields(logrus.Fields{
-			"accountId": accountID,
-			"userId":    userID,
-		}).Warn("Account not found")
 		return nil, err
 	}
 
-	// Verify the account belongs to the requesting user
-	if account.UserID != userID {
-		s.logger.WithFields(logrus.Fields{
-			"accountId": accountID,
-			"userId":    userID,
-			"ownerId":   account.UserID,
-		}).Warn("Unauthorized access attempt")
-		return nil, fmt.Errorf("unauthorized")
-	}
-
 	// Get user to determine currency based on country
 	user, err := s.repo.GetUserByID(userID)
 	if err != nil {
@@ -65,16 +62,37 @@ func (s *AccountService) GetAccountByID(accountID string, userID string) (*model
 	}).Debug("Retrieving account")
 
 	response := account.ToResponse(maskAmounts, currency)
+	response.Access = access
 	return &response, nil
 }
 
-// GetAccountsByUserID retrieves all accounts for a user with optional masking
+// GetAccountsByUserID retrieves all accounts for a user with optional masking:
+// the accounts they own, followed by the accounts shared with them
 func (s *AccountService) GetAccountsByUserID(userID string) ([]models.AccountResponse, error) {
 	accounts, err := s.repo.GetAccountsByUserID(userID)
 	if err != nil {
 		s.logger.WithField("userId", userID).Error("Failed to retrieve accounts")
 		return nil, err
 	}
+	access := make([]string, len(accounts))
+	for i := range accounts {
+		access[i] = models.AccessOwner
+	}
+
+	grants, err := s.repo.GetAccountGrantsByUserID(userID)
+	if err != nil {
+		s.logger.WithField("userId", userID).Error("Failed to retrieve shared accounts")
+		return nil, err
+	}
+	for _, grant := range grants {
+		account, err := s.repo.GetAccountByID(grant.AccountID)
+		if err != nil {
+			s.logger.WithField("accountId", grant.AccountID).Warn("Shared account not found")
+			continue
+		}
+		accounts = append(accounts, account)
+		access = append(access, grant.Access)
+	}
 
 	// Get user to determine currency based on country
 	user, err := s.repo.GetUserByID(userID)
@@ -97,7 +115,45 @@ func (s *AccountService) GetAccountsByUserID(userID string) ([]models.AccountRes
 	responses := make([]models.AccountResponse, len(accounts))
 	for i, account := range accounts {
 		responses[i] = account.ToResponse(maskAmounts, currency)
+		responses[i].Access = access[i]
 	}
 
 	return responses, nil
 }
+
+// accessibleAccount retrieves an account together with the requesting user's
+// access to it: owner, or the access they were granted. Users with neither get
+// ErrAccountAccessDenied.
+func accessibleAccount(repo repository.Store, logger *logrus.Logger, accountID string, userID string) (*models.Account, string, error) {
+	account, err := repo.GetAccountByID(accountID)
+	if errors.Is(err, repository.ErrAccountNotFound) {
+		logger.WithFields(logrus.Fields{
+			"accountId": accountID,
+			"userId":    userID,
+		}).Warn("Account not found")
+		return nil, "", ErrAccountNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	if account.UserID == userID {
+		return account, models.AccessOwner, nil
+	}
+
+	// Otherwise the user needs a grant from the owner
+	grant, err := repo.GetAccountGrant(accountID, userID)
+	if err != nil {
+		if !errors.Is(err, repository.ErrGrantNotFound) {
+			return nil, "", err
+		}
+		logger.WithFields(logrus.Fields{
+			"accountId": accountID,
+			"userId":    userID,
+			"ownerId":   account.UserID,
+		}).Warn("Unauthorized access attempt")
+		return nil, "", ErrAccountAccessDenied
+	}
+
+	return account, grant.Access, nil
+}
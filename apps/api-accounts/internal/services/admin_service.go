@@ -0,0 +1,247 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/models"
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/repository"
+	"github.com/CB-AccountStack/AccountStack/pkg/auth"
+	"github.com/sirupsen/logrus"
+)
+
+// maxImpersonationTTL caps how long an impersonation token lasts, whatever the
+// access token lifetime is
+const maxImpersonationTTL = 15 * time.Minute
+
+var (
+	// ErrUserNotFound is returned when the target of an admin action does not exist
+	ErrUserNotFound = errors.New("user not found")
+
+	// ErrInvalidRole is returned when assigning a role that does not exist
+	ErrInvalidRole = errors.New("role must be user, support or admin")
+
+	// ErrOwnRole is returned when an admin tries to change their own role, which
+	// could leave no admin able to undo it
+	ErrOwnRole = errors.New("you cannot change your own role")
+
+	// ErrReasonRequired is returned when impersonating without saying why
+	ErrReasonRequired = errors.New("a reason is required")
+
+	// ErrImpersonationNotAllowed is returned when impersonating a support or
+	// admin user, or oneself
+	ErrImpersonationNotAllowed = errors.New("only other customers can be impersonated")
+)
+
+// Impersonation is a read-only access token that lets a support user see the
+// service as a customer sees it
+type Impersonation struct {
+	AccessToken string
+	ExpiresAt   time.Time
+	User        *models.User
+}
+
+// AdminService handles the support and admin operations: looking up any user
+// and their accounts, changing roles and impersonating customers. Role changes
+// and impersonations are written to the audit log.
+type AdminService struct {
+	repo       repository.Store
+	accounts   *AccountService
+	tokens     *TokenService
+	jwtManager *auth.JWTManager
+	logger     *logrus.Logger
+	now        func() time.Time
+}
+
+// NewAdminService creates a new admin service
+func NewAdminService(repo repository.Store, accounts *AccountService, tokens *TokenService, jwtManager *auth.JWTManager, logger *logrus.Logger) *AdminService {
+	return &AdminService{
+		repo:       repo,
+		accounts:   accounts,
+		tokens:     tokens,
+		jwtManager: jwtManager,
+		logger:     logger,
+		now:        time.Now,
+	}
+}
+
+// ListUsers returns every user ordered by ID
+func (s *AdminService) ListUsers() ([]*models.User, error) {
+	users, err := s.repo.GetAllUsers()
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to list users")
+		return nil, err
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users, nil
+}
+
+// GetUser returns any user
+func (s *AdminService) GetUser(userID string) (*models.User, error) {
+	user, err := s.repo.GetUserByID(userID)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// GetUserAccounts returns any user's accounts as the user would see them
+func (s *AdminService) GetUserAccounts(userID string) ([]models.AccountResponse, error) {
+	if _, err := s.GetUser(userID); err != nil {
+		return nil, err
+	}
+	return s.accounts.GetAccountsByUserID(userID)
+}
+
+// GetAccount returns any account as its owner would see it
+func (s *AdminService) GetAccount(accountID string) (*models.AccountResponse, error) {
+	account, err := s.repo.GetAccountByID(accountID)
+	if errors.Is(err, repository.ErrAccountNotFound) {
+		return nil, ErrAccountNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.accounts.GetAccountByID(accountID, account.UserID)
+}
+
+// ChangeRole gives a user a new role. A role with more scopes takes effect in
+// the user's access tokens from their next sign-in or refresh. A role that
+// loses any scope signs the user out everywhere, so no token they hold keeps
+// the scopes taken away.
+func (s *AdminService) ChangeRole(actor *auth.Claims, userID, role string) (*models.User, error) {
+	if !models.ValidRole(role) {
+		return nil, ErrInvalidRole
+	}
+	if userID == actor.UserID {
+		return nil, ErrOwnRole
+	}
+	user, err := s.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role == role {
+		return user, nil
+	}
+
+	if err := s.repo.UpdateUserRole(userID, role); err != nil {
+		s.logger.WithError(err).WithField("userId", userID).Error("Failed to change role")
+		return nil, err
+	}
+	if err := s.audit(actor, models.AuditRoleChange, userID, user.Role+" -> "+role, ""); err != nil {
+		return nil, err
+	}
+	if losesScopes(user.Role, role) {
+		if err := s.tokens.RevokeAllForUser(userID); err != nil {
+			return nil, err
+		}
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"actorId": actor.UserID,
+		"userId":  userID,
+		"role":    role,
+	}).Info("User role changed")
+
+	updated := *user
+	updated.Role = role
+	return &updated, nil
+}
+
+// Impersonate issues a token for a customer carrying only read scopes and an
+// act claim naming the actor. There is no refresh token; once it expires the
+// actor has to impersonate again, which is audited again.
+func (s *AdminService) Impersonate(actor *auth.Claims, userID, reason string) (*Impersonation, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrReasonRequired
+	}
+	user, err := s.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.ID == actor.UserID || user.Role != models.RoleUser {
+		return nil, ErrImpersonationNotAllowed
+	}
+
+	ttl := s.jwtManager.TokenDuration()
+	if ttl > maxImpersonationTTL {
+		ttl = maxImpersonationTTL
+	}
+	claims := auth.NewClaims(user.ID, user.Email, user.Role)
+	claims.Scope = strings.Join(auth.ReadOnlyScopes, " ")
+	claims.Act = &auth.Actor{UserID: actor.UserID, Email: actor.Email}
+
+	token, issued, err := s.jwtManager.Issue(claims, ttl)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.audit(actor, models.AuditImpersonate, user.ID, reason, issued.ID); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"actorId": actor.UserID,
+		"userId":  user.ID,
+		"tokenId": issued.ID,
+	}).Warn("Impersonation token issued")
+
+	return &Impersonation{
+		AccessToken: token,
+		ExpiresAt:   issued.ExpiresAt.Time,
+		User:        user,
+	}, nil
+}
+
+// AuditLog returns up to limit audit entries, newest first
+func (s *AdminService) AuditLog(limit int) ([]*models.AuditEntry, error) {
+	entries, err := s.repo.ListAuditEntries(limit)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to read audit log")
+		return nil, err
+	}
+	return entries, nil
+}
+
+// losesScopes reports whether changing from one role to another takes away
+// any scope
+func losesScopes(from, to string) bool {
+	kept := make(map[string]bool)
+	for _, scope := range auth.ScopesForRole(to) {
+		kept[scope] = true
+	}
+	for _, scope := range auth.ScopesForRole(from) {
+		if !kept[scope] {
+			return true
+		}
+	}
+	return false
+}
+
+// audit records an action taken by actor. The action is refused if it cannot
+// be recorded.
+func (s *AdminService) audit(actor *auth.Claims, action, targetUserID, detail, tokenID string) error {
+	id, err := newID("audit")
+	if err != nil {
+		return err
+	}
+	entry := &models.AuditEntry{
+		ID:           id,
+		ActorID:      actor.UserID,
+		Action:       action,
+		TargetUserID: targetUserID,
+		Detail:       detail,
+		TokenID:      tokenID,
+		CreatedAt:    s.now().UTC(),
+	}
+	if err := s.repo.CreateAuditEntry(entry); err != nil {
+		s.logger.WithError(err).WithField("action", action).Error("Failed to write audit entry")
+		return err
+	}
+	return nil
+}
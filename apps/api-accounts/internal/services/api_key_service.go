@@ -0,0 +1,198 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/pkg/auth"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// maxAPIKeyNameLength caps the label a user gives a key
+	maxAPIKeyNameLength = 100
+
+	// maxAPIKeys is how many keys a user can hold at once
+	maxAPIKeys = 20
+
+	// apiKeyPrefixLength is how much of a key is kept in the clear to tell
+	// keys apart, including auth.APIKeyPrefix
+	apiKeyPrefixLength = 12
+)
+
+var (
+	// ErrInvalidAPIKeyName is returned when a key is created without a name
+	// or with a name that is too long
+	ErrInvalidAPIKeyName = errors.New("name must be between 1 and 100 characters")
+
+	// ErrInvalidAPIKeyScope is returned when a key asks for a scope the
+	// caller does not hold or that keys cannot carry
+	ErrInvalidAPIKeyScope = errors.New("scopes must be accounts, transactions or insights scopes you hold")
+
+	// ErrInvalidAPIKeyExpiry is returned when a key would expire in the past
+	ErrInvalidAPIKeyExpiry = errors.New("expiresAt must be in the future")
+
+	// ErrTooManyAPIKeys is returned when the user already holds maxAPIKeys keys
+	ErrTooManyAPIKeys = errors.New("you have too many API keys, revoke one first")
+
+	// ErrAPIKeyNotFound is returned when revoking a key the user does not have
+	ErrAPIKeyNotFound = errors.New("api key not found")
+)
+
+// CreatedAPIKey is a new API key together with the key itself, which is only
+// returned this once
+type CreatedAPIKey struct {
+	*auth.APIKey
+	Key string `json:"key"`
+}
+
+// APIKeyService manages personal API keys, which let scripts and integrations
+// call every service without signing in
+type APIKeyService struct {
+	keys   auth.APIKeyStore
+	logger *logrus.Logger
+	now    func() time.Time
+}
+
+// NewAPIKeyService creates a new API key service
+func NewAPIKeyService(keys auth.APIKeyStore, logger *logrus.Logger) *APIKeyService {
+	return &APIKeyService{
+		keys:   keys,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Create issues a key for the caller. scopes defaults to every scope the
+// caller holds that a key can carry; an explicit list must be a subset of
+// those. A key without expiresAt lasts until it is revoked.
+func (s *APIKeyService) Create(caller *auth.Claims, name string, scopes []string, expiresAt *time.Time) (*CreatedAPIKey, error) {
+	// A key must not be able to mint keys that outlive it or carry more scopes
+	if err := CheckSession(caller); err != nil {
+		return nil, err
+	}
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxAPIKeyNameLength {
+		return nil, ErrInvalidAPIKeyName
+	}
+	scope, err := apiKeyScope(caller, scopes)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now().UTC()
+	if expiresAt != nil {
+		if !expiresAt.After(now) {
+			return nil, ErrInvalidAPIKeyExpiry
+		}
+		utc := expiresAt.UTC()
+		expiresAt = &utc
+	}
+
+	existing, err := s.keys.List(caller.UserID)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to list API keys")
+		return nil, err
+	}
+	if len(existing) >= maxAPIKeys {
+		return nil, ErrTooManyAPIKeys
+	}
+
+	id, err := newID("key")
+	if err != nil {
+		return nil, err
+	}
+	secret, err := newSecretToken()
+	if err != nil {
+		return nil, err
+	}
+	key := auth.APIKeyPrefix + secret
+	apiKey := &auth.APIKey{
+		ID:        id,
+		UserID:    caller.UserID,
+		Name:      name,
+		Prefix:    key[:apiKeyPrefixLength],
+		KeyHash:   auth.HashAPIKey(key),
+		Scope:     scope,
+		CreatedAt: now,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.keys.Create(apiKey); err != nil {
+		s.logger.WithError(err).WithField("userId", caller.UserID).Error("Failed to store API key")
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"userId":   caller.UserID,
+		"apiKeyId": id,
+		"scope":    scope,
+	}).Info("API key created")
+
+	return &CreatedAPIKey{APIKey: apiKey, Key: key}, nil
+}
+
+// List returns the user's keys, oldest first, without the keys themselves
+func (s *APIKeyService) List(userID string) ([]*auth.APIKey, error) {
+	keys, err := s.keys.List(userID)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to list API keys")
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Revoke deletes one of the user's keys. Requests made with it are refused
+// from then on by every service sharing the store.
+func (s *APIKeyService) Revoke(userID, keyID string) error {
+	if err := s.keys.Revoke(userID, keyID); err != nil {
+		if errors.Is(err, auth.ErrAPIKeyNotFound) {
+			return ErrAPIKeyNotFound
+		}
+		s.logger.WithError(err).WithField("apiKeyId", keyID).Error("Failed to revoke API key")
+		return err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"userId":   userID,
+		"apiKeyId": keyID,
+	}).Info("API key revoked")
+	return nil
+}
+
+// apiKeyScope checks the requested scopes against what the caller holds and
+// a key can carry, and returns them space-separated
+func apiKeyScope(caller *auth.Claims, scopes []string) (string, error) {
+	var allowed []string
+	for _, scope := range auth.APIKeyScopes() {
+		if caller.HasScope(scope) {
+			allowed = append(allowed, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		if len(allowed) == 0 {
+			return "", ErrInvalidAPIKeyScope
+		}
+		return strings.Join(allowed, " "), nil
+	}
+
+	var granted []string
+	seen := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		if seen[scope] {
+			continue
+		}
+		seen[scope] = true
+		ok := false
+		for _, a := range allowed {
+			if a == scope {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return "", ErrInvalidAPIKeyScope
+		}
+		granted = append(granted, scope)
+	}
+	return strings.Join(granted, " "), nil
+}
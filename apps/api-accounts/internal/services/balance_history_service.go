@@ -0,0 +1,209 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/features"
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/models"
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// maxHistoryDays caps the range of a balance history request
+	maxHistoryDays = 5 * 366
+
+	// historyCacheTTL bounds how long a computed series is reused. Entries are
+	// also keyed on the account's balance and last activity, so a new posting
+	// invalidates them immediately; the TTL covers status changes that do not
+	// move the balance.
+	historyCacheTTL = 5 * time.Minute
+
+	// historyCacheSize caps the number of cached series
+	historyCacheSize = 1000
+)
+
+var (
+	// ErrInvalidInterval is returned for an interval other than day, week or month
+	ErrInvalidInterval = errors.New("interval must be day, week or month")
+
+	// ErrInvalidRange is returned when from is after to or the range is too long
+	ErrInvalidRange = fmt.Errorf("from must be on or before to, and at most %d days earlier", maxHistoryDays)
+)
+
+// BalanceHistoryRequest selects a balance history series. Zero dates take
+// the defaults for the interval.
+type BalanceHistoryRequest struct {
+	From     time.Time
+	To       time.Time
+	Interval string
+}
+
+// BalanceHistoryService reconstructs historical balances from the transactions data
+type BalanceHistoryService struct {
+	repo   repository.Store
+	flags  *features.Flags
+	logger *logrus.Logger
+	cache  *seriesCache
+	now    func() time.Time
+}
+
+// NewBalanceHistoryService creates a new balance history service
+func NewBalanceHistoryService(repo repository.Store, flags *features.Flags, logger *logrus.Logger) *BalanceHistoryService {
+	return &BalanceHistoryService{
+		repo:   repo,
+		flags:  flags,
+		logger: logger,
+		cache:  newSeriesCache(historyCacheTTL, historyCacheSize),
+		now:    time.Now,
+	}
+}
+
+// GetBalanceHistory returns the end-of-day balance series for an account.
+// The range is clamped to today and to the first day the account has a balance.
+func (s *BalanceHistoryService) GetBalanceHistory(accountID string, userID string, req *BalanceHistoryRequest) (*models.BalanceHistoryResponse, error) {
+	interval := req.Interval
+	if interval == "" {
+		interval = models.IntervalDay
+	}
+	if !models.ValidInterval(interval) {
+		return nil, ErrInvalidInterval
+	}
+
+	today := models.StartOfDay(s.now())
+	to := models.StartOfDay(req.To)
+	if req.To.IsZero() || to.After(today) {
+		to = today
+	}
+	from := models.StartOfDay(req.From)
+	if req.From.IsZero() {
+		from = defaultHistoryStart(to, interval)
+	}
+	if from.After(to) || to.Sub(from) > maxHistoryDays*24*time.Hour {
+		return nil, ErrInvalidRange
+	}
+
+	account, _, err := accessibleAccount(s.repo, s.logger, accountID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get user to determine currency based on country
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		s.logger.WithField("userId", userID).Warn("User not found")
+		return nil, err
+	}
+
+	key := fmt.Sprintf("%s|%s|%s|%s|%v|%d", accountID, from.Format(models.DateLayout), to.Format(models.DateLayout),
+		interval, account.Balance, account.LastActivity.UnixNano())
+	entry, cached := s.cache.get(key)
+	if !cached {
+		transactions, err := s.repo.GetTransactionsByAccountID(accountID)
+		if err != nil {
+			s.logger.WithError(err).WithField("accountId", accountID).Error("Failed to retrieve transactions")
+			return nil, fmt.Errorf("failed to retrieve transactions: %w", err)
+		}
+
+		entry = &seriesEntry{from: from}
+		if first := models.FirstBalanceDate(account, transactions); first.After(entry.from) {
+			entry.from = first
+		}
+		entry.points = models.BuildBalanceHistory(account, transactions, entry.from, to, interval)
+		s.cache.put(key, entry)
+	}
+
+	maskAmounts := s.flags.ShouldMaskAmounts()
+	currency := s.flags.GetCurrencyForUser(user.Country)
+	s.logger.WithFields(logrus.Fields{
+		"accountId":   accountID,
+		"userId":      userID,
+		"interval":    interval,
+		"points":      len(entry.points),
+		"cached":      cached,
+		"maskAmounts": maskAmounts,
+	}).Debug("Retrieving balance history")
+
+	response := models.ToBalanceHistoryResponse(accountID, currency, interval, entry.from, to, entry.points, maskAmounts)
+	return &response, nil
+}
+
+// defaultHistoryStart returns the default start of a series ending on to:
+// 30 days, 12 weeks or 12 months back
+func defaultHistoryStart(to time.Time, interval string) time.Time {
+	switch interval {
+	case models.IntervalWeek:
+		return to.AddDate(0, 0, -7*12+1)
+	case models.IntervalMonth:
+		return to.AddDate(0, -12, 1)
+	default:
+		return to.AddDate(0, 0, -29)
+	}
+}
+
+// seriesEntry is a computed series and the effective start of its range
+type seriesEntry struct {
+	from    time.Time
+	points  []models.BalancePoint
+	expires time.Time
+}
+
+// seriesCache is a size-bounded in-memory cache of computed balance series
+type seriesCache struct {
+	entries map[string]*seriesEntry
+	ttl     time.Duration
+	size    int
+	mu      sync.Mutex
+	now     func() time.Time
+}
+
+func newSeriesCache(ttl time.Duration, size int) *seriesCache {
+	return &seriesCache{
+		entries: make(map[string]*seriesEntry),
+		ttl:     ttl,
+		size:    size,
+		now:     time.Now,
+	}
+}
+
+// get returns an unexpired entry. Entries are never modified once stored, so
+// callers may read them without holding the lock.
+func (c *seriesCache) get(key string) (*seriesEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, exists := c.entries[key]
+	if !exists || c.now().After(entry.expires) {
+		return nil, false
+	}
+	return entry, true
+}
+
+// put stores an entry, evicting expired entries and then the entry closest to
+// expiry when the cache is full
+func (c *seriesCache) put(key string, entry *seriesEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	entry.expires = now.Add(c.ttl)
+
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= c.size {
+		oldestKey := ""
+		for k, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, k)
+			} else if oldestKey == "" || e.expires.Before(c.entries[oldestKey].expires) {
+				oldestKey = k
+			}
+		}
+		if len(c.entries) >= c.size {
+			delete(c.entries, oldestKey)
+		}
+	}
+
+	c.entries[key] = entry
+}
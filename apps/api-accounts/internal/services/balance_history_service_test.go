@@ -0,0 +1,35 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSeriesCache(t *testing.T) {
+	now := time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)
+	cache := newSeriesCache(time.Minute, 2)
+	cache.now = func() time.Time { return now }
+
+	cache.put("a", &seriesEntry{})
+	now = now.Add(10 * time.Second)
+	cache.put("b", &seriesEntry{})
+
+	// A full cache evicts the entry closest to expiry
+	cache.put("c", &seriesEntry{})
+	if _, ok := cache.get("a"); ok {
+		t.Error("Expected the oldest entry to be evicted")
+	}
+	if _, ok := cache.get("b"); !ok {
+		t.Error("Expected b to be cached")
+	}
+
+	// Entries expire after the TTL
+	now = now.Add(time.Minute + time.Second)
+	if _, ok := cache.get("c"); ok {
+		t.Error("Expected c to have expired")
+	}
+	cache.put("d", &seriesEntry{})
+	if len(cache.entries) != 1 {
+		t.Errorf("Expected expired entries to be dropped when full, got %d entries", len(cache.entries))
+	}
+}
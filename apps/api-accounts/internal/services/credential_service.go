@@ -0,0 +1,301 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/credentials"
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/models"
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/notify"
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// minPasswordLength and maxPasswordLength bound new passwords. bcrypt
+	// ignores everything after 72 bytes, so longer passwords are rejected.
+	minPasswordLength = 8
+	maxPasswordLength = 72
+
+	// passwordResetTTL is how long a password reset token can be redeemed
+	passwordResetTTL = 30 * time.Minute
+)
+
+var (
+	// ErrInvalidCredentials is returned when an email and password do not match
+	ErrInvalidCredentials = errors.New("invalid credentials")
+
+	// ErrWeakPassword is returned when a new password does not meet the length rules
+	ErrWeakPassword = fmt.Errorf("password must be between %d and %d bytes", minPasswordLength, maxPasswordLength)
+
+	// ErrInvalidRegistration is returned when registration details are incomplete or malformed
+	ErrInvalidRegistration = errors.New("invalid registration")
+)
+
+// CredentialConfig configures a CredentialService
+type CredentialConfig struct {
+	SeedPassword string // Password of seeded users that have never set their own
+	ResetURL     string // Page that accepts ?token=; when empty the token is sent on its own
+}
+
+// RegisterRequest holds the details of a new user
+type RegisterRequest struct {
+	Email     string `json:"email"`
+	Password  string `json:"password"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Country   string `json:"country"` // ISO 3166-1 alpha-2, defaults to US
+}
+
+// CredentialService handles registration, sign-in and password management
+type CredentialService struct {
+	repo     repository.Store
+	notifier notify.Notifier
+	seedHash string
+	resetURL string
+	logger   *logrus.Logger
+	now      func() time.Time
+}
+
+// NewCredentialService creates a new credential service
+func NewCredentialService(repo repository.Store, notifier notify.Notifier, cfg CredentialConfig, logger *logrus.Logger) (*CredentialService, error) {
+	seedHash, err := credentials.HashPassword(cfg.SeedPassword)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash seed password: %w", err)
+	}
+
+	return &CredentialService{
+		repo:     repo,
+		notifier: notifier,
+		seedHash: seedHash,
+		resetURL: cfg.ResetURL,
+		logger:   logger,
+		now:      time.Now,
+	}, nil
+}
+
+// Authenticate returns the user with the given email if the password matches.
+// Unknown emails are checked against a stand-in hash, so they take as long to
+// reject as a wrong password and are logged the same way.
+func (s *CredentialService) Authenticate(email, password string) (*models.User, error) {
+	email = normalizeEmail(email)
+	user, err := s.repo.GetUserByEmail(email)
+
+	hash := s.seedHash
+	if err == nil {
+		hash = s.passwordHash(user)
+	}
+	if verifyErr := credentials.VerifyPassword(hash, password); err != nil || verifyErr != nil {
+		s.logger.WithField("username", email).Warn("Sign-in failed")
+		return nil, ErrInvalidCredentials
+	}
+
+	return user, nil
+}
+
+// Register creates a user with their own password
+func (s *CredentialService) Register(req *RegisterRequest) (*models.User, error) {
+	email := normalizeEmail(req.Email)
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return nil, fmt.Errorf("%w: email is not a valid address", ErrInvalidRegistration)
+	}
+	firstName := strings.TrimSpace(req.FirstName)
+	lastName := strings.TrimSpace(req.LastName)
+	if firstName == "" || lastName == "" {
+		return nil, fmt.Errorf("%w: firstName and lastName are required", ErrInvalidRegistration)
+	}
+	country := strings.ToUpper(strings.TrimSpace(req.Country))
+	if country == "" {
+		country = "US"
+	}
+	if len(country) != 2 {
+		return nil, fmt.Errorf("%w: country must be a two-letter ISO 3166-1 code", ErrInvalidRegistration)
+	}
+
+	hash, err := hashNewPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := newID("user")
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now().UTC()
+	user := &models.User{
+		ID:           id,
+		Email:        email,
+		Name:         firstName + " " + lastName,
+		FirstName:    firstName,
+		LastName:     lastName,
+		Country:      country,
+		CreatedAt:    now,
+		LastLogin:    now,
+		Role:         models.RoleUser,
+		PasswordHash: hash,
+	}
+	if err := s.repo.CreateUser(user); err != nil {
+		if !errors.Is(err, repository.ErrEmailTaken) {
+			s.logger.WithError(err).Error("Failed to create user")
+		}
+		return nil, err
+	}
+
+	s.logger.WithField("userId", user.ID).Info("User registered")
+	return user, nil
+}
+
+// ChangePassword replaces the password of a signed-in user after checking the current one
+func (s *CredentialService) ChangePassword(userID, currentPassword, newPassword string) error {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		s.logger.WithField("userId", userID).Warn("User not found")
+		return err
+	}
+
+	if err := credentials.VerifyPassword(s.passwordHash(user), currentPassword); err != nil {
+		s.logger.WithField("userId", userID).Warn("Invalid current password")
+		return ErrInvalidCredentials
+	}
+
+	hash, err := hashNewPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.UpdatePasswordHash(userID, hash); err != nil {
+		s.logger.WithError(err).WithField("userId", userID).Error("Failed to update password")
+		return err
+	}
+
+	s.logger.WithField("userId", userID).Info("Password changed")
+	return nil
+}
+
+// RequestPasswordReset issues a single-use reset token and sends it to the
+// user. It reports success for unknown emails too, so callers cannot use it
+// to find out which addresses are registered.
+func (s *CredentialService) RequestPasswordReset(email string) error {
+	email = normalizeEmail(email)
+	user, err := s.repo.GetUserByEmail(email)
+	if err != nil {
+		s.logger.WithField("username", email).Info("Password reset requested for unknown email")
+		return nil
+	}
+
+	token, err := newSecretToken()
+	if err != nil {
+		return err
+	}
+
+	now := s.now().UTC()
+	if err := s.repo.CreatePasswordResetToken(&models.PasswordResetToken{
+		TokenHash: hashToken(token),
+		UserID:    user.ID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(passwordResetTTL),
+	}); err != nil {
+		s.logger.WithError(err).WithField("userId", user.ID).Error("Failed to store reset token")
+		return err
+	}
+
+	body := fmt.Sprintf("Use this code to reset your AccountStack password within %d minutes: %s",
+		int(passwordResetTTL.Minutes()), token)
+	if s.resetURL != "" {
+		body = fmt.Sprintf("Reset your AccountStack password within %d minutes: %s?token=%s",
+			int(passwordResetTTL.Minutes()), s.resetURL, url.QueryEscape(token))
+	}
+	if err := s.notifier.Send(&notify.Message{
+		To:      user.Email,
+		Subject: "Reset your AccountStack password",
+		Body:    body,
+		SentAt:  now,
+	}); err != nil {
+		// Still report success, a different response would reveal the account exists
+		s.logger.WithError(err).WithField("userId", user.ID).Error("Failed to send password reset")
+		return nil
+	}
+
+	s.logger.WithField("userId", user.ID).Info("Password reset issued")
+	return nil
+}
+
+// ResetPassword redeems a reset token and sets a new password. The token and
+// any other outstanding tokens of the same user can no longer be used. It
+// returns the ID of the user whose password was reset.
+func (s *CredentialService) ResetPassword(token, newPassword string) (string, error) {
+	hash, err := hashNewPassword(newPassword)
+	if err != nil {
+		return "", err
+	}
+
+	reset, err := s.repo.ConsumePasswordResetToken(hashToken(token), s.now().UTC())
+	if err != nil {
+		if errors.Is(err, repository.ErrResetTokenInvalid) {
+			s.logger.Warn("Invalid or expired password reset token")
+		}
+		return "", err
+	}
+
+	if err := s.repo.UpdatePasswordHash(reset.UserID, hash); err != nil {
+		s.logger.WithError(err).WithField("userId", reset.UserID).Error("Failed to update password")
+		return "", err
+	}
+
+	s.logger.WithField("userId", reset.UserID).Info("Password reset completed")
+	return reset.UserID, nil
+}
+
+// passwordHash returns the user's hash, or the shared seed hash for seeded
+// users that have never set a password
+func (s *CredentialService) passwordHash(user *models.User) string {
+	if user.PasswordHash == "" {
+		return s.seedHash
+	}
+	return user.PasswordHash
+}
+
+// hashNewPassword checks a new password against the length rules and hashes it
+func hashNewPassword(password string) (string, error) {
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return "", ErrWeakPassword
+	}
+	return credentials.HashPassword(password)
+}
+
+// newSecretToken generates an opaque, URL-safe token with 256 bits of entropy
+func newSecretToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the stored form of a reset or refresh token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeEmail lower-cases and trims an email address
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// newID generates a random identifier with the given prefix
+func newID(prefix string) (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + "-" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/credentials"
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/events"
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrLoginThrottled is returned when sign-in is refused because of recent failures
+var ErrLoginThrottled = errors.New("too many failed sign-in attempts")
+
+// ThrottlePolicy sets how failed sign-ins for one key are slowed down. The
+// first FreeAttempts failures have no delay; after that each failure doubles
+// the wait before the next attempt, starting at BaseDelay and capped at
+// MaxDelay, until LockoutAfter failures lock the key for LockoutDuration.
+type ThrottlePolicy struct {
+	FreeAttempts    int
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	LockoutAfter    int
+	LockoutDuration time.Duration
+}
+
+// Delay returns how long after the last of failures the next attempt is refused
+func (p ThrottlePolicy) Delay(failures int) time.Duration {
+	switch {
+	case failures >= p.LockoutAfter:
+		return p.LockoutDuration
+	case failures < p.FreeAttempts:
+		return 0
+	}
+	delay := p.BaseDelay
+	for i := p.FreeAttempts; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+var (
+	// usernamePolicy guards a single account against password guessing
+	usernamePolicy = ThrottlePolicy{
+		FreeAttempts:    5,
+		BaseDelay:       time.Second,
+		MaxDelay:        time.Minute,
+		LockoutAfter:    10,
+		LockoutDuration: 15 * time.Minute,
+	}
+
+	// ipPolicy guards against one client trying many accounts. It is looser
+	// because several users can share an address.
+	ipPolicy = ThrottlePolicy{
+		FreeAttempts:    20,
+		BaseDelay:       time.Second,
+		MaxDelay:        time.Minute,
+		LockoutAfter:    100,
+		LockoutDuration: 15 * time.Minute,
+	}
+)
+
+// ThrottledError reports when sign-in can be attempted again
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("%v, retry after %s", ErrLoginThrottled, e.RetryAfter)
+}
+
+// Unwrap makes errors.Is(err, ErrLoginThrottled) hold
+func (e *ThrottledError) Unwrap() error {
+	return ErrLoginThrottled
+}
+
+// LoginThrottle counts failed sign-ins per username and per client IP and
+// refuses attempts while either is backing off or locked. Counts are kept in
+// an credentials.AttemptCounter, so replicas sharing a store share the limits.
+type LoginThrottle struct {
+	repo     repository.Store
+	counter  credentials.AttemptCounter
+	events   events.Publisher
+	username ThrottlePolicy
+	ip       ThrottlePolicy
+	logger   *logrus.Logger
+	now      func() time.Time
+}
+
+// NewLoginThrottle creates a new login throttle
+func NewLoginThrottle(repo repository.Store, counter credentials.AttemptCounter, publisher events.Publisher, logger *logrus.Logger) *LoginThrottle {
+	return &LoginThrottle{
+		repo:     repo,
+		counter:  counter,
+		events:   publisher,
+		username: usernamePolicy,
+		ip:       ipPolicy,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// Check returns a *ThrottledError if a sign-in for username from ip must
+// wait. It behaves the same whether or not the username exists.
+func (t *LoginThrottle) Check(username, ip string) error {
+	now := t.now()
+	var wait time.Duration
+	for _, k := range t.keys(username, ip) {
+		record, err := t.counter.Get(k.key)
+		if err != nil {
+			// Failing open keeps sign-in available if the shared store is down
+			t.logger.WithError(err).Error("Failed to read login attempts")
+			continue
+		}
+		if until := record.LastFailure.Add(k.policy.Delay(record.Failures)); until.After(now) && until.Sub(now) > wait {
+			wait = until.Sub(now)
+		}
+	}
+	if wait > 0 {
+		return &ThrottledError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// Failed records a failed sign-in. When the username reaches the lockout
+// threshold a security event is published for the user, if there is one.
+func (t *LoginThrottle) Failed(username, ip string) {
+	now := t.now()
+	for _, k := range t.keys(username, ip) {
+		record, err := t.counter.RecordFailure(k.key, now, k.policy.LockoutDuration)
+		if err != nil {
+			t.logger.WithError(err).Error("Failed to record login attempt")
+			continue
+		}
+		if record.Failures != k.policy.LockoutAfter {
+			continue
+		}
+
+		t.logger.WithFields(logrus.Fields{
+			"key":      k.key,
+			"failures": record.Failures,
+			"until":    now.Add(k.policy.LockoutDuration),
+		}).Warn("Sign-in locked after repeated failures")
+		if k.account {
+			t.publishLockout(username, now, k.policy.LockoutDuration)
+		}
+	}
+}
+
+// Succeeded clears the username's failures. The IP count is left alone, so
+// signing in to one account does not reset guessing against others.
+func (t *LoginThrottle) Succeeded(username string) {
+	if err := t.counter.Reset(usernameKey(username)); err != nil {
+		t.logger.WithError(err).Error("Failed to reset login attempts")
+	}
+}
+
+// publishLockout tells the owner of username that their account was locked
+func (t *LoginThrottle) publishLockout(username string, now time.Time, duration time.Duration) {
+	user, err := t.repo.GetUserByEmail(normalizeEmail(username))
+	if err != nil {
+		return
+	}
+	id, err := newID("sec")
+	if err != nil {
+		t.logger.WithError(err).Error("Failed to generate event ID")
+		return
+	}
+
+	event := &events.Event{
+		ID:     id,
+		UserID: user.ID,
+		Type:   events.TypeAccountLocked,
+		Message: fmt.Sprintf("Sign-in to your account was locked for %d minutes after %d failed attempts. If this was not you, change your password.",
+			int(duration.Minutes()), t.username.LockoutAfter),
+		CreatedAt: now.UTC(),
+	}
+	if err := t.events.Publish(event); err != nil {
+		t.logger.WithError(err).WithField("userId", user.ID).Error("Failed to publish lockout event")
+	}
+}
+
+// throttleKey pairs a counter key with the policy that applies to it
+type throttleKey struct {
+	key     string
+	policy  ThrottlePolicy
+	account bool // counts failures for a username rather than an IP
+}
+
+func (t *LoginThrottle) keys(username, ip string) []throttleKey {
+	keys := []throttleKey{{key: usernameKey(username), policy: t.username, account: true}}
+	if ip != "" {
+		keys = append(keys, throttleKey{key: "ip:" + ip, policy: t.ip})
+	}
+	return keys
+}
+
+// usernameKey is the counter key for a username, normalized like emails so
+// case and whitespace variations share one count
+func usernameKey(username string) string {
+	return "user:" + normalizeEmail(username)
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/credentials"
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/events"
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+// recordingPublisher keeps published events in memory
+type recordingPublisher struct {
+	events []*events.Event
+}
+
+func (p *recordingPublisher) Publish(event *events.Event) error {
+	p.events = append(p.events, event)
+	return nil
+}
+
+func (p *recordingPublisher) Close() error {
+	return nil
+}
+
+func TestThrottlePolicyDelay(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{4, 0},
+		{5, time.Second},
+		{6, 2 * time.Second},
+		{8, 8 * time.Second},
+		{9, 16 * time.Second},
+		{10, 15 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := usernamePolicy.Delay(tt.failures); got != tt.want {
+			t.Errorf("Delay(%d) = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+
+	capped := ThrottlePolicy{FreeAttempts: 1, BaseDelay: time.Second, MaxDelay: 5 * time.Second, LockoutAfter: 100}
+	if got := capped.Delay(50); got != 5*time.Second {
+		t.Errorf("Expected the delay to be capped, got %s", got)
+	}
+}
+
+func TestLoginThrottle(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	repo, err := repository.NewStore(repository.Config{
+		Backend:  repository.BackendJSON,
+		DataPath: filepath.Join("..", "handlers", "testdata"),
+	}, logger)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	defer repo.Close()
+
+	publisher := &recordingPublisher{}
+	throttle := NewLoginThrottle(repo, credentials.NewMemoryAttemptCounter(), publisher, logger)
+	now := time.Date(2024, 12, 1, 12, 0, 0, 0, time.UTC)
+	throttle.now = func() time.Time { return now }
+
+	// Failures back off once the free attempts are used up
+	for i := 0; i < usernamePolicy.FreeAttempts; i++ {
+		if err := throttle.Check("Demo@AccountStack.com", "10.0.0.1"); err != nil {
+			t.Fatalf("Expected attempt %d to be allowed, got %v", i+1, err)
+		}
+		throttle.Failed("Demo@AccountStack.com", "10.0.0.1")
+	}
+	var throttled *ThrottledError
+	if err := throttle.Check("demo@accountstack.com", "10.0.0.2"); !errors.As(err, &throttled) || throttled.RetryAfter != time.Second {
+		t.Fatalf("Expected a 1s backoff for the username from any IP, got %v", err)
+	}
+	if err := throttle.Check("sarah.chen@accountstack.com", "10.0.0.1"); err != nil {
+		t.Errorf("Expected other users from the same IP to be allowed, got %v", err)
+	}
+
+	// Reaching the threshold locks the username and publishes one event
+	for i := usernamePolicy.FreeAttempts; i < usernamePolicy.LockoutAfter; i++ {
+		now = now.Add(time.Minute)
+		throttle.Failed("demo@accountstack.com", "10.0.0.1")
+	}
+	if err := throttle.Check("demo@accountstack.com", "10.0.0.3"); !errors.As(err, &throttled) || throttled.RetryAfter != 15*time.Minute {
+		t.Errorf("Expected a 15 minute lockout, got %v", err)
+	}
+	if len(publisher.events) != 1 || publisher.events[0].UserID != "user-001" || publisher.events[0].Type != events.TypeAccountLocked {
+		t.Fatalf("Expected one lockout event for user-001, got %+v", publisher.events)
+	}
+
+	// Unknown usernames are throttled alike but have no one to alert
+	for i := 0; i < usernamePolicy.LockoutAfter; i++ {
+		throttle.Failed("nobody@example.com", "10.0.0.4")
+	}
+	if err := throttle.Check("nobody@example.com", "10.0.0.4"); !errors.Is(err, ErrLoginThrottled) {
+		t.Errorf("Expected an unknown username to be locked, got %v", err)
+	}
+	if len(publisher.events) != 1 {
+		t.Errorf("Expected no event for an unknown username, got %d events", len(publisher.events))
+	}
+
+	// The lockout ends on its own, and success clears the username count
+	now = now.Add(15*time.Minute + time.Second)
+	if err := throttle.Check("demo@accountstack.com", "10.0.0.1"); err != nil {
+		t.Errorf("Expected the lockout to have ended, got %v", err)
+	}
+	throttle.Failed("demo@accountstack.com", "10.0.0.1")
+	throttle.Succeeded("demo@accountstack.com")
+	if record, _ := throttle.counter.Get(usernameKey("demo@accountstack.com")); record.Failures != 0 {
+		t.Errorf("Expected success to reset the username, got %+v", record)
+	}
+}
+
+func TestLoginThrottleByIP(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	throttle := NewLoginThrottle(nil, credentials.NewMemoryAttemptCounter(), &recordingPublisher{}, logger)
+
+	// Spreading guesses over many usernames still trips the IP limit
+	for i := 0; i < ipPolicy.LockoutAfter; i++ {
+		throttle.Failed(fmt.Sprintf("user%d@example.com", i), "10.0.0.9")
+	}
+	if err := throttle.Check("fresh@example.com", "10.0.0.9"); !errors.Is(err, ErrLoginThrottled) {
+		t.Errorf("Expected the IP to be locked, got %v", err)
+	}
+	if err := throttle.Check("fresh@example.com", "10.0.0.10"); err != nil {
+		t.Errorf("Expected another IP to be allowed, got %v", err)
+	}
+}
@@ -0,0 +1,384 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/credentials"
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/models"
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/repository"
+	"github.com/sirupsen/logrus"
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	// totpIssuer names the service in authenticator apps
+	totpIssuer = "AccountStack"
+
+	// totpQRCodeSize is the width and height of the enrollment QR code in pixels
+	totpQRCodeSize = 256
+
+	// recoveryCodeCount is how many recovery codes a user is given at a time
+	recoveryCodeCount = 10
+
+	// mfaChallengeTTL is how long a user has to enter their second factor
+	// after their password was accepted
+	mfaChallengeTTL = 5 * time.Minute
+
+	// mfaMaxAttempts is how many wrong codes end a challenge, after which the
+	// user has to enter their password again
+	mfaMaxAttempts = 5
+)
+
+var (
+	// ErrMFAAlreadyEnabled is returned when enrolling a user who already has TOTP enabled
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+
+	// ErrMFANotEnabled is returned when managing TOTP for a user who has not enabled it
+	ErrMFANotEnabled = errors.New("two-factor authentication is not enabled")
+
+	// ErrMFANotEnrolled is returned when confirming TOTP before enrolling
+	ErrMFANotEnrolled = errors.New("no TOTP enrollment to confirm")
+
+	// ErrInvalidMFACode is returned for a wrong, expired or already used TOTP or recovery code
+	ErrInvalidMFACode = errors.New("invalid verification code")
+
+	// ErrMFAChallengeInvalid is returned for an MFA token that is unknown,
+	// expired, already used or locked after too many wrong codes
+	ErrMFAChallengeInvalid = errors.New("invalid or expired MFA token")
+)
+
+// recoveryCodeEncoding turns random bytes into recovery codes, shown in lower case
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TOTPSetup is what a user needs to add an authenticator. The secret is
+// shown once; the enrollment only takes effect once confirmed with a code.
+type TOTPSetup struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauthUri"`
+	QRCode     []byte `json:"qrCode"` // PNG of OTPAuthURI, base64-encoded in JSON
+}
+
+// MFAStatus describes a user's second factors
+type MFAStatus struct {
+	TOTPEnabled            bool `json:"totpEnabled"`
+	RecoveryCodesRemaining int  `json:"recoveryCodesRemaining"`
+}
+
+// MFAService handles TOTP enrollment, recovery codes and the second step of sign-in
+type MFAService struct {
+	repo   repository.Store
+	logger *logrus.Logger
+	now    func() time.Time
+}
+
+// NewMFAService creates a new MFA service
+func NewMFAService(repo repository.Store, logger *logrus.Logger) *MFAService {
+	return &MFAService{
+		repo:   repo,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Status returns whether the user has TOTP enabled and how many recovery codes are left
+func (s *MFAService) Status(userID string) (*MFAStatus, error) {
+	enabled, err := s.Required(userID)
+	if err != nil {
+		return nil, err
+	}
+	status := &MFAStatus{TOTPEnabled: enabled}
+	if enabled {
+		if status.RecoveryCodesRemaining, err = s.repo.CountRecoveryCodes(userID); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// Required reports whether signing in as the user needs a second factor
+func (s *MFAService) Required(userID string) (bool, error) {
+	enrollment, err := s.repo.GetTOTPEnrollment(userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrTOTPNotEnrolled) {
+			return false, nil
+		}
+		return false, err
+	}
+	return enrollment.Enabled(), nil
+}
+
+// EnrollTOTP generates a new TOTP secret for the user. Enrolling again before
+// confirming replaces the secret.
+func (s *MFAService) EnrollTOTP(userID string) (*TOTPSetup, error) {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		s.logger.WithField("userId", userID).Warn("User not found")
+		return nil, err
+	}
+
+	secret, err := credentials.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	uri := credentials.TOTPURI(totpIssuer, user.Email, secret)
+	png, err := qrcode.Encode(uri, qrcode.Medium, totpQRCodeSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SaveTOTPEnrollment(&models.TOTPEnrollment{
+		UserID:    userID,
+		Secret:    secret,
+		CreatedAt: s.now().UTC(),
+	}); err != nil {
+		if errors.Is(err, repository.ErrTOTPAlreadyEnabled) {
+			return nil, ErrMFAAlreadyEnabled
+		}
+		s.logger.WithError(err).WithField("userId", userID).Error("Failed to store TOTP enrollment")
+		return nil, err
+	}
+
+	s.logger.WithField("userId", userID).Info("TOTP enrollment started")
+	return &TOTPSetup{Secret: secret, OTPAuthURI: uri, QRCode: png}, nil
+}
+
+// ConfirmTOTP enables TOTP once the user proves their authenticator produces
+// valid codes, and returns their first set of recovery codes
+func (s *MFAService) ConfirmTOTP(userID, code string) ([]string, error) {
+	enrollment, err := s.repo.GetTOTPEnrollment(userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrTOTPNotEnrolled) {
+			return nil, ErrMFANotEnrolled
+		}
+		return nil, err
+	}
+	if enrollment.Enabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	now := s.now().UTC()
+	step, ok := credentials.ValidateTOTP(enrollment.Secret, code, now)
+	if !ok {
+		s.logger.WithField("userId", userID).Warn("Invalid TOTP code during enrollment")
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, stored, err := newRecoveryCodes(userID, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ConfirmTOTPEnrollment(userID, step, stored, now); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrTOTPNotEnrolled):
+			return nil, ErrMFANotEnrolled
+		case errors.Is(err, repository.ErrTOTPAlreadyEnabled):
+			return nil, ErrMFAAlreadyEnabled
+		}
+		s.logger.WithError(err).WithField("userId", userID).Error("Failed to confirm TOTP enrollment")
+		return nil, err
+	}
+
+	s.logger.WithField("userId", userID).Info("TOTP enabled")
+	return codes, nil
+}
+
+// DisableTOTP turns off TOTP after checking a current TOTP or recovery code
+func (s *MFAService) DisableTOTP(userID, code, recoveryCode string) error {
+	if err := s.verify(userID, code, recoveryCode); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteTOTPEnrollment(userID); err != nil {
+		if errors.Is(err, repository.ErrTOTPNotEnrolled) {
+			return ErrMFANotEnabled
+		}
+		s.logger.WithError(err).WithField("userId", userID).Error("Failed to disable TOTP")
+		return err
+	}
+
+	s.logger.WithField("userId", userID).Info("TOTP disabled")
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes after checking a
+// current TOTP code. The previous codes stop working.
+func (s *MFAService) RegenerateRecoveryCodes(userID, code string) ([]string, error) {
+	if err := s.verify(userID, code, ""); err != nil {
+		return nil, err
+	}
+
+	codes, stored, err := newRecoveryCodes(userID, s.now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceRecoveryCodes(userID, stored); err != nil {
+		s.logger.WithError(err).WithField("userId", userID).Error("Failed to store recovery codes")
+		return nil, err
+	}
+
+	s.logger.WithField("userId", userID).Info("Recovery codes regenerated")
+	return codes, nil
+}
+
+// StartChallenge issues the token a user whose password was accepted
+// exchanges, together with their second factor, for a session
+func (s *MFAService) StartChallenge(userID string) (string, time.Time, error) {
+	token, err := newSecretToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := s.now().UTC()
+	challenge := &models.MFAChallenge{
+		TokenHash: hashToken(token),
+		UserID:    userID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(mfaChallengeTTL),
+	}
+	if err := s.repo.CreateMFAChallenge(challenge); err != nil {
+		s.logger.WithError(err).WithField("userId", userID).Error("Failed to store MFA challenge")
+		return "", time.Time{}, err
+	}
+	return token, challenge.ExpiresAt, nil
+}
+
+// ChallengeUser returns the user an MFA token was issued for, so a sign-in
+// can be throttled by username before its second factor is checked
+func (s *MFAService) ChallengeUser(token string) (*models.User, error) {
+	challenge, err := s.repo.GetMFAChallenge(hashToken(token))
+	if err != nil {
+		if errors.Is(err, repository.ErrMFAChallengeInvalid) {
+			return nil, ErrMFAChallengeInvalid
+		}
+		return nil, err
+	}
+	user, err := s.repo.GetUserByID(challenge.UserID)
+	if err != nil {
+		return nil, ErrMFAChallengeInvalid
+	}
+	return user, nil
+}
+
+// CompleteChallenge checks the second factor for an MFA token and returns the
+// user it was issued for. Each token can be completed once, and is locked
+// after mfaMaxAttempts wrong codes.
+func (s *MFAService) CompleteChallenge(token, code, recoveryCode string) (*models.User, error) {
+	tokenHash := hashToken(token)
+	challenge, err := s.repo.GetMFAChallenge(tokenHash)
+	if err != nil {
+		if errors.Is(err, repository.ErrMFAChallengeInvalid) {
+			s.logger.Warn("Unknown MFA token")
+			return nil, ErrMFAChallengeInvalid
+		}
+		return nil, err
+	}
+	if !challenge.Usable(s.now()) || challenge.Attempts >= mfaMaxAttempts {
+		s.logger.WithField("userId", challenge.UserID).Warn("Expired, used or locked MFA token")
+		return nil, ErrMFAChallengeInvalid
+	}
+
+	if err := s.verify(challenge.UserID, code, recoveryCode); err != nil {
+		if !errors.Is(err, ErrInvalidMFACode) {
+			// MFA was turned off since the password check; sign in again
+			if errors.Is(err, ErrMFANotEnabled) {
+				return nil, ErrMFAChallengeInvalid
+			}
+			return nil, err
+		}
+		attempts, recordErr := s.repo.RecordMFAChallengeFailure(tokenHash)
+		if recordErr != nil {
+			return nil, recordErr
+		}
+		if attempts >= mfaMaxAttempts {
+			s.logger.WithField("userId", challenge.UserID).Warn("Too many wrong MFA codes, challenge locked")
+		}
+		return nil, err
+	}
+
+	// A concurrent request may have completed the same challenge first
+	if err := s.repo.ConsumeMFAChallenge(tokenHash, s.now().UTC()); err != nil {
+		if errors.Is(err, repository.ErrMFAChallengeInvalid) {
+			return nil, ErrMFAChallengeInvalid
+		}
+		return nil, err
+	}
+
+	user, err := s.repo.GetUserByID(challenge.UserID)
+	if err != nil {
+		s.logger.WithField("userId", challenge.UserID).Warn("MFA challenge for unknown user")
+		return nil, ErrMFAChallengeInvalid
+	}
+	return user, nil
+}
+
+// verify checks a TOTP code, or a recovery code when one is given, for a user
+// with TOTP enabled. Accepted TOTP steps and recovery codes cannot be reused.
+func (s *MFAService) verify(userID, code, recoveryCode string) error {
+	enrollment, err := s.repo.GetTOTPEnrollment(userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrTOTPNotEnrolled) {
+			return ErrMFANotEnabled
+		}
+		return err
+	}
+	if !enrollment.Enabled() {
+		return ErrMFANotEnabled
+	}
+
+	now := s.now().UTC()
+	if recoveryCode != "" {
+		err := s.repo.ConsumeRecoveryCode(userID, hashToken(normalizeRecoveryCode(recoveryCode)), now)
+		if errors.Is(err, repository.ErrRecoveryCodeInvalid) {
+			s.logger.WithField("userId", userID).Warn("Invalid recovery code")
+			return ErrInvalidMFACode
+		}
+		if err == nil {
+			s.logger.WithField("userId", userID).Info("Recovery code used")
+		}
+		return err
+	}
+
+	step, ok := credentials.ValidateTOTP(enrollment.Secret, code, now)
+	if !ok {
+		s.logger.WithField("userId", userID).Warn("Invalid TOTP code")
+		return ErrInvalidMFACode
+	}
+	if err := s.repo.UseTOTPStep(userID, step); err != nil {
+		if errors.Is(err, repository.ErrTOTPStepUsed) {
+			s.logger.WithField("userId", userID).Warn("TOTP code replayed")
+			return ErrInvalidMFACode
+		}
+		return err
+	}
+	return nil
+}
+
+// newRecoveryCodes generates a set of recovery codes, returning the codes to
+// show the user and the hashed form to store
+func newRecoveryCodes(userID string, now time.Time) ([]string, []*models.RecoveryCode, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	stored := make([]*models.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(recoveryCodeEncoding.EncodeToString(b)) // 8 characters, 40 bits
+		codes = append(codes, raw[:4]+"-"+raw[4:])
+		stored = append(stored, &models.RecoveryCode{
+			CodeHash:  hashToken(raw),
+			UserID:    userID,
+			CreatedAt: now,
+		})
+	}
+	return codes, stored, nil
+}
+
+// normalizeRecoveryCode strips the separator, spaces and case from a recovery code
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
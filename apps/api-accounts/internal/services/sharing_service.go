@@ -0,0 +1,406 @@
+package services
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/mail"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/models"
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/notify"
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+// invitationTTL is how long an invitation to an account can be accepted
+const invitationTTL = 7 * 24 * time.Hour
+
+var (
+	// ErrNotAccountOwner is returned when someone other than the owner
+	// invites to or removes others from an account
+	ErrNotAccountOwner = errors.New("only the account owner can manage access")
+
+	// ErrViewerAccess is returned when a viewer lists who has access to an
+	// account, which only owners and joint holders can see
+	ErrViewerAccess = errors.New("viewers cannot see who has access to this account")
+
+	// ErrInvalidAccess is returned when inviting with an unknown access level
+	ErrInvalidAccess = errors.New("access must be joint or viewer")
+
+	// ErrInvalidInvitee is returned when the invitee's email is malformed or
+	// is the owner's own
+	ErrInvalidInvitee = errors.New("email must be a valid address other than your own")
+
+	// ErrAlreadyShared is returned when inviting someone who already has
+	// access to the account
+	ErrAlreadyShared = errors.New("this user already has access to the account")
+
+	// ErrInvitationPending is returned when the same email already has a
+	// pending invitation to the account
+	ErrInvitationPending = errors.New("an invitation to this email is already pending")
+
+	// ErrInvitationNotFound is returned for an invitation that does not exist
+	// or was not addressed to the user
+	ErrInvitationNotFound = errors.New("invitation not found")
+
+	// ErrInvitationTokenInvalid is returned when accepting an invitation
+	// without the token that was sent to the invitee's email
+	ErrInvitationTokenInvalid = errors.New("the invitation token is missing or incorrect")
+
+	// ErrInvitationClosed is returned when responding to an invitation that
+	// was already answered, was cancelled or has expired
+	ErrInvitationClosed = errors.New("invitation is no longer pending")
+
+	// ErrOwnerAccess is returned when removing the owner from their own account
+	ErrOwnerAccess = errors.New("the owner's access cannot be removed")
+
+	// ErrGrantNotFound is returned when removing someone who has no access
+	// to the account
+	ErrGrantNotFound = errors.New("that user has no access to this account")
+)
+
+// SharingService lets account owners grant joint holders and read-only
+// viewers access to their accounts. Access is offered by email and granted
+// when the invitee accepts with the token sent to that address, which proves
+// they own it: registering an account does not.
+type SharingService struct {
+	repo     repository.Store
+	accounts *AccountService
+	notifier notify.Notifier
+	logger   *logrus.Logger
+	now      func() time.Time
+}
+
+// NewSharingService creates a new sharing service
+func NewSharingService(repo repository.Store, accounts *AccountService, notifier notify.Notifier, logger *logrus.Logger) *SharingService {
+	return &SharingService{
+		repo:     repo,
+		accounts: accounts,
+		notifier: notifier,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// Invite offers access to an account to whoever holds email. Only the owner
+// can invite. The invitee is sent the token needed to accept and has
+// invitationTTL to do so; the token is not returned to the owner.
+func (s *SharingService) Invite(userID, accountID, email, access string) (*models.AccountInvitation, error) {
+	account, err := s.ownAccount(accountID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !models.ValidGrantAccess(access) {
+		return nil, ErrInvalidAccess
+	}
+	email = normalizeEmail(email)
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return nil, ErrInvalidInvitee
+	}
+
+	owner, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if normalizeEmail(owner.Email) == email {
+		return nil, ErrInvalidInvitee
+	}
+	if invitee, err := s.repo.GetUserByEmail(email); err == nil {
+		if _, err := s.repo.GetAccountGrant(accountID, invitee.ID); err == nil {
+			return nil, ErrAlreadyShared
+		} else if !errors.Is(err, repository.ErrGrantNotFound) {
+			return nil, err
+		}
+	}
+
+	now := s.now().UTC()
+	existing, err := s.repo.GetAccountInvitationsByAccountID(accountID)
+	if err != nil {
+		return nil, err
+	}
+	for _, invitation := range existing {
+		if invitation.Email == email && invitation.Pending(now) {
+			return nil, ErrInvitationPending
+		}
+	}
+
+	id, err := newID("inv")
+	if err != nil {
+		return nil, err
+	}
+	token, err := newSecretToken()
+	if err != nil {
+		return nil, err
+	}
+	invitation := &models.AccountInvitation{
+		ID:        id,
+		TokenHash: hashToken(token),
+		AccountID: accountID,
+		Email:     email,
+		Access:    access,
+		InvitedBy: userID,
+		Status:    models.InvitationPending,
+		CreatedAt: now,
+		ExpiresAt: now.Add(invitationTTL),
+	}
+	if err := s.repo.CreateAccountInvitation(invitation); err != nil {
+		s.logger.WithError(err).WithField("accountId", accountID).Error("Failed to store invitation")
+		return nil, err
+	}
+
+	if err := s.notifier.Send(&notify.Message{
+		To:      email,
+		Subject: "You have been invited to an AccountStack account",
+		Body: fmt.Sprintf("%s invited you to %s as a %s. Sign in to AccountStack within %d days to accept invitation %s with this token: %s",
+			owner.Name, account.AccountName, accessLabel(access), int(invitationTTL.Hours()/24), id, token),
+		SentAt: now,
+	}); err != nil {
+		// The owner can cancel and invite again once the notifier recovers
+		s.logger.WithError(err).WithField("invitationId", id).Error("Failed to send invitation")
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"userId":       userID,
+		"accountId":    accountID,
+		"invitationId": id,
+		"access":       access,
+	}).Info("Account invitation sent")
+
+	return invitation, nil
+}
+
+// ListAccess returns who has access to an account and who is invited to it.
+// Owners and joint holders can see it; viewers cannot.
+func (s *SharingService) ListAccess(userID, accountID string) (*models.AccountAccess, error) {
+	account, access, err := accessibleAccount(s.repo, s.logger, accountID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !models.CanWrite(access) {
+		return nil, ErrViewerAccess
+	}
+
+	owner, err := s.repo.GetUserByID(account.UserID)
+	if err != nil {
+		return nil, err
+	}
+	result := &models.AccountAccess{
+		AccountID: accountID,
+		Holders: []*models.AccessHolder{{
+			UserID: owner.ID,
+			Name:   owner.Name,
+			Email:  owner.Email,
+			Access: models.AccessOwner,
+		}},
+		Invitations: []*models.AccountInvitation{},
+	}
+
+	grants, err := s.repo.GetAccountGrantsByAccountID(accountID)
+	if err != nil {
+		return nil, err
+	}
+	for _, grant := range grants {
+		holder := &models.AccessHolder{UserID: grant.UserID, Access: grant.Access, GrantedAt: &grant.CreatedAt}
+		if user, err := s.repo.GetUserByID(grant.UserID); err == nil {
+			holder.Name = user.Name
+			holder.Email = user.Email
+		}
+		result.Holders = append(result.Holders, holder)
+	}
+
+	invitations, err := s.repo.GetAccountInvitationsByAccountID(accountID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	for _, invitation := range invitations {
+		if invitation.Pending(now) {
+			result.Invitations = append(result.Invitations, invitation)
+		}
+	}
+
+	return result, nil
+}
+
+// CancelInvitation withdraws a pending invitation to an account the user owns
+func (s *SharingService) CancelInvitation(userID, accountID, invitationID string) error {
+	if _, err := s.ownAccount(accountID, userID); err != nil {
+		return err
+	}
+	invitation, err := s.repo.GetAccountInvitation(invitationID)
+	if err != nil || invitation.AccountID != accountID {
+		return ErrInvitationNotFound
+	}
+	if err := s.closeInvitation(invitationID, models.InvitationCancelled); err != nil {
+		return err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"userId":       userID,
+		"accountId":    accountID,
+		"invitationId": invitationID,
+	}).Info("Account invitation cancelled")
+	return nil
+}
+
+// RemoveAccess revokes targetUserID's access to an account. The owner can
+// remove anyone else; joint holders and viewers can only remove themselves.
+func (s *SharingService) RemoveAccess(userID, accountID, targetUserID string) error {
+	account, access, err := accessibleAccount(s.repo, s.logger, accountID, userID)
+	if err != nil {
+		return err
+	}
+	if targetUserID == account.UserID {
+		return ErrOwnerAccess
+	}
+	if access != models.AccessOwner && targetUserID != userID {
+		return ErrNotAccountOwner
+	}
+
+	if err := s.repo.DeleteAccountGrant(accountID, targetUserID); err != nil {
+		if errors.Is(err, repository.ErrGrantNotFound) {
+			return ErrGrantNotFound
+		}
+		return err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"userId":       userID,
+		"accountId":    accountID,
+		"targetUserId": targetUserID,
+	}).Info("Account access removed")
+	return nil
+}
+
+// ListInvitations returns the pending invitations addressed to the user's email
+func (s *SharingService) ListInvitations(userID string) ([]*models.AccountInvitation, error) {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	invitations, err := s.repo.GetAccountInvitationsByEmail(normalizeEmail(user.Email))
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	pending := []*models.AccountInvitation{}
+	for _, invitation := range invitations {
+		if invitation.Pending(now) {
+			pending = append(pending, invitation)
+		}
+	}
+	return pending, nil
+}
+
+// AcceptInvitation grants the user the access an invitation to their email
+// offers and returns the account as they now see it. token must be the one
+// sent with the invitation.
+func (s *SharingService) AcceptInvitation(userID, invitationID, token string) (*models.AccountResponse, error) {
+	invitation, err := s.addressedInvitation(userID, invitationID)
+	if err != nil {
+		return nil, err
+	}
+	if invitation.TokenHash == "" || subtle.ConstantTimeCompare([]byte(hashToken(token)), []byte(invitation.TokenHash)) != 1 {
+		s.logger.WithFields(logrus.Fields{
+			"userId":       userID,
+			"invitationId": invitationID,
+		}).Warn("Invitation accepted with an invalid token")
+		return nil, ErrInvitationTokenInvalid
+	}
+	account, err := s.repo.GetAccountByID(invitation.AccountID)
+	if err != nil {
+		return nil, ErrInvitationNotFound
+	}
+	if account.UserID == userID {
+		return nil, ErrAlreadyShared
+	}
+
+	now := s.now().UTC()
+	if err := s.repo.AcceptAccountInvitation(invitationID, &models.AccountGrant{
+		AccountID: invitation.AccountID,
+		UserID:    userID,
+		Access:    invitation.Access,
+		GrantedBy: invitation.InvitedBy,
+		CreatedAt: now,
+	}, now); err != nil {
+		if errors.Is(err, repository.ErrInvitationClosed) {
+			return nil, ErrInvitationClosed
+		}
+		s.logger.WithError(err).WithField("invitationId", invitationID).Error("Failed to accept invitation")
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"userId":       userID,
+		"accountId":    invitation.AccountID,
+		"invitationId": invitationID,
+		"access":       invitation.Access,
+	}).Info("Account invitation accepted")
+
+	return s.accounts.GetAccountByID(invitation.AccountID, userID)
+}
+
+// DeclineInvitation turns down an invitation to the user's email
+func (s *SharingService) DeclineInvitation(userID, invitationID string) error {
+	if _, err := s.addressedInvitation(userID, invitationID); err != nil {
+		return err
+	}
+	if err := s.closeInvitation(invitationID, models.InvitationDeclined); err != nil {
+		return err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"userId":       userID,
+		"invitationId": invitationID,
+	}).Info("Account invitation declined")
+	return nil
+}
+
+// ownAccount retrieves an account and verifies the user owns it. Users who
+// only have access to it get ErrNotAccountOwner.
+func (s *SharingService) ownAccount(accountID, userID string) (*models.Account, error) {
+	account, access, err := accessibleAccount(s.repo, s.logger, accountID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if access != models.AccessOwner {
+		return nil, ErrNotAccountOwner
+	}
+	return account, nil
+}
+
+// addressedInvitation retrieves an invitation sent to the user's email.
+// Invitations to anyone else are reported as not found.
+func (s *SharingService) addressedInvitation(userID, invitationID string) (*models.AccountInvitation, error) {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	invitation, err := s.repo.GetAccountInvitation(invitationID)
+	if err != nil || invitation.Email != normalizeEmail(user.Email) {
+		return nil, ErrInvitationNotFound
+	}
+	return invitation, nil
+}
+
+// closeInvitation moves a pending invitation to status
+func (s *SharingService) closeInvitation(invitationID, status string) error {
+	err := s.repo.CloseAccountInvitation(invitationID, status, s.now().UTC())
+	switch {
+	case errors.Is(err, repository.ErrInvitationClosed):
+		return ErrInvitationClosed
+	case errors.Is(err, repository.ErrInvitationNotFound):
+		return ErrInvitationNotFound
+	}
+	return err
+}
+
+// accessLabel describes an access level in notifications
+func accessLabel(access string) string {
+	if access == models.AccessJoint {
+		return "joint holder"
+	}
+	return "read-only viewer"
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/features"
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/models"
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrInvalidPeriod is returned when a statement period is not in YYYY-MM format
+	ErrInvalidPeriod = errors.New("period must be in YYYY-MM format")
+
+	// ErrStatementNotAvailable is returned for periods before the account's
+	// first statement or after the current month
+	ErrStatementNotAvailable = errors.New("no statement is available for this period")
+)
+
+// StatementService builds account statements from the transactions data
+type StatementService struct {
+	repo   repository.Store
+	flags  *features.Flags
+	logger *logrus.Logger
+	now    func() time.Time
+}
+
+// NewStatementService creates a new statement service
+func NewStatementService(repo repository.Store, flags *features.Flags, logger *logrus.Logger) *StatementService {
+	return &StatementService{
+		repo:   repo,
+		flags:  flags,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// ListStatements returns a summary of every available statement for an
+// account, newest first
+func (s *StatementService) ListStatements(accountID string, userID string) ([]models.StatementSummaryResponse, error) {
+	account, transactions, currency, err := s.load(accountID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	maskAmounts := s.flags.ShouldMaskAmounts()
+	summaries := models.BuildStatementSummaries(account, transactions, s.now().UTC())
+	s.logger.WithFields(logrus.Fields{
+		"accountId":   accountID,
+		"userId":      userID,
+		"count":       len(summaries),
+		"maskAmounts": maskAmounts,
+		"currency":    currency,
+	}).Debug("Listing statements")
+
+	responses := make([]models.StatementSummaryResponse, len(summaries))
+	for i := range summaries {
+		responses[i] = summaries[i].ToResponse(maskAmounts)
+	}
+
+	return responses, nil
+}
+
+// GetStatement builds the statement for one YYYY-MM period
+func (s *StatementService) GetStatement(accountID string, userID string, period string) (*models.Statement, error) {
+	start, err := models.ParsePeriod(period)
+	if err != nil {
+		return nil, ErrInvalidPeriod
+	}
+
+	account, transactions, currency, err := s.load(accountID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now().UTC()
+	if start.Before(models.FirstPeriod(account, transactions)) || start.After(now) {
+		return nil, ErrStatementNotAvailable
+	}
+
+	statement := models.BuildStatement(account, transactions, start, now)
+	statement.Currency = currency
+	statement.MaskAmounts = s.flags.ShouldMaskAmounts()
+	s.logger.WithFields(logrus.Fields{
+		"accountId":   accountID,
+		"userId":      userID,
+		"period":      period,
+		"postings":    len(statement.Postings),
+		"maskAmounts": statement.MaskAmounts,
+		"currency":    currency,
+	}).Debug("Built statement")
+
+	return statement, nil
+}
+
+// load returns an account the user can access, its transactions and the
+// currency to display them in
+func (s *StatementService) load(accountID string, userID string) (*models.Account, []*models.Transaction, string, error) {
+	account, _, err := accessibleAccount(s.repo, s.logger, accountID, userID)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	// Get user to determine currency based on country
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		s.logger.WithField("userId", userID).Warn("User not found")
+		return nil, nil, "", err
+	}
+
+	transactions, err := s.repo.GetTransactionsByAccountID(accountID)
+	if err != nil {
+		s.logger.WithError(err).WithField("accountId", accountID).Error("Failed to retrieve transactions")
+		return nil, nil, "", fmt.Errorf("failed to retrieve transactions: %w", err)
+	}
+
+	return account, transactions, s.flags.GetCurrencyForUser(user.Country), nil
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/models"
+	"github.com/CB-AccountStack/AccountStack/apps/api-accounts/internal/repository"
+	"github.com/CB-AccountStack/AccountStack/pkg/auth"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrRefreshTokenInvalid is returned for a refresh token that is unknown,
+	// expired or revoked
+	ErrRefreshTokenInvalid = errors.New("invalid or expired refresh token")
+
+	// ErrRefreshTokenReused is returned when a refresh token is presented after
+	// it was already exchanged. Its family has been revoked, which signs out
+	// every session descended from the same login.
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+
+	// ErrSessionRequired is returned when an API key or an impersonation token
+	// is used to manage credentials or sharing, which only the user may do
+	// after signing in themselves
+	ErrSessionRequired = errors.New("sign in to do this, API keys and impersonation tokens cannot")
+)
+
+// CheckSession returns ErrSessionRequired unless claims belong to an access
+// token the user got by signing in themselves
+func CheckSession(claims *auth.Claims) error {
+	if claims.APIKeyID != "" || claims.Impersonated() {
+		return ErrSessionRequired
+	}
+	return nil
+}
+
+// TokenPair is an access token and the refresh token that renews it
+type TokenPair struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+// TokenService issues, rotates and revokes access and refresh tokens
+type TokenService struct {
+	repo        repository.Store
+	jwtManager  *auth.JWTManager
+	revocations auth.RevocationList
+	refreshTTL  time.Duration
+	logger      *logrus.Logger
+	now         func() time.Time
+}
+
+// NewTokenService creates a new token service. Access tokens last as long as
+// jwtManager's token duration, refresh tokens for refreshTTL after they are issued.
+func NewTokenService(repo repository.Store, jwtManager *auth.JWTManager, revocations auth.RevocationList, refreshTTL time.Duration, logger *logrus.Logger) *TokenService {
+	return &TokenService{
+		repo:        repo,
+		jwtManager:  jwtManager,
+		revocations: revocations,
+		refreshTTL:  refreshTTL,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// Issue starts a new session for a user that has just signed in
+func (s *TokenService) Issue(user *models.User) (*TokenPair, error) {
+	familyID, err := newID("session")
+	if err != nil {
+		return nil, err
+	}
+	return s.issue(user, familyID, "")
+}
+
+// Refresh exchanges a refresh token for a new token pair. The presented token
+// cannot be used again; presenting it again revokes its whole family.
+func (s *TokenService) Refresh(refreshToken string) (*TokenPair, *models.User, error) {
+	tokenHash := hashToken(refreshToken)
+	current, err := s.repo.GetRefreshToken(tokenHash)
+	if err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenNotFound) {
+			s.logger.Warn("Unknown refresh token")
+			return nil, nil, ErrRefreshTokenInvalid
+		}
+		return nil, nil, err
+	}
+
+	if current.RevokedAt != nil || !s.now().Before(current.ExpiresAt) {
+		s.logger.WithField("userId", current.UserID).Warn("Expired or revoked refresh token")
+		return nil, nil, ErrRefreshTokenInvalid
+	}
+	if current.UsedAt != nil {
+		return nil, nil, s.reused(current)
+	}
+
+	user, err := s.repo.GetUserByID(current.UserID)
+	if err != nil {
+		s.logger.WithField("userId", current.UserID).Warn("Refresh token for unknown user")
+		return nil, nil, ErrRefreshTokenInvalid
+	}
+
+	pair, err := s.issue(user, current.FamilyID, tokenHash)
+	if err != nil {
+		// A concurrent request exchanged the same token first
+		if errors.Is(err, repository.ErrRefreshTokenUsed) {
+			return nil, nil, s.reused(current)
+		}
+		return nil, nil, err
+	}
+
+	return pair, user, nil
+}
+
+// Logout ends the session an access token belongs to. The access token is
+// revoked, along with its refresh token family and the other access tokens
+// issued from it.
+func (s *TokenService) Logout(claims *auth.Claims) error {
+	expiresAt := s.now().Add(s.jwtManager.TokenDuration())
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+	if err := s.revocations.Revoke(claims.ID, expiresAt); err != nil {
+		s.logger.WithError(err).WithField("userId", claims.UserID).Error("Failed to revoke access token")
+		return err
+	}
+
+	token, err := s.repo.GetRefreshTokenByAccessTokenID(claims.ID)
+	if err != nil {
+		// Tokens issued without a refresh token have no family to revoke
+		if errors.Is(err, repository.ErrRefreshTokenNotFound) {
+			return nil
+		}
+		return err
+	}
+	if err := s.revokeFamily(token.FamilyID); err != nil {
+		return err
+	}
+
+	s.logger.WithField("userId", claims.UserID).Info("User logged out")
+	return nil
+}
+
+// RevokeAllForUser ends every session of the user: each refresh token is
+// revoked, along with every access token issued with them that has not yet
+// expired
+func (s *TokenService) RevokeAllForUser(userID string) error {
+	now := s.now().UTC()
+	tokens, err := s.repo.RevokeUserRefreshTokens(userID, now)
+	if err != nil {
+		s.logger.WithError(err).WithField("userId", userID).Error("Failed to revoke refresh tokens")
+		return err
+	}
+
+	for _, token := range tokens {
+		if err := s.revocations.Revoke(token.AccessTokenID, token.AccessExpiresAt); err != nil {
+			s.logger.WithError(err).WithField("userId", userID).Error("Failed to revoke access token")
+			return err
+		}
+	}
+
+	s.logger.WithFields(logrus.Fields{"userId": userID, "sessions": len(tokens)}).Info("Revoked all sessions of user")
+	return nil
+}
+
+// issue signs an access token and stores a new refresh token in the family.
+// When previousHash is set, that token is exchanged for the new one.
+func (s *TokenService) issue(user *models.User, familyID, previousHash string) (*TokenPair, error) {
+	accessToken, claims, err := s.jwtManager.Issue(auth.NewClaims(user.ID, user.Email, user.Role), 0)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := newSecretToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now().UTC()
+	stored := &models.RefreshToken{
+		TokenHash:       hashToken(refreshToken),
+		FamilyID:        familyID,
+		UserID:          user.ID,
+		AccessTokenID:   claims.ID,
+		AccessExpiresAt: claims.ExpiresAt.Time,
+		CreatedAt:       now,
+		ExpiresAt:       now.Add(s.refreshTTL),
+	}
+	if previousHash == "" {
+		err = s.repo.CreateRefreshToken(stored)
+	} else {
+		err = s.repo.RotateRefreshToken(previousHash, stored, now)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:      accessToken,
+		AccessExpiresAt:  claims.ExpiresAt.Time,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: stored.ExpiresAt,
+	}, nil
+}
+
+// reused handles a refresh token presented after it was exchanged, which
+// means it was copied. The whole family is revoked since there is no telling
+// whether the legitimate client or an attacker holds the latest token.
+func (s *TokenService) reused(token *models.RefreshToken) error {
+	s.logger.WithFields(logrus.Fields{
+		"userId":   token.UserID,
+		"familyId": token.FamilyID,
+	}).Warn("Refresh token reused, revoking its family")
+
+	if err := s.revokeFamily(token.FamilyID); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+// revokeFamily revokes every refresh token in the family and every access
+// token issued with them that has not yet expired
+func (s *TokenService) revokeFamily(familyID string) error {
+	now := s.now().UTC()
+	family, err := s.repo.RevokeRefreshTokenFamily(familyID, now)
+	if err != nil {
+		s.logger.WithError(err).WithField("familyId", familyID).Error("Failed to revoke refresh tokens")
+		return err
+	}
+
+	for _, token := range family {
+		if !token.AccessExpiresAt.After(now) {
+			continue
+		}
+		if err := s.revocations.Revoke(token.AccessTokenID, token.AccessExpiresAt); err != nil {
+			s.logger.WithError(err).WithField("familyId", familyID).Error("Failed to revoke access token")
+			return err
+		}
+	}
+	return nil
+}
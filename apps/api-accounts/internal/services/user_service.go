@@ -8,12 +8,12 @@ import (
 
 // UserService handles business logic for users
 type UserService struct {
-	repo   *repository.Repository
+	repo   repository.Store
 	logger *logrus.Logger
 }
 
 // NewUserService creates a new user service
-func NewUserService(repo *repository.Repository, logger *logrus.Logger) *UserService {
+func NewUserService(repo repository.Store, logger *logrus.Logger) *UserService {
 	return &UserService{
 		repo:   repo,
 		logger: logger,
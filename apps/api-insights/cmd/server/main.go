@@ -12,9 +12,15 @@ import (
 
 	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/features"
 	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/handlers"
-	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/middleware"
 	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/repository"
 	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/services"
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/stream"
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/webhook"
+	"github.com/CB-AccountStack/AccountStack/pkg/auth"
+	"github.com/CB-AccountStack/AccountStack/pkg/health"
+	"github.com/CB-AccountStack/AccountStack/pkg/middleware"
+	"github.com/CB-AccountStack/AccountStack/pkg/problem"
+	"github.com/CB-AccountStack/AccountStack/pkg/requestid"
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 )
@@ -50,6 +56,82 @@ func main() {
 		dataPath = filepath.Join("..", "..", "data", "seed")
 	}
 
+	// Revoked token IDs: "memory" (this process only) or "sqlite", shared by
+	// every service that opens the same REVOCATION_SQLITE_PATH
+	revocationSQLitePath := os.Getenv("REVOCATION_SQLITE_PATH")
+	if revocationSQLitePath == "" {
+		revocationSQLitePath = "accountstack.db"
+	}
+
+	// Personal API keys issued by api-accounts: "memory" (none are seen) or
+	// "sqlite", read from the same API_KEY_SQLITE_PATH as api-accounts
+	apiKeySQLitePath := os.Getenv("API_KEY_SQLITE_PATH")
+	if apiKeySQLitePath == "" {
+		apiKeySQLitePath = "accountstack.db"
+	}
+
+	// Security events such as lockouts: "none" or "sqlite", read from the
+	// database api-accounts publishes them to and shown as alerts
+	securityEventsSQLitePath := os.Getenv("SECURITY_EVENTS_SQLITE_PATH")
+	if securityEventsSQLitePath == "" {
+		securityEventsSQLitePath = "accountstack.db"
+	}
+
+	// Accounts shared through api-accounts: "none" or "sqlite", read from the
+	// database api-accounts records grants in
+	accountGrantsSQLitePath := os.Getenv("ACCOUNT_GRANTS_SQLITE_PATH")
+	if accountGrantsSQLitePath == "" {
+		accountGrantsSQLitePath = "accountstack.db"
+	}
+
+	// Read, dismissed and snoozed alerts: "memory" (lost on restart) or
+	// "sqlite", kept in ALERT_STATE_SQLITE_PATH
+	alertStateSQLitePath := os.Getenv("ALERT_STATE_SQLITE_PATH")
+	if alertStateSQLitePath == "" {
+		alertStateSQLitePath = "accountstack.db"
+	}
+
+	// Alert rules users set up: "memory" (lost on restart) or "sqlite", kept
+	// in ALERT_RULES_SQLITE_PATH
+	alertRulesSQLitePath := os.Getenv("ALERT_RULES_SQLITE_PATH")
+	if alertRulesSQLitePath == "" {
+		alertRulesSQLitePath = "accountstack.db"
+	}
+
+	// Webhooks and their delivery queue: "memory" (lost on restart, queued
+	// deliveries included) or "sqlite", kept in WEBHOOKS_SQLITE_PATH
+	webhooksSQLitePath := os.Getenv("WEBHOOKS_SQLITE_PATH")
+	if webhooksSQLitePath == "" {
+		webhooksSQLitePath = "accountstack.db"
+	}
+
+	// Webhooks may only reach public addresses over https. Setting
+	// WEBHOOKS_ALLOW_PRIVATE_NETWORKS lets them reach this machine and its
+	// network, for development against a local receiver; never in production.
+	webhooksAllowPrivate := os.Getenv("WEBHOOKS_ALLOW_PRIVATE_NETWORKS") == "true"
+
+	// Transactions the insights engine and alert rules read while api.insightsV2 is on:
+	// "none", "json" (the seed data in DATA_PATH) or "sqlite", read from the
+	// database api-transactions writes to
+	transactionsStore := os.Getenv("TRANSACTIONS_STORE")
+	transactionsPath := os.Getenv("TRANSACTIONS_SQLITE_PATH")
+	if transactionsPath == "" {
+		transactionsPath = "accountstack.db"
+	}
+	if transactionsStore == repository.TransactionsJSON {
+		transactionsPath = dataPath
+	}
+
+	// Origins of the pages that call the service, comma-separated. CORS allows
+	// any origin when unset; WebSockets then only accept pages on this host.
+	corsOrigins := middleware.ParseOrigins(os.Getenv("CORS_ALLOWED_ORIGINS"))
+
+	// Tokens are issued by api-accounts, which publishes its public keys here
+	jwksURL := os.Getenv("JWKS_URL")
+	if jwksURL == "" {
+		jwksURL = "http://localhost:8001/.well-known/jwks.json"
+	}
+
 	cloudBeesAPIKey := os.Getenv("CLOUDBEES_FM_API_KEY")
 	if cloudBeesAPIKey == "" {
 		logger.Warn("CLOUDBEES_FM_API_KEY not set, feature flags will use defaults")
@@ -76,33 +158,132 @@ func main() {
 		logger.WithError(err).Fatal("Failed to initialize repository")
 	}
 
+	revocations, err := auth.NewRevocationList(os.Getenv("REVOCATION_STORE"), revocationSQLitePath)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize revocation list")
+	}
+	defer revocations.Close()
+
+	apiKeys, err := auth.NewAPIKeyStore(os.Getenv("API_KEY_STORE"), apiKeySQLitePath)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize API keys")
+	}
+	defer apiKeys.Close()
+
+	securityEvents, err := repository.NewSecurityEventSource(os.Getenv("SECURITY_EVENTS_STORE"), securityEventsSQLitePath)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize security events")
+	}
+	defer securityEvents.Close()
+
+	grants, err := repository.NewAccountGrantSource(os.Getenv("ACCOUNT_GRANTS_STORE"), accountGrantsSQLitePath)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize account grants")
+	}
+	defer grants.Close()
+
+	alertStates, err := repository.NewAlertStateStore(os.Getenv("ALERT_STATE_STORE"), alertStateSQLitePath)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize alert states")
+	}
+	defer alertStates.Close()
+
+	alertRules, err := repository.NewAlertRuleStore(os.Getenv("ALERT_RULES_STORE"), alertRulesSQLitePath)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize alert rules")
+	}
+	defer alertRules.Close()
+
+	webhookStore, err := repository.NewWebhookStore(os.Getenv("WEBHOOKS_STORE"), webhooksSQLitePath)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize webhooks")
+	}
+	if webhooksAllowPrivate {
+		logger.Warn("Webhooks may be delivered to private networks and over plain http to this machine")
+	}
+	defer webhookStore.Close()
+
+	transactions, err := repository.NewTransactionSource(transactionsStore, transactionsPath)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize transactions")
+	}
+	defer transactions.Close()
+
+	verifier := auth.NewVerifier(auth.NewJWKSClient(jwksURL, logger))
+	streamTickets := auth.NewStreamTickets()
+
 	// Initialize services
-	insightsService := services.NewInsightsService(repo, flags, logger)
-	alertsService := services.NewAlertsService(repo, flags, logger)
+	insightsService := services.NewInsightsService(repo, grants, transactions, flags, logger)
+	alertRulesService := services.NewAlertRulesService(alertRules, grants, transactions, logger)
+	alertsService := services.NewAlertsService(repo, securityEvents, grants, alertStates, alertRulesService, flags, logger)
+
+	// Push new alerts to open streams, listing them for changes every 5 seconds
+	alertHub := stream.NewHub()
+	alertWatcher := stream.NewWatcher(alertHub, alertsService, 5*time.Second, logger)
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	go alertWatcher.Run(watchCtx)
+
+	// Queue the events of users with webhooks, looking for them every 5
+	// seconds, and deliver what is due every second
+	webhooksService := services.NewWebhooksService(webhookStore, grants, transactions, webhooksAllowPrivate, logger)
+	go webhook.NewWatcher(webhooksService, alertsService, 5*time.Second, logger).Run(watchCtx)
+	go webhook.NewDispatcher(webhookStore, time.Second, webhooksAllowPrivate, logger).Run(watchCtx)
 
 	// Initialize handlers
-	healthHandler := handlers.NewHealthHandler()
+	healthHandler := health.NewHandler("api-insights")
 	insightsHandler := handlers.NewInsightsHandler(insightsService, logger)
 	alertsHandler := handlers.NewAlertsHandler(alertsService, logger)
+	alertRulesHandler := handlers.NewAlertRulesHandler(alertRulesService, logger)
+	webhooksHandler := handlers.NewWebhooksHandler(webhooksService, logger)
+	alertStreamHandler := handlers.NewAlertStreamHandler(alertsService, alertHub, alertWatcher, streamTickets, corsOrigins, logger)
 
 	// Setup router
 	router := mux.NewRouter()
+	router.NotFoundHandler = problem.NotFoundHandler()
+	router.MethodNotAllowedHandler = problem.MethodNotAllowedHandler()
 
 	// Apply global middleware
 	router.Use(middleware.LoggingMiddleware(logger))
-	router.Use(middleware.AuthMiddleware(logger))
+	router.Use(middleware.AuthMiddleware(verifier, revocations, apiKeys, logger))
 
 	// Setup CORS
-	corsHandler := middleware.NewCORS()
+	corsHandler := middleware.NewCORS(corsOrigins...)
 
 	// Register routes
 	router.Handle("/healthz", healthHandler).Methods("GET")
-	router.HandleFunc("/insights", insightsHandler.GetInsights).Methods("GET")
-	router.HandleFunc("/insights/{id}", insightsHandler.GetInsightByID).Methods("GET")
-	router.HandleFunc("/alerts", alertsHandler.GetAlerts).Methods("GET")
+	scoped := func(scope string, h http.HandlerFunc) http.Handler {
+		return middleware.RequireScope(scope, logger)(h)
+	}
+	router.Handle("/insights", scoped(auth.ScopeInsightsRead, insightsHandler.GetInsights)).Methods("GET")
+	router.Handle("/insights/{id}", scoped(auth.ScopeInsightsRead, insightsHandler.GetInsightByID)).Methods("GET")
+	router.Handle("/alerts", scoped(auth.ScopeInsightsRead, alertsHandler.GetAlerts)).Methods("GET")
+	router.Handle("/alerts/unread-count", scoped(auth.ScopeInsightsRead, alertsHandler.GetUnreadCount)).Methods("GET")
+	router.Handle("/alerts/stream/tickets", scoped(auth.ScopeInsightsRead, alertStreamHandler.CreateStreamTicket)).Methods("POST")
+	router.Handle("/alerts/stream", scoped(auth.ScopeInsightsRead, alertStreamHandler.StreamAlerts)).Methods("GET")
+	router.Handle("/alerts/ws", scoped(auth.ScopeInsightsRead, alertStreamHandler.StreamAlertsWebSocket)).Methods("GET")
+	router.Handle("/alerts/mark-all-read", scoped(auth.ScopeInsightsWrite, alertsHandler.MarkAllRead)).Methods("POST")
+	router.Handle("/alerts/{id}", scoped(auth.ScopeInsightsWrite, alertsHandler.UpdateAlert)).Methods("PATCH")
+	router.Handle("/alert-rules", scoped(auth.ScopeInsightsRead, alertRulesHandler.ListAlertRules)).Methods("GET")
+	router.Handle("/alert-rules", scoped(auth.ScopeInsightsWrite, alertRulesHandler.CreateAlertRule)).Methods("POST")
+	router.Handle("/alert-rules/{id}", scoped(auth.ScopeInsightsRead, alertRulesHandler.GetAlertRule)).Methods("GET")
+	router.Handle("/alert-rules/{id}", scoped(auth.ScopeInsightsWrite, alertRulesHandler.UpdateAlertRule)).Methods("PUT")
+	router.Handle("/alert-rules/{id}", scoped(auth.ScopeInsightsWrite, alertRulesHandler.DeleteAlertRule)).Methods("DELETE")
+	router.Handle("/webhooks", scoped(auth.ScopeInsightsRead, webhooksHandler.ListWebhooks)).Methods("GET")
+	router.Handle("/webhooks", scoped(auth.ScopeInsightsWrite, webhooksHandler.CreateWebhook)).Methods("POST")
+	router.Handle("/webhooks/dead-letters", scoped(auth.ScopeInsightsRead, webhooksHandler.ListDeadLetters)).Methods("GET")
+	router.Handle("/webhooks/{id}", scoped(auth.ScopeInsightsRead, webhooksHandler.GetWebhook)).Methods("GET")
+	router.Handle("/webhooks/{id}", scoped(auth.ScopeInsightsWrite, webhooksHandler.UpdateWebhook)).Methods("PUT")
+	router.Handle("/webhooks/{id}", scoped(auth.ScopeInsightsWrite, webhooksHandler.DeleteWebhook)).Methods("DELETE")
+	router.Handle("/webhooks/{id}/deliveries", scoped(auth.ScopeInsightsRead, webhooksHandler.ListDeliveries)).Methods("GET")
+	router.Handle("/webhooks/{id}/deliveries/{deliveryId}/redeliver", scoped(auth.ScopeInsightsWrite, webhooksHandler.RedeliverDelivery)).Methods("POST")
+	router.Handle("/admin/users/{userId}/insights", scoped(auth.ScopeAdminRead, insightsHandler.GetUserInsights)).Methods("GET")
+	router.Handle("/admin/users/{userId}/alerts", scoped(auth.ScopeAdminRead, alertsHandler.GetUserAlerts)).Methods("GET")
 
-	// Wrap router with CORS
-	handler := corsHandler.Handler(router)
+	// Wrap router with request IDs, outside it so unmatched routes get one too, and CORS.
+	// Browsers cannot set headers on streams, so those take a stream ticket from the query.
+	streamAuth := middleware.TicketFromQuery(streamTickets, logger, "/alerts/stream", "/alerts/ws")
+	handler := corsHandler.Handler(requestid.Middleware(streamAuth(router)))
 
 	// Create HTTP server
 	server := &http.Server{
@@ -112,18 +293,42 @@ func main() {
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	// Alert streams clear these timeouts and set a deadline per write instead.
+	// Ending them on shutdown lets their connections close.
+	server.RegisterOnShutdown(alertHub.Close)
 
 	// Start server in a goroutine
 	go func() {
 		logger.Infof("Server listening on port %s", port)
 		logger.Info("API Endpoints:")
 		logger.Info("  GET /healthz - Health check")
-		logger.Info("  GET /insights - List user insights")
+		logger.Info("  GET /insights - List user and shared account insights")
 		logger.Info("  GET /insights/{id} - Get insight by ID")
-		logger.Info("  GET /alerts - List user alerts")
+		logger.Info("  GET /alerts - List user, shared account and alert rule alerts (unread, priority filters)")
+		logger.Info("  GET /alerts/unread-count - Count unread alerts")
+		logger.Info("  POST /alerts/stream/tickets - Get a single-use ticket to open an alert stream")
+		logger.Info("  GET /alerts/stream - Stream new alerts as Server-Sent Events")
+		logger.Info("  GET /alerts/ws - Stream new alerts over WebSocket")
+		logger.Info("  POST /alerts/mark-all-read - Mark all alerts read")
+		logger.Info("  PATCH /alerts/{id} - Mark an alert read or unread, dismiss or snooze it")
+		logger.Info("  GET /alert-rules - List alert rules")
+		logger.Info("  POST /alert-rules - Create an alert rule")
+		logger.Info("  GET /alert-rules/{id} - Get an alert rule")
+		logger.Info("  PUT /alert-rules/{id} - Replace an alert rule")
+		logger.Info("  DELETE /alert-rules/{id} - Delete an alert rule")
+		logger.Info("  GET /webhooks - List webhooks")
+		logger.Info("  POST /webhooks - Subscribe a webhook to events")
+		logger.Info("  GET /webhooks/dead-letters - List deliveries that failed every attempt")
+		logger.Info("  GET /webhooks/{id} - Get a webhook")
+		logger.Info("  PUT /webhooks/{id} - Replace a webhook")
+		logger.Info("  DELETE /webhooks/{id} - Delete a webhook")
+		logger.Info("  GET /webhooks/{id}/deliveries - List a webhook's deliveries")
+		logger.Info("  POST /webhooks/{id}/deliveries/{deliveryId}/redeliver - Queue a delivery again")
+		logger.Info("  GET /admin/users/{userId}/insights - List any user's insights (support, admin)")
+		logger.Info("  GET /admin/users/{userId}/alerts - List any user's alerts (support, admin)")
 		logger.Info("")
 		logger.Info("Feature Flags:")
-		logger.Infof("  api.insightsV2: %v (adds V2 suffix to titles)", flags.IsInsightsV2Enabled())
+		logger.Infof("  api.insightsV2: %v (computes insights from transactions)", flags.IsInsightsV2Enabled())
 		logger.Infof("  api.alertsEnabled: %v (enables/disables alerts endpoint)", flags.IsAlertsEnabled())
 
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
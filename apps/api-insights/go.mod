@@ -3,11 +3,28 @@ module github.com/CB-AccountStack/AccountStack/apps/api-insights
 go 1.21
 
 require (
+	github.com/CB-AccountStack/AccountStack/pkg v0.0.0
 	github.com/golang-jwt/jwt/v5 v5.2.0
 	github.com/gorilla/mux v1.8.1
 	github.com/rs/cors v1.10.1
 	github.com/sirupsen/logrus v1.9.3
-	golang.org/x/crypto v0.17.0
+	modernc.org/sqlite v1.29.0
 )
 
-require golang.org/x/sys v0.15.0 // indirect
+require (
+	github.com/dustin/go-humanize v1.0.1 // indirect
+	github.com/google/uuid v1.3.0 // indirect
+	github.com/hashicorp/golang-lru/v2 v2.0.7 // indirect
+	github.com/mattn/go-isatty v0.0.16 // indirect
+	github.com/ncruces/go-strftime v0.1.9 // indirect
+	github.com/remyoudompheng/bigfft v0.0.0-20230129092748-24d4a6f8daec // indirect
+	golang.org/x/sys v0.16.0 // indirect
+	modernc.org/gc/v3 v3.0.0-20240107210532-573471604cb6 // indirect
+	modernc.org/libc v1.41.0 // indirect
+	modernc.org/mathutil v1.6.0 // indirect
+	modernc.org/memory v1.7.2 // indirect
+	modernc.org/strutil v1.2.0 // indirect
+	modernc.org/token v1.1.0 // indirect
+)
+
+replace github.com/CB-AccountStack/AccountStack/pkg => ../../pkg
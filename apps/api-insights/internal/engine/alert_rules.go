@@ -0,0 +1,164 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/models"
+)
+
+// AlertInput is what alert rules are evaluated against: the transactions and
+// balances of the accounts a user can see
+type AlertInput struct {
+	// Transactions, oldest first
+	Transactions []*models.Transaction
+	// Balances by account ID
+	Balances map[string]float64
+	// Now is when the rules are evaluated. Category spending is summed over
+	// its calendar month.
+	Now time.Time
+}
+
+// EvaluateAlertRule returns the alerts a user's rule raises. Transaction rules
+// only match transactions made since the rule was created, so a new rule
+// does not alert about the past. Alert IDs are derived from the rule and what
+// set it off, so an alert keeps its ID, and the state saved for it, from one
+// evaluation to the next.
+func EvaluateAlertRule(rule *models.AlertRule, input *AlertInput) []*models.Alert {
+	if !rule.Enabled {
+		return nil
+	}
+	switch rule.Type {
+	case models.AlertRuleLargeTransaction, models.AlertRuleMerchant:
+		return transactionAlerts(rule, input)
+	case models.AlertRuleLowBalance:
+		return lowBalanceAlerts(rule, input)
+	case models.AlertRuleCategorySpend:
+		return categorySpendAlerts(rule, input)
+	default:
+		return nil
+	}
+}
+
+// transactionAlerts raises an alert for each transaction the rule matches
+func transactionAlerts(rule *models.AlertRule, input *AlertInput) []*models.Alert {
+	var alerts []*models.Alert
+	for _, txn := range input.Transactions {
+		if txn.Status == models.TransactionStatusFailed || txn.Date.Before(rule.CreatedAt) || !covers(rule, txn.AccountID) {
+			continue
+		}
+
+		var message string
+		switch rule.Type {
+		case models.AlertRuleLargeTransaction:
+			amount := math.Abs(txn.Amount)
+			if amount <= rule.Threshold {
+				continue
+			}
+			kind := "charge at"
+			if txn.Amount > 0 {
+				kind = "deposit from"
+			}
+			message = fmt.Sprintf("A %s %s %s on %s is over %s.",
+				money(amount), kind, merchantName(txn), txn.Date.Format("Jan 2"), money(rule.Threshold))
+		case models.AlertRuleMerchant:
+			if txn.Amount >= 0 || -txn.Amount < rule.Threshold || !strings.EqualFold(merchantName(txn), rule.Merchant) {
+				continue
+			}
+			message = fmt.Sprintf("You were charged %s by %s on %s.", money(-txn.Amount), merchantName(txn), txn.Date.Format("Jan 2"))
+		}
+		alerts = append(alerts, ruleAlert(rule, txn.ID, txn.AccountID, txn, txn.Date, message))
+	}
+	return alerts
+}
+
+// lowBalanceAlerts raises one alert while the account's balance is below the
+// threshold. It links to the latest transaction on the account since the rule
+// was created, which likely took the balance down.
+func lowBalanceAlerts(rule *models.AlertRule, input *AlertInput) []*models.Alert {
+	balance, ok := input.Balances[rule.AccountID]
+	if !ok || balance >= rule.Threshold {
+		return nil
+	}
+
+	var latest *models.Transaction
+	for _, txn := range input.Transactions {
+		if txn.AccountID == rule.AccountID && txn.Status != models.TransactionStatusFailed && !txn.Date.Before(rule.CreatedAt) {
+			latest = txn
+		}
+	}
+	at := rule.CreatedAt
+	if latest != nil {
+		at = latest.Date
+	}
+
+	message := fmt.Sprintf("The balance of %s is %s, below %s.", rule.AccountID, money(balance), money(rule.Threshold))
+	return []*models.Alert{ruleAlert(rule, "balance", rule.AccountID, latest, at, message)}
+}
+
+// categorySpendAlerts raises an alert once spending in the category this
+// month exceeds the threshold, linking to the transaction that took it over
+func categorySpendAlerts(rule *models.AlertRule, input *AlertInput) []*models.Alert {
+	now := input.Now.UTC()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	var spent float64
+	var over *models.Transaction
+	for _, txn := range input.Transactions {
+		if !isSpending(txn) || txn.Category != rule.Category || txn.Date.Before(monthStart) || !covers(rule, txn.AccountID) {
+			continue
+		}
+		spent += -txn.Amount
+		if over == nil && spent > rule.Threshold {
+			over = txn
+		}
+	}
+	if over == nil {
+		return nil
+	}
+
+	message := fmt.Sprintf("You've spent %s on %s in %s, over %s.",
+		money(spent), categoryLabel(rule.Category), now.Format("January"), money(rule.Threshold))
+	return []*models.Alert{ruleAlert(rule, now.Format("2006-01"), over.AccountID, over, over.Date, message)}
+}
+
+// covers reports whether the rule applies to an account
+func covers(rule *models.AlertRule, accountID string) bool {
+	return rule.AccountID == "" || rule.AccountID == accountID
+}
+
+// merchantName is who a transaction was with, falling back to its description
+func merchantName(txn *models.Transaction) string {
+	if name := strings.TrimSpace(txn.Merchant); name != "" {
+		return name
+	}
+	return strings.TrimSpace(txn.Description)
+}
+
+// ruleAlert creates an alert of the rule's, with an ID derived from the rule
+// and key. It links to txn, or to the account when txn is nil.
+func ruleAlert(rule *models.AlertRule, key, accountID string, txn *models.Transaction, at time.Time, message string) *models.Alert {
+	sum := sha256.Sum256([]byte(rule.ID + "/" + key))
+	alert := &models.Alert{
+		ID:        "alert-rule-" + hex.EncodeToString(sum[:8]),
+		UserID:    rule.UserID,
+		AccountID: accountID,
+		Type:      rule.Type,
+		Title:     rule.Name,
+		Message:   message,
+		Priority:  rule.Priority,
+		CreatedAt: at,
+		RuleID:    rule.ID,
+	}
+	actionURL := "/accounts/" + accountID
+	if txn != nil {
+		alert.TransactionID = txn.ID
+		actionURL = "/transactions/" + txn.ID
+	}
+	alert.ActionURL = &actionURL
+	return alert
+}
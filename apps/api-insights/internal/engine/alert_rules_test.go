@@ -0,0 +1,196 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/models"
+)
+
+// alertInput is the test history with a few December purchases, evaluated
+// on December 29th
+func alertInput() *AlertInput {
+	txns := append(history(),
+		txn(101, -612, "shopping", "Best Buy"),
+		txn(105, -15.99, "entertainment", "Netflix"),
+		txn(110, -45, "food_dining", "Chipotle"),
+		txn(115, -280, "food_dining", "Nobu"),
+	)
+	failed := txn(112, -900, "shopping", "Best Buy")
+	failed.Status = models.TransactionStatusFailed
+	txns = append(txns, failed)
+	sortByDate(txns)
+	return &AlertInput{
+		Transactions: txns,
+		Balances:     map[string]float64{"acc-001": 850},
+		Now:          time.Date(2024, 12, 29, 18, 0, 0, 0, time.UTC),
+	}
+}
+
+// testRule is an enabled rule created on December 1st
+func testRule(ruleType string) *models.AlertRule {
+	return &models.AlertRule{
+		ID:        "rule-" + ruleType,
+		UserID:    "user-001",
+		Name:      "Test rule",
+		Type:      ruleType,
+		Priority:  models.AlertPriorityHigh,
+		Enabled:   true,
+		CreatedAt: time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestEvaluateAlertRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    func(*models.AlertRule)
+		ruleFor string
+		want    []string // transactions the alerts link to, "" for the account
+		message string   // of the first alert
+	}{
+		{
+			name:    "large transaction",
+			ruleFor: models.AlertRuleLargeTransaction,
+			rule:    func(r *models.AlertRule) { r.Threshold = 500 },
+			// The September to November paychecks predate the rule, and the failed purchase is ignored
+			want:    []string{"txn-101-Best Buy"},
+			message: "A $612.00 charge at Best Buy on Dec 11 is over $500.00.",
+		},
+		{
+			name:    "large deposit",
+			ruleFor: models.AlertRuleLargeTransaction,
+			rule: func(r *models.AlertRule) {
+				r.Threshold = 500
+				r.CreatedAt = start.Add(85 * day)
+			},
+			want:    []string{"txn-090-Employer Inc", "txn-101-Best Buy"},
+			message: "A $3,000.00 deposit from Employer Inc on Nov 30 is over $500.00.",
+		},
+		{
+			name:    "merchant",
+			ruleFor: models.AlertRuleMerchant,
+			rule:    func(r *models.AlertRule) { r.Merchant = "netflix" },
+			want:    []string{"txn-105-Netflix"},
+			message: "You were charged $15.99 by Netflix on Dec 15.",
+		},
+		{
+			name:    "merchant under the minimum",
+			ruleFor: models.AlertRuleMerchant,
+			rule: func(r *models.AlertRule) {
+				r.Merchant = "Netflix"
+				r.Threshold = 20
+			},
+		},
+		{
+			name:    "low balance",
+			ruleFor: models.AlertRuleLowBalance,
+			rule: func(r *models.AlertRule) {
+				r.AccountID = "acc-001"
+				r.Threshold = 1000
+			},
+			want:    []string{"txn-119-Trader Joe's"},
+			message: "The balance of acc-001 is $850.00, below $1,000.00.",
+		},
+		{
+			name:    "balance above the threshold",
+			ruleFor: models.AlertRuleLowBalance,
+			rule: func(r *models.AlertRule) {
+				r.AccountID = "acc-001"
+				r.Threshold = 500
+			},
+		},
+		{
+			name:    "low balance without transactions since the rule",
+			ruleFor: models.AlertRuleLowBalance,
+			rule: func(r *models.AlertRule) {
+				r.AccountID = "acc-001"
+				r.Threshold = 1000
+				r.CreatedAt = time.Date(2024, 12, 29, 13, 0, 0, 0, time.UTC)
+			},
+			want:    []string{""},
+			message: "The balance of acc-001 is $850.00, below $1,000.00.",
+		},
+		{
+			name:    "category spend",
+			ruleFor: models.AlertRuleCategorySpend,
+			rule: func(r *models.AlertRule) {
+				r.Category = "food_dining"
+				r.Threshold = 300
+			},
+			want:    []string{"txn-115-Nobu"},
+			message: "You've spent $325.00 on dining in December, over $300.00.",
+		},
+		{
+			name:    "category spend under the threshold",
+			ruleFor: models.AlertRuleCategorySpend,
+			rule: func(r *models.AlertRule) {
+				r.Category = "food_dining"
+				r.Threshold = 400
+			},
+		},
+		{
+			name:    "another account",
+			ruleFor: models.AlertRuleLargeTransaction,
+			rule: func(r *models.AlertRule) {
+				r.AccountID = "acc-002"
+				r.Threshold = 500
+			},
+		},
+		{
+			name:    "disabled",
+			ruleFor: models.AlertRuleLargeTransaction,
+			rule: func(r *models.AlertRule) {
+				r.Threshold = 500
+				r.Enabled = false
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := testRule(tt.ruleFor)
+			tt.rule(rule)
+			alerts := EvaluateAlertRule(rule, alertInput())
+			if len(alerts) != len(tt.want) {
+				t.Fatalf("Expected %d alerts, got %+v", len(tt.want), alerts)
+			}
+			for i, alert := range alerts {
+				if alert.TransactionID != tt.want[i] {
+					t.Errorf("Expected alert %d to link to %q, got %q", i, tt.want[i], alert.TransactionID)
+				}
+				if alert.RuleID != rule.ID || alert.UserID != "user-001" || alert.Type != rule.Type || alert.Priority != models.AlertPriorityHigh {
+					t.Errorf("Unexpected alert %+v", alert)
+				}
+				if alert.ActionURL == nil || (*alert.ActionURL != "/transactions/"+alert.TransactionID && *alert.ActionURL != "/accounts/acc-001") {
+					t.Errorf("Expected a link to the triggering record, got %v", alert.ActionURL)
+				}
+			}
+			if len(alerts) > 0 && alerts[0].Message != tt.message {
+				t.Errorf("Expected message %q, got %q", tt.message, alerts[0].Message)
+			}
+		})
+	}
+}
+
+func TestEvaluateAlertRuleIDs(t *testing.T) {
+	rule := testRule(models.AlertRuleCategorySpend)
+	rule.Category = "food_dining"
+	rule.Threshold = 300
+
+	first := EvaluateAlertRule(rule, alertInput())
+	// More spending the same month keeps the alert
+	input := alertInput()
+	input.Transactions = append(input.Transactions, txn(119, -20, "food_dining", "Chipotle"))
+	again := EvaluateAlertRule(rule, input)
+	if len(first) != 1 || len(again) != 1 || first[0].ID != again[0].ID {
+		t.Fatalf("Expected the same alert, got %+v and %+v", first, again)
+	}
+
+	other := testRule(models.AlertRuleCategorySpend)
+	other.ID = "rule-other"
+	other.Category = "food_dining"
+	other.Threshold = 300
+	if alerts := EvaluateAlertRule(other, alertInput()); alerts[0].ID == first[0].ID {
+		t.Error("Expected alerts of different rules to have different IDs")
+	}
+}
@@ -0,0 +1,82 @@
+// Package engine computes insights from transactions with a set of rules,
+// for the insightsV2 flag, and evaluates the alert rules users set up
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/models"
+)
+
+// day is the granularity the rules work in
+const day = 24 * time.Hour
+
+// Snapshot is what rules evaluate: one user's or account's transactions and
+// the period they cover
+type Snapshot struct {
+	// Transactions, oldest first
+	Transactions []*models.Transaction
+	// Since is the start of the day of the first transaction. Periods that
+	// begin earlier are only partly known, so rules leave them out.
+	Since time.Time
+	// AsOf is the end of the day of the latest transaction. Rules look back
+	// from here, so a data set that is not updated keeps its insights.
+	AsOf time.Time
+}
+
+// Rule produces insights from a snapshot. It sets what the insight says:
+// Type, Category, Title, Description, Severity, Actionable, Recommendation
+// and Evidence. The engine fills in the rest.
+type Rule interface {
+	Evaluate(snapshot *Snapshot) []*models.Insight
+}
+
+// Engine evaluates its rules over transactions
+type Engine struct {
+	rules []Rule
+}
+
+// New creates an engine evaluating the given rules, in order
+func New(rules ...Rule) *Engine {
+	return &Engine{rules: rules}
+}
+
+// NewDefault creates an engine with every rule in this package
+func NewDefault() *Engine {
+	return New(SpendingSpikeRule{}, BudgetStatusRule{}, IncomeTrendRule{}, SubscriptionRule{})
+}
+
+// Evaluate computes userID's insights from transactions, which must be
+// oldest first. accountID is set on the insights when the transactions are
+// those of a single account rather than all of the user's.
+func (e *Engine) Evaluate(userID, accountID string, transactions []*models.Transaction) []*models.Insight {
+	if len(transactions) == 0 {
+		return nil
+	}
+	snapshot := &Snapshot{
+		Transactions: transactions,
+		Since:        transactions[0].Date.UTC().Truncate(day),
+		AsOf:         transactions[len(transactions)-1].Date.UTC().Truncate(day).Add(day),
+	}
+
+	var insights []*models.Insight
+	for _, rule := range e.rules {
+		for _, insight := range rule.Evaluate(snapshot) {
+			insight.ID = insightID(userID, accountID, insight)
+			insight.UserID = userID
+			insight.AccountID = accountID
+			insight.CreatedAt = snapshot.AsOf
+			insights = append(insights, insight)
+		}
+	}
+	return insights
+}
+
+// insightID derives a stable ID from what the insight is about, so the same
+// insight keeps its ID from one evaluation to the next
+func insightID(userID, accountID string, insight *models.Insight) string {
+	sum := sha256.Sum256([]byte(userID + "/" + accountID + "/" + insight.Type + "/" + insight.Category))
+	return "insight-" + hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,255 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/models"
+)
+
+// start is the first day of the test transactions
+var start = time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC)
+
+// txn creates a transaction dayOffset days after start
+func txn(dayOffset int, amount float64, category, merchant string) *models.Transaction {
+	return &models.Transaction{
+		ID:        fmt.Sprintf("txn-%03d-%s", dayOffset, merchant),
+		AccountID: "acc-001",
+		Date:      start.Add(time.Duration(dayOffset)*day + 12*time.Hour),
+		Amount:    amount,
+		Category:  category,
+		Merchant:  merchant,
+		Status:    "completed",
+	}
+}
+
+// history is four 30-day windows of steady spending and income. The last
+// transaction is on day 119, so the engine evaluates as of the end of
+// December 29th.
+func history() []*models.Transaction {
+	var txns []*models.Transaction
+	for d := 0; d < 120; d += 30 {
+		txns = append(txns, txn(d, 3000, "income", "Employer Inc"))
+	}
+	for d := 9; d < 120; d += 10 {
+		txns = append(txns, txn(d, -50, "groceries", "Trader Joe's"))
+	}
+	sortByDate(txns)
+	return txns
+}
+
+// byType indexes insights by type and category
+func byType(insights []*models.Insight) map[string]*models.Insight {
+	found := make(map[string]*models.Insight)
+	for _, insight := range insights {
+		found[insight.Type+"/"+insight.Category] = insight
+	}
+	return found
+}
+
+func TestEvaluateSetsCommonFields(t *testing.T) {
+	engine := NewDefault()
+	txns := append(history(), txn(119, -400, "groceries", "Whole Foods"))
+
+	insights := engine.Evaluate("user-001", "acc-001", txns)
+	if len(insights) == 0 {
+		t.Fatal("Expected insights")
+	}
+	asOf := time.Date(2024, 12, 30, 0, 0, 0, 0, time.UTC)
+	for _, insight := range insights {
+		if insight.UserID != "user-001" || insight.AccountID != "acc-001" || !insight.CreatedAt.Equal(asOf) {
+			t.Errorf("Unexpected insight %+v", insight)
+		}
+		if !strings.HasPrefix(insight.ID, "insight-") {
+			t.Errorf("Expected an insight ID, got %q", insight.ID)
+		}
+	}
+
+	// IDs are stable, and differ between users
+	again := engine.Evaluate("user-001", "acc-001", txns)
+	other := engine.Evaluate("user-002", "acc-001", txns)
+	if again[0].ID != insights[0].ID {
+		t.Errorf("Expected the same ID on each evaluation, got %s and %s", insights[0].ID, again[0].ID)
+	}
+	if other[0].ID == insights[0].ID {
+		t.Error("Expected insights of different users to have different IDs")
+	}
+
+	if insights := engine.Evaluate("user-001", "", nil); len(insights) != 0 {
+		t.Errorf("Expected no insights without transactions, got %+v", insights)
+	}
+}
+
+func TestSpendingSpikeRule(t *testing.T) {
+	tests := []struct {
+		name      string
+		extra     []*models.Transaction
+		wantSpike bool
+		severity  string
+	}{
+		{"steady spending", nil, false, ""},
+		{"spike", []*models.Transaction{txn(118, -100, "groceries", "Whole Foods")}, true, "medium"},
+		{"doubled", []*models.Transaction{txn(118, -200, "groceries", "Whole Foods")}, true, "high"},
+		{"too small to report", []*models.Transaction{txn(118, -20, "groceries", "Whole Foods")}, false, ""},
+		{"failed purchases are ignored", []*models.Transaction{{ID: "txn-failed", Date: start.Add(118 * day), Amount: -500, Category: "groceries", Status: "failed"}}, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txns := append(history(), tt.extra...)
+			sortByDate(txns)
+			insight := byType(New(SpendingSpikeRule{}).Evaluate("user-001", "", txns))["spending_alert/groceries"]
+			if (insight != nil) != tt.wantSpike {
+				t.Fatalf("Expected spike %v, got %+v", tt.wantSpike, insight)
+			}
+			if insight == nil {
+				return
+			}
+			if insight.Severity != tt.severity || !insight.Actionable {
+				t.Errorf("Expected severity %s, got %+v", tt.severity, insight)
+			}
+			if insight.Evidence["trailingAverage"] != 150 || insight.Evidence["historyWindows"] != 3 {
+				t.Errorf("Unexpected evidence %v", insight.Evidence)
+			}
+		})
+	}
+}
+
+func TestSpendingSpikeRuleNeedsHistory(t *testing.T) {
+	// Thirty days of data give no average to compare with
+	txns := []*models.Transaction{txn(0, -10, "groceries", "Aldi"), txn(29, -500, "groceries", "Aldi")}
+	if insights := New(SpendingSpikeRule{}).Evaluate("user-001", "", txns); len(insights) != 0 {
+		t.Errorf("Expected no spike without history, got %+v", insights)
+	}
+}
+
+func TestBudgetStatusRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		december float64
+		title    string
+		severity string
+	}{
+		{"on track", -50, "On track with monthly budget", "info"},
+		{"ahead of pace", -120, "Spending ahead of your monthly budget", "medium"},
+		{"over budget", -350, "Over your monthly budget", "high"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// $300 a month from September to November, then one purchase
+			// on December 10th. The transfer on September 1st is not spending.
+			txns := []*models.Transaction{
+				txn(0, -1000, "transfer", "Savings"),
+				txn(4, -300, "groceries", "Costco"),
+				txn(34, -300, "groceries", "Costco"),
+				txn(65, -300, "groceries", "Costco"),
+				txn(100, tt.december, "groceries", "Costco"),
+			}
+			insights := New(BudgetStatusRule{}).Evaluate("user-001", "", txns)
+			if len(insights) != 1 {
+				t.Fatalf("Expected a budget insight, got %+v", insights)
+			}
+			insight := insights[0]
+			if insight.Title != tt.title || insight.Severity != tt.severity {
+				t.Errorf("Expected %q (%s), got %q (%s)", tt.title, tt.severity, insight.Title, insight.Severity)
+			}
+			if insight.Evidence["budget"] != 300 || insight.Evidence["daysRemaining"] != 21 || insight.Evidence["historyMonths"] != 3 {
+				t.Errorf("Unexpected evidence %v", insight.Evidence)
+			}
+		})
+	}
+}
+
+func TestIncomeTrendRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		extra    float64
+		title    string
+		severity string
+	}{
+		{"steady income", 0, "", ""},
+		{"raise", 600, "Income increased this month", "positive"},
+		{"cut", -600, "Income decreased this month", "medium"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var txns []*models.Transaction
+			for _, txn := range history() {
+				// The latest paycheck is on day 90
+				if txn.Category == "income" && txn.Date.After(start.Add(90*day)) {
+					txn.Amount += tt.extra
+				}
+				txns = append(txns, txn)
+			}
+			insights := New(IncomeTrendRule{}).Evaluate("user-001", "", txns)
+			if tt.title == "" {
+				if len(insights) != 0 {
+					t.Errorf("Expected no income insight, got %+v", insights)
+				}
+				return
+			}
+			if len(insights) != 1 || insights[0].Title != tt.title || insights[0].Severity != tt.severity {
+				t.Fatalf("Expected %q (%s), got %+v", tt.title, tt.severity, insights)
+			}
+			if insights[0].Evidence["previousIncome"] != 3000 || insights[0].Evidence["changePercent"] != tt.extra/30 {
+				t.Errorf("Unexpected evidence %v", insights[0].Evidence)
+			}
+		})
+	}
+}
+
+func TestSubscriptionRule(t *testing.T) {
+	txns := history()
+	for _, d := range []int{28, 58, 89, 119} {
+		txns = append(txns, txn(d, -15.99, "entertainment", "Netflix"))
+	}
+	// Price went up, but only slightly
+	for d, amount := range map[int]float64{40: -9.99, 70: -9.99, 100: -10.49} {
+		txns = append(txns, txn(d, amount, "subscriptions", "Spotify"))
+	}
+	// Cancelled in October
+	txns = append(txns, txn(20, -12.99, "subscriptions", "Hulu"), txn(50, -12.99, "subscriptions", "Hulu"))
+	// Irregular
+	txns = append(txns, txn(10, -35, "shopping", "Amazon"), txn(110, -35, "shopping", "Amazon"))
+	sortByDate(txns)
+
+	insights := New(SubscriptionRule{}).Evaluate("user-001", "", txns)
+	if len(insights) != 1 {
+		t.Fatalf("Expected a subscription review, got %+v", insights)
+	}
+	insight := insights[0]
+	if insight.Description != "You have 2 active subscriptions totaling $26.48/month: Netflix, Spotify." {
+		t.Errorf("Unexpected description %q", insight.Description)
+	}
+	if insight.Evidence["subscriptions"] != 2 || insight.Evidence["monthlyTotal"] != 26.48 {
+		t.Errorf("Unexpected evidence %v", insight.Evidence)
+	}
+}
+
+func TestMoney(t *testing.T) {
+	tests := map[float64]string{
+		0:           "$0.00",
+		5.5:         "$5.50",
+		1847:        "$1,847.00",
+		1234567.891: "$1,234,567.89",
+		-123:        "-$123.00",
+	}
+	for amount, want := range tests {
+		if got := money(amount); got != want {
+			t.Errorf("money(%v): expected %s, got %s", amount, want, got)
+		}
+	}
+}
+
+// sortByDate puts transactions oldest first, as the engine expects
+func sortByDate(txns []*models.Transaction) {
+	for i := 1; i < len(txns); i++ {
+		for j := i; j > 0 && txns[j].Date.Before(txns[j-1].Date); j-- {
+			txns[j], txns[j-1] = txns[j-1], txns[j]
+		}
+	}
+}
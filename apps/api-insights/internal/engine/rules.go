@@ -0,0 +1,369 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/models"
+)
+
+// Rule parameters
+const (
+	windowDays     = 30   // Length of the rolling windows spikes and income trends compare
+	historyPeriods = 3    // Windows or months a trailing average is taken over
+	spikeRatio     = 1.25 // Spending this many times the average is a spike...
+	spikeMinimum   = 25.0 // ...if it is also at least this much over it
+	overPaceRatio  = 1.1  // Projected month spending this many times the budget is ahead of it
+	incomeChange   = 0.10 // Income changes smaller than this are not reported
+
+	subscriptionMinDays  = 25   // Charges of a subscription are this many...
+	subscriptionMaxDays  = 35   // ...to this many days apart,
+	subscriptionVariance = 0.10 // within this fraction of the latest amount
+)
+
+// Categories that move money rather than spend or earn it
+var nonSpendingCategories = map[string]bool{
+	"income":   true,
+	"transfer": true,
+	"payment":  true,
+}
+
+// SpendingSpikeRule reports categories where spending over the last 30 days
+// is well above the average of the preceding 30-day windows
+type SpendingSpikeRule struct{}
+
+// Evaluate implements Rule
+func (SpendingSpikeRule) Evaluate(s *Snapshot) []*models.Insight {
+	window := windowDays * day
+	current := s.AsOf.Add(-window)
+
+	periods := 0
+	for k := 1; k <= historyPeriods && !current.Add(-time.Duration(k)*window).Before(s.Since); k++ {
+		periods++
+	}
+	if periods == 0 {
+		return nil
+	}
+	historyStart := current.Add(-time.Duration(periods) * window)
+
+	spent := make(map[string]float64)
+	history := make(map[string]float64)
+	for _, txn := range s.Transactions {
+		if !isSpending(txn) {
+			continue
+		}
+		switch {
+		case !txn.Date.Before(current):
+			spent[txn.Category] += -txn.Amount
+		case !txn.Date.Before(historyStart):
+			history[txn.Category] += -txn.Amount
+		}
+	}
+
+	var insights []*models.Insight
+	for _, category := range sortedKeys(spent) {
+		average := history[category] / float64(periods)
+		amount := spent[category]
+		if average == 0 || amount < average*spikeRatio || amount-average < spikeMinimum {
+			continue
+		}
+
+		change := (amount - average) / average
+		severity := "medium"
+		if change >= 1 {
+			severity = "high"
+		}
+		label := categoryLabel(category)
+		insights = append(insights, &models.Insight{
+			Type:     "spending_alert",
+			Category: category,
+			Title:    fmt.Sprintf("Higher than usual %s spending", label),
+			Description: fmt.Sprintf("You've spent %s on %s in the last %d days, which is %.0f%% higher than your average of %s.",
+				money(amount), label, windowDays, change*100, money(average)),
+			Severity:       severity,
+			Actionable:     true,
+			Recommendation: recommendation(fmt.Sprintf("Review recent %s purchases and set a limit for the category.", label)),
+			Evidence: map[string]float64{
+				"currentSpend":    round(amount),
+				"trailingAverage": round(average),
+				"changePercent":   round(change * 100),
+				"windowDays":      windowDays,
+				"historyWindows":  float64(periods),
+			},
+		})
+	}
+	return insights
+}
+
+// BudgetStatusRule compares spending this month with a budget set at the
+// average of the preceding months
+type BudgetStatusRule struct{}
+
+// Evaluate implements Rule
+func (BudgetStatusRule) Evaluate(s *Snapshot) []*models.Insight {
+	today := s.AsOf.Add(-day)
+	monthStart := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	periods := 0
+	for k := 1; k <= historyPeriods && !monthStart.AddDate(0, -k, 0).Before(s.Since); k++ {
+		periods++
+	}
+	if periods == 0 {
+		return nil
+	}
+	historyStart := monthStart.AddDate(0, -periods, 0)
+
+	var spent, history float64
+	for _, txn := range s.Transactions {
+		if !isSpending(txn) {
+			continue
+		}
+		switch {
+		case !txn.Date.Before(monthStart):
+			spent += -txn.Amount
+		case !txn.Date.Before(historyStart):
+			history += -txn.Amount
+		}
+	}
+	budget := history / float64(periods)
+	if budget == 0 {
+		return nil
+	}
+
+	daysInMonth := monthStart.AddDate(0, 1, -1).Day()
+	remaining := daysInMonth - today.Day()
+	projected := spent / float64(today.Day()) * float64(daysInMonth)
+
+	insight := &models.Insight{
+		Type:     "budget_status",
+		Category: "overall",
+		Description: fmt.Sprintf("You've spent %s of your %s monthly budget with %s remaining.",
+			money(spent), money(budget), plural(remaining, "day")),
+		Evidence: map[string]float64{
+			"monthToDate":     round(spent),
+			"budget":          round(budget),
+			"projected":       round(projected),
+			"daysRemaining":   float64(remaining),
+			"historyMonths":   float64(periods),
+			"percentOfBudget": round(spent / budget * 100),
+		},
+	}
+	switch {
+	case spent >= budget:
+		insight.Title = "Over your monthly budget"
+		insight.Severity = "high"
+		insight.Actionable = true
+		insight.Recommendation = recommendation("Hold off on non-essential purchases until next month.")
+	case projected >= budget*overPaceRatio:
+		insight.Title = "Spending ahead of your monthly budget"
+		insight.Severity = "medium"
+		insight.Actionable = true
+		insight.Recommendation = recommendation(fmt.Sprintf("At this pace you will spend %s this month. Slow down on non-essential purchases.", money(projected)))
+	default:
+		insight.Title = "On track with monthly budget"
+		insight.Severity = "info"
+	}
+	return []*models.Insight{insight}
+}
+
+// IncomeTrendRule compares income over the last 30 days with the 30 days before
+type IncomeTrendRule struct{}
+
+// Evaluate implements Rule
+func (IncomeTrendRule) Evaluate(s *Snapshot) []*models.Insight {
+	window := windowDays * day
+	current := s.AsOf.Add(-window)
+	previous := current.Add(-window)
+	if previous.Before(s.Since) {
+		return nil
+	}
+
+	var earned, before float64
+	for _, txn := range s.Transactions {
+		if !isIncome(txn) {
+			continue
+		}
+		switch {
+		case !txn.Date.Before(current):
+			earned += txn.Amount
+		case !txn.Date.Before(previous):
+			before += txn.Amount
+		}
+	}
+	if before == 0 {
+		return nil
+	}
+	change := (earned - before) / before
+	if math.Abs(change) < incomeChange {
+		return nil
+	}
+
+	insight := &models.Insight{
+		Type:     "income_trend",
+		Category: "income",
+		Evidence: map[string]float64{
+			"currentIncome":  round(earned),
+			"previousIncome": round(before),
+			"changePercent":  round(change * 100),
+			"windowDays":     windowDays,
+		},
+	}
+	if change > 0 {
+		insight.Title = "Income increased this month"
+		insight.Description = fmt.Sprintf("Income is up %.0f%% compared to the previous %d days, at %s.", change*100, windowDays, money(earned))
+		insight.Severity = "positive"
+		insight.Recommendation = recommendation("Consider increasing savings or investments with extra income.")
+	} else {
+		insight.Title = "Income decreased this month"
+		insight.Description = fmt.Sprintf("Income is down %.0f%% compared to the previous %d days, at %s.", -change*100, windowDays, money(earned))
+		insight.Severity = "medium"
+		insight.Actionable = true
+		insight.Recommendation = recommendation("Review upcoming bills and adjust your budget to the lower income.")
+	}
+	return []*models.Insight{insight}
+}
+
+// SubscriptionRule finds recurring monthly charges still being made and
+// suggests reviewing them
+type SubscriptionRule struct{}
+
+// Evaluate implements Rule
+func (SubscriptionRule) Evaluate(s *Snapshot) []*models.Insight {
+	charges := make(map[string][]*models.Transaction)
+	names := make(map[string]string)
+	for _, txn := range s.Transactions {
+		if !isSpending(txn) {
+			continue
+		}
+		name := txn.Merchant
+		if name == "" {
+			name = txn.Description
+		}
+		key := strings.ToLower(strings.TrimSpace(name))
+		charges[key] = append(charges[key], txn)
+		names[key] = name
+	}
+
+	var merchants []string
+	var monthly float64
+	for _, key := range sortedKeys(charges) {
+		if amount, ok := subscriptionAmount(charges[key], s.AsOf); ok {
+			merchants = append(merchants, names[key])
+			monthly += amount
+		}
+	}
+	if len(merchants) == 0 {
+		return nil
+	}
+
+	return []*models.Insight{{
+		Type:     "subscription_review",
+		Category: "subscriptions",
+		Title:    "Review your subscriptions",
+		Description: fmt.Sprintf("You have %s totaling %s/month: %s.",
+			plural(len(merchants), "active subscription"), money(monthly), strings.Join(merchants, ", ")),
+		Severity:       "low",
+		Actionable:     true,
+		Recommendation: recommendation("Review and cancel unused subscriptions to save money."),
+		Evidence: map[string]float64{
+			"subscriptions": float64(len(merchants)),
+			"monthlyTotal":  round(monthly),
+			"annualTotal":   round(monthly * 12),
+		},
+	}}
+}
+
+// subscriptionAmount reports whether charges, oldest first, recur monthly
+// at a steady amount up to asOf, and the latest amount if they do
+func subscriptionAmount(charges []*models.Transaction, asOf time.Time) (float64, bool) {
+	if len(charges) < 2 {
+		return 0, false
+	}
+	latest := charges[len(charges)-1]
+	if asOf.Sub(latest.Date) > subscriptionMaxDays*day {
+		return 0, false
+	}
+	amount := -latest.Amount
+	for i, txn := range charges {
+		if math.Abs(-txn.Amount-amount) > amount*subscriptionVariance {
+			return 0, false
+		}
+		if i == 0 {
+			continue
+		}
+		gap := txn.Date.Sub(charges[i-1].Date)
+		if gap < subscriptionMinDays*day || gap > subscriptionMaxDays*day {
+			return 0, false
+		}
+	}
+	return amount, true
+}
+
+// isSpending reports whether a transaction is money spent
+func isSpending(txn *models.Transaction) bool {
+	return txn.Amount < 0 && txn.Status != models.TransactionStatusFailed && !nonSpendingCategories[txn.Category]
+}
+
+// isIncome reports whether a transaction is money earned
+func isIncome(txn *models.Transaction) bool {
+	return txn.Amount > 0 && txn.Status != models.TransactionStatusFailed && txn.Category == "income"
+}
+
+// sortedKeys returns the keys of m in order, so insights come out the same way each time
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// categoryLabels names the categories that do not read well as they are
+var categoryLabels = map[string]string{
+	"food_dining":    "dining",
+	"health_fitness": "health and fitness",
+}
+
+// categoryLabel turns a category such as home_improvement into words
+func categoryLabel(category string) string {
+	if label, ok := categoryLabels[category]; ok {
+		return label
+	}
+	return strings.ReplaceAll(category, "_", " ")
+}
+
+// money formats an amount in dollars, such as $1,847.50 or -$12.00
+func money(amount float64) string {
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	s := fmt.Sprintf("%.2f", amount)
+	whole, cents := s[:len(s)-3], s[len(s)-3:]
+	for i := len(whole) - 3; i > 0; i -= 3 {
+		whole = whole[:i] + "," + whole[i:]
+	}
+	return sign + "$" + whole + cents
+}
+
+// plural formats a count of things, such as "1 day" or "8 days"
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// round rounds evidence to cents
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// recommendation returns a pointer for Insight.Recommendation
+func recommendation(s string) *string {
+	return &s
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/models"
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/services"
+	"github.com/CB-AccountStack/AccountStack/pkg/middleware"
+	"github.com/CB-AccountStack/AccountStack/pkg/problem"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// AlertRulesHandler handles the alert rule endpoints under /alert-rules
+type AlertRulesHandler struct {
+	service *services.AlertRulesService
+	logger  *logrus.Logger
+}
+
+// NewAlertRulesHandler creates a new alert rules handler
+func NewAlertRulesHandler(service *services.AlertRulesService, logger *logrus.Logger) *AlertRulesHandler {
+	return &AlertRulesHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ListAlertRules handles GET /alert-rules - list the authenticated user's rules
+func (h *AlertRulesHandler) ListAlertRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.service.List(middleware.GetUserID(r))
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to retrieve alert rules")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(rules)
+}
+
+// GetAlertRule handles GET /alert-rules/{id}
+func (h *AlertRulesHandler) GetAlertRule(w http.ResponseWriter, r *http.Request) {
+	rule, err := h.service.Get(middleware.GetUserID(r), mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to retrieve alert rule")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(rule)
+}
+
+// CreateAlertRule handles POST /alert-rules
+func (h *AlertRulesHandler) CreateAlertRule(w http.ResponseWriter, r *http.Request) {
+	var req models.AlertRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	rule, err := h.service.Create(middleware.GetUserID(r), &req)
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to create alert rule")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rule)
+}
+
+// UpdateAlertRule handles PUT /alert-rules/{id} - replace a rule
+func (h *AlertRulesHandler) UpdateAlertRule(w http.ResponseWriter, r *http.Request) {
+	var req models.AlertRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	rule, err := h.service.Update(middleware.GetUserID(r), mux.Vars(r)["id"], &req)
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to update alert rule")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(rule)
+}
+
+// DeleteAlertRule handles DELETE /alert-rules/{id}
+func (h *AlertRulesHandler) DeleteAlertRule(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.Delete(middleware.GetUserID(r), mux.Vars(r)["id"]); err != nil {
+		writeError(w, r, h.logger, err, "Failed to delete alert rule")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/models"
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/repository"
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/stream"
+)
+
+// newAlertRulesRouter serves alert rules over user-001's acc-001 and
+// user-002's acc-004, and returns the database to add transactions to
+func newAlertRulesRouter(t *testing.T) (http.Handler, *sql.DB) {
+	t.Helper()
+	app, db := newAlertRulesApp(t)
+	return app.router, db
+}
+
+// newAlertRulesApp is newAlertRulesRouter with the background workers
+func newAlertRulesApp(t *testing.T) (*testApp, *sql.DB) {
+	t.Helper()
+	dbPath := filepath.Join(t.TempDir(), "rules.db")
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec(`CREATE TABLE accounts (id TEXT PRIMARY KEY, user_id TEXT NOT NULL, balance REAL NOT NULL);
+		CREATE TABLE transactions (id TEXT PRIMARY KEY, account_id TEXT NOT NULL, date TEXT NOT NULL, description TEXT NOT NULL,
+			amount REAL NOT NULL, category TEXT NOT NULL, merchant TEXT NOT NULL, status TEXT NOT NULL, type TEXT NOT NULL);
+		INSERT INTO accounts (id, user_id, balance) VALUES ('acc-001', 'user-001', 750), ('acc-004', 'user-002', 5000);
+		INSERT INTO transactions VALUES ('txn-old', 'acc-001', '2024-12-01T09:00:00.000000000Z', 'TV', -900, 'shopping', 'Best Buy', 'completed', 'debit')`); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	transactions, err := repository.NewSQLiteTransactions(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open transactions: %v", err)
+	}
+	t.Cleanup(func() { transactions.Close() })
+	grants, _ := repository.NewAccountGrantSource(repository.AccountGrantsNone, "")
+	return newTestApp(t, grants, transactions, stream.HeartbeatInterval), db
+}
+
+// createRule creates an alert rule as userID and returns it
+func createRule(t *testing.T, router http.Handler, userID, body string) *models.AlertRule {
+	t.Helper()
+	rec := sendJSON(t, router, "POST", "/alert-rules", userID, body)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var rule models.AlertRule
+	if err := json.NewDecoder(rec.Body).Decode(&rule); err != nil {
+		t.Fatalf("Failed to decode rule: %v", err)
+	}
+	return &rule
+}
+
+func TestAlertRules(t *testing.T) {
+	router, db := newAlertRulesRouter(t)
+
+	large := createRule(t, router, "user-001", `{"name": "Big purchases", "type": "large_transaction", "threshold": 500, "priority": "critical"}`)
+	if large.UserID != "user-001" || !large.Enabled || large.Priority != models.AlertPriorityCritical || large.ID == "" {
+		t.Errorf("Unexpected rule %+v", large)
+	}
+	lowBalance := createRule(t, router, "user-001", `{"name": "Checking low", "type": "low_balance", "accountId": "acc-001", "threshold": 1000}`)
+
+	// The balance is low straight away; the purchase before the rule is not alerted about
+	alerts := listAlerts(t, router, "/alerts?priority=medium", "user-001")
+	if len(alerts) != 1 || alerts[0].RuleID != lowBalance.ID || alerts[0].ActionURL == nil || *alerts[0].ActionURL != "/accounts/acc-001" {
+		t.Fatalf("Expected the low balance alert, got %+v", alerts)
+	}
+	if alerts := listAlerts(t, router, "/alerts?priority=critical", "user-001"); len(alerts) != 0 {
+		t.Errorf("Expected no large transaction alerts yet, got %+v", alerts)
+	}
+
+	// A new purchase sets the large transaction rule off
+	if _, err := db.Exec(`INSERT INTO transactions VALUES ('txn-new', 'acc-001', ?, 'Laptop', -1200, 'shopping', 'Apple', 'completed', 'debit')`,
+		time.Now().UTC().Add(time.Second).Format(time.RFC3339Nano)); err != nil {
+		t.Fatalf("Failed to insert transaction: %v", err)
+	}
+	alerts = listAlerts(t, router, "/alerts?priority=critical", "user-001")
+	if len(alerts) != 1 || alerts[0].RuleID != large.ID || alerts[0].TransactionID != "txn-new" || alerts[0].Title != "Big purchases" {
+		t.Fatalf("Expected an alert about txn-new, got %+v", alerts)
+	}
+
+	// Rule alerts keep their state like any other
+	if rec := sendJSON(t, router, "PATCH", "/alerts/"+alerts[0].ID, "user-001", `{"read": true}`); rec.Code != http.StatusOK {
+		t.Errorf("Expected the rule alert to be marked read, got %d", rec.Code)
+	}
+	if alerts := listAlerts(t, router, "/alerts?priority=critical&unread=true", "user-001"); len(alerts) != 0 {
+		t.Errorf("Expected the alert to stay read, got %+v", alerts)
+	}
+
+	// Disabling the rule with PUT removes its alerts
+	rec := sendJSON(t, router, "PUT", "/alert-rules/"+large.ID, "user-001",
+		`{"name": "Big purchases", "type": "large_transaction", "threshold": 500, "priority": "critical", "enabled": false}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var updated models.AlertRule
+	if err := json.NewDecoder(rec.Body).Decode(&updated); err != nil {
+		t.Fatalf("Failed to decode rule: %v", err)
+	}
+	if updated.Enabled || updated.ID != large.ID || !updated.CreatedAt.Equal(large.CreatedAt) {
+		t.Errorf("Expected the rule disabled in place, got %+v", updated)
+	}
+	if alerts := listAlerts(t, router, "/alerts?priority=critical", "user-001"); len(alerts) != 0 {
+		t.Errorf("Expected no alerts from a disabled rule, got %+v", alerts)
+	}
+
+	rec = doRequest(t, router, "/alert-rules", "user-001")
+	var rules []models.AlertRule
+	if err := json.NewDecoder(rec.Body).Decode(&rules); err != nil || len(rules) != 2 {
+		t.Errorf("Expected both rules, got %+v (%v)", rules, err)
+	}
+
+	if rec := sendJSON(t, router, "DELETE", "/alert-rules/"+lowBalance.ID, "user-001", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", rec.Code)
+	}
+	if alerts := listAlerts(t, router, "/alerts?priority=medium", "user-001"); len(alerts) != 0 {
+		t.Errorf("Expected the deleted rule's alert to be gone, got %+v", alerts)
+	}
+	if rec := doRequest(t, router, "/alert-rules/"+lowBalance.ID, "user-001"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected the deleted rule not to be found, got %d", rec.Code)
+	}
+}
+
+func TestAlertRuleErrors(t *testing.T) {
+	router, _ := newAlertRulesRouter(t)
+	rule := createRule(t, router, "user-001", `{"name": "Coffee", "type": "merchant", "merchant": "Starbucks"}`)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		userID string
+		body   string
+		want   int
+	}{
+		{"unknown type", "POST", "/alert-rules", "user-001", `{"name": "x", "type": "weather"}`, http.StatusBadRequest},
+		{"missing name", "POST", "/alert-rules", "user-001", `{"type": "large_transaction", "threshold": 100}`, http.StatusBadRequest},
+		{"missing threshold", "POST", "/alert-rules", "user-001", `{"name": "x", "type": "category_spend", "category": "food_dining"}`, http.StatusBadRequest},
+		{"missing account", "POST", "/alert-rules", "user-001", `{"name": "x", "type": "low_balance", "threshold": 100}`, http.StatusBadRequest},
+		{"another user's account", "POST", "/alert-rules", "user-001", `{"name": "x", "type": "low_balance", "accountId": "acc-004", "threshold": 100}`, http.StatusBadRequest},
+		{"unknown priority", "POST", "/alert-rules", "user-001", `{"name": "x", "type": "merchant", "merchant": "Uber", "priority": "urgent"}`, http.StatusBadRequest},
+		{"invalid body", "POST", "/alert-rules", "user-001", `{"threshold": "lots"}`, http.StatusBadRequest},
+		{"another user's rule", "GET", "/alert-rules/" + rule.ID, "user-002", "", http.StatusNotFound},
+		{"update another user's rule", "PUT", "/alert-rules/" + rule.ID, "user-002", `{"name": "x", "type": "merchant", "merchant": "Uber"}`, http.StatusNotFound},
+		{"delete another user's rule", "DELETE", "/alert-rules/" + rule.ID, "user-002", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := sendJSON(t, router, tt.method, tt.path, tt.userID, tt.body)
+			if rec.Code != tt.want {
+				t.Errorf("Expected status %d, got %d: %s", tt.want, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	// Read-only tokens cannot create rules
+	req := httptest.NewRequest("POST", "/alert-rules", strings.NewReader(`{"name": "x", "type": "merchant", "merchant": "Uber"}`))
+	req.Header.Set("Authorization", "Bearer "+issueScopedToken(t, "user-001", "insights:read"))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a read-only token to be refused, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/models"
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/services"
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/stream"
+	"github.com/CB-AccountStack/AccountStack/pkg/auth"
+	"github.com/CB-AccountStack/AccountStack/pkg/middleware"
+	"github.com/CB-AccountStack/AccountStack/pkg/problem"
+	"github.com/sirupsen/logrus"
+)
+
+// sseRetry is how long EventSource clients wait before reconnecting
+const sseRetry = 3 * time.Second
+
+// AlertStreamHandler pushes new alerts to clients as they appear
+type AlertStreamHandler struct {
+	alerts    *services.AlertsService
+	hub       *stream.Hub
+	watcher   *stream.Watcher
+	tickets   *auth.StreamTickets
+	origins   []string
+	heartbeat time.Duration
+	logger    *logrus.Logger
+}
+
+// NewAlertStreamHandler creates a new alert stream handler. tickets are
+// redeemed by middleware.TicketFromQuery on the stream paths, and WebSocket
+// handshakes from pages are only accepted from origins.
+func NewAlertStreamHandler(alerts *services.AlertsService, hub *stream.Hub, watcher *stream.Watcher, tickets *auth.StreamTickets, origins []string, logger *logrus.Logger) *AlertStreamHandler {
+	return &AlertStreamHandler{
+		alerts:    alerts,
+		hub:       hub,
+		watcher:   watcher,
+		tickets:   tickets,
+		origins:   origins,
+		heartbeat: stream.HeartbeatInterval,
+		logger:    logger,
+	}
+}
+
+// StreamTicketResponse is a ticket for opening an alert stream
+type StreamTicketResponse struct {
+	Ticket    string `json:"ticket"`
+	ExpiresIn int    `json:"expiresIn"` // Seconds until the ticket can no longer be used
+}
+
+// StreamMessage is a message of the WebSocket alert stream
+type StreamMessage struct {
+	Type  string        `json:"type"` // "alert", or "reset" when alerts may have been missed
+	ID    string        `json:"id,omitempty"`
+	Alert *models.Alert `json:"alert,omitempty"`
+}
+
+// CreateStreamTicket handles POST /alerts/stream/tickets - returns a
+// single-use ticket that opens one alert stream as the caller, for browsers
+// that cannot send the Authorization header on EventSource and WebSocket
+// requests
+func (h *AlertStreamHandler) CreateStreamTicket(w http.ResponseWriter, r *http.Request) {
+	ticket, _, err := h.tickets.Issue(middleware.GetClaims(r))
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to issue stream ticket")
+		problem.Write(w, r, http.StatusInternalServerError, "Failed to issue stream ticket")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(&StreamTicketResponse{
+		Ticket:    ticket,
+		ExpiresIn: int(auth.StreamTicketTTL.Seconds()),
+	})
+}
+
+// StreamAlerts handles GET /alerts/stream - pushes the authenticated user's
+// new alerts as Server-Sent Events. A client reconnecting with Last-Event-ID
+// first gets the alerts it missed, or a reset event when those are no longer
+// known and it should list its alerts again.
+func (h *AlertStreamHandler) StreamAlerts(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	sub, replay, resumed, ok := h.subscribe(w, r, userID)
+	if !ok {
+		return
+	}
+	defer sub.Close()
+
+	// The stream outlives the server's timeouts. Without a read deadline a
+	// closed connection still ends the request, and each write below sets
+	// its own deadline.
+	rc := http.NewResponseController(w)
+	if err := rc.SetReadDeadline(time.Time{}); err != nil {
+		h.logger.WithError(err).Error("Failed to clear read deadline")
+		problem.Write(w, r, http.StatusInternalServerError, "Streaming is not supported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	chunk := fmt.Sprintf("retry: %d\n\n", sseRetry.Milliseconds())
+	if !resumed {
+		chunk += "event: reset\ndata: {}\n\n"
+	}
+	for _, event := range replay {
+		chunk += sseEvent(event)
+	}
+	if err := writeSSE(w, rc, chunk); err != nil {
+		return
+	}
+
+	h.logger.WithField("userId", userID).Debug("Alert stream opened")
+	defer h.logger.WithField("userId", userID).Debug("Alert stream closed")
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-sub.Dropped():
+			// Fell behind or shutting down; the client reconnects and resumes
+			return
+		case event := <-sub.Events():
+			if err := writeSSE(w, rc, sseEvent(event)); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err := writeSSE(w, rc, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// StreamAlertsWebSocket handles GET /alerts/ws - the alert stream over
+// WebSocket, as JSON StreamMessages. A client reconnecting passes the ID of
+// the last message it got as lastEventId.
+func (h *AlertStreamHandler) StreamAlertsWebSocket(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	sub, replay, resumed, ok := h.subscribe(w, r, userID)
+	if !ok {
+		return
+	}
+	defer sub.Close()
+
+	conn, err := stream.Upgrade(w, r, h.origins)
+	if errors.Is(err, stream.ErrNotWebSocket) {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		problem.Write(w, r, http.StatusUpgradeRequired, "A WebSocket handshake is required")
+		return
+	}
+	if errors.Is(err, stream.ErrOriginNotAllowed) {
+		h.logger.WithFields(logrus.Fields{"userId": userID, "origin": r.Header.Get("Origin")}).Warn("WebSocket origin not allowed")
+		problem.Write(w, r, http.StatusForbidden, "This origin may not open the alert stream")
+		return
+	}
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to upgrade to WebSocket")
+		problem.Write(w, r, http.StatusInternalServerError, "Failed to open alert stream")
+		return
+	}
+	defer conn.Close(stream.CloseGoingAway, "")
+
+	h.logger.WithField("userId", userID).Debug("Alert stream opened")
+	defer h.logger.WithField("userId", userID).Debug("Alert stream closed")
+
+	// Clients answer each ping, so a client silent for two heartbeats is gone
+	closed := make(chan error, 1)
+	go func() {
+		closed <- conn.ReadLoop(2 * h.heartbeat)
+	}()
+
+	if !resumed {
+		if err := writeMessage(conn, &StreamMessage{Type: "reset"}); err != nil {
+			return
+		}
+	}
+	for _, event := range replay {
+		if err := writeMessage(conn, alertMessage(event)); err != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-sub.Dropped():
+			conn.Close(stream.CloseTryAgainLater, "reconnect to resume")
+			return
+		case event := <-sub.Events():
+			if err := writeMessage(conn, alertMessage(event)); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err := conn.Ping(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// subscribe opens the user's stream from the last event ID the client sent,
+// answering the request itself and returning false when it cannot
+func (h *AlertStreamHandler) subscribe(w http.ResponseWriter, r *http.Request, userID string) (*stream.Subscription, []stream.Event, bool, bool) {
+	if !h.alerts.IsAlertsEnabled() {
+		writeError(w, r, h.logger, services.ErrAlertsDisabled, "")
+		return nil, nil, false, false
+	}
+
+	var lastEventID uint64
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("lastEventId")
+	}
+	if value != "" {
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			problem.Write(w, r, http.StatusBadRequest, "Invalid last event ID. Must be the id of an event")
+			return nil, nil, false, false
+		}
+		lastEventID = id
+	}
+
+	sub, replay, resumed := h.hub.Subscribe(userID, lastEventID)
+	if err := h.watcher.Track(userID); err != nil {
+		sub.Close()
+		writeError(w, r, h.logger, err, "Failed to open alert stream")
+		return nil, nil, false, false
+	}
+	return sub, replay, resumed, true
+}
+
+// sseEvent formats an event as a Server-Sent Event
+func sseEvent(event stream.Event) string {
+	data, _ := json.Marshal(event.Alert)
+	return fmt.Sprintf("id: %d\nevent: alert\ndata: %s\n\n", event.ID, data)
+}
+
+// writeSSE writes and flushes chunk within stream.WriteWait
+func writeSSE(w http.ResponseWriter, rc *http.ResponseController, chunk string) error {
+	if err := rc.SetWriteDeadline(time.Now().Add(stream.WriteWait)); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, chunk); err != nil {
+		return err
+	}
+	return rc.Flush()
+}
+
+// alertMessage is the WebSocket message for an event
+func alertMessage(event stream.Event) *StreamMessage {
+	return &StreamMessage{Type: "alert", ID: strconv.FormatUint(event.ID, 10), Alert: event.Alert}
+}
+
+// writeMessage sends msg as a WebSocket text message
+func writeMessage(conn *stream.Conn, msg *StreamMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return conn.WriteText(data)
+}
@@ -0,0 +1,400 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/repository"
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/stream"
+	"github.com/CB-AccountStack/AccountStack/pkg/middleware"
+	"github.com/sirupsen/logrus"
+)
+
+// serverTimeout stands in for the server's read and write timeouts, which
+// streams must outlive
+const serverTimeout = 200 * time.Millisecond
+
+// newStreamServer serves the test router with short timeouts and fast
+// heartbeats, taking stream tickets from the query as main does
+func newStreamServer(t *testing.T) (*httptest.Server, http.Handler, *stream.Watcher) {
+	t.Helper()
+	grants, err := repository.NewAccountGrantSource(repository.AccountGrantsNone, "")
+	if err != nil {
+		t.Fatalf("Failed to open account grants: %v", err)
+	}
+	app := newTestApp(t, grants, nil, 50*time.Millisecond)
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	server := httptest.NewUnstartedServer(middleware.TicketFromQuery(app.tickets, logger, "/alerts/stream", "/alerts/ws")(app.router))
+	server.Config.ReadTimeout = serverTimeout
+	server.Config.WriteTimeout = serverTimeout
+	server.Start()
+	t.Cleanup(server.Close)
+	return server, app.router, app.streams
+}
+
+// setDismissed dismisses alert-001 or brings it back, so it is new to streams
+func setDismissed(t *testing.T, router http.Handler, dismissed bool) {
+	t.Helper()
+	body := `{"dismissed": false}`
+	if dismissed {
+		body = `{"dismissed": true}`
+	}
+	if rec := sendJSON(t, router, "PATCH", "/alerts/alert-001", "user-001", body); rec.Code != http.StatusOK {
+		t.Fatalf("Failed to update alert-001: %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+// streamTicket asks for a ticket to open a stream of user-001 with
+func streamTicket(t *testing.T, router http.Handler) string {
+	t.Helper()
+	rec := sendJSON(t, router, "POST", "/alerts/stream/tickets", "user-001", "")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201 from the ticket endpoint, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var ticket StreamTicketResponse
+	if err := json.NewDecoder(rec.Body).Decode(&ticket); err != nil {
+		t.Fatalf("Failed to decode ticket: %v", err)
+	}
+	return ticket.Ticket
+}
+
+// getSSE requests the event stream at path, resuming after lastEventID unless it is empty
+func getSSE(t *testing.T, server *httptest.Server, path, lastEventID string) *http.Response {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+path, nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// openSSE opens the event stream of user-001 with a new ticket, resuming after
+// lastEventID unless it is empty
+func openSSE(t *testing.T, server *httptest.Server, router http.Handler, lastEventID string) *bufio.Reader {
+	t.Helper()
+	resp := getSSE(t, server, "/alerts/stream?ticket="+streamTicket(t, router), lastEventID)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	return bufio.NewReader(resp.Body)
+}
+
+// readSSE reads the next event from the stream, as its lines
+func readSSE(t *testing.T, reader *bufio.Reader) []string {
+	t.Helper()
+	var lines []string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read stream after %v: %v", lines, err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return lines
+		}
+		lines = append(lines, line)
+	}
+}
+
+// nextAlertSSE skips heartbeats and returns the id and data of the next event
+func nextAlertSSE(t *testing.T, reader *bufio.Reader) (string, string) {
+	t.Helper()
+	for {
+		lines := readSSE(t, reader)
+		if len(lines) == 1 && lines[0] == ": ping" {
+			continue
+		}
+		if len(lines) != 3 || !strings.HasPrefix(lines[0], "id: ") || lines[1] != "event: alert" {
+			t.Fatalf("Expected an alert event, got %v", lines)
+		}
+		return strings.TrimPrefix(lines[0], "id: "), strings.TrimPrefix(lines[2], "data: ")
+	}
+}
+
+func TestStreamAlertsSSE(t *testing.T) {
+	server, router, watcher := newStreamServer(t)
+	setDismissed(t, router, true)
+
+	reader := openSSE(t, server, router, "")
+	if lines := readSSE(t, reader); len(lines) != 1 || lines[0] != "retry: 3000" {
+		t.Fatalf("Expected the retry interval first, got %v", lines)
+	}
+
+	// The stream outlives the server's timeouts, kept alive by heartbeats
+	time.Sleep(2 * serverTimeout)
+	if lines := readSSE(t, reader); len(lines) != 1 || lines[0] != ": ping" {
+		t.Fatalf("Expected a heartbeat, got %v", lines)
+	}
+
+	setDismissed(t, router, false)
+	watcher.Check()
+	firstID, data := nextAlertSSE(t, reader)
+	if !strings.Contains(data, `"id":"alert-001"`) {
+		t.Errorf("Expected alert-001, got %s", data)
+	}
+
+	setDismissed(t, router, true)
+	watcher.Check()
+	setDismissed(t, router, false)
+	watcher.Check()
+	secondID, _ := nextAlertSSE(t, reader)
+
+	// A client reconnecting gets the events after the last one it saw
+	resumed := openSSE(t, server, router, firstID)
+	readSSE(t, resumed)
+	if id, _ := nextAlertSSE(t, resumed); id != secondID {
+		t.Errorf("Expected event %s replayed, got %s", secondID, id)
+	}
+
+	// and is told to list its alerts again when those are not known
+	reset := openSSE(t, server, router, "1")
+	if lines := readSSE(t, reset); len(lines) != 1 || !strings.HasPrefix(lines[0], "retry: ") {
+		t.Fatalf("Expected the retry interval first, got %v", lines)
+	}
+	if lines := readSSE(t, reset); len(lines) != 2 || lines[0] != "event: reset" {
+		t.Errorf("Expected a reset event, got %v", lines)
+	}
+}
+
+// wsClient is the client end of a WebSocket alert stream
+type wsClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// dialWebSocket opens the WebSocket alert stream of user-001 at path
+func dialWebSocket(t *testing.T, server *httptest.Server, path string) *wsClient {
+	t.Helper()
+	client, resp := handshakeWebSocket(t, server, path, "")
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Expected the upgrade to be accepted, got %d %v", resp.StatusCode, resp.Header)
+	}
+	return client
+}
+
+// handshakeWebSocket sends the opening handshake of user-001 to path, from a
+// page at origin unless it is empty, and reads the response
+func handshakeWebSocket(t *testing.T, server *httptest.Server, path, origin string) (*wsClient, *http.Response) {
+	t.Helper()
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// The key and accept value are the example from RFC 6455
+	handshake := "GET " + path + " HTTP/1.1\r\n" +
+		"Host: " + server.Listener.Addr().String() + "\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Version: 13\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n" +
+		"Authorization: Bearer " + issueTestToken(t, "user-001") + "\r\n"
+	if origin != "" {
+		handshake += "Origin: " + origin + "\r\n"
+	}
+	handshake += "\r\n"
+	if _, err := io.WriteString(conn, handshake); err != nil {
+		t.Fatalf("Failed to send handshake: %v", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("Failed to read handshake: %v", err)
+	}
+	return &wsClient{conn: conn, reader: reader}, resp
+}
+
+// read reads the next frame the server sent
+func (c *wsClient) read(t *testing.T) (byte, []byte) {
+	t.Helper()
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		t.Fatalf("Failed to read frame: %v", err)
+	}
+	if header[1]&0x80 != 0 {
+		t.Fatal("Expected server frames to be unmasked")
+	}
+	length := int(header[1] & 0x7F)
+	if length == 126 {
+		var ext [2]byte
+		io.ReadFull(c.reader, ext[:])
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		t.Fatalf("Failed to read frame payload: %v", err)
+	}
+	return header[0] & 0x0F, payload
+}
+
+// write sends a masked frame, as clients must
+func (c *wsClient) write(t *testing.T, opcode byte, payload []byte) {
+	t.Helper()
+	mask := []byte{1, 2, 3, 4}
+	frame := append([]byte{0x80 | opcode, 0x80 | byte(len(payload))}, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		t.Fatalf("Failed to write frame: %v", err)
+	}
+}
+
+// nextMessage answers pings and returns the next message
+func (c *wsClient) nextMessage(t *testing.T) StreamMessage {
+	t.Helper()
+	for {
+		opcode, payload := c.read(t)
+		switch opcode {
+		case 0x9:
+			c.write(t, 0xA, payload)
+		case 0x1:
+			var msg StreamMessage
+			if err := json.Unmarshal(payload, &msg); err != nil {
+				t.Fatalf("Failed to decode message %s: %v", payload, err)
+			}
+			return msg
+		default:
+			t.Fatalf("Expected a message, got opcode %d", opcode)
+		}
+	}
+}
+
+func TestStreamAlertsWebSocket(t *testing.T) {
+	server, router, watcher := newStreamServer(t)
+	setDismissed(t, router, true)
+
+	client := dialWebSocket(t, server, "/alerts/ws")
+
+	// The stream outlives the server's timeouts, kept alive by pings the
+	// client answers
+	for end := time.Now().Add(2 * serverTimeout); time.Now().Before(end); {
+		opcode, payload := client.read(t)
+		if opcode != 0x9 {
+			t.Fatalf("Expected a ping, got opcode %d", opcode)
+		}
+		client.write(t, 0xA, payload)
+	}
+
+	setDismissed(t, router, false)
+	watcher.Check()
+	msg := client.nextMessage(t)
+	if msg.Type != "alert" || msg.ID == "" || msg.Alert == nil || msg.Alert.ID != "alert-001" {
+		t.Errorf("Expected alert-001, got %+v", msg)
+	}
+
+	// The server answers the closing handshake
+	client.write(t, 0x8, binary.BigEndian.AppendUint16(nil, stream.CloseNormal))
+	for {
+		opcode, payload := client.read(t)
+		if opcode == 0x9 {
+			continue
+		}
+		if opcode != 0x8 || binary.BigEndian.Uint16(payload) != stream.CloseNormal {
+			t.Errorf("Expected a normal close, got opcode %d %v", opcode, payload)
+		}
+		break
+	}
+
+	// Resuming from an unknown event starts with a reset
+	reset := dialWebSocket(t, server, "/alerts/ws?lastEventId=1")
+	if msg := reset.nextMessage(t); msg.Type != "reset" {
+		t.Errorf("Expected a reset message, got %+v", msg)
+	}
+}
+
+func TestStreamAlertsErrors(t *testing.T) {
+	router := newTestRouter(t)
+
+	tests := []struct {
+		name   string
+		path   string
+		userID string
+		want   int
+	}{
+		{"invalid last event ID", "/alerts/stream?lastEventId=latest", "user-001", http.StatusBadRequest},
+		{"no token", "/alerts/stream", "", http.StatusUnauthorized},
+		{"not a WebSocket handshake", "/alerts/ws", "user-001", http.StatusUpgradeRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(t, router, tt.path, tt.userID)
+			if rec.Code != tt.want {
+				t.Errorf("Expected status %d, got %d: %s", tt.want, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestStreamTickets(t *testing.T) {
+	server, router, _ := newStreamServer(t)
+
+	rec := sendJSON(t, router, "POST", "/alerts/stream/tickets", "user-001", "")
+	var ticket StreamTicketResponse
+	json.NewDecoder(rec.Body).Decode(&ticket)
+	if rec.Code != http.StatusCreated || ticket.Ticket == "" || ticket.ExpiresIn != 30 {
+		t.Fatalf("Expected a ticket valid for 30 seconds, got %d %+v", rec.Code, ticket)
+	}
+	if cache := rec.Header().Get("Cache-Control"); cache != "no-store" {
+		t.Errorf("Expected the ticket not to be cached, got %q", cache)
+	}
+
+	// A ticket opens one stream
+	if resp := getSSE(t, server, "/alerts/stream?ticket="+ticket.Ticket, ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected the ticket to open the stream, got %d", resp.StatusCode)
+	}
+	if resp := getSSE(t, server, "/alerts/stream?ticket="+ticket.Ticket, ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected a used ticket to be refused, got %d", resp.StatusCode)
+	}
+
+	// Access tokens are not accepted in the URL
+	if resp := getSSE(t, server, "/alerts/stream?access_token="+issueTestToken(t, "user-001"), ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected a token in the query to be refused, got %d", resp.StatusCode)
+	}
+	if resp := getSSE(t, server, "/alerts/stream?ticket="+issueTestToken(t, "user-001"), ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected a token sent as a ticket to be refused, got %d", resp.StatusCode)
+	}
+}
+
+func TestStreamAlertsWebSocketOrigin(t *testing.T) {
+	server, _, _ := newStreamServer(t)
+
+	tests := []struct {
+		name   string
+		origin string
+		want   int
+	}{
+		{"no origin", "", http.StatusSwitchingProtocols},
+		{"same origin", "http://" + server.Listener.Addr().String(), http.StatusSwitchingProtocols},
+		{"other origin", "https://evil.example.net", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, resp := handshakeWebSocket(t, server, "/alerts/ws", tt.origin)
+			if resp.StatusCode != tt.want {
+				t.Errorf("Expected status %d, got %d", tt.want, resp.StatusCode)
+			}
+		})
+	}
+}
@@ -3,9 +3,13 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
 
-	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/middleware"
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/models"
 	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/services"
+	"github.com/CB-AccountStack/AccountStack/pkg/middleware"
+	"github.com/CB-AccountStack/AccountStack/pkg/problem"
+	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 )
 
@@ -23,31 +27,98 @@ func NewAlertsHandler(service *services.AlertsService, logger *logrus.Logger) *A
 	}
 }
 
-// GetAlerts handles GET /alerts - list all alerts for the authenticated user
+// UnreadCountResponse is the body of GET /alerts/unread-count
+type UnreadCountResponse struct {
+	Unread int `json:"unread"`
+}
+
+// MarkAllReadResponse is the body of POST /alerts/mark-all-read
+type MarkAllReadResponse struct {
+	Updated int `json:"updated"`
+}
+
+// GetAlerts handles GET /alerts - list the authenticated user's alerts,
+// optionally only unread ones (unread=true) or those of one priority
 // Returns 503 Service Unavailable if the alerts feature is disabled
 func (h *AlertsHandler) GetAlerts(w http.ResponseWriter, r *http.Request) {
-	// Check if alerts feature is enabled
-	if !h.service.IsAlertsEnabled() {
-		h.logger.Warn("Alerts endpoint accessed but feature is disabled")
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusServiceUnavailable)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error":   "Service Unavailable",
-			"message": "Alerts feature is currently disabled",
-		})
+	h.writeAlerts(w, r, middleware.GetUserID(r))
+}
+
+// GetUserAlerts handles GET /admin/users/{userId}/alerts - lets support list
+// any user's alerts
+func (h *AlertsHandler) GetUserAlerts(w http.ResponseWriter, r *http.Request) {
+	h.writeAlerts(w, r, mux.Vars(r)["userId"])
+}
+
+// writeAlerts responds with userID's alerts that match the query filters
+func (h *AlertsHandler) writeAlerts(w http.ResponseWriter, r *http.Request, userID string) {
+	query := r.URL.Query()
+	filters := models.AlertFilters{Priority: query.Get("priority")}
+	if unreadStr := query.Get("unread"); unreadStr != "" {
+		unread, err := strconv.ParseBool(unreadStr)
+		if err != nil {
+			problem.Write(w, r, http.StatusBadRequest, "Invalid unread parameter. Must be true or false")
+			return
+		}
+		filters.Unread = unread
+	}
+
+	alerts, err := h.service.GetAlertsByUserID(userID, filters)
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to retrieve alerts")
 		return
 	}
 
-	userID := middleware.GetUserID(r)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(alerts)
+}
 
-	alerts, err := h.service.GetAlertsByUserID(userID)
+// GetUnreadCount handles GET /alerts/unread-count - how many of the listed
+// alerts are unread, for badges
+func (h *AlertsHandler) GetUnreadCount(w http.ResponseWriter, r *http.Request) {
+	count, err := h.service.CountUnread(middleware.GetUserID(r))
 	if err != nil {
-		h.logger.WithError(err).Error("Failed to get alerts")
-		http.Error(w, "Failed to retrieve alerts", http.StatusInternalServerError)
+		writeError(w, r, h.logger, err, "Failed to count alerts")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(alerts)
+	json.NewEncoder(w).Encode(UnreadCountResponse{Unread: count})
+}
+
+// UpdateAlert handles PATCH /alerts/{id} - mark an alert read or unread,
+// dismiss it or snooze it
+func (h *AlertsHandler) UpdateAlert(w http.ResponseWriter, r *http.Request) {
+	var update models.AlertUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		h.logger.WithError(err).Warn("Failed to decode alert update")
+		problem.Write(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	alert, err := h.service.UpdateAlert(middleware.GetUserID(r), mux.Vars(r)["id"], &update)
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to update alert")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(alert)
+}
+
+// MarkAllRead handles POST /alerts/mark-all-read - mark every alert the user
+// has not dismissed as read
+func (h *AlertsHandler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	count, err := h.service.MarkAllRead(middleware.GetUserID(r))
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to mark alerts read")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(MarkAllReadResponse{Updated: count})
 }
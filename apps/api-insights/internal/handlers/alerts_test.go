@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/models"
+)
+
+// sendJSON issues a request with a JSON body as userID, with the customer scopes
+func sendJSON(t *testing.T, router http.Handler, method, path, userID, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+issueTestToken(t, userID))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+// listAlerts returns the alerts GET path lists for userID
+func listAlerts(t *testing.T, router http.Handler, path, userID string) []models.Alert {
+	t.Helper()
+	rec := doRequest(t, router, path, userID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 from %s, got %d: %s", path, rec.Code, rec.Body.String())
+	}
+	var alerts []models.Alert
+	if err := json.NewDecoder(rec.Body).Decode(&alerts); err != nil {
+		t.Fatalf("Failed to decode alerts: %v", err)
+	}
+	return alerts
+}
+
+// unreadCount returns userID's unread alert count
+func unreadCount(t *testing.T, router http.Handler, userID string) int {
+	t.Helper()
+	rec := doRequest(t, router, "/alerts/unread-count", userID)
+	var body UnreadCountResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode unread count: %v", err)
+	}
+	return body.Unread
+}
+
+func TestAlertFilters(t *testing.T) {
+	router := newTestRouter(t)
+
+	// alert-001 is user-001's only alert, of high priority
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantCount  int
+	}{
+		{"/alerts?unread=true", http.StatusOK, 1},
+		{"/alerts?priority=high", http.StatusOK, 1},
+		{"/alerts?priority=critical", http.StatusOK, 0},
+		{"/alerts?priority=urgent", http.StatusBadRequest, 0},
+		{"/alerts?unread=maybe", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		rec := doRequest(t, router, tt.path, "user-001")
+		if rec.Code != tt.wantStatus {
+			t.Errorf("%s: expected status %d, got %d", tt.path, tt.wantStatus, rec.Code)
+			continue
+		}
+		if tt.wantStatus != http.StatusOK {
+			continue
+		}
+		var alerts []models.Alert
+		if err := json.NewDecoder(rec.Body).Decode(&alerts); err != nil {
+			t.Fatalf("Failed to decode alerts: %v", err)
+		}
+		if len(alerts) != tt.wantCount {
+			t.Errorf("%s: expected %d alerts, got %d", tt.path, tt.wantCount, len(alerts))
+		}
+	}
+}
+
+func TestUpdateAlert(t *testing.T) {
+	router := newTestRouter(t)
+
+	if count := unreadCount(t, router, "user-001"); count != 1 {
+		t.Fatalf("Expected 1 unread alert, got %d", count)
+	}
+
+	rec := sendJSON(t, router, "PATCH", "/alerts/alert-001", "user-001", `{"read": true}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var alert models.Alert
+	if err := json.NewDecoder(rec.Body).Decode(&alert); err != nil {
+		t.Fatalf("Failed to decode alert: %v", err)
+	}
+	if !alert.Read || alert.Dismissed || alert.ID != "alert-001" {
+		t.Errorf("Expected alert-001 to be read, got %+v", alert)
+	}
+	if count := unreadCount(t, router, "user-001"); count != 0 {
+		t.Errorf("Expected no unread alerts, got %d", count)
+	}
+	if alerts := listAlerts(t, router, "/alerts?unread=true", "user-001"); len(alerts) != 0 {
+		t.Errorf("Expected no unread alerts listed, got %+v", alerts)
+	}
+	if alerts := listAlerts(t, router, "/alerts", "user-001"); len(alerts) != 1 || !alerts[0].Read {
+		t.Errorf("Expected the read alert to stay listed, got %+v", alerts)
+	}
+
+	// Snoozed alerts are hidden until the snooze ends or is cleared
+	until := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	if rec := sendJSON(t, router, "PATCH", "/alerts/alert-001", "user-001", `{"snoozedUntil": "`+until+`"}`); rec.Code != http.StatusOK {
+		t.Fatalf("Expected the snooze to be accepted, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if alerts := listAlerts(t, router, "/alerts", "user-001"); len(alerts) != 0 {
+		t.Errorf("Expected the snoozed alert to be hidden, got %+v", alerts)
+	}
+	if rec := sendJSON(t, router, "PATCH", "/alerts/alert-001", "user-001", `{"snoozedUntil": null}`); rec.Code != http.StatusOK {
+		t.Fatalf("Expected the snooze to be cleared, got %d", rec.Code)
+	}
+	alerts := listAlerts(t, router, "/alerts", "user-001")
+	if len(alerts) != 1 || alerts[0].SnoozedUntil != nil || !alerts[0].Read {
+		t.Errorf("Expected the alert back, still read, got %+v", alerts)
+	}
+
+	// Dismissed alerts are hidden and left alone by mark-all-read
+	if rec := sendJSON(t, router, "PATCH", "/alerts/alert-001", "user-001", `{"read": false, "dismissed": true}`); rec.Code != http.StatusOK {
+		t.Fatalf("Expected the dismissal to be accepted, got %d", rec.Code)
+	}
+	if alerts := listAlerts(t, router, "/alerts", "user-001"); len(alerts) != 0 {
+		t.Errorf("Expected the dismissed alert to be hidden, got %+v", alerts)
+	}
+	rec = sendJSON(t, router, "POST", "/alerts/mark-all-read", "user-001", "")
+	var marked MarkAllReadResponse
+	if err := json.NewDecoder(rec.Body).Decode(&marked); err != nil || marked.Updated != 0 {
+		t.Errorf("Expected no dismissed alerts to be marked read, got %+v (%v)", marked, err)
+	}
+}
+
+func TestUpdateAlertErrors(t *testing.T) {
+	router := newTestRouter(t)
+
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	tests := []struct {
+		name   string
+		userID string
+		path   string
+		body   string
+		want   int
+	}{
+		{"another user's alert", "user-002", "/alerts/alert-001", `{"read": true}`, http.StatusNotFound},
+		{"missing alert", "user-001", "/alerts/alert-999", `{"read": true}`, http.StatusNotFound},
+		{"nothing to change", "user-001", "/alerts/alert-001", `{}`, http.StatusBadRequest},
+		{"snooze in the past", "user-001", "/alerts/alert-001", `{"snoozedUntil": "` + past + `"}`, http.StatusBadRequest},
+		{"invalid body", "user-001", "/alerts/alert-001", `{"read": "yes"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := sendJSON(t, router, "PATCH", tt.path, tt.userID, tt.body)
+			if rec.Code != tt.want {
+				t.Errorf("Expected status %d, got %d: %s", tt.want, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	// Read-only tokens, such as support impersonation, cannot change alerts
+	req := httptest.NewRequest("POST", "/alerts/mark-all-read", nil)
+	req.Header.Set("Authorization", "Bearer "+issueScopedToken(t, "user-001", "accounts:read transactions:read insights:read"))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a read-only token to be refused, got %d", rec.Code)
+	}
+}
+
+func TestMarkAllRead(t *testing.T) {
+	router := newTestRouter(t)
+
+	rec := sendJSON(t, router, "POST", "/alerts/mark-all-read", "user-001", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var body MarkAllReadResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Updated != 1 {
+		t.Fatalf("Expected one alert marked read, got %+v (%v)", body, err)
+	}
+	if count := unreadCount(t, router, "user-001"); count != 0 {
+		t.Errorf("Expected no unread alerts, got %d", count)
+	}
+
+	// Marking again changes nothing
+	rec = sendJSON(t, router, "POST", "/alerts/mark-all-read", "user-001", "")
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Updated != 0 {
+		t.Errorf("Expected nothing left to mark, got %+v (%v)", body, err)
+	}
+}
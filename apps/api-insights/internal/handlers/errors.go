@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/repository"
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/services"
+	"github.com/CB-AccountStack/AccountStack/pkg/problem"
+	"github.com/sirupsen/logrus"
+)
+
+// errorStatus is how an error returned by a service is answered
+type errorStatus struct {
+	err    error
+	status int
+	detail string // sent instead of the error's message when set
+}
+
+// errorStatuses maps the errors the services return to the status of the
+// response. Errors not listed are internal and answered with 500.
+var errorStatuses = []errorStatus{
+	{repository.ErrInsightNotFound, http.StatusNotFound, "Insight not found"},
+	{services.ErrAlertsDisabled, http.StatusServiceUnavailable, "Alerts feature is currently disabled"},
+	{services.ErrAlertNotFound, http.StatusNotFound, "Alert not found"},
+	{services.ErrInvalidAlertFilter, http.StatusBadRequest, ""},
+	{services.ErrInvalidAlertUpdate, http.StatusBadRequest, ""},
+	{services.ErrInvalidAlertRule, http.StatusBadRequest, ""},
+	{services.ErrAlertRuleNotFound, http.StatusNotFound, "Alert rule not found"},
+	{services.ErrTooManyAlertRules, http.StatusConflict, ""},
+	{services.ErrInvalidWebhook, http.StatusBadRequest, ""},
+	{services.ErrWebhookNotFound, http.StatusNotFound, "Webhook not found"},
+	{services.ErrTooManyWebhooks, http.StatusConflict, ""},
+	{services.ErrDeliveryNotFound, http.StatusNotFound, "Webhook delivery not found"},
+	{services.ErrDeliveryPending, http.StatusConflict, ""},
+	{services.ErrInvalidDeliveryFilter, http.StatusBadRequest, ""},
+}
+
+// writeError answers a failed service call with a problem response. Errors in
+// errorStatuses get their status; anything else is logged and answered with
+// 500 and fallback as the detail, so internal errors are not exposed.
+func writeError(w http.ResponseWriter, r *http.Request, logger *logrus.Logger, err error, fallback string) {
+	for _, known := range errorStatuses {
+		if errors.Is(err, known.err) {
+			detail := known.detail
+			if detail == "" {
+				detail = err.Error()
+			}
+			problem.Write(w, r, known.status, detail)
+			return
+		}
+	}
+
+	logger.WithError(err).WithField("path", r.URL.Path).Error(fallback)
+	problem.Write(w, r, http.StatusInternalServerError, fallback)
+}
@@ -4,8 +4,9 @@ import (
 	"encoding/json"
 	"net/http"
 
-	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/middleware"
 	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/services"
+	"github.com/CB-AccountStack/AccountStack/pkg/middleware"
+	"github.com/CB-AccountStack/AccountStack/pkg/problem"
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 )
@@ -26,12 +27,20 @@ func NewInsightsHandler(service *services.InsightsService, logger *logrus.Logger
 
 // GetInsights handles GET /insights - list all insights for the authenticated user
 func (h *InsightsHandler) GetInsights(w http.ResponseWriter, r *http.Request) {
-	userID := middleware.GetUserID(r)
+	h.writeInsights(w, r, middleware.GetUserID(r))
+}
+
+// GetUserInsights handles GET /admin/users/{userId}/insights - lets support
+// list any user's insights
+func (h *InsightsHandler) GetUserInsights(w http.ResponseWriter, r *http.Request) {
+	h.writeInsights(w, r, mux.Vars(r)["userId"])
+}
 
+// writeInsights responds with userID's insights
+func (h *InsightsHandler) writeInsights(w http.ResponseWriter, r *http.Request, userID string) {
 	insights, err := h.service.GetInsightsByUserID(userID)
 	if err != nil {
-		h.logger.WithError(err).Error("Failed to get insights")
-		http.Error(w, "Failed to retrieve insights", http.StatusInternalServerError)
+		writeError(w, r, h.logger, err, "Failed to retrieve insights")
 		return
 	}
 
@@ -46,21 +55,27 @@ func (h *InsightsHandler) GetInsightByID(w http.ResponseWriter, r *http.Request)
 	insightID := vars["id"]
 	userID := middleware.GetUserID(r)
 
-	insight, err := h.service.GetInsightByID(insightID)
+	insight, err := h.service.GetInsightByID(userID, insightID)
 	if err != nil {
-		h.logger.WithError(err).WithField("insightId", insightID).Error("Failed to get insight")
-		http.Error(w, "Insight not found", http.StatusNotFound)
+		writeError(w, r, h.logger, err, "Failed to retrieve insight")
 		return
 	}
 
-	// Verify the insight belongs to the authenticated user
-	if insight.UserID != userID {
+	// Verify the insight belongs to the authenticated user or is about an
+	// account shared with them
+	allowed, err := h.service.HasAccess(insight, userID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to retrieve insight")
+		return
+	}
+	if !allowed {
 		h.logger.WithFields(logrus.Fields{
 			"insightId":  insightID,
 			"userId":     userID,
 			"ownerId":    insight.UserID,
 		}).Warn("User attempted to access another user's insight")
-		http.Error(w, "Forbidden", http.StatusForbidden)
+		// Answered like a missing insight, so IDs cannot be probed
+		problem.Write(w, r, http.StatusNotFound, "Insight not found")
 		return
 	}
 
@@ -0,0 +1,502 @@
+package handlers
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/features"
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/models"
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/repository"
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/services"
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/stream"
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/webhook"
+	"github.com/CB-AccountStack/AccountStack/pkg/auth"
+	"github.com/CB-AccountStack/AccountStack/pkg/middleware"
+	"github.com/CB-AccountStack/AccountStack/pkg/problem"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// testKeyID names testSigningKey in the kid header, as api-accounts does
+const testKeyID = "test"
+
+// testSigningKey stands in for the api-accounts signing key
+var testPublicKey, testSigningKey, _ = ed25519.GenerateKey(rand.Reader)
+
+// testAPIKey is a personal API key of user-001's, as api-accounts issues them
+const testAPIKey = "ask_test"
+
+// customerScope is what api-accounts grants users with the user role
+const customerScope = "accounts:read accounts:write transactions:read transactions:write insights:read insights:write"
+
+// issueTestToken signs a customer access token for userID the way api-accounts does
+func issueTestToken(t *testing.T, userID string) string {
+	t.Helper()
+	return issueScopedToken(t, userID, customerScope)
+}
+
+// issueScopedToken signs an access token for userID with the given scopes
+func issueScopedToken(t *testing.T, userID, scope string) string {
+	t.Helper()
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		t.Fatalf("Failed to generate jti: %v", err)
+	}
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, &auth.Claims{
+		UserID: userID,
+		Email:  userID + "@example.com",
+		Scope:  scope,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        hex.EncodeToString(id),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	})
+	token.Header["kid"] = testKeyID
+	signed, err := token.SignedString(testSigningKey)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return signed
+}
+
+// newTestRouter wires the real handlers and auth middleware over the test fixtures
+func newTestRouter(t *testing.T) *mux.Router {
+	t.Helper()
+	grants, err := repository.NewAccountGrantSource(repository.AccountGrantsNone, "")
+	if err != nil {
+		t.Fatalf("Failed to open account grants: %v", err)
+	}
+	return newGrantsRouter(t, grants)
+}
+
+// newGrantsRouter is newTestRouter with accounts shared through grants
+func newGrantsRouter(t *testing.T, grants repository.AccountGrantSource) *mux.Router {
+	t.Helper()
+	return newInsightsRouter(t, grants, nil)
+}
+
+// newInsightsRouter is newGrantsRouter with the insightsV2 flag on and
+// insights computed from transactions, unless transactions is nil. Alert
+// rules are evaluated over transactions either way.
+func newInsightsRouter(t *testing.T, grants repository.AccountGrantSource, transactions repository.TransactionSource) *mux.Router {
+	t.Helper()
+	return newTestApp(t, grants, transactions, stream.HeartbeatInterval).router
+}
+
+// testApp is the router of newInsightsRouter with the workers main runs in
+// the background. They do not run here: tests call them.
+type testApp struct {
+	router     *mux.Router
+	streams    *stream.Watcher
+	tickets    *auth.StreamTickets
+	webhooks   *webhook.Watcher
+	dispatcher *webhook.Dispatcher
+}
+
+// newTestApp is newInsightsRouter with alert streams pinged every heartbeat
+func newTestApp(t *testing.T, grants repository.AccountGrantSource, transactions repository.TransactionSource, heartbeat time.Duration) *testApp {
+	t.Helper()
+	t.Setenv("FEATURE_INSIGHTS_V2", strconv.FormatBool(transactions != nil))
+	t.Setenv("FEATURE_ALERTS_ENABLED", "true")
+	if transactions == nil {
+		transactions, _ = repository.NewTransactionSource(repository.TransactionsNone, "")
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	flags, err := features.Initialize("", logger)
+	if err != nil {
+		t.Fatalf("Failed to initialize flags: %v", err)
+	}
+
+	repo, err := repository.NewRepository("testdata", logger)
+	if err != nil {
+		t.Fatalf("Failed to load test data: %v", err)
+	}
+
+	securityEvents, err := repository.NewSecurityEventSource(repository.SecurityEventsNone, "")
+	if err != nil {
+		t.Fatalf("Failed to open security events: %v", err)
+	}
+
+	insightsHandler := NewInsightsHandler(services.NewInsightsService(repo, grants, transactions, flags, logger), logger)
+	alertRulesService := services.NewAlertRulesService(repository.NewMemoryAlertRules(), grants, transactions, logger)
+	alertsService := services.NewAlertsService(repo, securityEvents, grants, repository.NewMemoryAlertStates(), alertRulesService, flags, logger)
+	alertsHandler := NewAlertsHandler(alertsService, logger)
+	alertRulesHandler := NewAlertRulesHandler(alertRulesService, logger)
+	hub := stream.NewHub()
+	watcher := stream.NewWatcher(hub, alertsService, time.Hour, logger)
+	tickets := auth.NewStreamTickets()
+	alertStreamHandler := NewAlertStreamHandler(alertsService, hub, watcher, tickets, nil, logger)
+	alertStreamHandler.heartbeat = heartbeat
+	webhookStore := repository.NewMemoryWebhooks()
+	// As in development, so tests can deliver to receivers on this machine
+	webhooksService := services.NewWebhooksService(webhookStore, grants, transactions, true, logger)
+	webhooksHandler := NewWebhooksHandler(webhooksService, logger)
+
+	apiKeys := auth.NewMemoryAPIKeyStore()
+	if err := apiKeys.Create(&auth.APIKey{ID: "key-test", UserID: "user-001", KeyHash: auth.HashAPIKey(testAPIKey), Scope: auth.ScopeInsightsRead}); err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+
+	router := mux.NewRouter()
+	router.Use(middleware.AuthMiddleware(
+		auth.NewVerifier(auth.StaticKeys{testKeyID: testPublicKey}),
+		auth.NewMemoryRevocationList(),
+		apiKeys,
+		logger,
+	))
+	scoped := func(scope string, h http.HandlerFunc) http.Handler {
+		return middleware.RequireScope(scope, logger)(h)
+	}
+	router.Handle("/insights", scoped(auth.ScopeInsightsRead, insightsHandler.GetInsights)).Methods("GET")
+	router.Handle("/insights/{id}", scoped(auth.ScopeInsightsRead, insightsHandler.GetInsightByID)).Methods("GET")
+	router.Handle("/alerts", scoped(auth.ScopeInsightsRead, alertsHandler.GetAlerts)).Methods("GET")
+	router.Handle("/alerts/unread-count", scoped(auth.ScopeInsightsRead, alertsHandler.GetUnreadCount)).Methods("GET")
+	router.Handle("/alerts/stream/tickets", scoped(auth.ScopeInsightsRead, alertStreamHandler.CreateStreamTicket)).Methods("POST")
+	router.Handle("/alerts/stream", scoped(auth.ScopeInsightsRead, alertStreamHandler.StreamAlerts)).Methods("GET")
+	router.Handle("/alerts/ws", scoped(auth.ScopeInsightsRead, alertStreamHandler.StreamAlertsWebSocket)).Methods("GET")
+	router.Handle("/alerts/mark-all-read", scoped(auth.ScopeInsightsWrite, alertsHandler.MarkAllRead)).Methods("POST")
+	router.Handle("/alerts/{id}", scoped(auth.ScopeInsightsWrite, alertsHandler.UpdateAlert)).Methods("PATCH")
+	router.Handle("/alert-rules", scoped(auth.ScopeInsightsRead, alertRulesHandler.ListAlertRules)).Methods("GET")
+	router.Handle("/alert-rules", scoped(auth.ScopeInsightsWrite, alertRulesHandler.CreateAlertRule)).Methods("POST")
+	router.Handle("/alert-rules/{id}", scoped(auth.ScopeInsightsRead, alertRulesHandler.GetAlertRule)).Methods("GET")
+	router.Handle("/alert-rules/{id}", scoped(auth.ScopeInsightsWrite, alertRulesHandler.UpdateAlertRule)).Methods("PUT")
+	router.Handle("/alert-rules/{id}", scoped(auth.ScopeInsightsWrite, alertRulesHandler.DeleteAlertRule)).Methods("DELETE")
+	router.Handle("/webhooks", scoped(auth.ScopeInsightsRead, webhooksHandler.ListWebhooks)).Methods("GET")
+	router.Handle("/webhooks", scoped(auth.ScopeInsightsWrite, webhooksHandler.CreateWebhook)).Methods("POST")
+	router.Handle("/webhooks/dead-letters", scoped(auth.ScopeInsightsRead, webhooksHandler.ListDeadLetters)).Methods("GET")
+	router.Handle("/webhooks/{id}", scoped(auth.ScopeInsightsRead, webhooksHandler.GetWebhook)).Methods("GET")
+	router.Handle("/webhooks/{id}", scoped(auth.ScopeInsightsWrite, webhooksHandler.UpdateWebhook)).Methods("PUT")
+	router.Handle("/webhooks/{id}", scoped(auth.ScopeInsightsWrite, webhooksHandler.DeleteWebhook)).Methods("DELETE")
+	router.Handle("/webhooks/{id}/deliveries", scoped(auth.ScopeInsightsRead, webhooksHandler.ListDeliveries)).Methods("GET")
+	router.Handle("/webhooks/{id}/deliveries/{deliveryId}/redeliver", scoped(auth.ScopeInsightsWrite, webhooksHandler.RedeliverDelivery)).Methods("POST")
+	router.Handle("/admin/users/{userId}/insights", scoped(auth.ScopeAdminRead, insightsHandler.GetUserInsights)).Methods("GET")
+	router.Handle("/admin/users/{userId}/alerts", scoped(auth.ScopeAdminRead, alertsHandler.GetUserAlerts)).Methods("GET")
+	return &testApp{
+		router:     router,
+		streams:    watcher,
+		tickets:    tickets,
+		webhooks:   webhook.NewWatcher(webhooksService, alertsService, time.Hour, logger),
+		dispatcher: webhook.NewDispatcher(webhookStore, time.Hour, true, logger),
+	}
+}
+
+// doRequest issues a GET as userID, or anonymously when userID is empty
+func doRequest(t *testing.T, router http.Handler, path, userID string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest("GET", path, nil)
+	if userID != "" {
+		req.Header.Set("Authorization", "Bearer "+issueTestToken(t, userID))
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGetInsightByIDIsolation(t *testing.T) {
+	router := newTestRouter(t)
+
+	tests := []struct {
+		name       string
+		userID     string
+		insightID  string
+		wantStatus int
+	}{
+		{"owner can read", "user-001", "insight-001", http.StatusOK},
+		{"second owner can read", "user-002", "insight-006", http.StatusOK},
+		{"other user's insight", "user-002", "insight-001", http.StatusNotFound},
+		{"other user's insight reversed", "user-001", "insight-006", http.StatusNotFound},
+		{"unknown user", "user-999", "insight-001", http.StatusNotFound},
+		{"missing insight", "user-001", "insight-999", http.StatusNotFound},
+		{"no token", "", "insight-001", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(t, router, "/insights/"+tt.insightID, tt.userID)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if rec.Code != http.StatusOK && strings.Contains(rec.Body.String(), "description") {
+				t.Errorf("Response leaked insight data: %s", rec.Body.String())
+			}
+			if rec.Code == http.StatusNotFound && !strings.Contains(rec.Body.String(), `"detail":"Insight not found"`) {
+				t.Errorf("Expected the same answer as for a missing insight, got %s", rec.Body.String())
+			}
+			if rec.Code != http.StatusOK && rec.Header().Get("Content-Type") != problem.ContentType {
+				t.Errorf("Expected a problem response, got %q", rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestListEndpointsIsolation(t *testing.T) {
+	router := newTestRouter(t)
+
+	rec := doRequest(t, router, "/insights", "user-002")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	var insights []models.Insight
+	if err := json.NewDecoder(rec.Body).Decode(&insights); err != nil {
+		t.Fatalf("Failed to decode insights: %v", err)
+	}
+	for _, insight := range insights {
+		if insight.UserID != "user-002" {
+			t.Errorf("Response contains another user's insight %s", insight.ID)
+		}
+	}
+
+	// insight-001 is a medium severity actionable insight, so user-001 has an alert
+	// derived from it that user-002 must not see
+	rec = doRequest(t, router, "/alerts", "user-002")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	var alerts []models.Alert
+	if err := json.NewDecoder(rec.Body).Decode(&alerts); err != nil {
+		t.Fatalf("Failed to decode alerts: %v", err)
+	}
+	for _, alert := range alerts {
+		if alert.UserID != "user-002" {
+			t.Errorf("Response contains another user's alert %s", alert.ID)
+		}
+	}
+}
+
+func TestAdminInsightsScopes(t *testing.T) {
+	router := newTestRouter(t)
+
+	tests := []struct {
+		name   string
+		path   string
+		scope  string
+		userID string
+		want   string
+		status int
+	}{
+		{"support lists any user's insights", "/admin/users/user-002/insights", customerScope + " admin:read", "user-900", "user-002", http.StatusOK},
+		{"support lists any user's alerts", "/admin/users/user-001/alerts", customerScope + " admin:read", "user-900", "user-001", http.StatusOK},
+		{"customer cannot use admin routes", "/admin/users/user-002/insights", customerScope, "user-001", "", http.StatusForbidden},
+		{"token without scopes", "/insights", "", "user-001", "", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+issueScopedToken(t, tt.userID, tt.scope))
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+
+			var items []struct {
+				UserID string `json:"userId"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&items); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if len(items) == 0 {
+				t.Fatal("Expected results for the user")
+			}
+			for _, item := range items {
+				if item.UserID != tt.want {
+					t.Errorf("Expected only %s's items, got one for %s", tt.want, item.UserID)
+				}
+			}
+		})
+	}
+}
+
+func TestSharedAccountInsights(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "shared.db")
+	grants, err := repository.NewSQLiteAccountGrants(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open account grants: %v", err)
+	}
+	t.Cleanup(func() { grants.Close() })
+
+	// user-001 shared acc-001 with user-002 as api-accounts records it
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`INSERT INTO account_grants (account_id, user_id, access, granted_by, created_at)
+		VALUES ('acc-001', 'user-002', 'viewer', 'user-001', '2024-12-01T12:00:00Z')`); err != nil {
+		t.Fatalf("Failed to grant access: %v", err)
+	}
+
+	router := newGrantsRouter(t, grants)
+
+	if rec := doRequest(t, router, "/insights/insight-001", "user-002"); rec.Code != http.StatusOK {
+		t.Errorf("Expected a shared account's insight to be readable, got %d", rec.Code)
+	}
+	if rec := doRequest(t, router, "/insights/insight-006", "user-001"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected sharing to be one way, got %d", rec.Code)
+	}
+
+	rec := doRequest(t, router, "/insights", "user-002")
+	var insights []models.Insight
+	if err := json.NewDecoder(rec.Body).Decode(&insights); err != nil {
+		t.Fatalf("Failed to decode insights: %v", err)
+	}
+	if len(insights) != 2 || insights[0].ID != "insight-006" || insights[1].ID != "insight-001" {
+		t.Errorf("Expected user-002's insight followed by the shared one, got %+v", insights)
+	}
+
+	// The alert derived from insight-001 is about acc-001 too
+	rec = doRequest(t, router, "/alerts", "user-002")
+	var alerts []models.Alert
+	if err := json.NewDecoder(rec.Body).Decode(&alerts); err != nil {
+		t.Fatalf("Failed to decode alerts: %v", err)
+	}
+	if len(alerts) != 1 || alerts[0].AccountID != "acc-001" {
+		t.Errorf("Expected the shared account's alert, got %+v", alerts)
+	}
+}
+
+func TestAPIKeyRequests(t *testing.T) {
+	router := newTestRouter(t)
+	get := func(path, authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", authorization)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/insights", "ApiKey "+testAPIKey)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected the key to be accepted, got %d", rec.Code)
+	}
+	var insights []models.Insight
+	if err := json.NewDecoder(rec.Body).Decode(&insights); err != nil {
+		t.Fatalf("Failed to decode insights: %v", err)
+	}
+	if len(insights) != 1 || insights[0].UserID != "user-001" {
+		t.Errorf("Expected the key's user's insights, got %+v", insights)
+	}
+
+	if rec := get("/insights/insight-006", "ApiKey "+testAPIKey); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected another user's insight to be refused, got %d", rec.Code)
+	}
+	if rec := get("/admin/users/user-002/insights", "ApiKey "+testAPIKey); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected the key's scopes to apply, got %d", rec.Code)
+	}
+	if rec := get("/insights", "ApiKey ask_unknown"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected an unknown key to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestComputedInsights(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "shared.db")
+	grants, err := repository.NewSQLiteAccountGrants(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open account grants: %v", err)
+	}
+	t.Cleanup(func() { grants.Close() })
+
+	// The tables api-transactions creates, with four months of groceries on
+	// user-001's acc-001 ending in a spike, shared with user-002
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE accounts (id TEXT PRIMARY KEY, user_id TEXT NOT NULL);
+		CREATE TABLE transactions (id TEXT PRIMARY KEY, account_id TEXT NOT NULL, date TEXT NOT NULL, description TEXT NOT NULL,
+			amount REAL NOT NULL, category TEXT NOT NULL, merchant TEXT NOT NULL, status TEXT NOT NULL, type TEXT NOT NULL);
+		INSERT INTO accounts (id, user_id) VALUES ('acc-001', 'user-001'), ('acc-004', 'user-002');
+		INSERT INTO account_grants (account_id, user_id, access, granted_by, created_at)
+			VALUES ('acc-001', 'user-002', 'viewer', 'user-001', '2024-12-01T12:00:00Z')`); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+	start := time.Date(2024, 9, 1, 12, 0, 0, 0, time.UTC)
+	for d := 0; d < 120; d += 10 {
+		amount := -50.0
+		if d == 110 {
+			amount = -200
+		}
+		if _, err := db.Exec(`INSERT INTO transactions VALUES (?, 'acc-001', ?, 'Groceries', ?, 'groceries', 'Trader Joe''s', 'completed', 'debit')`,
+			"txn-"+strconv.Itoa(d), start.AddDate(0, 0, d).Format(time.RFC3339Nano), amount); err != nil {
+			t.Fatalf("Failed to insert transaction: %v", err)
+		}
+	}
+
+	transactions, err := repository.NewSQLiteTransactions(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open transactions: %v", err)
+	}
+	t.Cleanup(func() { transactions.Close() })
+	router := newInsightsRouter(t, grants, transactions)
+
+	rec := doRequest(t, router, "/insights", "user-001")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var insights []models.Insight
+	if err := json.NewDecoder(rec.Body).Decode(&insights); err != nil {
+		t.Fatalf("Failed to decode insights: %v", err)
+	}
+	var spike *models.Insight
+	for i, insight := range insights {
+		if insight.UserID != "user-001" || insight.AccountID != "" {
+			t.Errorf("Unexpected owner on %+v", insight)
+		}
+		if insight.Type == "spending_alert" && insight.Category == "groceries" {
+			spike = &insights[i]
+		}
+	}
+	if spike == nil {
+		t.Fatalf("Expected a groceries spending spike, got %+v", insights)
+	}
+	if spike.Evidence["currentSpend"] != 300 || spike.Evidence["trailingAverage"] != 150 {
+		t.Errorf("Expected the spike's figures as evidence, got %v", spike.Evidence)
+	}
+
+	if rec := doRequest(t, router, "/insights/"+spike.ID, "user-001"); rec.Code != http.StatusOK {
+		t.Errorf("Expected the computed insight to be readable by ID, got %d", rec.Code)
+	}
+	if rec := doRequest(t, router, "/insights/"+spike.ID, "user-003"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected another user's computed insight not to be found, got %d", rec.Code)
+	}
+
+	// user-002 sees the insights about the shared account, computed for it alone
+	rec = doRequest(t, router, "/insights", "user-002")
+	var shared []models.Insight
+	if err := json.NewDecoder(rec.Body).Decode(&shared); err != nil {
+		t.Fatalf("Failed to decode insights: %v", err)
+	}
+	if len(shared) == 0 {
+		t.Fatal("Expected insights about the shared account")
+	}
+	for _, insight := range shared {
+		if insight.UserID != "user-001" || insight.AccountID != "acc-001" {
+			t.Errorf("Expected an insight about acc-001, got %+v", insight)
+		}
+	}
+	if rec := doRequest(t, router, "/insights/"+shared[0].ID, "user-002"); rec.Code != http.StatusOK {
+		t.Errorf("Expected a shared account's computed insight to be readable, got %d", rec.Code)
+	}
+}
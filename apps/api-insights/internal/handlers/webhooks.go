@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/models"
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/services"
+	"github.com/CB-AccountStack/AccountStack/pkg/middleware"
+	"github.com/CB-AccountStack/AccountStack/pkg/problem"
+	"github.com/CB-AccountStack/AccountStack/pkg/respond"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// WebhooksHandler handles the webhook endpoints under /webhooks
+type WebhooksHandler struct {
+	service *services.WebhooksService
+	logger  *logrus.Logger
+}
+
+// NewWebhooksHandler creates a new webhooks handler
+func NewWebhooksHandler(service *services.WebhooksService, logger *logrus.Logger) *WebhooksHandler {
+	return &WebhooksHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ListWebhooks handles GET /webhooks - list the authenticated user's webhooks
+func (h *WebhooksHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := h.service.List(middleware.GetUserID(r))
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to retrieve webhooks")
+		return
+	}
+	respond.JSON(w, http.StatusOK, webhooks)
+}
+
+// GetWebhook handles GET /webhooks/{id}
+func (h *WebhooksHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	webhook, err := h.service.Get(middleware.GetUserID(r), mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to retrieve webhook")
+		return
+	}
+	respond.JSON(w, http.StatusOK, webhook)
+}
+
+// CreateWebhook handles POST /webhooks - the response is the only one to
+// include the signing secret
+func (h *WebhooksHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req models.WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	webhook, err := h.service.Create(middleware.GetUserID(r), &req)
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to create webhook")
+		return
+	}
+	respond.JSON(w, http.StatusCreated, webhook)
+}
+
+// UpdateWebhook handles PUT /webhooks/{id} - replace a webhook
+func (h *WebhooksHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req models.WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	webhook, err := h.service.Update(middleware.GetUserID(r), mux.Vars(r)["id"], &req)
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to update webhook")
+		return
+	}
+	respond.JSON(w, http.StatusOK, webhook)
+}
+
+// DeleteWebhook handles DELETE /webhooks/{id}
+func (h *WebhooksHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.Delete(middleware.GetUserID(r), mux.Vars(r)["id"]); err != nil {
+		writeError(w, r, h.logger, err, "Failed to delete webhook")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries handles GET /webhooks/{id}/deliveries - the delivery log of
+// a webhook, optionally only deliveries with one status
+func (h *WebhooksHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	deliveries, err := h.service.ListDeliveries(middleware.GetUserID(r), mux.Vars(r)["id"], r.URL.Query().Get("status"))
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to retrieve webhook deliveries")
+		return
+	}
+	respond.JSON(w, http.StatusOK, deliveries)
+}
+
+// ListDeadLetters handles GET /webhooks/dead-letters - the deliveries to any
+// of the user's webhooks that failed every attempt
+func (h *WebhooksHandler) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	deliveries, err := h.service.ListDeadLetters(middleware.GetUserID(r))
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to retrieve webhook deliveries")
+		return
+	}
+	respond.JSON(w, http.StatusOK, deliveries)
+}
+
+// RedeliverDelivery handles POST /webhooks/{id}/deliveries/{deliveryId}/redeliver -
+// queue a delivery again, such as one on the dead-letter list
+func (h *WebhooksHandler) RedeliverDelivery(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	delivery, err := h.service.Redeliver(middleware.GetUserID(r), vars["id"], vars["deliveryId"])
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to queue webhook delivery")
+		return
+	}
+	respond.JSON(w, http.StatusAccepted, delivery)
+}
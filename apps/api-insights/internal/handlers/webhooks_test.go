@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/models"
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/repository"
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/services"
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/webhook"
+	"github.com/sirupsen/logrus"
+)
+
+// createWebhook creates a webhook as userID and returns it with its secret
+func createWebhook(t *testing.T, router http.Handler, userID, body string) *models.Webhook {
+	t.Helper()
+	rec := sendJSON(t, router, "POST", "/webhooks", userID, body)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created models.Webhook
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode webhook: %v", err)
+	}
+	return &created
+}
+
+// listDeliveries returns the deliveries GET path lists for userID
+func listDeliveries(t *testing.T, router http.Handler, path, userID string) []models.WebhookDelivery {
+	t.Helper()
+	rec := doRequest(t, router, path, userID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var deliveries []models.WebhookDelivery
+	if err := json.NewDecoder(rec.Body).Decode(&deliveries); err != nil {
+		t.Fatalf("Failed to decode deliveries: %v", err)
+	}
+	return deliveries
+}
+
+func TestWebhooks(t *testing.T) {
+	router := newTestRouter(t)
+
+	created := createWebhook(t, router, "user-001", `{"url": "https://hooks.example.com/accountstack", "events": ["alert.created", "transaction.created", "alert.created"]}`)
+	if created.ID == "" || created.UserID != "user-001" || !created.Enabled || !strings.HasPrefix(created.Secret, "whsec_") {
+		t.Errorf("Unexpected webhook %+v", created)
+	}
+	if len(created.Events) != 2 {
+		t.Errorf("Expected the events without duplicates, got %v", created.Events)
+	}
+
+	// The secret is only shown once
+	rec := doRequest(t, router, "/webhooks/"+created.ID, "user-001")
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "secret") {
+		t.Errorf("Expected the webhook without its secret, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = doRequest(t, router, "/webhooks", "user-001")
+	var webhooks []models.Webhook
+	if err := json.NewDecoder(rec.Body).Decode(&webhooks); err != nil || len(webhooks) != 1 || webhooks[0].Secret != "" {
+		t.Errorf("Expected the webhook listed without its secret, got %+v (%v)", webhooks, err)
+	}
+	if rec := doRequest(t, router, "/webhooks", "user-002"); strings.TrimSpace(rec.Body.String()) != "[]" {
+		t.Errorf("Expected user-002 to have no webhooks, got %s", rec.Body.String())
+	}
+
+	rec = sendJSON(t, router, "PUT", "/webhooks/"+created.ID, "user-001",
+		`{"url": "https://hooks.example.com/v2", "events": ["account.balance_low"], "enabled": false}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var updated models.Webhook
+	if err := json.NewDecoder(rec.Body).Decode(&updated); err != nil {
+		t.Fatalf("Failed to decode webhook: %v", err)
+	}
+	if updated.ID != created.ID || updated.Enabled || updated.URL != "https://hooks.example.com/v2" || updated.Secret != "" ||
+		!updated.CreatedAt.Equal(created.CreatedAt) {
+		t.Errorf("Expected the webhook updated in place, got %+v", updated)
+	}
+
+	if rec := sendJSON(t, router, "DELETE", "/webhooks/"+created.ID, "user-001", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", rec.Code)
+	}
+	if rec := doRequest(t, router, "/webhooks/"+created.ID, "user-001"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected the deleted webhook to be gone, got %d", rec.Code)
+	}
+}
+
+func TestWebhookErrors(t *testing.T) {
+	router := newTestRouter(t)
+	created := createWebhook(t, router, "user-001", `{"url": "https://hooks.example.com", "events": ["alert.created"]}`)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		userID string
+		body   string
+		want   int
+	}{
+		{"plain http", "POST", "/webhooks", "user-001", `{"url": "http://hooks.example.com", "events": ["alert.created"]}`, http.StatusBadRequest},
+		{"not a URL", "POST", "/webhooks", "user-001", `{"url": "hooks", "events": ["alert.created"]}`, http.StatusBadRequest},
+		{"credentials in URL", "POST", "/webhooks", "user-001", `{"url": "https://me:pw@hooks.example.com", "events": ["alert.created"]}`, http.StatusBadRequest},
+		{"unknown event", "POST", "/webhooks", "user-001", `{"url": "https://hooks.example.com", "events": ["account.closed"]}`, http.StatusBadRequest},
+		{"no events", "POST", "/webhooks", "user-001", `{"url": "https://hooks.example.com", "events": []}`, http.StatusBadRequest},
+		{"invalid body", "POST", "/webhooks", "user-001", `{"events": "alert.created"}`, http.StatusBadRequest},
+		{"another user's webhook", "GET", "/webhooks/" + created.ID, "user-002", "", http.StatusNotFound},
+		{"update another user's webhook", "PUT", "/webhooks/" + created.ID, "user-002", `{"url": "https://hooks.example.com", "events": ["alert.created"]}`, http.StatusNotFound},
+		{"delete another user's webhook", "DELETE", "/webhooks/" + created.ID, "user-002", "", http.StatusNotFound},
+		{"another user's deliveries", "GET", "/webhooks/" + created.ID + "/deliveries", "user-002", "", http.StatusNotFound},
+		{"unknown status", "GET", "/webhooks/" + created.ID + "/deliveries?status=lost", "user-001", "", http.StatusBadRequest},
+		{"unknown delivery", "POST", "/webhooks/" + created.ID + "/deliveries/dlv-missing/redeliver", "user-001", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := sendJSON(t, router, tt.method, tt.path, tt.userID, tt.body)
+			if rec.Code != tt.want {
+				t.Errorf("Expected status %d, got %d: %s", tt.want, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	// Each user has a limited number of webhooks
+	for i := 1; i < 10; i++ {
+		createWebhook(t, router, "user-001", fmt.Sprintf(`{"url": "https://hooks.example.com/%d", "events": ["alert.created"]}`, i))
+	}
+	if rec := sendJSON(t, router, "POST", "/webhooks", "user-001", `{"url": "https://hooks.example.com/11", "events": ["alert.created"]}`); rec.Code != http.StatusConflict {
+		t.Errorf("Expected the eleventh webhook to be refused, got %d", rec.Code)
+	}
+
+	// Read-only tokens cannot create webhooks
+	req := httptest.NewRequest("POST", "/webhooks", strings.NewReader(`{"url": "https://hooks.example.com", "events": ["alert.created"]}`))
+	req.Header.Set("Authorization", "Bearer "+issueScopedToken(t, "user-002", "insights:read"))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a read-only token to be refused, got %d", rec.Code)
+	}
+}
+
+func TestWebhookPrivateHosts(t *testing.T) {
+	grants, err := repository.NewAccountGrantSource(repository.AccountGrantsNone, "")
+	if err != nil {
+		t.Fatalf("Failed to open account grants: %v", err)
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	// Outside development webhooks cannot point at this machine or its network
+	service := services.NewWebhooksService(repository.NewMemoryWebhooks(), grants, nil, false, logger)
+	tests := []struct {
+		url  string
+		want error
+	}{
+		{"https://hooks.example.com", nil},
+		{"http://localhost:8080/hook", services.ErrInvalidWebhook},
+		{"http://127.0.0.1:8080/hook", services.ErrInvalidWebhook},
+		{"https://localhost/hook", services.ErrInvalidWebhook},
+		{"https://127.0.0.1/hook", services.ErrInvalidWebhook},
+		{"https://[::1]/hook", services.ErrInvalidWebhook},
+		{"https://10.0.0.5/hook", services.ErrInvalidWebhook},
+		{"https://192.168.1.1/hook", services.ErrInvalidWebhook},
+		{"https://169.254.169.254/latest/meta-data", services.ErrInvalidWebhook},
+		{"https://0.0.0.0/hook", services.ErrInvalidWebhook},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			_, err := service.Create("user-001", &models.WebhookRequest{URL: tt.url, Events: []string{models.WebhookAlertCreated}})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+// signedRequest is a request received by a webhook endpoint
+type signedRequest struct {
+	event     models.WebhookEvent
+	body      string
+	signature string
+}
+
+func TestWebhookDeliveries(t *testing.T) {
+	app, db := newAlertRulesApp(t)
+	router := app.router
+
+	var mu sync.Mutex
+	var received []signedRequest
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var event models.WebhookEvent
+		json.Unmarshal(body, &event)
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, signedRequest{event, string(body), r.Header.Get(webhook.SignatureHeader)})
+	}))
+	defer receiver.Close()
+
+	created := createWebhook(t, router, "user-001", fmt.Sprintf(`{"url": %q, "events": ["transaction.created", "account.balance_low"]}`, receiver.URL))
+
+	// Only what happens after the first check is sent
+	app.webhooks.Check()
+	createRule(t, router, "user-001", `{"name": "Checking low", "type": "low_balance", "accountId": "acc-001", "threshold": 1000}`)
+	if _, err := db.Exec(`INSERT INTO transactions VALUES ('txn-new', 'acc-001', ?, 'Laptop', -1200, 'shopping', 'Apple', 'completed', 'debit')`,
+		time.Now().UTC().Add(time.Second).Format(time.RFC3339Nano)); err != nil {
+		t.Fatalf("Failed to insert transaction: %v", err)
+	}
+	app.webhooks.Check()
+
+	path := "/webhooks/" + created.ID + "/deliveries"
+	pending := listDeliveries(t, router, path+"?status=pending", "user-001")
+	if len(pending) != 2 {
+		t.Fatalf("Expected a delivery for the low balance and the transaction, got %+v", pending)
+	}
+	rec := sendJSON(t, router, "POST", path+"/"+pending[0].ID+"/redeliver", "user-001", "")
+	if rec.Code != http.StatusConflict {
+		t.Errorf("Expected a pending delivery not to be redelivered, got %d", rec.Code)
+	}
+
+	app.dispatcher.DeliverDue(context.Background())
+	if len(received) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(received))
+	}
+	types := map[string]signedRequest{}
+	for _, request := range received {
+		types[request.event.Type] = request
+		if request.event.UserID != "user-001" || request.event.ID == "" || request.event.CreatedAt.IsZero() {
+			t.Errorf("Unexpected event %+v", request.event)
+		}
+	}
+	transaction, ok := types[models.WebhookTransactionCreated]
+	if !ok || !strings.Contains(transaction.body, `"id":"txn-new"`) {
+		t.Errorf("Expected transaction.created for txn-new, got %+v", received)
+	}
+	if balance, ok := types[models.WebhookBalanceLow]; !ok || !strings.Contains(balance.body, `"accountId":"acc-001"`) {
+		t.Errorf("Expected account.balance_low for acc-001, got %+v", received)
+	}
+
+	// Receivers check the signature against the secret from creation
+	var timestamp int64
+	fmt.Sscanf(transaction.signature, "t=%d", &timestamp)
+	if want := webhook.Sign(created.Secret, time.Unix(timestamp, 0), []byte(transaction.body)); transaction.signature != want {
+		t.Errorf("Expected signature %q, got %q", want, transaction.signature)
+	}
+
+	log := listDeliveries(t, router, path, "user-001")
+	if len(log) != 2 || log[0].Status != models.DeliverySucceeded || log[1].Status != models.DeliverySucceeded || log[0].Attempts != 1 {
+		t.Errorf("Expected both deliveries to succeed, got %+v", log)
+	}
+
+	// A delivered event can be sent again
+	rec = sendJSON(t, router, "POST", path+"/"+log[0].ID+"/redeliver", "user-001", "")
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	app.dispatcher.DeliverDue(context.Background())
+	if len(received) != 3 || received[2].body != types[log[0].EventType].body {
+		t.Errorf("Expected the same event sent again, got %+v", received)
+	}
+	if dead := listDeliveries(t, router, "/webhooks/dead-letters", "user-001"); len(dead) != 0 {
+		t.Errorf("Expected no dead letters, got %+v", dead)
+	}
+}
@@ -6,11 +6,28 @@ import "time"
 type Alert struct {
 	ID          string    `json:"id"`
 	UserID      string    `json:"userId"`
+	AccountID   string    `json:"accountId,omitempty"`
 	Type        string    `json:"type"`
 	Title       string    `json:"title"`
 	Message     string    `json:"message"`
 	Priority    string    `json:"priority"`
 	CreatedAt   time.Time `json:"createdAt"`
 	Read        bool      `json:"read"`
+	Dismissed   bool      `json:"dismissed"`
+	SnoozedUntil *time.Time `json:"snoozedUntil,omitempty"` // Hidden from the list until then
+	RuleID      string    `json:"ruleId,omitempty"`        // Alert rule that raised the alert
+	TransactionID string  `json:"transactionId,omitempty"` // Transaction that set the rule off
 	ActionURL   *string   `json:"actionUrl,omitempty"`
 }
+
+// Alert priorities, from least to most urgent
+const (
+	AlertPriorityMedium   = "medium"
+	AlertPriorityHigh     = "high"
+	AlertPriorityCritical = "critical"
+)
+
+// IsSnoozed reports whether the alert is snoozed at now
+func (a *Alert) IsSnoozed(now time.Time) bool {
+	return a.SnoozedUntil != nil && a.SnoozedUntil.After(now)
+}
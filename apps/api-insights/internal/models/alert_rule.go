@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// AlertRule is a condition a user wants to be alerted about, such as any
+// transaction over $500 or a balance falling below $1,000. Rules are
+// evaluated whenever alerts are listed.
+type AlertRule struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"userId"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	AccountID string    `json:"accountId,omitempty"` // Only this account; every account the user can see when empty
+	Merchant  string    `json:"merchant,omitempty"`  // merchant rules only
+	Category  string    `json:"category,omitempty"`  // category_spend rules only
+	Threshold float64   `json:"threshold"`           // Dollar amount the rule compares against
+	Priority  string    `json:"priority"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Alert rule types
+const (
+	// AlertRuleLargeTransaction matches transactions over Threshold, either way
+	AlertRuleLargeTransaction = "large_transaction"
+	// AlertRuleLowBalance matches while AccountID's balance is below Threshold
+	AlertRuleLowBalance = "low_balance"
+	// AlertRuleMerchant matches charges from Merchant of at least Threshold
+	AlertRuleMerchant = "merchant"
+	// AlertRuleCategorySpend matches once spending in Category this month
+	// exceeds Threshold
+	AlertRuleCategorySpend = "category_spend"
+)
+
+// AlertRuleRequest is the body of POST /alert-rules and PUT /alert-rules/{id}
+type AlertRuleRequest struct {
+	Name      string  `json:"name"`
+	Type      string  `json:"type"`
+	AccountID string  `json:"accountId"`
+	Merchant  string  `json:"merchant"`
+	Category  string  `json:"category"`
+	Threshold float64 `json:"threshold"`
+	Priority  string  `json:"priority"` // Defaults to medium
+	Enabled   *bool   `json:"enabled"`  // Defaults to true
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// AlertState is what a user changed about an alert. Alerts are derived from
+// insights, security events and alert rules, so their state is kept apart,
+// per user and alert ID, and applied when they are listed.
+type AlertState struct {
+	UserID       string
+	AlertID      string
+	Read         bool
+	Dismissed    bool
+	SnoozedUntil *time.Time
+	UpdatedAt    time.Time
+}
+
+// Apply copies the state onto the alert
+func (s *AlertState) Apply(alert *Alert) {
+	alert.Read = s.Read
+	alert.Dismissed = s.Dismissed
+	alert.SnoozedUntil = s.SnoozedUntil
+}
+
+// AlertUpdate is the body of PATCH /alerts/{id}. Fields left out are not changed.
+type AlertUpdate struct {
+	Read         *bool        `json:"read"`
+	Dismissed    *bool        `json:"dismissed"`
+	SnoozedUntil OptionalTime `json:"snoozedUntil"` // null ends a snooze
+}
+
+// OptionalTime is a time in a JSON body that can be left out, set, or
+// cleared with null
+type OptionalTime struct {
+	Set  bool
+	Time *time.Time
+}
+
+// UnmarshalJSON records that the field was present, null or not
+func (t *OptionalTime) UnmarshalJSON(data []byte) error {
+	t.Set = true
+	if bytes.Equal(data, []byte("null")) {
+		t.Time = nil
+		return nil
+	}
+	var v time.Time
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	t.Time = &v
+	return nil
+}
+
+// AlertFilters narrows GET /alerts
+type AlertFilters struct {
+	Unread   bool   // Only alerts not yet read
+	Priority string // Only alerts of this priority
+}
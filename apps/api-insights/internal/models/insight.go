@@ -6,6 +6,7 @@ import "time"
 type Insight struct {
 	ID             string     `json:"id"`
 	UserID         string     `json:"userId"`
+	AccountID      string     `json:"accountId,omitempty"` // Set when the insight is about one account
 	Type           string     `json:"type"`
 	Category       string     `json:"category"`
 	Title          string     `json:"title"`
@@ -14,4 +15,5 @@ type Insight struct {
 	CreatedAt      time.Time  `json:"createdAt"`
 	Actionable     bool       `json:"actionable"`
 	Recommendation *string    `json:"recommendation"`
+	Evidence       map[string]float64 `json:"evidence,omitempty"` // Figures a computed insight is based on
 }
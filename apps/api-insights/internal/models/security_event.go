@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// SecurityEvent is a security event api-accounts published for a user, such
+// as sign-in being locked after repeated failures
+type SecurityEvent struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"userId"`
+	Type      string    `json:"type"`
+	Message   string    `json:"message"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Security event types
+const (
+	SecurityEventAccountLocked = "account_locked"
+)
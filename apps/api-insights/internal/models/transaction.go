@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Transaction is a transaction recorded by api-transactions, as far as the
+// insights engine needs it. Debits have negative amounts, credits positive.
+type Transaction struct {
+	ID          string    `json:"id"`
+	AccountID   string    `json:"accountId"`
+	Date        time.Time `json:"date"`
+	Description string    `json:"description"`
+	Amount      float64   `json:"amount"`
+	Category    string    `json:"category"`
+	Merchant    string    `json:"merchant"`
+	Status      string    `json:"status"`
+	Type        string    `json:"type"`
+}
+
+// Transaction statuses
+const (
+	TransactionStatusFailed = "failed"
+)
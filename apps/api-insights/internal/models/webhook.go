@@ -0,0 +1,104 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook is an endpoint of an integrator's that events of a user's are
+// posted to, signed with Secret
+type Webhook struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"userId"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"` // Only returned when the webhook is created
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Wants reports whether the webhook is enabled and subscribed to eventType
+func (w *Webhook) Wants(eventType string) bool {
+	if !w.Enabled {
+		return false
+	}
+	for _, event := range w.Events {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Webhook event types
+const (
+	// WebhookAlertCreated is sent for each new alert
+	WebhookAlertCreated = "alert.created"
+	// WebhookTransactionCreated is sent for each new transaction in the
+	// accounts the user can see
+	WebhookTransactionCreated = "transaction.created"
+	// WebhookBalanceLow is sent when a low_balance alert rule goes off
+	WebhookBalanceLow = "account.balance_low"
+)
+
+// WebhookEventTypes are the event types webhooks can subscribe to
+var WebhookEventTypes = []string{WebhookAlertCreated, WebhookTransactionCreated, WebhookBalanceLow}
+
+// WebhookRequest is the body of POST /webhooks and PUT /webhooks/{id}
+type WebhookRequest struct {
+	URL     string   `json:"url"`
+	Events  []string `json:"events"`
+	Enabled *bool    `json:"enabled"` // Defaults to true
+}
+
+// WebhookEvent is the body posted to webhooks
+type WebhookEvent struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	UserID    string      `json:"userId"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Data      interface{} `json:"data"`
+}
+
+// BalanceLowData is the data of an account.balance_low event
+type BalanceLowData struct {
+	AccountID string `json:"accountId"`
+	RuleID    string `json:"ruleId"`
+	Alert     *Alert `json:"alert"`
+}
+
+// WebhookDelivery is one event queued for, or delivered to, one webhook
+type WebhookDelivery struct {
+	ID             string          `json:"id"`
+	WebhookID      string          `json:"webhookId"`
+	UserID         string          `json:"userId"`
+	EventID        string          `json:"eventId"`
+	EventType      string          `json:"eventType"`
+	Payload        json.RawMessage `json:"payload"` // The body posted, as signed
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"nextAttemptAt,omitempty"` // While pending
+	LastAttemptAt  *time.Time      `json:"lastAttemptAt,omitempty"`
+	ResponseStatus int             `json:"responseStatus,omitempty"` // Of the last attempt
+	LastError      string          `json:"lastError,omitempty"`
+	CreatedAt      time.Time       `json:"createdAt"`
+}
+
+// Webhook delivery statuses
+const (
+	// DeliveryPending deliveries are attempted at NextAttemptAt
+	DeliveryPending = "pending"
+	// DeliverySucceeded deliveries got a 2xx response
+	DeliverySucceeded = "succeeded"
+	// DeliveryDead deliveries failed every attempt and are kept on the
+	// dead-letter list until redelivered
+	DeliveryDead = "dead"
+)
+
+// DeliveryFilters narrow the delivery log
+type DeliveryFilters struct {
+	WebhookID string // Deliveries to this webhook only, when set
+	Status    string // Deliveries with this status only, when set
+	Limit     int
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Account grant sources selectable via the ACCOUNT_GRANTS_STORE environment variable
+const (
+	AccountGrantsNone   = "none"
+	AccountGrantsSQLite = "sqlite"
+)
+
+// AccountGrantSource lists the accounts their owners shared with a user
+// through api-accounts, as a joint holder or a read-only viewer
+type AccountGrantSource interface {
+	GetSharedAccountIDs(userID string) ([]string, error)
+	Close() error
+}
+
+// NewAccountGrantSource creates the source for the configured store. path is
+// the database file api-accounts writes account grants to.
+func NewAccountGrantSource(store, path string) (AccountGrantSource, error) {
+	switch store {
+	case "", AccountGrantsNone:
+		return noAccountGrants{}, nil
+	case AccountGrantsSQLite:
+		return NewSQLiteAccountGrants(path)
+	default:
+		return nil, fmt.Errorf("unknown account grants store %q", store)
+	}
+}
+
+// noAccountGrants is used when account grants are not shared with this service
+type noAccountGrants struct{}
+
+func (noAccountGrants) GetSharedAccountIDs(string) ([]string, error) {
+	return nil, nil
+}
+
+func (noAccountGrants) Close() error {
+	return nil
+}
+
+// SQLiteAccountGrants reads the account_grants table api-accounts maintains
+// in the shared database
+type SQLiteAccountGrants struct {
+	db *sql.DB
+}
+
+// NewSQLiteAccountGrants opens (or creates) the account_grants table in the
+// database at dbPath. api-accounts and api-transactions create the same
+// table, so whichever service starts first can open it.
+func NewSQLiteAccountGrants(dbPath string) (*SQLiteAccountGrants, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", dbPath)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open account grants database: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS account_grants (
+		account_id TEXT NOT NULL,
+		user_id    TEXT NOT NULL,
+		access     TEXT NOT NULL,
+		granted_by TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		PRIMARY KEY (account_id, user_id)
+	);
+	CREATE INDEX IF NOT EXISTS idx_account_grants_user_id ON account_grants (user_id)`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create account_grants: %w", err)
+	}
+
+	return &SQLiteAccountGrants{db: db}, nil
+}
+
+// GetSharedAccountIDs returns the IDs of the accounts shared with the user
+func (s *SQLiteAccountGrants) GetSharedAccountIDs(userID string) ([]string, error) {
+	rows, err := s.db.Query(`SELECT account_id FROM account_grants WHERE user_id = ? ORDER BY account_id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accountIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		accountIDs = append(accountIDs, id)
+	}
+	return accountIDs, rows.Err()
+}
+
+// Close closes the underlying database
+func (s *SQLiteAccountGrants) Close() error {
+	return s.db.Close()
+}
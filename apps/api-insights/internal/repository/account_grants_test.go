@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSQLiteAccountGrants(t *testing.T) {
+	source, err := NewSQLiteAccountGrants(filepath.Join(t.TempDir(), "grants.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteAccountGrants failed: %v", err)
+	}
+	defer source.Close()
+
+	// Rows as api-accounts writes them when invitations are accepted
+	for _, row := range []struct{ accountID, userID, access string }{
+		{"acc-003", "user-002", "joint"},
+		{"acc-001", "user-002", "viewer"},
+		{"acc-001", "user-003", "viewer"},
+	} {
+		if _, err := source.db.Exec(`INSERT INTO account_grants (account_id, user_id, access, granted_by, created_at) VALUES (?, ?, ?, 'user-001', '2024-12-01T12:00:00Z')`,
+			row.accountID, row.userID, row.access); err != nil {
+			t.Fatalf("Failed to insert grant: %v", err)
+		}
+	}
+
+	accountIDs, err := source.GetSharedAccountIDs("user-002")
+	if err != nil {
+		t.Fatalf("GetSharedAccountIDs failed: %v", err)
+	}
+	if len(accountIDs) != 2 || accountIDs[0] != "acc-001" || accountIDs[1] != "acc-003" {
+		t.Fatalf("Expected user-002's shared accounts, got %v", accountIDs)
+	}
+
+	none, err := NewAccountGrantSource("", "")
+	if err != nil {
+		t.Fatalf("NewAccountGrantSource failed: %v", err)
+	}
+	if accountIDs, err := none.GetSharedAccountIDs("user-002"); err != nil || len(accountIDs) != 0 {
+		t.Errorf("Expected no shared accounts without a store, got %v (%v)", accountIDs, err)
+	}
+}
@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/models"
+)
+
+// ErrAlertRuleNotFound is returned for an alert rule the user does not have
+var ErrAlertRuleNotFound = errors.New("alert rule not found")
+
+// Alert rule stores selectable via the ALERT_RULES_STORE environment variable
+const (
+	AlertRulesMemory = "memory"
+	AlertRulesSQLite = "sqlite"
+)
+
+// AlertRuleStore keeps the alert rules users set up
+type AlertRuleStore interface {
+	// GetAlertRules returns the user's rules, oldest first
+	GetAlertRules(userID string) ([]*models.AlertRule, error)
+	// GetAlertRule returns one of the user's rules, or ErrAlertRuleNotFound
+	GetAlertRule(userID, ruleID string) (*models.AlertRule, error)
+	// SaveAlertRule creates or replaces a rule
+	SaveAlertRule(rule *models.AlertRule) error
+	// DeleteAlertRule deletes one of the user's rules, or returns
+	// ErrAlertRuleNotFound
+	DeleteAlertRule(userID, ruleID string) error
+	Close() error
+}
+
+// NewAlertRuleStore creates the store for the configured backend. path is
+// the database file used by the sqlite store.
+func NewAlertRuleStore(store, path string) (AlertRuleStore, error) {
+	switch store {
+	case "", AlertRulesMemory:
+		return NewMemoryAlertRules(), nil
+	case AlertRulesSQLite:
+		return NewSQLiteAlertRules(path)
+	default:
+		return nil, fmt.Errorf("unknown alert rule store %q", store)
+	}
+}
+
+// MemoryAlertRules keeps alert rules in this process. They are lost on
+// restart, so it suits tests and development.
+type MemoryAlertRules struct {
+	rules map[string]*models.AlertRule // by rule ID
+	mu    sync.RWMutex
+}
+
+// NewMemoryAlertRules creates an empty in-memory store
+func NewMemoryAlertRules() *MemoryAlertRules {
+	return &MemoryAlertRules{rules: make(map[string]*models.AlertRule)}
+}
+
+// GetAlertRules returns the user's rules, oldest first
+func (s *MemoryAlertRules) GetAlertRules(userID string) ([]*models.AlertRule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var rules []*models.AlertRule
+	for _, rule := range s.rules {
+		if rule.UserID == userID {
+			copied := *rule
+			rules = append(rules, &copied)
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		if !rules[i].CreatedAt.Equal(rules[j].CreatedAt) {
+			return rules[i].CreatedAt.Before(rules[j].CreatedAt)
+		}
+		return rules[i].ID < rules[j].ID
+	})
+	return rules, nil
+}
+
+// GetAlertRule returns one of the user's rules
+func (s *MemoryAlertRules) GetAlertRule(userID, ruleID string) (*models.AlertRule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rule, ok := s.rules[ruleID]
+	if !ok || rule.UserID != userID {
+		return nil, ErrAlertRuleNotFound
+	}
+	copied := *rule
+	return &copied, nil
+}
+
+// SaveAlertRule creates or replaces a rule
+func (s *MemoryAlertRules) SaveAlertRule(rule *models.AlertRule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	copied := *rule
+	s.rules[rule.ID] = &copied
+	return nil
+}
+
+// DeleteAlertRule deletes one of the user's rules
+func (s *MemoryAlertRules) DeleteAlertRule(userID, ruleID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rule, ok := s.rules[ruleID]
+	if !ok || rule.UserID != userID {
+		return ErrAlertRuleNotFound
+	}
+	delete(s.rules, ruleID)
+	return nil
+}
+
+// Close releases nothing; the rules are held in memory
+func (s *MemoryAlertRules) Close() error {
+	return nil
+}
+
+// alertRuleColumns are the columns of alert_rules, in the order scanAlertRule reads them
+const alertRuleColumns = `id, user_id, name, type, account_id, merchant, category, threshold, priority, enabled, created_at, updated_at`
+
+// SQLiteAlertRules keeps alert rules in the alert_rules table, so they
+// survive restarts
+type SQLiteAlertRules struct {
+	db *sql.DB
+}
+
+// NewSQLiteAlertRules opens (or creates) the alert_rules table in the
+// database at dbPath
+func NewSQLiteAlertRules(dbPath string) (*SQLiteAlertRules, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", dbPath)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open alert rules database: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS alert_rules (
+		id         TEXT PRIMARY KEY,
+		user_id    TEXT NOT NULL,
+		name       TEXT NOT NULL,
+		type       TEXT NOT NULL,
+		account_id TEXT NOT NULL,
+		merchant   TEXT NOT NULL,
+		category   TEXT NOT NULL,
+		threshold  REAL NOT NULL,
+		priority   TEXT NOT NULL,
+		enabled    INTEGER NOT NULL,
+		created_at INTEGER NOT NULL,
+		updated_at INTEGER NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_alert_rules_user_id ON alert_rules (user_id)`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create alert_rules: %w", err)
+	}
+
+	return &SQLiteAlertRules{db: db}, nil
+}
+
+// GetAlertRules returns the user's rules, oldest first
+func (s *SQLiteAlertRules) GetAlertRules(userID string) ([]*models.AlertRule, error) {
+	rows, err := s.db.Query(`SELECT `+alertRuleColumns+` FROM alert_rules
+		WHERE user_id = ? ORDER BY created_at, id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []*models.AlertRule
+	for rows.Next() {
+		rule, err := scanAlertRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// GetAlertRule returns one of the user's rules
+func (s *SQLiteAlertRules) GetAlertRule(userID, ruleID string) (*models.AlertRule, error) {
+	rule, err := scanAlertRule(s.db.QueryRow(`SELECT `+alertRuleColumns+` FROM alert_rules
+		WHERE id = ? AND user_id = ?`, ruleID, userID))
+	if err == sql.ErrNoRows {
+		return nil, ErrAlertRuleNotFound
+	}
+	return rule, err
+}
+
+// SaveAlertRule creates or replaces a rule
+func (s *SQLiteAlertRules) SaveAlertRule(rule *models.AlertRule) error {
+	_, err := s.db.Exec(`INSERT INTO alert_rules (`+alertRuleColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET name = excluded.name, type = excluded.type, account_id = excluded.account_id,
+			merchant = excluded.merchant, category = excluded.category, threshold = excluded.threshold,
+			priority = excluded.priority, enabled = excluded.enabled, updated_at = excluded.updated_at`,
+		rule.ID, rule.UserID, rule.Name, rule.Type, rule.AccountID, rule.Merchant, rule.Category, rule.Threshold,
+		rule.Priority, rule.Enabled, rule.CreatedAt.Unix(), rule.UpdatedAt.Unix())
+	return err
+}
+
+// DeleteAlertRule deletes one of the user's rules
+func (s *SQLiteAlertRules) DeleteAlertRule(userID, ruleID string) error {
+	res, err := s.db.Exec(`DELETE FROM alert_rules WHERE id = ? AND user_id = ?`, ruleID, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrAlertRuleNotFound
+	}
+	return nil
+}
+
+// Close closes the underlying database
+func (s *SQLiteAlertRules) Close() error {
+	return s.db.Close()
+}
+
+// scanAlertRule reads a row of alertRuleColumns
+func scanAlertRule(row interface{ Scan(...any) error }) (*models.AlertRule, error) {
+	var rule models.AlertRule
+	var createdAt, updatedAt int64
+	if err := row.Scan(&rule.ID, &rule.UserID, &rule.Name, &rule.Type, &rule.AccountID, &rule.Merchant, &rule.Category,
+		&rule.Threshold, &rule.Priority, &rule.Enabled, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+	rule.CreatedAt = time.Unix(createdAt, 0).UTC()
+	rule.UpdatedAt = time.Unix(updatedAt, 0).UTC()
+	return &rule, nil
+}
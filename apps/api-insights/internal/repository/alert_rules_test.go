@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/models"
+)
+
+func TestAlertRuleStores(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "rules.db")
+	sqliteStore, err := NewSQLiteAlertRules(dbPath)
+	if err != nil {
+		t.Fatalf("NewSQLiteAlertRules failed: %v", err)
+	}
+	defer sqliteStore.Close()
+
+	stores := map[string]AlertRuleStore{
+		"memory": NewMemoryAlertRules(),
+		"sqlite": sqliteStore,
+	}
+	now := time.Date(2024, 12, 13, 8, 0, 0, 0, time.UTC)
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			rules := []*models.AlertRule{
+				{ID: "rule-2", UserID: "user-001", Name: "Big spends", Type: models.AlertRuleLargeTransaction,
+					Threshold: 500, Priority: "high", Enabled: true, CreatedAt: now.Add(time.Minute), UpdatedAt: now.Add(time.Minute)},
+				{ID: "rule-1", UserID: "user-001", Name: "Low checking", Type: models.AlertRuleLowBalance,
+					AccountID: "acc-001", Threshold: 1000, Priority: "medium", Enabled: true, CreatedAt: now, UpdatedAt: now},
+				{ID: "rule-3", UserID: "user-002", Name: "Netflix", Type: models.AlertRuleMerchant,
+					Merchant: "Netflix", Priority: "medium", CreatedAt: now, UpdatedAt: now},
+			}
+			for _, rule := range rules {
+				if err := store.SaveAlertRule(rule); err != nil {
+					t.Fatalf("SaveAlertRule failed: %v", err)
+				}
+			}
+
+			list, err := store.GetAlertRules("user-001")
+			if err != nil || len(list) != 2 || list[0].ID != "rule-1" || list[1].ID != "rule-2" {
+				t.Fatalf("Expected user-001's rules oldest first, got %+v (%v)", list, err)
+			}
+			if *list[0] != *rules[1] {
+				t.Errorf("Expected the rule as saved, got %+v", list[0])
+			}
+
+			// Saving again replaces the rule
+			updated := *rules[0]
+			updated.Threshold = 750
+			updated.Enabled = false
+			updated.UpdatedAt = now.Add(time.Hour)
+			if err := store.SaveAlertRule(&updated); err != nil {
+				t.Fatalf("SaveAlertRule failed: %v", err)
+			}
+			rule, err := store.GetAlertRule("user-001", "rule-2")
+			if err != nil || *rule != updated {
+				t.Errorf("Expected the replaced rule, got %+v (%v)", rule, err)
+			}
+
+			// Other users' rules are not found
+			if _, err := store.GetAlertRule("user-002", "rule-2"); !errors.Is(err, ErrAlertRuleNotFound) {
+				t.Errorf("Expected ErrAlertRuleNotFound, got %v", err)
+			}
+			if err := store.DeleteAlertRule("user-002", "rule-2"); !errors.Is(err, ErrAlertRuleNotFound) {
+				t.Errorf("Expected ErrAlertRuleNotFound, got %v", err)
+			}
+
+			if err := store.DeleteAlertRule("user-001", "rule-2"); err != nil {
+				t.Fatalf("DeleteAlertRule failed: %v", err)
+			}
+			if list, _ := store.GetAlertRules("user-001"); len(list) != 1 {
+				t.Errorf("Expected one rule left, got %+v", list)
+			}
+		})
+	}
+
+	// Rules outlive the process
+	sqliteStore.Close()
+	reopened, err := NewAlertRuleStore(AlertRulesSQLite, dbPath)
+	if err != nil {
+		t.Fatalf("NewAlertRuleStore failed: %v", err)
+	}
+	defer reopened.Close()
+	if rules, err := reopened.GetAlertRules("user-002"); err != nil || len(rules) != 1 || rules[0].Merchant != "Netflix" {
+		t.Errorf("Expected user-002's rule after reopening, got %+v (%v)", rules, err)
+	}
+}
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/models"
+)
+
+// Alert state stores selectable via the ALERT_STATE_STORE environment variable
+const (
+	AlertStatesMemory = "memory"
+	AlertStatesSQLite = "sqlite"
+)
+
+// AlertStateStore keeps what users changed about their alerts: read,
+// dismissed and snoozed
+type AlertStateStore interface {
+	// GetAlertStates returns the user's alert states by alert ID
+	GetAlertStates(userID string) (map[string]*models.AlertState, error)
+	// SaveAlertStates creates or replaces the states, all or none of them
+	SaveAlertStates(states []*models.AlertState) error
+	Close() error
+}
+
+// NewAlertStateStore creates the store for the configured backend. path is
+// the database file used by the sqlite store.
+func NewAlertStateStore(store, path string) (AlertStateStore, error) {
+	switch store {
+	case "", AlertStatesMemory:
+		return NewMemoryAlertStates(), nil
+	case AlertStatesSQLite:
+		return NewSQLiteAlertStates(path)
+	default:
+		return nil, fmt.Errorf("unknown alert state store %q", store)
+	}
+}
+
+// MemoryAlertStates keeps alert states in this process. They are lost on
+// restart, so it suits tests and development.
+type MemoryAlertStates struct {
+	states map[string]map[string]*models.AlertState // user ID to alert ID to state
+	mu     sync.RWMutex
+}
+
+// NewMemoryAlertStates creates an empty in-memory store
+func NewMemoryAlertStates() *MemoryAlertStates {
+	return &MemoryAlertStates{states: make(map[string]map[string]*models.AlertState)}
+}
+
+// GetAlertStates returns the user's alert states by alert ID
+func (s *MemoryAlertStates) GetAlertStates(userID string) (map[string]*models.AlertState, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	states := make(map[string]*models.AlertState, len(s.states[userID]))
+	for id, state := range s.states[userID] {
+		copied := *state
+		states[id] = &copied
+	}
+	return states, nil
+}
+
+// SaveAlertStates creates or replaces the states
+func (s *MemoryAlertStates) SaveAlertStates(states []*models.AlertState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, state := range states {
+		if s.states[state.UserID] == nil {
+			s.states[state.UserID] = make(map[string]*models.AlertState)
+		}
+		copied := *state
+		s.states[state.UserID][state.AlertID] = &copied
+	}
+	return nil
+}
+
+// Close releases nothing; the states are held in memory
+func (s *MemoryAlertStates) Close() error {
+	return nil
+}
+
+// SQLiteAlertStates keeps alert states in the alert_states table, so they
+// survive restarts
+type SQLiteAlertStates struct {
+	db *sql.DB
+}
+
+// NewSQLiteAlertStates opens (or creates) the alert_states table in the
+// database at dbPath
+func NewSQLiteAlertStates(dbPath string) (*SQLiteAlertStates, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", dbPath)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open alert states database: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS alert_states (
+		user_id       TEXT NOT NULL,
+		alert_id      TEXT NOT NULL,
+		read          INTEGER NOT NULL,
+		dismissed     INTEGER NOT NULL,
+		snoozed_until INTEGER,
+		updated_at    INTEGER NOT NULL,
+		PRIMARY KEY (user_id, alert_id)
+	)`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create alert_states: %w", err)
+	}
+
+	return &SQLiteAlertStates{db: db}, nil
+}
+
+// GetAlertStates returns the user's alert states by alert ID
+func (s *SQLiteAlertStates) GetAlertStates(userID string) (map[string]*models.AlertState, error) {
+	rows, err := s.db.Query(`SELECT user_id, alert_id, read, dismissed, snoozed_until, updated_at
+		FROM alert_states WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	states := make(map[string]*models.AlertState)
+	for rows.Next() {
+		var state models.AlertState
+		var snoozedUntil sql.NullInt64
+		var updatedAt int64
+		if err := rows.Scan(&state.UserID, &state.AlertID, &state.Read, &state.Dismissed, &snoozedUntil, &updatedAt); err != nil {
+			return nil, err
+		}
+		if snoozedUntil.Valid {
+			t := time.Unix(snoozedUntil.Int64, 0).UTC()
+			state.SnoozedUntil = &t
+		}
+		state.UpdatedAt = time.Unix(updatedAt, 0).UTC()
+		states[state.AlertID] = &state
+	}
+	return states, rows.Err()
+}
+
+// SaveAlertStates creates or replaces the states in one transaction
+func (s *SQLiteAlertStates) SaveAlertStates(states []*models.AlertState) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, state := range states {
+		var snoozedUntil sql.NullInt64
+		if state.SnoozedUntil != nil {
+			snoozedUntil = sql.NullInt64{Int64: state.SnoozedUntil.Unix(), Valid: true}
+		}
+		if _, err := tx.Exec(`INSERT INTO alert_states (user_id, alert_id, read, dismissed, snoozed_until, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT (user_id, alert_id) DO UPDATE SET read = excluded.read, dismissed = excluded.dismissed,
+				snoozed_until = excluded.snoozed_until, updated_at = excluded.updated_at`,
+			state.UserID, state.AlertID, state.Read, state.Dismissed, snoozedUntil, state.UpdatedAt.Unix()); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Close closes the underlying database
+func (s *SQLiteAlertStates) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/models"
+)
+
+func TestAlertStateStores(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "alerts.db")
+	sqliteStore, err := NewSQLiteAlertStates(dbPath)
+	if err != nil {
+		t.Fatalf("NewSQLiteAlertStates failed: %v", err)
+	}
+	defer sqliteStore.Close()
+
+	stores := map[string]AlertStateStore{
+		"memory": NewMemoryAlertStates(),
+		"sqlite": sqliteStore,
+	}
+	now := time.Date(2024, 12, 13, 8, 0, 0, 0, time.UTC)
+	until := now.Add(24 * time.Hour)
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			if err := store.SaveAlertStates([]*models.AlertState{
+				{UserID: "user-001", AlertID: "alert-001", Read: true, UpdatedAt: now},
+				{UserID: "user-001", AlertID: "alert-002", SnoozedUntil: &until, UpdatedAt: now},
+				{UserID: "user-002", AlertID: "alert-001", Dismissed: true, UpdatedAt: now},
+			}); err != nil {
+				t.Fatalf("SaveAlertStates failed: %v", err)
+			}
+			// Saving again replaces the state
+			if err := store.SaveAlertStates([]*models.AlertState{
+				{UserID: "user-001", AlertID: "alert-001", Read: true, Dismissed: true, UpdatedAt: now.Add(time.Minute)},
+			}); err != nil {
+				t.Fatalf("SaveAlertStates failed: %v", err)
+			}
+
+			states, err := store.GetAlertStates("user-001")
+			if err != nil {
+				t.Fatalf("GetAlertStates failed: %v", err)
+			}
+			if len(states) != 2 {
+				t.Fatalf("Expected user-001's two states, got %v", states)
+			}
+			if first := states["alert-001"]; !first.Read || !first.Dismissed || !first.UpdatedAt.Equal(now.Add(time.Minute)) {
+				t.Errorf("Expected the replaced state, got %+v", first)
+			}
+			if second := states["alert-002"]; second.Read || second.SnoozedUntil == nil || !second.SnoozedUntil.Equal(until) {
+				t.Errorf("Expected a snoozed alert, got %+v", second)
+			}
+		})
+	}
+
+	// States outlive the process
+	sqliteStore.Close()
+	reopened, err := NewAlertStateStore(AlertStatesSQLite, dbPath)
+	if err != nil {
+		t.Fatalf("NewAlertStateStore failed: %v", err)
+	}
+	defer reopened.Close()
+	states, err := reopened.GetAlertStates("user-002")
+	if err != nil || len(states) != 1 || !states["alert-001"].Dismissed {
+		t.Errorf("Expected user-002's state after reopening, got %v (%v)", states, err)
+	}
+}
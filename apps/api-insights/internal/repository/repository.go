@@ -2,15 +2,20 @@ package repository
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 
 	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/models"
 	"github.com/sirupsen/logrus"
 )
 
+// ErrInsightNotFound is returned for an insight that does not exist
+var ErrInsightNotFound = errors.New("insight not found")
+
 // Repository provides data access for insights and alerts
 type Repository struct {
 	insights map[string]*models.Insight
@@ -62,18 +67,28 @@ func (r *Repository) loadInsights(filePath string) error {
 	return nil
 }
 
-// generateAlerts creates alerts from high-priority actionable insights
+// generateAlerts creates alerts from high-priority actionable insights. They
+// are numbered in insight ID order, so an alert keeps its ID, and the state
+// users saved for it, from one start to the next.
 func (r *Repository) generateAlerts() {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	insightIDs := make([]string, 0, len(r.insights))
+	for id := range r.insights {
+		insightIDs = append(insightIDs, id)
+	}
+	sort.Strings(insightIDs)
+
 	alertCounter := 1
-	for _, insight := range r.insights {
+	for _, id := range insightIDs {
+		insight := r.insights[id]
 		// Create alerts for medium and high severity actionable insights
 		if insight.Actionable && (insight.Severity == "medium" || insight.Severity == "high") {
 			alert := &models.Alert{
 				ID:        fmt.Sprintf("alert-%03d", alertCounter),
 				UserID:    insight.UserID,
+				AccountID: insight.AccountID,
 				Type:      insight.Type,
 				Title:     insight.Title,
 				Message:   insight.Description,
@@ -106,7 +121,7 @@ func (r *Repository) GetInsightByID(insightID string) (*models.Insight, error) {
 
 	insight, exists := r.insights[insightID]
 	if !exists {
-		return nil, fmt.Errorf("insight not found")
+		return nil, ErrInsightNotFound
 	}
 
 	return insight, nil
@@ -142,6 +157,46 @@ func (r *Repository) GetAlertsByUserID(userID string) ([]*models.Alert, error) {
 	return userAlerts, nil
 }
 
+// GetInsightsByAccountIDs retrieves the insights about any of the given
+// accounts, whoever owns them
+func (r *Repository) GetInsightsByAccountIDs(accountIDs []string) ([]*models.Insight, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	wanted := make(map[string]bool, len(accountIDs))
+	for _, id := range accountIDs {
+		wanted[id] = true
+	}
+	var accountInsights []*models.Insight
+	for _, insight := range r.insights {
+		if insight.AccountID != "" && wanted[insight.AccountID] {
+			accountInsights = append(accountInsights, insight)
+		}
+	}
+
+	return accountInsights, nil
+}
+
+// GetAlertsByAccountIDs retrieves the alerts about any of the given accounts,
+// whoever owns them
+func (r *Repository) GetAlertsByAccountIDs(accountIDs []string) ([]*models.Alert, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	wanted := make(map[string]bool, len(accountIDs))
+	for _, id := range accountIDs {
+		wanted[id] = true
+	}
+	var accountAlerts []*models.Alert
+	for _, alert := range r.alerts {
+		if alert.AccountID != "" && wanted[alert.AccountID] {
+			accountAlerts = append(accountAlerts, alert)
+		}
+	}
+
+	return accountAlerts, nil
+}
+
 // GetAllInsights returns all insights (for testing/admin purposes)
 func (r *Repository) GetAllInsights() []*models.Insight {
 	r.mu.RLock()
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/models"
+	_ "modernc.org/sqlite" // Pure Go SQLite driver, keeps CGO_ENABLED=0 builds working
+)
+
+// Security event sources selectable via the SECURITY_EVENTS_STORE environment variable
+const (
+	SecurityEventsNone   = "none"
+	SecurityEventsSQLite = "sqlite"
+)
+
+// SecurityEventSource lists the security events api-accounts published
+type SecurityEventSource interface {
+	GetSecurityEventsByUserID(userID string) ([]*models.SecurityEvent, error)
+	Close() error
+}
+
+// NewSecurityEventSource creates the source for the configured store. path is
+// the database file api-accounts writes security events to.
+func NewSecurityEventSource(store, path string) (SecurityEventSource, error) {
+	switch store {
+	case "", SecurityEventsNone:
+		return noSecurityEvents{}, nil
+	case SecurityEventsSQLite:
+		return NewSQLiteSecurityEvents(path)
+	default:
+		return nil, fmt.Errorf("unknown security events store %q", store)
+	}
+}
+
+// noSecurityEvents is used when security events are not shared with this service
+type noSecurityEvents struct{}
+
+func (noSecurityEvents) GetSecurityEventsByUserID(string) ([]*models.SecurityEvent, error) {
+	return nil, nil
+}
+
+func (noSecurityEvents) Close() error {
+	return nil
+}
+
+// SQLiteSecurityEvents reads the security_events table api-accounts appends
+// to in the shared database
+type SQLiteSecurityEvents struct {
+	db *sql.DB
+}
+
+// NewSQLiteSecurityEvents opens (or creates) the security_events table in
+// the database at dbPath. api-accounts creates the same table, so whichever
+// service starts first can open it.
+func NewSQLiteSecurityEvents(dbPath string) (*SQLiteSecurityEvents, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", dbPath)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open security events database: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS security_events (
+		id         TEXT PRIMARY KEY,
+		user_id    TEXT NOT NULL,
+		type       TEXT NOT NULL,
+		message    TEXT NOT NULL,
+		created_at INTEGER NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_security_events_user_id ON security_events (user_id)`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create security_events: %w", err)
+	}
+
+	return &SQLiteSecurityEvents{db: db}, nil
+}
+
+// GetSecurityEventsByUserID returns the user's events, newest first
+func (s *SQLiteSecurityEvents) GetSecurityEventsByUserID(userID string) ([]*models.SecurityEvent, error) {
+	rows, err := s.db.Query(`SELECT id, user_id, type, message, created_at FROM security_events
+		WHERE user_id = ? ORDER BY created_at DESC, id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*models.SecurityEvent
+	for rows.Next() {
+		var event models.SecurityEvent
+		var createdAt int64
+		if err := rows.Scan(&event.ID, &event.UserID, &event.Type, &event.Message, &createdAt); err != nil {
+			return nil, err
+		}
+		event.CreatedAt = time.Unix(createdAt, 0).UTC()
+		events = append(events, &event)
+	}
+	return events, rows.Err()
+}
+
+// Close closes the underlying database
+func (s *SQLiteSecurityEvents) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/models"
+)
+
+func TestSQLiteSecurityEvents(t *testing.T) {
+	source, err := NewSQLiteSecurityEvents(filepath.Join(t.TempDir(), "events.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteSecurityEvents failed: %v", err)
+	}
+	defer source.Close()
+
+	// Rows as api-accounts writes them
+	now := time.Date(2024, 12, 1, 12, 0, 0, 0, time.UTC)
+	for i, row := range []struct{ id, userID string }{
+		{"sec-1", "user-001"},
+		{"sec-2", "user-002"},
+		{"sec-3", "user-001"},
+	} {
+		if _, err := source.db.Exec(`INSERT INTO security_events (id, user_id, type, message, created_at) VALUES (?, ?, ?, ?, ?)`,
+			row.id, row.userID, models.SecurityEventAccountLocked, "Sign-in was locked", now.Add(time.Duration(i)*time.Minute).Unix()); err != nil {
+			t.Fatalf("Failed to insert event: %v", err)
+		}
+	}
+
+	events, err := source.GetSecurityEventsByUserID("user-001")
+	if err != nil {
+		t.Fatalf("GetSecurityEventsByUserID failed: %v", err)
+	}
+	if len(events) != 2 || events[0].ID != "sec-3" || events[1].ID != "sec-1" {
+		t.Fatalf("Expected user-001's events newest first, got %+v", events)
+	}
+	if !events[1].CreatedAt.Equal(now) || events[1].Type != models.SecurityEventAccountLocked {
+		t.Errorf("Unexpected event %+v", events[1])
+	}
+
+	none, err := NewSecurityEventSource("", "")
+	if err != nil {
+		t.Fatalf("NewSecurityEventSource failed: %v", err)
+	}
+	if events, err := none.GetSecurityEventsByUserID("user-001"); err != nil || len(events) != 0 {
+		t.Errorf("Expected no events without a store, got %v (%v)", events, err)
+	}
+}
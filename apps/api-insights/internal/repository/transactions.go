@@ -0,0 +1,302 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/models"
+)
+
+// Transaction sources selectable via the TRANSACTIONS_STORE environment
+// variable. Pick the one matching the STORAGE_BACKEND of api-transactions.
+const (
+	TransactionsNone   = "none"
+	TransactionsJSON   = "json"
+	TransactionsSQLite = "sqlite"
+)
+
+// TransactionSource reads the accounts and transactions api-transactions
+// keeps, for the insights engine and alert rules
+type TransactionSource interface {
+	// GetAccountIDsByUserID returns the IDs of the accounts the user owns
+	GetAccountIDsByUserID(userID string) ([]string, error)
+	// GetAccountOwnerID returns the owner of an account, or "" if it does not exist
+	GetAccountOwnerID(accountID string) (string, error)
+	// GetTransactionsByAccountIDs returns the transactions on any of the
+	// accounts, oldest first
+	GetTransactionsByAccountIDs(accountIDs []string) ([]*models.Transaction, error)
+	// GetBalancesByAccountIDs returns the balances of the accounts that
+	// exist, by account ID
+	GetBalancesByAccountIDs(accountIDs []string) (map[string]float64, error)
+	Close() error
+}
+
+// NewTransactionSource creates the source for the configured store. For the
+// json store path is the seed data directory, for sqlite the database file
+// api-transactions writes to.
+func NewTransactionSource(store, path string) (TransactionSource, error) {
+	switch store {
+	case "", TransactionsNone:
+		return noTransactions{}, nil
+	case TransactionsJSON:
+		return NewJSONTransactions(path)
+	case TransactionsSQLite:
+		return NewSQLiteTransactions(path)
+	default:
+		return nil, fmt.Errorf("unknown transactions store %q", store)
+	}
+}
+
+// noTransactions is used when transactions are not shared with this service
+type noTransactions struct{}
+
+func (noTransactions) GetAccountIDsByUserID(string) ([]string, error) {
+	return nil, nil
+}
+
+func (noTransactions) GetAccountOwnerID(string) (string, error) {
+	return "", nil
+}
+
+func (noTransactions) GetTransactionsByAccountIDs([]string) ([]*models.Transaction, error) {
+	return nil, nil
+}
+
+func (noTransactions) GetBalancesByAccountIDs([]string) (map[string]float64, error) {
+	return nil, nil
+}
+
+func (noTransactions) Close() error {
+	return nil
+}
+
+// JSONTransactions serves the seed accounts and transactions api-transactions
+// starts from with its json backend. Changes made through the API are not seen.
+type JSONTransactions struct {
+	owners       map[string]string  // account ID to user ID
+	balances     map[string]float64 // account ID to balance
+	transactions []*models.Transaction
+}
+
+// NewJSONTransactions loads accounts.json and transactions.json from dataPath
+func NewJSONTransactions(dataPath string) (*JSONTransactions, error) {
+	var accounts []struct {
+		ID      string  `json:"id"`
+		UserID  string  `json:"userId"`
+		Balance float64 `json:"balance"`
+	}
+	if err := readJSONFile(filepath.Join(dataPath, "accounts.json"), &accounts); err != nil {
+		return nil, fmt.Errorf("failed to load accounts: %w", err)
+	}
+	var transactions []*models.Transaction
+	if err := readJSONFile(filepath.Join(dataPath, "transactions.json"), &transactions); err != nil {
+		return nil, fmt.Errorf("failed to load transactions: %w", err)
+	}
+
+	owners := make(map[string]string, len(accounts))
+	balances := make(map[string]float64, len(accounts))
+	for _, account := range accounts {
+		owners[account.ID] = account.UserID
+		balances[account.ID] = account.Balance
+	}
+	sortTransactions(transactions)
+
+	return &JSONTransactions{owners: owners, balances: balances, transactions: transactions}, nil
+}
+
+// GetAccountIDsByUserID returns the IDs of the accounts the user owns
+func (s *JSONTransactions) GetAccountIDsByUserID(userID string) ([]string, error) {
+	var accountIDs []string
+	for id, owner := range s.owners {
+		if owner == userID {
+			accountIDs = append(accountIDs, id)
+		}
+	}
+	sort.Strings(accountIDs)
+	return accountIDs, nil
+}
+
+// GetAccountOwnerID returns the owner of an account, or "" if it does not exist
+func (s *JSONTransactions) GetAccountOwnerID(accountID string) (string, error) {
+	return s.owners[accountID], nil
+}
+
+// GetTransactionsByAccountIDs returns the transactions on any of the accounts, oldest first
+func (s *JSONTransactions) GetTransactionsByAccountIDs(accountIDs []string) ([]*models.Transaction, error) {
+	wanted := make(map[string]bool, len(accountIDs))
+	for _, id := range accountIDs {
+		wanted[id] = true
+	}
+	var transactions []*models.Transaction
+	for _, txn := range s.transactions {
+		if wanted[txn.AccountID] {
+			transactions = append(transactions, txn)
+		}
+	}
+	return transactions, nil
+}
+
+// GetBalancesByAccountIDs returns the balances of the accounts that exist, by account ID
+func (s *JSONTransactions) GetBalancesByAccountIDs(accountIDs []string) (map[string]float64, error) {
+	balances := make(map[string]float64, len(accountIDs))
+	for _, id := range accountIDs {
+		if balance, ok := s.balances[id]; ok {
+			balances[id] = balance
+		}
+	}
+	return balances, nil
+}
+
+// Close releases nothing; the data is held in memory
+func (s *JSONTransactions) Close() error {
+	return nil
+}
+
+// SQLiteTransactions reads the accounts and transactions tables
+// api-transactions maintains in the shared database
+type SQLiteTransactions struct {
+	db *sql.DB
+}
+
+// NewSQLiteTransactions opens the database at dbPath. The tables belong to
+// the api-transactions migrations and are not created here, so queries fail
+// until api-transactions has started once against the same file.
+func NewSQLiteTransactions(dbPath string) (*SQLiteTransactions, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", dbPath)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open transactions database: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open transactions database: %w", err)
+	}
+
+	return &SQLiteTransactions{db: db}, nil
+}
+
+// GetAccountIDsByUserID returns the IDs of the accounts the user owns
+func (s *SQLiteTransactions) GetAccountIDsByUserID(userID string) ([]string, error) {
+	rows, err := s.db.Query(`SELECT id FROM accounts WHERE user_id = ? ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accountIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		accountIDs = append(accountIDs, id)
+	}
+	return accountIDs, rows.Err()
+}
+
+// GetAccountOwnerID returns the owner of an account, or "" if it does not exist
+func (s *SQLiteTransactions) GetAccountOwnerID(accountID string) (string, error) {
+	var userID string
+	err := s.db.QueryRow(`SELECT user_id FROM accounts WHERE id = ?`, accountID).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return userID, err
+}
+
+// GetTransactionsByAccountIDs returns the transactions on any of the accounts, oldest first
+func (s *SQLiteTransactions) GetTransactionsByAccountIDs(accountIDs []string) ([]*models.Transaction, error) {
+	if len(accountIDs) == 0 {
+		return nil, nil
+	}
+	placeholders, args := inClause(accountIDs)
+
+	rows, err := s.db.Query(`SELECT id, account_id, date, description, amount, category, merchant, status, type
+		FROM transactions WHERE account_id IN (`+placeholders+`) ORDER BY date, id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []*models.Transaction
+	for rows.Next() {
+		var txn models.Transaction
+		var date string
+		if err := rows.Scan(&txn.ID, &txn.AccountID, &date, &txn.Description, &txn.Amount,
+			&txn.Category, &txn.Merchant, &txn.Status, &txn.Type); err != nil {
+			return nil, err
+		}
+		// api-transactions writes fixed-width RFC 3339 UTC timestamps
+		if txn.Date, err = time.Parse(time.RFC3339Nano, date); err != nil {
+			return nil, fmt.Errorf("transaction %s has an invalid date: %w", txn.ID, err)
+		}
+		transactions = append(transactions, &txn)
+	}
+	return transactions, rows.Err()
+}
+
+// GetBalancesByAccountIDs returns the balances of the accounts that exist, by account ID
+func (s *SQLiteTransactions) GetBalancesByAccountIDs(accountIDs []string) (map[string]float64, error) {
+	if len(accountIDs) == 0 {
+		return nil, nil
+	}
+	placeholders, args := inClause(accountIDs)
+
+	rows, err := s.db.Query(`SELECT id, balance FROM accounts WHERE id IN (`+placeholders+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	balances := make(map[string]float64, len(accountIDs))
+	for rows.Next() {
+		var id string
+		var balance float64
+		if err := rows.Scan(&id, &balance); err != nil {
+			return nil, err
+		}
+		balances[id] = balance
+	}
+	return balances, rows.Err()
+}
+
+// Close closes the underlying database
+func (s *SQLiteTransactions) Close() error {
+	return s.db.Close()
+}
+
+// inClause returns the placeholders and arguments of an IN (...) over ids
+func inClause(ids []string) (string, []any) {
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", "), args
+}
+
+// readJSONFile decodes the JSON file at path into v
+func readJSONFile(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// sortTransactions orders transactions oldest first, by ID on the same instant
+func sortTransactions(transactions []*models.Transaction) {
+	sort.Slice(transactions, func(i, j int) bool {
+		if !transactions[i].Date.Equal(transactions[j].Date) {
+			return transactions[i].Date.Before(transactions[j].Date)
+		}
+		return transactions[i].ID < transactions[j].ID
+	})
+}
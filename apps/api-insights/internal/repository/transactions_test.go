@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSQLiteTransactions(t *testing.T) {
+	source, err := NewSQLiteTransactions(filepath.Join(t.TempDir(), "transactions.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteTransactions failed: %v", err)
+	}
+	defer source.Close()
+
+	// Rows as api-transactions writes them, dates in its fixed-width format
+	if _, err := source.db.Exec(`CREATE TABLE accounts (id TEXT PRIMARY KEY, user_id TEXT NOT NULL, balance REAL NOT NULL);
+		CREATE TABLE transactions (id TEXT PRIMARY KEY, account_id TEXT NOT NULL, date TEXT NOT NULL, description TEXT NOT NULL,
+			amount REAL NOT NULL, category TEXT NOT NULL, merchant TEXT NOT NULL, status TEXT NOT NULL, type TEXT NOT NULL);
+		INSERT INTO accounts (id, user_id, balance) VALUES ('acc-001', 'user-001', 5847.32), ('acc-002', 'user-001', 100), ('acc-004', 'user-002', 0);
+		INSERT INTO transactions VALUES
+			('txn-2', 'acc-002', '2024-12-02T09:00:00.000000000Z', 'Transfer', 100, 'transfer', 'Internal Transfer', 'completed', 'credit'),
+			('txn-1', 'acc-001', '2024-12-01T09:00:00.000000000Z', 'Coffee', -5.47, 'food_dining', 'Starbucks', 'completed', 'debit'),
+			('txn-3', 'acc-004', '2024-12-03T09:00:00.000000000Z', 'Paper', -20, 'business', 'Office Depot', 'pending', 'debit')`); err != nil {
+		t.Fatalf("Failed to insert rows: %v", err)
+	}
+
+	accountIDs, err := source.GetAccountIDsByUserID("user-001")
+	if err != nil || len(accountIDs) != 2 || accountIDs[0] != "acc-001" || accountIDs[1] != "acc-002" {
+		t.Fatalf("Expected user-001's accounts, got %v (%v)", accountIDs, err)
+	}
+	if owner, err := source.GetAccountOwnerID("acc-004"); err != nil || owner != "user-002" {
+		t.Errorf("Expected acc-004 to belong to user-002, got %q (%v)", owner, err)
+	}
+	if owner, err := source.GetAccountOwnerID("acc-999"); err != nil || owner != "" {
+		t.Errorf("Expected no owner for a missing account, got %q (%v)", owner, err)
+	}
+
+	transactions, err := source.GetTransactionsByAccountIDs(accountIDs)
+	if err != nil {
+		t.Fatalf("GetTransactionsByAccountIDs failed: %v", err)
+	}
+	if len(transactions) != 2 || transactions[0].ID != "txn-1" || transactions[1].ID != "txn-2" {
+		t.Fatalf("Expected user-001's transactions oldest first, got %+v", transactions)
+	}
+	if want := time.Date(2024, 12, 1, 9, 0, 0, 0, time.UTC); !transactions[0].Date.Equal(want) || transactions[0].Amount != -5.47 {
+		t.Errorf("Unexpected transaction %+v", transactions[0])
+	}
+
+	balances, err := source.GetBalancesByAccountIDs([]string{"acc-001", "acc-999"})
+	if err != nil || len(balances) != 1 || balances["acc-001"] != 5847.32 {
+		t.Errorf("Expected acc-001's balance only, got %v (%v)", balances, err)
+	}
+}
+
+func TestJSONTransactions(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"accounts.json": `[{"id": "acc-001", "userId": "user-001", "balance": 250.5}, {"id": "acc-004", "userId": "user-002", "balance": 10}]`,
+		"transactions.json": `[
+			{"id": "txn-2", "accountId": "acc-001", "date": "2024-12-02T09:00:00Z", "amount": -20, "category": "groceries"},
+			{"id": "txn-3", "accountId": "acc-004", "date": "2024-12-01T09:00:00Z", "amount": -30, "category": "business"},
+			{"id": "txn-1", "accountId": "acc-001", "date": "2024-12-01T09:00:00Z", "amount": -10, "category": "groceries"}
+		]`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	source, err := NewTransactionSource(TransactionsJSON, dir)
+	if err != nil {
+		t.Fatalf("NewTransactionSource failed: %v", err)
+	}
+	defer source.Close()
+
+	accountIDs, err := source.GetAccountIDsByUserID("user-001")
+	if err != nil || len(accountIDs) != 1 || accountIDs[0] != "acc-001" {
+		t.Fatalf("Expected user-001's account, got %v (%v)", accountIDs, err)
+	}
+	transactions, err := source.GetTransactionsByAccountIDs(accountIDs)
+	if err != nil || len(transactions) != 2 || transactions[0].ID != "txn-1" || transactions[1].ID != "txn-2" {
+		t.Errorf("Expected acc-001's transactions oldest first, got %+v (%v)", transactions, err)
+	}
+	if balances, err := source.GetBalancesByAccountIDs(accountIDs); err != nil || balances["acc-001"] != 250.5 {
+		t.Errorf("Expected acc-001's balance, got %v (%v)", balances, err)
+	}
+
+	none, err := NewTransactionSource("", "")
+	if err != nil {
+		t.Fatalf("NewTransactionSource failed: %v", err)
+	}
+	if transactions, err := none.GetTransactionsByAccountIDs([]string{"acc-001"}); err != nil || len(transactions) != 0 {
+		t.Errorf("Expected no transactions without a store, got %v (%v)", transactions, err)
+	}
+}
@@ -0,0 +1,550 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/models"
+)
+
+var (
+	// ErrWebhookNotFound is returned for a webhook the user does not have
+	ErrWebhookNotFound = errors.New("webhook not found")
+
+	// ErrDeliveryNotFound is returned for a delivery the user does not have
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+// Webhook stores selectable via the WEBHOOKS_STORE environment variable
+const (
+	WebhooksMemory = "memory"
+	WebhooksSQLite = "sqlite"
+)
+
+// WebhookStore keeps webhooks and the queue and log of their deliveries
+type WebhookStore interface {
+	// GetWebhooks returns the user's webhooks, oldest first
+	GetWebhooks(userID string) ([]*models.Webhook, error)
+	// GetWebhook returns one of the user's webhooks, or ErrWebhookNotFound
+	GetWebhook(userID, webhookID string) (*models.Webhook, error)
+	// GetSubscribedUserIDs returns the users with an enabled webhook
+	GetSubscribedUserIDs() ([]string, error)
+	// SaveWebhook creates or replaces a webhook
+	SaveWebhook(webhook *models.Webhook) error
+	// DeleteWebhook deletes one of the user's webhooks and its deliveries, or
+	// returns ErrWebhookNotFound
+	DeleteWebhook(userID, webhookID string) error
+
+	// AddDeliveries queues deliveries
+	AddDeliveries(deliveries []*models.WebhookDelivery) error
+	// GetDueDeliveries returns up to limit pending deliveries due at now,
+	// those due first first
+	GetDueDeliveries(now time.Time, limit int) ([]*models.WebhookDelivery, error)
+	// GetDeliveries returns the user's deliveries that match filters, newest first
+	GetDeliveries(userID string, filters models.DeliveryFilters) ([]*models.WebhookDelivery, error)
+	// GetDelivery returns one of the user's deliveries, or ErrDeliveryNotFound
+	GetDelivery(userID, deliveryID string) (*models.WebhookDelivery, error)
+	// SaveDelivery records the outcome of an attempt, or a redelivery
+	SaveDelivery(delivery *models.WebhookDelivery) error
+	Close() error
+}
+
+// NewWebhookStore creates the store for the configured backend. path is the
+// database file used by the sqlite store.
+func NewWebhookStore(store, path string) (WebhookStore, error) {
+	switch store {
+	case "", WebhooksMemory:
+		return NewMemoryWebhooks(), nil
+	case WebhooksSQLite:
+		return NewSQLiteWebhooks(path)
+	default:
+		return nil, fmt.Errorf("unknown webhook store %q", store)
+	}
+}
+
+// MemoryWebhooks keeps webhooks and deliveries in this process. Both are
+// lost on restart, queued deliveries included, so it suits tests and
+// development.
+type MemoryWebhooks struct {
+	webhooks   map[string]*models.Webhook         // by webhook ID
+	deliveries map[string]*models.WebhookDelivery // by delivery ID
+	mu         sync.RWMutex
+}
+
+// NewMemoryWebhooks creates an empty in-memory store
+func NewMemoryWebhooks() *MemoryWebhooks {
+	return &MemoryWebhooks{
+		webhooks:   make(map[string]*models.Webhook),
+		deliveries: make(map[string]*models.WebhookDelivery),
+	}
+}
+
+// GetWebhooks returns the user's webhooks, oldest first
+func (s *MemoryWebhooks) GetWebhooks(userID string) ([]*models.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var webhooks []*models.Webhook
+	for _, webhook := range s.webhooks {
+		if webhook.UserID == userID {
+			webhooks = append(webhooks, copyWebhook(webhook))
+		}
+	}
+	sort.Slice(webhooks, func(i, j int) bool {
+		if !webhooks[i].CreatedAt.Equal(webhooks[j].CreatedAt) {
+			return webhooks[i].CreatedAt.Before(webhooks[j].CreatedAt)
+		}
+		return webhooks[i].ID < webhooks[j].ID
+	})
+	return webhooks, nil
+}
+
+// GetWebhook returns one of the user's webhooks
+func (s *MemoryWebhooks) GetWebhook(userID, webhookID string) (*models.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	webhook, ok := s.webhooks[webhookID]
+	if !ok || webhook.UserID != userID {
+		return nil, ErrWebhookNotFound
+	}
+	return copyWebhook(webhook), nil
+}
+
+// GetSubscribedUserIDs returns the users with an enabled webhook
+func (s *MemoryWebhooks) GetSubscribedUserIDs() ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var userIDs []string
+	for _, webhook := range s.webhooks {
+		if webhook.Enabled && !seen[webhook.UserID] {
+			seen[webhook.UserID] = true
+			userIDs = append(userIDs, webhook.UserID)
+		}
+	}
+	sort.Strings(userIDs)
+	return userIDs, nil
+}
+
+// SaveWebhook creates or replaces a webhook
+func (s *MemoryWebhooks) SaveWebhook(webhook *models.Webhook) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.webhooks[webhook.ID] = copyWebhook(webhook)
+	return nil
+}
+
+// DeleteWebhook deletes one of the user's webhooks and its deliveries
+func (s *MemoryWebhooks) DeleteWebhook(userID, webhookID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	webhook, ok := s.webhooks[webhookID]
+	if !ok || webhook.UserID != userID {
+		return ErrWebhookNotFound
+	}
+	delete(s.webhooks, webhookID)
+	for id, delivery := range s.deliveries {
+		if delivery.WebhookID == webhookID {
+			delete(s.deliveries, id)
+		}
+	}
+	return nil
+}
+
+// AddDeliveries queues deliveries
+func (s *MemoryWebhooks) AddDeliveries(deliveries []*models.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, delivery := range deliveries {
+		s.deliveries[delivery.ID] = copyDelivery(delivery)
+	}
+	return nil
+}
+
+// GetDueDeliveries returns up to limit pending deliveries due at now
+func (s *MemoryWebhooks) GetDueDeliveries(now time.Time, limit int) ([]*models.WebhookDelivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var due []*models.WebhookDelivery
+	for _, delivery := range s.deliveries {
+		if delivery.Status == models.DeliveryPending && delivery.NextAttemptAt != nil && !delivery.NextAttemptAt.After(now) {
+			due = append(due, copyDelivery(delivery))
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].NextAttemptAt.Equal(*due[j].NextAttemptAt) {
+			return due[i].NextAttemptAt.Before(*due[j].NextAttemptAt)
+		}
+		if !due[i].CreatedAt.Equal(due[j].CreatedAt) {
+			return due[i].CreatedAt.Before(due[j].CreatedAt)
+		}
+		return due[i].ID < due[j].ID
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+// GetDeliveries returns the user's deliveries that match filters, newest first
+func (s *MemoryWebhooks) GetDeliveries(userID string, filters models.DeliveryFilters) ([]*models.WebhookDelivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var deliveries []*models.WebhookDelivery
+	for _, delivery := range s.deliveries {
+		if delivery.UserID != userID ||
+			(filters.WebhookID != "" && delivery.WebhookID != filters.WebhookID) ||
+			(filters.Status != "" && delivery.Status != filters.Status) {
+			continue
+		}
+		deliveries = append(deliveries, copyDelivery(delivery))
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		if !deliveries[i].CreatedAt.Equal(deliveries[j].CreatedAt) {
+			return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+		}
+		return deliveries[i].ID > deliveries[j].ID
+	})
+	if filters.Limit > 0 && len(deliveries) > filters.Limit {
+		deliveries = deliveries[:filters.Limit]
+	}
+	return deliveries, nil
+}
+
+// GetDelivery returns one of the user's deliveries
+func (s *MemoryWebhooks) GetDelivery(userID, deliveryID string) (*models.WebhookDelivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	delivery, ok := s.deliveries[deliveryID]
+	if !ok || delivery.UserID != userID {
+		return nil, ErrDeliveryNotFound
+	}
+	return copyDelivery(delivery), nil
+}
+
+// SaveDelivery replaces a delivery. One whose webhook was deleted meanwhile
+// is not kept.
+func (s *MemoryWebhooks) SaveDelivery(delivery *models.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.deliveries[delivery.ID]; ok {
+		s.deliveries[delivery.ID] = copyDelivery(delivery)
+	}
+	return nil
+}
+
+// Close releases nothing; the webhooks are held in memory
+func (s *MemoryWebhooks) Close() error {
+	return nil
+}
+
+// copyWebhook copies a webhook, so callers cannot change the stored one
+func copyWebhook(webhook *models.Webhook) *models.Webhook {
+	copied := *webhook
+	copied.Events = append([]string(nil), webhook.Events...)
+	return &copied
+}
+
+// copyDelivery copies a delivery, so callers cannot change the stored one
+func copyDelivery(delivery *models.WebhookDelivery) *models.WebhookDelivery {
+	copied := *delivery
+	copied.Payload = append([]byte(nil), delivery.Payload...)
+	return &copied
+}
+
+// webhookColumns are the columns of webhooks, in the order scanWebhook reads them
+const webhookColumns = `id, user_id, url, events, secret, enabled, created_at, updated_at`
+
+// deliveryColumns are the columns of webhook_deliveries, in the order
+// scanDelivery reads them
+const deliveryColumns = `id, webhook_id, user_id, event_id, event_type, payload, status, attempts,
+	next_attempt_at, last_attempt_at, response_status, last_error, created_at`
+
+// SQLiteWebhooks keeps webhooks in the webhooks table and deliveries in
+// webhook_deliveries, so queued deliveries survive restarts
+type SQLiteWebhooks struct {
+	db *sql.DB
+}
+
+// NewSQLiteWebhooks opens (or creates) the webhooks and webhook_deliveries
+// tables in the database at dbPath
+func NewSQLiteWebhooks(dbPath string) (*SQLiteWebhooks, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", dbPath)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open webhooks database: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS webhooks (
+		id         TEXT PRIMARY KEY,
+		user_id    TEXT NOT NULL,
+		url        TEXT NOT NULL,
+		events     TEXT NOT NULL,
+		secret     TEXT NOT NULL,
+		enabled    INTEGER NOT NULL,
+		created_at INTEGER NOT NULL,
+		updated_at INTEGER NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_webhooks_user_id ON webhooks (user_id);
+	CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id              TEXT PRIMARY KEY,
+		webhook_id      TEXT NOT NULL,
+		user_id         TEXT NOT NULL,
+		event_id        TEXT NOT NULL,
+		event_type      TEXT NOT NULL,
+		payload         TEXT NOT NULL,
+		status          TEXT NOT NULL,
+		attempts        INTEGER NOT NULL,
+		next_attempt_at INTEGER,
+		last_attempt_at INTEGER,
+		response_status INTEGER NOT NULL,
+		last_error      TEXT NOT NULL,
+		created_at      INTEGER NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);
+	CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_user_id ON webhook_deliveries (user_id, created_at)`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create webhooks: %w", err)
+	}
+
+	return &SQLiteWebhooks{db: db}, nil
+}
+
+// GetWebhooks returns the user's webhooks, oldest first
+func (s *SQLiteWebhooks) GetWebhooks(userID string) ([]*models.Webhook, error) {
+	rows, err := s.db.Query(`SELECT `+webhookColumns+` FROM webhooks
+		WHERE user_id = ? ORDER BY created_at, id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []*models.Webhook
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+// GetWebhook returns one of the user's webhooks
+func (s *SQLiteWebhooks) GetWebhook(userID, webhookID string) (*models.Webhook, error) {
+	webhook, err := scanWebhook(s.db.QueryRow(`SELECT `+webhookColumns+` FROM webhooks
+		WHERE id = ? AND user_id = ?`, webhookID, userID))
+	if err == sql.ErrNoRows {
+		return nil, ErrWebhookNotFound
+	}
+	return webhook, err
+}
+
+// GetSubscribedUserIDs returns the users with an enabled webhook
+func (s *SQLiteWebhooks) GetSubscribedUserIDs() ([]string, error) {
+	rows, err := s.db.Query(`SELECT DISTINCT user_id FROM webhooks WHERE enabled = 1 ORDER BY user_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+// SaveWebhook creates or replaces a webhook
+func (s *SQLiteWebhooks) SaveWebhook(webhook *models.Webhook) error {
+	_, err := s.db.Exec(`INSERT INTO webhooks (`+webhookColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET url = excluded.url, events = excluded.events, secret = excluded.secret,
+			enabled = excluded.enabled, updated_at = excluded.updated_at`,
+		webhook.ID, webhook.UserID, webhook.URL, strings.Join(webhook.Events, ","), webhook.Secret,
+		webhook.Enabled, webhook.CreatedAt.Unix(), webhook.UpdatedAt.Unix())
+	return err
+}
+
+// DeleteWebhook deletes one of the user's webhooks and its deliveries
+func (s *SQLiteWebhooks) DeleteWebhook(userID, webhookID string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`DELETE FROM webhooks WHERE id = ? AND user_id = ?`, webhookID, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrWebhookNotFound
+	}
+	if _, err := tx.Exec(`DELETE FROM webhook_deliveries WHERE webhook_id = ?`, webhookID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// AddDeliveries queues deliveries
+func (s *SQLiteWebhooks) AddDeliveries(deliveries []*models.WebhookDelivery) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, d := range deliveries {
+		if _, err := tx.Exec(`INSERT INTO webhook_deliveries (`+deliveryColumns+`)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			d.ID, d.WebhookID, d.UserID, d.EventID, d.EventType, string(d.Payload), d.Status, d.Attempts,
+			unixOrNull(d.NextAttemptAt), unixOrNull(d.LastAttemptAt), d.ResponseStatus, d.LastError, d.CreatedAt.Unix()); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetDueDeliveries returns up to limit pending deliveries due at now
+func (s *SQLiteWebhooks) GetDueDeliveries(now time.Time, limit int) ([]*models.WebhookDelivery, error) {
+	return s.queryDeliveries(`SELECT `+deliveryColumns+` FROM webhook_deliveries
+		WHERE status = ? AND next_attempt_at <= ?
+		ORDER BY next_attempt_at, created_at, id LIMIT ?`, models.DeliveryPending, now.Unix(), limit)
+}
+
+// GetDeliveries returns the user's deliveries that match filters, newest first
+func (s *SQLiteWebhooks) GetDeliveries(userID string, filters models.DeliveryFilters) ([]*models.WebhookDelivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries WHERE user_id = ?`
+	args := []any{userID}
+	if filters.WebhookID != "" {
+		query += ` AND webhook_id = ?`
+		args = append(args, filters.WebhookID)
+	}
+	if filters.Status != "" {
+		query += ` AND status = ?`
+		args = append(args, filters.Status)
+	}
+	query += ` ORDER BY created_at DESC, id DESC`
+	if filters.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, filters.Limit)
+	}
+	return s.queryDeliveries(query, args...)
+}
+
+// GetDelivery returns one of the user's deliveries
+func (s *SQLiteWebhooks) GetDelivery(userID, deliveryID string) (*models.WebhookDelivery, error) {
+	delivery, err := scanDelivery(s.db.QueryRow(`SELECT `+deliveryColumns+` FROM webhook_deliveries
+		WHERE id = ? AND user_id = ?`, deliveryID, userID))
+	if err == sql.ErrNoRows {
+		return nil, ErrDeliveryNotFound
+	}
+	return delivery, err
+}
+
+// SaveDelivery replaces a delivery. One whose webhook was deleted meanwhile
+// is not kept.
+func (s *SQLiteWebhooks) SaveDelivery(d *models.WebhookDelivery) error {
+	_, err := s.db.Exec(`UPDATE webhook_deliveries SET status = ?, attempts = ?, next_attempt_at = ?,
+			last_attempt_at = ?, response_status = ?, last_error = ?
+		WHERE id = ?`,
+		d.Status, d.Attempts, unixOrNull(d.NextAttemptAt), unixOrNull(d.LastAttemptAt), d.ResponseStatus, d.LastError, d.ID)
+	return err
+}
+
+// Close closes the underlying database
+func (s *SQLiteWebhooks) Close() error {
+	return s.db.Close()
+}
+
+// queryDeliveries runs a query selecting deliveryColumns
+func (s *SQLiteWebhooks) queryDeliveries(query string, args ...any) ([]*models.WebhookDelivery, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*models.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// scanWebhook reads a row of webhookColumns
+func scanWebhook(row interface{ Scan(...any) error }) (*models.Webhook, error) {
+	var webhook models.Webhook
+	var events string
+	var createdAt, updatedAt int64
+	if err := row.Scan(&webhook.ID, &webhook.UserID, &webhook.URL, &events, &webhook.Secret, &webhook.Enabled,
+		&createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+	if events != "" {
+		webhook.Events = strings.Split(events, ",")
+	}
+	webhook.CreatedAt = time.Unix(createdAt, 0).UTC()
+	webhook.UpdatedAt = time.Unix(updatedAt, 0).UTC()
+	return &webhook, nil
+}
+
+// scanDelivery reads a row of deliveryColumns
+func scanDelivery(row interface{ Scan(...any) error }) (*models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	var payload string
+	var nextAttemptAt, lastAttemptAt sql.NullInt64
+	var createdAt int64
+	if err := row.Scan(&d.ID, &d.WebhookID, &d.UserID, &d.EventID, &d.EventType, &payload, &d.Status, &d.Attempts,
+		&nextAttemptAt, &lastAttemptAt, &d.ResponseStatus, &d.LastError, &createdAt); err != nil {
+		return nil, err
+	}
+	d.Payload = []byte(payload)
+	d.NextAttemptAt = timeOrNil(nextAttemptAt)
+	d.LastAttemptAt = timeOrNil(lastAttemptAt)
+	d.CreatedAt = time.Unix(createdAt, 0).UTC()
+	return &d, nil
+}
+
+// unixOrNull stores an optional time as unix seconds, or NULL
+func unixOrNull(t *time.Time) sql.NullInt64 {
+	if t == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: t.Unix(), Valid: true}
+}
+
+// timeOrNil reads an optional time stored by unixOrNull
+func timeOrNil(v sql.NullInt64) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	t := time.Unix(v.Int64, 0).UTC()
+	return &t
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/models"
+)
+
+func TestWebhookStores(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "webhooks.db")
+	sqliteStore, err := NewSQLiteWebhooks(dbPath)
+	if err != nil {
+		t.Fatalf("NewSQLiteWebhooks failed: %v", err)
+	}
+	defer sqliteStore.Close()
+
+	stores := map[string]WebhookStore{
+		"memory": NewMemoryWebhooks(),
+		"sqlite": sqliteStore,
+	}
+	now := time.Date(2024, 12, 13, 8, 0, 0, 0, time.UTC)
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			webhooks := []*models.Webhook{
+				{ID: "wh-2", UserID: "user-001", URL: "https://example.com/b", Events: []string{models.WebhookAlertCreated},
+					Secret: "whsec_b", Enabled: true, CreatedAt: now.Add(time.Minute), UpdatedAt: now.Add(time.Minute)},
+				{ID: "wh-1", UserID: "user-001", URL: "https://example.com/a",
+					Events: []string{models.WebhookTransactionCreated, models.WebhookBalanceLow},
+					Secret: "whsec_a", Enabled: true, CreatedAt: now, UpdatedAt: now},
+				{ID: "wh-3", UserID: "user-002", URL: "https://example.org", Events: []string{models.WebhookAlertCreated},
+					Secret: "whsec_c", Enabled: false, CreatedAt: now, UpdatedAt: now},
+			}
+			for _, webhook := range webhooks {
+				if err := store.SaveWebhook(webhook); err != nil {
+					t.Fatalf("SaveWebhook failed: %v", err)
+				}
+			}
+
+			list, err := store.GetWebhooks("user-001")
+			if err != nil || len(list) != 2 || list[0].ID != "wh-1" || list[1].ID != "wh-2" {
+				t.Fatalf("Expected user-001's webhooks oldest first, got %+v (%v)", list, err)
+			}
+			if !reflect.DeepEqual(list[0], webhooks[1]) {
+				t.Errorf("Expected the webhook as saved, got %+v", list[0])
+			}
+			if _, err := store.GetWebhook("user-002", "wh-1"); !errors.Is(err, ErrWebhookNotFound) {
+				t.Errorf("Expected another user's webhook not to be found, got %v", err)
+			}
+			if userIDs, err := store.GetSubscribedUserIDs(); err != nil || !reflect.DeepEqual(userIDs, []string{"user-001"}) {
+				t.Errorf("Expected only user-001 to have an enabled webhook, got %v (%v)", userIDs, err)
+			}
+
+			next := now.Add(time.Minute)
+			deliveries := []*models.WebhookDelivery{
+				{ID: "dlv-1", WebhookID: "wh-1", UserID: "user-001", EventID: "evt-1", EventType: models.WebhookTransactionCreated,
+					Payload: []byte(`{"id":"evt-1"}`), Status: models.DeliveryPending, NextAttemptAt: &now, CreatedAt: now},
+				{ID: "dlv-2", WebhookID: "wh-2", UserID: "user-001", EventID: "evt-2", EventType: models.WebhookAlertCreated,
+					Payload: []byte(`{"id":"evt-2"}`), Status: models.DeliveryPending, NextAttemptAt: &next, CreatedAt: now.Add(time.Second)},
+				{ID: "dlv-3", WebhookID: "wh-3", UserID: "user-002", EventID: "evt-3", EventType: models.WebhookAlertCreated,
+					Payload: []byte(`{"id":"evt-3"}`), Status: models.DeliveryPending, NextAttemptAt: &now, CreatedAt: now},
+			}
+			if err := store.AddDeliveries(deliveries); err != nil {
+				t.Fatalf("AddDeliveries failed: %v", err)
+			}
+
+			due, err := store.GetDueDeliveries(now, 10)
+			if err != nil || len(due) != 2 || due[0].ID != "dlv-1" || due[1].ID != "dlv-3" {
+				t.Fatalf("Expected the deliveries due now, got %+v (%v)", due, err)
+			}
+			if !reflect.DeepEqual(due[0], deliveries[0]) {
+				t.Errorf("Expected the delivery as queued, got %+v", due[0])
+			}
+			if due, _ := store.GetDueDeliveries(now.Add(time.Hour), 1); len(due) != 1 || due[0].ID != "dlv-1" {
+				t.Errorf("Expected the limit to keep the earliest due, got %+v", due)
+			}
+
+			// A failed attempt is rescheduled
+			attempted := *deliveries[0]
+			retryAt := now.Add(30 * time.Second)
+			attempted.Attempts = 1
+			attempted.LastAttemptAt = &now
+			attempted.NextAttemptAt = &retryAt
+			attempted.ResponseStatus = 500
+			attempted.LastError = "endpoint responded with status 500"
+			if err := store.SaveDelivery(&attempted); err != nil {
+				t.Fatalf("SaveDelivery failed: %v", err)
+			}
+			got, err := store.GetDelivery("user-001", "dlv-1")
+			if err != nil || !reflect.DeepEqual(got, &attempted) {
+				t.Errorf("Expected the attempt recorded, got %+v (%v)", got, err)
+			}
+			if due, _ := store.GetDueDeliveries(now, 10); len(due) != 1 || due[0].ID != "dlv-3" {
+				t.Errorf("Expected the retry not to be due yet, got %+v", due)
+			}
+
+			// Dead deliveries leave the queue for the dead-letter list
+			attempted.Status = models.DeliveryDead
+			attempted.NextAttemptAt = nil
+			if err := store.SaveDelivery(&attempted); err != nil {
+				t.Fatalf("SaveDelivery failed: %v", err)
+			}
+			if due, _ := store.GetDueDeliveries(now.Add(time.Hour), 10); len(due) != 2 {
+				t.Errorf("Expected the dead delivery to leave the queue, got %+v", due)
+			}
+			dead, err := store.GetDeliveries("user-001", models.DeliveryFilters{Status: models.DeliveryDead})
+			if err != nil || len(dead) != 1 || dead[0].ID != "dlv-1" || dead[0].NextAttemptAt != nil {
+				t.Errorf("Expected dlv-1 on the dead-letter list, got %+v (%v)", dead, err)
+			}
+
+			log, err := store.GetDeliveries("user-001", models.DeliveryFilters{})
+			if err != nil || len(log) != 2 || log[0].ID != "dlv-2" || log[1].ID != "dlv-1" {
+				t.Errorf("Expected user-001's deliveries newest first, got %+v (%v)", log, err)
+			}
+			if log, _ := store.GetDeliveries("user-001", models.DeliveryFilters{WebhookID: "wh-2", Limit: 1}); len(log) != 1 || log[0].ID != "dlv-2" {
+				t.Errorf("Expected wh-2's deliveries, got %+v", log)
+			}
+			if _, err := store.GetDelivery("user-002", "dlv-1"); !errors.Is(err, ErrDeliveryNotFound) {
+				t.Errorf("Expected another user's delivery not to be found, got %v", err)
+			}
+
+			// Deleting a webhook deletes its deliveries
+			if err := store.DeleteWebhook("user-001", "wh-1"); err != nil {
+				t.Fatalf("DeleteWebhook failed: %v", err)
+			}
+			if err := store.DeleteWebhook("user-001", "wh-1"); !errors.Is(err, ErrWebhookNotFound) {
+				t.Errorf("Expected ErrWebhookNotFound deleting again, got %v", err)
+			}
+			if _, err := store.GetDelivery("user-001", "dlv-1"); !errors.Is(err, ErrDeliveryNotFound) {
+				t.Errorf("Expected the webhook's deliveries to be deleted, got %v", err)
+			}
+			if err := store.DeleteWebhook("user-001", "wh-3"); !errors.Is(err, ErrWebhookNotFound) {
+				t.Errorf("Expected another user's webhook not to be deleted, got %v", err)
+			}
+		})
+	}
+}
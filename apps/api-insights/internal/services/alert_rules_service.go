@@ -0,0 +1,322 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/engine"
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/models"
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// maxAlertRuleNameLength caps the name a user gives a rule
+	maxAlertRuleNameLength = 100
+
+	// maxAlertRules is how many rules a user can have at once
+	maxAlertRules = 50
+)
+
+var (
+	// ErrInvalidAlertRule wraps alert rules that cannot be saved
+	ErrInvalidAlertRule = errors.New("invalid alert rule")
+
+	// ErrAlertRuleNotFound is returned for a rule the user does not have
+	ErrAlertRuleNotFound = errors.New("alert rule not found")
+
+	// ErrTooManyAlertRules is returned when the user already has maxAlertRules rules
+	ErrTooManyAlertRules = errors.New("you have too many alert rules, delete one first")
+)
+
+// AlertRulesService manages the alert rules users set up and evaluates them
+// against the transactions and balances of their accounts
+type AlertRulesService struct {
+	rules        repository.AlertRuleStore
+	grants       repository.AccountGrantSource
+	transactions repository.TransactionSource
+	logger       *logrus.Logger
+	now          func() time.Time
+}
+
+// NewAlertRulesService creates a new alert rules service
+func NewAlertRulesService(rules repository.AlertRuleStore, grants repository.AccountGrantSource, transactions repository.TransactionSource, logger *logrus.Logger) *AlertRulesService {
+	return &AlertRulesService{
+		rules:        rules,
+		grants:       grants,
+		transactions: transactions,
+		logger:       logger,
+		now:          time.Now,
+	}
+}
+
+// List returns the user's rules, oldest first
+func (s *AlertRulesService) List(userID string) ([]*models.AlertRule, error) {
+	rules, err := s.rules.GetAlertRules(userID)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to retrieve alert rules")
+		return nil, err
+	}
+	if rules == nil {
+		rules = []*models.AlertRule{}
+	}
+	return rules, nil
+}
+
+// Get returns one of the user's rules
+func (s *AlertRulesService) Get(userID, ruleID string) (*models.AlertRule, error) {
+	rule, err := s.rules.GetAlertRule(userID, ruleID)
+	if errors.Is(err, repository.ErrAlertRuleNotFound) {
+		return nil, ErrAlertRuleNotFound
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to retrieve alert rule")
+		return nil, err
+	}
+	return rule, nil
+}
+
+// Create saves a new rule for the user. It only alerts about transactions
+// made from now on.
+func (s *AlertRulesService) Create(userID string, req *models.AlertRuleRequest) (*models.AlertRule, error) {
+	rule, err := s.validate(userID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.rules.GetAlertRules(userID)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to retrieve alert rules")
+		return nil, err
+	}
+	if len(existing) >= maxAlertRules {
+		return nil, ErrTooManyAlertRules
+	}
+
+	if rule.ID, err = newID("rule"); err != nil {
+		return nil, err
+	}
+	rule.UserID = userID
+	rule.CreatedAt = s.now().UTC().Truncate(time.Second)
+	rule.UpdatedAt = rule.CreatedAt
+	if err := s.rules.SaveAlertRule(rule); err != nil {
+		s.logger.WithError(err).Error("Failed to save alert rule")
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"userId": userID,
+		"ruleId": rule.ID,
+		"type":   rule.Type,
+	}).Info("Alert rule created")
+
+	return rule, nil
+}
+
+// Update replaces one of the user's rules. It keeps its ID and creation
+// time, so alerts it raised for the same records keep their state.
+func (s *AlertRulesService) Update(userID, ruleID string, req *models.AlertRuleRequest) (*models.AlertRule, error) {
+	existing, err := s.Get(userID, ruleID)
+	if err != nil {
+		return nil, err
+	}
+	rule, err := s.validate(userID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	rule.ID = existing.ID
+	rule.UserID = userID
+	rule.CreatedAt = existing.CreatedAt
+	rule.UpdatedAt = s.now().UTC().Truncate(time.Second)
+	if err := s.rules.SaveAlertRule(rule); err != nil {
+		s.logger.WithError(err).Error("Failed to save alert rule")
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"userId": userID,
+		"ruleId": rule.ID,
+	}).Info("Alert rule updated")
+
+	return rule, nil
+}
+
+// Delete deletes one of the user's rules, and with it the alerts it raised
+func (s *AlertRulesService) Delete(userID, ruleID string) error {
+	if err := s.rules.DeleteAlertRule(userID, ruleID); err != nil {
+		if errors.Is(err, repository.ErrAlertRuleNotFound) {
+			return ErrAlertRuleNotFound
+		}
+		s.logger.WithError(err).WithField("ruleId", ruleID).Error("Failed to delete alert rule")
+		return err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"userId": userID,
+		"ruleId": ruleID,
+	}).Info("Alert rule deleted")
+	return nil
+}
+
+// EvaluateRules returns the alerts the user's rules raise over the accounts
+// they can see, newest first
+func (s *AlertRulesService) EvaluateRules(userID string) ([]*models.Alert, error) {
+	rules, err := s.rules.GetAlertRules(userID)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to retrieve alert rules")
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	accountIDs, err := s.accountIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+	transactions, err := s.transactions.GetTransactionsByAccountIDs(accountIDs)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to retrieve transactions")
+		return nil, err
+	}
+	balances, err := s.transactions.GetBalancesByAccountIDs(accountIDs)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to retrieve balances")
+		return nil, err
+	}
+
+	input := &engine.AlertInput{Transactions: transactions, Balances: balances, Now: s.now()}
+	var alerts []*models.Alert
+	for _, rule := range rules {
+		alerts = append(alerts, engine.EvaluateAlertRule(rule, input)...)
+	}
+	sort.SliceStable(alerts, func(i, j int) bool {
+		return alerts[i].CreatedAt.After(alerts[j].CreatedAt)
+	})
+
+	s.logger.WithFields(logrus.Fields{
+		"userId":     userID,
+		"ruleCount":  len(rules),
+		"alertCount": len(alerts),
+	}).Debug("Evaluated alert rules for user")
+
+	return alerts, nil
+}
+
+// validate checks a rule request and returns the rule it describes. Fields
+// that do not apply to the rule's type are dropped.
+func (s *AlertRulesService) validate(userID string, req *models.AlertRuleRequest) (*models.AlertRule, error) {
+	rule := &models.AlertRule{
+		Name:      strings.TrimSpace(req.Name),
+		Type:      req.Type,
+		AccountID: strings.TrimSpace(req.AccountID),
+		Threshold: req.Threshold,
+		Priority:  req.Priority,
+		Enabled:   req.Enabled == nil || *req.Enabled,
+	}
+	if rule.Name == "" || len(rule.Name) > maxAlertRuleNameLength {
+		return nil, fmt.Errorf("%w: name must be between 1 and %d characters", ErrInvalidAlertRule, maxAlertRuleNameLength)
+	}
+	if rule.Threshold < 0 {
+		return nil, fmt.Errorf("%w: threshold cannot be negative", ErrInvalidAlertRule)
+	}
+
+	switch rule.Type {
+	case models.AlertRuleLargeTransaction:
+		if rule.Threshold == 0 {
+			return nil, fmt.Errorf("%w: threshold is required", ErrInvalidAlertRule)
+		}
+	case models.AlertRuleLowBalance:
+		if rule.AccountID == "" {
+			return nil, fmt.Errorf("%w: accountId is required", ErrInvalidAlertRule)
+		}
+	case models.AlertRuleMerchant:
+		if rule.Merchant = strings.TrimSpace(req.Merchant); rule.Merchant == "" {
+			return nil, fmt.Errorf("%w: merchant is required", ErrInvalidAlertRule)
+		}
+	case models.AlertRuleCategorySpend:
+		if rule.Category = strings.ToLower(strings.TrimSpace(req.Category)); rule.Category == "" {
+			return nil, fmt.Errorf("%w: category is required", ErrInvalidAlertRule)
+		}
+		if rule.Threshold == 0 {
+			return nil, fmt.Errorf("%w: threshold is required", ErrInvalidAlertRule)
+		}
+	default:
+		return nil, fmt.Errorf("%w: type must be %s, %s, %s or %s", ErrInvalidAlertRule, models.AlertRuleLargeTransaction,
+			models.AlertRuleLowBalance, models.AlertRuleMerchant, models.AlertRuleCategorySpend)
+	}
+
+	switch rule.Priority {
+	case "":
+		rule.Priority = models.AlertPriorityMedium
+	case models.AlertPriorityMedium, models.AlertPriorityHigh, models.AlertPriorityCritical:
+	default:
+		return nil, fmt.Errorf("%w: priority must be %s, %s or %s", ErrInvalidAlertRule,
+			models.AlertPriorityMedium, models.AlertPriorityHigh, models.AlertPriorityCritical)
+	}
+
+	if rule.AccountID != "" {
+		accountIDs, err := s.accountIDs(userID)
+		if err != nil {
+			return nil, err
+		}
+		found := false
+		for _, id := range accountIDs {
+			if id == rule.AccountID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: accountId must be one of your accounts or one shared with you", ErrInvalidAlertRule)
+		}
+	}
+	return rule, nil
+}
+
+// accountIDs returns the accounts the user owns, followed by those shared
+// with them
+func (s *AlertRulesService) accountIDs(userID string) ([]string, error) {
+	return visibleAccountIDs(s.transactions, s.grants, s.logger, userID)
+}
+
+// visibleAccountIDs returns the accounts the user owns, followed by those
+// shared with them
+func visibleAccountIDs(transactions repository.TransactionSource, grants repository.AccountGrantSource, logger *logrus.Logger, userID string) ([]string, error) {
+	accountIDs, err := transactions.GetAccountIDsByUserID(userID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to retrieve accounts")
+		return nil, err
+	}
+	sharedIDs, err := grants.GetSharedAccountIDs(userID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to retrieve shared accounts")
+		return nil, err
+	}
+	for _, accountID := range sharedIDs {
+		ownerID, err := transactions.GetAccountOwnerID(accountID)
+		if err != nil {
+			logger.WithError(err).Error("Failed to retrieve account owner")
+			return nil, err
+		}
+		if ownerID != "" && ownerID != userID {
+			accountIDs = append(accountIDs, accountID)
+		}
+	}
+	return accountIDs, nil
+}
+
+// newID generates a random identifier with the given prefix
+func newID(prefix string) (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + "-" + hex.EncodeToString(b), nil
+}
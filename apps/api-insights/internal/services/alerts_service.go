@@ -1,7 +1,9 @@
 package services
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/features"
 	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/models"
@@ -9,29 +11,180 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+var (
+	// ErrAlertsDisabled is returned while the alerts feature flag is off
+	ErrAlertsDisabled = errors.New("alerts feature is currently disabled")
+
+	// ErrAlertNotFound is returned for an alert that does not exist or is
+	// not one the user can see
+	ErrAlertNotFound = errors.New("alert not found")
+
+	// ErrInvalidAlertFilter wraps unknown priorities in alert filters
+	ErrInvalidAlertFilter = errors.New("invalid alert filter")
+
+	// ErrInvalidAlertUpdate wraps alert updates that cannot be applied
+	ErrInvalidAlertUpdate = errors.New("invalid alert update")
+)
+
 // AlertsService handles business logic for alerts
 type AlertsService struct {
-	repo   *repository.Repository
-	flags  *features.Flags
-	logger *logrus.Logger
+	repo           *repository.Repository
+	securityEvents repository.SecurityEventSource
+	grants         repository.AccountGrantSource
+	states         repository.AlertStateStore
+	rules          *AlertRulesService
+	flags          *features.Flags
+	logger         *logrus.Logger
+	now            func() time.Time
 }
 
 // NewAlertsService creates a new alerts service
-func NewAlertsService(repo *repository.Repository, flags *features.Flags, logger *logrus.Logger) *AlertsService {
+func NewAlertsService(repo *repository.Repository, securityEvents repository.SecurityEventSource, grants repository.AccountGrantSource, states repository.AlertStateStore, rules *AlertRulesService, flags *features.Flags, logger *logrus.Logger) *AlertsService {
 	return &AlertsService{
-		repo:   repo,
-		flags:  flags,
-		logger: logger,
+		repo:           repo,
+		securityEvents: securityEvents,
+		grants:         grants,
+		states:         states,
+		rules:          rules,
+		flags:          flags,
+		logger:         logger,
+		now:            time.Now,
 	}
 }
 
-// GetAlertsByUserID retrieves alerts for a user
+// GetAlertsByUserID retrieves the alerts for a user that match the filters.
+// Dismissed alerts and alerts snoozed until later are left out.
 // Returns an error if alerts are disabled via feature flag
-func (s *AlertsService) GetAlertsByUserID(userID string) ([]*models.Alert, error) {
+func (s *AlertsService) GetAlertsByUserID(userID string, filters models.AlertFilters) ([]*models.Alert, error) {
+	switch filters.Priority {
+	case "", models.AlertPriorityMedium, models.AlertPriorityHigh, models.AlertPriorityCritical:
+	default:
+		return nil, fmt.Errorf("%w: priority must be %s, %s or %s", ErrInvalidAlertFilter,
+			models.AlertPriorityMedium, models.AlertPriorityHigh, models.AlertPriorityCritical)
+	}
+
+	alerts, err := s.allAlerts(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	visible := make([]*models.Alert, 0, len(alerts))
+	for _, alert := range alerts {
+		if alert.Dismissed || alert.IsSnoozed(now) {
+			continue
+		}
+		if (filters.Unread && alert.Read) || (filters.Priority != "" && alert.Priority != filters.Priority) {
+			continue
+		}
+		visible = append(visible, alert)
+	}
+	return visible, nil
+}
+
+// CountUnread returns how many of the alerts GetAlertsByUserID lists are unread
+func (s *AlertsService) CountUnread(userID string) (int, error) {
+	alerts, err := s.GetAlertsByUserID(userID, models.AlertFilters{Unread: true})
+	if err != nil {
+		return 0, err
+	}
+	return len(alerts), nil
+}
+
+// UpdateAlert applies a user's changes to one of their alerts and returns it.
+// Snoozing until a time that has passed is refused.
+func (s *AlertsService) UpdateAlert(userID, alertID string, update *models.AlertUpdate) (*models.Alert, error) {
+	if update.Read == nil && update.Dismissed == nil && !update.SnoozedUntil.Set {
+		return nil, fmt.Errorf("%w: set read, dismissed or snoozedUntil", ErrInvalidAlertUpdate)
+	}
+	now := s.now()
+	if update.SnoozedUntil.Time != nil && !update.SnoozedUntil.Time.After(now) {
+		return nil, fmt.Errorf("%w: snoozedUntil must be in the future", ErrInvalidAlertUpdate)
+	}
+
+	alerts, err := s.allAlerts(userID)
+	if err != nil {
+		return nil, err
+	}
+	var alert *models.Alert
+	for _, candidate := range alerts {
+		if candidate.ID == alertID {
+			alert = candidate
+			break
+		}
+	}
+	if alert == nil {
+		return nil, ErrAlertNotFound
+	}
+
+	if update.Read != nil {
+		alert.Read = *update.Read
+	}
+	if update.Dismissed != nil {
+		alert.Dismissed = *update.Dismissed
+	}
+	if update.SnoozedUntil.Set {
+		alert.SnoozedUntil = nil
+		if update.SnoozedUntil.Time != nil {
+			until := update.SnoozedUntil.Time.UTC().Truncate(time.Second)
+			alert.SnoozedUntil = &until
+		}
+	}
+
+	if err := s.states.SaveAlertStates([]*models.AlertState{alertState(userID, alert, now)}); err != nil {
+		s.logger.WithError(err).Error("Failed to save alert state")
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"userId":  userID,
+		"alertId": alertID,
+	}).Info("Alert updated")
+
+	return alert, nil
+}
+
+// MarkAllRead marks every unread alert the user has not dismissed as read,
+// snoozed ones included, and returns how many were changed
+func (s *AlertsService) MarkAllRead(userID string) (int, error) {
+	alerts, err := s.allAlerts(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	now := s.now()
+	var states []*models.AlertState
+	for _, alert := range alerts {
+		if alert.Read || alert.Dismissed {
+			continue
+		}
+		alert.Read = true
+		states = append(states, alertState(userID, alert, now))
+	}
+	if len(states) == 0 {
+		return 0, nil
+	}
+
+	if err := s.states.SaveAlertStates(states); err != nil {
+		s.logger.WithError(err).Error("Failed to save alert states")
+		return 0, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"userId": userID,
+		"count":  len(states),
+	}).Info("Alerts marked read")
+
+	return len(states), nil
+}
+
+// allAlerts returns every alert of the user's, with the state they saved for
+// each applied
+func (s *AlertsService) allAlerts(userID string) ([]*models.Alert, error) {
 	// Check if alerts feature is enabled
 	if !s.flags.IsAlertsEnabled() {
 		s.logger.WithField("userId", userID).Warn("Alerts feature is disabled")
-		return nil, fmt.Errorf("alerts feature is currently disabled")
+		return nil, ErrAlertsDisabled
 	}
 
 	alerts, err := s.repo.GetAlertsByUserID(userID)
@@ -40,6 +193,56 @@ func (s *AlertsService) GetAlertsByUserID(userID string) ([]*models.Alert, error
 		return nil, err
 	}
 
+	// Alerts about accounts shared with the user follow their own. Security
+	// alerts stay with the account holder they concern.
+	accountIDs, err := s.grants.GetSharedAccountIDs(userID)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to retrieve shared accounts")
+		return nil, err
+	}
+	if len(accountIDs) > 0 {
+		shared, err := s.repo.GetAlertsByAccountIDs(accountIDs)
+		if err != nil {
+			s.logger.WithError(err).Error("Failed to retrieve shared alerts")
+			return nil, err
+		}
+		alerts = append(alerts, shared...)
+	}
+
+	// Then those the user's own alert rules raise
+	ruleAlerts, err := s.rules.EvaluateRules(userID)
+	if err != nil {
+		return nil, err
+	}
+	alerts = append(alerts, ruleAlerts...)
+
+	// Security events from api-accounts, such as lockouts, come first
+	events, err := s.securityEvents.GetSecurityEventsByUserID(userID)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to retrieve security events")
+		return nil, err
+	}
+	securityAlerts := make([]*models.Alert, 0, len(events)+len(alerts))
+	for _, event := range events {
+		securityAlerts = append(securityAlerts, securityAlert(event))
+	}
+	alerts = append(securityAlerts, alerts...)
+
+	// Alerts are shared with the repository, so the user's state is applied
+	// to copies
+	states, err := s.states.GetAlertStates(userID)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to retrieve alert states")
+		return nil, err
+	}
+	for i, alert := range alerts {
+		copied := *alert
+		if state, ok := states[alert.ID]; ok {
+			state.Apply(&copied)
+		}
+		alerts[i] = &copied
+	}
+
 	s.logger.WithFields(logrus.Fields{
 		"userId":      userID,
 		"alertCount":  len(alerts),
@@ -52,3 +255,32 @@ func (s *AlertsService) GetAlertsByUserID(userID string) ([]*models.Alert, error
 func (s *AlertsService) IsAlertsEnabled() bool {
 	return s.flags.IsAlertsEnabled()
 }
+
+// alertState records the user's state of an alert at now
+func alertState(userID string, alert *models.Alert, now time.Time) *models.AlertState {
+	return &models.AlertState{
+		UserID:       userID,
+		AlertID:      alert.ID,
+		Read:         alert.Read,
+		Dismissed:    alert.Dismissed,
+		SnoozedUntil: alert.SnoozedUntil,
+		UpdatedAt:    now,
+	}
+}
+
+// securityAlert turns a security event into a critical alert
+func securityAlert(event *models.SecurityEvent) *models.Alert {
+	title := "Security alert"
+	if event.Type == models.SecurityEventAccountLocked {
+		title = "Sign-in temporarily locked"
+	}
+	return &models.Alert{
+		ID:        "alert-" + event.ID,
+		UserID:    event.UserID,
+		Type:      "security",
+		Title:     title,
+		Message:   event.Message,
+		Priority:  models.AlertPriorityCritical,
+		CreatedAt: event.CreatedAt,
+	}
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/engine"
 	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/features"
 	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/models"
 	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/repository"
@@ -9,73 +10,146 @@ import (
 
 // InsightsService handles business logic for insights
 type InsightsService struct {
-	repo   *repository.Repository
-	flags  *features.Flags
-	logger *logrus.Logger
+	repo         *repository.Repository
+	grants       repository.AccountGrantSource
+	transactions repository.TransactionSource
+	engine       *engine.Engine
+	flags        *features.Flags
+	logger       *logrus.Logger
 }
 
 // NewInsightsService creates a new insights service
-func NewInsightsService(repo *repository.Repository, flags *features.Flags, logger *logrus.Logger) *InsightsService {
+func NewInsightsService(repo *repository.Repository, grants repository.AccountGrantSource, transactions repository.TransactionSource, flags *features.Flags, logger *logrus.Logger) *InsightsService {
 	return &InsightsService{
-		repo:   repo,
-		flags:  flags,
-		logger: logger,
+		repo:         repo,
+		grants:       grants,
+		transactions: transactions,
+		engine:       engine.NewDefault(),
+		flags:        flags,
+		logger:       logger,
 	}
 }
 
-// GetInsightsByUserID retrieves insights for a user, applying V2 modifications if enabled
+// GetInsightsByUserID retrieves insights for a user, followed by the insights
+// about accounts shared with them. With the insightsV2 flag they are computed
+// from transactions, otherwise they come from the static data set.
 func (s *InsightsService) GetInsightsByUserID(userID string) ([]*models.Insight, error) {
+	if s.flags.IsInsightsV2Enabled() {
+		return s.computeInsights(userID)
+	}
+
 	insights, err := s.repo.GetInsightsByUserID(userID)
 	if err != nil {
 		s.logger.WithError(err).Error("Failed to retrieve insights")
 		return nil, err
 	}
 
-	// Apply V2 algorithm modifications if flag is enabled
-	if s.flags.IsInsightsV2Enabled() {
-		insights = s.applyV2Algorithm(insights)
-		s.logger.WithField("userId", userID).Debug("Applied V2 insights algorithm")
+	accountIDs, err := s.grants.GetSharedAccountIDs(userID)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to retrieve shared accounts")
+		return nil, err
+	}
+	if len(accountIDs) > 0 {
+		shared, err := s.repo.GetInsightsByAccountIDs(accountIDs)
+		if err != nil {
+			s.logger.WithError(err).Error("Failed to retrieve shared insights")
+			return nil, err
+		}
+		insights = append(insights, shared...)
 	}
 
 	return insights, nil
 }
 
-// GetInsightByID retrieves a specific insight by ID, applying V2 modifications if enabled
-func (s *InsightsService) GetInsightByID(insightID string) (*models.Insight, error) {
-	insight, err := s.repo.GetInsightByID(insightID)
+// GetInsightByID retrieves a specific insight by ID on behalf of userID.
+// Computed insights are looked up among the user's own, so another user's
+// is not found rather than refused.
+func (s *InsightsService) GetInsightByID(userID, insightID string) (*models.Insight, error) {
+	if !s.flags.IsInsightsV2Enabled() {
+		insight, err := s.repo.GetInsightByID(insightID)
+		if err != nil {
+			s.logger.WithError(err).Error("Failed to retrieve insight")
+			return nil, err
+		}
+		return insight, nil
+	}
+
+	insights, err := s.computeInsights(userID)
 	if err != nil {
-		s.logger.WithError(err).Error("Failed to retrieve insight")
 		return nil, err
 	}
-
-	// Apply V2 algorithm modifications if flag is enabled
-	if s.flags.IsInsightsV2Enabled() {
-		insight = s.applyV2ToSingleInsight(insight)
-		s.logger.WithField("insightId", insightID).Debug("Applied V2 insights algorithm")
+	for _, insight := range insights {
+		if insight.ID == insightID {
+			return insight, nil
+		}
 	}
-
-	return insight, nil
+	return nil, repository.ErrInsightNotFound
 }
 
-// applyV2Algorithm applies the V2 insights calculation algorithm
-// In this demo, we append "(V2)" to titles to show the new algorithm is active
-func (s *InsightsService) applyV2Algorithm(insights []*models.Insight) []*models.Insight {
-	modifiedInsights := make([]*models.Insight, len(insights))
+// computeInsights runs the insights engine over the transactions on the
+// user's accounts, then over each account shared with them on its own
+func (s *InsightsService) computeInsights(userID string) ([]*models.Insight, error) {
+	accountIDs, err := s.transactions.GetAccountIDsByUserID(userID)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to retrieve accounts")
+		return nil, err
+	}
+	transactions, err := s.transactions.GetTransactionsByAccountIDs(accountIDs)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to retrieve transactions")
+		return nil, err
+	}
+	insights := s.engine.Evaluate(userID, "", transactions)
 
-	for i, insight := range insights {
-		// Create a copy to avoid modifying the original
-		modified := *insight
-		modified.Title = insight.Title + " (V2)"
-		modifiedInsights[i] = &modified
+	sharedIDs, err := s.grants.GetSharedAccountIDs(userID)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to retrieve shared accounts")
+		return nil, err
+	}
+	for _, accountID := range sharedIDs {
+		ownerID, err := s.transactions.GetAccountOwnerID(accountID)
+		if err != nil {
+			s.logger.WithError(err).Error("Failed to retrieve account owner")
+			return nil, err
+		}
+		if ownerID == "" || ownerID == userID {
+			continue
+		}
+		transactions, err := s.transactions.GetTransactionsByAccountIDs([]string{accountID})
+		if err != nil {
+			s.logger.WithError(err).Error("Failed to retrieve shared transactions")
+			return nil, err
+		}
+		insights = append(insights, s.engine.Evaluate(ownerID, accountID, transactions)...)
 	}
 
-	return modifiedInsights
+	s.logger.WithFields(logrus.Fields{
+		"userId":       userID,
+		"insightCount": len(insights),
+	}).Debug("Computed insights for user")
+
+	return insights, nil
 }
 
-// applyV2ToSingleInsight applies V2 algorithm to a single insight
-func (s *InsightsService) applyV2ToSingleInsight(insight *models.Insight) *models.Insight {
-	// Create a copy to avoid modifying the original
-	modified := *insight
-	modified.Title = insight.Title + " (V2)"
-	return &modified
+// HasAccess reports whether the user may see an insight: it is theirs, or it
+// is about an account shared with them
+func (s *InsightsService) HasAccess(insight *models.Insight, userID string) (bool, error) {
+	if insight.UserID == userID {
+		return true, nil
+	}
+	if insight.AccountID == "" {
+		return false, nil
+	}
+
+	accountIDs, err := s.grants.GetSharedAccountIDs(userID)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to retrieve shared accounts")
+		return false, err
+	}
+	for _, id := range accountIDs {
+		if id == insight.AccountID {
+			return true, nil
+		}
+	}
+	return false, nil
 }
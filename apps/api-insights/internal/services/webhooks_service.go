@@ -0,0 +1,425 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/models"
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/repository"
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/webhook"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// maxWebhooks is how many webhooks a user can have at once
+	maxWebhooks = 10
+
+	// maxWebhookURLLength caps the URL of a webhook
+	maxWebhookURLLength = 2048
+
+	// maxDeliveries is how many deliveries the delivery log returns
+	maxDeliveries = 100
+)
+
+var (
+	// ErrInvalidWebhook wraps webhooks that cannot be saved
+	ErrInvalidWebhook = errors.New("invalid webhook")
+
+	// ErrWebhookNotFound is returned for a webhook the user does not have
+	ErrWebhookNotFound = errors.New("webhook not found")
+
+	// ErrTooManyWebhooks is returned when the user already has maxWebhooks webhooks
+	ErrTooManyWebhooks = errors.New("you have too many webhooks, delete one first")
+
+	// ErrDeliveryNotFound is returned for a delivery the user does not have
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+
+	// ErrDeliveryPending is returned when redelivering a delivery still queued
+	ErrDeliveryPending = errors.New("delivery is still queued")
+
+	// ErrInvalidDeliveryFilter wraps delivery log filters that are not valid
+	ErrInvalidDeliveryFilter = errors.New("invalid delivery filter")
+)
+
+// WebhooksService manages the webhooks users subscribe and queues the events
+// published for them
+type WebhooksService struct {
+	store        repository.WebhookStore
+	grants       repository.AccountGrantSource
+	transactions repository.TransactionSource
+	allowPrivate bool
+	logger       *logrus.Logger
+	now          func() time.Time
+}
+
+// NewWebhooksService creates a new webhooks service. Webhooks must be https
+// URLs on public hosts unless allowPrivate is set, which also allows plain
+// http to this machine and is meant for development.
+func NewWebhooksService(store repository.WebhookStore, grants repository.AccountGrantSource, transactions repository.TransactionSource, allowPrivate bool, logger *logrus.Logger) *WebhooksService {
+	return &WebhooksService{
+		store:        store,
+		grants:       grants,
+		transactions: transactions,
+		allowPrivate: allowPrivate,
+		logger:       logger,
+		now:          time.Now,
+	}
+}
+
+// List returns the user's webhooks, oldest first, without their secrets
+func (s *WebhooksService) List(userID string) ([]*models.Webhook, error) {
+	webhooks, err := s.store.GetWebhooks(userID)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to retrieve webhooks")
+		return nil, err
+	}
+	if webhooks == nil {
+		webhooks = []*models.Webhook{}
+	}
+	for _, webhook := range webhooks {
+		webhook.Secret = ""
+	}
+	return webhooks, nil
+}
+
+// Get returns one of the user's webhooks, without its secret
+func (s *WebhooksService) Get(userID, webhookID string) (*models.Webhook, error) {
+	webhook, err := s.get(userID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	webhook.Secret = ""
+	return webhook, nil
+}
+
+// Create saves a new webhook for the user. It is returned with its signing
+// secret, which is not shown again.
+func (s *WebhooksService) Create(userID string, req *models.WebhookRequest) (*models.Webhook, error) {
+	webhook, err := validateWebhook(req, s.allowPrivate)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.store.GetWebhooks(userID)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to retrieve webhooks")
+		return nil, err
+	}
+	if len(existing) >= maxWebhooks {
+		return nil, ErrTooManyWebhooks
+	}
+
+	if webhook.ID, err = newID("wh"); err != nil {
+		return nil, err
+	}
+	if webhook.Secret, err = newSecret(); err != nil {
+		return nil, err
+	}
+	webhook.UserID = userID
+	webhook.CreatedAt = s.now().UTC().Truncate(time.Second)
+	webhook.UpdatedAt = webhook.CreatedAt
+	if err := s.store.SaveWebhook(webhook); err != nil {
+		s.logger.WithError(err).Error("Failed to save webhook")
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"userId":    userID,
+		"webhookId": webhook.ID,
+		"events":    webhook.Events,
+	}).Info("Webhook created")
+
+	return webhook, nil
+}
+
+// Update replaces one of the user's webhooks. It keeps its ID, secret and
+// deliveries.
+func (s *WebhooksService) Update(userID, webhookID string, req *models.WebhookRequest) (*models.Webhook, error) {
+	existing, err := s.get(userID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	webhook, err := validateWebhook(req, s.allowPrivate)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook.ID = existing.ID
+	webhook.UserID = userID
+	webhook.Secret = existing.Secret
+	webhook.CreatedAt = existing.CreatedAt
+	webhook.UpdatedAt = s.now().UTC().Truncate(time.Second)
+	if err := s.store.SaveWebhook(webhook); err != nil {
+		s.logger.WithError(err).Error("Failed to save webhook")
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"userId":    userID,
+		"webhookId": webhook.ID,
+	}).Info("Webhook updated")
+
+	webhook.Secret = ""
+	return webhook, nil
+}
+
+// Delete deletes one of the user's webhooks, and with it its deliveries,
+// queued ones included
+func (s *WebhooksService) Delete(userID, webhookID string) error {
+	if err := s.store.DeleteWebhook(userID, webhookID); err != nil {
+		if errors.Is(err, repository.ErrWebhookNotFound) {
+			return ErrWebhookNotFound
+		}
+		s.logger.WithError(err).WithField("webhookId", webhookID).Error("Failed to delete webhook")
+		return err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"userId":    userID,
+		"webhookId": webhookID,
+	}).Info("Webhook deleted")
+	return nil
+}
+
+// ListDeliveries returns the latest deliveries to one of the user's
+// webhooks, newest first, optionally only those with status
+func (s *WebhooksService) ListDeliveries(userID, webhookID, status string) ([]*models.WebhookDelivery, error) {
+	switch status {
+	case "", models.DeliveryPending, models.DeliverySucceeded, models.DeliveryDead:
+	default:
+		return nil, fmt.Errorf("%w: status must be %s, %s or %s", ErrInvalidDeliveryFilter,
+			models.DeliveryPending, models.DeliverySucceeded, models.DeliveryDead)
+	}
+	if _, err := s.get(userID, webhookID); err != nil {
+		return nil, err
+	}
+	return s.deliveries(userID, models.DeliveryFilters{WebhookID: webhookID, Status: status, Limit: maxDeliveries})
+}
+
+// ListDeadLetters returns the deliveries to any of the user's webhooks that
+// failed every attempt, newest first
+func (s *WebhooksService) ListDeadLetters(userID string) ([]*models.WebhookDelivery, error) {
+	return s.deliveries(userID, models.DeliveryFilters{Status: models.DeliveryDead, Limit: maxDeliveries})
+}
+
+// Redeliver queues a delivery that is done, such as one on the dead-letter
+// list, to be attempted again now with a fresh set of attempts
+func (s *WebhooksService) Redeliver(userID, webhookID, deliveryID string) (*models.WebhookDelivery, error) {
+	delivery, err := s.store.GetDelivery(userID, deliveryID)
+	if errors.Is(err, repository.ErrDeliveryNotFound) || (err == nil && delivery.WebhookID != webhookID) {
+		return nil, ErrDeliveryNotFound
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to retrieve webhook delivery")
+		return nil, err
+	}
+	if delivery.Status == models.DeliveryPending {
+		return nil, ErrDeliveryPending
+	}
+
+	now := s.now().UTC().Truncate(time.Second)
+	delivery.Status = models.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = &now
+	if err := s.store.SaveDelivery(delivery); err != nil {
+		s.logger.WithError(err).Error("Failed to save webhook delivery")
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"userId":     userID,
+		"webhookId":  webhookID,
+		"deliveryId": deliveryID,
+	}).Info("Webhook delivery queued again")
+
+	return delivery, nil
+}
+
+// GetSubscribedUserIDs returns the users with an enabled webhook
+func (s *WebhooksService) GetSubscribedUserIDs() ([]string, error) {
+	return s.store.GetSubscribedUserIDs()
+}
+
+// GetTransactions returns the transactions of the accounts the user owns or
+// that are shared with them
+func (s *WebhooksService) GetTransactions(userID string) ([]*models.Transaction, error) {
+	accountIDs, err := visibleAccountIDs(s.transactions, s.grants, s.logger, userID)
+	if err != nil {
+		return nil, err
+	}
+	transactions, err := s.transactions.GetTransactionsByAccountIDs(accountIDs)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to retrieve transactions")
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// Publish queues an event for each of the user's enabled webhooks that
+// subscribed to its type. The same payload is sent on every attempt.
+func (s *WebhooksService) Publish(userID, eventType string, data interface{}) error {
+	webhooks, err := s.store.GetWebhooks(userID)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to retrieve webhooks")
+		return err
+	}
+
+	now := s.now().UTC().Truncate(time.Second)
+	event := &models.WebhookEvent{Type: eventType, UserID: userID, CreatedAt: now, Data: data}
+	var deliveries []*models.WebhookDelivery
+	for _, webhook := range webhooks {
+		if !webhook.Wants(eventType) {
+			continue
+		}
+		if event.ID == "" {
+			if event.ID, err = newID("evt"); err != nil {
+				return err
+			}
+		}
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		id, err := newID("dlv")
+		if err != nil {
+			return err
+		}
+		deliveries = append(deliveries, &models.WebhookDelivery{
+			ID:            id,
+			WebhookID:     webhook.ID,
+			UserID:        userID,
+			EventID:       event.ID,
+			EventType:     eventType,
+			Payload:       payload,
+			Status:        models.DeliveryPending,
+			NextAttemptAt: &now,
+			CreatedAt:     now,
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	if err := s.store.AddDeliveries(deliveries); err != nil {
+		s.logger.WithError(err).Error("Failed to queue webhook deliveries")
+		return err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"userId":    userID,
+		"eventId":   event.ID,
+		"eventType": eventType,
+		"webhooks":  len(deliveries),
+	}).Debug("Webhook event queued")
+	return nil
+}
+
+// get returns one of the user's webhooks, secret included
+func (s *WebhooksService) get(userID, webhookID string) (*models.Webhook, error) {
+	webhook, err := s.store.GetWebhook(userID, webhookID)
+	if errors.Is(err, repository.ErrWebhookNotFound) {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to retrieve webhook")
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// deliveries returns the user's deliveries that match filters
+func (s *WebhooksService) deliveries(userID string, filters models.DeliveryFilters) ([]*models.WebhookDelivery, error) {
+	deliveries, err := s.store.GetDeliveries(userID, filters)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to retrieve webhook deliveries")
+		return nil, err
+	}
+	if deliveries == nil {
+		deliveries = []*models.WebhookDelivery{}
+	}
+	return deliveries, nil
+}
+
+// validateWebhook checks a webhook request and returns the webhook it
+// describes. Hosts given as a private address are refused here; the
+// dispatcher checks the addresses host names resolve to when it connects.
+func validateWebhook(req *models.WebhookRequest, allowPrivate bool) (*models.Webhook, error) {
+	webhook := &models.Webhook{
+		URL:     strings.TrimSpace(req.URL),
+		Enabled: req.Enabled == nil || *req.Enabled,
+	}
+
+	u, err := url.Parse(webhook.URL)
+	if err != nil || u.Host == "" || len(webhook.URL) > maxWebhookURLLength {
+		return nil, fmt.Errorf("%w: url must be an absolute https URL of at most %d characters", ErrInvalidWebhook, maxWebhookURLLength)
+	}
+	switch u.Scheme {
+	case "https":
+	case "http":
+		// Plain HTTP would expose payloads in transit, except to this machine
+		if !allowPrivate || !isLoopback(u.Hostname()) {
+			return nil, fmt.Errorf("%w: url must use https", ErrInvalidWebhook)
+		}
+	default:
+		return nil, fmt.Errorf("%w: url must use https", ErrInvalidWebhook)
+	}
+	if u.User != nil {
+		return nil, fmt.Errorf("%w: url must not contain credentials", ErrInvalidWebhook)
+	}
+	if !allowPrivate && isPrivateHost(u.Hostname()) {
+		return nil, fmt.Errorf("%w: url must be on a public host", ErrInvalidWebhook)
+	}
+
+	known := make(map[string]bool, len(models.WebhookEventTypes))
+	for _, eventType := range models.WebhookEventTypes {
+		known[eventType] = true
+	}
+	seen := make(map[string]bool)
+	for _, eventType := range req.Events {
+		if !known[eventType] {
+			return nil, fmt.Errorf("%w: events must be among %s", ErrInvalidWebhook, strings.Join(models.WebhookEventTypes, ", "))
+		}
+		if !seen[eventType] {
+			seen[eventType] = true
+			webhook.Events = append(webhook.Events, eventType)
+		}
+	}
+	if len(webhook.Events) == 0 {
+		return nil, fmt.Errorf("%w: events must list at least one event type", ErrInvalidWebhook)
+	}
+	return webhook, nil
+}
+
+// isLoopback reports whether host is this machine
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// isPrivateHost reports whether host is this machine or an address
+// deliveries may not be sent to
+func isPrivateHost(host string) bool {
+	if isLoopback(host) {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && !webhook.PublicIP(ip)
+}
+
+// newSecret generates a webhook signing secret
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,188 @@
+// Package stream pushes new alerts to the clients of each user as they
+// appear, over Server-Sent Events or WebSocket
+package stream
+
+import (
+	"sync"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/models"
+)
+
+const (
+	// subscriberBuffer is how many events a stream may fall behind by before
+	// it is dropped. The client reconnects and resumes from its last event.
+	subscriberBuffer = 32
+
+	// historySize is how many recent events are kept per user, so a client
+	// that reconnects with the ID of the last event it saw gets those after it
+	historySize = 100
+)
+
+// Event is an alert pushed to a user's streams. IDs increase across users.
+type Event struct {
+	ID    uint64
+	Alert *models.Alert
+}
+
+// Hub fans the events published for a user out to each of their streams
+type Hub struct {
+	users   map[string]*userEvents
+	firstID uint64 // ID of the first event of this process
+	nextID  uint64
+	closed  bool
+	mu      sync.Mutex
+}
+
+// userEvents are the open streams and recent events of one user
+type userEvents struct {
+	subscribers map[*Subscription]struct{}
+	recent      []Event // oldest first
+	evicted     uint64  // ID of the newest event dropped from recent
+}
+
+// NewHub creates a hub. Event IDs start from the current time in
+// milliseconds, so those of an earlier process are told apart and not
+// mistaken for recent ones.
+func NewHub() *Hub {
+	first := uint64(time.Now().UnixMilli())
+	return &Hub{
+		users:   make(map[string]*userEvents),
+		firstID: first,
+		nextID:  first,
+	}
+}
+
+// Subscription is one stream of a user's events
+type Subscription struct {
+	hub     *Hub
+	userID  string
+	events  chan Event
+	dropped chan struct{}
+	once    sync.Once
+}
+
+// Events delivers the events published after the subscription was made
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Dropped is closed when the subscription fell too far behind and the hub
+// stopped delivering to it, or when it was closed
+func (s *Subscription) Dropped() <-chan struct{} {
+	return s.dropped
+}
+
+// Close ends the subscription
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
+
+// Subscribe opens a stream of the user's events. lastEventID is the ID of the
+// last event the client saw, or 0 for a new client. Events after it that are
+// still kept are returned to be sent first. resumed is false when events
+// after it may have been missed, because they are no longer kept or the ID is
+// from an earlier process, so the client should list its alerts again.
+func (h *Hub) Subscribe(userID string, lastEventID uint64) (sub *Subscription, replay []Event, resumed bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	user := h.user(userID)
+	sub = &Subscription{
+		hub:     h,
+		userID:  userID,
+		events:  make(chan Event, subscriberBuffer),
+		dropped: make(chan struct{}),
+	}
+	user.subscribers[sub] = struct{}{}
+	if h.closed {
+		h.remove(sub)
+	}
+
+	if lastEventID == 0 {
+		return sub, nil, true
+	}
+	if lastEventID < h.firstID || lastEventID >= h.nextID || lastEventID < user.evicted {
+		return sub, nil, false
+	}
+	for _, event := range user.recent {
+		if event.ID > lastEventID {
+			replay = append(replay, event)
+		}
+	}
+	return sub, replay, true
+}
+
+// Publish sends an alert to each of the user's streams. It does not wait for
+// slow streams: one whose buffer is full is dropped instead.
+func (h *Hub) Publish(userID string, alert *models.Alert) Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	event := Event{ID: h.nextID, Alert: alert}
+	h.nextID++
+
+	user := h.user(userID)
+	user.recent = append(user.recent, event)
+	if len(user.recent) > historySize {
+		user.evicted = user.recent[0].ID
+		user.recent = user.recent[1:]
+	}
+
+	for sub := range user.subscribers {
+		select {
+		case sub.events <- event:
+		default:
+			h.remove(sub)
+		}
+	}
+	return event
+}
+
+// Close drops every subscription and any made later, so streams end when
+// the server shuts down
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for _, user := range h.users {
+		for sub := range user.subscribers {
+			h.remove(sub)
+		}
+	}
+}
+
+// UserIDs returns the users with open streams
+func (h *Hub) UserIDs() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var userIDs []string
+	for userID, user := range h.users {
+		if len(user.subscribers) > 0 {
+			userIDs = append(userIDs, userID)
+		}
+	}
+	return userIDs
+}
+
+// user returns the user's events, creating them if needed. h.mu must be held.
+func (h *Hub) user(userID string) *userEvents {
+	user, ok := h.users[userID]
+	if !ok {
+		user = &userEvents{subscribers: make(map[*Subscription]struct{})}
+		h.users[userID] = user
+	}
+	return user
+}
+
+// remove stops delivering to a subscription. h.mu must be held.
+func (h *Hub) remove(sub *Subscription) {
+	sub.once.Do(func() {
+		delete(h.users[sub.userID].subscribers, sub)
+		close(sub.dropped)
+	})
+}
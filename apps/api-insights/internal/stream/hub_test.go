@@ -0,0 +1,145 @@
+package stream
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/models"
+)
+
+func TestHubFanOut(t *testing.T) {
+	hub := NewHub()
+	first, _, _ := hub.Subscribe("user-001", 0)
+	second, _, _ := hub.Subscribe("user-001", 0)
+	other, _, _ := hub.Subscribe("user-002", 0)
+
+	event := hub.Publish("user-001", &models.Alert{ID: "alert-001"})
+
+	for i, sub := range []*Subscription{first, second} {
+		select {
+		case got := <-sub.Events():
+			if got.ID != event.ID || got.Alert.ID != "alert-001" {
+				t.Errorf("Stream %d: expected event %d for alert-001, got %+v", i, event.ID, got)
+			}
+		default:
+			t.Errorf("Stream %d: expected the event to be delivered", i)
+		}
+	}
+	select {
+	case got := <-other.Events():
+		t.Errorf("Expected another user's stream to get nothing, got %+v", got)
+	default:
+	}
+
+	first.Close()
+	if next := hub.Publish("user-001", &models.Alert{ID: "alert-002"}); next.ID <= event.ID {
+		t.Errorf("Expected event IDs to increase, got %d after %d", next.ID, event.ID)
+	}
+	if got := <-second.Events(); got.Alert.ID != "alert-002" {
+		t.Errorf("Expected alert-002 on the open stream, got %+v", got)
+	}
+}
+
+func TestHubDropsSlowSubscribers(t *testing.T) {
+	hub := NewHub()
+	slow, _, _ := hub.Subscribe("user-001", 0)
+
+	for i := 0; i < subscriberBuffer; i++ {
+		hub.Publish("user-001", &models.Alert{ID: fmt.Sprintf("alert-%03d", i)})
+	}
+	select {
+	case <-slow.Dropped():
+		t.Fatal("Expected a full buffer to be kept")
+	default:
+	}
+
+	last := hub.Publish("user-001", &models.Alert{ID: "alert-overflow"})
+	select {
+	case <-slow.Dropped():
+	default:
+		t.Fatal("Expected the stream to be dropped once its buffer overflowed")
+	}
+	if ids := hub.UserIDs(); len(ids) != 0 {
+		t.Errorf("Expected no users with open streams, got %v", ids)
+	}
+
+	// The dropped client resumes from the last event it read
+	sub, replay, resumed := hub.Subscribe("user-001", last.ID-1)
+	defer sub.Close()
+	if !resumed || len(replay) != 1 || replay[0].Alert.ID != "alert-overflow" {
+		t.Errorf("Expected the missed event replayed, got %+v (resumed %v)", replay, resumed)
+	}
+}
+
+func TestHubResume(t *testing.T) {
+	hub := NewHub()
+	var events []Event
+	for i := 0; i < 3; i++ {
+		events = append(events, hub.Publish("user-001", &models.Alert{ID: fmt.Sprintf("alert-%03d", i)}))
+	}
+	hub.Publish("user-002", &models.Alert{ID: "alert-other"})
+
+	tests := []struct {
+		name        string
+		lastEventID uint64
+		wantReplay  int
+		wantResumed bool
+	}{
+		{"new client", 0, 0, true},
+		{"missed two", events[0].ID, 2, true},
+		{"up to date", events[2].ID, 0, true},
+		{"earlier process", events[0].ID - 1, 0, false},
+		{"future event", events[2].ID + 100, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub, replay, resumed := hub.Subscribe("user-001", tt.lastEventID)
+			defer sub.Close()
+			if len(replay) != tt.wantReplay || resumed != tt.wantResumed {
+				t.Errorf("Expected %d events replayed and resumed %v, got %d and %v", tt.wantReplay, tt.wantResumed, len(replay), resumed)
+			}
+			for _, event := range replay {
+				if event.Alert.ID == "alert-other" {
+					t.Errorf("Expected only the user's own events, got %+v", event)
+				}
+			}
+		})
+	}
+}
+
+func TestHubResumeAfterEviction(t *testing.T) {
+	hub := NewHub()
+	first := hub.Publish("user-001", &models.Alert{ID: "alert-first"})
+	for i := 0; i < historySize; i++ {
+		hub.Publish("user-001", &models.Alert{ID: fmt.Sprintf("alert-%03d", i)})
+	}
+
+	// Every event after the first is still kept
+	sub, replay, resumed := hub.Subscribe("user-001", first.ID)
+	sub.Close()
+	if !resumed || len(replay) != historySize {
+		t.Errorf("Expected %d events replayed, got %d (resumed %v)", historySize, len(replay), resumed)
+	}
+
+	hub.Publish("user-001", &models.Alert{ID: "alert-last"})
+	sub, replay, resumed = hub.Subscribe("user-001", first.ID)
+	defer sub.Close()
+	if resumed || replay != nil {
+		t.Errorf("Expected a reset once events after the last one were evicted, got %d replayed", len(replay))
+	}
+}
+
+func TestHubClose(t *testing.T) {
+	hub := NewHub()
+	before, _, _ := hub.Subscribe("user-001", 0)
+	hub.Close()
+	after, _, _ := hub.Subscribe("user-001", 0)
+
+	for name, sub := range map[string]*Subscription{"before": before, "after": after} {
+		select {
+		case <-sub.Dropped():
+		default:
+			t.Errorf("Expected the stream opened %s closing to be dropped", name)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package stream
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// AlertLister lists the alerts a user sees. services.AlertsService satisfies it.
+type AlertLister interface {
+	GetAlertsByUserID(userID string, filters models.AlertFilters) ([]*models.Alert, error)
+}
+
+// Watcher finds new alerts for the users with open streams and publishes them
+// to the hub. Alerts are derived when they are listed rather than stored, so
+// it lists them periodically and publishes those it has not seen listed
+// before. An alert that comes back, such as when a snooze ends, is new again.
+type Watcher struct {
+	hub      *Hub
+	alerts   AlertLister
+	interval time.Duration
+	logger   *logrus.Logger
+
+	seen map[string]map[string]bool // user ID to the IDs of the alerts last listed
+	mu   sync.Mutex
+}
+
+// NewWatcher creates a watcher listing alerts every interval
+func NewWatcher(hub *Hub, alerts AlertLister, interval time.Duration, logger *logrus.Logger) *Watcher {
+	return &Watcher{
+		hub:      hub,
+		alerts:   alerts,
+		interval: interval,
+		logger:   logger,
+		seen:     make(map[string]map[string]bool),
+	}
+}
+
+// Track starts watching a user when their first stream opens. The alerts
+// listed now are the ones the client already has. A user watched before
+// keeps what was seen then, so alerts that appeared while they were away are
+// published on the next check and reach a client resuming its stream.
+func (w *Watcher) Track(userID string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, ok := w.seen[userID]; ok {
+		return nil
+	}
+	alerts, err := w.alerts.GetAlertsByUserID(userID, models.AlertFilters{})
+	if err != nil {
+		return err
+	}
+	w.seen[userID] = alertIDs(alerts)
+	return nil
+}
+
+// Run checks for new alerts every interval until ctx is done
+func (w *Watcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.Check()
+		}
+	}
+}
+
+// Check publishes the new alerts of every user with open streams
+func (w *Watcher) Check() {
+	for _, userID := range w.hub.UserIDs() {
+		if err := w.check(userID); err != nil {
+			w.logger.WithError(err).WithField("userId", userID).Debug("Failed to check for new alerts")
+		}
+	}
+}
+
+// check publishes the user's alerts not listed last time, oldest first
+func (w *Watcher) check(userID string) error {
+	alerts, err := w.alerts.GetAlertsByUserID(userID, models.AlertFilters{})
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	seen, tracked := w.seen[userID]
+	w.seen[userID] = alertIDs(alerts)
+	if !tracked {
+		return nil
+	}
+
+	var fresh []*models.Alert
+	for _, alert := range alerts {
+		if !seen[alert.ID] {
+			fresh = append(fresh, alert)
+		}
+	}
+	sort.SliceStable(fresh, func(i, j int) bool {
+		return fresh[i].CreatedAt.Before(fresh[j].CreatedAt)
+	})
+	for _, alert := range fresh {
+		w.hub.Publish(userID, alert)
+	}
+	if len(fresh) > 0 {
+		w.logger.WithFields(logrus.Fields{
+			"userId": userID,
+			"count":  len(fresh),
+		}).Debug("Published new alerts")
+	}
+	return nil
+}
+
+// alertIDs returns the set of the alerts' IDs
+func alertIDs(alerts []*models.Alert) map[string]bool {
+	ids := make(map[string]bool, len(alerts))
+	for _, alert := range alerts {
+		ids[alert.ID] = true
+	}
+	return ids
+}
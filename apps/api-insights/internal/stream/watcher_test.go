@@ -0,0 +1,114 @@
+package stream
+
+import (
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeAlerts is an AlertLister whose alerts tests change
+type fakeAlerts struct {
+	alerts map[string][]*models.Alert
+	mu     sync.Mutex
+}
+
+func (f *fakeAlerts) GetAlertsByUserID(userID string, filters models.AlertFilters) ([]*models.Alert, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.alerts[userID], nil
+}
+
+func (f *fakeAlerts) set(userID string, alerts ...*models.Alert) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.alerts[userID] = alerts
+}
+
+// received drains the events delivered to sub
+func received(sub *Subscription) []string {
+	var ids []string
+	for {
+		select {
+		case event := <-sub.Events():
+			ids = append(ids, event.Alert.ID)
+		default:
+			return ids
+		}
+	}
+}
+
+func newTestWatcher() (*Hub, *Watcher, *fakeAlerts) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	hub := NewHub()
+	alerts := &fakeAlerts{alerts: make(map[string][]*models.Alert)}
+	return hub, NewWatcher(hub, alerts, time.Hour, logger), alerts
+}
+
+func TestWatcherPublishesNewAlerts(t *testing.T) {
+	hub, watcher, alerts := newTestWatcher()
+	now := time.Now()
+	existing := &models.Alert{ID: "alert-001", CreatedAt: now.Add(-time.Hour)}
+	alerts.set("user-001", existing)
+
+	sub, _, _ := hub.Subscribe("user-001", 0)
+	defer sub.Close()
+	if err := watcher.Track("user-001"); err != nil {
+		t.Fatalf("Failed to track user: %v", err)
+	}
+
+	watcher.Check()
+	if ids := received(sub); len(ids) != 0 {
+		t.Errorf("Expected alerts listed when the stream opened to be skipped, got %v", ids)
+	}
+
+	// New alerts are published oldest first
+	alerts.set("user-001",
+		&models.Alert{ID: "alert-003", CreatedAt: now},
+		&models.Alert{ID: "alert-002", CreatedAt: now.Add(-time.Minute)},
+		existing,
+	)
+	watcher.Check()
+	if ids := received(sub); len(ids) != 2 || ids[0] != "alert-002" || ids[1] != "alert-003" {
+		t.Errorf("Expected alert-002 then alert-003, got %v", ids)
+	}
+
+	// An alert that goes away and comes back, such as a snoozed one, is new again
+	alerts.set("user-001")
+	watcher.Check()
+	alerts.set("user-001", existing)
+	watcher.Check()
+	if ids := received(sub); len(ids) != 1 || ids[0] != "alert-001" {
+		t.Errorf("Expected alert-001 published again, got %v", ids)
+	}
+}
+
+func TestWatcherKeepsUsersWhileAway(t *testing.T) {
+	hub, watcher, alerts := newTestWatcher()
+	alerts.set("user-001", &models.Alert{ID: "alert-001"})
+
+	sub, _, _ := hub.Subscribe("user-001", 0)
+	if err := watcher.Track("user-001"); err != nil {
+		t.Fatalf("Failed to track user: %v", err)
+	}
+	watcher.Check()
+	sub.Close()
+
+	// Alerts that appear while the user has no stream are published once
+	// they reconnect, and kept for them to resume from
+	alerts.set("user-001", &models.Alert{ID: "alert-001"}, &models.Alert{ID: "alert-002"})
+	watcher.Check()
+	sub, _, _ = hub.Subscribe("user-001", 0)
+	defer sub.Close()
+	if err := watcher.Track("user-001"); err != nil {
+		t.Fatalf("Failed to track user: %v", err)
+	}
+	watcher.Check()
+	if ids := received(sub); len(ids) != 1 || ids[0] != "alert-002" {
+		t.Errorf("Expected alert-002 published on reconnect, got %v", ids)
+	}
+}
@@ -0,0 +1,247 @@
+package stream
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/pkg/middleware"
+)
+
+// The server side of the WebSocket protocol (RFC 6455), as far as a stream
+// that only sends needs it: text messages out, pings both ways and the
+// closing handshake. Data the client sends is read and ignored.
+
+const (
+	// HeartbeatInterval is how often idle streams are pinged, so proxies do
+	// not close them and dead clients are noticed
+	HeartbeatInterval = 15 * time.Second
+
+	// WriteWait is how long each write to a stream may take. Streams outlive
+	// the server's WriteTimeout, so each write gets its own deadline instead.
+	WriteWait = 10 * time.Second
+
+	// maxClientFrame caps the frames a client may send. Clients only need
+	// control frames, which are smaller.
+	maxClientFrame = 4096
+
+	// websocketGUID is appended to the client's key to derive the accept key
+	websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+)
+
+// Frame opcodes
+const (
+	opText  = 0x1
+	opClose = 0x8
+	opPing  = 0x9
+	opPong  = 0xA
+)
+
+// Close codes
+const (
+	CloseNormal        = 1000
+	CloseGoingAway     = 1001
+	CloseProtocolError = 1002
+	CloseMessageTooBig = 1009
+	CloseTryAgainLater = 1013
+)
+
+// ErrNotWebSocket is returned by Upgrade for requests that are not a valid
+// WebSocket opening handshake
+var ErrNotWebSocket = errors.New("not a websocket handshake")
+
+// ErrOriginNotAllowed is returned by Upgrade for a handshake from a page
+// whose origin may not connect
+var ErrOriginNotAllowed = errors.New("websocket origin not allowed")
+
+// errClosed is returned by ReadLoop when the client closes the connection
+var errClosed = errors.New("websocket closed by client")
+
+// Conn is a WebSocket connection taken over from an HTTP request
+type Conn struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	writeMu sync.Mutex
+	once    sync.Once
+}
+
+// Upgrade completes the opening handshake of r and takes over its connection.
+// Browsers let any page open a WebSocket, so a handshake sent from a page must
+// come from an origin middleware.OriginAllowed accepts for allowedOrigins.
+// Nothing is written to w on error, so the caller can still answer it.
+func Upgrade(w http.ResponseWriter, r *http.Request, allowedOrigins []string) (*Conn, error) {
+	if r.Method != http.MethodGet || !headerHasToken(r.Header, "Connection", "upgrade") || !headerHasToken(r.Header, "Upgrade", "websocket") {
+		return nil, ErrNotWebSocket
+	}
+	if !middleware.OriginAllowed(r, allowedOrigins) {
+		return nil, ErrOriginNotAllowed
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		return nil, fmt.Errorf("%w: only version 13 is supported", ErrNotWebSocket)
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, fmt.Errorf("%w: invalid Sec-WebSocket-Key", ErrNotWebSocket)
+	}
+
+	netConn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, err
+	}
+	// The server's read and write timeouts are meant for ordinary requests
+	netConn.SetDeadline(time.Time{})
+
+	netConn.SetWriteDeadline(time.Now().Add(WriteWait))
+	brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n")
+	if err := brw.Flush(); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	return &Conn{conn: netConn, reader: brw.Reader}, nil
+}
+
+// WriteText sends a text message
+func (c *Conn) WriteText(data []byte) error {
+	return c.writeFrame(opText, data)
+}
+
+// Ping sends a ping, which the client answers with a pong
+func (c *Conn) Ping() error {
+	return c.writeFrame(opPing, nil)
+}
+
+// ReadLoop reads what the client sends until the connection ends, answering
+// pings. It gives up on the client if nothing, not even a pong, arrives
+// within readWait. The returned error says why the loop ended.
+func (c *Conn) ReadLoop(readWait time.Duration) error {
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(readWait))
+		opcode, payload, err := c.readFrame()
+		if err != nil {
+			return err
+		}
+		switch opcode {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return err
+			}
+		case opClose:
+			code := CloseNormal
+			if len(payload) >= 2 {
+				code = int(binary.BigEndian.Uint16(payload))
+			}
+			c.Close(code, "")
+			return errClosed
+		}
+	}
+}
+
+// Close sends a close frame with code and reason and closes the connection.
+// Calls after the first do nothing.
+func (c *Conn) Close(code int, reason string) error {
+	var err error
+	c.once.Do(func() {
+		payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+		c.writeFrame(opClose, append(payload, reason...))
+		err = c.conn.Close()
+	})
+	return err
+}
+
+// writeFrame sends one unfragmented, unmasked frame, as servers do
+func (c *Conn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	frame := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n < 126:
+		frame = append(frame, byte(n))
+	case n <= 0xFFFF:
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	frame = append(frame, payload...)
+
+	c.conn.SetWriteDeadline(time.Now().Add(WriteWait))
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// readFrame reads one frame from the client and unmasks its payload.
+// Frames that break the protocol close the connection.
+func (c *Conn) readFrame() (byte, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return 0, nil, err
+	}
+	opcode := header[0] & 0x0F
+	if header[1]&0x80 == 0 {
+		c.Close(CloseProtocolError, "client frames must be masked")
+		return 0, nil, fmt.Errorf("unmasked frame from client")
+	}
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if length > maxClientFrame {
+		c.Close(CloseMessageTooBig, "")
+		return 0, nil, fmt.Errorf("frame of %d bytes from client", length)
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return opcode, payload, nil
+}
+
+// acceptKey derives Sec-WebSocket-Accept from the client's key
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// headerHasToken reports whether a comma-separated header lists token,
+// ignoring case
+func headerHasToken(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
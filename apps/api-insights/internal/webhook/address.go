@@ -0,0 +1,37 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// ErrPrivateAddress is returned when a delivery would connect to an address
+// that is not on the public internet
+var ErrPrivateAddress = errors.New("webhook address is not public")
+
+// PublicIP reports whether deliveries may be sent to ip. Webhook URLs are
+// chosen by users, so loopback, private, link-local, unspecified and
+// multicast addresses are refused: otherwise a webhook could make this
+// service post to itself or to other services on its network.
+func PublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast()
+}
+
+// refusePrivate is the dialer's Control function. It is called with the
+// address a host name resolved to, just before connecting, so a name that
+// resolves to a public address when the webhook is saved and to a private
+// one later is refused as well.
+func refusePrivate(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !PublicIP(ip) {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, host)
+	}
+	return nil
+}
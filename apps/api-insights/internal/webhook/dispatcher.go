@@ -0,0 +1,199 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/models"
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// MaxAttempts is how many times a delivery is tried before it is moved
+	// to the dead-letter list
+	MaxAttempts = 8
+
+	// firstRetryDelay is the wait after the first failed attempt. It doubles
+	// after each one, up to maxRetryDelay.
+	firstRetryDelay = 30 * time.Second
+	maxRetryDelay   = time.Hour
+
+	// deliveryTimeout is how long an endpoint has to respond
+	deliveryTimeout = 10 * time.Second
+
+	// batchSize is how many due deliveries are read from the queue at once
+	batchSize = 50
+)
+
+// Dispatcher posts the deliveries due in the queue to their webhooks
+type Dispatcher struct {
+	store    repository.WebhookStore
+	client   *http.Client
+	interval time.Duration
+	logger   *logrus.Logger
+	now      func() time.Time
+}
+
+// NewDispatcher creates a dispatcher checking the queue every interval.
+// Deliveries only connect to addresses PublicIP accepts unless allowPrivate
+// is set, which is meant for development against receivers on this machine.
+func NewDispatcher(store repository.WebhookStore, interval time.Duration, allowPrivate bool, logger *logrus.Logger) *Dispatcher {
+	dialer := &net.Dialer{Timeout: deliveryTimeout}
+	if !allowPrivate {
+		dialer.Control = refusePrivate
+	}
+
+	return &Dispatcher{
+		store: store,
+		client: &http.Client{
+			Timeout: deliveryTimeout,
+			// No proxy is used, so the dialer checks the endpoint's own address
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				ForceAttemptHTTP2:   true,
+				MaxIdleConns:        100,
+				IdleConnTimeout:     90 * time.Second,
+				TLSHandshakeTimeout: deliveryTimeout,
+			},
+			// A redirect is not a delivery, and following it would send the
+			// payload somewhere the user did not subscribe
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		interval: interval,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// Run delivers what is due every interval until ctx is done
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.DeliverDue(ctx)
+		}
+	}
+}
+
+// DeliverDue attempts every delivery due now, one at a time
+func (d *Dispatcher) DeliverDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		due, err := d.store.GetDueDeliveries(d.now(), batchSize)
+		if err != nil {
+			d.logger.WithError(err).Error("Failed to read the webhook delivery queue")
+			return
+		}
+		for _, delivery := range due {
+			if err := d.deliver(ctx, delivery); err != nil {
+				d.logger.WithError(err).WithField("deliveryId", delivery.ID).Error("Failed to record webhook delivery")
+				return
+			}
+		}
+		if len(due) < batchSize {
+			return
+		}
+	}
+}
+
+// deliver attempts one delivery and records the outcome. Failed attempts
+// are retried with exponential backoff until MaxAttempts.
+func (d *Dispatcher) deliver(ctx context.Context, delivery *models.WebhookDelivery) error {
+	webhook, err := d.store.GetWebhook(delivery.UserID, delivery.WebhookID)
+	if errors.Is(err, repository.ErrWebhookNotFound) {
+		// Deleted meanwhile, and its deliveries with it
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	now := d.now().UTC().Truncate(time.Second)
+	if !webhook.Enabled {
+		delivery.Status = models.DeliveryDead
+		delivery.NextAttemptAt = nil
+		delivery.LastError = "webhook is disabled"
+		return d.store.SaveDelivery(delivery)
+	}
+
+	status, postErr := d.post(ctx, webhook, delivery, now)
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.ResponseStatus = status
+	delivery.LastError = ""
+	delivery.NextAttemptAt = nil
+
+	logger := d.logger.WithFields(logrus.Fields{
+		"deliveryId": delivery.ID,
+		"webhookId":  webhook.ID,
+		"eventType":  delivery.EventType,
+		"attempt":    delivery.Attempts,
+		"status":     status,
+	})
+	switch {
+	case postErr == nil:
+		delivery.Status = models.DeliverySucceeded
+		logger.Info("Webhook delivered")
+	case delivery.Attempts >= MaxAttempts:
+		delivery.Status = models.DeliveryDead
+		delivery.LastError = postErr.Error()
+		logger.WithError(postErr).Warn("Webhook delivery failed for the last time, moved to the dead-letter list")
+	default:
+		next := now.Add(retryDelay(delivery.Attempts))
+		delivery.Status = models.DeliveryPending
+		delivery.LastError = postErr.Error()
+		delivery.NextAttemptAt = &next
+		logger.WithError(postErr).Info("Webhook delivery failed, will retry")
+	}
+	return d.store.SaveDelivery(delivery)
+}
+
+// post sends the delivery's payload, signed at now, and returns the
+// response status. Anything but a 2xx response is an error.
+func (d *Dispatcher) post(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery, now time.Time) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "AccountStack-Webhooks/1.0")
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, now, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// retryDelay is the wait after the given number of failed attempts
+func retryDelay(attempts int) time.Duration {
+	delay := firstRetryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
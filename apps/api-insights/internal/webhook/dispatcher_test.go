@@ -0,0 +1,256 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/models"
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+const testSecret = "whsec_test"
+
+// receiver is an integrator's endpoint answering with status
+type receiver struct {
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+	mu       sync.Mutex
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+	if r.URL.Path == "/moved" {
+		http.Redirect(w, r, "/", http.StatusFound)
+		return
+	}
+	w.WriteHeader(rc.status)
+}
+
+// newTestDispatcher returns a dispatcher at now over a store with webhook
+// wh-1 of user-001 posting to path of rc
+func newTestDispatcher(t *testing.T, rc *receiver, path string, now *time.Time) (*Dispatcher, *repository.MemoryWebhooks) {
+	t.Helper()
+	server := httptest.NewServer(rc)
+	t.Cleanup(server.Close)
+
+	store := repository.NewMemoryWebhooks()
+	store.SaveWebhook(&models.Webhook{ID: "wh-1", UserID: "user-001", URL: server.URL + path,
+		Events: []string{models.WebhookAlertCreated}, Secret: testSecret, Enabled: true, CreatedAt: *now, UpdatedAt: *now})
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	dispatcher := NewDispatcher(store, time.Hour, true, logger)
+	dispatcher.now = func() time.Time { return *now }
+	return dispatcher, store
+}
+
+// queue adds a delivery to wh-1 due at now
+func queue(t *testing.T, store repository.WebhookStore, id string, now time.Time) {
+	t.Helper()
+	err := store.AddDeliveries([]*models.WebhookDelivery{{ID: id, WebhookID: "wh-1", UserID: "user-001", EventID: "evt-" + id,
+		EventType: models.WebhookAlertCreated, Payload: []byte(`{"id":"evt-` + id + `"}`), Status: models.DeliveryPending,
+		NextAttemptAt: &now, CreatedAt: now}})
+	if err != nil {
+		t.Fatalf("AddDeliveries failed: %v", err)
+	}
+}
+
+func TestDispatcherDelivers(t *testing.T) {
+	now := time.Date(2024, 12, 13, 8, 0, 0, 0, time.UTC)
+	rc := &receiver{status: http.StatusNoContent}
+	dispatcher, store := newTestDispatcher(t, rc, "/hook", &now)
+	queue(t, store, "dlv-1", now)
+
+	dispatcher.DeliverDue(context.Background())
+
+	if len(rc.requests) != 1 {
+		t.Fatalf("Expected one request, got %d", len(rc.requests))
+	}
+	req, body := rc.requests[0], rc.bodies[0]
+	if req.Method != "POST" || req.URL.Path != "/hook" || string(body) != `{"id":"evt-dlv-1"}` {
+		t.Errorf("Unexpected request %s %s %s", req.Method, req.URL.Path, body)
+	}
+	if req.Header.Get(EventHeader) != models.WebhookAlertCreated || req.Header.Get(DeliveryHeader) != "dlv-1" ||
+		req.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Unexpected headers %v", req.Header)
+	}
+
+	// Receivers verify the HMAC-SHA256 of "<t>.<body>" keyed with the secret
+	mac := hmac.New(sha256.New, []byte(testSecret))
+	fmt.Fprintf(mac, "%d.%s", now.Unix(), body)
+	want := fmt.Sprintf("t=%d,v1=%s", now.Unix(), hex.EncodeToString(mac.Sum(nil)))
+	if got := req.Header.Get(SignatureHeader); got != want {
+		t.Errorf("Expected signature %q, got %q", want, got)
+	}
+
+	delivery, _ := store.GetDelivery("user-001", "dlv-1")
+	if delivery.Status != models.DeliverySucceeded || delivery.Attempts != 1 || delivery.ResponseStatus != http.StatusNoContent ||
+		delivery.NextAttemptAt != nil || delivery.LastAttemptAt == nil || !delivery.LastAttemptAt.Equal(now) {
+		t.Errorf("Expected the delivery to succeed, got %+v", delivery)
+	}
+
+	// Delivered once only
+	dispatcher.DeliverDue(context.Background())
+	if len(rc.requests) != 1 {
+		t.Errorf("Expected no more requests, got %d", len(rc.requests))
+	}
+}
+
+func TestDispatcherRetriesUntilDead(t *testing.T) {
+	now := time.Date(2024, 12, 13, 8, 0, 0, 0, time.UTC)
+	rc := &receiver{status: http.StatusInternalServerError}
+	dispatcher, store := newTestDispatcher(t, rc, "/hook", &now)
+	queue(t, store, "dlv-1", now)
+
+	for attempt := 1; attempt <= MaxAttempts; attempt++ {
+		dispatcher.DeliverDue(context.Background())
+		if len(rc.requests) != attempt {
+			t.Fatalf("Attempt %d: expected %d requests, got %d", attempt, attempt, len(rc.requests))
+		}
+		if got := rc.requests[attempt-1].Header.Get(DeliveryHeader); got != "dlv-1" {
+			t.Errorf("Attempt %d: expected the same delivery ID, got %q", attempt, got)
+		}
+
+		delivery, _ := store.GetDelivery("user-001", "dlv-1")
+		if delivery.Attempts != attempt || delivery.ResponseStatus != http.StatusInternalServerError ||
+			!strings.Contains(delivery.LastError, "500") {
+			t.Fatalf("Attempt %d: unexpected delivery %+v", attempt, delivery)
+		}
+		if attempt == MaxAttempts {
+			if delivery.Status != models.DeliveryDead || delivery.NextAttemptAt != nil {
+				t.Errorf("Expected the delivery to be dead after %d attempts, got %+v", attempt, delivery)
+			}
+			break
+		}
+		if delivery.Status != models.DeliveryPending || !delivery.NextAttemptAt.Equal(now.Add(retryDelay(attempt))) {
+			t.Fatalf("Attempt %d: expected a retry after %v, got %+v", attempt, retryDelay(attempt), delivery)
+		}
+
+		// Nothing is sent before the retry is due
+		now = delivery.NextAttemptAt.Add(-time.Second)
+		dispatcher.DeliverDue(context.Background())
+		if len(rc.requests) != attempt {
+			t.Fatalf("Attempt %d: expected no request before the retry is due", attempt)
+		}
+		now = *delivery.NextAttemptAt
+	}
+
+	dead, _ := store.GetDeliveries("user-001", models.DeliveryFilters{Status: models.DeliveryDead})
+	if len(dead) != 1 || dead[0].ID != "dlv-1" {
+		t.Errorf("Expected dlv-1 on the dead-letter list, got %+v", dead)
+	}
+}
+
+func TestDispatcherFailures(t *testing.T) {
+	now := time.Date(2024, 12, 13, 8, 0, 0, 0, time.UTC)
+
+	// Redirects are not followed
+	rc := &receiver{status: http.StatusOK}
+	dispatcher, store := newTestDispatcher(t, rc, "/moved", &now)
+	queue(t, store, "dlv-1", now)
+	dispatcher.DeliverDue(context.Background())
+	if len(rc.requests) != 1 {
+		t.Errorf("Expected the redirect not to be followed, got %d requests", len(rc.requests))
+	}
+	if delivery, _ := store.GetDelivery("user-001", "dlv-1"); delivery.Status != models.DeliveryPending || delivery.ResponseStatus != http.StatusFound {
+		t.Errorf("Expected the redirect to be retried, got %+v", delivery)
+	}
+
+	// Deliveries to a disabled webhook go straight to the dead-letter list
+	rc = &receiver{status: http.StatusOK}
+	dispatcher, store = newTestDispatcher(t, rc, "/hook", &now)
+	webhook, _ := store.GetWebhook("user-001", "wh-1")
+	webhook.Enabled = false
+	store.SaveWebhook(webhook)
+	queue(t, store, "dlv-2", now)
+	dispatcher.DeliverDue(context.Background())
+	if len(rc.requests) != 0 {
+		t.Errorf("Expected nothing sent to a disabled webhook, got %d requests", len(rc.requests))
+	}
+	if delivery, _ := store.GetDelivery("user-001", "dlv-2"); delivery.Status != models.DeliveryDead || delivery.LastError != "webhook is disabled" {
+		t.Errorf("Expected the delivery to be dead, got %+v", delivery)
+	}
+
+	// Receivers on this machine are not connected to unless private
+	// addresses are allowed
+	rc = &receiver{status: http.StatusOK}
+	_, store = newTestDispatcher(t, rc, "/hook", &now)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	dispatcher = NewDispatcher(store, time.Hour, false, logger)
+	dispatcher.now = func() time.Time { return now }
+	queue(t, store, "dlv-3", now)
+	dispatcher.DeliverDue(context.Background())
+	if len(rc.requests) != 0 {
+		t.Errorf("Expected nothing sent to a loopback address, got %d requests", len(rc.requests))
+	}
+	if delivery, _ := store.GetDelivery("user-001", "dlv-3"); delivery.Status != models.DeliveryPending || !strings.Contains(delivery.LastError, "not public") {
+		t.Errorf("Expected the delivery to fail, got %+v", delivery)
+	}
+}
+
+func TestPublicIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"fd00::1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"224.0.0.1", false},
+		{"ff02::1", false},
+		{"::ffff:127.0.0.1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := PublicIP(net.ParseIP(tt.ip)); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour},
+		{20, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := retryDelay(tt.attempts); got != tt.want {
+			t.Errorf("retryDelay(%d): expected %v, got %v", tt.attempts, tt.want, got)
+		}
+	}
+}
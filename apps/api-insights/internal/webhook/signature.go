@@ -0,0 +1,32 @@
+// Package webhook posts events of a user's to the webhooks they subscribed,
+// signed with each webhook's secret, and retries deliveries that fail
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+// Headers sent with each delivery
+const (
+	// SignatureHeader carries "t=<unix seconds>,v1=<hex HMAC-SHA256>" of
+	// "<t>.<body>" keyed with the webhook's secret. Receivers recompute it,
+	// and reject old timestamps to stop replays.
+	SignatureHeader = "X-AccountStack-Signature"
+	// EventHeader carries the event type
+	EventHeader = "X-AccountStack-Event"
+	// DeliveryHeader carries the delivery ID, the same on every attempt
+	DeliveryHeader = "X-AccountStack-Delivery"
+)
+
+// Sign returns the SignatureHeader value for body sent at timestamp
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t + "."))
+	mac.Write(body)
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,192 @@
+package webhook
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// AlertLister lists the alerts a user sees. services.AlertsService satisfies it.
+type AlertLister interface {
+	GetAlertsByUserID(userID string, filters models.AlertFilters) ([]*models.Alert, error)
+}
+
+// Subscriptions is what the watcher needs of the webhooks service.
+// services.WebhooksService satisfies it.
+type Subscriptions interface {
+	// GetSubscribedUserIDs returns the users with an enabled webhook
+	GetSubscribedUserIDs() ([]string, error)
+	// GetTransactions returns the transactions of the accounts the user can see
+	GetTransactions(userID string) ([]*models.Transaction, error)
+	// Publish queues the event for each of the user's webhooks that want it
+	Publish(userID, eventType string, data interface{}) error
+}
+
+// Watcher finds the events of the users with webhooks and publishes them.
+// Alerts are derived when they are listed and transactions are written by
+// api-transactions, so it lists both periodically and publishes those it has
+// not seen listed before, like the alert stream. An alert that comes back,
+// such as a balance falling low again, is new again.
+type Watcher struct {
+	subscriptions Subscriptions
+	alerts        AlertLister
+	interval      time.Duration
+	logger        *logrus.Logger
+
+	alertsSeen       map[string]map[string]bool // user ID to the IDs last listed
+	transactionsSeen map[string]map[string]bool
+	mu               sync.Mutex
+}
+
+// NewWatcher creates a watcher listing events every interval
+func NewWatcher(subscriptions Subscriptions, alerts AlertLister, interval time.Duration, logger *logrus.Logger) *Watcher {
+	return &Watcher{
+		subscriptions:    subscriptions,
+		alerts:           alerts,
+		interval:         interval,
+		logger:           logger,
+		alertsSeen:       make(map[string]map[string]bool),
+		transactionsSeen: make(map[string]map[string]bool),
+	}
+}
+
+// Run checks for new events every interval until ctx is done
+func (w *Watcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.Check()
+		}
+	}
+}
+
+// Check publishes the new events of every user with an enabled webhook. The
+// first check of a user only notes what is already there.
+func (w *Watcher) Check() {
+	userIDs, err := w.subscriptions.GetSubscribedUserIDs()
+	if err != nil {
+		w.logger.WithError(err).Error("Failed to list users with webhooks")
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	subscribed := make(map[string]bool, len(userIDs))
+	for _, userID := range userIDs {
+		subscribed[userID] = true
+		if err := w.checkAlerts(userID); err != nil {
+			w.logger.WithError(err).WithField("userId", userID).Debug("Failed to check for new alerts")
+		}
+		if err := w.checkTransactions(userID); err != nil {
+			w.logger.WithError(err).WithField("userId", userID).Debug("Failed to check for new transactions")
+		}
+	}
+
+	// Users who subscribe again start afresh
+	for userID := range w.alertsSeen {
+		if !subscribed[userID] {
+			delete(w.alertsSeen, userID)
+		}
+	}
+	for userID := range w.transactionsSeen {
+		if !subscribed[userID] {
+			delete(w.transactionsSeen, userID)
+		}
+	}
+}
+
+// checkAlerts publishes the user's alerts not listed last time, oldest first
+func (w *Watcher) checkAlerts(userID string) error {
+	alerts, err := w.alerts.GetAlertsByUserID(userID, models.AlertFilters{})
+	if err != nil {
+		return err
+	}
+	ids := make([]string, len(alerts))
+	for i, alert := range alerts {
+		ids[i] = alert.ID
+	}
+	fresh := update(w.alertsSeen, userID, ids)
+
+	var created []*models.Alert
+	for _, alert := range alerts {
+		if fresh[alert.ID] {
+			created = append(created, alert)
+		}
+	}
+	sort.SliceStable(created, func(i, j int) bool {
+		return created[i].CreatedAt.Before(created[j].CreatedAt)
+	})
+	for _, alert := range created {
+		if err := w.subscriptions.Publish(userID, models.WebhookAlertCreated, alert); err != nil {
+			return err
+		}
+		if alert.RuleID != "" && alert.Type == models.AlertRuleLowBalance {
+			data := &models.BalanceLowData{AccountID: alert.AccountID, RuleID: alert.RuleID, Alert: alert}
+			if err := w.subscriptions.Publish(userID, models.WebhookBalanceLow, data); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkTransactions publishes the user's transactions not listed last time,
+// oldest first
+func (w *Watcher) checkTransactions(userID string) error {
+	transactions, err := w.subscriptions.GetTransactions(userID)
+	if err != nil {
+		return err
+	}
+	ids := make([]string, len(transactions))
+	for i, txn := range transactions {
+		ids[i] = txn.ID
+	}
+	fresh := update(w.transactionsSeen, userID, ids)
+
+	var created []*models.Transaction
+	for _, txn := range transactions {
+		if fresh[txn.ID] {
+			created = append(created, txn)
+		}
+	}
+	sort.SliceStable(created, func(i, j int) bool {
+		return created[i].Date.Before(created[j].Date)
+	})
+	for _, txn := range created {
+		if err := w.subscriptions.Publish(userID, models.WebhookTransactionCreated, txn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// update records the IDs listed for the user and returns those not listed
+// last time. Nothing is new the first time a user is seen.
+func update(seen map[string]map[string]bool, userID string, ids []string) map[string]bool {
+	previous, tracked := seen[userID]
+	current := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		current[id] = true
+	}
+	seen[userID] = current
+	if !tracked {
+		return nil
+	}
+
+	fresh := make(map[string]bool)
+	for id := range current {
+		if !previous[id] {
+			fresh[id] = true
+		}
+	}
+	return fresh
+}
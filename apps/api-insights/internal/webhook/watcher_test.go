@@ -0,0 +1,127 @@
+package webhook
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-insights/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// published is an event passed to Publish
+type published struct {
+	userID    string
+	eventType string
+	data      interface{}
+}
+
+// fakeSubscriptions is a webhooks service whose users and data tests change
+type fakeSubscriptions struct {
+	userIDs      []string
+	alerts       map[string][]*models.Alert
+	transactions map[string][]*models.Transaction
+	published    []published
+}
+
+func (f *fakeSubscriptions) GetSubscribedUserIDs() ([]string, error) {
+	return f.userIDs, nil
+}
+
+func (f *fakeSubscriptions) GetTransactions(userID string) ([]*models.Transaction, error) {
+	return f.transactions[userID], nil
+}
+
+func (f *fakeSubscriptions) Publish(userID, eventType string, data interface{}) error {
+	f.published = append(f.published, published{userID, eventType, data})
+	return nil
+}
+
+func (f *fakeSubscriptions) GetAlertsByUserID(userID string, filters models.AlertFilters) ([]*models.Alert, error) {
+	return f.alerts[userID], nil
+}
+
+// take returns and forgets what was published
+func (f *fakeSubscriptions) take() []published {
+	events := f.published
+	f.published = nil
+	return events
+}
+
+func TestWatcherPublishesEvents(t *testing.T) {
+	now := time.Now()
+	fake := &fakeSubscriptions{
+		userIDs:      []string{"user-001"},
+		alerts:       map[string][]*models.Alert{"user-001": {{ID: "alert-001", CreatedAt: now}}},
+		transactions: map[string][]*models.Transaction{"user-001": {{ID: "txn-001", Date: now}}},
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	watcher := NewWatcher(fake, fake, time.Hour, logger)
+
+	// The first check notes what is already there
+	watcher.Check()
+	if events := fake.take(); len(events) != 0 {
+		t.Fatalf("Expected nothing published on the first check, got %+v", events)
+	}
+
+	lowBalance := &models.Alert{ID: "alert-rule-1", Type: models.AlertRuleLowBalance, RuleID: "rule-1", AccountID: "acc-001", CreatedAt: now}
+	fake.alerts["user-001"] = append(fake.alerts["user-001"], lowBalance)
+	fake.transactions["user-001"] = append(fake.transactions["user-001"],
+		&models.Transaction{ID: "txn-003", Date: now.Add(time.Minute)},
+		&models.Transaction{ID: "txn-002", Date: now},
+	)
+	watcher.Check()
+
+	events := fake.take()
+	if len(events) != 4 {
+		t.Fatalf("Expected 4 events, got %+v", events)
+	}
+	if events[0].eventType != models.WebhookAlertCreated || events[0].data.(*models.Alert).ID != "alert-rule-1" {
+		t.Errorf("Expected alert.created first, got %+v", events[0])
+	}
+	balance, ok := events[1].data.(*models.BalanceLowData)
+	if events[1].eventType != models.WebhookBalanceLow || !ok || balance.AccountID != "acc-001" || balance.RuleID != "rule-1" {
+		t.Errorf("Expected account.balance_low for acc-001, got %+v", events[1])
+	}
+	if events[2].data.(*models.Transaction).ID != "txn-002" || events[3].data.(*models.Transaction).ID != "txn-003" {
+		t.Errorf("Expected the transactions oldest first, got %+v and %+v", events[2], events[3])
+	}
+	for _, event := range events[2:] {
+		if event.eventType != models.WebhookTransactionCreated || event.userID != "user-001" {
+			t.Errorf("Expected transaction.created for user-001, got %+v", event)
+		}
+	}
+
+	// A low balance that recovers and falls again is published again
+	fake.alerts["user-001"] = fake.alerts["user-001"][:1]
+	watcher.Check()
+	fake.alerts["user-001"] = append(fake.alerts["user-001"], lowBalance)
+	watcher.Check()
+	if events := fake.take(); len(events) != 2 || events[1].eventType != models.WebhookBalanceLow {
+		t.Errorf("Expected the low balance published again, got %+v", events)
+	}
+}
+
+func TestWatcherForgetsUnsubscribedUsers(t *testing.T) {
+	fake := &fakeSubscriptions{
+		userIDs:      []string{"user-001"},
+		alerts:       map[string][]*models.Alert{"user-001": {{ID: "alert-001"}}},
+		transactions: map[string][]*models.Transaction{},
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	watcher := NewWatcher(fake, fake, time.Hour, logger)
+	watcher.Check()
+
+	// Events while the user has no webhook are not published when they
+	// subscribe again
+	fake.userIDs = nil
+	watcher.Check()
+	fake.alerts["user-001"] = append(fake.alerts["user-001"], &models.Alert{ID: "alert-002"})
+	fake.userIDs = []string{"user-001"}
+	watcher.Check()
+	if events := fake.take(); len(events) != 0 {
+		t.Errorf("Expected a fresh start, got %+v", events)
+	}
+}
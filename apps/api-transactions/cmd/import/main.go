@@ -0,0 +1,143 @@
+// Command import loads a CSV or OFX bank statement into an account, using the
+// same storage configuration as the server. It prints the import report as
+// JSON and only stores anything when -commit is given.
+//
+//	import -account acc-001 [-format csv|ofx] [-map date=Posted Date,amount=Amount]
+//	       [-date-format DD/MM/YYYY] [-user user-001] [-commit] statement.csv
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/importer"
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/repository"
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/services"
+	"github.com/sirupsen/logrus"
+)
+
+func main() {
+	accountID := flag.String("account", "", "account to import into (required)")
+	userID := flag.String("user", "", "user performing the import (default: the account owner)")
+	format := flag.String("format", "", "statement format, csv or ofx (default: detected from the file)")
+	mapSpec := flag.String("map", "", "CSV column mapping as field=Header pairs separated by commas; fields: "+
+		strings.Join(importer.MappingFields, ", "))
+	dateFormat := flag.String("date-format", "", "CSV date pattern such as DD/MM/YYYY")
+	commit := flag.Bool("commit", false, "store the new transactions instead of only reporting them")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s -account ID [flags] FILE\n\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *accountID == "" || flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Logs go to stderr so stdout carries only the report
+	logger := logrus.New()
+	logger.SetOutput(os.Stderr)
+	level, err := logrus.ParseLevel(os.Getenv("LOG_LEVEL"))
+	if err != nil {
+		level = logrus.WarnLevel
+	}
+	logger.SetLevel(level)
+
+	mapping := importer.DefaultCSVMapping()
+	if *mapSpec != "" {
+		for _, pair := range strings.Split(*mapSpec, ",") {
+			field, column, ok := strings.Cut(pair, "=")
+			if !ok {
+				fatalf("invalid -map entry %q, expected field=Header", pair)
+			}
+			if err := mapping.Set(strings.TrimSpace(field), strings.TrimSpace(column)); err != nil {
+				fatalf("%v", err)
+			}
+		}
+	}
+	mapping.DateFormat = *dateFormat
+
+	// Storage configuration matches cmd/server
+	dataPath := os.Getenv("DATA_PATH")
+	if dataPath == "" {
+		dataPath = filepath.Join("..", "..", "data", "seed")
+	}
+	storageBackend := os.Getenv("STORAGE_BACKEND")
+	if storageBackend == "" {
+		storageBackend = repository.BackendJSON
+	}
+	sqlitePath := os.Getenv("SQLITE_PATH")
+	if sqlitePath == "" {
+		sqlitePath = "accountstack.db"
+	}
+
+	if *commit && storageBackend == repository.BackendJSON {
+		fatalf("-commit requires STORAGE_BACKEND=sqlite; the json backend does not persist changes")
+	}
+
+	repo, err := repository.NewStore(repository.Config{
+		Backend:    storageBackend,
+		DataPath:   dataPath,
+		SQLitePath: sqlitePath,
+	}, logger)
+	if err != nil {
+		fatalf("failed to open storage: %v", err)
+	}
+	defer repo.Close()
+
+	if *userID == "" {
+		account, err := repo.GetAccountByID(*accountID)
+		if err != nil {
+			fatalf("account %s not found", *accountID)
+		}
+		*userID = account.UserID
+	}
+
+	file, err := os.Open(flag.Arg(0))
+	if err != nil {
+		fatalf("%v", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	if *format == "" {
+		head, _ := reader.Peek(512)
+		*format = importer.DetectFormat(head)
+	}
+
+	service := services.NewImportService(repo, logger)
+	report, err := service.ImportStatement(*userID, &services.ImportRequest{
+		AccountID: *accountID,
+		Format:    *format,
+		Mapping:   &mapping,
+		Commit:    *commit,
+	}, reader)
+	if err != nil {
+		fatalf("import failed: %v", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		fatalf("failed to write report: %v", err)
+	}
+
+	action := "would be imported (dry run, use -commit to store them)"
+	if report.Committed {
+		action = "imported"
+	}
+	fmt.Fprintf(os.Stderr, "%d new transactions %s, %d duplicates, %d invalid lines\n",
+		report.New, action, report.Duplicates, report.Invalid)
+}
+
+// fatalf prints an error and exits
+func fatalf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "import: "+format+"\n", args...)
+	os.Exit(1)
+}
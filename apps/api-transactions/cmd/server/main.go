@@ -12,9 +12,13 @@ import (
 
 	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/features"
 	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/handlers"
-	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/middleware"
 	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/repository"
 	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/services"
+	"github.com/CB-AccountStack/AccountStack/pkg/auth"
+	"github.com/CB-AccountStack/AccountStack/pkg/health"
+	"github.com/CB-AccountStack/AccountStack/pkg/middleware"
+	"github.com/CB-AccountStack/AccountStack/pkg/problem"
+	"github.com/CB-AccountStack/AccountStack/pkg/requestid"
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 )
@@ -50,6 +54,37 @@ func main() {
 		dataPath = filepath.Join("..", "..", "data", "seed")
 	}
 
+	// Storage backend: "json" (in-memory, seeded on every start) or "sqlite" (durable)
+	storageBackend := os.Getenv("STORAGE_BACKEND")
+	if storageBackend == "" {
+		storageBackend = repository.BackendJSON
+	}
+
+	sqlitePath := os.Getenv("SQLITE_PATH")
+	if sqlitePath == "" {
+		sqlitePath = "accountstack.db"
+	}
+
+	// Revoked token IDs: "memory" (this process only) or "sqlite", shared by
+	// every service that opens the same REVOCATION_SQLITE_PATH
+	revocationSQLitePath := os.Getenv("REVOCATION_SQLITE_PATH")
+	if revocationSQLitePath == "" {
+		revocationSQLitePath = sqlitePath
+	}
+
+	// Personal API keys issued by api-accounts: "memory" (none are seen) or
+	// "sqlite", read from the same API_KEY_SQLITE_PATH as api-accounts
+	apiKeySQLitePath := os.Getenv("API_KEY_SQLITE_PATH")
+	if apiKeySQLitePath == "" {
+		apiKeySQLitePath = sqlitePath
+	}
+
+	// Tokens are issued by api-accounts, which publishes its public keys here
+	jwksURL := os.Getenv("JWKS_URL")
+	if jwksURL == "" {
+		jwksURL = "http://localhost:8001/.well-known/jwks.json"
+	}
+
 	cloudBeesAPIKey := os.Getenv("CLOUDBEES_FM_API_KEY")
 	if cloudBeesAPIKey == "" {
 		logger.Warn("CLOUDBEES_FM_API_KEY not set, feature flags will use defaults")
@@ -65,35 +100,68 @@ func main() {
 	defer features.Shutdown()
 
 	// Initialize repository
-	repo, err := repository.NewRepository(dataPath, logger)
+	repo, err := repository.NewStore(repository.Config{
+		Backend:    storageBackend,
+		DataPath:   dataPath,
+		SQLitePath: sqlitePath,
+	}, logger)
 	if err != nil {
 		logger.WithError(err).Fatal("Failed to initialize repository")
 	}
+	defer repo.Close()
+
+	revocations, err := auth.NewRevocationList(os.Getenv("REVOCATION_STORE"), revocationSQLitePath)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize revocation list")
+	}
+	defer revocations.Close()
+
+	apiKeys, err := auth.NewAPIKeyStore(os.Getenv("API_KEY_STORE"), apiKeySQLitePath)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize API keys")
+	}
+	defer apiKeys.Close()
+
+	verifier := auth.NewVerifier(auth.NewJWKSClient(jwksURL, logger))
 
 	// Initialize services
 	transactionService := services.NewTransactionService(repo, flags, logger)
+	transferService := services.NewTransferService(repo, logger)
+	importService := services.NewImportService(repo, logger)
 
 	// Initialize handlers
-	healthHandler := handlers.NewHealthHandler()
+	healthHandler := health.NewHandler("api-transactions")
 	transactionHandler := handlers.NewTransactionHandler(transactionService, logger)
+	transferHandler := handlers.NewTransferHandler(transferService, logger)
+	importHandler := handlers.NewImportHandler(importService, logger)
 
 	// Setup router
 	router := mux.NewRouter()
+	router.NotFoundHandler = problem.NotFoundHandler()
+	router.MethodNotAllowedHandler = problem.MethodNotAllowedHandler()
 
 	// Apply global middleware
 	router.Use(middleware.LoggingMiddleware(logger))
-	router.Use(middleware.AuthMiddleware(logger))
+	router.Use(middleware.AuthMiddleware(verifier, revocations, apiKeys, logger))
 
 	// Setup CORS
 	corsHandler := middleware.NewCORS()
 
 	// Register routes
 	router.Handle("/healthz", healthHandler).Methods("GET")
-	router.HandleFunc("/transactions", transactionHandler.GetTransactions).Methods("GET")
-	router.HandleFunc("/transactions/{id}", transactionHandler.GetTransactionByID).Methods("GET")
+	scoped := func(scope string, h http.HandlerFunc) http.Handler {
+		return middleware.RequireScope(scope, logger)(h)
+	}
+	router.Handle("/transactions", scoped(auth.ScopeTransactionsRead, transactionHandler.GetTransactions)).Methods("GET")
+	router.Handle("/transactions", scoped(auth.ScopeTransactionsWrite, transactionHandler.CreateTransaction)).Methods("POST")
+	router.Handle("/transactions/export", scoped(auth.ScopeTransactionsRead, transactionHandler.ExportTransactions)).Methods("GET")
+	router.Handle("/transactions/import", scoped(auth.ScopeTransactionsWrite, importHandler.ImportTransactions)).Methods("POST")
+	router.Handle("/transactions/{id}", scoped(auth.ScopeTransactionsRead, transactionHandler.GetTransactionByID)).Methods("GET")
+	router.Handle("/transfers", scoped(auth.ScopeTransactionsWrite, transferHandler.CreateTransfer)).Methods("POST")
+	router.Handle("/admin/users/{userId}/transactions", scoped(auth.ScopeAdminRead, transactionHandler.GetUserTransactions)).Methods("GET")
 
-	// Wrap router with CORS
-	handler := corsHandler.Handler(router)
+	// Wrap router with request IDs, outside it so unmatched routes get one too, and CORS
+	handler := corsHandler.Handler(requestid.Middleware(router))
 
 	// Create HTTP server
 	server := &http.Server{
@@ -112,7 +180,12 @@ func main() {
 		logger.Info("  GET /transactions - List transactions with optional filters")
 		logger.Info("    Query params: accountId, startDate, endDate, category, minAmount, maxAmount")
 		logger.Info("    Note: Advanced filters require api.advancedFilters feature flag")
+		logger.Info("  POST /transactions - Create a transaction")
+		logger.Info("  GET /transactions/export - Export transactions as CSV, OFX or QIF")
+		logger.Info("  POST /transactions/import - Import a CSV or OFX statement (dry run unless commit=true)")
 		logger.Info("  GET /transactions/{id} - Get transaction by ID")
+		logger.Info("  POST /transfers - Transfer money between your accounts")
+		logger.Info("  GET /admin/users/{userId}/transactions - List any user's transactions (support, admin)")
 
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			logger.WithError(err).Fatal("Server failed to start")
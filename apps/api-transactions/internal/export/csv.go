@@ -0,0 +1,81 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/models"
+)
+
+// csvHeader lists the exported columns
+var csvHeader = []string{"id", "accountId", "date", "description", "merchant", "category", "type", "status", "amount"}
+
+// csvEncoder writes one row per transaction under a single header row
+type csvEncoder struct {
+	w      *csv.Writer
+	opts   Options
+	header bool
+}
+
+func newCSVEncoder(w io.Writer, opts Options) *csvEncoder {
+	return &csvEncoder{
+		w:    csv.NewWriter(w),
+		opts: opts,
+	}
+}
+
+// BeginAccount writes the header row before the first account
+func (e *csvEncoder) BeginAccount(account *Account) error {
+	if e.header {
+		return nil
+	}
+	e.header = true
+	return e.w.Write(csvHeader)
+}
+
+// Encode writes a transaction row
+func (e *csvEncoder) Encode(txn *models.Transaction) error {
+	return e.w.Write([]string{
+		txn.ID,
+		txn.AccountID,
+		txn.Date.UTC().Format(time.RFC3339),
+		escapeFormula(txn.Description),
+		escapeFormula(txn.Merchant),
+		escapeFormula(txn.Category),
+		txn.Type,
+		txn.Status,
+		formatAmount(txn.Amount, e.opts),
+	})
+}
+
+// EndAccount is a no-op; CSV rows carry their account ID
+func (e *csvEncoder) EndAccount() error {
+	return nil
+}
+
+// Flush pushes buffered rows to the underlying writer
+func (e *csvEncoder) Flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// Close writes the header if no account was exported and flushes
+func (e *csvEncoder) Close() error {
+	if !e.header {
+		e.header = true
+		if err := e.w.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+	return e.Flush()
+}
+
+// escapeFormula stops spreadsheets from evaluating user-supplied text as a formula
+func escapeFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
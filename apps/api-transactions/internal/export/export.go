@@ -0,0 +1,83 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/models"
+)
+
+// Export formats accepted by GET /transactions/export
+const (
+	FormatCSV = "csv"
+	FormatOFX = "ofx"
+	FormatQIF = "qif"
+)
+
+// MaskedAmount replaces amounts when the maskAmounts flag is enabled, matching api-accounts
+const MaskedAmount = "***.**"
+
+// Format describes how an export is served
+type Format struct {
+	ContentType string
+	Extension   string
+}
+
+// Formats lists the supported export formats by name
+var Formats = map[string]Format{
+	FormatCSV: {ContentType: "text/csv; charset=utf-8", Extension: "csv"},
+	FormatOFX: {ContentType: "application/x-ofx", Extension: "ofx"},
+	FormatQIF: {ContentType: "application/qif", Extension: "qif"},
+}
+
+// Account describes the account whose transactions follow
+type Account struct {
+	ID       string
+	Name     string
+	Type     string // checking, savings or credit
+	Currency string
+	Balance  float64
+	Start    time.Time // Date of the first exported transaction
+	End      time.Time // Date of the last exported transaction
+}
+
+// Options controls how amounts are written
+type Options struct {
+	MaskAmounts bool
+}
+
+// Encoder writes transactions in one export format. Transactions are passed
+// one account at a time in date order, so output can be streamed.
+type Encoder interface {
+	BeginAccount(account *Account) error
+	Encode(txn *models.Transaction) error
+	EndAccount() error
+	// Flush pushes buffered output to the underlying writer
+	Flush() error
+	// Close writes any trailer and flushes
+	Close() error
+}
+
+// NewEncoder creates an encoder for the named format writing to w
+func NewEncoder(format string, w io.Writer, opts Options) (Encoder, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVEncoder(w, opts), nil
+	case FormatOFX:
+		return newOFXEncoder(w, opts), nil
+	case FormatQIF:
+		return newQIFEncoder(w, opts), nil
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+// formatAmount renders an amount with two decimals, or the mask placeholder
+func formatAmount(amount float64, opts Options) string {
+	if opts.MaskAmounts {
+		return MaskedAmount
+	}
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
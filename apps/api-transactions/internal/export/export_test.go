@@ -0,0 +1,216 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/models"
+)
+
+var (
+	testChecking = &Account{
+		ID:       "acc-001",
+		Name:     "Personal Checking",
+		Type:     "checking",
+		Currency: "USD",
+		Balance:  5847.32,
+		Start:    time.Date(2024, 12, 1, 9, 0, 0, 0, time.UTC),
+		End:      time.Date(2024, 12, 13, 7, 20, 0, 0, time.UTC),
+	}
+	testCredit = &Account{
+		ID:       "acc-003",
+		Name:     "Rewards Credit Card",
+		Type:     "credit",
+		Currency: "USD",
+		Balance:  -2134.56,
+		Start:    time.Date(2024, 12, 13, 7, 20, 0, 0, time.UTC),
+		End:      time.Date(2024, 12, 13, 7, 20, 0, 0, time.UTC),
+	}
+	testCheckingTxns = []*models.Transaction{
+		{ID: "txn-005", AccountID: "acc-001", Date: testChecking.Start, Description: "Interest Payment",
+			Amount: 42.15, Category: "income", Merchant: "AccountStack Bank", Status: "completed", Type: "credit"},
+		{ID: "txn-001", AccountID: "acc-001", Date: testChecking.End, Description: "=HYPERLINK(\"evil\")",
+			Amount: -5.47, Category: "food_dining", Merchant: "Starbucks & Co", Status: "pending", Type: "debit"},
+	}
+	testCreditTxns = []*models.Transaction{
+		{ID: "txn-011", AccountID: "acc-003", Date: testCredit.Start, Description: "Apple Store",
+			Amount: -1299.00, Category: "shopping", Merchant: "Apple", Status: "completed", Type: "debit"},
+	}
+)
+
+// encodeAll runs the encoder over the checking and credit test accounts
+func encodeAll(t *testing.T, format string, opts Options) string {
+	t.Helper()
+	var buf bytes.Buffer
+	enc, err := NewEncoder(format, &buf, opts)
+	if err != nil {
+		t.Fatalf("NewEncoder failed: %v", err)
+	}
+	if ofx, ok := enc.(*ofxEncoder); ok {
+		ofx.now = func() time.Time { return time.Date(2024, 12, 14, 0, 0, 0, 0, time.UTC) }
+	}
+
+	for _, group := range []struct {
+		account *Account
+		txns    []*models.Transaction
+	}{{testChecking, testCheckingTxns}, {testCredit, testCreditTxns}} {
+		if err := enc.BeginAccount(group.account); err != nil {
+			t.Fatalf("BeginAccount failed: %v", err)
+		}
+		for _, txn := range group.txns {
+			if err := enc.Encode(txn); err != nil {
+				t.Fatalf("Encode failed: %v", err)
+			}
+		}
+		if err := enc.EndAccount(); err != nil {
+			t.Fatalf("EndAccount failed: %v", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	return buf.String()
+}
+
+func TestNewEncoderUnknownFormat(t *testing.T) {
+	if _, err := NewEncoder("xlsx", io.Discard, Options{}); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+func TestCSVEncoder(t *testing.T) {
+	records, err := csv.NewReader(strings.NewReader(encodeAll(t, FormatCSV, Options{}))).ReadAll()
+	if err != nil {
+		t.Fatalf("Output is not valid CSV: %v", err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("Expected header and 3 rows, got %d records", len(records))
+	}
+	if strings.Join(records[0], ",") != "id,accountId,date,description,merchant,category,type,status,amount" {
+		t.Errorf("Unexpected header: %v", records[0])
+	}
+
+	row := records[2]
+	if row[0] != "txn-001" || row[2] != "2024-12-13T07:20:00Z" || row[8] != "-5.47" {
+		t.Errorf("Unexpected row: %v", row)
+	}
+	if row[3] != `'=HYPERLINK("evil")` {
+		t.Errorf("Expected formula to be escaped, got %q", row[3])
+	}
+	if records[3][8] != "-1299.00" {
+		t.Errorf("Expected two decimal amount, got %q", records[3][8])
+	}
+}
+
+func TestCSVEncoderEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	enc, _ := NewEncoder(FormatCSV, &buf, Options{})
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if strings.Count(buf.String(), "\n") != 1 || !strings.HasPrefix(buf.String(), "id,") {
+		t.Errorf("Expected only a header row, got %q", buf.String())
+	}
+}
+
+func TestOFXEncoder(t *testing.T) {
+	out := encodeAll(t, FormatOFX, Options{})
+
+	// The document must be well-formed XML
+	dec := xml.NewDecoder(strings.NewReader(out))
+	for {
+		if _, err := dec.Token(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Output is not well-formed: %v\n%s", err, out)
+		}
+	}
+
+	var doc struct {
+		Bank struct {
+			Statements []struct {
+				CurDef string `xml:"STMTRS>CURDEF"`
+				AcctID string `xml:"STMTRS>BANKACCTFROM>ACCTID"`
+				Type   string `xml:"STMTRS>BANKACCTFROM>ACCTTYPE"`
+				Start  string `xml:"STMTRS>BANKTRANLIST>DTSTART"`
+				Txns   []struct {
+					TrnType string `xml:"TRNTYPE"`
+					Posted  string `xml:"DTPOSTED"`
+					Amount  string `xml:"TRNAMT"`
+					FITID   string `xml:"FITID"`
+					Name    string `xml:"NAME"`
+				} `xml:"STMTRS>BANKTRANLIST>STMTTRN"`
+				Balance string `xml:"STMTRS>LEDGERBAL>BALAMT"`
+			} `xml:"STMTTRNRS"`
+		} `xml:"BANKMSGSRSV1"`
+		Credit struct {
+			AcctID string   `xml:"CCSTMTTRNRS>CCSTMTRS>CCACCTFROM>ACCTID"`
+			FITIDs []string `xml:"CCSTMTTRNRS>CCSTMTRS>BANKTRANLIST>STMTTRN>FITID"`
+		} `xml:"CREDITCARDMSGSRSV1"`
+	}
+	if err := xml.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("Failed to parse OFX: %v", err)
+	}
+
+	if len(doc.Bank.Statements) != 1 {
+		t.Fatalf("Expected one bank statement, got %d", len(doc.Bank.Statements))
+	}
+	stmt := doc.Bank.Statements[0]
+	if stmt.AcctID != "acc-001" || stmt.Type != "CHECKING" || stmt.CurDef != "USD" || stmt.Balance != "5847.32" {
+		t.Errorf("Unexpected statement header: %+v", stmt)
+	}
+	if stmt.Start != "20241201090000.000[0:GMT]" {
+		t.Errorf("Unexpected DTSTART %q", stmt.Start)
+	}
+	if len(stmt.Txns) != 2 {
+		t.Fatalf("Expected 2 STMTTRN blocks, got %d", len(stmt.Txns))
+	}
+	if txn := stmt.Txns[0]; txn.TrnType != "CREDIT" || txn.FITID != "txn-005" || txn.Amount != "42.15" {
+		t.Errorf("Unexpected credit STMTTRN: %+v", txn)
+	}
+	if txn := stmt.Txns[1]; txn.TrnType != "DEBIT" || txn.FITID != "txn-001" || txn.Amount != "-5.47" ||
+		txn.Name != "Starbucks & Co" || txn.Posted != "20241213072000.000[0:GMT]" {
+		t.Errorf("Unexpected debit STMTTRN: %+v", txn)
+	}
+
+	if doc.Credit.AcctID != "acc-003" || len(doc.Credit.FITIDs) != 1 || doc.Credit.FITIDs[0] != "txn-011" {
+		t.Errorf("Unexpected credit card statement: %+v", doc.Credit)
+	}
+}
+
+func TestQIFEncoder(t *testing.T) {
+	out := encodeAll(t, FormatQIF, Options{})
+
+	for _, want := range []string{
+		"!Account\nNPersonal Checking\nTBank\n^\n!Type:Bank\n",
+		"D12/01/2024\nT42.15\nCX\nPAccountStack Bank\nMInterest Payment\nLincome\nNtxn-005\n^\n",
+		"D12/13/2024\nT-5.47\nPStarbucks & Co\n",
+		"!Account\nNRewards Credit Card\nTCCard\n^\n!Type:CCard\n",
+		"T-1299.00\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected QIF to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestMaskAmounts(t *testing.T) {
+	for _, format := range []string{FormatCSV, FormatOFX, FormatQIF} {
+		t.Run(format, func(t *testing.T) {
+			out := encodeAll(t, format, Options{MaskAmounts: true})
+			if !strings.Contains(out, MaskedAmount) {
+				t.Errorf("Expected masked amounts in %s output", format)
+			}
+			for _, amount := range []string{"42.15", "5.47", "1299.00", "5847.32"} {
+				if strings.Contains(out, amount) {
+					t.Errorf("Amount %s leaked in masked %s output", amount, format)
+				}
+			}
+		})
+	}
+}
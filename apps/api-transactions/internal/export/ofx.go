@@ -0,0 +1,202 @@
+package export
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/models"
+)
+
+// OFX identifiers for statements produced by AccountStack
+const (
+	ofxBankID       = "ACCOUNTSTACK"
+	ofxNameMaxRunes = 32 // OFX limits NAME to 32 characters
+)
+
+// ofxEncoder writes an OFX 2.2 document. Checking and savings accounts are
+// written as bank statements and credit accounts as credit card statements.
+type ofxEncoder struct {
+	w         *bufio.Writer
+	opts      Options
+	now       func() time.Time
+	started   bool
+	msgSet    string // Open message set aggregate, if any
+	account   *Account
+	statement int
+}
+
+func newOFXEncoder(w io.Writer, opts Options) *ofxEncoder {
+	return &ofxEncoder{
+		w:    bufio.NewWriter(w),
+		opts: opts,
+		now:  time.Now,
+	}
+}
+
+// start writes the OFX headers and sign-on response once
+func (e *ofxEncoder) start() {
+	if e.started {
+		return
+	}
+	e.started = true
+	fmt.Fprint(e.w, `<?xml version="1.0" encoding="UTF-8" standalone="no"?>`+"\n")
+	fmt.Fprint(e.w, `<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>`+"\n")
+	fmt.Fprint(e.w, "<OFX>\n")
+	fmt.Fprint(e.w, "  <SIGNONMSGSRSV1>\n    <SONRS>\n")
+	e.status(6)
+	e.element(6, "DTSERVER", ofxTime(e.now()))
+	e.element(6, "LANGUAGE", "ENG")
+	fmt.Fprint(e.w, "    </SONRS>\n  </SIGNONMSGSRSV1>\n")
+}
+
+// BeginAccount opens a statement for the account
+func (e *ofxEncoder) BeginAccount(account *Account) error {
+	e.start()
+	e.account = account
+	e.statement++
+
+	credit := account.Type == "credit"
+	msgSet := "BANKMSGSRSV1"
+	if credit {
+		msgSet = "CREDITCARDMSGSRSV1"
+	}
+	if e.msgSet != msgSet {
+		e.closeMsgSet()
+		fmt.Fprintf(e.w, "  <%s>\n", msgSet)
+		e.msgSet = msgSet
+	}
+
+	currency := account.Currency
+	if currency == "" {
+		currency = "USD"
+	}
+
+	if credit {
+		fmt.Fprint(e.w, "    <CCSTMTTRNRS>\n")
+		e.element(6, "TRNUID", fmt.Sprint(e.statement))
+		e.status(6)
+		fmt.Fprint(e.w, "      <CCSTMTRS>\n")
+		e.element(8, "CURDEF", currency)
+		fmt.Fprint(e.w, "        <CCACCTFROM>\n")
+		e.element(10, "ACCTID", account.ID)
+		fmt.Fprint(e.w, "        </CCACCTFROM>\n")
+	} else {
+		fmt.Fprint(e.w, "    <STMTTRNRS>\n")
+		e.element(6, "TRNUID", fmt.Sprint(e.statement))
+		e.status(6)
+		fmt.Fprint(e.w, "      <STMTRS>\n")
+		e.element(8, "CURDEF", currency)
+		fmt.Fprint(e.w, "        <BANKACCTFROM>\n")
+		e.element(10, "BANKID", ofxBankID)
+		e.element(10, "ACCTID", account.ID)
+		e.element(10, "ACCTTYPE", ofxAccountType(account.Type))
+		fmt.Fprint(e.w, "        </BANKACCTFROM>\n")
+	}
+
+	fmt.Fprint(e.w, "        <BANKTRANLIST>\n")
+	e.element(10, "DTSTART", ofxTime(account.Start))
+	e.element(10, "DTEND", ofxTime(account.End))
+	return nil
+}
+
+// Encode writes a STMTTRN block
+func (e *ofxEncoder) Encode(txn *models.Transaction) error {
+	trnType := "DEBIT"
+	if txn.Type == models.TypeCredit {
+		trnType = "CREDIT"
+	}
+	name := txn.Merchant
+	if name == "" {
+		name = txn.Description
+	}
+
+	fmt.Fprint(e.w, "          <STMTTRN>\n")
+	e.element(12, "TRNTYPE", trnType)
+	e.element(12, "DTPOSTED", ofxTime(txn.Date))
+	e.element(12, "TRNAMT", formatAmount(txn.Amount, e.opts))
+	e.element(12, "FITID", txn.ID)
+	e.element(12, "NAME", truncateRunes(name, ofxNameMaxRunes))
+	e.element(12, "MEMO", txn.Description)
+	fmt.Fprint(e.w, "          </STMTTRN>\n")
+	return nil
+}
+
+// EndAccount closes the transaction list and writes the ledger balance
+func (e *ofxEncoder) EndAccount() error {
+	fmt.Fprint(e.w, "        </BANKTRANLIST>\n")
+	fmt.Fprint(e.w, "        <LEDGERBAL>\n")
+	e.element(10, "BALAMT", formatAmount(e.account.Balance, e.opts))
+	e.element(10, "DTASOF", ofxTime(e.now()))
+	fmt.Fprint(e.w, "        </LEDGERBAL>\n")
+
+	if e.account.Type == "credit" {
+		fmt.Fprint(e.w, "      </CCSTMTRS>\n    </CCSTMTTRNRS>\n")
+	} else {
+		fmt.Fprint(e.w, "      </STMTRS>\n    </STMTTRNRS>\n")
+	}
+	e.account = nil
+	return nil
+}
+
+// Flush pushes buffered output to the underlying writer
+func (e *ofxEncoder) Flush() error {
+	return e.w.Flush()
+}
+
+// Close finishes the document and flushes
+func (e *ofxEncoder) Close() error {
+	e.start()
+	e.closeMsgSet()
+	fmt.Fprint(e.w, "</OFX>\n")
+	return e.Flush()
+}
+
+// closeMsgSet ends the open message set aggregate, if any
+func (e *ofxEncoder) closeMsgSet() {
+	if e.msgSet != "" {
+		fmt.Fprintf(e.w, "  </%s>\n", e.msgSet)
+		e.msgSet = ""
+	}
+}
+
+// status writes a successful STATUS aggregate
+func (e *ofxEncoder) status(indent int) {
+	pad := strings.Repeat(" ", indent)
+	fmt.Fprintf(e.w, "%s<STATUS>\n", pad)
+	e.element(indent+2, "CODE", "0")
+	e.element(indent+2, "SEVERITY", "INFO")
+	fmt.Fprintf(e.w, "%s</STATUS>\n", pad)
+}
+
+// element writes a single escaped element on its own line
+func (e *ofxEncoder) element(indent int, name, value string) {
+	fmt.Fprintf(e.w, "%s<%s>", strings.Repeat(" ", indent), name)
+	xml.EscapeText(e.w, []byte(value))
+	fmt.Fprintf(e.w, "</%s>\n", name)
+}
+
+// ofxTime formats a timestamp as an OFX datetime in GMT
+func ofxTime(t time.Time) string {
+	return t.UTC().Format("20060102150405.000") + "[0:GMT]"
+}
+
+// ofxAccountType maps account types to OFX ACCTTYPE values
+func ofxAccountType(accountType string) string {
+	if accountType == "savings" {
+		return "SAVINGS"
+	}
+	return "CHECKING"
+}
+
+// truncateRunes shortens s to at most n characters
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
@@ -0,0 +1,76 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/models"
+)
+
+// qifEncoder writes a Quicken Interchange Format file with one !Account
+// block per account followed by its transactions
+type qifEncoder struct {
+	w    *bufio.Writer
+	opts Options
+}
+
+func newQIFEncoder(w io.Writer, opts Options) *qifEncoder {
+	return &qifEncoder{
+		w:    bufio.NewWriter(w),
+		opts: opts,
+	}
+}
+
+// BeginAccount writes the account header and the transaction type line
+func (e *qifEncoder) BeginAccount(account *Account) error {
+	qifType := "Bank"
+	if account.Type == "credit" {
+		qifType = "CCard"
+	}
+	name := account.Name
+	if name == "" {
+		name = account.ID
+	}
+
+	fmt.Fprintf(e.w, "!Account\nN%s\nT%s\n^\n!Type:%s\n", qifLine(name), qifType, qifType)
+	return nil
+}
+
+// Encode writes a transaction record
+func (e *qifEncoder) Encode(txn *models.Transaction) error {
+	fmt.Fprintf(e.w, "D%s\n", txn.Date.UTC().Format("01/02/2006"))
+	fmt.Fprintf(e.w, "T%s\n", formatAmount(txn.Amount, e.opts))
+	if txn.Status == models.StatusCompleted {
+		fmt.Fprint(e.w, "CX\n")
+	}
+	if txn.Merchant != "" {
+		fmt.Fprintf(e.w, "P%s\n", qifLine(txn.Merchant))
+	}
+	fmt.Fprintf(e.w, "M%s\n", qifLine(txn.Description))
+	fmt.Fprintf(e.w, "L%s\n", qifLine(txn.Category))
+	fmt.Fprintf(e.w, "N%s\n", txn.ID)
+	fmt.Fprint(e.w, "^\n")
+	return nil
+}
+
+// EndAccount is a no-op; each record is terminated by ^
+func (e *qifEncoder) EndAccount() error {
+	return nil
+}
+
+// Flush pushes buffered output to the underlying writer
+func (e *qifEncoder) Flush() error {
+	return e.w.Flush()
+}
+
+// Close flushes the remaining output
+func (e *qifEncoder) Close() error {
+	return e.Flush()
+}
+
+// qifLine keeps a value on one line, since QIF fields are newline delimited
+func qifLine(s string) string {
+	return strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ").Replace(s)
+}
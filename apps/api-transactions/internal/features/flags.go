@@ -11,6 +11,7 @@ import (
 // Flags holds all feature flags for the application
 type Flags struct {
 	advancedFilters bool
+	maskAmounts     bool
 	mu              sync.RWMutex
 	logger          *logrus.Logger
 }
@@ -35,8 +36,18 @@ func Initialize(apiKey string, logger *logrus.Logger) (*Flags, error) {
 		}
 	}
 
+	// api.maskAmounts (default: false) - mask dollar amounts in exports, shared with api-accounts
+	maskAmountsStr := os.Getenv("FEATURE_MASK_AMOUNTS")
+	if maskAmountsStr != "" {
+		maskAmounts, err := strconv.ParseBool(maskAmountsStr)
+		if err == nil {
+			flags.maskAmounts = maskAmounts
+		}
+	}
+
 	logger.WithFields(logrus.Fields{
 		"advancedFilters": flags.advancedFilters,
+		"maskAmounts":     flags.maskAmounts,
 	}).Info("Feature flags initialized")
 
 	if apiKey != "" && apiKey != "dev-mode" {
@@ -72,6 +83,27 @@ func (f *Flags) SetAdvancedFilters(enabled bool) {
 	f.logger.WithField("advancedFilters", enabled).Info("Feature flag updated")
 }
 
+// ShouldMaskAmounts returns whether amounts should be masked in exports
+func (f *Flags) ShouldMaskAmounts() bool {
+	if f == nil {
+		return false
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.maskAmounts
+}
+
+// SetMaskAmounts sets the mask amounts flag (for testing/admin purposes)
+func (f *Flags) SetMaskAmounts(enabled bool) {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.maskAmounts = enabled
+	f.logger.WithField("maskAmounts", enabled).Info("Feature flag updated")
+}
+
 // Shutdown gracefully shuts down the feature management system
 func Shutdown() {
 	if flags != nil {
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/models"
+	"github.com/CB-AccountStack/AccountStack/pkg/auth"
+)
+
+// readOnlyScope is what api-accounts grants impersonation tokens
+const readOnlyScope = "accounts:read transactions:read insights:read"
+
+// doScopedRequest issues a request as userID with a token carrying scope
+func doScopedRequest(t *testing.T, router http.Handler, method, path, body, userID, scope string, act *auth.Actor) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	token, _ := issueScopedToken(t, userID, scope, act)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestScopesEnforced(t *testing.T) {
+	router := newTestRouter(t)
+	support := &auth.Actor{UserID: "user-900", Email: "support@accountstack.com"}
+	transfer := `{"fromAccountId":"acc-001","toAccountId":"acc-002","amount":1}`
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		scope  string
+		act    *auth.Actor
+		want   int
+	}{
+		{"impersonation can read", "GET", "/transactions", "", readOnlyScope, support, http.StatusOK},
+		{"impersonation can export", "GET", "/transactions/export", "", readOnlyScope, support, http.StatusOK},
+		{"impersonation cannot create", "POST", "/transactions", `{"accountId":"acc-001","amount":-1}`, readOnlyScope, support, http.StatusForbidden},
+		{"impersonation cannot transfer", "POST", "/transfers", transfer, readOnlyScope, support, http.StatusForbidden},
+		{"impersonation cannot import", "POST", "/transactions/import", "", readOnlyScope, support, http.StatusForbidden},
+		{"token without scopes", "GET", "/transactions", "", "", nil, http.StatusForbidden},
+		{"customer cannot use admin routes", "GET", "/admin/users/user-002/transactions", "", customerScope, nil, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doScopedRequest(t, router, tt.method, tt.path, tt.body, "user-001", tt.scope, tt.act)
+			if rec.Code != tt.want {
+				t.Errorf("Expected status %d, got %d: %s", tt.want, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestGetUserTransactions(t *testing.T) {
+	router := newTestRouter(t)
+
+	rec := doScopedRequest(t, router, "GET", "/admin/users/user-002/transactions?limit=50", "", "user-900", customerScope+" admin:read", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var transactions []models.Transaction
+	if err := json.NewDecoder(rec.Body).Decode(&transactions); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(transactions) == 0 {
+		t.Fatal("Expected user-002's transactions")
+	}
+	for _, txn := range transactions {
+		if txn.AccountID != "acc-004" {
+			t.Errorf("Expected only user-002's transactions, got %s on %s", txn.ID, txn.AccountID)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/services"
+	"github.com/CB-AccountStack/AccountStack/pkg/problem"
+	"github.com/sirupsen/logrus"
+)
+
+// errorStatus is how an error returned by a service is answered
+type errorStatus struct {
+	err    error
+	status int
+	detail string // sent instead of the error's message when set
+}
+
+// errorStatuses maps the errors the services return to the status of the
+// response. Errors not listed are internal and answered with 500.
+var errorStatuses = []errorStatus{
+	{services.ErrInvalidTransaction, http.StatusBadRequest, ""},
+	{services.ErrInvalidPageRequest, http.StatusBadRequest, ""},
+	{services.ErrInvalidTransfer, http.StatusBadRequest, ""},
+	{services.ErrInvalidImport, http.StatusBadRequest, ""},
+	{services.ErrTransactionNotFound, http.StatusNotFound, "Transaction not found"},
+	{services.ErrAccountNotFound, http.StatusNotFound, "Account not found"},
+	{services.ErrUnauthorized, http.StatusNotFound, "Account not found"},
+	{services.ErrReadOnlyAccess, http.StatusForbidden, ""},
+	{services.ErrImportConflict, http.StatusConflict, "Some lines were imported by another request. Run the import again"},
+	{services.ErrInsufficientFunds, http.StatusUnprocessableEntity, "Insufficient funds"},
+}
+
+// writeError answers a failed service call with a problem response. Errors in
+// errorStatuses get their status; anything else is logged and answered with
+// 500 and fallback as the detail, so internal errors are not exposed.
+func writeError(w http.ResponseWriter, r *http.Request, logger *logrus.Logger, err error, fallback string) {
+	for _, known := range errorStatuses {
+		if errors.Is(err, known.err) {
+			detail := known.detail
+			if detail == "" {
+				detail = err.Error()
+			}
+			problem.Write(w, r, known.status, detail)
+			return
+		}
+	}
+
+	logger.WithError(err).WithField("path", r.URL.Path).Error(fallback)
+	problem.Write(w, r, http.StatusInternalServerError, fallback)
+}
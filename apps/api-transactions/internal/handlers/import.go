@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/importer"
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/services"
+	"github.com/CB-AccountStack/AccountStack/pkg/middleware"
+	"github.com/CB-AccountStack/AccountStack/pkg/problem"
+	"github.com/sirupsen/logrus"
+)
+
+// maxImportSize caps the size of an uploaded statement
+const maxImportSize = 10 << 20
+
+// ImportHandler handles statement import HTTP requests
+type ImportHandler struct {
+	service *services.ImportService
+	logger  *logrus.Logger
+}
+
+// NewImportHandler creates a new import handler
+func NewImportHandler(service *services.ImportService, logger *logrus.Logger) *ImportHandler {
+	return &ImportHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ImportTransactions handles POST /transactions/import
+// The statement is sent as the raw request body, or as the "file" field of a
+// multipart/form-data upload. Supports query parameters:
+// - accountId: account to import into (required)
+// - format: csv or ofx (default: detected from the file)
+// - commit: true to store the new transactions (default: dry run)
+// - dateColumn, amountColumn, debitColumn, creditColumn, descriptionColumn,
+// merchantColumn, categoryColumn, idColumn: CSV column headers
+// - dateFormat: CSV date pattern such as DD/MM/YYYY
+//
+// The response reports every line as new, duplicate or invalid.
+//
+// IMPORTANT: Enforces user isolation - only the authenticated user's accounts can be imported into
+func (h *ImportHandler) ImportTransactions(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		h.logger.Warn("User ID not found in context")
+		problem.Write(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	query := r.URL.Query()
+	req := &services.ImportRequest{
+		AccountID: query.Get("accountId"),
+		Format:    query.Get("format"),
+	}
+	if req.AccountID == "" {
+		problem.Write(w, r, http.StatusBadRequest, "accountId is required")
+		return
+	}
+	if req.Format != "" && req.Format != importer.FormatCSV && req.Format != importer.FormatOFX {
+		problem.Write(w, r, http.StatusBadRequest, "Invalid format. Use csv or ofx")
+		return
+	}
+	if commitStr := query.Get("commit"); commitStr != "" {
+		commit, err := strconv.ParseBool(commitStr)
+		if err != nil {
+			problem.Write(w, r, http.StatusBadRequest, "Invalid commit parameter. Must be true or false")
+			return
+		}
+		req.Commit = commit
+	}
+
+	mapping := importer.DefaultCSVMapping()
+	for _, field := range importer.MappingFields {
+		if column := query.Get(field + "Column"); column != "" {
+			mapping.Set(field, column)
+		}
+	}
+	mapping.DateFormat = query.Get("dateFormat")
+	req.Mapping = &mapping
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	body, err := statementBody(r)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			problem.Write(w, r, http.StatusRequestEntityTooLarge, "Statement is too large")
+			return
+		}
+		h.logger.WithError(err).Warn("Failed to read statement upload")
+		problem.Write(w, r, http.StatusBadRequest, "Invalid upload. Send the statement as the request body or a multipart \"file\" field")
+		return
+	}
+	defer body.Close()
+
+	// Sniff the format from the start of the file when it is not given
+	reader := bufio.NewReader(body)
+	if req.Format == "" {
+		head, _ := reader.Peek(512)
+		req.Format = importer.DetectFormat(head)
+	}
+
+	report, err := h.service.ImportStatement(userID, req, reader)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			problem.Write(w, r, http.StatusRequestEntityTooLarge, "Statement is too large")
+			return
+		}
+		writeError(w, r, h.logger, err, "Failed to import statement")
+		return
+	}
+
+	status := http.StatusOK
+	if report.Committed {
+		status = http.StatusCreated
+	}
+	respondJSON(w, h.logger, status, report)
+}
+
+// statementBody returns the uploaded file, from a multipart form or the raw body
+func statementBody(r *http.Request) (io.ReadCloser, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		return r.Body, nil
+	}
+
+	if err := r.ParseMultipartForm(maxImportSize); err != nil {
+		return nil, err
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
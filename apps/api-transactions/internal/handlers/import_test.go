@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/models"
+)
+
+const bankStatementCSV = `Posted Date,Payee,Debit,Credit,Reference
+12/13/2024,Starbucks,5.47,,
+12/14/2024,Corner Bakery,12.40,,B-100
+12/15/2024,Employer,,2500.00,B-101
+`
+
+const bankStatementQuery = "accountId=acc-001&dateColumn=Posted+Date&descriptionColumn=Payee" +
+	"&debitColumn=Debit&creditColumn=Credit&idColumn=Reference"
+
+func TestImportTransactions(t *testing.T) {
+	router := newTestRouter(t)
+
+	// The default is a dry run
+	rec := doRequest(t, router, "POST", "/transactions/import?"+bankStatementQuery, bankStatementCSV, "user-001")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var report models.ImportReport
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("Failed to decode report: %v", err)
+	}
+	if report.Committed || report.Format != "csv" || report.New != 2 || report.Duplicates != 1 {
+		t.Errorf("Unexpected dry run report: %+v", report)
+	}
+
+	rec = doRequest(t, router, "POST", "/transactions/import?commit=true&"+bankStatementQuery, bankStatementCSV, "user-001")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = doRequest(t, router, "GET", "/transactions?accountId=acc-001", "", "user-001")
+	var transactions []models.Transaction
+	if err := json.NewDecoder(rec.Body).Decode(&transactions); err != nil {
+		t.Fatalf("Failed to decode transactions: %v", err)
+	}
+	if len(transactions) != 4 || transactions[0].Merchant != "Employer" || transactions[0].ExternalID != "B-101" {
+		t.Errorf("Expected the two imported transactions on acc-001, got %+v", transactions)
+	}
+}
+
+func TestImportTransactionsMultipartOFX(t *testing.T) {
+	router := newTestRouter(t)
+
+	ofx := `OFXHEADER:100
+<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS><BANKTRANLIST>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20241214<TRNAMT>-12.40<FITID>B-100<NAME>Corner Bakery</STMTTRN>
+</BANKTRANLIST></STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>`
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("file", "statement.ofx")
+	part.Write([]byte(ofx))
+	form.Close()
+
+	req := httptest.NewRequest("POST", "/transactions/import?accountId=acc-001", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	token, _ := issueTestToken(t, "user-001")
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var report models.ImportReport
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("Failed to decode report: %v", err)
+	}
+	if report.Format != "ofx" || report.New != 1 || report.Rows[0].Transaction.ExternalID != "B-100" {
+		t.Errorf("Unexpected report for detected OFX upload: %+v", report)
+	}
+}
+
+func TestImportTransactionsInvalidParameters(t *testing.T) {
+	router := newTestRouter(t)
+
+	tests := []struct {
+		name       string
+		query      string
+		body       string
+		wantStatus int
+	}{
+		{"missing account", "", bankStatementCSV, http.StatusBadRequest},
+		{"unknown format", "accountId=acc-001&format=qif", bankStatementCSV, http.StatusBadRequest},
+		{"invalid commit flag", "accountId=acc-001&commit=maybe", bankStatementCSV, http.StatusBadRequest},
+		{"unmapped columns", "accountId=acc-001", bankStatementCSV, http.StatusBadRequest},
+		{"missing account ID", "accountId=acc-404", bankStatementCSV, http.StatusNotFound},
+		{"oversized statement", "accountId=acc-001", strings.Repeat("x", maxImportSize+1), http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(t, router, "POST", "/transactions/import?"+tt.query, tt.body, "user-001")
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,400 @@
+package handlers
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/models"
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/repository"
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/services"
+	"github.com/CB-AccountStack/AccountStack/pkg/auth"
+	"github.com/CB-AccountStack/AccountStack/pkg/middleware"
+	"github.com/CB-AccountStack/AccountStack/pkg/problem"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	testDataPath = "../services/testdata"
+	// testKeyID names testSigningKey in the kid header, as api-accounts does
+	testKeyID = "test"
+)
+
+// testSigningKey stands in for the api-accounts signing key
+var testPublicKey, testSigningKey, _ = ed25519.GenerateKey(rand.Reader)
+
+// customerScope is what api-accounts grants users with the user role
+const customerScope = "accounts:read accounts:write transactions:read transactions:write insights:read insights:write"
+
+// newTestVerifier verifies tokens signed with testSigningKey
+func newTestVerifier() *auth.Verifier {
+	return auth.NewVerifier(auth.StaticKeys{testKeyID: testPublicKey})
+}
+
+// issueTestToken signs a customer access token for userID the way api-accounts does
+func issueTestToken(t *testing.T, userID string) (string, *auth.Claims) {
+	t.Helper()
+	return issueScopedToken(t, userID, customerScope, nil)
+}
+
+// issueScopedToken signs an access token for userID with the given scopes,
+// issued to act when it is set
+func issueScopedToken(t *testing.T, userID, scope string, act *auth.Actor) (string, *auth.Claims) {
+	t.Helper()
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		t.Fatalf("Failed to generate jti: %v", err)
+	}
+	now := time.Now()
+	claims := &auth.Claims{
+		UserID: userID,
+		Email:  userID + "@example.com",
+		Scope:  scope,
+		Act:    act,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        hex.EncodeToString(id),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = testKeyID
+	signed, err := token.SignedString(testSigningKey)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return signed, claims
+}
+
+// newTestRouter wires the real handlers and auth middleware over the test fixtures
+func newTestRouter(t *testing.T) *mux.Router {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	repo, err := repository.NewJSONStore(testDataPath, logger)
+	if err != nil {
+		t.Fatalf("Failed to load test data: %v", err)
+	}
+
+	transactionHandler := NewTransactionHandler(services.NewTransactionService(repo, nil, logger), logger)
+	transferHandler := NewTransferHandler(services.NewTransferService(repo, logger), logger)
+	importHandler := NewImportHandler(services.NewImportService(repo, logger), logger)
+
+	router := mux.NewRouter()
+	router.Use(middleware.AuthMiddleware(newTestVerifier(), auth.NewMemoryRevocationList(), auth.NewMemoryAPIKeyStore(), logger))
+	scoped := func(scope string, h http.HandlerFunc) http.Handler {
+		return middleware.RequireScope(scope, logger)(h)
+	}
+	router.Handle("/transactions", scoped(auth.ScopeTransactionsRead, transactionHandler.GetTransactions)).Methods("GET")
+	router.Handle("/transactions", scoped(auth.ScopeTransactionsWrite, transactionHandler.CreateTransaction)).Methods("POST")
+	router.Handle("/transactions/export", scoped(auth.ScopeTransactionsRead, transactionHandler.ExportTransactions)).Methods("GET")
+	router.Handle("/transactions/import", scoped(auth.ScopeTransactionsWrite, importHandler.ImportTransactions)).Methods("POST")
+	router.Handle("/transactions/{id}", scoped(auth.ScopeTransactionsRead, transactionHandler.GetTransactionByID)).Methods("GET")
+	router.Handle("/transfers", scoped(auth.ScopeTransactionsWrite, transferHandler.CreateTransfer)).Methods("POST")
+	router.Handle("/admin/users/{userId}/transactions", scoped(auth.ScopeAdminRead, transactionHandler.GetUserTransactions)).Methods("GET")
+	return router
+}
+
+// doRequest issues a request as userID, or anonymously when userID is empty
+func doRequest(t *testing.T, router http.Handler, method, path, body, userID string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if userID != "" {
+		token, _ := issueTestToken(t, userID)
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGetTransactionByIDIsolation(t *testing.T) {
+	router := newTestRouter(t)
+
+	tests := []struct {
+		name       string
+		userID     string
+		txnID      string
+		wantStatus int
+	}{
+		{"owner can read", "user-001", "txn-001", http.StatusOK},
+		{"second owner can read", "user-002", "txn-021", http.StatusOK},
+		{"other user's transaction", "user-002", "txn-001", http.StatusNotFound},
+		{"other user's transaction reversed", "user-001", "txn-021", http.StatusNotFound},
+		{"unknown user", "user-999", "txn-001", http.StatusNotFound},
+		{"missing transaction", "user-001", "txn-999", http.StatusNotFound},
+		{"no token", "", "txn-001", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(t, router, "GET", "/transactions/"+tt.txnID, "", tt.userID)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if rec.Code != http.StatusOK && strings.Contains(rec.Body.String(), tt.txnID) {
+				t.Errorf("Response leaked the transaction: %s", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestGetTransactionByIDIndistinguishableFromMissing(t *testing.T) {
+	router := newTestRouter(t)
+
+	foreign := doRequest(t, router, "GET", "/transactions/txn-021", "", "user-001")
+	missing := doRequest(t, router, "GET", "/transactions/txn-999", "", "user-001")
+
+	if foreign.Code != missing.Code || foreign.Body.String() != missing.Body.String() {
+		t.Errorf("Foreign and missing transactions must look the same, got %d %q and %d %q",
+			foreign.Code, foreign.Body.String(), missing.Code, missing.Body.String())
+	}
+}
+
+func TestGetTransactionsIsolation(t *testing.T) {
+	router := newTestRouter(t)
+
+	for _, path := range []string{"/transactions", "/transactions?accountId=acc-004"} {
+		t.Run(path, func(t *testing.T) {
+			rec := doRequest(t, router, "GET", path, "", "user-001")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", rec.Code)
+			}
+
+			var transactions []models.Transaction
+			if err := json.NewDecoder(rec.Body).Decode(&transactions); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			for _, txn := range transactions {
+				if txn.AccountID == "acc-004" {
+					t.Errorf("Response contains another user's transaction %s", txn.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestExportTransactionsIsolation(t *testing.T) {
+	router := newTestRouter(t)
+
+	for _, path := range []string{"/transactions/export", "/transactions/export?accountId=acc-001&format=ofx"} {
+		t.Run(path, func(t *testing.T) {
+			rec := doRequest(t, router, "GET", path, "", "user-002")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", rec.Code)
+			}
+			for _, foreign := range []string{"txn-001", "txn-011", "txn-025", "acc-001"} {
+				if strings.Contains(rec.Body.String(), foreign) {
+					t.Errorf("Export leaked %s: %s", foreign, rec.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestWriteEndpointsIsolation(t *testing.T) {
+	router := newTestRouter(t)
+
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{
+			"create on another user's account",
+			"/transactions",
+			`{"accountId":"acc-004","description":"Coffee","amount":-4.5,"category":"food_dining","type":"debit"}`,
+			http.StatusNotFound,
+		},
+		{
+			// Answered the same, so other users' accounts cannot be discovered
+			"create on a missing account",
+			"/transactions",
+			`{"accountId":"acc-999","description":"Coffee","amount":-4.5,"category":"food_dining","type":"debit"}`,
+			http.StatusNotFound,
+		},
+		{
+			"transfer out of another user's account",
+			"/transfers",
+			`{"fromAccountId":"acc-004","toAccountId":"acc-001","amount":100}`,
+			http.StatusNotFound,
+		},
+		{
+			"transfer into another user's account",
+			"/transfers",
+			`{"fromAccountId":"acc-001","toAccountId":"acc-004","amount":100}`,
+			http.StatusNotFound,
+		},
+		{
+			"import into another user's account",
+			"/transactions/import?accountId=acc-004&commit=true",
+			"date,description,amount\n2024-12-14,Coffee,-4.50\n",
+			http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(t, router, "POST", tt.path, tt.body, "user-001")
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			var body problem.Details
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || rec.Header().Get("Content-Type") != problem.ContentType {
+				t.Fatalf("Expected a problem response, got %q: %v", rec.Header().Get("Content-Type"), err)
+			}
+			if body.Status != tt.wantStatus || body.Detail != "Account not found" {
+				t.Errorf("Unexpected problem %+v", body)
+			}
+		})
+	}
+
+	// None of the rejected writes may have touched user-002's ledger
+	rec := doRequest(t, router, "GET", "/transactions", "", "user-002")
+	var transactions []models.Transaction
+	if err := json.NewDecoder(rec.Body).Decode(&transactions); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(transactions) != 1 || transactions[0].ID != "txn-021" {
+		t.Errorf("Expected user-002 to still have only txn-021, got %+v", transactions)
+	}
+}
+
+func TestRevokedTokenRejected(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	// api-accounts and this service open the same revocation database
+	path := filepath.Join(t.TempDir(), "shared.db")
+	issuer, err := auth.NewSQLiteRevocationList(path)
+	if err != nil {
+		t.Fatalf("Failed to open revocation list: %v", err)
+	}
+	defer issuer.Close()
+	revocations, err := auth.NewSQLiteRevocationList(path)
+	if err != nil {
+		t.Fatalf("Failed to open revocation list: %v", err)
+	}
+	defer revocations.Close()
+
+	router := mux.NewRouter()
+	router.Use(middleware.AuthMiddleware(newTestVerifier(), revocations, auth.NewMemoryAPIKeyStore(), logger))
+	router.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	token, claims := issueTestToken(t, "user-001")
+	ping := func() int {
+		req := httptest.NewRequest("GET", "/ping", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := ping(); code != http.StatusNoContent {
+		t.Fatalf("Expected the token to be accepted, got %d", code)
+	}
+	if err := issuer.Revoke(claims.ID, claims.ExpiresAt.Time); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	if code := ping(); code != http.StatusUnauthorized {
+		t.Errorf("Expected the revoked token to be rejected, got %d", code)
+	}
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	// api-accounts issues keys into the database this service reads
+	path := filepath.Join(t.TempDir(), "shared.db")
+	apiKeys, err := auth.NewSQLiteAPIKeyStore(path)
+	if err != nil {
+		t.Fatalf("Failed to open API keys: %v", err)
+	}
+	defer apiKeys.Close()
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	now := time.Now()
+	for _, key := range []struct {
+		id, key   string
+		expiresAt interface{}
+	}{
+		{"key-read", "ask_read", nil},
+		{"key-expired", "ask_expired", now.Add(-time.Minute).Unix()},
+	} {
+		if _, err := db.Exec(`INSERT INTO api_keys (id, user_id, name, prefix, key_hash, scope, created_at, expires_at)
+			VALUES (?, 'user-001', 'Script', ?, ?, 'transactions:read', ?, ?)`,
+			key.id, key.key, auth.HashAPIKey(key.key), now.Add(-time.Hour).Unix(), key.expiresAt); err != nil {
+			t.Fatalf("Failed to issue key: %v", err)
+		}
+	}
+
+	router := mux.NewRouter()
+	router.Use(middleware.AuthMiddleware(newTestVerifier(), auth.NewMemoryRevocationList(), apiKeys, logger))
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		if middleware.GetUserID(r) != "user-001" {
+			t.Errorf("Expected the key's user, got %s", middleware.GetUserID(r))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+	router.Handle("/ping", middleware.RequireScope(auth.ScopeTransactionsRead, logger)(http.HandlerFunc(ok))).Methods("GET")
+	router.Handle("/ping", middleware.RequireScope(auth.ScopeTransactionsWrite, logger)(http.HandlerFunc(ok))).Methods("POST")
+
+	ping := func(method, authorization string) int {
+		req := httptest.NewRequest(method, "/ping", nil)
+		req.Header.Set("Authorization", authorization)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	tests := []struct {
+		name          string
+		method        string
+		authorization string
+		want          int
+	}{
+		{"key within its scopes", "GET", "ApiKey ask_read", http.StatusNoContent},
+		{"key outside its scopes", "POST", "ApiKey ask_read", http.StatusForbidden},
+		{"expired key", "GET", "ApiKey ask_expired", http.StatusUnauthorized},
+		{"unknown key", "GET", "ApiKey ask_unknown", http.StatusUnauthorized},
+		{"key sent as a bearer token", "GET", "Bearer ask_read", http.StatusUnauthorized},
+		{"unknown scheme", "GET", "Basic ask_read", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		if code := ping(tt.method, tt.authorization); code != tt.want {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.want, code)
+		}
+	}
+
+	var lastUsed sql.NullInt64
+	if err := db.QueryRow(`SELECT last_used_at FROM api_keys WHERE id = 'key-read'`).Scan(&lastUsed); err != nil || !lastUsed.Valid {
+		t.Errorf("Expected the key's last use to be recorded, got %v (%v)", lastUsed, err)
+	}
+
+	// Revoking deletes the key in api-accounts
+	if _, err := db.Exec(`DELETE FROM api_keys WHERE id = 'key-read'`); err != nil {
+		t.Fatalf("Failed to revoke key: %v", err)
+	}
+	if code := ping("GET", "ApiKey ask_read"); code != http.StatusUnauthorized {
+		t.Errorf("Expected a revoked key to be rejected, got %d", code)
+	}
+}
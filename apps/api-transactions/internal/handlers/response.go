@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/CB-AccountStack/AccountStack/pkg/respond"
+	"github.com/sirupsen/logrus"
+)
+
+// respondJSON sends a JSON response
+func respondJSON(w http.ResponseWriter, logger *logrus.Logger, status int, data interface{}) {
+	if err := respond.JSON(w, status, data); err != nil {
+		logger.WithError(err).Error("Failed to encode response")
+	}
+}
+
+// streamWriter flushes each write to the client so large responses are
+// delivered as they are produced, and records whether anything was sent
+type streamWriter struct {
+	http.ResponseWriter
+	wrote bool
+}
+
+// Write sends p to the client immediately
+func (s *streamWriter) Write(p []byte) (int, error) {
+	s.wrote = true
+	n, err := s.ResponseWriter.Write(p)
+	if flusher, ok := s.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return n, err
+}
@@ -2,12 +2,17 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
-	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/middleware"
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/export"
 	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/models"
 	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/services"
+	"github.com/CB-AccountStack/AccountStack/pkg/middleware"
+	"github.com/CB-AccountStack/AccountStack/pkg/problem"
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 )
@@ -34,6 +39,13 @@ func NewTransactionHandler(service *services.TransactionService, logger *logrus.
 // - category: filter by category (requires advancedFilters flag)
 // - minAmount: filter by minimum amount (requires advancedFilters flag)
 // - maxAmount: filter by maximum amount (requires advancedFilters flag)
+// - q: full-text search over description and merchant, with match highlights
+// - limit: page size, 1-200 (default: all matching transactions)
+// - cursor: opaque cursor taken from the previous page's Link header
+// - sort: date (default), amount or merchant
+// - order: desc (default) or asc
+//
+// When more results are available a Link header with rel="next" is returned.
 //
 // IMPORTANT: Enforces user isolation - only returns transactions for the authenticated user's accounts
 func (h *TransactionHandler) GetTransactions(w http.ResponseWriter, r *http.Request) {
@@ -41,15 +53,135 @@ func (h *TransactionHandler) GetTransactions(w http.ResponseWriter, r *http.Requ
 	userID := middleware.GetUserID(r)
 	if userID == "" {
 		h.logger.Warn("User ID not found in context")
-		h.respondError(w, http.StatusUnauthorized, "Unauthorized")
+		problem.Write(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	h.listTransactions(w, r, userID)
+}
+
+// GetUserTransactions handles GET /admin/users/{userId}/transactions, which
+// lets support list any user's transactions. It takes the same query
+// parameters as GET /transactions.
+func (h *TransactionHandler) GetUserTransactions(w http.ResponseWriter, r *http.Request) {
+	h.listTransactions(w, r, mux.Vars(r)["userId"])
+}
+
+// listTransactions writes the page of userID's transactions selected by the
+// request's query parameters
+func (h *TransactionHandler) listTransactions(w http.ResponseWriter, r *http.Request, userID string) {
+	filters, ok := h.parseFilters(w, r)
+	if !ok {
 		return
 	}
 
-	// Parse query parameters
+	query := r.URL.Query()
+
+	// Parse pagination and sorting
+	pageRequest := &models.PageRequest{
+		Sort:   query.Get("sort"),
+		Order:  query.Get("order"),
+		Cursor: query.Get("cursor"),
+	}
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			h.logger.WithField("limit", limitStr).Warn("Invalid limit parameter")
+			problem.Write(w, r, http.StatusBadRequest, fmt.Sprintf("Invalid limit. Must be between 1 and %d", models.MaxPageSize))
+			return
+		}
+		pageRequest.Limit = limit
+	}
+
+	// Get transactions with filters (user isolation enforced in service layer)
+	page, err := h.service.ListTransactions(userID, filters, pageRequest)
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to retrieve transactions")
+		return
+	}
+
+	if page.NextCursor != "" {
+		w.Header().Set("Link", nextLink(r, page.NextCursor))
+	}
+
+	if filters.Query != "" {
+		results := make([]*models.SearchResult, len(page.Transactions))
+		for i, txn := range page.Transactions {
+			results[i] = models.NewSearchResult(txn, filters.Query)
+		}
+		respondJSON(w, h.logger, http.StatusOK, results)
+		return
+	}
+
+	respondJSON(w, h.logger, http.StatusOK, page.Transactions)
+}
+
+// nextLink builds an RFC 8288 Link header pointing at the next page, keeping
+// every other query parameter of the current request
+func nextLink(r *http.Request, cursor string) string {
+	next := *r.URL
+	params := next.Query()
+	params.Set("cursor", cursor)
+	next.RawQuery = params.Encode()
+	return fmt.Sprintf("<%s>; rel=\"next\"", next.RequestURI())
+}
+
+// ExportTransactions handles GET /transactions/export
+// Accepts the same filters as GET /transactions plus:
+// - format: csv (default), ofx or qif
+//
+// The file is streamed to the client as it is generated. Amounts are masked
+// when the maskAmounts flag is enabled.
+//
+// IMPORTANT: Enforces user isolation - only exports the authenticated user's accounts
+func (h *TransactionHandler) ExportTransactions(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		h.logger.Warn("User ID not found in context")
+		problem.Write(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	formatName := r.URL.Query().Get("format")
+	if formatName == "" {
+		formatName = export.FormatCSV
+	}
+	format, ok := export.Formats[formatName]
+	if !ok {
+		problem.Write(w, r, http.StatusBadRequest, "Invalid format. Use csv, ofx or qif")
+		return
+	}
+
+	filters, ok := h.parseFilters(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="transactions.%s"`, format.Extension))
+
+	stream := &streamWriter{ResponseWriter: w}
+	if err := h.service.ExportTransactions(userID, filters, formatName, stream); err != nil {
+		h.logger.WithError(err).WithFields(logrus.Fields{
+			"userId": userID,
+			"format": formatName,
+		}).Error("Failed to export transactions")
+		// Once streaming has begun the status is already sent and the body is truncated
+		if !stream.wrote {
+			w.Header().Del("Content-Disposition")
+			problem.Write(w, r, http.StatusInternalServerError, "Failed to export transactions")
+		}
+	}
+}
+
+// parseFilters reads the filter query parameters shared by GET /transactions and
+// GET /transactions/export. On invalid input it writes a 400 and returns false.
+func (h *TransactionHandler) parseFilters(w http.ResponseWriter, r *http.Request) (*models.TransactionFilters, bool) {
 	query := r.URL.Query()
 
 	filters := &models.TransactionFilters{
 		AccountID: query.Get("accountId"),
+		Query:     strings.TrimSpace(query.Get("q")),
 	}
 
 	// Parse date filters
@@ -57,8 +189,8 @@ func (h *TransactionHandler) GetTransactions(w http.ResponseWriter, r *http.Requ
 		startDate, err := services.ParseDateParam(startDateStr)
 		if err != nil {
 			h.logger.WithError(err).Warn("Invalid startDate parameter")
-			h.respondError(w, http.StatusBadRequest, "Invalid startDate format. Use ISO 8601 (YYYY-MM-DD or RFC3339)")
-			return
+			problem.Write(w, r, http.StatusBadRequest, "Invalid startDate format. Use ISO 8601 (YYYY-MM-DD or RFC3339)")
+			return nil, false
 		}
 		filters.StartDate = startDate
 	}
@@ -67,8 +199,8 @@ func (h *TransactionHandler) GetTransactions(w http.ResponseWriter, r *http.Requ
 		endDate, err := services.ParseDateParam(endDateStr)
 		if err != nil {
 			h.logger.WithError(err).Warn("Invalid endDate parameter")
-			h.respondError(w, http.StatusBadRequest, "Invalid endDate format. Use ISO 8601 (YYYY-MM-DD or RFC3339)")
-			return
+			problem.Write(w, r, http.StatusBadRequest, "Invalid endDate format. Use ISO 8601 (YYYY-MM-DD or RFC3339)")
+			return nil, false
 		}
 		filters.EndDate = endDate
 	}
@@ -83,8 +215,8 @@ func (h *TransactionHandler) GetTransactions(w http.ResponseWriter, r *http.Requ
 		minAmount, err := strconv.ParseFloat(minAmountStr, 64)
 		if err != nil {
 			h.logger.WithError(err).Warn("Invalid minAmount parameter")
-			h.respondError(w, http.StatusBadRequest, "Invalid minAmount format. Must be a number")
-			return
+			problem.Write(w, r, http.StatusBadRequest, "Invalid minAmount format. Must be a number")
+			return nil, false
 		}
 		filters.MinAmount = &minAmount
 	}
@@ -93,53 +225,93 @@ func (h *TransactionHandler) GetTransactions(w http.ResponseWriter, r *http.Requ
 		maxAmount, err := strconv.ParseFloat(maxAmountStr, 64)
 		if err != nil {
 			h.logger.WithError(err).Warn("Invalid maxAmount parameter")
-			h.respondError(w, http.StatusBadRequest, "Invalid maxAmount format. Must be a number")
-			return
+			problem.Write(w, r, http.StatusBadRequest, "Invalid maxAmount format. Must be a number")
+			return nil, false
 		}
 		filters.MaxAmount = &maxAmount
 	}
 
-	// Get transactions with filters (user isolation enforced in service layer)
-	transactions, err := h.service.GetTransactions(userID, filters)
-	if err != nil {
-		h.logger.WithError(err).Error("Failed to retrieve transactions")
-		h.respondError(w, http.StatusInternalServerError, "Failed to retrieve transactions")
-		return
-	}
-
-	h.respondJSON(w, http.StatusOK, transactions)
+	return filters, true
 }
 
 // GetTransactionByID handles GET /transactions/{id}
+//
+// IMPORTANT: Enforces user isolation - transactions on other users' accounts
+// return 404, the same as IDs that do not exist
 func (h *TransactionHandler) GetTransactionByID(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		h.logger.Warn("User ID not found in context")
+		problem.Write(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
 	vars := mux.Vars(r)
 	txnID := vars["id"]
 
 	if txnID == "" {
-		h.respondError(w, http.StatusBadRequest, "Transaction ID is required")
+		problem.Write(w, r, http.StatusBadRequest, "Transaction ID is required")
 		return
 	}
 
-	transaction, err := h.service.GetTransactionByID(txnID)
+	transaction, err := h.service.GetTransactionByID(userID, txnID)
 	if err != nil {
-		h.logger.WithError(err).WithField("txnId", txnID).Warn("Transaction not found")
-		h.respondError(w, http.StatusNotFound, "Transaction not found")
+		writeError(w, r, h.logger, err, "Failed to retrieve transaction")
 		return
 	}
 
-	h.respondJSON(w, http.StatusOK, transaction)
+	respondJSON(w, h.logger, http.StatusOK, transaction)
 }
 
-// respondJSON sends a JSON response
-func (h *TransactionHandler) respondJSON(w http.ResponseWriter, status int, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	if err := json.NewEncoder(w).Encode(data); err != nil {
-		h.logger.WithError(err).Error("Failed to encode response")
-	}
+// CreateTransactionRequest represents the body of POST /transactions
+type CreateTransactionRequest struct {
+	AccountID   string     `json:"accountId"`
+	Date        *time.Time `json:"date,omitempty"`
+	Description string     `json:"description"`
+	Amount      float64    `json:"amount"`
+	Category    string     `json:"category"`
+	Merchant    string     `json:"merchant"`
+	Status      string     `json:"status,omitempty"`
+	Type        string     `json:"type"`
 }
 
-// respondError sends an error response
-func (h *TransactionHandler) respondError(w http.ResponseWriter, status int, message string) {
-	h.respondJSON(w, status, map[string]string{"error": message})
+// CreateTransaction handles POST /transactions
+// Credits must have a positive amount and debits a negative amount.
+// The account must belong to the authenticated user.
+func (h *TransactionHandler) CreateTransaction(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		h.logger.Warn("User ID not found in context")
+		problem.Write(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req CreateTransactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WithError(err).Warn("Failed to decode transaction request")
+		problem.Write(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	txn := &models.Transaction{
+		AccountID:   req.AccountID,
+		Description: req.Description,
+		Amount:      req.Amount,
+		Category:    req.Category,
+		Merchant:    req.Merchant,
+		Status:      req.Status,
+		Type:        req.Type,
+	}
+	if req.Date != nil {
+		txn.Date = req.Date.UTC()
+	}
+
+	created, err := h.service.CreateTransaction(userID, txn)
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to create transaction")
+		return
+	}
+
+	w.Header().Set("Location", "/transactions/"+created.ID)
+	respondJSON(w, h.logger, http.StatusCreated, created)
 }
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/models"
+)
+
+var nextLinkPattern = regexp.MustCompile(`^<(.+)>; rel="next"$`)
+
+func TestGetTransactionsPagination(t *testing.T) {
+	router := newTestRouter(t)
+
+	var ids []string
+	path := "/transactions?limit=3&sort=amount&order=asc"
+	for path != "" {
+		rec := doRequest(t, router, "GET", path, "", "user-001")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+
+		var transactions []models.Transaction
+		if err := json.NewDecoder(rec.Body).Decode(&transactions); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		for _, txn := range transactions {
+			ids = append(ids, txn.ID)
+		}
+
+		path = ""
+		if link := rec.Header().Get("Link"); link != "" {
+			match := nextLinkPattern.FindStringSubmatch(link)
+			if match == nil {
+				t.Fatalf("Malformed Link header: %s", link)
+			}
+			path = match[1]
+		}
+	}
+
+	want := []string{"txn-011", "txn-025", "txn-001", "txn-005"}
+	if len(ids) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, ids)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Errorf("Expected %v, got %v", want, ids)
+			break
+		}
+	}
+}
+
+func TestGetTransactionsInvalidPagination(t *testing.T) {
+	router := newTestRouter(t)
+
+	for _, query := range []string{"limit=0", "limit=abc", "limit=201", "sort=category", "order=up", "cursor=garbage"} {
+		t.Run(query, func(t *testing.T) {
+			rec := doRequest(t, router, "GET", "/transactions?"+query, "", "user-001")
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestGetTransactionsSearchHighlights(t *testing.T) {
+	router := newTestRouter(t)
+
+	rec := doRequest(t, router, "GET", "/transactions?q=gei", "", "user-001")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var results []struct {
+		ID         string `json:"id"`
+		Highlights map[string][]struct {
+			Text  string `json:"text"`
+			Match bool   `json:"match"`
+		} `json:"highlights"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&results); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != "txn-025" {
+		t.Fatalf("Expected only txn-025, got %+v", results)
+	}
+
+	for _, field := range []string{"description", "merchant"} {
+		segments := results[0].Highlights[field]
+		if len(segments) == 0 || segments[0].Text != "Geico" || !segments[0].Match {
+			t.Errorf("Expected %s to highlight Geico, got %+v", field, segments)
+		}
+	}
+}
+
+func TestExportTransactions(t *testing.T) {
+	router := newTestRouter(t)
+
+	tests := []struct {
+		query       string
+		contentType string
+		filename    string
+		contains    string
+	}{
+		{"", "text/csv; charset=utf-8", "transactions.csv", "txn-025,acc-001,"},
+		{"?format=ofx", "application/x-ofx", "transactions.ofx", "<FITID>txn-025</FITID>"},
+		{"?format=qif&accountId=acc-003", "application/qif", "transactions.qif", "!Type:CCard"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			rec := doRequest(t, router, "GET", "/transactions/export"+tt.query, "", "user-001")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Expected Content-Type %q, got %q", tt.contentType, got)
+			}
+			if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename="`+tt.filename+`"` {
+				t.Errorf("Unexpected Content-Disposition %q", got)
+			}
+			if !strings.Contains(rec.Body.String(), tt.contains) {
+				t.Errorf("Expected body to contain %q, got:\n%s", tt.contains, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestExportTransactionsInvalidParameters(t *testing.T) {
+	router := newTestRouter(t)
+
+	for _, query := range []string{"format=xlsx", "startDate=yesterday", "minAmount=lots"} {
+		t.Run(query, func(t *testing.T) {
+			rec := doRequest(t, router, "GET", "/transactions/export?"+query, "", "user-001")
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", rec.Code)
+			}
+			if rec.Header().Get("Content-Disposition") != "" {
+				t.Error("Error responses must not be served as attachments")
+			}
+		})
+	}
+}
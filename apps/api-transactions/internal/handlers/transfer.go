@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/models"
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/services"
+	"github.com/CB-AccountStack/AccountStack/pkg/middleware"
+	"github.com/CB-AccountStack/AccountStack/pkg/problem"
+	"github.com/sirupsen/logrus"
+)
+
+// TransferHandler handles transfer-related HTTP requests
+type TransferHandler struct {
+	service *services.TransferService
+	logger  *logrus.Logger
+}
+
+// NewTransferHandler creates a new transfer handler
+func NewTransferHandler(service *services.TransferService, logger *logrus.Logger) *TransferHandler {
+	return &TransferHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// CreateTransferRequest represents the body of POST /transfers
+type CreateTransferRequest struct {
+	FromAccountID string  `json:"fromAccountId"`
+	ToAccountID   string  `json:"toAccountId"`
+	Amount        float64 `json:"amount"`
+	Description   string  `json:"description,omitempty"`
+}
+
+// CreateTransfer handles POST /transfers
+// Both accounts must belong to the authenticated user. The source account may
+// not go below zero, or below its credit limit for credit accounts.
+func (h *TransferHandler) CreateTransfer(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		h.logger.Warn("User ID not found in context")
+		problem.Write(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req CreateTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WithError(err).Warn("Failed to decode transfer request")
+		problem.Write(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	transfer, err := h.service.CreateTransfer(userID, &models.Transfer{
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        req.Amount,
+		Description:   req.Description,
+	})
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to create transfer")
+		return
+	}
+
+	respondJSON(w, h.logger, http.StatusCreated, transfer)
+}
@@ -0,0 +1,313 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CSVMapping names the header of the column holding each field. Amounts are
+// read either from one signed Amount column or from separate Debit and Credit
+// columns holding unsigned values.
+type CSVMapping struct {
+	Date        string
+	Amount      string
+	Debit       string
+	Credit      string
+	Description string
+	Merchant    string
+	Category    string
+	ID          string // Column with the bank's unique ID for the line, used as the FITID
+	DateFormat  string // Pattern such as DD/MM/YYYY; empty accepts ISO 8601 and MM/DD/YYYY
+}
+
+// DefaultCSVMapping matches the columns written by GET /transactions/export,
+// so exported files can be imported again
+func DefaultCSVMapping() CSVMapping {
+	return CSVMapping{
+		Date:        "date",
+		Amount:      "amount",
+		Description: "description",
+		Merchant:    "merchant",
+		Category:    "category",
+		ID:          "id",
+	}
+}
+
+// MappingFields lists the field names accepted by Set
+var MappingFields = []string{"date", "amount", "debit", "credit", "description", "merchant", "category", "id"}
+
+// Set maps a field, named as in MappingFields, to a column header
+func (m *CSVMapping) Set(field, column string) error {
+	switch field {
+	case "date":
+		m.Date = column
+	case "amount":
+		m.Amount = column
+	case "debit":
+		m.Debit = column
+	case "credit":
+		m.Credit = column
+	case "description":
+		m.Description = column
+	case "merchant":
+		m.Merchant = column
+	case "category":
+		m.Category = column
+	case "id":
+		m.ID = column
+	default:
+		return fmt.Errorf("unknown column mapping field %q", field)
+	}
+	return nil
+}
+
+// defaultDateLayouts are tried in order when no DateFormat is given
+var defaultDateLayouts = []string{time.RFC3339, "2006-1-2", "1/2/2006", "2006/1/2"}
+
+// dateFormatReplacer turns a DateFormat pattern into a Go layout. Day and
+// month accept one or two digits.
+var dateFormatReplacer = strings.NewReplacer("YYYY", "2006", "YY", "06", "MM", "1", "DD", "2")
+
+// csvColumns holds the header index of each mapped field, or -1 if unmapped
+type csvColumns struct {
+	date, amount, debit, credit, description, merchant, category, id int
+}
+
+// parseCSV reads a statement with a header row
+func parseCSV(r io.Reader, mapping *CSVMapping) ([]*Record, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	cols, err := resolveColumns(header, mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	layouts := defaultDateLayouts
+	if mapping.DateFormat != "" {
+		layouts = []string{dateFormatReplacer.Replace(mapping.DateFormat)}
+	}
+
+	var records []*Record
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if isBlank(row) {
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+		records = append(records, csvRecord(line, row, cols, layouts))
+	}
+
+	return records, nil
+}
+
+// resolveColumns finds each mapped header, ignoring case and surrounding spaces
+func resolveColumns(header []string, mapping *CSVMapping) (*csvColumns, error) {
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		key := strings.ToLower(strings.TrimSpace(name))
+		if _, exists := index[key]; !exists {
+			index[key] = i
+		}
+	}
+
+	lookup := func(column string, required bool) (int, error) {
+		if column == "" {
+			return -1, nil
+		}
+		i, ok := index[strings.ToLower(strings.TrimSpace(column))]
+		if !ok {
+			if required {
+				return -1, fmt.Errorf("column %q not found in header", column)
+			}
+			return -1, nil
+		}
+		return i, nil
+	}
+
+	if mapping.Date == "" {
+		return nil, errors.New("a date column is required")
+	}
+	if mapping.Amount == "" && mapping.Debit == "" && mapping.Credit == "" {
+		return nil, errors.New("an amount column, or debit and credit columns, is required")
+	}
+	if mapping.Description == "" && mapping.Merchant == "" {
+		return nil, errors.New("a description or merchant column is required")
+	}
+
+	cols := &csvColumns{}
+	var err error
+	// Explicitly split debit and credit columns take precedence over a
+	// default amount column that may not exist in this file
+	split := mapping.Debit != "" || mapping.Credit != ""
+	for _, c := range []struct {
+		dest     *int
+		column   string
+		required bool
+	}{
+		{&cols.date, mapping.Date, true},
+		{&cols.amount, mapping.Amount, !split},
+		{&cols.debit, mapping.Debit, true},
+		{&cols.credit, mapping.Credit, true},
+		{&cols.description, mapping.Description, mapping.Merchant == ""},
+		{&cols.merchant, mapping.Merchant, mapping.Description == ""},
+		{&cols.category, mapping.Category, false},
+		{&cols.id, mapping.ID, false},
+	} {
+		if *c.dest, err = lookup(c.column, c.required); err != nil {
+			return nil, err
+		}
+	}
+	if split {
+		cols.amount = -1
+	}
+	if cols.description < 0 && cols.merchant < 0 {
+		return nil, fmt.Errorf("column %q not found in header", mapping.Description)
+	}
+
+	return cols, nil
+}
+
+// csvRecord converts a data row into a record
+func csvRecord(line int, row []string, cols *csvColumns, layouts []string) *Record {
+	field := func(i int) string {
+		if i < 0 || i >= len(row) {
+			return ""
+		}
+		return unescapeFormula(strings.TrimSpace(row[i]))
+	}
+
+	record := &Record{
+		Line:        line,
+		FITID:       field(cols.id),
+		Description: field(cols.description),
+		Merchant:    field(cols.merchant),
+		Category:    field(cols.category),
+	}
+
+	date, err := parseDate(field(cols.date), layouts)
+	if err != nil {
+		record.Err = err
+		return record
+	}
+	record.Date = date
+
+	if cols.amount >= 0 {
+		record.Amount, err = parseAmount(field(cols.amount))
+	} else {
+		record.Amount, err = splitAmount(field(cols.debit), field(cols.credit))
+	}
+	if err != nil {
+		record.Err = err
+	}
+
+	return record
+}
+
+// parseDate tries each layout in turn; dates without a zone are taken as UTC
+func parseDate(value string, layouts []string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, errors.New("date is empty")
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised date %q", value)
+}
+
+// parseAmount reads a signed amount, allowing currency symbols, thousands
+// separators and accounting-style parentheses for negatives
+func parseAmount(value string) (float64, error) {
+	cleaned := strings.NewReplacer("$", "", "£", "", "€", "", ",", "", " ", "").Replace(value)
+	negative := false
+	if strings.HasPrefix(cleaned, "(") && strings.HasSuffix(cleaned, ")") {
+		negative = true
+		cleaned = cleaned[1 : len(cleaned)-1]
+	}
+	if cleaned == "" {
+		return 0, errors.New("amount is empty")
+	}
+
+	amount, err := strconv.ParseFloat(cleaned, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unrecognised amount %q", value)
+	}
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+// splitAmount combines unsigned debit and credit cells into a signed amount
+func splitAmount(debit, credit string) (float64, error) {
+	if debit == "" && credit == "" {
+		return 0, errors.New("amount is empty")
+	}
+
+	var amount float64
+	if debit != "" {
+		d, err := parseAmount(debit)
+		if err != nil {
+			return 0, err
+		}
+		if d < 0 {
+			d = -d
+		}
+		amount -= d
+	}
+	if credit != "" {
+		c, err := parseAmount(credit)
+		if err != nil {
+			return 0, err
+		}
+		if c < 0 {
+			c = -c
+		}
+		amount += c
+	}
+	return amount, nil
+}
+
+// unescapeFormula removes the quote that CSV exports add in front of text
+// that a spreadsheet would otherwise evaluate as a formula
+func unescapeFormula(s string) string {
+	if len(s) > 1 && s[0] == '\'' && strings.ContainsRune("=+-@\t\r", rune(s[1])) {
+		return s[1:]
+	}
+	return s
+}
+
+// isBlank reports whether every cell of a row is empty
+func isBlank(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
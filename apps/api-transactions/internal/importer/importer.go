@@ -0,0 +1,56 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Import formats accepted by POST /transactions/import and the import command
+const (
+	FormatCSV = "csv"
+	FormatOFX = "ofx"
+)
+
+// Record is one line of a bank statement. Lines that could not be parsed are
+// still returned, with Err set, so they can be reported back to the user.
+type Record struct {
+	Line        int // CSV row number (the header is row 1) or position of the OFX STMTTRN
+	FITID       string
+	Date        time.Time
+	Amount      float64
+	Description string
+	Merchant    string
+	Category    string
+	Err         error
+}
+
+// Parse reads every record of a statement in the given format. The mapping is
+// only used for CSV; nil selects DefaultCSVMapping. An error is returned only
+// when the file as a whole is unreadable, such as a CSV header missing a
+// mapped column.
+func Parse(format string, r io.Reader, mapping *CSVMapping) ([]*Record, error) {
+	switch format {
+	case FormatCSV:
+		if mapping == nil {
+			m := DefaultCSVMapping()
+			mapping = &m
+		}
+		return parseCSV(r, mapping)
+	case FormatOFX:
+		return parseOFX(r)
+	default:
+		return nil, fmt.Errorf("unsupported import format %q", format)
+	}
+}
+
+// DetectFormat guesses the format of a statement from its first bytes. OFX
+// files start with an SGML or XML header; anything else is treated as CSV.
+func DetectFormat(head []byte) string {
+	head = bytes.ToUpper(bytes.TrimLeft(head, "\ufeff \t\r\n"))
+	if bytes.HasPrefix(head, []byte("OFXHEADER")) || bytes.Contains(head, []byte("<OFX")) {
+		return FormatOFX
+	}
+	return FormatCSV
+}
@@ -0,0 +1,237 @@
+package importer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/export"
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/models"
+)
+
+func TestParseCSVMapping(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		mapping    func(m *CSVMapping)
+		wantAmount []float64
+		wantDate   time.Time
+		wantErrs   int
+	}{
+		{
+			name:       "default columns",
+			input:      "id,date,description,merchant,amount\nB1,2024-12-14,Coffee,Starbucks,-5.47\n",
+			wantAmount: []float64{-5.47},
+			wantDate:   time.Date(2024, 12, 14, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "renamed columns and custom date format",
+			input: "Posted,Details,Value\n14/12/2024,Starbucks,\"(1,005.47)\"\n",
+			mapping: func(m *CSVMapping) {
+				m.Set("date", "Posted")
+				m.Set("description", "Details")
+				m.Set("amount", "Value")
+				m.DateFormat = "DD/MM/YYYY"
+			},
+			wantAmount: []float64{-1005.47},
+			wantDate:   time.Date(2024, 12, 14, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "split debit and credit columns",
+			input: "Date,Payee,Debit,Credit\n12/14/2024,Starbucks,$5.47,\n12/15/2024,Employer,,2500\n",
+			mapping: func(m *CSVMapping) {
+				m.Set("date", "Date")
+				m.Set("merchant", "Payee")
+				m.Set("debit", "Debit")
+				m.Set("credit", "Credit")
+			},
+			wantAmount: []float64{-5.47, 2500},
+			wantDate:   time.Date(2024, 12, 14, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "bad lines are reported, not fatal",
+			input:      "date,description,amount\nyesterday,Coffee,-5\n2024-12-14,Coffee,***.**\n2024-12-14,Coffee,-5\n",
+			wantAmount: []float64{0, 0, -5},
+			wantErrs:   2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapping := DefaultCSVMapping()
+			if tt.mapping != nil {
+				tt.mapping(&mapping)
+			}
+
+			records, err := Parse(FormatCSV, strings.NewReader(tt.input), &mapping)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			if len(records) != len(tt.wantAmount) {
+				t.Fatalf("Expected %d records, got %d", len(tt.wantAmount), len(records))
+			}
+
+			errs := 0
+			for i, record := range records {
+				if record.Err != nil {
+					errs++
+					continue
+				}
+				if record.Amount != tt.wantAmount[i] {
+					t.Errorf("Record %d: expected amount %v, got %v", i, tt.wantAmount[i], record.Amount)
+				}
+			}
+			if errs != tt.wantErrs {
+				t.Errorf("Expected %d invalid records, got %d", tt.wantErrs, errs)
+			}
+			if !tt.wantDate.IsZero() && !records[0].Date.Equal(tt.wantDate) {
+				t.Errorf("Expected date %v, got %v", tt.wantDate, records[0].Date)
+			}
+		})
+	}
+}
+
+func TestParseCSVMissingColumn(t *testing.T) {
+	mapping := DefaultCSVMapping()
+	mapping.Set("date", "Posted Date")
+
+	_, err := Parse(FormatCSV, strings.NewReader("date,description,amount\n"), &mapping)
+	if err == nil || !strings.Contains(err.Error(), "Posted Date") {
+		t.Errorf("Expected an error naming the missing column, got %v", err)
+	}
+}
+
+func TestParseCSVLineNumbers(t *testing.T) {
+	input := "date,description,amount\n2024-12-14,\"Multi\nline\",-5\n\n2024-12-15,Coffee,-3\n"
+
+	records, err := Parse(FormatCSV, strings.NewReader(input), nil)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(records) != 2 || records[0].Line != 2 || records[1].Line != 5 {
+		t.Errorf("Expected records on lines 2 and 5, got %+v", records)
+	}
+}
+
+const sgmlStatement = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+
+<OFX>
+<BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<BANKTRANLIST>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20241214093000.000[-5:EST]
+<TRNAMT>-12.40
+<FITID>20241214-1
+<NAME>Corner Bakery &amp; Cafe
+<MEMO>POS PURCHASE
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20241215
+<TRNAMT>2500,00
+<FITID>20241215-1
+<NAME>EMPLOYER PAYROLL
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<TRNAMT>-1.00
+<NAME>NO DATE
+</STMTTRN>
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`
+
+func TestParseOFXSGML(t *testing.T) {
+	records, err := Parse(FormatOFX, strings.NewReader(sgmlStatement), nil)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 records, got %d", len(records))
+	}
+
+	first := records[0]
+	if first.FITID != "20241214-1" || first.Amount != -12.40 {
+		t.Errorf("Unexpected first record: %+v", first)
+	}
+	if first.Merchant != "Corner Bakery & Cafe" || first.Description != "POS PURCHASE" {
+		t.Errorf("Expected unescaped NAME and MEMO, got %q and %q", first.Merchant, first.Description)
+	}
+	if want := time.Date(2024, 12, 14, 14, 30, 0, 0, time.UTC); !first.Date.Equal(want) {
+		t.Errorf("Expected DTPOSTED %v, got %v", want, first.Date)
+	}
+
+	if records[1].Amount != 2500 || records[1].Description != "EMPLOYER PAYROLL" {
+		t.Errorf("Unexpected second record: %+v", records[1])
+	}
+	if records[2].Err == nil {
+		t.Error("Expected the record without DTPOSTED to be invalid")
+	}
+}
+
+func TestParseOFXRejectsOtherFiles(t *testing.T) {
+	if _, err := Parse(FormatOFX, strings.NewReader("date,amount\n"), nil); err == nil {
+		t.Error("Expected a non-OFX file to be rejected")
+	}
+	if _, err := Parse(FormatOFX, strings.NewReader("<OFX><STMTTRN><TRNAMT>1"), nil); err == nil {
+		t.Error("Expected a truncated OFX file to be rejected")
+	}
+}
+
+// TestExportRoundTrip checks that files produced by GET /transactions/export
+// import back to the same transactions
+func TestExportRoundTrip(t *testing.T) {
+	txns := []*models.Transaction{
+		{ID: "txn-001", AccountID: "acc-001", Date: time.Date(2024, 12, 13, 7, 20, 0, 0, time.UTC),
+			Description: "=Starbucks Coffee", Merchant: "Starbucks", Category: "food_dining",
+			Amount: -5.47, Type: models.TypeDebit, Status: models.StatusCompleted},
+		{ID: "txn-002", AccountID: "acc-001", Date: time.Date(2024, 12, 14, 9, 0, 0, 0, time.UTC),
+			Description: "Payroll", Merchant: "Employer", Category: "income",
+			Amount: 2500, Type: models.TypeCredit, Status: models.StatusCompleted},
+	}
+
+	for _, format := range []string{FormatCSV, FormatOFX} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			enc, err := export.NewEncoder(format, &buf, export.Options{})
+			if err != nil {
+				t.Fatalf("NewEncoder failed: %v", err)
+			}
+			enc.BeginAccount(&export.Account{ID: "acc-001", Type: "checking"})
+			for _, txn := range txns {
+				enc.Encode(txn)
+			}
+			enc.EndAccount()
+			if err := enc.Close(); err != nil {
+				t.Fatalf("Close failed: %v", err)
+			}
+
+			if got := DetectFormat(buf.Bytes()); got != format {
+				t.Errorf("Expected format %s to be detected, got %s", format, got)
+			}
+
+			records, err := Parse(format, &buf, nil)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			if len(records) != len(txns) {
+				t.Fatalf("Expected %d records, got %d", len(txns), len(records))
+			}
+			for i, record := range records {
+				want := txns[i]
+				if record.Err != nil {
+					t.Fatalf("Record %d invalid: %v", i, record.Err)
+				}
+				if record.FITID != want.ID || !record.Date.Equal(want.Date) || record.Amount != want.Amount ||
+					record.Description != want.Description || record.Merchant != want.Merchant {
+					t.Errorf("Record %d: expected %+v, got %+v", i, want, record)
+				}
+			}
+		})
+	}
+}
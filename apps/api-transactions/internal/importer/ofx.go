@@ -0,0 +1,145 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseOFX reads the STMTTRN records of an OFX statement. Both OFX 1.x (SGML,
+// where leaf elements have no closing tag) and OFX 2.x (XML) are accepted, so
+// the document is scanned tag by tag rather than decoded as XML.
+func parseOFX(r io.Reader) ([]*Record, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	doc := string(data)
+	if !strings.Contains(strings.ToUpper(doc), "<OFX>") {
+		return nil, errors.New("not an OFX document: missing <OFX> element")
+	}
+
+	var records []*Record
+	var fields map[string]string // Elements of the open STMTTRN, if any
+	for pos := 0; ; {
+		open := strings.IndexByte(doc[pos:], '<')
+		if open < 0 {
+			break
+		}
+		open += pos
+		end := strings.IndexByte(doc[open:], '>')
+		if end < 0 {
+			break
+		}
+		end += open
+		tag := strings.ToUpper(strings.TrimSpace(doc[open+1 : end]))
+
+		pos = end + 1
+		next := strings.IndexByte(doc[pos:], '<')
+		if next < 0 {
+			next = len(doc) - pos
+		}
+		value := strings.TrimSpace(doc[pos : pos+next])
+
+		switch {
+		case tag == "STMTTRN":
+			fields = make(map[string]string)
+		case tag == "/STMTTRN":
+			if fields != nil {
+				records = append(records, ofxRecord(len(records)+1, fields))
+			}
+			fields = nil
+		case fields != nil && tag != "" && tag[0] != '/' && tag[0] != '?' && tag[0] != '!':
+			// The first NAME wins, so a nested PAYEE aggregate does not override it
+			if _, exists := fields[tag]; !exists && value != "" {
+				fields[tag] = html.UnescapeString(value)
+			}
+		}
+	}
+	if fields != nil {
+		return nil, errors.New("truncated OFX document: unterminated <STMTTRN>")
+	}
+
+	return records, nil
+}
+
+// ofxRecord converts the elements of a STMTTRN into a record
+func ofxRecord(n int, fields map[string]string) *Record {
+	record := &Record{
+		Line:        n,
+		FITID:       fields["FITID"],
+		Merchant:    fields["NAME"],
+		Description: fields["MEMO"],
+	}
+	if record.Description == "" {
+		record.Description = record.Merchant
+	}
+
+	date, err := parseOFXTime(fields["DTPOSTED"])
+	if err != nil {
+		record.Err = err
+		return record
+	}
+	record.Date = date
+
+	amount := strings.ReplaceAll(fields["TRNAMT"], ",", ".")
+	if amount == "" {
+		record.Err = errors.New("TRNAMT is missing")
+		return record
+	}
+	record.Amount, err = strconv.ParseFloat(amount, 64)
+	if err != nil {
+		record.Err = fmt.Errorf("unrecognised TRNAMT %q", fields["TRNAMT"])
+	}
+
+	return record
+}
+
+// parseOFXTime reads an OFX datetime: YYYYMMDD, optionally followed by
+// HHMMSS, fractional seconds and a [offset:TZ] suffix. Times without an
+// offset are in GMT.
+func parseOFXTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, errors.New("DTPOSTED is missing")
+	}
+
+	loc := time.UTC
+	if i := strings.IndexByte(value, '['); i >= 0 {
+		zone := strings.TrimSuffix(value[i+1:], "]")
+		value = value[:i]
+		offset := zone
+		if j := strings.IndexByte(zone, ':'); j >= 0 {
+			offset = zone[:j]
+		}
+		hours, err := strconv.ParseFloat(offset, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("unrecognised DTPOSTED time zone %q", zone)
+		}
+		loc = time.FixedZone("", int(hours*3600))
+	}
+	if i := strings.IndexByte(value, '.'); i >= 0 {
+		value = value[:i]
+	}
+
+	layout := ""
+	switch len(value) {
+	case 8:
+		layout = "20060102"
+	case 12:
+		layout = "200601021504"
+	case 14:
+		layout = "20060102150405"
+	default:
+		return time.Time{}, fmt.Errorf("unrecognised DTPOSTED %q", value)
+	}
+
+	t, err := time.ParseInLocation(layout, value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unrecognised DTPOSTED %q", value)
+	}
+	return t.UTC(), nil
+}
@@ -0,0 +1,37 @@
+package models
+
+// Outcomes of a statement line in an import report
+const (
+	ImportStatusNew       = "new"       // Will be (or was) stored
+	ImportStatusDuplicate = "duplicate" // Already on the account, or repeated earlier in the file
+	ImportStatusInvalid   = "invalid"   // Could not be parsed or failed validation
+)
+
+// How a duplicate statement line was recognised
+const (
+	MatchFITID       = "fitid"       // Same bank-assigned ID
+	MatchFingerprint = "fingerprint" // Same day, amount and merchant
+)
+
+// ImportRow describes what happens to one statement line
+type ImportRow struct {
+	Line            int          `json:"line"`
+	Status          string       `json:"status"`
+	Transaction     *Transaction `json:"transaction,omitempty"`
+	DuplicateOf     string       `json:"duplicateOf,omitempty"`     // ID of the matching stored transaction
+	DuplicateOfLine int          `json:"duplicateOfLine,omitempty"` // Earlier line of the same file with the same FITID
+	MatchedBy       string       `json:"matchedBy,omitempty"`
+	Error           string       `json:"error,omitempty"`
+}
+
+// ImportReport is the result of importing a statement into an account. When
+// Committed is false nothing was stored and the rows show what would happen.
+type ImportReport struct {
+	AccountID  string       `json:"accountId"`
+	Format     string       `json:"format"`
+	Committed  bool         `json:"committed"`
+	New        int          `json:"new"`
+	Duplicates int          `json:"duplicates"`
+	Invalid    int          `json:"invalid"`
+	Rows       []*ImportRow `json:"rows"`
+}
@@ -0,0 +1,159 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Sort fields for transaction listings
+const (
+	SortDate     = "date"
+	SortAmount   = "amount"
+	SortMerchant = "merchant"
+)
+
+// Sort orders for transaction listings
+const (
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+)
+
+// MaxPageSize is the largest page a client may request
+const MaxPageSize = 200
+
+// PageRequest describes which page of a transaction listing to return
+type PageRequest struct {
+	Limit  int    // 0 returns every match
+	Sort   string // SortDate (default), SortAmount or SortMerchant
+	Order  string // OrderDesc (default) or OrderAsc
+	Cursor string // Opaque cursor from a previous page
+}
+
+// Normalize applies defaults and validates the request
+func (p *PageRequest) Normalize() error {
+	if p.Sort == "" {
+		p.Sort = SortDate
+	}
+	if p.Order == "" {
+		p.Order = OrderDesc
+	}
+
+	switch p.Sort {
+	case SortDate, SortAmount, SortMerchant:
+	default:
+		return errors.New("sort must be one of date, amount or merchant")
+	}
+	switch p.Order {
+	case OrderAsc, OrderDesc:
+	default:
+		return errors.New("order must be asc or desc")
+	}
+	if p.Limit < 0 || p.Limit > MaxPageSize {
+		return fmt.Errorf("limit must be between 1 and %d", MaxPageSize)
+	}
+
+	return nil
+}
+
+// TransactionPage is one page of a transaction listing
+type TransactionPage struct {
+	Transactions []*Transaction
+	NextCursor   string // Empty on the last page
+}
+
+// Cursor is the decoded form of a page cursor. It records the sort key of the
+// last transaction returned, with date and ID as tie-breakers, so pages stay
+// stable when new transactions are inserted.
+type Cursor struct {
+	Sort     string    `json:"s"`
+	Order    string    `json:"o"`
+	Date     time.Time `json:"d"`
+	ID       string    `json:"i"`
+	Amount   float64   `json:"a,omitempty"`
+	Merchant string    `json:"m,omitempty"`
+}
+
+// NewCursor returns the cursor positioned just after txn
+func NewCursor(txn *Transaction, sort, order string) *Cursor {
+	c := &Cursor{
+		Sort:  sort,
+		Order: order,
+		Date:  txn.Date,
+		ID:    txn.ID,
+	}
+	switch sort {
+	case SortAmount:
+		c.Amount = txn.Amount
+	case SortMerchant:
+		c.Merchant = txn.Merchant
+	}
+	return c
+}
+
+// Encode returns the opaque string form of the cursor
+func (c *Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a cursor produced by Encode
+func DecodeCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" {
+		return nil, errors.New("invalid cursor")
+	}
+	return &c, nil
+}
+
+// TransactionQuery is a resolved listing request passed to the repository
+type TransactionQuery struct {
+	AccountIDs []string
+	Filters    TransactionFilters // AccountID is ignored in favour of AccountIDs
+	Sort       string
+	Order      string
+	After      *Cursor // Only transactions after this position are returned
+	Limit      int     // 0 returns every match
+}
+
+// CompareTransactions orders a and b by the sort field, then by date and ID.
+// It returns a negative number when a sorts first in ascending order.
+func CompareTransactions(a, b *Transaction, sort string) int {
+	switch sort {
+	case SortAmount:
+		if a.Amount != b.Amount {
+			if a.Amount < b.Amount {
+				return -1
+			}
+			return 1
+		}
+	case SortMerchant:
+		if c := strings.Compare(a.Merchant, b.Merchant); c != 0 {
+			return c
+		}
+	}
+	if !a.Date.Equal(b.Date) {
+		if a.Date.Before(b.Date) {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(a.ID, b.ID)
+}
+
+// After reports whether txn comes after the cursor position in the cursor's order
+func (c *Cursor) After(txn *Transaction) bool {
+	pos := &Transaction{ID: c.ID, Date: c.Date, Amount: c.Amount, Merchant: c.Merchant}
+	cmp := CompareTransactions(txn, pos, c.Sort)
+	if c.Order == OrderAsc {
+		return cmp > 0
+	}
+	return cmp < 0
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPageRequestNormalize(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       PageRequest
+		wantSort  string
+		wantOrder string
+		wantErr   bool
+	}{
+		{"defaults", PageRequest{}, SortDate, OrderDesc, false},
+		{"amount asc", PageRequest{Sort: SortAmount, Order: OrderAsc, Limit: 10}, SortAmount, OrderAsc, false},
+		{"max limit", PageRequest{Limit: MaxPageSize}, SortDate, OrderDesc, false},
+		{"unknown sort", PageRequest{Sort: "category"}, "", "", true},
+		{"unknown order", PageRequest{Order: "up"}, "", "", true},
+		{"negative limit", PageRequest{Limit: -1}, "", "", true},
+		{"limit too large", PageRequest{Limit: MaxPageSize + 1}, "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Normalize()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Normalize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if tt.req.Sort != tt.wantSort || tt.req.Order != tt.wantOrder {
+				t.Errorf("Expected %s/%s, got %s/%s", tt.wantSort, tt.wantOrder, tt.req.Sort, tt.req.Order)
+			}
+		})
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	txn := &Transaction{
+		ID:       "txn-042",
+		Date:     time.Date(2024, 12, 10, 10, 0, 0, 123456789, time.UTC),
+		Amount:   -50.25,
+		Merchant: "Test Store",
+	}
+
+	for _, sort := range []string{SortDate, SortAmount, SortMerchant} {
+		t.Run(sort, func(t *testing.T) {
+			decoded, err := DecodeCursor(NewCursor(txn, sort, OrderAsc).Encode())
+			if err != nil {
+				t.Fatalf("DecodeCursor failed: %v", err)
+			}
+			if decoded.Sort != sort || decoded.Order != OrderAsc || decoded.ID != txn.ID || !decoded.Date.Equal(txn.Date) {
+				t.Errorf("Cursor not round-tripped: %+v", decoded)
+			}
+			// The cursor sits exactly on txn, which is therefore not after it
+			if decoded.After(txn) {
+				t.Error("Transaction at the cursor position must not be after it")
+			}
+		})
+	}
+
+	for _, bad := range []string{"", "!!!", "e30"} {
+		if _, err := DecodeCursor(bad); err == nil {
+			t.Errorf("Expected error decoding %q", bad)
+		}
+	}
+}
+
+func TestCursorAfter(t *testing.T) {
+	base := time.Date(2024, 12, 10, 10, 0, 0, 0, time.UTC)
+	cursorTxn := &Transaction{ID: "txn-005", Date: base, Amount: -10}
+
+	tests := []struct {
+		name  string
+		sort  string
+		order string
+		txn   *Transaction
+		want  bool
+	}{
+		{"older is after in desc", SortDate, OrderDesc, &Transaction{ID: "txn-009", Date: base.Add(-time.Hour)}, true},
+		{"newer is not after in desc", SortDate, OrderDesc, &Transaction{ID: "txn-001", Date: base.Add(time.Hour)}, false},
+		{"same date lower ID is after in desc", SortDate, OrderDesc, &Transaction{ID: "txn-004", Date: base}, true},
+		{"same date higher ID is after in asc", SortDate, OrderAsc, &Transaction{ID: "txn-006", Date: base}, true},
+		{"larger amount is after in asc", SortAmount, OrderAsc, &Transaction{ID: "txn-001", Date: base, Amount: 5}, true},
+		{"smaller amount is not after in asc", SortAmount, OrderAsc, &Transaction{ID: "txn-009", Date: base, Amount: -20}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewCursor(cursorTxn, tt.sort, tt.order).After(tt.txn); got != tt.want {
+				t.Errorf("After() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package models
+
+import "github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/search"
+
+// SearchResult is a transaction returned by a full-text search, with the words
+// that matched the query marked in each field that matched
+type SearchResult struct {
+	*Transaction
+	Highlights map[string][]search.Segment `json:"highlights"`
+}
+
+// NewSearchResult highlights the query terms in the transaction's description and merchant
+func NewSearchResult(txn *Transaction, query string) *SearchResult {
+	highlights := make(map[string][]search.Segment)
+	if segments := search.Highlight(txn.Description, query); segments != nil {
+		highlights["description"] = segments
+	}
+	if segments := search.Highlight(txn.Merchant, query); segments != nil {
+		highlights["merchant"] = segments
+	}
+	return &SearchResult{
+		Transaction: txn,
+		Highlights:  highlights,
+	}
+}
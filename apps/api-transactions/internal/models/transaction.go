@@ -1,6 +1,12 @@
 package models
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
 
 // Transaction represents a financial transaction
 type Transaction struct {
@@ -13,6 +19,8 @@ type Transaction struct {
 	Merchant    string    `json:"merchant"`
 	Status      string    `json:"status"`
 	Type        string    `json:"type"`
+	TransferID  string    `json:"transferId,omitempty"` // Links the two legs of a transfer
+	ExternalID  string    `json:"externalId,omitempty"` // Bank-assigned ID (OFX FITID) of an imported statement line
 }
 
 // TransactionFilters represents filters for transaction queries
@@ -23,9 +31,11 @@ type TransactionFilters struct {
 	Category  string
 	MinAmount *float64
 	MaxAmount *float64
+	Query     string // Full-text search over description and merchant, resolved by the store's index
 }
 
-// Matches checks if a transaction matches the given filters
+// Matches checks if a transaction matches the given filters.
+// Query is not evaluated here; stores apply it through their search index.
 func (t *Transaction) Matches(filters *TransactionFilters) bool {
 	// Account ID filter (always allowed)
 	if filters.AccountID != "" && t.AccountID != filters.AccountID {
@@ -55,3 +65,63 @@ func (t *Transaction) Matches(filters *TransactionFilters) bool {
 
 	return true
 }
+
+// CategoryTransfer is the category recorded on both legs of a transfer
+const CategoryTransfer = "transfer"
+
+// CategoryUncategorized is assigned to imported statement lines without a category
+const CategoryUncategorized = "uncategorized"
+
+// Transaction types
+const (
+	TypeCredit = "credit"
+	TypeDebit  = "debit"
+)
+
+// Transaction statuses
+const (
+	StatusPending   = "pending"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// Validate checks that a transaction is well formed before it is stored.
+// Credits must carry a positive amount and debits a negative one.
+func (t *Transaction) Validate() error {
+	if t.AccountID == "" {
+		return errors.New("accountId is required")
+	}
+	if strings.TrimSpace(t.Description) == "" {
+		return errors.New("description is required")
+	}
+	if t.Category == "" {
+		return errors.New("category is required")
+	}
+	if t.Date.IsZero() {
+		return errors.New("date is required")
+	}
+	if math.IsNaN(t.Amount) || math.IsInf(t.Amount, 0) {
+		return errors.New("amount must be a finite number")
+	}
+
+	switch t.Type {
+	case TypeCredit:
+		if t.Amount <= 0 {
+			return errors.New("credit amount must be positive")
+		}
+	case TypeDebit:
+		if t.Amount >= 0 {
+			return errors.New("debit amount must be negative")
+		}
+	default:
+		return fmt.Errorf("type must be %q or %q", TypeCredit, TypeDebit)
+	}
+
+	switch t.Status {
+	case StatusPending, StatusCompleted, StatusFailed:
+	default:
+		return fmt.Errorf("status must be one of %q, %q or %q", StatusPending, StatusCompleted, StatusFailed)
+	}
+
+	return nil
+}
@@ -119,3 +119,48 @@ func timePtr(t time.Time) *time.Time {
 func floatPtr(f float64) *float64 {
 	return &f
 }
+
+func TestTransactionValidate(t *testing.T) {
+	valid := func() *Transaction {
+		return &Transaction{
+			AccountID:   "acc-001",
+			Date:        time.Date(2024, 12, 10, 10, 0, 0, 0, time.UTC),
+			Description: "Test Transaction",
+			Amount:      -50.00,
+			Category:    "shopping",
+			Merchant:    "Test Store",
+			Status:      "completed",
+			Type:        "debit",
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(t *Transaction)
+		wantErr bool
+	}{
+		{"valid debit", func(t *Transaction) {}, false},
+		{"valid credit", func(t *Transaction) { t.Type = "credit"; t.Amount = 100.0 }, false},
+		{"valid pending", func(t *Transaction) { t.Status = "pending" }, false},
+		{"credit with negative amount", func(t *Transaction) { t.Type = "credit" }, true},
+		{"debit with positive amount", func(t *Transaction) { t.Amount = 50.0 }, true},
+		{"zero amount", func(t *Transaction) { t.Amount = 0 }, true},
+		{"unknown type", func(t *Transaction) { t.Type = "transfer" }, true},
+		{"missing account", func(t *Transaction) { t.AccountID = "" }, true},
+		{"blank description", func(t *Transaction) { t.Description = "   " }, true},
+		{"missing category", func(t *Transaction) { t.Category = "" }, true},
+		{"missing date", func(t *Transaction) { t.Date = time.Time{} }, true},
+		{"unknown status", func(t *Transaction) { t.Status = "cancelled" }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txn := valid()
+			tt.modify(txn)
+			err := txn.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
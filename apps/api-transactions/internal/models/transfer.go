@@ -0,0 +1,49 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// Transfer moves money between two accounts owned by the same user.
+// It is recorded as a debit on the source account and a credit on the
+// destination account, linked by TransferID.
+type Transfer struct {
+	ID            string       `json:"id"`
+	FromAccountID string       `json:"fromAccountId"`
+	ToAccountID   string       `json:"toAccountId"`
+	Amount        float64      `json:"amount"`
+	Description   string       `json:"description,omitempty"`
+	Date          time.Time    `json:"date"`
+	Debit         *Transaction `json:"debit,omitempty"`
+	Credit        *Transaction `json:"credit,omitempty"`
+}
+
+// Validate checks that a transfer request is well formed
+func (t *Transfer) Validate() error {
+	if t.FromAccountID == "" {
+		return errors.New("fromAccountId is required")
+	}
+	if t.ToAccountID == "" {
+		return errors.New("toAccountId is required")
+	}
+	if t.FromAccountID == t.ToAccountID {
+		return errors.New("cannot transfer to the same account")
+	}
+	if math.IsNaN(t.Amount) || math.IsInf(t.Amount, 0) {
+		return errors.New("amount must be a finite number")
+	}
+	if t.Amount <= 0 {
+		return errors.New("amount must be positive")
+	}
+	if RoundCents(t.Amount) != t.Amount {
+		return errors.New("amount must not have more than two decimal places")
+	}
+	return nil
+}
+
+// RoundCents rounds an amount to the nearest cent
+func RoundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,324 @@
+package repository
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/models"
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/search"
+	"github.com/sirupsen/logrus"
+)
+
+// JSONStore is an in-memory Store loaded from the seed JSON files.
+// Created transactions are not persisted across restarts.
+type JSONStore struct {
+	transactions map[string]*models.Transaction
+	byAccount    map[string][]*models.Transaction // accountID -> transactions, for listings
+	accounts     map[string]*Account              // accountID -> Account
+	index        *search.Index                    // Full-text index over description and merchant
+	mu           sync.RWMutex
+	logger       *logrus.Logger
+}
+
+// NewJSONStore creates a new store and loads data from JSON files
+func NewJSONStore(dataPath string, logger *logrus.Logger) (*JSONStore, error) {
+	repo := &JSONStore{
+		transactions: make(map[string]*models.Transaction),
+		byAccount:    make(map[string][]*models.Transaction),
+		index:        search.NewIndex(),
+		accounts:     make(map[string]*Account),
+		logger:       logger,
+	}
+
+	// Load accounts first (needed for user isolation)
+	if err := repo.loadAccounts(filepath.Join(dataPath, "accounts.json")); err != nil {
+		return nil, fmt.Errorf("failed to load accounts: %w", err)
+	}
+
+	// Load transactions
+	if err := repo.loadTransactions(filepath.Join(dataPath, "transactions.json")); err != nil {
+		return nil, fmt.Errorf("failed to load transactions: %w", err)
+	}
+
+	logger.Infof("Loaded %d accounts and %d transactions from %s", len(repo.accounts), len(repo.transactions), dataPath)
+
+	return repo, nil
+}
+
+// loadAccounts loads accounts from a JSON file
+func (r *JSONStore) loadAccounts(filePath string) error {
+	var accounts []*Account
+	if err := readJSONFile(filePath, &accounts); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, acc := range accounts {
+		r.accounts[acc.ID] = acc
+	}
+
+	return nil
+}
+
+// loadTransactions loads transactions from a JSON file
+func (r *JSONStore) loadTransactions(filePath string) error {
+	var transactions []*models.Transaction
+	if err := readJSONFile(filePath, &transactions); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, txn := range transactions {
+		r.add(txn)
+	}
+
+	return nil
+}
+
+// add indexes a transaction; the caller must hold the write lock
+func (r *JSONStore) add(txn *models.Transaction) {
+	r.transactions[txn.ID] = txn
+	r.byAccount[txn.AccountID] = append(r.byAccount[txn.AccountID], txn)
+	r.index.Add(txn.ID, txn.Description, txn.Merchant)
+}
+
+// searchMatches resolves a full-text query to the set of matching IDs,
+// returning nil when there is no query to apply
+func (r *JSONStore) searchMatches(filters *models.TransactionFilters) map[string]struct{} {
+	if filters.Query == "" {
+		return nil
+	}
+	return r.index.Search(filters.Query)
+}
+
+// GetTransactionByID retrieves a transaction by ID
+func (r *JSONStore) GetTransactionByID(txnID string) (*models.Transaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	txn, exists := r.transactions[txnID]
+	if !exists {
+		return nil, ErrTransactionNotFound
+	}
+
+	return txn, nil
+}
+
+// GetAllTransactions returns all transactions
+func (r *JSONStore) GetAllTransactions() ([]*models.Transaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	transactions := make([]*models.Transaction, 0, len(r.transactions))
+	for _, txn := range r.transactions {
+		transactions = append(transactions, txn)
+	}
+
+	return transactions, nil
+}
+
+// CreateTransaction stores a new transaction and posts it to its account
+func (r *JSONStore) CreateTransaction(txn *models.Transaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	account, exists := r.accounts[txn.AccountID]
+	if !exists {
+		return fmt.Errorf("account not found")
+	}
+	if _, exists := r.transactions[txn.ID]; exists {
+		return fmt.Errorf("transaction already exists")
+	}
+
+	account.Post(txn)
+	r.add(txn)
+	return nil
+}
+
+// CreateTransactions atomically stores a batch of new transactions and posts
+// them to their accounts
+func (r *JSONStore) CreateTransactions(txns []*models.Transaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Validate the whole batch before storing anything
+	ids := make(map[string]bool, len(txns))
+	externalIDs := make(map[[2]string]bool)
+	for _, txn := range r.transactions {
+		if txn.ExternalID != "" {
+			externalIDs[[2]string{txn.AccountID, txn.ExternalID}] = true
+		}
+	}
+	for _, txn := range txns {
+		if _, exists := r.accounts[txn.AccountID]; !exists {
+			return fmt.Errorf("account not found")
+		}
+		if _, exists := r.transactions[txn.ID]; exists || ids[txn.ID] {
+			return fmt.Errorf("transaction already exists")
+		}
+		ids[txn.ID] = true
+
+		if txn.ExternalID != "" {
+			key := [2]string{txn.AccountID, txn.ExternalID}
+			if externalIDs[key] {
+				return ErrDuplicateExternalID
+			}
+			externalIDs[key] = true
+		}
+	}
+
+	for _, txn := range txns {
+		r.accounts[txn.AccountID].Post(txn)
+		r.add(txn)
+	}
+	return nil
+}
+
+// CreateTransfer atomically stores both legs of a transfer and updates balances
+func (r *JSONStore) CreateTransfer(debit, credit *models.Transaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	from, exists := r.accounts[debit.AccountID]
+	if !exists {
+		return fmt.Errorf("account not found")
+	}
+	to, exists := r.accounts[credit.AccountID]
+	if !exists {
+		return fmt.Errorf("account not found")
+	}
+
+	for _, txn := range []*models.Transaction{debit, credit} {
+		if _, exists := r.transactions[txn.ID]; exists {
+			return fmt.Errorf("transaction already exists")
+		}
+	}
+
+	newFromBalance := models.RoundCents(from.Balance + debit.Amount)
+	if newFromBalance < from.AvailableFloor() {
+		return ErrInsufficientFunds
+	}
+
+	from.Post(debit)
+	to.Post(credit)
+	r.add(debit)
+	r.add(credit)
+
+	return nil
+}
+
+// GetAccountByID retrieves an account by ID
+func (r *JSONStore) GetAccountByID(accountID string) (*Account, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	acc, exists := r.accounts[accountID]
+	if !exists {
+		return nil, fmt.Errorf("account not found")
+	}
+
+	// Return a copy so callers never observe balances changing under them
+	account := *acc
+	return &account, nil
+}
+
+// GetAccountIDsByUserID retrieves all account IDs for a given user
+func (r *JSONStore) GetAccountIDsByUserID(userID string) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var accountIDs []string
+	for _, acc := range r.accounts {
+		if acc.UserID == userID {
+			accountIDs = append(accountIDs, acc.ID)
+		}
+	}
+
+	return accountIDs, nil
+}
+
+// GetAccountAccess returns the user's access to the account. Accounts can only
+// be shared through the database api-accounts writes grants to, so with this
+// store only owners have access.
+func (r *JSONStore) GetAccountAccess(accountID, userID string) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if acc, exists := r.accounts[accountID]; exists && acc.UserID == userID {
+		return AccessOwner, nil
+	}
+	return "", nil
+}
+
+// GetTransactionsByFilter retrieves transactions matching the given filters
+func (r *JSONStore) GetTransactionsByFilter(filters *models.TransactionFilters) ([]*models.Transaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	matchIDs := r.searchMatches(filters)
+
+	var filtered []*models.Transaction
+	for _, txn := range r.transactions {
+		if !txn.Matches(filters) {
+			continue
+		}
+		if _, ok := matchIDs[txn.ID]; matchIDs != nil && !ok {
+			continue
+		}
+		filtered = append(filtered, txn)
+	}
+
+	return filtered, nil
+}
+
+// ListTransactions returns a page of transactions for the given accounts,
+// reading only those accounts' transactions rather than the whole store
+func (r *JSONStore) ListTransactions(query *models.TransactionQuery) ([]*models.Transaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	filters := query.Filters
+	filters.AccountID = ""
+	matchIDs := r.searchMatches(&filters)
+
+	var matched []*models.Transaction
+	for _, accountID := range query.AccountIDs {
+		for _, txn := range r.byAccount[accountID] {
+			if !txn.Matches(&filters) {
+				continue
+			}
+			if _, ok := matchIDs[txn.ID]; matchIDs != nil && !ok {
+				continue
+			}
+			if query.After != nil && !query.After.After(txn) {
+				continue
+			}
+			matched = append(matched, txn)
+		}
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		cmp := models.CompareTransactions(matched[i], matched[j], query.Sort)
+		if query.Order == models.OrderAsc {
+			return cmp < 0
+		}
+		return cmp > 0
+	})
+
+	if query.Limit > 0 && len(matched) > query.Limit {
+		matched = matched[:query.Limit]
+	}
+
+	return matched, nil
+}
+
+// Close is a no-op for the in-memory store
+func (r *JSONStore) Close() error {
+	return nil
+}
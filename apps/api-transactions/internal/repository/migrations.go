@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// migration is a single forward-only schema change. IDs are prefixed with the
+// owning service so several services can share one database file.
+type migration struct {
+	id  string
+	sql string
+}
+
+// migrations lists the transactions schema in the order it must be applied.
+// The accounts table matches the api-accounts definition so that both services
+// can point SQLITE_PATH at the same file.
+var migrations = []migration{
+	{
+		id: "transactions_0001_create_accounts",
+		sql: `CREATE TABLE IF NOT EXISTS accounts (
+			id             TEXT PRIMARY KEY,
+			user_id        TEXT NOT NULL,
+			account_number TEXT NOT NULL,
+			account_type   TEXT NOT NULL,
+			account_name   TEXT NOT NULL,
+			balance        REAL NOT NULL,
+			currency       TEXT NOT NULL,
+			credit_limit   REAL,
+			status         TEXT NOT NULL,
+			opened_date    TEXT NOT NULL,
+			last_activity  TEXT NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_accounts_user_id ON accounts (user_id)`,
+	},
+	{
+		id: "transactions_0002_create_transactions",
+		sql: `CREATE TABLE IF NOT EXISTS transactions (
+			id          TEXT PRIMARY KEY,
+			account_id  TEXT NOT NULL,
+			date        TEXT NOT NULL,
+			description TEXT NOT NULL,
+			amount      REAL NOT NULL,
+			category    TEXT NOT NULL,
+			merchant    TEXT NOT NULL,
+			status      TEXT NOT NULL,
+			type        TEXT NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_transactions_account_date ON transactions (account_id, date)`,
+	},
+	{
+		id: "transactions_0003_add_transfer_id",
+		sql: `ALTER TABLE transactions ADD COLUMN transfer_id TEXT;
+		CREATE INDEX IF NOT EXISTS idx_transactions_transfer_id ON transactions (transfer_id)`,
+	},
+	{
+		id: "transactions_0004_add_sort_indexes",
+		sql: `DROP INDEX IF EXISTS idx_transactions_account_date;
+		CREATE INDEX IF NOT EXISTS idx_transactions_account_date_id ON transactions (account_id, date, id);
+		CREATE INDEX IF NOT EXISTS idx_transactions_account_amount ON transactions (account_id, amount, date, id);
+		CREATE INDEX IF NOT EXISTS idx_transactions_account_merchant ON transactions (account_id, merchant, date, id)`,
+	},
+	{
+		id: "transactions_0005_add_external_id",
+		sql: `ALTER TABLE transactions ADD COLUMN external_id TEXT;
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_account_external_id
+			ON transactions (account_id, external_id) WHERE external_id IS NOT NULL`,
+	},
+	{
+		// Written by api-accounts when an owner shares an account
+		id: "transactions_0006_create_account_grants",
+		sql: `CREATE TABLE IF NOT EXISTS account_grants (
+			account_id TEXT NOT NULL,
+			user_id    TEXT NOT NULL,
+			access     TEXT NOT NULL,
+			granted_by TEXT NOT NULL,
+			created_at TEXT NOT NULL,
+			PRIMARY KEY (account_id, user_id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_account_grants_user_id ON account_grants (user_id)`,
+	},
+	{
+		// Full-text search over description and merchant. Triggers keep it in
+		// step with every writer of the file, such as cmd/import, not only
+		// the server. Diacritics are kept to match the json backend's index.
+		id: "transactions_0007_create_transactions_fts",
+		sql: `CREATE VIRTUAL TABLE IF NOT EXISTS transactions_fts USING fts5(
+			id UNINDEXED,
+			description,
+			merchant,
+			tokenize = "unicode61 remove_diacritics 0"
+		);
+		INSERT INTO transactions_fts (id, description, merchant) SELECT id, description, merchant FROM transactions;
+		CREATE TRIGGER IF NOT EXISTS transactions_fts_insert AFTER INSERT ON transactions BEGIN
+			INSERT INTO transactions_fts (id, description, merchant) VALUES (new.id, new.description, new.merchant);
+		END;
+		CREATE TRIGGER IF NOT EXISTS transactions_fts_update AFTER UPDATE OF id, description, merchant ON transactions BEGIN
+			DELETE FROM transactions_fts WHERE id = old.id;
+			INSERT INTO transactions_fts (id, description, merchant) VALUES (new.id, new.description, new.merchant);
+		END;
+		CREATE TRIGGER IF NOT EXISTS transactions_fts_delete AFTER DELETE ON transactions BEGIN
+			DELETE FROM transactions_fts WHERE id = old.id;
+		END`,
+	},
+}
+
+// migrate applies any migrations that have not yet been recorded
+func migrate(db *sql.DB, migrations []migration) (int, error) {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		id         TEXT PRIMARY KEY,
+		applied_at TEXT NOT NULL
+	)`); err != nil {
+		return 0, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	applied := 0
+	for _, m := range migrations {
+		var exists int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM schema_migrations WHERE id = ?`, m.id).Scan(&exists); err != nil {
+			return applied, err
+		}
+		if exists > 0 {
+			continue
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			return applied, err
+		}
+		if _, err := tx.Exec(m.sql); err != nil {
+			tx.Rollback()
+			return applied, fmt.Errorf("migration %s failed: %w", m.id, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (id, applied_at) VALUES (?, ?)`,
+			m.id, time.Now().UTC().Format(time.RFC3339)); err != nil {
+			tx.Rollback()
+			return applied, err
+		}
+		if err := tx.Commit(); err != nil {
+			return applied, err
+		}
+		applied++
+	}
+
+	return applied, nil
+}
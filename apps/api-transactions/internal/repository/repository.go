@@ -2,148 +2,131 @@ package repository
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
-	"sync"
+	"time"
 
 	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/models"
 	"github.com/sirupsen/logrus"
 )
 
-// Account represents a user's account (minimal structure needed for filtering)
-type Account struct {
-	ID     string `json:"id"`
-	UserID string `json:"userId"`
-}
+// Storage backends selectable via the STORAGE_BACKEND environment variable
+const (
+	BackendJSON   = "json"
+	BackendSQLite = "sqlite"
+)
 
-// Repository provides data access for transactions
-type Repository struct {
-	transactions map[string]*models.Transaction
-	accounts     map[string]*Account // accountID -> Account
-	mu           sync.RWMutex
-	logger       *logrus.Logger
-}
+// ErrTransactionNotFound is returned when no transaction has the requested ID
+var ErrTransactionNotFound = errors.New("transaction not found")
 
-// NewRepository creates a new repository and loads data from JSON files
-func NewRepository(dataPath string, logger *logrus.Logger) (*Repository, error) {
-	repo := &Repository{
-		transactions: make(map[string]*models.Transaction),
-		accounts:     make(map[string]*Account),
-		logger:       logger,
-	}
+// ErrInsufficientFunds is returned when a transfer would take the source
+// account below zero, or below its credit limit for credit accounts
+var ErrInsufficientFunds = errors.New("insufficient funds")
 
-	// Load accounts first (needed for user isolation)
-	if err := repo.loadAccounts(filepath.Join(dataPath, "accounts.json")); err != nil {
-		return nil, fmt.Errorf("failed to load accounts: %w", err)
-	}
+// ErrDuplicateExternalID is returned when an account already holds a
+// transaction with the same external (bank-assigned) ID
+var ErrDuplicateExternalID = errors.New("duplicate external ID")
 
-	// Load transactions
-	if err := repo.loadTransactions(filepath.Join(dataPath, "transactions.json")); err != nil {
-		return nil, fmt.Errorf("failed to load transactions: %w", err)
-	}
-
-	logger.Infof("Loaded %d accounts and %d transactions from %s", len(repo.accounts), len(repo.transactions), dataPath)
+// Levels of access to an account, matching api-accounts. Joint holders and
+// viewers are granted access by the owner through api-accounts.
+const (
+	AccessOwner  = "owner"
+	AccessJoint  = "joint"
+	AccessViewer = "viewer"
+)
 
-	return repo, nil
+// Account represents a user's account (the fields needed for filtering, postings and exports)
+type Account struct {
+	ID           string    `json:"id"`
+	UserID       string    `json:"userId"`
+	AccountType  string    `json:"accountType"`
+	AccountName  string    `json:"accountName"`
+	Currency     string    `json:"currency"`
+	Status       string    `json:"status"`
+	Balance      float64   `json:"balance"`
+	CreditLimit  *float64  `json:"creditLimit,omitempty"`
+	LastActivity time.Time `json:"lastActivity"`
 }
 
-// loadAccounts loads accounts from a JSON file
-func (r *Repository) loadAccounts(filePath string) error {
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		return err
-	}
-
-	var accounts []*Account
-	if err := json.Unmarshal(data, &accounts); err != nil {
-		return err
+// Post applies a completed transaction to the account: the balance moves by
+// its amount and the last activity advances to its date. Backdated
+// transactions leave the last activity alone, and other statuses change
+// nothing.
+func (a *Account) Post(txn *models.Transaction) {
+	if txn.Status != models.StatusCompleted {
+		return
 	}
-
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	for _, acc := range accounts {
-		r.accounts[acc.ID] = acc
+	a.Balance = models.RoundCents(a.Balance + txn.Amount)
+	if txn.Date.After(a.LastActivity) {
+		a.LastActivity = txn.Date
 	}
-
-	return nil
 }
 
-// loadTransactions loads transactions from a JSON file
-func (r *Repository) loadTransactions(filePath string) error {
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		return err
-	}
-
-	var transactions []*models.Transaction
-	if err := json.Unmarshal(data, &transactions); err != nil {
-		return err
+// AvailableFloor returns the lowest balance the account may reach
+func (a *Account) AvailableFloor() float64 {
+	if a.CreditLimit != nil {
+		return -*a.CreditLimit
 	}
-
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	for _, txn := range transactions {
-		r.transactions[txn.ID] = txn
-	}
-
-	return nil
+	return 0
 }
 
-// GetTransactionByID retrieves a transaction by ID
-func (r *Repository) GetTransactionByID(txnID string) (*models.Transaction, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-
-	txn, exists := r.transactions[txnID]
-	if !exists {
-		return nil, fmt.Errorf("transaction not found")
-	}
-
-	return txn, nil
+// Store provides data access for transactions and the accounts that own them
+type Store interface {
+	GetTransactionByID(txnID string) (*models.Transaction, error)
+	GetAllTransactions() ([]*models.Transaction, error)
+	GetTransactionsByFilter(filters *models.TransactionFilters) ([]*models.Transaction, error)
+	// ListTransactions returns the transactions on query.AccountIDs that match
+	// the filters, in the requested order, starting after query.After and
+	// returning at most query.Limit rows
+	ListTransactions(query *models.TransactionQuery) ([]*models.Transaction, error)
+	// CreateTransaction stores a new transaction and, once it is completed,
+	// posts it to its account (see Account.Post). api-accounts only counts
+	// completed postings when it works balances back in time.
+	CreateTransaction(txn *models.Transaction) error
+	// CreateTransactions atomically stores a batch of new transactions and
+	// posts the completed ones like CreateTransaction. Nothing is stored if an
+	// account does not exist, any ID is taken, or an ExternalID is already in
+	// use on the same account (ErrDuplicateExternalID).
+	CreateTransactions(txns []*models.Transaction) error
+	// CreateTransfer atomically stores both legs of a transfer and moves the
+	// balances of the two accounts, failing with ErrInsufficientFunds if the
+	// debited account would drop below its AvailableFloor
+	CreateTransfer(debit, credit *models.Transaction) error
+	GetAccountByID(accountID string) (*Account, error)
+	// GetAccountIDsByUserID returns the accounts the user owns or was granted
+	// access to
+	GetAccountIDsByUserID(userID string) ([]string, error)
+	// GetAccountAccess returns the user's access to the account: owner, joint,
+	// viewer, or empty when they have none
+	GetAccountAccess(accountID, userID string) (string, error)
+	Close() error
 }
 
-// GetAllTransactions returns all transactions
-func (r *Repository) GetAllTransactions() []*models.Transaction {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-
-	transactions := make([]*models.Transaction, 0, len(r.transactions))
-	for _, txn := range r.transactions {
-		transactions = append(transactions, txn)
-	}
-
-	return transactions
+// Config selects and configures the storage backend
+type Config struct {
+	Backend    string // json (default) or sqlite
+	DataPath   string // Directory containing the seed JSON files
+	SQLitePath string // Database file used by the sqlite backend
 }
 
-// GetAccountIDsByUserID retrieves all account IDs for a given user
-func (r *Repository) GetAccountIDsByUserID(userID string) []string {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-
-	var accountIDs []string
-	for _, acc := range r.accounts {
-		if acc.UserID == userID {
-			accountIDs = append(accountIDs, acc.ID)
-		}
+// NewStore creates the store for the configured backend
+func NewStore(cfg Config, logger *logrus.Logger) (Store, error) {
+	switch cfg.Backend {
+	case "", BackendJSON:
+		return NewJSONStore(cfg.DataPath, logger)
+	case BackendSQLite:
+		return NewSQLiteStore(cfg.SQLitePath, cfg.DataPath, logger)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
 	}
-
-	return accountIDs
 }
 
-// GetTransactionsByFilter retrieves transactions matching the given filters
-func (r *Repository) GetTransactionsByFilter(filters *models.TransactionFilters) []*models.Transaction {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-
-	var filtered []*models.Transaction
-	for _, txn := range r.transactions {
-		if txn.Matches(filters) {
-			filtered = append(filtered, txn)
-		}
+// readJSONFile decodes a seed file into v
+func readJSONFile(filePath string, v any) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
 	}
-
-	return filtered
+	return json.Unmarshal(data, v)
 }
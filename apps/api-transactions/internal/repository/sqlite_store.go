@@ -0,0 +1,514 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/models"
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/search"
+	"github.com/sirupsen/logrus"
+	_ "modernc.org/sqlite" // Pure Go SQLite driver, keeps CGO_ENABLED=0 builds working
+)
+
+const (
+	transactionColumns = `id, account_id, date, description, amount, category, merchant, status, type, transfer_id, external_id`
+	accountColumns     = `id, user_id, account_type, account_name, currency, status, balance, credit_limit, last_activity`
+
+	// timeLayout is fixed width so that stored dates sort chronologically as text
+	timeLayout = "2006-01-02T15:04:05.000000000Z07:00"
+)
+
+// seedAccount is the full accounts.json record, used to populate the shared accounts table
+type seedAccount struct {
+	ID            string    `json:"id"`
+	UserID        string    `json:"userId"`
+	AccountNumber string    `json:"accountNumber"`
+	AccountType   string    `json:"accountType"`
+	AccountName   string    `json:"accountName"`
+	Balance       float64   `json:"balance"`
+	Currency      string    `json:"currency"`
+	CreditLimit   *float64  `json:"creditLimit,omitempty"`
+	Status        string    `json:"status"`
+	OpenedDate    time.Time `json:"openedDate"`
+	LastActivity  time.Time `json:"lastActivity"`
+}
+
+// SQLiteStore is a durable Store backed by a SQLite database file. Search
+// uses the transactions_fts table, which triggers keep up to date.
+type SQLiteStore struct {
+	db     *sql.DB
+	logger *logrus.Logger
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// NewSQLiteStore opens (or creates) the database at dbPath, applies pending
+// migrations and imports the seed data from dataPath when the database is empty
+func NewSQLiteStore(dbPath, dataPath string, logger *logrus.Logger) (*SQLiteStore, error) {
+	// Immediate transactions take the write lock up front, so balance checks
+	// cannot race with another process sharing the file
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate", dbPath)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	// A single connection serialises writers within the process and avoids SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+
+	store := &SQLiteStore{
+		db:     db,
+		logger: logger,
+	}
+
+	applied, err := migrate(db, migrations)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+	if applied > 0 {
+		logger.Infof("Applied %d schema migrations to %s", applied, dbPath)
+	}
+
+	if err := store.importSeed(dataPath); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to import seed data: %w", err)
+	}
+
+	logger.Infof("Using SQLite storage at %s", dbPath)
+
+	return store, nil
+}
+
+// importSeed loads accounts and transactions from the seed files if the
+// transactions table is empty. Accounts that already exist (for example when
+// the file is shared with api-accounts) are left untouched.
+func (s *SQLiteStore) importSeed(dataPath string) error {
+	var count int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM transactions`).Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	var accounts []*seedAccount
+	if err := readJSONFile(filepath.Join(dataPath, "accounts.json"), &accounts); err != nil {
+		return fmt.Errorf("failed to load accounts: %w", err)
+	}
+
+	var transactions []*models.Transaction
+	if err := readJSONFile(filepath.Join(dataPath, "transactions.json"), &transactions); err != nil {
+		return fmt.Errorf("failed to load transactions: %w", err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, a := range accounts {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO accounts (id, user_id, account_number, account_type, account_name,
+			balance, currency, credit_limit, status, opened_date, last_activity) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			a.ID, a.UserID, a.AccountNumber, a.AccountType, a.AccountName, a.Balance, a.Currency,
+			a.CreditLimit, a.Status, formatTime(a.OpenedDate), formatTime(a.LastActivity)); err != nil {
+			return err
+		}
+	}
+
+	for _, txn := range transactions {
+		if err := insertTransaction(tx, txn); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.logger.Infof("Imported %d accounts and %d transactions from %s", len(accounts), len(transactions), dataPath)
+	return nil
+}
+
+// GetTransactionByID retrieves a transaction by ID
+func (s *SQLiteStore) GetTransactionByID(txnID string) (*models.Transaction, error) {
+	row := s.db.QueryRow(`SELECT `+transactionColumns+` FROM transactions WHERE id = ?`, txnID)
+	txn, err := scanTransaction(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTransactionNotFound
+	}
+	return txn, err
+}
+
+// GetAllTransactions returns all transactions
+func (s *SQLiteStore) GetAllTransactions() ([]*models.Transaction, error) {
+	return s.queryTransactions(`SELECT ` + transactionColumns + ` FROM transactions`)
+}
+
+// GetTransactionsByFilter retrieves transactions matching the given filters
+func (s *SQLiteStore) GetTransactionsByFilter(filters *models.TransactionFilters) ([]*models.Transaction, error) {
+	where, args := s.filterClauses(filters)
+
+	query := `SELECT ` + transactionColumns + ` FROM transactions`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+
+	return s.queryTransactions(query, args...)
+}
+
+// ListTransactions returns a page of transactions for the given accounts using
+// keyset pagination over the (account_id, sort key, date, id) indexes
+func (s *SQLiteStore) ListTransactions(query *models.TransactionQuery) ([]*models.Transaction, error) {
+	if len(query.AccountIDs) == 0 {
+		return nil, nil
+	}
+
+	filters := query.Filters
+	filters.AccountID = ""
+	where, args := s.filterClauses(&filters)
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(query.AccountIDs)), ", ")
+	where = append(where, "account_id IN ("+placeholders+")")
+	for _, id := range query.AccountIDs {
+		args = append(args, id)
+	}
+
+	keyColumns := []string{"date", "id"}
+	switch query.Sort {
+	case models.SortAmount:
+		keyColumns = []string{"amount", "date", "id"}
+	case models.SortMerchant:
+		keyColumns = []string{"merchant", "date", "id"}
+	}
+
+	direction, comparison := "DESC", "<"
+	if query.Order == models.OrderAsc {
+		direction, comparison = "ASC", ">"
+	}
+
+	if c := query.After; c != nil {
+		where = append(where, "("+strings.Join(keyColumns, ", ")+") "+comparison+
+			" ("+strings.TrimSuffix(strings.Repeat("?, ", len(keyColumns)), ", ")+")")
+		switch query.Sort {
+		case models.SortAmount:
+			args = append(args, c.Amount)
+		case models.SortMerchant:
+			args = append(args, c.Merchant)
+		}
+		args = append(args, formatTime(c.Date), c.ID)
+	}
+
+	orderBy := make([]string, len(keyColumns))
+	for i, col := range keyColumns {
+		orderBy[i] = col + " " + direction
+	}
+
+	sqlQuery := `SELECT ` + transactionColumns + ` FROM transactions WHERE ` + strings.Join(where, " AND ") +
+		` ORDER BY ` + strings.Join(orderBy, ", ")
+	if query.Limit > 0 {
+		sqlQuery += ` LIMIT ?`
+		args = append(args, query.Limit)
+	}
+
+	return s.queryTransactions(sqlQuery, args...)
+}
+
+// CreateTransaction stores a new transaction and posts it to its account
+func (s *SQLiteStore) CreateTransaction(txn *models.Transaction) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM transactions WHERE id = ?`, txn.ID).Scan(&exists); err != nil {
+		return err
+	}
+	if exists > 0 {
+		return fmt.Errorf("transaction already exists")
+	}
+
+	if err := postTransaction(tx, txn); err != nil {
+		return err
+	}
+	if err := insertTransaction(tx, txn); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CreateTransactions atomically stores a batch of new transactions and posts
+// them to their accounts
+func (s *SQLiteStore) CreateTransactions(txns []*models.Transaction) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, txn := range txns {
+		var exists int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM transactions WHERE id = ?`, txn.ID).Scan(&exists); err != nil {
+			return err
+		}
+		if exists > 0 {
+			return fmt.Errorf("transaction already exists")
+		}
+
+		if txn.ExternalID != "" {
+			if err := tx.QueryRow(`SELECT COUNT(*) FROM transactions WHERE account_id = ? AND external_id = ?`,
+				txn.AccountID, txn.ExternalID).Scan(&exists); err != nil {
+				return err
+			}
+			if exists > 0 {
+				return ErrDuplicateExternalID
+			}
+		}
+
+		if err := postTransaction(tx, txn); err != nil {
+			return err
+		}
+		if err := insertTransaction(tx, txn); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetAccountByID retrieves an account by ID
+func (s *SQLiteStore) GetAccountByID(accountID string) (*Account, error) {
+	row := s.db.QueryRow(`SELECT `+accountColumns+` FROM accounts WHERE id = ?`, accountID)
+	acc, err := scanAccount(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("account not found")
+	}
+	return acc, err
+}
+
+// CreateTransfer atomically stores both legs of a transfer and updates balances
+func (s *SQLiteStore) CreateTransfer(debit, credit *models.Transaction) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	from, err := scanAccount(tx.QueryRow(`SELECT `+accountColumns+` FROM accounts WHERE id = ?`, debit.AccountID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("account not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	newFromBalance := models.RoundCents(from.Balance + debit.Amount)
+	if newFromBalance < from.AvailableFloor() {
+		return ErrInsufficientFunds
+	}
+
+	for _, txn := range []*models.Transaction{debit, credit} {
+		if err := postTransaction(tx, txn); err != nil {
+			return err
+		}
+		if err := insertTransaction(tx, txn); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetAccountIDsByUserID retrieves the IDs of the accounts a user owns or was
+// granted access to
+func (s *SQLiteStore) GetAccountIDsByUserID(userID string) ([]string, error) {
+	rows, err := s.db.Query(`SELECT id FROM accounts WHERE user_id = ?
+		UNION SELECT account_id FROM account_grants WHERE user_id = ?
+		ORDER BY id`, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accountIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		accountIDs = append(accountIDs, id)
+	}
+
+	return accountIDs, rows.Err()
+}
+
+// GetAccountAccess returns the user's access to the account
+func (s *SQLiteStore) GetAccountAccess(accountID, userID string) (string, error) {
+	var access string
+	err := s.db.QueryRow(`SELECT 'owner' FROM accounts WHERE id = ? AND user_id = ?
+		UNION ALL SELECT access FROM account_grants WHERE account_id = ? AND user_id = ?
+		LIMIT 1`, accountID, userID, accountID, userID).Scan(&access)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return access, err
+}
+
+// Close closes the underlying database
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// queryTransactions runs a query selecting transactionColumns
+func (s *SQLiteStore) queryTransactions(query string, args ...any) ([]*models.Transaction, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []*models.Transaction
+	for rows.Next() {
+		txn, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, txn)
+	}
+
+	return transactions, rows.Err()
+}
+
+// filterClauses translates transaction filters into WHERE clauses and arguments
+func (s *SQLiteStore) filterClauses(filters *models.TransactionFilters) ([]string, []any) {
+	var where []string
+	var args []any
+
+	if filters.AccountID != "" {
+		where = append(where, "account_id = ?")
+		args = append(args, filters.AccountID)
+	}
+	if filters.StartDate != nil {
+		where = append(where, "date >= ?")
+		args = append(args, formatTime(*filters.StartDate))
+	}
+	if filters.EndDate != nil {
+		where = append(where, "date <= ?")
+		args = append(args, formatTime(*filters.EndDate))
+	}
+	if filters.Category != "" {
+		where = append(where, "category = ?")
+		args = append(args, filters.Category)
+	}
+	if filters.MinAmount != nil {
+		where = append(where, "amount >= ?")
+		args = append(args, *filters.MinAmount)
+	}
+	if filters.MaxAmount != nil {
+		where = append(where, "amount <= ?")
+		args = append(args, *filters.MaxAmount)
+	}
+	if filters.Query != "" {
+		// Every term must start a word of the description or merchant, as
+		// with search.Index. A query without terms matches nothing.
+		terms := search.Terms(filters.Query)
+		if len(terms) == 0 {
+			where = append(where, "0")
+		} else {
+			for i, term := range terms {
+				terms[i] = `"` + term + `"*`
+			}
+			where = append(where, "id IN (SELECT id FROM transactions_fts WHERE transactions_fts MATCH ?)")
+			args = append(args, strings.Join(terms, " "))
+		}
+	}
+
+	return where, args
+}
+
+// insertTransaction writes a single transactions row
+func insertTransaction(tx *sql.Tx, txn *models.Transaction) error {
+	_, err := tx.Exec(`INSERT INTO transactions (`+transactionColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		txn.ID, txn.AccountID, formatTime(txn.Date), txn.Description, txn.Amount,
+		txn.Category, txn.Merchant, txn.Status, txn.Type, nullString(txn.TransferID), nullString(txn.ExternalID))
+	return err
+}
+
+// scanTransaction reads a transactions row selected with transactionColumns
+func scanTransaction(row rowScanner) (*models.Transaction, error) {
+	var t models.Transaction
+	var date string
+	var transferID, externalID sql.NullString
+	if err := row.Scan(&t.ID, &t.AccountID, &date, &t.Description, &t.Amount,
+		&t.Category, &t.Merchant, &t.Status, &t.Type, &transferID, &externalID); err != nil {
+		return nil, err
+	}
+	t.Date = parseTime(date)
+	t.TransferID = transferID.String
+	t.ExternalID = externalID.String
+	return &t, nil
+}
+
+// nullString stores empty optional columns as NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// scanAccount reads an accounts row selected with accountColumns
+func scanAccount(row rowScanner) (*Account, error) {
+	var acc Account
+	var creditLimit sql.NullFloat64
+	var lastActivity string
+	if err := row.Scan(&acc.ID, &acc.UserID, &acc.AccountType, &acc.AccountName, &acc.Currency,
+		&acc.Status, &acc.Balance, &creditLimit, &lastActivity); err != nil {
+		return nil, err
+	}
+	if creditLimit.Valid {
+		acc.CreditLimit = &creditLimit.Float64
+	}
+	acc.LastActivity = parseTime(lastActivity)
+	return &acc, nil
+}
+
+// postTransaction posts txn to its account within tx, as Account.Post does,
+// and fails when the account does not exist
+func postTransaction(tx *sql.Tx, txn *models.Transaction) error {
+	account, err := scanAccount(tx.QueryRow(`SELECT `+accountColumns+` FROM accounts WHERE id = ?`, txn.AccountID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("account not found")
+	}
+	if err != nil {
+		return err
+	}
+	if txn.Status != models.StatusCompleted {
+		return nil
+	}
+
+	// The new values are worked out in Go: api-accounts writes last_activity
+	// in a format that does not sort as text
+	account.Post(txn)
+	_, err = tx.Exec(`UPDATE accounts SET balance = ?, last_activity = ? WHERE id = ?`,
+		account.Balance, formatTime(account.LastActivity), account.ID)
+	return err
+}
+
+// formatTime encodes timestamps as fixed-width UTC text
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
+
+// parseTime decodes a timestamp written by formatTime
+func parseTime(s string) time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, s)
+	return t
+}
@@ -0,0 +1,349 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// testDataPath reuses the service fixtures
+var testDataPath = filepath.Join("..", "services", "testdata")
+
+func newTestLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+func TestSQLiteStoreFilter(t *testing.T) {
+	store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"), testDataPath, newTestLogger())
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer store.Close()
+
+	start := time.Date(2024, 12, 13, 0, 0, 0, 0, time.UTC)
+	minAmount := -100.0
+
+	tests := []struct {
+		name      string
+		filters   *models.TransactionFilters
+		wantCount int
+	}{
+		{"no filters", &models.TransactionFilters{}, 5},
+		{"by account", &models.TransactionFilters{AccountID: "acc-001"}, 2},
+		{"by start date", &models.TransactionFilters{StartDate: &start}, 4},
+		{"by category", &models.TransactionFilters{Category: "income"}, 2},
+		{"by min amount", &models.TransactionFilters{AccountID: "acc-001", MinAmount: &minAmount}, 1},
+		{"by search prefix", &models.TransactionFilters{Query: "GEI"}, 1},
+		{"by every search term", &models.TransactionFilters{Query: "payment cli"}, 1},
+		{"by search and account", &models.TransactionFilters{AccountID: "acc-001", Query: "payment"}, 0},
+		{"search without terms", &models.TransactionFilters{Query: "#!"}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txns, err := store.GetTransactionsByFilter(tt.filters)
+			if err != nil {
+				t.Fatalf("GetTransactionsByFilter failed: %v", err)
+			}
+			if len(txns) != tt.wantCount {
+				t.Errorf("Expected %d transactions, got %d", tt.wantCount, len(txns))
+			}
+		})
+	}
+}
+
+func TestSQLiteStorePersistsTransactions(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	first, err := NewSQLiteStore(dbPath, testDataPath, newTestLogger())
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+
+	txn := &models.Transaction{
+		ID:          "txn-new",
+		AccountID:   "acc-001",
+		Date:        time.Date(2024, 12, 14, 9, 30, 0, 0, time.UTC),
+		Description: "Corner Bakery",
+		Amount:      -12.40,
+		Category:    "food_dining",
+		Merchant:    "Corner Bakery",
+		Status:      "completed",
+		Type:        "debit",
+	}
+	if err := first.CreateTransaction(txn); err != nil {
+		t.Fatalf("CreateTransaction failed: %v", err)
+	}
+	if err := first.CreateTransaction(txn); err == nil {
+		t.Error("Expected duplicate ID to be rejected")
+	}
+	first.Close()
+
+	second, err := NewSQLiteStore(dbPath, testDataPath, newTestLogger())
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	defer second.Close()
+
+	stored, err := second.GetTransactionByID("txn-new")
+	if err != nil {
+		t.Fatalf("Transaction not persisted: %v", err)
+	}
+	if !stored.Date.Equal(txn.Date) || stored.Amount != txn.Amount {
+		t.Errorf("Stored transaction mismatch: %+v", stored)
+	}
+
+	all, err := second.GetAllTransactions()
+	if err != nil {
+		t.Fatalf("GetAllTransactions failed: %v", err)
+	}
+	if len(all) != 6 {
+		t.Errorf("Expected seed not to be re-imported, got %d transactions", len(all))
+	}
+}
+
+func TestSQLiteStoreSearchSeesOtherWriters(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	server, err := NewSQLiteStore(dbPath, testDataPath, newTestLogger())
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer server.Close()
+
+	// cmd/import writes through its own store on the same file
+	importer, err := NewSQLiteStore(dbPath, testDataPath, newTestLogger())
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer importer.Close()
+	if err := importer.CreateTransactions([]*models.Transaction{{
+		ID:          "txn-imported",
+		AccountID:   "acc-001",
+		Date:        time.Date(2024, 12, 14, 0, 0, 0, 0, time.UTC),
+		Description: "Corner Bakery",
+		Amount:      -12.40,
+		Category:    "food_dining",
+		Merchant:    "Corner Bakery",
+		Status:      "completed",
+		Type:        "debit",
+	}}); err != nil {
+		t.Fatalf("CreateTransactions failed: %v", err)
+	}
+
+	txns, err := server.GetTransactionsByFilter(&models.TransactionFilters{Query: "bak"})
+	if err != nil {
+		t.Fatalf("GetTransactionsByFilter failed: %v", err)
+	}
+	if len(txns) != 1 || txns[0].ID != "txn-imported" {
+		t.Errorf("Expected the imported transaction to be found, got %+v", txns)
+	}
+}
+
+func TestCreateTransactionsRejectsDuplicateExternalID(t *testing.T) {
+	jsonStore, err := NewJSONStore(testDataPath, newTestLogger())
+	if err != nil {
+		t.Fatalf("NewJSONStore failed: %v", err)
+	}
+	sqliteStore, err := NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"), testDataPath, newTestLogger())
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer sqliteStore.Close()
+
+	newTxn := func(id, externalID string) *models.Transaction {
+		return &models.Transaction{
+			ID:          id,
+			AccountID:   "acc-001",
+			Date:        time.Date(2024, 12, 14, 0, 0, 0, 0, time.UTC),
+			Description: "Corner Bakery",
+			Amount:      -12.40,
+			Category:    "food_dining",
+			Merchant:    "Corner Bakery",
+			Status:      "completed",
+			Type:        "debit",
+			ExternalID:  externalID,
+		}
+	}
+
+	for name, store := range map[string]Store{BackendJSON: jsonStore, BackendSQLite: sqliteStore} {
+		t.Run(name, func(t *testing.T) {
+			if err := store.CreateTransactions([]*models.Transaction{newTxn("txn-a", "B-1"), newTxn("txn-b", "")}); err != nil {
+				t.Fatalf("CreateTransactions failed: %v", err)
+			}
+
+			// The whole batch fails when one line reuses an external ID
+			err := store.CreateTransactions([]*models.Transaction{newTxn("txn-c", "B-2"), newTxn("txn-d", "B-1")})
+			if !errors.Is(err, ErrDuplicateExternalID) {
+				t.Fatalf("Expected ErrDuplicateExternalID, got %v", err)
+			}
+			if _, err := store.GetTransactionByID("txn-c"); err == nil {
+				t.Error("Expected no part of the failed batch to be stored")
+			}
+
+			stored, err := store.GetTransactionByID("txn-a")
+			if err != nil || stored.ExternalID != "B-1" {
+				t.Errorf("Expected txn-a with external ID B-1, got %+v (%v)", stored, err)
+			}
+
+			// Only the stored batch was posted to the account
+			account, err := store.GetAccountByID("acc-001")
+			if err != nil {
+				t.Fatalf("GetAccountByID failed: %v", err)
+			}
+			if account.Balance != 5822.52 || !account.LastActivity.Equal(time.Date(2024, 12, 14, 0, 0, 0, 0, time.UTC)) {
+				t.Errorf("Expected balance 5822.52 and last activity on 2024-12-14, got %v and %v", account.Balance, account.LastActivity)
+			}
+		})
+	}
+}
+
+func TestCreateTransactionMovesBalance(t *testing.T) {
+	jsonStore, err := NewJSONStore(testDataPath, newTestLogger())
+	if err != nil {
+		t.Fatalf("NewJSONStore failed: %v", err)
+	}
+	sqliteStore, err := NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"), testDataPath, newTestLogger())
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer sqliteStore.Close()
+
+	date := time.Date(2024, 12, 14, 9, 30, 0, 0, time.UTC)
+	newTxn := func(id, status string, amount float64) *models.Transaction {
+		return &models.Transaction{
+			ID:          id,
+			AccountID:   "acc-001",
+			Date:        date,
+			Description: "Corner Bakery",
+			Amount:      amount,
+			Category:    "food_dining",
+			Merchant:    "Corner Bakery",
+			Status:      status,
+			Type:        "debit",
+		}
+	}
+
+	for name, store := range map[string]Store{BackendJSON: jsonStore, BackendSQLite: sqliteStore} {
+		t.Run(name, func(t *testing.T) {
+			if err := store.CreateTransaction(newTxn("txn-a", models.StatusCompleted, -12.40)); err != nil {
+				t.Fatalf("CreateTransaction failed: %v", err)
+			}
+			// Pending and failed transactions leave the balance alone
+			if err := store.CreateTransaction(newTxn("txn-b", models.StatusPending, -7.60)); err != nil {
+				t.Fatalf("CreateTransaction failed: %v", err)
+			}
+			if err := store.CreateTransaction(newTxn("txn-c", models.StatusFailed, -100)); err != nil {
+				t.Fatalf("CreateTransaction failed: %v", err)
+			}
+
+			account, err := store.GetAccountByID("acc-001")
+			if err != nil {
+				t.Fatalf("GetAccountByID failed: %v", err)
+			}
+			if account.Balance != 5834.92 {
+				t.Errorf("Expected balance 5834.92, got %v", account.Balance)
+			}
+
+			// Last activity only moves forward, and only for completed transactions
+			if !account.LastActivity.Equal(date) {
+				t.Errorf("Expected last activity %v, got %v", date, account.LastActivity)
+			}
+			later := newTxn("txn-e", models.StatusPending, -1)
+			later.Date = date.Add(time.Hour)
+			backdated := newTxn("txn-f", models.StatusCompleted, -1)
+			backdated.Date = date.AddDate(0, 0, -30)
+			for _, txn := range []*models.Transaction{later, backdated} {
+				if err := store.CreateTransaction(txn); err != nil {
+					t.Fatalf("CreateTransaction failed: %v", err)
+				}
+			}
+			if account, _ := store.GetAccountByID("acc-001"); !account.LastActivity.Equal(date) || account.Balance != 5833.92 {
+				t.Errorf("Expected last activity %v and balance 5833.92, got %v and %v", date, account.LastActivity, account.Balance)
+			}
+
+			missing := newTxn("txn-d", models.StatusCompleted, -1)
+			missing.AccountID = "acc-999"
+			if err := store.CreateTransaction(missing); err == nil {
+				t.Error("Expected a transaction on an unknown account to fail")
+			}
+		})
+	}
+
+}
+
+// balanceAt works the account's balance back to the end of at from its
+// current balance over the completed transactions after it, the way
+// api-accounts builds balance history and statement opening balances
+func balanceAt(t *testing.T, store Store, accountID string, at time.Time) float64 {
+	t.Helper()
+	account, err := store.GetAccountByID(accountID)
+	if err != nil {
+		t.Fatalf("GetAccountByID failed: %v", err)
+	}
+	txns, err := store.GetTransactionsByFilter(&models.TransactionFilters{AccountID: accountID})
+	if err != nil {
+		t.Fatalf("GetTransactionsByFilter failed: %v", err)
+	}
+	balance := account.Balance
+	for _, txn := range txns {
+		if txn.Status == models.StatusCompleted && txn.Date.After(at) {
+			balance -= txn.Amount
+		}
+	}
+	return models.RoundCents(balance)
+}
+
+func TestBalanceMatchesCompletedPostings(t *testing.T) {
+	jsonStore, err := NewJSONStore(testDataPath, newTestLogger())
+	if err != nil {
+		t.Fatalf("NewJSONStore failed: %v", err)
+	}
+	sqliteStore, err := NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"), testDataPath, newTestLogger())
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer sqliteStore.Close()
+
+	seedEnd := time.Date(2024, 12, 13, 23, 59, 59, 0, time.UTC)
+	for name, store := range map[string]Store{BackendJSON: jsonStore, BackendSQLite: sqliteStore} {
+		t.Run(name, func(t *testing.T) {
+			before := balanceAt(t, store, "acc-001", seedEnd)
+
+			for i, status := range []string{models.StatusCompleted, models.StatusPending, models.StatusFailed, models.StatusCompleted} {
+				err := store.CreateTransaction(&models.Transaction{
+					ID:          fmt.Sprintf("txn-x%d", i),
+					AccountID:   "acc-001",
+					Date:        seedEnd.Add(time.Duration(i+1) * time.Hour),
+					Description: "Corner Bakery",
+					Amount:      -10.25,
+					Category:    "food_dining",
+					Merchant:    "Corner Bakery",
+					Status:      status,
+					Type:        "debit",
+				})
+				if err != nil {
+					t.Fatalf("CreateTransaction failed: %v", err)
+				}
+			}
+
+			imported := &models.Transaction{ID: "txn-x9", AccountID: "acc-001", Date: seedEnd.Add(5 * time.Hour),
+				Description: "Payroll", Amount: 2500, Category: "income", Merchant: "Acme", Status: models.StatusCompleted, Type: "credit"}
+			if err := store.CreateTransactions([]*models.Transaction{imported}); err != nil {
+				t.Fatalf("CreateTransactions failed: %v", err)
+			}
+
+			// Whatever was created or imported, the history up to then is unchanged
+			if after := balanceAt(t, store, "acc-001", seedEnd); after != before {
+				t.Errorf("Expected the balance at %v to stay %v, got %v", seedEnd, before, after)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Index is an in-memory inverted index from case-folded terms to document IDs.
+// Query terms match any indexed term they are a prefix of, so "gei" finds "Geico".
+type Index struct {
+	postings map[string]map[string]struct{} // term -> document IDs
+	terms    []string                       // sorted keys of postings, for prefix lookups
+	mu       sync.RWMutex
+}
+
+// NewIndex creates an empty index
+func NewIndex() *Index {
+	return &Index{
+		postings: make(map[string]map[string]struct{}),
+	}
+}
+
+// Add indexes the given texts under the document ID
+func (ix *Index) Add(id string, texts ...string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	for _, text := range texts {
+		for _, tok := range tokenize(text) {
+			docs, exists := ix.postings[tok.term]
+			if !exists {
+				docs = make(map[string]struct{})
+				ix.postings[tok.term] = docs
+
+				// Keep terms sorted as we go so lookups never need a rebuild
+				i := sort.SearchStrings(ix.terms, tok.term)
+				ix.terms = append(ix.terms, "")
+				copy(ix.terms[i+1:], ix.terms[i:])
+				ix.terms[i] = tok.term
+			}
+			docs[id] = struct{}{}
+		}
+	}
+}
+
+// Search returns the IDs of documents matching every term in the query.
+// A query without any terms matches nothing.
+func (ix *Index) Search(query string) map[string]struct{} {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	var result map[string]struct{}
+	for _, term := range Terms(query) {
+		matches := make(map[string]struct{})
+		for i := sort.SearchStrings(ix.terms, term); i < len(ix.terms) && strings.HasPrefix(ix.terms[i], term); i++ {
+			for id := range ix.postings[ix.terms[i]] {
+				if result == nil {
+					matches[id] = struct{}{}
+				} else if _, ok := result[id]; ok {
+					matches[id] = struct{}{}
+				}
+			}
+		}
+		result = matches
+		if len(result) == 0 {
+			break
+		}
+	}
+
+	if result == nil {
+		result = map[string]struct{}{}
+	}
+	return result
+}
+
+// Len returns the number of distinct terms in the index
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.terms)
+}
+
+// Terms returns the distinct case-folded terms of a query, in order
+func Terms(query string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, tok := range tokenize(query) {
+		if !seen[tok.term] {
+			seen[tok.term] = true
+			terms = append(terms, tok.term)
+		}
+	}
+	return terms
+}
+
+// Segment is a run of text that either matched the query or did not
+type Segment struct {
+	Text  string `json:"text"`
+	Match bool   `json:"match,omitempty"`
+}
+
+// Highlight splits text into segments, marking every word that a query term is
+// a prefix of. It returns nil when nothing in the text matches.
+func Highlight(text, query string) []Segment {
+	terms := Terms(query)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	var segments []Segment
+	matched := false
+	last := 0
+	for _, tok := range tokenize(text) {
+		if !matchesAny(tok.term, terms) {
+			continue
+		}
+		if tok.start > last {
+			segments = append(segments, Segment{Text: text[last:tok.start]})
+		}
+		segments = append(segments, Segment{Text: text[tok.start:tok.end], Match: true})
+		last = tok.end
+		matched = true
+	}
+	if !matched {
+		return nil
+	}
+	if last < len(text) {
+		segments = append(segments, Segment{Text: text[last:]})
+	}
+
+	return segments
+}
+
+// matchesAny reports whether any query term is a prefix of term
+func matchesAny(term string, queryTerms []string) bool {
+	for _, q := range queryTerms {
+		if strings.HasPrefix(term, q) {
+			return true
+		}
+	}
+	return false
+}
+
+// token is a word in a text, with its byte offsets and case-folded form
+type token struct {
+	term       string
+	start, end int
+}
+
+// tokenize splits text into runs of letters and digits
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			tokens = append(tokens, token{term: fold(text[start:i]), start: start, end: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{term: fold(text[start:]), start: start, end: len(text)})
+	}
+	return tokens
+}
+
+// fold returns the case-folded form of a word
+func fold(word string) string {
+	return strings.ToLower(word)
+}
@@ -0,0 +1,111 @@
+package search
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func sortedIDs(ids map[string]struct{}) []string {
+	result := make([]string, 0, len(ids))
+	for id := range ids {
+		result = append(result, id)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func TestIndexSearch(t *testing.T) {
+	ix := NewIndex()
+	ix.Add("txn-001", "Starbucks Coffee", "Starbucks")
+	ix.Add("txn-002", "GEICO Auto Insurance", "Geico")
+	ix.Add("txn-003", "Geico Premium", "Geico")
+	ix.Add("txn-004", "Café Olé", "Café-Olé")
+	ix.Add("txn-005", "Client Payment - Invoice #1234", "Client Corp")
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"exact term", "coffee", []string{"txn-001"}},
+		{"case folded", "geico", []string{"txn-002", "txn-003"}},
+		{"prefix", "gei", []string{"txn-002", "txn-003"}},
+		{"all terms must match", "geico premium", []string{"txn-003"}},
+		{"terms in any field", "insurance geico", []string{"txn-002"}},
+		{"unicode", "CAFÉ", []string{"txn-004"}},
+		{"digits", "12", []string{"txn-005"}},
+		{"punctuation ignored", "#1234!", []string{"txn-005"}},
+		{"no match", "walmart", []string{}},
+		{"one term missing", "geico walmart", []string{}},
+		{"no terms", "  -- ", []string{}},
+		{"prefix only, not substring", "ico", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sortedIDs(ix.Search(tt.query))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndexAddIsIncremental(t *testing.T) {
+	ix := NewIndex()
+	ix.Add("txn-001", "Zebra Supplies")
+	ix.Add("txn-002", "Aardvark Market")
+	ix.Add("txn-003", "Market Street Deli")
+
+	if got := sortedIDs(ix.Search("market")); !reflect.DeepEqual(got, []string{"txn-002", "txn-003"}) {
+		t.Errorf("Expected both market transactions, got %v", got)
+	}
+	if ix.Len() != 6 {
+		t.Errorf("Expected 6 distinct terms, got %d", ix.Len())
+	}
+	for i := 1; i < len(ix.terms); i++ {
+		if ix.terms[i-1] >= ix.terms[i] {
+			t.Fatalf("Terms not kept sorted: %v", ix.terms)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		query string
+		want  []Segment
+	}{
+		{
+			"prefix marks whole word",
+			"GEICO Auto Insurance",
+			"gei",
+			[]Segment{{Text: "GEICO", Match: true}, {Text: " Auto Insurance"}},
+		},
+		{
+			"several matches",
+			"Client Payment - Invoice #1234",
+			"inv 1234",
+			[]Segment{{Text: "Client Payment - "}, {Text: "Invoice", Match: true}, {Text: " #"}, {Text: "1234", Match: true}},
+		},
+		{
+			"whole text",
+			"Geico",
+			"geico",
+			[]Segment{{Text: "Geico", Match: true}},
+		},
+		{"no match", "Starbucks Coffee", "tea", nil},
+		{"empty query", "Starbucks Coffee", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Highlight(tt.text, tt.query)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Highlight(%q, %q) = %+v, want %+v", tt.text, tt.query, got, tt.want)
+			}
+		})
+	}
+}
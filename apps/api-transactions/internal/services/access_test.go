@@ -0,0 +1,88 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/models"
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/repository"
+)
+
+// newSharedStore opens the testdata seed in SQLite after api-accounts has
+// granted user-002 read-only access to acc-001 and joint access to acc-003
+func newSharedStore(t *testing.T) repository.Store {
+	t.Helper()
+	dbPath := filepath.Join(t.TempDir(), "shared.db")
+	repo, err := repository.NewStore(repository.Config{
+		Backend:    repository.BackendSQLite,
+		DataPath:   "testdata",
+		SQLitePath: dbPath,
+	}, newTestLogger())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+
+	// Rows as api-accounts writes them when an invitation is accepted
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	for _, grant := range []struct{ accountID, access string }{
+		{"acc-001", repository.AccessViewer},
+		{"acc-003", repository.AccessJoint},
+	} {
+		if _, err := db.Exec(`INSERT INTO account_grants (account_id, user_id, access, granted_by, created_at)
+			VALUES (?, 'user-002', ?, 'user-001', '2024-12-01T12:00:00Z')`, grant.accountID, grant.access); err != nil {
+			t.Fatalf("Failed to grant access: %v", err)
+		}
+	}
+	return repo
+}
+
+func TestSharedAccountAccess(t *testing.T) {
+	repo := newSharedStore(t)
+	service := NewTransactionService(repo, nil, newTestLogger())
+	transfers := NewTransferService(repo, newTestLogger())
+
+	// Shared accounts are listed with the user's own; acc-002 stays private
+	ids := collectPages(t, service, "user-002", models.PageRequest{})
+	seen := map[string]bool{}
+	for _, id := range ids {
+		seen[id] = true
+	}
+	for _, id := range []string{"txn-001", "txn-011", "txn-021"} {
+		if !seen[id] {
+			t.Errorf("Expected %s in user-002's transactions, got %v", id, ids)
+		}
+	}
+	if seen["txn-005"] {
+		t.Errorf("Expected acc-002 to stay private, got %v", ids)
+	}
+	if _, err := service.GetTransactionByID("user-002", "txn-001"); err != nil {
+		t.Errorf("Expected a viewer to read a transaction, got %v", err)
+	}
+	if _, err := service.GetTransactionByID("user-002", "txn-005"); !errors.Is(err, ErrTransactionNotFound) {
+		t.Errorf("Expected ErrTransactionNotFound on an unshared account, got %v", err)
+	}
+
+	// Viewers cannot post; joint holders can
+	viewerTxn := models.Transaction{AccountID: "acc-001", Description: "Coffee", Amount: -4.50, Category: "food_dining", Type: "debit"}
+	if _, err := service.CreateTransaction("user-002", &viewerTxn); !errors.Is(err, ErrReadOnlyAccess) {
+		t.Errorf("Expected a viewer to be refused, got %v", err)
+	}
+	jointTxn := models.Transaction{AccountID: "acc-003", Description: "Coffee", Amount: -4.50, Category: "food_dining", Type: "debit"}
+	if _, err := service.CreateTransaction("user-002", &jointTxn); err != nil {
+		t.Errorf("Expected a joint holder to post, got %v", err)
+	}
+
+	if _, err := transfers.CreateTransfer("user-002", &models.Transfer{FromAccountID: "acc-004", ToAccountID: "acc-003", Amount: 100}); err != nil {
+		t.Errorf("Expected a transfer into a joint account, got %v", err)
+	}
+	if _, err := transfers.CreateTransfer("user-002", &models.Transfer{FromAccountID: "acc-001", ToAccountID: "acc-004", Amount: 100}); !errors.Is(err, ErrReadOnlyAccess) {
+		t.Errorf("Expected a viewer not to move money, got %v", err)
+	}
+}
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/importer"
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/models"
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/repository"
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/search"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrInvalidImport wraps statements that cannot be read or accounts that cannot receive them
+	ErrInvalidImport = errors.New("invalid import")
+	// ErrImportConflict is returned when lines of the statement were stored by a concurrent import
+	ErrImportConflict = errors.New("import conflict")
+)
+
+// ImportRequest describes a bank statement to import into one account
+type ImportRequest struct {
+	AccountID string
+	Format    string               // importer.FormatCSV or importer.FormatOFX
+	Mapping   *importer.CSVMapping // CSV column mapping; nil uses importer.DefaultCSVMapping
+	Commit    bool                 // Store the new transactions; otherwise only report them
+}
+
+// ImportService handles business logic for statement imports
+type ImportService struct {
+	repo   repository.Store
+	logger *logrus.Logger
+}
+
+// NewImportService creates a new import service
+func NewImportService(repo repository.Store, logger *logrus.Logger) *ImportService {
+	return &ImportService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// ImportStatement parses a statement and reports, line by line, which entries
+// are new, already on the account, or invalid. Lines are duplicates when their
+// FITID matches a stored transaction or an earlier line, or when a stored
+// transaction has the same day, amount and merchant. Only when req.Commit is
+// set are the new lines stored, all at once.
+func (s *ImportService) ImportStatement(userID string, req *ImportRequest, r io.Reader) (*models.ImportReport, error) {
+	account, err := accessibleAccount(s.repo, s.logger, req.AccountID, userID, true)
+	if err != nil {
+		return nil, err
+	}
+	if account.Status != "active" {
+		return nil, fmt.Errorf("%w: account is %s", ErrInvalidImport, account.Status)
+	}
+
+	records, err := importer.Parse(req.Format, r, req.Mapping)
+	if err != nil {
+		// Keep the cause too, so callers can tell an oversized upload apart
+		return nil, fmt.Errorf("%w: %w", ErrInvalidImport, err)
+	}
+
+	existing, err := s.repo.ListTransactions(&models.TransactionQuery{
+		AccountIDs: []string{account.ID},
+		Sort:       models.SortDate,
+		Order:      models.OrderAsc,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.ImportReport{
+		AccountID: account.ID,
+		Format:    req.Format,
+		Rows:      make([]*models.ImportRow, 0, len(records)),
+	}
+	matcher := newDuplicateMatcher(existing)
+	var created []*models.Transaction
+
+	for _, record := range records {
+		row := &models.ImportRow{Line: record.Line}
+		report.Rows = append(report.Rows, row)
+
+		if record.Err != nil {
+			row.Status = models.ImportStatusInvalid
+			row.Error = record.Err.Error()
+			report.Invalid++
+			continue
+		}
+
+		txn := recordTransaction(account.ID, record)
+		row.Transaction = txn
+		if err := txn.Validate(); err != nil {
+			row.Status = models.ImportStatusInvalid
+			row.Error = err.Error()
+			report.Invalid++
+			continue
+		}
+
+		if matcher.match(record, txn, row) {
+			row.Status = models.ImportStatusDuplicate
+			report.Duplicates++
+			continue
+		}
+
+		row.Status = models.ImportStatusNew
+		report.New++
+		created = append(created, txn)
+	}
+
+	if !req.Commit {
+		return report, nil
+	}
+
+	for _, txn := range created {
+		if txn.ID, err = newTransactionID(); err != nil {
+			return nil, err
+		}
+	}
+	if len(created) > 0 {
+		if err := s.repo.CreateTransactions(created); err != nil {
+			if errors.Is(err, repository.ErrDuplicateExternalID) {
+				return nil, ErrImportConflict
+			}
+			s.logger.WithError(err).WithField("accountId", account.ID).Error("Failed to store imported transactions")
+			return nil, err
+		}
+	}
+	report.Committed = true
+
+	s.logger.WithFields(logrus.Fields{
+		"userId":     userID,
+		"accountId":  account.ID,
+		"format":     req.Format,
+		"new":        report.New,
+		"duplicates": report.Duplicates,
+		"invalid":    report.Invalid,
+	}).Info("Imported statement")
+
+	return report, nil
+}
+
+// recordTransaction maps a statement line onto a transaction for the account
+func recordTransaction(accountID string, record *importer.Record) *models.Transaction {
+	txn := &models.Transaction{
+		AccountID:   accountID,
+		Date:        record.Date,
+		Description: record.Description,
+		Amount:      models.RoundCents(record.Amount),
+		Category:    record.Category,
+		Merchant:    record.Merchant,
+		Status:      models.StatusCompleted,
+		Type:        models.TypeDebit,
+		ExternalID:  record.FITID,
+	}
+	if txn.Amount > 0 {
+		txn.Type = models.TypeCredit
+	}
+	if txn.Description == "" {
+		txn.Description = txn.Merchant
+	}
+	if txn.Merchant == "" {
+		txn.Merchant = txn.Description
+	}
+	if txn.Category == "" {
+		txn.Category = models.CategoryUncategorized
+	}
+	return txn
+}
+
+// duplicateMatcher finds statement lines that are already on the account.
+// Each stored transaction matches at most one line, so two identical coffees
+// on the same day are only both skipped if both are already stored.
+type duplicateMatcher struct {
+	byExternalID  map[string]string                // FITID -> stored transaction ID
+	byID          map[string]bool                  // Stored IDs, matched by FITIDs of re-imported exports
+	byFingerprint map[string][]*models.Transaction // fingerprint -> stored transactions
+	used          map[string]bool                  // Stored transactions already matched
+	lines         map[string]int                   // FITID -> first line of the file carrying it
+}
+
+func newDuplicateMatcher(existing []*models.Transaction) *duplicateMatcher {
+	m := &duplicateMatcher{
+		byExternalID:  make(map[string]string),
+		byID:          make(map[string]bool),
+		byFingerprint: make(map[string][]*models.Transaction),
+		used:          make(map[string]bool),
+		lines:         make(map[string]int),
+	}
+	for _, txn := range existing {
+		if txn.ExternalID != "" {
+			m.byExternalID[txn.ExternalID] = txn.ID
+		}
+		m.byID[txn.ID] = true
+		fp := fingerprint(txn)
+		m.byFingerprint[fp] = append(m.byFingerprint[fp], txn)
+	}
+	return m
+}
+
+// match reports whether the line duplicates a stored transaction or an
+// earlier line, recording what it matched on the row
+func (m *duplicateMatcher) match(record *importer.Record, txn *models.Transaction, row *models.ImportRow) bool {
+	if fitid := record.FITID; fitid != "" {
+		if line, seen := m.lines[fitid]; seen {
+			row.DuplicateOfLine = line
+			row.MatchedBy = models.MatchFITID
+			return true
+		}
+		m.lines[fitid] = record.Line
+
+		id, found := m.byExternalID[fitid]
+		if !found && m.byID[fitid] {
+			id, found = fitid, true
+		}
+		if found {
+			m.used[id] = true
+			row.DuplicateOf = id
+			row.MatchedBy = models.MatchFITID
+			return true
+		}
+	}
+
+	for _, candidate := range m.byFingerprint[fingerprint(txn)] {
+		// Lines with different bank IDs are different lines, however alike
+		if m.used[candidate.ID] || (record.FITID != "" && candidate.ExternalID != "") {
+			continue
+		}
+		m.used[candidate.ID] = true
+		row.DuplicateOf = candidate.ID
+		row.MatchedBy = models.MatchFingerprint
+		return true
+	}
+
+	return false
+}
+
+// fingerprint identifies a transaction by posting day, amount in cents and
+// normalised merchant name, for statements that carry no FITID
+func fingerprint(txn *models.Transaction) string {
+	merchant := txn.Merchant
+	if merchant == "" {
+		merchant = txn.Description
+	}
+	return fmt.Sprintf("%s|%d|%s", txn.Date.UTC().Format("2006-01-02"),
+		int64(math.Round(txn.Amount*100)), strings.Join(search.Terms(merchant), " "))
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/importer"
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/models"
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/repository"
+)
+
+// statementCSV holds, in order: a line already stored as txn-001 (matched by
+// fingerprint), a new line, the same FITID again, the FITID of a stored
+// transaction, and a line with a zero amount
+const statementCSV = `id,date,description,merchant,amount
+,2024-12-13,Coffee,STARBUCKS,-5.47
+B-100,2024-12-14,Corner Bakery,Corner Bakery,-12.40
+B-100,2024-12-14,Corner Bakery,Corner Bakery,-12.40
+txn-025,2024-12-13,Geico Premium,Geico,-309.62
+,2024-12-15,Nothing,Nobody,0
+`
+
+func TestImportStatementDryRun(t *testing.T) {
+	for _, backend := range []string{repository.BackendJSON, repository.BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			repo := newTestStore(t, backend)
+			service := NewImportService(repo, newTestLogger())
+
+			report, err := service.ImportStatement("user-001", &ImportRequest{
+				AccountID: "acc-001",
+				Format:    importer.FormatCSV,
+			}, strings.NewReader(statementCSV))
+			if err != nil {
+				t.Fatalf("ImportStatement failed: %v", err)
+			}
+
+			if report.Committed || report.New != 1 || report.Duplicates != 3 || report.Invalid != 1 {
+				t.Errorf("Unexpected totals: committed=%v new=%d duplicates=%d invalid=%d",
+					report.Committed, report.New, report.Duplicates, report.Invalid)
+			}
+
+			want := []struct {
+				status, duplicateOf, matchedBy string
+				duplicateOfLine                int
+			}{
+				{models.ImportStatusDuplicate, "txn-001", models.MatchFingerprint, 0},
+				{models.ImportStatusNew, "", "", 0},
+				{models.ImportStatusDuplicate, "", models.MatchFITID, 3},
+				{models.ImportStatusDuplicate, "txn-025", models.MatchFITID, 0},
+				{models.ImportStatusInvalid, "", "", 0},
+			}
+			if len(report.Rows) != len(want) {
+				t.Fatalf("Expected %d rows, got %d", len(want), len(report.Rows))
+			}
+			for i, row := range report.Rows {
+				w := want[i]
+				if row.Status != w.status || row.DuplicateOf != w.duplicateOf ||
+					row.MatchedBy != w.matchedBy || row.DuplicateOfLine != w.duplicateOfLine {
+					t.Errorf("Row %d: expected %+v, got %+v", i, w, row)
+				}
+			}
+
+			// A dry run stores nothing
+			txns, _ := repo.ListTransactions(&models.TransactionQuery{AccountIDs: []string{"acc-001"}})
+			if len(txns) != 2 {
+				t.Errorf("Expected acc-001 to still hold 2 transactions, got %d", len(txns))
+			}
+		})
+	}
+}
+
+func TestImportStatementCommit(t *testing.T) {
+	for _, backend := range []string{repository.BackendJSON, repository.BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			repo := newTestStore(t, backend)
+			service := NewImportService(repo, newTestLogger())
+			req := &ImportRequest{AccountID: "acc-001", Format: importer.FormatCSV, Commit: true}
+
+			report, err := service.ImportStatement("user-001", req, strings.NewReader(statementCSV))
+			if err != nil {
+				t.Fatalf("ImportStatement failed: %v", err)
+			}
+			if !report.Committed || report.New != 1 {
+				t.Fatalf("Expected one committed transaction, got %+v", report)
+			}
+
+			created := report.Rows[1].Transaction
+			stored, err := repo.GetTransactionByID(created.ID)
+			if err != nil {
+				t.Fatalf("Imported transaction not stored: %v", err)
+			}
+			if stored.ExternalID != "B-100" || stored.Category != models.CategoryUncategorized || stored.Type != models.TypeDebit {
+				t.Errorf("Unexpected stored transaction: %+v", stored)
+			}
+
+			// Importing the same statement again adds nothing
+			again, err := service.ImportStatement("user-001", req, strings.NewReader(statementCSV))
+			if err != nil {
+				t.Fatalf("Second ImportStatement failed: %v", err)
+			}
+			if again.New != 0 || again.Rows[1].DuplicateOf != created.ID || again.Rows[1].MatchedBy != models.MatchFITID {
+				t.Errorf("Expected the re-import to match %s by FITID, got %+v", created.ID, again.Rows[1])
+			}
+		})
+	}
+}
+
+func TestImportStatementErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		userID  string
+		req     ImportRequest
+		input   string
+		wantErr error
+	}{
+		{"another user's account", "user-001", ImportRequest{AccountID: "acc-004", Format: importer.FormatCSV}, statementCSV, ErrUnauthorized},
+		{"missing account", "user-001", ImportRequest{AccountID: "acc-404", Format: importer.FormatCSV}, statementCSV, ErrAccountNotFound},
+		{"closed account", "user-001", ImportRequest{AccountID: "acc-009", Format: importer.FormatCSV}, statementCSV, ErrInvalidImport},
+		{"unreadable file", "user-001", ImportRequest{AccountID: "acc-001", Format: importer.FormatOFX}, statementCSV, ErrInvalidImport},
+	}
+
+	for _, backend := range []string{repository.BackendJSON, repository.BackendSQLite} {
+		for _, tt := range tests {
+			t.Run(backend+"/"+tt.name, func(t *testing.T) {
+				service := NewImportService(newTestStore(t, backend), newTestLogger())
+				_, err := service.ImportStatement(tt.userID, &tt.req, strings.NewReader(tt.input))
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Expected %v, got %v", tt.wantErr, err)
+				}
+			})
+		}
+	}
+}
@@ -1,24 +1,47 @@
 package services
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
 	"sort"
 	"time"
 
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/export"
 	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/features"
 	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/models"
 	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/repository"
 	"github.com/sirupsen/logrus"
 )
 
+var (
+	// ErrInvalidTransaction wraps validation failures for new transactions
+	ErrInvalidTransaction = errors.New("invalid transaction")
+	// ErrTransactionNotFound is returned when a transaction does not exist or belongs to another user
+	ErrTransactionNotFound = errors.New("transaction not found")
+	// ErrInvalidPageRequest is returned when limit, sort, order or cursor are invalid
+	ErrInvalidPageRequest = errors.New("invalid page request")
+	// ErrAccountNotFound is returned when the owning account does not exist
+	ErrAccountNotFound = errors.New("account not found")
+	// ErrUnauthorized is returned when the user has no access to the account.
+	// It is answered like ErrAccountNotFound so that other users' accounts
+	// cannot be told apart from missing ones.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrReadOnlyAccess is returned when a viewer of a shared account writes to it
+	ErrReadOnlyAccess = errors.New("this account is shared with you read-only")
+)
+
 // TransactionService handles business logic for transactions
 type TransactionService struct {
-	repo   *repository.Repository
+	repo   repository.Store
 	flags  *features.Flags
 	logger *logrus.Logger
 }
 
 // NewTransactionService creates a new transaction service
-func NewTransactionService(repo *repository.Repository, flags *features.Flags, logger *logrus.Logger) *TransactionService {
+func NewTransactionService(repo repository.Store, flags *features.Flags, logger *logrus.Logger) *TransactionService {
 	return &TransactionService{
 		repo:   repo,
 		flags:  flags,
@@ -27,69 +50,344 @@ func NewTransactionService(repo *repository.Repository, flags *features.Flags, l
 }
 
 // GetTransactionByID retrieves a transaction by ID
-func (s *TransactionService) GetTransactionByID(txnID string) (*models.Transaction, error) {
-	return s.repo.GetTransactionByID(txnID)
+// IMPORTANT: This enforces user isolation - a transaction on an account the user
+// cannot access is reported as not found so that IDs cannot be enumerated
+func (s *TransactionService) GetTransactionByID(userID string, txnID string) (*models.Transaction, error) {
+	txn, err := s.repo.GetTransactionByID(txnID)
+	if errors.Is(err, repository.ErrTransactionNotFound) {
+		return nil, ErrTransactionNotFound
+	}
+	if err != nil {
+		s.logger.WithError(err).WithField("txnId", txnID).Error("Failed to retrieve transaction")
+		return nil, err
+	}
+
+	access, err := s.repo.GetAccountAccess(txn.AccountID, userID)
+	if err != nil {
+		s.logger.WithError(err).WithField("txnId", txnID).Error("Failed to check account access")
+		return nil, err
+	}
+	if access == "" {
+		s.logger.WithFields(logrus.Fields{
+			"txnId":     txnID,
+			"accountId": txn.AccountID,
+			"userId":    userID,
+		}).Warn("Unauthorized access attempt")
+		return nil, ErrTransactionNotFound
+	}
+
+	return txn, nil
 }
 
-// GetTransactions retrieves transactions with optional filters
-// Advanced filters (date range, amount range, category) are only applied if feature flag is enabled
+// GetTransactions retrieves every transaction matching the filters, most recent first
 // IMPORTANT: This enforces user isolation - only returns transactions for the specified user's accounts
 func (s *TransactionService) GetTransactions(userID string, filters *models.TransactionFilters) ([]*models.Transaction, error) {
+	page, err := s.ListTransactions(userID, filters, &models.PageRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return page.Transactions, nil
+}
+
+// ListTransactions retrieves one page of transactions with optional filters and search
+// Advanced filters (date range, amount range, category) are only applied if feature flag is enabled
+// IMPORTANT: This enforces user isolation - only returns transactions for the specified user's accounts
+func (s *TransactionService) ListTransactions(userID string, filters *models.TransactionFilters, page *models.PageRequest) (*models.TransactionPage, error) {
+	if err := page.Normalize(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPageRequest, err)
+	}
+
+	query := &models.TransactionQuery{
+		Sort:  page.Sort,
+		Order: page.Order,
+	}
+
+	if page.Cursor != "" {
+		cursor, err := models.DecodeCursor(page.Cursor)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPageRequest, err)
+		}
+		if cursor.Sort != page.Sort || cursor.Order != page.Order {
+			return nil, fmt.Errorf("%w: cursor was issued for a different sort or order", ErrInvalidPageRequest)
+		}
+		query.After = cursor
+	}
+
 	// Get all account IDs for this user (enforces user isolation)
-	userAccountIDs := s.repo.GetAccountIDsByUserID(userID)
+	userAccountIDs, err := s.repo.GetAccountIDsByUserID(userID)
+	if err != nil {
+		s.logger.WithError(err).WithField("userId", userID).Error("Failed to retrieve user accounts")
+		return nil, err
+	}
 	if len(userAccountIDs) == 0 {
 		s.logger.WithField("userId", userID).Warn("No accounts found for user")
-		return []*models.Transaction{}, nil
+		return &models.TransactionPage{Transactions: []*models.Transaction{}}, nil
+	}
+
+	// Narrow to a single account when requested; another user's account matches nothing
+	query.AccountIDs = userAccountIDs
+	if filters.AccountID != "" {
+		query.AccountIDs = nil
+		for _, id := range userAccountIDs {
+			if id == filters.AccountID {
+				query.AccountIDs = []string{id}
+				break
+			}
+		}
 	}
 
 	s.logger.WithFields(logrus.Fields{
 		"userId":     userID,
-		"accountIds": userAccountIDs,
+		"accountIds": query.AccountIDs,
 	}).Debug("Filtering transactions by user accounts")
 
-	// Check if advanced filters are enabled
-	advancedFiltersEnabled := s.flags.IsAdvancedFiltersEnabled()
+	// Only apply advanced filters if the feature flag is enabled
+	if s.flags.IsAdvancedFiltersEnabled() {
+		query.Filters = models.TransactionFilters{
+			StartDate: filters.StartDate,
+			EndDate:   filters.EndDate,
+			Category:  filters.Category,
+			MinAmount: filters.MinAmount,
+			MaxAmount: filters.MaxAmount,
+		}
+	} else if filters.StartDate != nil || filters.EndDate != nil || filters.Category != "" ||
+		filters.MinAmount != nil || filters.MaxAmount != nil {
+		// Log that advanced filters are ignored
+		s.logger.Info("Advanced filters requested but feature flag is disabled, only accountId filter will be applied")
+	}
+
+	// Full-text search is always available
+	query.Filters.Query = filters.Query
+
+	// Fetch one extra row to find out whether there is a next page
+	if page.Limit > 0 {
+		query.Limit = page.Limit + 1
+	}
+
+	transactions, err := s.repo.ListTransactions(query)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.TransactionPage{Transactions: transactions}
+	if page.Limit > 0 && len(transactions) > page.Limit {
+		result.Transactions = transactions[:page.Limit]
+		last := result.Transactions[page.Limit-1]
+		result.NextCursor = models.NewCursor(last, page.Sort, page.Order).Encode()
+	}
+	if result.Transactions == nil {
+		result.Transactions = []*models.Transaction{}
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"userId":  userID,
+		"count":   len(result.Transactions),
+		"hasNext": result.NextCursor != "",
+	}).Info("Retrieved transactions for user")
+
+	return result, nil
+}
+
+// ExportTransactions streams every transaction matching the filters to w in the
+// given export format. Accounts are exported one at a time, bank accounts before
+// credit accounts as OFX requires, and transactions are read a page at a time in
+// date order so the full result set is never held in memory.
+// IMPORTANT: This enforces user isolation - only accounts the user can access are exported
+func (s *TransactionService) ExportTransactions(userID string, filters *models.TransactionFilters, format string, w io.Writer) error {
+	enc, err := export.NewEncoder(format, w, export.Options{MaskAmounts: s.flags.ShouldMaskAmounts()})
+	if err != nil {
+		return err
+	}
+
+	accountIDs, err := s.repo.GetAccountIDsByUserID(userID)
+	if err != nil {
+		s.logger.WithError(err).WithField("userId", userID).Error("Failed to retrieve user accounts")
+		return err
+	}
+
+	var accounts []*repository.Account
+	for _, id := range accountIDs {
+		if filters.AccountID != "" && id != filters.AccountID {
+			continue
+		}
+		account, err := s.repo.GetAccountByID(id)
+		if err != nil {
+			return err
+		}
+		accounts = append(accounts, account)
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		iCredit, jCredit := accounts[i].AccountType == "credit", accounts[j].AccountType == "credit"
+		if iCredit != jCredit {
+			return jCredit
+		}
+		return accounts[i].ID < accounts[j].ID
+	})
+
+	for _, account := range accounts {
+		if err := s.exportAccount(userID, filters, account, enc); err != nil {
+			return err
+		}
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"userId":   userID,
+		"format":   format,
+		"accounts": len(accounts),
+	}).Info("Exported transactions for user")
+
+	return enc.Close()
+}
+
+// exportAccount streams one account's matching transactions to the encoder,
+// skipping accounts with nothing to export
+func (s *TransactionService) exportAccount(userID string, filters *models.TransactionFilters, account *repository.Account, enc export.Encoder) error {
+	accountFilters := *filters
+	accountFilters.AccountID = account.ID
+
+	// Statement headers carry the period, so find the last date up front
+	last, err := s.ListTransactions(userID, &accountFilters, &models.PageRequest{Limit: 1, Order: models.OrderDesc})
+	if err != nil {
+		return err
+	}
+	if len(last.Transactions) == 0 {
+		return nil
+	}
 
-	// Get transactions for all user's accounts
-	var allTransactions []*models.Transaction
+	pageRequest := &models.PageRequest{Limit: models.MaxPageSize, Order: models.OrderAsc}
+	for began := false; ; {
+		page, err := s.ListTransactions(userID, &accountFilters, pageRequest)
+		if err != nil {
+			return err
+		}
 
-	for _, accountID := range userAccountIDs {
-		// Create filter for this account
-		effectiveFilters := &models.TransactionFilters{
-			AccountID: accountID,
+		if !began {
+			if len(page.Transactions) == 0 {
+				return nil
+			}
+			if err := enc.BeginAccount(&export.Account{
+				ID:       account.ID,
+				Name:     account.AccountName,
+				Type:     account.AccountType,
+				Currency: account.Currency,
+				Balance:  account.Balance,
+				Start:    page.Transactions[0].Date,
+				End:      last.Transactions[0].Date,
+			}); err != nil {
+				return err
+			}
+			began = true
 		}
 
-		// Only apply advanced filters if the feature flag is enabled
-		if advancedFiltersEnabled {
-			effectiveFilters.StartDate = filters.StartDate
-			effectiveFilters.EndDate = filters.EndDate
-			effectiveFilters.Category = filters.Category
-			effectiveFilters.MinAmount = filters.MinAmount
-			effectiveFilters.MaxAmount = filters.MaxAmount
-		} else {
-			// Log that advanced filters are ignored
-			if filters.StartDate != nil || filters.EndDate != nil || filters.Category != "" ||
-				filters.MinAmount != nil || filters.MaxAmount != nil {
-				s.logger.Info("Advanced filters requested but feature flag is disabled, only accountId filter will be applied")
+		for _, txn := range page.Transactions {
+			if err := enc.Encode(txn); err != nil {
+				return err
 			}
 		}
+		if err := enc.Flush(); err != nil {
+			return err
+		}
 
-		// Get transactions for this account
-		accountTransactions := s.repo.GetTransactionsByFilter(effectiveFilters)
-		allTransactions = append(allTransactions, accountTransactions...)
+		if page.NextCursor == "" {
+			break
+		}
+		pageRequest.Cursor = page.NextCursor
 	}
 
-	// Sort by date descending (most recent first)
-	sort.Slice(allTransactions, func(i, j int) bool {
-		return allTransactions[i].Date.After(allTransactions[j].Date)
-	})
+	return enc.EndAccount()
+}
+
+// CreateTransaction validates a new transaction against its owning account,
+// assigns it an ID and stores it
+func (s *TransactionService) CreateTransaction(userID string, txn *models.Transaction) (*models.Transaction, error) {
+	if txn.Date.IsZero() {
+		txn.Date = time.Now().UTC()
+	}
+	if txn.Status == "" {
+		txn.Status = models.StatusCompleted
+	}
+
+	if err := txn.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTransaction, err)
+	}
+
+	// Verify the requesting user owns the account or holds it jointly
+	account, err := accessibleAccount(s.repo, s.logger, txn.AccountID, userID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if account.Status != "active" {
+		return nil, fmt.Errorf("%w: account is %s", ErrInvalidTransaction, account.Status)
+	}
+
+	id, err := newTransactionID()
+	if err != nil {
+		return nil, err
+	}
+	txn.ID = id
+
+	if err := s.repo.CreateTransaction(txn); err != nil {
+		s.logger.WithError(err).WithField("txnId", txn.ID).Error("Failed to store transaction")
+		return nil, err
+	}
 
 	s.logger.WithFields(logrus.Fields{
-		"userId": userID,
-		"count":  len(allTransactions),
-	}).Info("Retrieved transactions for user")
+		"userId":    userID,
+		"txnId":     txn.ID,
+		"accountId": txn.AccountID,
+		"amount":    txn.Amount,
+	}).Info("Created transaction")
+
+	return txn, nil
+}
+
+// accessibleAccount loads an account, applying the same access rules as
+// AccountService.GetAccountByID in api-accounts: owners and the joint holders
+// and viewers they granted access to can read it, and only owners and joint
+// holders can write to it
+func accessibleAccount(repo repository.Store, logger *logrus.Logger, accountID, userID string, write bool) (*repository.Account, error) {
+	account, err := repo.GetAccountByID(accountID)
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"accountId": accountID,
+			"userId":    userID,
+		}).Warn("Account not found")
+		return nil, ErrAccountNotFound
+	}
+
+	access, err := repo.GetAccountAccess(accountID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if access == "" || (write && access == repository.AccessViewer) {
+		logger.WithFields(logrus.Fields{
+			"accountId": accountID,
+			"userId":    userID,
+			"ownerId":   account.UserID,
+			"access":    access,
+		}).Warn("Unauthorized access attempt")
+		if access == "" {
+			return nil, ErrUnauthorized
+		}
+		return nil, ErrReadOnlyAccess
+	}
+
+	return account, nil
+}
 
-	return allTransactions, nil
+// newTransactionID generates a random transaction ID
+func newTransactionID() (string, error) {
+	return newID("txn")
+}
+
+// newID generates a random identifier with the given prefix
+func newID(prefix string) (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + "-" + hex.EncodeToString(b), nil
 }
 
 // ParseDateParam parses a date string in ISO 8601 format
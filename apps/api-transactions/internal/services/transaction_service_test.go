@@ -1,10 +1,19 @@
 package services
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/export"
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/features"
 	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/models"
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/repository"
+	"github.com/sirupsen/logrus"
 )
 
 func TestFilterTransactionsByAccount(t *testing.T) {
@@ -206,3 +215,449 @@ func filterByStatus(txns []*models.Transaction, status string) []*models.Transac
 	}
 	return result
 }
+
+func newTestLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+// newTestStore opens the testdata seed using the given storage backend
+func newTestStore(t *testing.T, backend string) repository.Store {
+	t.Helper()
+	repo, err := repository.NewStore(repository.Config{
+		Backend:    backend,
+		DataPath:   "testdata",
+		SQLitePath: filepath.Join(t.TempDir(), "test.db"),
+	}, newTestLogger())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	return repo
+}
+
+// newTestService builds a service over the testdata seed using the given storage backend
+func newTestService(t *testing.T, backend string) *TransactionService {
+	t.Helper()
+	return NewTransactionService(newTestStore(t, backend), nil, newTestLogger())
+}
+
+func TestCreateTransaction(t *testing.T) {
+	tests := []struct {
+		name    string
+		userID  string
+		txn     models.Transaction
+		wantErr error
+	}{
+		{
+			name:   "valid debit",
+			userID: "user-001",
+			txn:    models.Transaction{AccountID: "acc-001", Description: "Coffee", Amount: -4.50, Category: "food_dining", Type: "debit"},
+		},
+		{
+			name:   "valid credit",
+			userID: "user-001",
+			txn:    models.Transaction{AccountID: "acc-002", Description: "Refund", Amount: 20.00, Category: "shopping", Type: "credit"},
+		},
+		{
+			name:    "credit with negative amount",
+			userID:  "user-001",
+			txn:     models.Transaction{AccountID: "acc-001", Description: "Bad", Amount: -20.00, Category: "shopping", Type: "credit"},
+			wantErr: ErrInvalidTransaction,
+		},
+		{
+			name:    "debit with positive amount",
+			userID:  "user-001",
+			txn:     models.Transaction{AccountID: "acc-001", Description: "Bad", Amount: 20.00, Category: "shopping", Type: "debit"},
+			wantErr: ErrInvalidTransaction,
+		},
+		{
+			name:    "unknown account",
+			userID:  "user-001",
+			txn:     models.Transaction{AccountID: "acc-999", Description: "Coffee", Amount: -4.50, Category: "food_dining", Type: "debit"},
+			wantErr: ErrAccountNotFound,
+		},
+		{
+			name:    "another user's account",
+			userID:  "user-001",
+			txn:     models.Transaction{AccountID: "acc-004", Description: "Coffee", Amount: -4.50, Category: "food_dining", Type: "debit"},
+			wantErr: ErrUnauthorized,
+		},
+		{
+			name:    "closed account",
+			userID:  "user-001",
+			txn:     models.Transaction{AccountID: "acc-009", Description: "Coffee", Amount: -4.50, Category: "food_dining", Type: "debit"},
+			wantErr: ErrInvalidTransaction,
+		},
+	}
+
+	for _, backend := range []string{repository.BackendJSON, repository.BackendSQLite} {
+		for _, tt := range tests {
+			t.Run(backend+"/"+tt.name, func(t *testing.T) {
+				service := newTestService(t, backend)
+				txn := tt.txn
+
+				created, err := service.CreateTransaction(tt.userID, &txn)
+				if tt.wantErr != nil {
+					if !errors.Is(err, tt.wantErr) {
+						t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("CreateTransaction failed: %v", err)
+				}
+				if created.ID == "" {
+					t.Error("Expected an ID to be assigned")
+				}
+				if created.Status != models.StatusCompleted {
+					t.Errorf("Expected default status completed, got %s", created.Status)
+				}
+				if created.Date.IsZero() {
+					t.Error("Expected date to default to now")
+				}
+
+				stored, err := service.GetTransactionByID(tt.userID, created.ID)
+				if err != nil {
+					t.Fatalf("Created transaction not found: %v", err)
+				}
+				if stored.Amount != tt.txn.Amount || stored.AccountID != tt.txn.AccountID {
+					t.Errorf("Stored transaction mismatch: %+v", stored)
+				}
+			})
+		}
+	}
+}
+
+func TestGetTransactionByIDIsolation(t *testing.T) {
+	tests := []struct {
+		name    string
+		userID  string
+		txnID   string
+		wantErr bool
+	}{
+		{"own transaction", "user-001", "txn-001", false},
+		{"own credit card transaction", "user-001", "txn-011", false},
+		{"other user's transaction", "user-002", "txn-001", true},
+		{"other user's transaction reversed", "user-001", "txn-021", true},
+		{"missing transaction", "user-001", "txn-999", true},
+		{"unknown user", "user-999", "txn-001", true},
+	}
+
+	for _, backend := range []string{repository.BackendJSON, repository.BackendSQLite} {
+		service := newTestService(t, backend)
+		for _, tt := range tests {
+			t.Run(backend+"/"+tt.name, func(t *testing.T) {
+				txn, err := service.GetTransactionByID(tt.userID, tt.txnID)
+				if tt.wantErr {
+					if !errors.Is(err, ErrTransactionNotFound) {
+						t.Errorf("Expected ErrTransactionNotFound, got %v", err)
+					}
+					if txn != nil {
+						t.Errorf("Expected no transaction to be returned, got %+v", txn)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("GetTransactionByID failed: %v", err)
+				}
+				if txn.ID != tt.txnID {
+					t.Errorf("Expected %s, got %s", tt.txnID, txn.ID)
+				}
+			})
+		}
+	}
+}
+
+func TestGetTransactionByIDStoreFailure(t *testing.T) {
+	store := newTestStore(t, repository.BackendSQLite)
+	service := NewTransactionService(store, nil, newTestLogger())
+	store.Close()
+
+	// A database failure is not reported as a missing transaction
+	_, err := service.GetTransactionByID("user-001", "txn-001")
+	if err == nil || errors.Is(err, ErrTransactionNotFound) {
+		t.Errorf("Expected the store's error, got %v", err)
+	}
+}
+
+// collectPages walks every page of a listing and returns the transaction IDs in order
+func collectPages(t *testing.T, service *TransactionService, userID string, req models.PageRequest) []string {
+	t.Helper()
+	var ids []string
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			t.Fatal("Pagination did not terminate")
+		}
+		page, err := service.ListTransactions(userID, &models.TransactionFilters{}, &req)
+		if err != nil {
+			t.Fatalf("ListTransactions failed: %v", err)
+		}
+		if req.Limit > 0 && len(page.Transactions) > req.Limit {
+			t.Fatalf("Page of %d exceeds limit %d", len(page.Transactions), req.Limit)
+		}
+		for _, txn := range page.Transactions {
+			ids = append(ids, txn.ID)
+		}
+		if page.NextCursor == "" {
+			return ids
+		}
+		req.Cursor = page.NextCursor
+	}
+}
+
+func TestListTransactionsPagination(t *testing.T) {
+	tests := []struct {
+		name  string
+		sort  string
+		order string
+		want  []string
+	}{
+		// txn-001 and txn-011 share a timestamp, so ID breaks the tie
+		{"default", "", "", []string{"txn-025", "txn-011", "txn-001", "txn-005"}},
+		{"date asc", models.SortDate, models.OrderAsc, []string{"txn-005", "txn-001", "txn-011", "txn-025"}},
+		{"amount asc", models.SortAmount, models.OrderAsc, []string{"txn-011", "txn-025", "txn-001", "txn-005"}},
+		{"amount desc", models.SortAmount, models.OrderDesc, []string{"txn-005", "txn-001", "txn-025", "txn-011"}},
+		{"merchant asc", models.SortMerchant, models.OrderAsc, []string{"txn-005", "txn-011", "txn-025", "txn-001"}},
+	}
+
+	for _, backend := range []string{repository.BackendJSON, repository.BackendSQLite} {
+		service := newTestService(t, backend)
+		for _, tt := range tests {
+			for _, limit := range []int{0, 1, 3, 4} {
+				t.Run(fmt.Sprintf("%s/%s/limit=%d", backend, tt.name, limit), func(t *testing.T) {
+					got := collectPages(t, service, "user-001", models.PageRequest{Limit: limit, Sort: tt.sort, Order: tt.order})
+					if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+						t.Errorf("Expected %v, got %v", tt.want, got)
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestListTransactionsCursorStableAcrossInserts(t *testing.T) {
+	for _, backend := range []string{repository.BackendJSON, repository.BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			service := newTestService(t, backend)
+
+			first, err := service.ListTransactions("user-001", &models.TransactionFilters{}, &models.PageRequest{Limit: 2})
+			if err != nil {
+				t.Fatalf("ListTransactions failed: %v", err)
+			}
+
+			// A newer transaction lands between page requests; it sorts before the
+			// cursor and must not shift or duplicate anything on the next page
+			if _, err := service.CreateTransaction("user-001", &models.Transaction{
+				AccountID:   "acc-001",
+				Date:        time.Date(2024, 12, 14, 0, 0, 0, 0, time.UTC),
+				Description: "Late arrival",
+				Amount:      -1,
+				Category:    "shopping",
+				Type:        models.TypeDebit,
+			}); err != nil {
+				t.Fatalf("CreateTransaction failed: %v", err)
+			}
+
+			second, err := service.ListTransactions("user-001", &models.TransactionFilters{}, &models.PageRequest{Limit: 2, Cursor: first.NextCursor})
+			if err != nil {
+				t.Fatalf("ListTransactions failed: %v", err)
+			}
+			if len(second.Transactions) != 2 || second.Transactions[0].ID != "txn-001" || second.Transactions[1].ID != "txn-005" {
+				t.Errorf("Expected [txn-001 txn-005] on the second page, got %v", second.Transactions)
+			}
+			if second.NextCursor != "" {
+				t.Errorf("Expected the second page to be the last")
+			}
+		})
+	}
+}
+
+func TestListTransactionsAccountFilter(t *testing.T) {
+	tests := []struct {
+		name      string
+		accountID string
+		wantCount int
+	}{
+		{"all accounts", "", 4},
+		{"own account", "acc-001", 2},
+		{"other user's account", "acc-004", 0},
+		{"missing account", "acc-999", 0},
+	}
+
+	for _, backend := range []string{repository.BackendJSON, repository.BackendSQLite} {
+		service := newTestService(t, backend)
+		for _, tt := range tests {
+			t.Run(backend+"/"+tt.name, func(t *testing.T) {
+				page, err := service.ListTransactions("user-001", &models.TransactionFilters{AccountID: tt.accountID}, &models.PageRequest{})
+				if err != nil {
+					t.Fatalf("ListTransactions failed: %v", err)
+				}
+				if len(page.Transactions) != tt.wantCount {
+					t.Errorf("Expected %d transactions, got %d", tt.wantCount, len(page.Transactions))
+				}
+			})
+		}
+	}
+}
+
+func TestListTransactionsInvalidPageRequest(t *testing.T) {
+	service := newTestService(t, repository.BackendJSON)
+
+	first, err := service.ListTransactions("user-001", &models.TransactionFilters{}, &models.PageRequest{Limit: 1})
+	if err != nil {
+		t.Fatalf("ListTransactions failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		req  models.PageRequest
+	}{
+		{"unknown sort", models.PageRequest{Sort: "category"}},
+		{"unknown order", models.PageRequest{Order: "sideways"}},
+		{"limit too large", models.PageRequest{Limit: models.MaxPageSize + 1}},
+		{"garbage cursor", models.PageRequest{Limit: 1, Cursor: "not-a-cursor"}},
+		{"cursor from another sort", models.PageRequest{Limit: 1, Sort: models.SortAmount, Cursor: first.NextCursor}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.ListTransactions("user-001", &models.TransactionFilters{}, &tt.req)
+			if !errors.Is(err, ErrInvalidPageRequest) {
+				t.Errorf("Expected ErrInvalidPageRequest, got %v", err)
+			}
+		})
+	}
+}
+
+func TestListTransactionsSearch(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"merchant", "geico", []string{"txn-025"}},
+		{"prefix and case", "STAR", []string{"txn-001"}},
+		{"description and merchant", "apple store", []string{"txn-011"}},
+		{"shared term", "payment", []string{"txn-005"}},
+		{"other user's transaction", "invoice", []string{}},
+		{"no match", "walmart", []string{}},
+	}
+
+	for _, backend := range []string{repository.BackendJSON, repository.BackendSQLite} {
+		service := newTestService(t, backend)
+		for _, tt := range tests {
+			t.Run(backend+"/"+tt.name, func(t *testing.T) {
+				page, err := service.ListTransactions("user-001", &models.TransactionFilters{Query: tt.query}, &models.PageRequest{})
+				if err != nil {
+					t.Fatalf("ListTransactions failed: %v", err)
+				}
+				got := []string{}
+				for _, txn := range page.Transactions {
+					got = append(got, txn.ID)
+				}
+				if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+					t.Errorf("Expected %v, got %v", tt.want, got)
+				}
+			})
+		}
+	}
+}
+
+func TestListTransactionsSearchIndexesNewTransactions(t *testing.T) {
+	for _, backend := range []string{repository.BackendJSON, repository.BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			service := newTestService(t, backend)
+			transfers := NewTransferService(service.repo, newTestLogger())
+
+			created, err := service.CreateTransaction("user-001", &models.Transaction{
+				AccountID:   "acc-001",
+				Description: "Geico Roadside Assistance",
+				Merchant:    "Geico",
+				Amount:      -19.99,
+				Category:    "insurance",
+				Type:        models.TypeDebit,
+			})
+			if err != nil {
+				t.Fatalf("CreateTransaction failed: %v", err)
+			}
+			if _, err := transfers.CreateTransfer("user-001", &models.Transfer{
+				FromAccountID: "acc-002",
+				ToAccountID:   "acc-001",
+				Amount:        10,
+			}); err != nil {
+				t.Fatalf("CreateTransfer failed: %v", err)
+			}
+
+			page, err := service.ListTransactions("user-001", &models.TransactionFilters{Query: "roadside"}, &models.PageRequest{})
+			if err != nil {
+				t.Fatalf("ListTransactions failed: %v", err)
+			}
+			if len(page.Transactions) != 1 || page.Transactions[0].ID != created.ID {
+				t.Errorf("Expected only %s, got %v", created.ID, page.Transactions)
+			}
+
+			page, err = service.ListTransactions("user-001", &models.TransactionFilters{Query: "internal transfer"}, &models.PageRequest{})
+			if err != nil {
+				t.Fatalf("ListTransactions failed: %v", err)
+			}
+			if len(page.Transactions) != 2 {
+				t.Errorf("Expected both transfer legs, got %d transactions", len(page.Transactions))
+			}
+		})
+	}
+}
+
+func TestExportTransactions(t *testing.T) {
+	tests := []struct {
+		name      string
+		userID    string
+		accountID string
+		wantIDs   []string
+	}{
+		// Bank accounts come first, each in date order, then credit accounts
+		{"all accounts", "user-001", "", []string{"txn-001", "txn-025", "txn-005", "txn-011"}},
+		{"single account", "user-001", "acc-003", []string{"txn-011"}},
+		{"other user's account", "user-001", "acc-004", nil},
+		{"other user", "user-002", "", []string{"txn-021"}},
+	}
+
+	for _, backend := range []string{repository.BackendJSON, repository.BackendSQLite} {
+		service := newTestService(t, backend)
+		for _, tt := range tests {
+			t.Run(backend+"/"+tt.name, func(t *testing.T) {
+				var buf strings.Builder
+				filters := &models.TransactionFilters{AccountID: tt.accountID}
+				if err := service.ExportTransactions(tt.userID, filters, export.FormatCSV, &buf); err != nil {
+					t.Fatalf("ExportTransactions failed: %v", err)
+				}
+
+				var ids []string
+				for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n")[1:] {
+					ids = append(ids, strings.SplitN(line, ",", 2)[0])
+				}
+				if fmt.Sprint(ids) != fmt.Sprint(tt.wantIDs) {
+					t.Errorf("Expected %v, got %v", tt.wantIDs, ids)
+				}
+			})
+		}
+	}
+}
+
+func TestExportTransactionsMasksAmounts(t *testing.T) {
+	t.Setenv("FEATURE_MASK_AMOUNTS", "true")
+	flags, err := features.Initialize("", newTestLogger())
+	if err != nil {
+		t.Fatalf("Failed to initialize flags: %v", err)
+	}
+	service := NewTransactionService(newTestStore(t, repository.BackendJSON), flags, newTestLogger())
+
+	var buf strings.Builder
+	if err := service.ExportTransactions("user-001", &models.TransactionFilters{}, export.FormatOFX, &buf); err != nil {
+		t.Fatalf("ExportTransactions failed: %v", err)
+	}
+	if strings.Contains(buf.String(), "-5.47") || !strings.Contains(buf.String(), export.MaskedAmount) {
+		t.Errorf("Expected amounts to be masked, got:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/models"
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+const transferMerchant = "Internal Transfer"
+
+var (
+	// ErrInvalidTransfer wraps validation failures for transfer requests
+	ErrInvalidTransfer = errors.New("invalid transfer")
+	// ErrInsufficientFunds is returned when the source account cannot cover the transfer
+	ErrInsufficientFunds = errors.New("insufficient funds")
+)
+
+// TransferService handles business logic for account-to-account transfers
+type TransferService struct {
+	repo   repository.Store
+	logger *logrus.Logger
+}
+
+// NewTransferService creates a new transfer service
+func NewTransferService(repo repository.Store, logger *logrus.Logger) *TransferService {
+	return &TransferService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// CreateTransfer moves money between two of the user's accounts. The debit
+// and credit legs and both balance updates are applied atomically.
+func (s *TransferService) CreateTransfer(userID string, transfer *models.Transfer) (*models.Transfer, error) {
+	if err := transfer.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTransfer, err)
+	}
+
+	// Both accounts must exist, belong to the requesting user and be open
+	for _, accountID := range []string{transfer.FromAccountID, transfer.ToAccountID} {
+		if err := s.checkAccount(accountID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	transferID, err := newID("xfr")
+	if err != nil {
+		return nil, err
+	}
+	debitID, err := newTransactionID()
+	if err != nil {
+		return nil, err
+	}
+	creditID, err := newTransactionID()
+	if err != nil {
+		return nil, err
+	}
+
+	transfer.ID = transferID
+	transfer.Date = time.Now().UTC()
+
+	debitDescription := transfer.Description
+	creditDescription := transfer.Description
+	if transfer.Description == "" {
+		debitDescription = "Transfer to " + transfer.ToAccountID
+		creditDescription = "Transfer from " + transfer.FromAccountID
+	}
+
+	transfer.Debit = &models.Transaction{
+		ID:          debitID,
+		AccountID:   transfer.FromAccountID,
+		Date:        transfer.Date,
+		Description: debitDescription,
+		Amount:      -transfer.Amount,
+		Category:    models.CategoryTransfer,
+		Merchant:    transferMerchant,
+		Status:      models.StatusCompleted,
+		Type:        models.TypeDebit,
+		TransferID:  transferID,
+	}
+	transfer.Credit = &models.Transaction{
+		ID:          creditID,
+		AccountID:   transfer.ToAccountID,
+		Date:        transfer.Date,
+		Description: creditDescription,
+		Amount:      transfer.Amount,
+		Category:    models.CategoryTransfer,
+		Merchant:    transferMerchant,
+		Status:      models.StatusCompleted,
+		Type:        models.TypeCredit,
+		TransferID:  transferID,
+	}
+
+	if err := s.repo.CreateTransfer(transfer.Debit, transfer.Credit); err != nil {
+		if errors.Is(err, repository.ErrInsufficientFunds) {
+			s.logger.WithFields(logrus.Fields{
+				"userId":        userID,
+				"fromAccountId": transfer.FromAccountID,
+				"amount":        transfer.Amount,
+			}).Warn("Transfer refused: insufficient funds")
+			return nil, ErrInsufficientFunds
+		}
+		s.logger.WithError(err).WithField("transferId", transferID).Error("Failed to store transfer")
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"userId":        userID,
+		"transferId":    transferID,
+		"fromAccountId": transfer.FromAccountID,
+		"toAccountId":   transfer.ToAccountID,
+		"amount":        transfer.Amount,
+	}).Info("Created transfer")
+
+	return transfer, nil
+}
+
+// checkAccount verifies that the user owns or jointly holds the account and
+// that it is open
+func (s *TransferService) checkAccount(accountID, userID string) error {
+	account, err := accessibleAccount(s.repo, s.logger, accountID, userID, true)
+	if err != nil {
+		return err
+	}
+
+	if account.Status != "active" {
+		return fmt.Errorf("%w: account %s is %s", ErrInvalidTransfer, accountID, account.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/models"
+	"github.com/CB-AccountStack/AccountStack/apps/api-transactions/internal/repository"
+)
+
+func TestCreateTransfer(t *testing.T) {
+	tests := []struct {
+		name     string
+		userID   string
+		transfer models.Transfer
+		wantErr  error
+		wantFrom float64
+		wantTo   float64
+	}{
+		{
+			name:     "checking to savings",
+			userID:   "user-001",
+			transfer: models.Transfer{FromAccountID: "acc-001", ToAccountID: "acc-002", Amount: 500.00},
+			wantFrom: 5347.32,
+			wantTo:   23956.89,
+		},
+		{
+			name:     "entire checking balance",
+			userID:   "user-001",
+			transfer: models.Transfer{FromAccountID: "acc-001", ToAccountID: "acc-002", Amount: 5847.32},
+			wantFrom: 0,
+			wantTo:   29304.21,
+		},
+		{
+			name:     "credit card up to its limit",
+			userID:   "user-001",
+			transfer: models.Transfer{FromAccountID: "acc-003", ToAccountID: "acc-001", Amount: 7865.44},
+			wantFrom: -10000.00,
+			wantTo:   13712.76,
+		},
+		{
+			name:     "overdraft on checking",
+			userID:   "user-001",
+			transfer: models.Transfer{FromAccountID: "acc-001", ToAccountID: "acc-002", Amount: 5847.33},
+			wantErr:  ErrInsufficientFunds,
+		},
+		{
+			name:     "beyond credit limit",
+			userID:   "user-001",
+			transfer: models.Transfer{FromAccountID: "acc-003", ToAccountID: "acc-001", Amount: 7865.45},
+			wantErr:  ErrInsufficientFunds,
+		},
+		{
+			name:     "to another user's account",
+			userID:   "user-001",
+			transfer: models.Transfer{FromAccountID: "acc-001", ToAccountID: "acc-004", Amount: 10.00},
+			wantErr:  ErrUnauthorized,
+		},
+		{
+			name:     "from another user's account",
+			userID:   "user-002",
+			transfer: models.Transfer{FromAccountID: "acc-001", ToAccountID: "acc-004", Amount: 10.00},
+			wantErr:  ErrUnauthorized,
+		},
+		{
+			name:     "unknown account",
+			userID:   "user-001",
+			transfer: models.Transfer{FromAccountID: "acc-001", ToAccountID: "acc-999", Amount: 10.00},
+			wantErr:  ErrAccountNotFound,
+		},
+		{
+			name:     "closed account",
+			userID:   "user-001",
+			transfer: models.Transfer{FromAccountID: "acc-001", ToAccountID: "acc-009", Amount: 10.00},
+			wantErr:  ErrInvalidTransfer,
+		},
+		{
+			name:     "same account",
+			userID:   "user-001",
+			transfer: models.Transfer{FromAccountID: "acc-001", ToAccountID: "acc-001", Amount: 10.00},
+			wantErr:  ErrInvalidTransfer,
+		},
+		{
+			name:     "fractional cents",
+			userID:   "user-001",
+			transfer: models.Transfer{FromAccountID: "acc-001", ToAccountID: "acc-002", Amount: 10.005},
+			wantErr:  ErrInvalidTransfer,
+		},
+	}
+
+	for _, backend := range []string{repository.BackendJSON, repository.BackendSQLite} {
+		for _, tt := range tests {
+			t.Run(backend+"/"+tt.name, func(t *testing.T) {
+				repo := newTestStore(t, backend)
+				service := NewTransferService(repo, newTestLogger())
+				transfer := tt.transfer
+
+				created, err := service.CreateTransfer(tt.userID, &transfer)
+				if tt.wantErr != nil {
+					if !errors.Is(err, tt.wantErr) {
+						t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("CreateTransfer failed: %v", err)
+				}
+
+				from, _ := repo.GetAccountByID(tt.transfer.FromAccountID)
+				to, _ := repo.GetAccountByID(tt.transfer.ToAccountID)
+				if from.Balance != tt.wantFrom {
+					t.Errorf("Expected source balance %.2f, got %.2f", tt.wantFrom, from.Balance)
+				}
+				if to.Balance != tt.wantTo {
+					t.Errorf("Expected destination balance %.2f, got %.2f", tt.wantTo, to.Balance)
+				}
+
+				for _, leg := range []*models.Transaction{created.Debit, created.Credit} {
+					stored, err := repo.GetTransactionByID(leg.ID)
+					if err != nil {
+						t.Fatalf("Transfer leg %s not stored: %v", leg.ID, err)
+					}
+					if stored.TransferID != created.ID || stored.Category != models.CategoryTransfer {
+						t.Errorf("Leg not linked to transfer: %+v", stored)
+					}
+					if err := stored.Validate(); err != nil {
+						t.Errorf("Stored leg is invalid: %v", err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestCreateTransferFailureLeavesNoTrace(t *testing.T) {
+	for _, backend := range []string{repository.BackendJSON, repository.BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			repo := newTestStore(t, backend)
+			service := NewTransferService(repo, newTestLogger())
+			before, _ := repo.GetAllTransactions()
+
+			_, err := service.CreateTransfer("user-001", &models.Transfer{FromAccountID: "acc-001", ToAccountID: "acc-002", Amount: 100000})
+			if !errors.Is(err, ErrInsufficientFunds) {
+				t.Fatalf("Expected ErrInsufficientFunds, got %v", err)
+			}
+
+			after, _ := repo.GetAllTransactions()
+			if len(after) != len(before) {
+				t.Errorf("Expected no transactions to be recorded, got %d new", len(after)-len(before))
+			}
+			to, _ := repo.GetAccountByID("acc-002")
+			if to.Balance != 23456.89 {
+				t.Errorf("Expected destination balance unchanged, got %.2f", to.Balance)
+			}
+		})
+	}
+}
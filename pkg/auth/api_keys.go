@@ -0,0 +1,354 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// API key stores selectable via the API_KEY_STORE environment variable
+const (
+	APIKeysMemory = "memory"
+	APIKeysSQLite = "sqlite"
+)
+
+// APIKeyPrefix starts every personal API key, so leaked keys are easy to spot
+const APIKeyPrefix = "ask_"
+
+// apiKeyTouchInterval is how stale a key's last-used time may get before a
+// request records it again, so busy scripts do not write on every call
+const apiKeyTouchInterval = time.Minute
+
+var (
+	// ErrAPIKeyNotFound is returned for a key that does not exist or belongs
+	// to someone else
+	ErrAPIKeyNotFound = errors.New("api key not found")
+
+	// ErrInvalidAPIKey is returned when a request presents an unknown or
+	// expired key
+	ErrInvalidAPIKey = errors.New("invalid api key")
+)
+
+// APIKey is a personal API key that scripts and integrations send as
+// "Authorization: ApiKey <key>". Only a SHA-256 hash of the key is stored;
+// the key itself is shown once, when it is created.
+type APIKey struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"userId"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"` // The start of the key, to tell keys apart
+	KeyHash    string     `json:"-"`
+	Scope      string     `json:"scope"` // Space-separated, as in access tokens
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+}
+
+// Expired reports whether the key has passed its expiry at now. Keys without
+// one never expire.
+func (k *APIKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// APIKeyStore holds personal API keys by the hash of the key. api-accounts
+// creates and revokes them; every service's AuthMiddleware looks them up, so
+// a key works everywhere once the services share a store.
+type APIKeyStore interface {
+	Create(key *APIKey) error
+
+	// Get returns the key whose hash is keyHash, or ErrAPIKeyNotFound
+	Get(keyHash string) (*APIKey, error)
+
+	// List returns the user's keys, oldest first
+	List(userID string) ([]*APIKey, error)
+
+	// Revoke deletes one of the user's keys, or returns ErrAPIKeyNotFound
+	Revoke(userID, id string) error
+
+	// Touch records that the key was used at usedAt
+	Touch(id string, usedAt time.Time) error
+
+	Close() error
+}
+
+// NewAPIKeyStore creates the API key store for the configured store. path is
+// the database file used by the sqlite store.
+func NewAPIKeyStore(store, path string) (APIKeyStore, error) {
+	switch store {
+	case "", APIKeysMemory:
+		return NewMemoryAPIKeyStore(), nil
+	case APIKeysSQLite:
+		return NewSQLiteAPIKeyStore(path)
+	default:
+		return nil, fmt.Errorf("unknown api key store %q", store)
+	}
+}
+
+// HashAPIKey returns the stored form of an API key. Keys carry 256 bits of
+// entropy, so a fast hash is enough and lets every service look them up.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// AuthenticateAPIKey looks up the key presented with a request and returns
+// claims for its user carrying the key's scopes. Unknown and expired keys get
+// ErrInvalidAPIKey; other errors mean the store could not be read.
+func AuthenticateAPIKey(store APIKeyStore, key string, now time.Time) (*Claims, error) {
+	if !strings.HasPrefix(key, APIKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+	apiKey, err := store.Get(HashAPIKey(key))
+	if errors.Is(err, ErrAPIKeyNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if apiKey.Expired(now) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= apiKeyTouchInterval {
+		if err := store.Touch(apiKey.ID, now); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Claims{
+		UserID:   apiKey.UserID,
+		Scope:    apiKey.Scope,
+		APIKeyID: apiKey.ID,
+	}, nil
+}
+
+// MemoryAPIKeyStore keeps keys in process. They are only accepted by the
+// process that created them and are lost on restart, so it suits tests and
+// single-service development.
+type MemoryAPIKeyStore struct {
+	keys map[string]*APIKey // by hash
+	mu   sync.RWMutex
+}
+
+// NewMemoryAPIKeyStore creates an empty in-memory API key store
+func NewMemoryAPIKeyStore() *MemoryAPIKeyStore {
+	return &MemoryAPIKeyStore{keys: make(map[string]*APIKey)}
+}
+
+// Create stores a new key
+func (s *MemoryAPIKeyStore) Create(key *APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := *key
+	s.keys[key.KeyHash] = &stored
+	return nil
+}
+
+// Get returns the key whose hash is keyHash
+func (s *MemoryAPIKeyStore) Get(keyHash string) (*APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, ok := s.keys[keyHash]
+	if !ok {
+		return nil, ErrAPIKeyNotFound
+	}
+	found := *key
+	return &found, nil
+}
+
+// List returns the user's keys, oldest first
+func (s *MemoryAPIKeyStore) List(userID string) ([]*APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := []*APIKey{}
+	for _, key := range s.keys {
+		if key.UserID == userID {
+			found := *key
+			keys = append(keys, &found)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+		return keys[i].ID < keys[j].ID
+	})
+	return keys, nil
+}
+
+// Revoke deletes one of the user's keys
+func (s *MemoryAPIKeyStore) Revoke(userID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for hash, key := range s.keys {
+		if key.ID == id && key.UserID == userID {
+			delete(s.keys, hash)
+			return nil
+		}
+	}
+	return ErrAPIKeyNotFound
+}
+
+// Touch records that the key was used at usedAt
+func (s *MemoryAPIKeyStore) Touch(id string, usedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range s.keys {
+		if key.ID == id {
+			key.LastUsedAt = &usedAt
+		}
+	}
+	return nil
+}
+
+// Close is a no-op for the in-memory store
+func (s *MemoryAPIKeyStore) Close() error {
+	return nil
+}
+
+// SQLiteAPIKeyStore keeps keys in a SQLite file that every service opens,
+// such as the shared accountstack.db volume
+type SQLiteAPIKeyStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteAPIKeyStore opens (or creates) the api_keys table in the database
+// at dbPath. Every service reads the table, so it is created here instead of
+// in a service's migrations.
+func NewSQLiteAPIKeyStore(dbPath string) (*SQLiteAPIKeyStore, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", dbPath)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open api key database: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS api_keys (
+		id           TEXT PRIMARY KEY,
+		user_id      TEXT NOT NULL,
+		name         TEXT NOT NULL,
+		prefix       TEXT NOT NULL,
+		key_hash     TEXT NOT NULL UNIQUE,
+		scope        TEXT NOT NULL,
+		created_at   INTEGER NOT NULL,
+		expires_at   INTEGER,
+		last_used_at INTEGER
+	);
+	CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys (user_id)`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create api_keys: %w", err)
+	}
+
+	return &SQLiteAPIKeyStore{db: db}, nil
+}
+
+const apiKeyColumns = `id, user_id, name, prefix, key_hash, scope, created_at, expires_at, last_used_at`
+
+// Create stores a new key
+func (s *SQLiteAPIKeyStore) Create(key *APIKey) error {
+	_, err := s.db.Exec(`INSERT INTO api_keys (`+apiKeyColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		key.ID, key.UserID, key.Name, key.Prefix, key.KeyHash, key.Scope,
+		key.CreatedAt.Unix(), unixOrNull(key.ExpiresAt), unixOrNull(key.LastUsedAt))
+	return err
+}
+
+// Get returns the key whose hash is keyHash
+func (s *SQLiteAPIKeyStore) Get(keyHash string) (*APIKey, error) {
+	key, err := scanAPIKey(s.db.QueryRow(`SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = ?`, keyHash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+	return key, err
+}
+
+// List returns the user's keys, oldest first
+func (s *SQLiteAPIKeyStore) List(userID string) ([]*APIKey, error) {
+	rows, err := s.db.Query(`SELECT `+apiKeyColumns+` FROM api_keys WHERE user_id = ? ORDER BY created_at, id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// Revoke deletes one of the user's keys
+func (s *SQLiteAPIKeyStore) Revoke(userID, id string) error {
+	result, err := s.db.Exec(`DELETE FROM api_keys WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// Touch records that the key was used at usedAt
+func (s *SQLiteAPIKeyStore) Touch(id string, usedAt time.Time) error {
+	_, err := s.db.Exec(`UPDATE api_keys SET last_used_at = ? WHERE id = ?`, usedAt.Unix(), id)
+	return err
+}
+
+// Close closes the underlying database
+func (s *SQLiteAPIKeyStore) Close() error {
+	return s.db.Close()
+}
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanAPIKey reads a row selected with apiKeyColumns
+func scanAPIKey(row rowScanner) (*APIKey, error) {
+	var key APIKey
+	var createdAt int64
+	var expiresAt, lastUsedAt sql.NullInt64
+	if err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.KeyHash, &key.Scope,
+		&createdAt, &expiresAt, &lastUsedAt); err != nil {
+		return nil, err
+	}
+	key.CreatedAt = time.Unix(createdAt, 0).UTC()
+	key.ExpiresAt = timeOrNil(expiresAt)
+	key.LastUsedAt = timeOrNil(lastUsedAt)
+	return &key, nil
+}
+
+// unixOrNull stores an optional time as Unix seconds or NULL
+func unixOrNull(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.Unix()
+}
+
+// timeOrNil reads an optional time stored by unixOrNull
+func timeOrNil(v sql.NullInt64) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	t := time.Unix(v.Int64, 0).UTC()
+	return &t
+}
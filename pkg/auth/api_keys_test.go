@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAPIKeyStores(t *testing.T) {
+	sqliteStore, err := NewSQLiteAPIKeyStore(filepath.Join(t.TempDir(), "keys.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteAPIKeyStore failed: %v", err)
+	}
+	defer sqliteStore.Close()
+
+	tests := []struct {
+		name  string
+		store APIKeyStore
+	}{
+		{"memory", NewMemoryAPIKeyStore()},
+		{"sqlite", sqliteStore},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2024, 12, 1, 12, 0, 0, 0, time.UTC)
+			expiresAt := now.Add(time.Hour)
+			for _, key := range []*APIKey{
+				{ID: "key-1", UserID: "user-001", Name: "Sync", Prefix: "ask_one", KeyHash: HashAPIKey("ask_one"), Scope: "accounts:read", CreatedAt: now},
+				{ID: "key-2", UserID: "user-001", Name: "Report", Prefix: "ask_two", KeyHash: HashAPIKey("ask_two"), Scope: "insights:read", CreatedAt: now.Add(time.Second), ExpiresAt: &expiresAt},
+				{ID: "key-3", UserID: "user-002", Name: "Other", Prefix: "ask_three", KeyHash: HashAPIKey("ask_three"), Scope: "accounts:read", CreatedAt: now},
+			} {
+				if err := tt.store.Create(key); err != nil {
+					t.Fatalf("Create failed: %v", err)
+				}
+			}
+
+			keys, err := tt.store.List("user-001")
+			if err != nil || len(keys) != 2 || keys[0].ID != "key-1" || keys[1].ID != "key-2" {
+				t.Fatalf("Expected user-001's keys oldest first, got %+v (%v)", keys, err)
+			}
+			if keys[1].ExpiresAt == nil || !keys[1].ExpiresAt.Equal(expiresAt) {
+				t.Errorf("Expected the expiry to round-trip, got %v", keys[1].ExpiresAt)
+			}
+
+			// A key is accepted until it expires, and records when it was used
+			claims, err := AuthenticateAPIKey(tt.store, "ask_two", now)
+			if err != nil || claims.UserID != "user-001" || claims.Scope != "insights:read" || claims.APIKeyID != "key-2" {
+				t.Fatalf("Unexpected claims %+v (%v)", claims, err)
+			}
+			if key, _ := tt.store.Get(HashAPIKey("ask_two")); key.LastUsedAt == nil || !key.LastUsedAt.Equal(now) {
+				t.Errorf("Expected the last use to be recorded, got %v", key.LastUsedAt)
+			}
+			if _, err := AuthenticateAPIKey(tt.store, "ask_two", expiresAt); !errors.Is(err, ErrInvalidAPIKey) {
+				t.Errorf("Expected an expired key to be refused, got %v", err)
+			}
+			if _, err := AuthenticateAPIKey(tt.store, "ask_four", now); !errors.Is(err, ErrInvalidAPIKey) {
+				t.Errorf("Expected an unknown key to be refused, got %v", err)
+			}
+
+			// Only the owner can revoke a key, once
+			if err := tt.store.Revoke("user-002", "key-1"); !errors.Is(err, ErrAPIKeyNotFound) {
+				t.Errorf("Expected ErrAPIKeyNotFound revoking another user's key, got %v", err)
+			}
+			if err := tt.store.Revoke("user-001", "key-1"); err != nil {
+				t.Fatalf("Revoke failed: %v", err)
+			}
+			if err := tt.store.Revoke("user-001", "key-1"); !errors.Is(err, ErrAPIKeyNotFound) {
+				t.Errorf("Expected ErrAPIKeyNotFound revoking twice, got %v", err)
+			}
+			if _, err := AuthenticateAPIKey(tt.store, "ask_one", now); !errors.Is(err, ErrInvalidAPIKey) {
+				t.Errorf("Expected a revoked key to be refused, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// jwksCacheTTL is how long a fetched key set is used before it is refetched
+	jwksCacheTTL = 5 * time.Minute
+	// jwksMinRefresh is the shortest interval between fetches, so tokens with
+	// made-up kids cannot be used to flood api-accounts
+	jwksMinRefresh = 10 * time.Second
+	// jwksFetchTimeout bounds a single fetch of the key set
+	jwksFetchTimeout = 5 * time.Second
+)
+
+// KeySource looks up the public key a token was signed with by its kid
+type KeySource interface {
+	PublicKey(kid string) (crypto.PublicKey, error)
+}
+
+// PublicKey decodes an RSA or Ed25519 JWK
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent: %w", err)
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid RSA key")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key: %w", err)
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key length %d", len(x))
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// StaticKeys is a fixed set of public keys by kid, for tests and for
+// deployments that pin the accounts keys instead of fetching them
+type StaticKeys map[string]crypto.PublicKey
+
+// PublicKey returns the key with the given kid
+func (s StaticKeys) PublicKey(kid string) (crypto.PublicKey, error) {
+	key, ok := s[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+// JWKSClient fetches and caches the key set published by api-accounts. The
+// set is refetched when it is older than the cache TTL or when a token names
+// a key it does not contain, so a key added during rotation is picked up
+// straight away while the keys it replaces stay valid for as long as the
+// issuer keeps publishing them. If a fetch fails the cached keys are kept.
+// Fetches run without holding the lock, so keys that are already cached are
+// served while one is in flight.
+type JWKSClient struct {
+	url    string
+	client *http.Client
+	logger *logrus.Logger
+	now    func() time.Time
+
+	mu          sync.Mutex
+	keys        map[string]crypto.PublicKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+	// fetching is closed when the fetch in flight finishes, nil when none is
+	fetching chan struct{}
+}
+
+// NewJWKSClient creates a client for the key set at url. Keys are fetched
+// lazily on first use.
+func NewJWKSClient(url string, logger *logrus.Logger) *JWKSClient {
+	return &JWKSClient{
+		url:    url,
+		client: &http.Client{Timeout: jwksFetchTimeout},
+		logger: logger,
+		now:    time.Now,
+		keys:   make(map[string]crypto.PublicKey),
+	}
+}
+
+// PublicKey returns the key with the given kid, refreshing the cached set
+// when it is stale or does not contain the key
+func (c *JWKSClient) PublicKey(kid string) (crypto.PublicKey, error) {
+	c.mu.Lock()
+	now := c.now()
+	key, ok := c.keys[kid]
+	if ok && now.Sub(c.fetchedAt) < jwksCacheTTL {
+		c.mu.Unlock()
+		return key, nil
+	}
+
+	// Callers that need a fetch while one is in flight wait for it instead of
+	// starting their own. Neither unknown kids nor an unreachable issuer may
+	// turn every request into a fetch.
+	done := c.fetching
+	switch {
+	case done != nil:
+		c.mu.Unlock()
+		<-done
+		c.mu.Lock()
+		key, ok = c.keys[kid]
+	case now.Sub(c.lastAttempt) >= jwksMinRefresh:
+		c.lastAttempt = now
+		done = make(chan struct{})
+		c.fetching = done
+		c.mu.Unlock()
+
+		keys, err := c.fetch()
+		if err != nil {
+			c.logger.WithError(err).WithField("url", c.url).Warn("Failed to fetch JWKS, using cached keys")
+		}
+
+		c.mu.Lock()
+		if err == nil {
+			c.keys = keys
+			c.fetchedAt = now
+		}
+		c.fetching = nil
+		close(done)
+		key, ok = c.keys[kid]
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+// fetch downloads and decodes the current key set. Keys that fail to decode
+// are skipped so one bad entry does not block the others.
+func (c *JWKSClient) fetch() (map[string]crypto.PublicKey, error) {
+	resp, err := c.client.Get(c.url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var set JWKS
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.PublicKey()
+		if err != nil {
+			c.logger.WithError(err).WithField("kid", jwk.Kid).Warn("Skipping invalid JWK")
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS has no usable keys")
+	}
+	return keys, nil
+}
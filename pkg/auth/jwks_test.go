@@ -0,0 +1,311 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// testIssuer signs tokens and serves its public keys like api-accounts does
+type testIssuer struct {
+	mu      sync.Mutex
+	keys    map[string]crypto.Signer
+	fetches int
+	down    bool
+	// hold, when set, delays responses until it is closed
+	hold chan struct{}
+}
+
+func (i *testIssuer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	i.mu.Lock()
+	i.fetches++
+	hold := i.hold
+	i.mu.Unlock()
+	if hold != nil {
+		<-hold
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.down {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	var set JWKS
+	for kid, key := range i.keys {
+		switch pub := key.Public().(type) {
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JWK{Kty: "OKP", Kid: kid, Use: "sig", Alg: "EdDSA", Crv: "Ed25519",
+				X: base64.RawURLEncoding.EncodeToString(pub)})
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{Kty: "RSA", Kid: kid, Use: "sig", Alg: "RS256",
+				N: base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())})
+		}
+	}
+	json.NewEncoder(w).Encode(set)
+}
+
+func (i *testIssuer) set(kid string, key crypto.Signer) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if key == nil {
+		delete(i.keys, kid)
+		return
+	}
+	i.keys[kid] = key
+}
+
+func (i *testIssuer) fetchCount() int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.fetches
+}
+
+func newEd25519Key(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	return key
+}
+
+// signToken signs a token for user-001 with the given method, key and kid
+func signToken(t *testing.T, method jwt.SigningMethod, key interface{}, kid string) string {
+	t.Helper()
+	claims := Claims{
+		UserID: "user-001",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "jti-001",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return signed
+}
+
+func newTestClient(url string, now *time.Time) *JWKSClient {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	client := NewJWKSClient(url, logger)
+	client.now = func() time.Time { return *now }
+	return client
+}
+
+func TestJWKSClientKeyRotation(t *testing.T) {
+	oldKey := newEd25519Key(t)
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	issuer := &testIssuer{keys: map[string]crypto.Signer{"old": oldKey}}
+	server := httptest.NewServer(issuer)
+	defer server.Close()
+
+	now := time.Now()
+	verifier := NewVerifier(newTestClient(server.URL, &now))
+
+	oldToken := signToken(t, jwt.SigningMethodEdDSA, oldKey, "old")
+	if _, err := verifier.Verify(oldToken); err != nil {
+		t.Fatalf("Expected the old key to verify, got %v", err)
+	}
+	if _, err := verifier.Verify(oldToken); err != nil || issuer.fetchCount() != 1 {
+		t.Fatalf("Expected the cached set to be reused, got %v after %d fetches", err, issuer.fetchCount())
+	}
+
+	// The issuer publishes a new key next to the old one and starts signing
+	// with it: the unknown kid triggers a refetch, and both keys verify
+	issuer.set("new", newKey)
+	newToken := signToken(t, jwt.SigningMethodRS256, newKey, "new")
+	now = now.Add(jwksMinRefresh)
+	if _, err := verifier.Verify(newToken); err != nil {
+		t.Fatalf("Expected the new key to verify, got %v", err)
+	}
+	if _, err := verifier.Verify(oldToken); err != nil {
+		t.Errorf("Expected the old key to verify during the overlap, got %v", err)
+	}
+	if issuer.fetchCount() != 2 {
+		t.Errorf("Expected 2 fetches, got %d", issuer.fetchCount())
+	}
+
+	// Unknown kids do not cause a fetch more than once per jwksMinRefresh
+	stranger := signToken(t, jwt.SigningMethodEdDSA, newEd25519Key(t), "stranger")
+	for i := 0; i < 3; i++ {
+		if _, err := verifier.Verify(stranger); !errors.Is(err, ErrUnknownKey) {
+			t.Errorf("Expected ErrUnknownKey, got %v", err)
+		}
+	}
+	if issuer.fetchCount() != 2 {
+		t.Errorf("Expected unknown kids to be rate limited, got %d fetches", issuer.fetchCount())
+	}
+
+	// Once the cache expires the retired key is dropped
+	issuer.set("old", nil)
+	now = now.Add(jwksCacheTTL)
+	if _, err := verifier.Verify(oldToken); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Expected the retired key to be rejected, got %v", err)
+	}
+	if _, err := verifier.Verify(newToken); err != nil {
+		t.Errorf("Expected the new key to verify, got %v", err)
+	}
+}
+
+func TestJWKSClientServesStaleKeys(t *testing.T) {
+	key := newEd25519Key(t)
+	issuer := &testIssuer{keys: map[string]crypto.Signer{"k1": key}}
+	server := httptest.NewServer(issuer)
+	defer server.Close()
+
+	now := time.Now()
+	client := newTestClient(server.URL, &now)
+	if _, err := client.PublicKey("k1"); err != nil {
+		t.Fatalf("PublicKey failed: %v", err)
+	}
+
+	// api-accounts being down does not lock everyone out
+	issuer.mu.Lock()
+	issuer.down = true
+	issuer.mu.Unlock()
+	now = now.Add(jwksCacheTTL)
+	if _, err := client.PublicKey("k1"); err != nil {
+		t.Errorf("Expected the cached key while the issuer is down, got %v", err)
+	}
+	if _, err := client.PublicKey("k1"); err != nil || issuer.fetchCount() != 2 {
+		t.Errorf("Expected failed fetches to be rate limited, got %v after %d fetches", err, issuer.fetchCount())
+	}
+}
+
+func TestJWKSClientFetchesOutsideTheLock(t *testing.T) {
+	known, rotated := newEd25519Key(t), newEd25519Key(t)
+	issuer := &testIssuer{keys: map[string]crypto.Signer{"k1": known}}
+	server := httptest.NewServer(issuer)
+	defer server.Close()
+
+	now := time.Now()
+	client := newTestClient(server.URL, &now)
+	if _, err := client.PublicKey("k1"); err != nil {
+		t.Fatalf("PublicKey failed: %v", err)
+	}
+
+	// Callers asking for a new key while the issuer is slow share one fetch
+	release := make(chan struct{})
+	issuer.mu.Lock()
+	issuer.hold = release
+	issuer.mu.Unlock()
+	issuer.set("k2", rotated)
+	now = now.Add(jwksMinRefresh)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.PublicKey("k2")
+			errs <- err
+		}()
+	}
+	for issuer.fetchCount() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Cached keys are served in the meantime
+	cached := make(chan error, 1)
+	go func() {
+		_, err := client.PublicKey("k1")
+		cached <- err
+	}()
+	select {
+	case err := <-cached:
+		if err != nil {
+			t.Errorf("Expected the cached key during the fetch, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected the cached key without waiting for the fetch")
+	}
+
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Expected the new key once fetched, got %v", err)
+		}
+	}
+	if issuer.fetchCount() != 2 {
+		t.Errorf("Expected one fetch for the concurrent callers, got %d fetches", issuer.fetchCount())
+	}
+}
+
+func TestVerifierRejects(t *testing.T) {
+	key := newEd25519Key(t)
+	verifier := NewVerifier(StaticKeys{"k1": key.Public()})
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"missing kid", signToken(t, jwt.SigningMethodEdDSA, key, "")},
+		{"unknown kid", signToken(t, jwt.SigningMethodEdDSA, key, "k2")},
+		{"wrong key", signToken(t, jwt.SigningMethodEdDSA, newEd25519Key(t), "k1")},
+		{"HMAC", signToken(t, jwt.SigningMethodHS256, []byte("shared-secret"), "k1")},
+		{"malformed", "header.payload"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := verifier.Verify(tt.token); err == nil {
+				t.Error("Verify should fail")
+			}
+		})
+	}
+
+	if claims, err := verifier.Verify(signToken(t, jwt.SigningMethodEdDSA, key, "k1")); err != nil || claims.UserID != "user-001" {
+		t.Errorf("Expected a valid token to verify, got %v", err)
+	}
+}
+
+func TestJWKPublicKeyRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		jwk  JWK
+	}{
+		{"unknown type", JWK{Kty: "EC", Crv: "P-256"}},
+		{"unknown curve", JWK{Kty: "OKP", Crv: "X25519", X: "AAAA"}},
+		{"short Ed25519 key", JWK{Kty: "OKP", Crv: "Ed25519", X: "AAAA"}},
+		{"bad RSA exponent", JWK{Kty: "RSA", N: "AQAB", E: "AQ"}},
+		{"bad encoding", JWK{Kty: "RSA", N: "!!", E: "AQAB"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.jwk.PublicKey(); err == nil {
+				t.Error("PublicKey should fail")
+			}
+		})
+	}
+}
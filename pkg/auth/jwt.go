@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTManager manages JWT token creation and validation. Tokens are signed
+// with the active key; tokens signed with any of its keys are accepted, so
+// tokens issued before a key rotation keep working until they expire.
+type JWTManager struct {
+	keys          map[string]*SigningKey
+	active        *SigningKey
+	tokenDuration time.Duration
+}
+
+// NewJWTManager creates a new JWT manager that signs with the key whose ID is
+// activeKID, or the last of keys when activeKID is empty
+func NewJWTManager(keys []*SigningKey, activeKID string, tokenDuration time.Duration) (*JWTManager, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("at least one signing key is required")
+	}
+
+	manager := &JWTManager{
+		keys:          make(map[string]*SigningKey, len(keys)),
+		active:        keys[len(keys)-1],
+		tokenDuration: tokenDuration,
+	}
+	for _, key := range keys {
+		if _, err := key.Method(); err != nil {
+			return nil, fmt.Errorf("key %s: %w", key.ID, err)
+		}
+		if _, exists := manager.keys[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate key ID %s", key.ID)
+		}
+		manager.keys[key.ID] = key
+	}
+	if activeKID != "" {
+		active, exists := manager.keys[activeKID]
+		if !exists {
+			return nil, fmt.Errorf("active key %s: %w", activeKID, ErrUnknownKey)
+		}
+		manager.active = active
+	}
+
+	return manager, nil
+}
+
+// Generate creates a new JWT token for a user with the user role
+func (manager *JWTManager) Generate(userID, email string) (string, error) {
+	token, _, err := manager.Issue(NewClaims(userID, email, RoleUser), 0)
+	return token, err
+}
+
+// Issue signs claims as a new JWT token and returns it with the completed
+// claims. Every token gets a random ID (jti) so it can be revoked before it
+// expires. It expires after ttl, or the manager's token duration when ttl is 0.
+func (manager *JWTManager) Issue(claims *Claims, ttl time.Duration) (string, *Claims, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", nil, err
+	}
+	if ttl <= 0 {
+		ttl = manager.tokenDuration
+	}
+
+	now := time.Now()
+	issued := *claims
+	issued.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        hex.EncodeToString(id),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+	}
+	claims = &issued
+
+	method, err := manager.active.Method()
+	if err != nil {
+		return "", nil, err
+	}
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = manager.active.ID
+
+	signed, err := token.SignedString(manager.active.Key)
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, claims, nil
+}
+
+// Verify validates a JWT token and returns the claims
+func (manager *JWTManager) Verify(tokenString string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(
+		tokenString,
+		&Claims{},
+		func(token *jwt.Token) (interface{}, error) {
+			kid, _ := token.Header["kid"].(string)
+			key, exists := manager.keys[kid]
+			if !exists {
+				return nil, ErrUnknownKey
+			}
+			// The header's alg must be the one the key signs with
+			method, err := key.Method()
+			if err != nil || method.Alg() != token.Method.Alg() {
+				return nil, ErrInvalidToken
+			}
+			return key.Key.Public(), nil
+		},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*Claims)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+// TokenDuration returns how long issued tokens are valid
+func (manager *JWTManager) TokenDuration() time.Duration {
+	return manager.tokenDuration
+}
+
+// JWKS returns the public keys of every key the manager accepts, sorted by ID
+func (manager *JWTManager) JWKS() (*JWKS, error) {
+	set := &JWKS{Keys: make([]JWK, 0, len(manager.keys))}
+	for _, key := range manager.keys {
+		jwk, err := key.JWK()
+		if err != nil {
+			return nil, err
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set, nil
+}
@@ -0,0 +1,390 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// newTestKey generates an Ed25519 signing key with the given ID
+func newTestKey(t *testing.T, id string) *SigningKey {
+	t.Helper()
+	key, err := GenerateSigningKey()
+	if err != nil {
+		t.Fatalf("GenerateSigningKey failed: %v", err)
+	}
+	key.ID = id
+	return key
+}
+
+// newTestRSAKey generates a 2048-bit RSA signing key with the given ID
+func newTestRSAKey(t *testing.T, id string) *SigningKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	return &SigningKey{ID: id, Key: key}
+}
+
+// newTestManager creates a manager with a single Ed25519 key
+func newTestManager(t *testing.T, tokenDuration time.Duration) *JWTManager {
+	t.Helper()
+	manager, err := NewJWTManager([]*SigningKey{newTestKey(t, "test")}, "", tokenDuration)
+	if err != nil {
+		t.Fatalf("NewJWTManager failed: %v", err)
+	}
+	return manager
+}
+
+func TestNewJWTManager(t *testing.T) {
+	first, second := newTestKey(t, "2024-01"), newTestKey(t, "2024-06")
+
+	tests := []struct {
+		name       string
+		keys       []*SigningKey
+		activeKID  string
+		wantActive string
+		wantErr    bool
+	}{
+		{"single key", []*SigningKey{first}, "", "2024-01", false},
+		{"defaults to the last key", []*SigningKey{first, second}, "", "2024-06", false},
+		{"explicit active key", []*SigningKey{first, second}, "2024-01", "2024-01", false},
+		{"unknown active key", []*SigningKey{first}, "2025-01", "", true},
+		{"no keys", nil, "", "", true},
+		{"duplicate IDs", []*SigningKey{first, first}, "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager, err := NewJWTManager(tt.keys, tt.activeKID, 24*time.Hour)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewJWTManager() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if manager.active.ID != tt.wantActive {
+				t.Errorf("Active key mismatch: got %v, want %v", manager.active.ID, tt.wantActive)
+			}
+			if manager.TokenDuration() != 24*time.Hour {
+				t.Errorf("Token duration mismatch: got %v", manager.TokenDuration())
+			}
+		})
+	}
+}
+
+func TestJWTManagerGenerate(t *testing.T) {
+	manager := newTestManager(t, 24*time.Hour)
+
+	tests := []struct {
+		name   string
+		userID string
+		email  string
+	}{
+		{"user 1", "user-001", "user1@example.com"},
+		{"user 2", "user-002", "user2@example.com"},
+		{"user 3", "user-003", "user3@test.org"},
+		{"user with long id", "user-123456789", "longid@example.com"},
+		{"user with special email", "user-005", "special+tag@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := manager.Generate(tt.userID, tt.email)
+			if err != nil {
+				t.Fatalf("Generate failed: %v", err)
+			}
+			if token == "" {
+				t.Error("Generated token is empty")
+			}
+		})
+	}
+}
+
+func TestJWTManagerVerify(t *testing.T) {
+	for _, key := range []*SigningKey{newTestKey(t, "ed"), newTestRSAKey(t, "rsa")} {
+		t.Run(key.ID, func(t *testing.T) {
+			manager, err := NewJWTManager([]*SigningKey{key}, "", 24*time.Hour)
+			if err != nil {
+				t.Fatalf("NewJWTManager failed: %v", err)
+			}
+
+			// Generate a valid token
+			userID := "test-user"
+			email := "test@example.com"
+			token, err := manager.Generate(userID, email)
+			if err != nil {
+				t.Fatalf("Failed to generate token: %v", err)
+			}
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &Claims{})
+			if err != nil {
+				t.Fatalf("Failed to parse token: %v", err)
+			}
+			method, _ := key.Method()
+			if parsed.Header["kid"] != key.ID || parsed.Method.Alg() != method.Alg() {
+				t.Errorf("Unexpected header: %v", parsed.Header)
+			}
+
+			// Test verification
+			claims, err := manager.Verify(token)
+			if err != nil {
+				t.Fatalf("Verify failed: %v", err)
+			}
+
+			if claims.UserID != userID {
+				t.Errorf("UserID mismatch: got %v, want %v", claims.UserID, userID)
+			}
+			if claims.Email != email {
+				t.Errorf("Email mismatch: got %v, want %v", claims.Email, email)
+			}
+		})
+	}
+}
+
+func TestJWTManagerVerifyInvalidToken(t *testing.T) {
+	manager := newTestManager(t, 24*time.Hour)
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"empty token", ""},
+		{"invalid format", "notavalidtoken"},
+		{"malformed jwt", "header.payload"},
+		{"random string", "xyz123abc456"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := manager.Verify(tt.token)
+			if err == nil {
+				t.Error("Verify should fail for invalid token")
+			}
+		})
+	}
+}
+
+func TestJWTManagerVerifyExpiredToken(t *testing.T) {
+	// Create manager with very short duration
+	manager := newTestManager(t, -1*time.Hour) // Already expired
+
+	token, err := manager.Generate("user-001", "user@example.com")
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	_, err = manager.Verify(token)
+	if err == nil {
+		t.Error("Verify should fail for expired token")
+	}
+}
+
+func TestJWTManagerVerifyDifferentKey(t *testing.T) {
+	manager1 := newTestManager(t, 24*time.Hour)
+	manager2 := newTestManager(t, 24*time.Hour)
+
+	token, err := manager1.Generate("user-001", "user@example.com")
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	// Both keys are called "test", so only the signature tells them apart
+	_, err = manager2.Verify(token)
+	if err == nil {
+		t.Error("Verify should fail when signed with a different key")
+	}
+}
+
+func TestJWTManagerRejectsHMAC(t *testing.T) {
+	manager := newTestManager(t, 24*time.Hour)
+
+	// A token signed with a shared secret must not verify, whatever the kid says
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{UserID: "user-001"})
+	token.Header["kid"] = "test"
+	signed, err := token.SignedString([]byte("shared-secret"))
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+
+	if _, err := manager.Verify(signed); err == nil {
+		t.Error("Verify should reject HMAC-signed tokens")
+	}
+}
+
+func TestJWTManagerKeyRotation(t *testing.T) {
+	oldKey, newKey := newTestKey(t, "old"), newTestRSAKey(t, "new")
+
+	before, err := NewJWTManager([]*SigningKey{oldKey}, "", time.Hour)
+	if err != nil {
+		t.Fatalf("NewJWTManager failed: %v", err)
+	}
+	issuedBefore, err := before.Generate("user-001", "user@example.com")
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	// During the overlap both keys are accepted and the new one signs
+	during, err := NewJWTManager([]*SigningKey{oldKey, newKey}, "new", time.Hour)
+	if err != nil {
+		t.Fatalf("NewJWTManager failed: %v", err)
+	}
+	if _, err := during.Verify(issuedBefore); err != nil {
+		t.Errorf("Expected a token signed with the old key to verify, got %v", err)
+	}
+	issuedDuring, err := during.Generate("user-001", "user@example.com")
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	// Once the old key is retired only tokens signed with the new key verify
+	after, err := NewJWTManager([]*SigningKey{newKey}, "", time.Hour)
+	if err != nil {
+		t.Fatalf("NewJWTManager failed: %v", err)
+	}
+	if _, err := after.Verify(issuedDuring); err != nil {
+		t.Errorf("Expected a token signed with the new key to verify, got %v", err)
+	}
+	if _, err := after.Verify(issuedBefore); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Expected ErrUnknownKey for a retired key, got %v", err)
+	}
+
+	set, err := during.JWKS()
+	if err != nil {
+		t.Fatalf("JWKS failed: %v", err)
+	}
+	if len(set.Keys) != 2 || set.Keys[0].Kid != "new" || set.Keys[1].Kid != "old" {
+		t.Fatalf("Expected both keys sorted by kid, got %+v", set.Keys)
+	}
+	if set.Keys[0].Kty != "RSA" || set.Keys[0].Alg != "RS256" || set.Keys[0].E != "AQAB" {
+		t.Errorf("Unexpected RSA JWK: %+v", set.Keys[0])
+	}
+	if set.Keys[1].Kty != "OKP" || set.Keys[1].Crv != "Ed25519" || set.Keys[1].Alg != "EdDSA" {
+		t.Errorf("Unexpected Ed25519 JWK: %+v", set.Keys[1])
+	}
+	if strings.Contains(set.Keys[0].N, "=") {
+		t.Error("Expected unpadded base64url values")
+	}
+}
+
+func TestJWTTokenLifecycle(t *testing.T) {
+	manager := newTestManager(t, 1*time.Hour)
+
+	tests := []struct {
+		name   string
+		userID string
+		email  string
+	}{
+		{"lifecycle user 1", "u1", "u1@test.com"},
+		{"lifecycle user 2", "u2", "u2@test.com"},
+		{"lifecycle user 3", "u3", "u3@test.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Generate
+			token, err := manager.Generate(tt.userID, tt.email)
+			if err != nil {
+				t.Fatalf("Generate failed: %v", err)
+			}
+
+			// Verify
+			claims, err := manager.Verify(token)
+			if err != nil {
+				t.Fatalf("Verify failed: %v", err)
+			}
+
+			// Check claims
+			if claims.UserID != tt.userID {
+				t.Errorf("UserID mismatch: got %v, want %v", claims.UserID, tt.userID)
+			}
+			if claims.Email != tt.email {
+				t.Errorf("Email mismatch: got %v, want %v", claims.Email, tt.email)
+			}
+
+			// Check timestamps
+			if claims.ExpiresAt == nil {
+				t.Error("ExpiresAt should not be nil")
+			}
+			if claims.IssuedAt == nil {
+				t.Error("IssuedAt should not be nil")
+			}
+			if claims.NotBefore == nil {
+				t.Error("NotBefore should not be nil")
+			}
+		})
+	}
+}
+
+func TestJWTManagerIssue(t *testing.T) {
+	manager := newTestManager(t, 15*time.Minute)
+
+	first, claims, err := manager.Issue(NewClaims("user-001", "user@example.com", RoleSupport), 0)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	if claims.ID == "" {
+		t.Fatal("Expected the token to have a jti")
+	}
+	if got := claims.ExpiresAt.Sub(claims.IssuedAt.Time); got != 15*time.Minute {
+		t.Errorf("Expected a 15 minute lifetime, got %v", got)
+	}
+
+	verified, err := manager.Verify(first)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if verified.ID != claims.ID {
+		t.Errorf("jti mismatch: got %v, want %v", verified.ID, claims.ID)
+	}
+	if len(verified.Roles) != 1 || verified.Roles[0] != RoleSupport {
+		t.Errorf("Expected the support role, got %v", verified.Roles)
+	}
+	if !verified.HasScope(ScopeAdminRead) || !verified.HasScope(ScopeAccountsRead) || verified.HasScope(ScopeAdminWrite) {
+		t.Errorf("Unexpected scopes %q", verified.Scope)
+	}
+
+	_, second, err := manager.Issue(NewClaims("user-001", "user@example.com", RoleUser), 5*time.Minute)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	if second.ID == claims.ID {
+		t.Error("Expected every token to get a distinct jti")
+	}
+	if got := second.ExpiresAt.Sub(second.IssuedAt.Time); got != 5*time.Minute {
+		t.Errorf("Expected the requested 5 minute lifetime, got %v", got)
+	}
+}
+
+func TestScopesForRole(t *testing.T) {
+	tests := []struct {
+		role    string
+		allowed []string
+		denied  []string
+	}{
+		{RoleUser, []string{ScopeAccountsWrite, ScopeTransactionsWrite, ScopeInsightsRead, ScopeInsightsWrite}, []string{ScopeAdminRead, ScopeAdminImpersonate}},
+		{"", []string{ScopeAccountsRead}, []string{ScopeAdminRead}},
+		{RoleSupport, []string{ScopeAdminRead, ScopeAdminImpersonate}, []string{ScopeAdminWrite}},
+		{RoleAdmin, []string{ScopeAdminRead, ScopeAdminImpersonate, ScopeAdminWrite}, nil},
+	}
+
+	for _, tt := range tests {
+		claims := NewClaims("user-001", "user@example.com", tt.role)
+		for _, scope := range tt.allowed {
+			if !claims.HasScope(scope) {
+				t.Errorf("Expected role %q to grant %s", tt.role, scope)
+			}
+		}
+		for _, scope := range tt.denied {
+			if claims.HasScope(scope) {
+				t.Errorf("Expected role %q not to grant %s", tt.role, scope)
+			}
+		}
+	}
+}
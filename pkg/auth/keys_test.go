@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+}
+
+func TestLoadSigningKeys(t *testing.T) {
+	dir := t.TempDir()
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	edDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+	writePEM(t, filepath.Join(dir, "2024-06.pem"), "PRIVATE KEY", edDER)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	writePEM(t, filepath.Join(dir, "2024-01.pem"), "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+
+	// Other files are ignored
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("keys"), 0o600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	keys, err := LoadSigningKeys(dir)
+	if err != nil {
+		t.Fatalf("LoadSigningKeys failed: %v", err)
+	}
+	if len(keys) != 2 || keys[0].ID != "2024-01" || keys[1].ID != "2024-06" {
+		t.Fatalf("Expected keys 2024-01 and 2024-06, got %+v", keys)
+	}
+	if _, ok := keys[0].Key.(*rsa.PrivateKey); !ok {
+		t.Errorf("Expected an RSA key, got %T", keys[0].Key)
+	}
+	if _, ok := keys[1].Key.(ed25519.PrivateKey); !ok {
+		t.Errorf("Expected an Ed25519 key, got %T", keys[1].Key)
+	}
+
+	if _, err := LoadSigningKeys(t.TempDir()); err == nil {
+		t.Error("Expected an error for a directory without keys")
+	}
+}
+
+func TestParsePrivateKeyRejects(t *testing.T) {
+	weak, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"not PEM", []byte("not a key")},
+		{"public key", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte{1}})},
+		{"short RSA key", pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(weak)})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParsePrivateKey(tt.data); err == nil {
+				t.Error("Expected ParsePrivateKey to fail")
+			}
+		})
+	}
+}
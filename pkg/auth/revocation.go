@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite" // Pure Go SQLite driver, keeps CGO_ENABLED=0 builds working
+)
+
+// Revocation stores selectable via the REVOCATION_STORE environment variable
+const (
+	RevocationMemory = "memory"
+	RevocationSQLite = "sqlite"
+)
+
+// RevocationList records access tokens, by jti, that were revoked before
+// they expire. Every service's AuthMiddleware consults it, so a token revoked
+// by api-accounts is rejected everywhere once the services share a store.
+type RevocationList interface {
+	// Revoke rejects the token from now on. expiresAt is when the token would
+	// have expired anyway, after which the entry can be dropped.
+	Revoke(jti string, expiresAt time.Time) error
+	IsRevoked(jti string) (bool, error)
+	Close() error
+}
+
+// NewRevocationList creates the revocation list for the configured store.
+// path is the database file used by the sqlite store.
+func NewRevocationList(store, path string) (RevocationList, error) {
+	switch store {
+	case "", RevocationMemory:
+		return NewMemoryRevocationList(), nil
+	case RevocationSQLite:
+		return NewSQLiteRevocationList(path)
+	default:
+		return nil, fmt.Errorf("unknown revocation store %q", store)
+	}
+}
+
+// MemoryRevocationList is an in-process stand-in for a shared revocation
+// store. Revocations are only seen by the process that made them and are lost
+// on restart, so it suits tests and single-service development.
+type MemoryRevocationList struct {
+	entries map[string]time.Time
+	mu      sync.RWMutex
+	now     func() time.Time
+}
+
+// NewMemoryRevocationList creates an empty in-memory revocation list
+func NewMemoryRevocationList() *MemoryRevocationList {
+	return &MemoryRevocationList{
+		entries: make(map[string]time.Time),
+		now:     time.Now,
+	}
+}
+
+// Revoke records the token and drops entries whose tokens have expired
+func (l *MemoryRevocationList) Revoke(jti string, expiresAt time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	for id, exp := range l.entries {
+		if !exp.After(now) {
+			delete(l.entries, id)
+		}
+	}
+	l.entries[jti] = expiresAt
+	return nil
+}
+
+// IsRevoked reports whether the token was revoked
+func (l *MemoryRevocationList) IsRevoked(jti string) (bool, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	_, revoked := l.entries[jti]
+	return revoked, nil
+}
+
+// Close is a no-op for the in-memory list
+func (l *MemoryRevocationList) Close() error {
+	return nil
+}
+
+// SQLiteRevocationList keeps revocations in a SQLite file that every service
+// opens, such as the shared accountstack.db volume
+type SQLiteRevocationList struct {
+	db  *sql.DB
+	now func() time.Time
+}
+
+// NewSQLiteRevocationList opens (or creates) the revocation table in the
+// database at dbPath. The table is shared by all services rather than owned
+// by one, so it is created here instead of in a service's migrations.
+func NewSQLiteRevocationList(dbPath string) (*SQLiteRevocationList, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", dbPath)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open revocation database: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS revoked_tokens (
+		jti        TEXT PRIMARY KEY,
+		expires_at INTEGER NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens (expires_at)`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create revoked_tokens: %w", err)
+	}
+
+	return &SQLiteRevocationList{db: db, now: time.Now}, nil
+}
+
+// Revoke records the token and drops entries whose tokens have expired
+func (l *SQLiteRevocationList) Revoke(jti string, expiresAt time.Time) error {
+	if _, err := l.db.Exec(`DELETE FROM revoked_tokens WHERE expires_at <= ?`, l.now().Unix()); err != nil {
+		return err
+	}
+	_, err := l.db.Exec(`INSERT INTO revoked_tokens (jti, expires_at) VALUES (?, ?)
+		ON CONFLICT (jti) DO UPDATE SET expires_at = MAX(expires_at, excluded.expires_at)`, jti, expiresAt.Unix())
+	return err
+}
+
+// IsRevoked reports whether the token was revoked
+func (l *SQLiteRevocationList) IsRevoked(jti string) (bool, error) {
+	var exists int
+	if err := l.db.QueryRow(`SELECT COUNT(*) FROM revoked_tokens WHERE jti = ?`, jti).Scan(&exists); err != nil {
+		return false, err
+	}
+	return exists > 0, nil
+}
+
+// Close closes the underlying database
+func (l *SQLiteRevocationList) Close() error {
+	return l.db.Close()
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRevocationLists(t *testing.T) {
+	sqliteList, err := NewSQLiteRevocationList(filepath.Join(t.TempDir(), "revocations.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteRevocationList failed: %v", err)
+	}
+	defer sqliteList.Close()
+
+	now := time.Date(2024, 12, 1, 12, 0, 0, 0, time.UTC)
+	sqliteList.now = func() time.Time { return now }
+	memoryList := NewMemoryRevocationList()
+	memoryList.now = func() time.Time { return now }
+
+	tests := []struct {
+		name string
+		list RevocationList
+	}{
+		{"memory", memoryList},
+		{"sqlite", sqliteList},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if revoked, err := tt.list.IsRevoked("jti-1"); err != nil || revoked {
+				t.Fatalf("Expected jti-1 not to be revoked, got %v (%v)", revoked, err)
+			}
+
+			if err := tt.list.Revoke("jti-1", now.Add(time.Minute)); err != nil {
+				t.Fatalf("Revoke failed: %v", err)
+			}
+			if err := tt.list.Revoke("jti-old", now.Add(-time.Minute)); err != nil {
+				t.Fatalf("Revoke failed: %v", err)
+			}
+			// Revoking twice is harmless
+			if err := tt.list.Revoke("jti-1", now.Add(time.Minute)); err != nil {
+				t.Fatalf("Second Revoke failed: %v", err)
+			}
+
+			if revoked, err := tt.list.IsRevoked("jti-1"); err != nil || !revoked {
+				t.Errorf("Expected jti-1 to be revoked, got %v (%v)", revoked, err)
+			}
+			if revoked, _ := tt.list.IsRevoked("jti-2"); revoked {
+				t.Error("Expected jti-2 not to be revoked")
+			}
+
+			// Entries for tokens that have expired are dropped on the next revocation
+			if err := tt.list.Revoke("jti-3", now.Add(time.Minute)); err != nil {
+				t.Fatalf("Revoke failed: %v", err)
+			}
+			if revoked, _ := tt.list.IsRevoked("jti-old"); revoked {
+				t.Error("Expected the expired entry to be dropped")
+			}
+		})
+	}
+}
+
+func TestSQLiteRevocationListShared(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shared.db")
+
+	// Two services opening the same file see each other's revocations
+	issuer, err := NewSQLiteRevocationList(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteRevocationList failed: %v", err)
+	}
+	defer issuer.Close()
+	verifier, err := NewSQLiteRevocationList(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteRevocationList failed: %v", err)
+	}
+	defer verifier.Close()
+
+	if err := issuer.Revoke("jti-1", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	if revoked, err := verifier.IsRevoked("jti-1"); err != nil || !revoked {
+		t.Errorf("Expected the other connection to see the revocation, got %v (%v)", revoked, err)
+	}
+}
+
+func TestNewRevocationList(t *testing.T) {
+	if _, err := NewRevocationList("", ""); err != nil {
+		t.Errorf("Expected the memory store by default, got %v", err)
+	}
+	if _, err := NewRevocationList("redis", ""); err == nil {
+		t.Error("Expected an error for an unknown store")
+	}
+}
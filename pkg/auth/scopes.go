@@ -0,0 +1,75 @@
+package auth
+
+import "strings"
+
+// Roles a user can hold, sent in the roles claim. Each role grants a fixed
+// set of scopes, see ScopesForRole.
+const (
+	RoleUser    = "user"    // A customer, with access to their own data
+	RoleSupport = "support" // Reads any customer's data and can impersonate customers read-only
+	RoleAdmin   = "admin"   // Support, plus changing other users' roles
+)
+
+// Scopes carried by access tokens. Every service checks the scope an
+// endpoint needs with middleware.RequireScope.
+const (
+	ScopeAccountsRead      = "accounts:read"
+	ScopeAccountsWrite     = "accounts:write"
+	ScopeTransactionsRead  = "transactions:read"
+	ScopeTransactionsWrite = "transactions:write"
+	ScopeInsightsRead      = "insights:read"
+	ScopeInsightsWrite     = "insights:write"
+	ScopeAdminRead         = "admin:read"
+	ScopeAdminWrite        = "admin:write"
+	ScopeAdminImpersonate  = "admin:impersonate"
+)
+
+// customerScopes is what every signed-in user can do with their own data
+var customerScopes = []string{
+	ScopeAccountsRead,
+	ScopeAccountsWrite,
+	ScopeTransactionsRead,
+	ScopeTransactionsWrite,
+	ScopeInsightsRead,
+	ScopeInsightsWrite,
+}
+
+// ReadOnlyScopes are granted to impersonation tokens, so support can see what
+// a customer sees without being able to change anything
+var ReadOnlyScopes = []string{
+	ScopeAccountsRead,
+	ScopeTransactionsRead,
+	ScopeInsightsRead,
+}
+
+// APIKeyScopes returns the scopes a personal API key can carry. Keys act on
+// the user's own data, so admin scopes are never given to them.
+func APIKeyScopes() []string {
+	return append([]string{}, customerScopes...)
+}
+
+// ScopesForRole returns the scopes a role grants. Unknown roles get the
+// customer scopes only.
+func ScopesForRole(role string) []string {
+	scopes := append([]string{}, customerScopes...)
+	switch role {
+	case RoleSupport:
+		scopes = append(scopes, ScopeAdminRead, ScopeAdminImpersonate)
+	case RoleAdmin:
+		scopes = append(scopes, ScopeAdminRead, ScopeAdminImpersonate, ScopeAdminWrite)
+	}
+	return scopes
+}
+
+// NewClaims returns the claims of an access token for a user holding role
+func NewClaims(userID, email, role string) *Claims {
+	if role == "" {
+		role = RoleUser
+	}
+	return &Claims{
+		UserID: userID,
+		Email:  email,
+		Roles:  []string{role},
+		Scope:  strings.Join(ScopesForRole(role), " "),
+	}
+}
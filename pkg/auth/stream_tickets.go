@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"time"
+)
+
+// StreamTicketTTL is how long a stream ticket can be redeemed after it is issued
+const StreamTicketTTL = 30 * time.Second
+
+// ErrInvalidTicket is returned for a stream ticket that is unknown, expired
+// or already used
+var ErrInvalidTicket = errors.New("invalid or expired stream ticket")
+
+// StreamTickets hands out single-use tickets that stand in for the
+// credentials of the request that asked for one. Browsers cannot set headers
+// on EventSource and WebSocket requests, so they send a ticket in the URL
+// rather than an access token or API key, which would end up in proxy and
+// server logs. Tickets are kept in memory and only work on the process that
+// issued them.
+type StreamTickets struct {
+	tickets map[string]streamTicket
+	mu      sync.Mutex
+	now     func() time.Time
+}
+
+// streamTicket is what a ticket is redeemed for
+type streamTicket struct {
+	claims    Claims
+	expiresAt time.Time
+}
+
+// NewStreamTickets creates an empty ticket store
+func NewStreamTickets() *StreamTickets {
+	return &StreamTickets{
+		tickets: make(map[string]streamTicket),
+		now:     time.Now,
+	}
+}
+
+// Issue returns a new ticket for claims and when it expires, dropping the
+// tickets that have expired unused
+func (s *StreamTickets) Issue(claims *Claims) (string, time.Time, error) {
+	id := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return "", time.Time{}, err
+	}
+	ticket := base64.RawURLEncoding.EncodeToString(id)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for t, entry := range s.tickets {
+		if !entry.expiresAt.After(now) {
+			delete(s.tickets, t)
+		}
+	}
+	expiresAt := now.Add(StreamTicketTTL)
+	s.tickets[ticket] = streamTicket{claims: *claims, expiresAt: expiresAt}
+	return ticket, expiresAt, nil
+}
+
+// Redeem returns the claims of the request the ticket was issued to. A
+// ticket can only be redeemed once.
+func (s *StreamTickets) Redeem(ticket string) (*Claims, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.tickets[ticket]
+	if !ok {
+		return nil, ErrInvalidTicket
+	}
+	delete(s.tickets, ticket)
+	if !entry.expiresAt.After(s.now()) {
+		return nil, ErrInvalidTicket
+	}
+	claims := entry.claims
+	return &claims, nil
+}
@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStreamTickets(t *testing.T) {
+	now := time.Date(2024, 12, 1, 12, 0, 0, 0, time.UTC)
+	tickets := NewStreamTickets()
+	tickets.now = func() time.Time { return now }
+
+	claims := &Claims{UserID: "user-001", Scope: ScopeInsightsRead, APIKeyID: "key-1"}
+	ticket, expiresAt, err := tickets.Issue(claims)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	if !expiresAt.Equal(now.Add(StreamTicketTTL)) || len(ticket) < 40 {
+		t.Errorf("Unexpected ticket %q expiring at %v", ticket, expiresAt)
+	}
+	other, _, _ := tickets.Issue(claims)
+	if other == ticket {
+		t.Error("Expected each ticket to be different")
+	}
+
+	redeemed, err := tickets.Redeem(ticket)
+	if err != nil {
+		t.Fatalf("Redeem failed: %v", err)
+	}
+	if redeemed.UserID != "user-001" || redeemed.Scope != ScopeInsightsRead || redeemed.APIKeyID != "key-1" {
+		t.Errorf("Expected the claims the ticket was issued for, got %+v", redeemed)
+	}
+
+	// Tickets work once, and not after they expire
+	if _, err := tickets.Redeem(ticket); !errors.Is(err, ErrInvalidTicket) {
+		t.Errorf("Expected a used ticket to be refused, got %v", err)
+	}
+	now = now.Add(StreamTicketTTL)
+	if _, err := tickets.Redeem(other); !errors.Is(err, ErrInvalidTicket) {
+		t.Errorf("Expected an expired ticket to be refused, got %v", err)
+	}
+	if _, err := tickets.Redeem("made-up"); !errors.Is(err, ErrInvalidTicket) {
+		t.Errorf("Expected an unknown ticket to be refused, got %v", err)
+	}
+}
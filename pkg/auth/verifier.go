@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Verifier validates tokens issued by api-accounts. It never signs tokens;
+// the public keys come from a KeySource, normally the accounts JWKS.
+type Verifier struct {
+	keys KeySource
+}
+
+// NewVerifier creates a new token verifier
+func NewVerifier(keys KeySource) *Verifier {
+	return &Verifier{keys: keys}
+}
+
+// Verify validates a JWT token and returns the claims. The token must name
+// its key in the kid header and be signed with RS256 or EdDSA.
+func (v *Verifier) Verify(tokenString string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(
+		tokenString,
+		&Claims{},
+		func(token *jwt.Token) (interface{}, error) {
+			kid, ok := token.Header["kid"].(string)
+			if !ok || kid == "" {
+				return nil, ErrInvalidToken
+			}
+			key, err := v.keys.PublicKey(kid)
+			if err != nil {
+				return nil, err
+			}
+
+			// The key type decides the algorithm, never the token header
+			switch key.(type) {
+			case *rsa.PublicKey:
+				if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+					return nil, ErrInvalidToken
+				}
+			case ed25519.PublicKey:
+				if _, ok := token.Method.(*jwt.SigningMethodEd25519); !ok {
+					return nil, ErrInvalidToken
+				}
+			default:
+				return nil, ErrInvalidToken
+			}
+			return key, nil
+		},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*Claims)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
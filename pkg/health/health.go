@@ -0,0 +1,35 @@
+// Package health serves the /healthz endpoint every service exposes.
+package health
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/pkg/respond"
+)
+
+// Response represents the health check response
+type Response struct {
+	Status    string    `json:"status"`
+	Timestamp time.Time `json:"timestamp"`
+	Service   string    `json:"service"`
+}
+
+// Handler handles health check requests
+type Handler struct {
+	service string
+}
+
+// NewHandler creates a health handler reporting the given service name
+func NewHandler(service string) *Handler {
+	return &Handler{service: service}
+}
+
+// ServeHTTP handles GET /healthz
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	respond.JSON(w, http.StatusOK, Response{
+		Status:    "ok",
+		Timestamp: time.Now(),
+		Service:   h.service,
+	})
+}
@@ -0,0 +1,209 @@
+// Package middleware provides the HTTP middleware every service runs:
+// authentication, scope checks, CORS and request logging.
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/pkg/auth"
+	"github.com/CB-AccountStack/AccountStack/pkg/problem"
+	"github.com/sirupsen/logrus"
+)
+
+// contextKey is a custom type for context keys to avoid collisions
+type contextKey string
+
+const (
+	userIDKey contextKey = "userID"
+	claimsKey contextKey = "claims"
+)
+
+// Details of the problem responses sent when a request cannot be authenticated
+const (
+	unauthorizedDetail = "A valid access token or API key is required"
+	unavailableDetail  = "Credentials cannot be checked right now, try again later"
+)
+
+// TokenVerifier validates an access token and returns its claims. The
+// issuing auth.JWTManager and the JWKS-backed auth.Verifier both satisfy it.
+type TokenVerifier interface {
+	Verify(tokenString string) (*auth.Claims, error)
+}
+
+// AuthMiddleware validates JWT tokens and extracts user information. Tokens
+// whose jti is on the revocation list are rejected. Requests may instead send
+// a personal API key as "Authorization: ApiKey <key>". /healthz and
+// publicPaths are served without credentials, and requests authenticated by
+// TicketFromQuery are passed through.
+func AuthMiddleware(verifier TokenVerifier, revocations auth.RevocationList, apiKeys auth.APIKeyStore, logger *logrus.Logger, publicPaths ...string) func(http.Handler) http.Handler {
+	public := map[string]bool{"/healthz": true}
+	for _, path := range publicPaths {
+		public[path] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Skip auth for health check and the service's signed-out endpoints
+			if public[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Requests TicketFromQuery authenticated already carry their claims
+			if GetClaims(r) != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Extract token from Authorization header
+			authHeader := r.Header.Get("Authorization")
+			if authHeader == "" {
+				logger.Warn("No authorization header provided")
+				problem.Write(w, r, http.StatusUnauthorized, unauthorizedDetail)
+				return
+			}
+
+			// Check Bearer token or API key format
+			parts := strings.SplitN(authHeader, " ", 2)
+			if len(parts) != 2 || (parts[0] != "Bearer" && parts[0] != "ApiKey") {
+				logger.Warn("Invalid authorization header format")
+				problem.Write(w, r, http.StatusUnauthorized, unauthorizedDetail)
+				return
+			}
+
+			if parts[0] == "ApiKey" {
+				claims, err := auth.AuthenticateAPIKey(apiKeys, parts[1], time.Now())
+				if errors.Is(err, auth.ErrInvalidAPIKey) {
+					logger.Warn("Invalid API key")
+					problem.Write(w, r, http.StatusUnauthorized, unauthorizedDetail)
+					return
+				}
+				if err != nil {
+					logger.WithError(err).Error("Failed to check API key")
+					problem.Write(w, r, http.StatusServiceUnavailable, unavailableDetail)
+					return
+				}
+
+				ctx := context.WithValue(r.Context(), userIDKey, claims.UserID)
+				ctx = context.WithValue(ctx, claimsKey, claims)
+				logger.WithFields(logrus.Fields{
+					"userId":   claims.UserID,
+					"apiKeyId": claims.APIKeyID,
+				}).Debug("User authenticated with API key")
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			// Verify token
+			claims, err := verifier.Verify(parts[1])
+			if err != nil {
+				logger.WithError(err).Warn("Invalid token")
+				problem.Write(w, r, http.StatusUnauthorized, unauthorizedDetail)
+				return
+			}
+
+			// Reject revoked tokens, tokens without a jti since they cannot be
+			// revoked, and tokens that do not name a user
+			if claims.ID == "" || claims.UserID == "" {
+				logger.WithField("userId", claims.UserID).Warn("Token has no jti or user")
+				problem.Write(w, r, http.StatusUnauthorized, unauthorizedDetail)
+				return
+			}
+			revoked, err := revocations.IsRevoked(claims.ID)
+			if err != nil {
+				logger.WithError(err).Error("Failed to check token revocation")
+				problem.Write(w, r, http.StatusServiceUnavailable, unavailableDetail)
+				return
+			}
+			if revoked {
+				logger.WithField("userId", claims.UserID).Warn("Revoked token")
+				problem.Write(w, r, http.StatusUnauthorized, unauthorizedDetail)
+				return
+			}
+
+			// Add user ID to request context
+			ctx := context.WithValue(r.Context(), userIDKey, claims.UserID)
+			ctx = context.WithValue(ctx, claimsKey, claims)
+
+			logger.WithField("userId", claims.UserID).Debug("User authenticated")
+
+			// Every request made while impersonating is recorded with who made it
+			if claims.Impersonated() {
+				logger.WithFields(logrus.Fields{
+					"actorId": claims.Act.UserID,
+					"userId":  claims.UserID,
+					"tokenId": claims.ID,
+					"method":  r.Method,
+					"path":    r.URL.Path,
+				}).Info("Impersonated request")
+			}
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// TicketQueryParam carries a stream ticket on the paths TicketFromQuery is given
+const TicketQueryParam = "ticket"
+
+// TicketFromQuery lets clients that cannot set headers, such as the browser
+// EventSource and WebSocket APIs, authenticate on the given paths with a
+// stream ticket from auth.StreamTickets, sent as the ticket query parameter.
+// Access tokens and API keys are never accepted in the URL. The ticket is
+// redeemed, so it works once, and taken out of the URL so it is not passed
+// on. It must run before AuthMiddleware, which lets the requests it
+// authenticated through. An Authorization header takes precedence over it.
+func TicketFromQuery(tickets *auth.StreamTickets, logger *logrus.Logger, paths ...string) func(http.Handler) http.Handler {
+	allowed := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		allowed[path] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			query := r.URL.Query()
+			ticket := query.Get(TicketQueryParam)
+			if !allowed[r.URL.Path] || ticket == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			query.Del(TicketQueryParam)
+			r = r.Clone(r.Context())
+			r.URL.RawQuery = query.Encode()
+			if r.Header.Get("Authorization") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			claims, err := tickets.Redeem(ticket)
+			if err != nil {
+				logger.WithField("path", r.URL.Path).Warn("Invalid stream ticket")
+				problem.Write(w, r, http.StatusUnauthorized, "The stream ticket is invalid, expired or already used")
+				return
+			}
+			ctx := context.WithValue(r.Context(), userIDKey, claims.UserID)
+			ctx = context.WithValue(ctx, claimsKey, claims)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// GetUserID returns the ID of the authenticated user, or "" when the request
+// was not authenticated. Handlers only see "" on public paths, since
+// AuthMiddleware and RequireScope refuse requests without a user.
+func GetUserID(r *http.Request) string {
+	userID, _ := r.Context().Value(userIDKey).(string)
+	return userID
+}
+
+// GetClaims returns the verified token claims of the request, or nil when it
+// was not authenticated
+func GetClaims(r *http.Request) *auth.Claims {
+	claims, _ := r.Context().Value(claimsKey).(*auth.Claims)
+	return claims
+}
@@ -0,0 +1,253 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/CB-AccountStack/AccountStack/pkg/auth"
+	"github.com/CB-AccountStack/AccountStack/pkg/problem"
+	"github.com/sirupsen/logrus"
+)
+
+// failingAPIKeys is an API key store that cannot be read
+type failingAPIKeys struct {
+	auth.APIKeyStore
+}
+
+func (failingAPIKeys) Get(keyHash string) (*auth.APIKey, error) {
+	return nil, errors.New("database is locked")
+}
+
+// verifierFunc adapts a function to TokenVerifier
+type verifierFunc func(tokenString string) (*auth.Claims, error)
+
+func (f verifierFunc) Verify(tokenString string) (*auth.Claims, error) {
+	return f(tokenString)
+}
+
+func newTestLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+func newTestJWTManager(t *testing.T) *auth.JWTManager {
+	t.Helper()
+	key, err := auth.GenerateSigningKey()
+	if err != nil {
+		t.Fatalf("GenerateSigningKey failed: %v", err)
+	}
+	manager, err := auth.NewJWTManager([]*auth.SigningKey{key}, "", time.Hour)
+	if err != nil {
+		t.Fatalf("NewJWTManager failed: %v", err)
+	}
+	return manager
+}
+
+// whoAmI responds with the user the middleware put in the request context
+func whoAmI(w http.ResponseWriter, r *http.Request) {
+	io.WriteString(w, GetUserID(r))
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	logger := newTestLogger()
+	manager := newTestJWTManager(t)
+	revocations := auth.NewMemoryRevocationList()
+	apiKeys := auth.NewMemoryAPIKeyStore()
+
+	token, _, err := manager.Issue(auth.NewClaims("user-002", "user@example.com", auth.RoleUser), 0)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	revoked, revokedClaims, err := manager.Issue(auth.NewClaims("user-002", "user@example.com", auth.RoleUser), 0)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	if err := revocations.Revoke(revokedClaims.ID, revokedClaims.ExpiresAt.Time); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	if err := apiKeys.Create(&auth.APIKey{ID: "key-1", UserID: "user-003", KeyHash: auth.HashAPIKey("ask_valid"), Scope: auth.ScopeInsightsRead}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	handler := AuthMiddleware(manager, revocations, apiKeys, logger, "/login")(http.HandlerFunc(whoAmI))
+	unavailable := AuthMiddleware(manager, revocations, failingAPIKeys{}, logger)(http.HandlerFunc(whoAmI))
+
+	tests := []struct {
+		name          string
+		handler       http.Handler
+		path          string
+		authorization string
+		want          int
+		wantUser      string
+	}{
+		{"health check is public", handler, "/healthz", "", http.StatusOK, ""},
+		{"listed paths are public", handler, "/login", "", http.StatusOK, ""},
+		{"missing header", handler, "/accounts", "", http.StatusUnauthorized, ""},
+		{"unknown scheme", handler, "/accounts", "Basic dXNlcjpwYXNz", http.StatusUnauthorized, ""},
+		{"valid token", handler, "/accounts", "Bearer " + token, http.StatusOK, "user-002"},
+		{"malformed token", handler, "/accounts", "Bearer not-a-token", http.StatusUnauthorized, ""},
+		{"revoked token", handler, "/accounts", "Bearer " + revoked, http.StatusUnauthorized, ""},
+		{"valid API key", handler, "/accounts", "ApiKey ask_valid", http.StatusOK, "user-003"},
+		{"unknown API key", handler, "/accounts", "ApiKey ask_unknown", http.StatusUnauthorized, ""},
+		{"token sent as an API key", handler, "/accounts", "ApiKey " + token, http.StatusUnauthorized, ""},
+		{"API key store unavailable", unavailable, "/accounts", "ApiKey ask_valid", http.StatusServiceUnavailable, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("Expected status %d, got %d: %s", tt.want, rec.Code, rec.Body.String())
+			}
+			if tt.want == http.StatusOK && rec.Body.String() != tt.wantUser {
+				t.Errorf("Expected user %s, got %s", tt.wantUser, rec.Body.String())
+			}
+			if tt.want != http.StatusOK && rec.Header().Get("Content-Type") != problem.ContentType {
+				t.Errorf("Expected a problem response, got %q", rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewareRejectsIncompleteTokens(t *testing.T) {
+	manager := newTestJWTManager(t)
+	token, _, err := manager.Issue(auth.NewClaims("user-002", "user@example.com", auth.RoleUser), 0)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+
+	// Verifiers that strip a claim stand in for tokens issued without it
+	tests := []struct {
+		name  string
+		strip func(claims *auth.Claims)
+	}{
+		{"no jti", func(claims *auth.Claims) { claims.ID = "" }},
+		{"no user", func(claims *auth.Claims) { claims.UserID = "" }},
+	}
+	for _, tt := range tests {
+		verifier := verifierFunc(func(tokenString string) (*auth.Claims, error) {
+			claims, err := manager.Verify(tokenString)
+			if err != nil {
+				return nil, err
+			}
+			tt.strip(claims)
+			return claims, nil
+		})
+		handler := AuthMiddleware(verifier, auth.NewMemoryRevocationList(), auth.NewMemoryAPIKeyStore(), newTestLogger())(http.HandlerFunc(whoAmI))
+
+		req := httptest.NewRequest("GET", "/accounts", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s: expected status 401, got %d", tt.name, rec.Code)
+		}
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	logger := newTestLogger()
+	manager := newTestJWTManager(t)
+	apiKeys := auth.NewMemoryAPIKeyStore()
+	if err := apiKeys.Create(&auth.APIKey{ID: "key-1", UserID: "user-002", KeyHash: auth.HashAPIKey("ask_reader"), Scope: auth.ScopeTransactionsRead}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	support, _, err := manager.Issue(auth.NewClaims("user-009", "support@example.com", auth.RoleSupport), 0)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	customer, _, err := manager.Issue(auth.NewClaims("user-002", "user@example.com", auth.RoleUser), 0)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+
+	authenticate := AuthMiddleware(manager, auth.NewMemoryRevocationList(), apiKeys, logger)
+	tests := []struct {
+		name          string
+		scope         string
+		authorization string
+		want          int
+	}{
+		{"customer reads", auth.ScopeTransactionsRead, "Bearer " + customer, http.StatusOK},
+		{"customer is not support", auth.ScopeAdminRead, "Bearer " + customer, http.StatusForbidden},
+		{"support reads any user", auth.ScopeAdminRead, "Bearer " + support, http.StatusOK},
+		{"API key within its scopes", auth.ScopeTransactionsRead, "ApiKey ask_reader", http.StatusOK},
+		{"API key beyond its scopes", auth.ScopeTransactionsWrite, "ApiKey ask_reader", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		handler := authenticate(RequireScope(tt.scope, logger)(http.HandlerFunc(whoAmI)))
+		req := httptest.NewRequest("GET", "/transactions", nil)
+		req.Header.Set("Authorization", tt.authorization)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.want, rec.Code)
+		}
+	}
+
+	// Without AuthMiddleware there are no claims to check
+	rec := httptest.NewRecorder()
+	RequireScope(auth.ScopeTransactionsRead, logger)(http.HandlerFunc(whoAmI)).ServeHTTP(rec, httptest.NewRequest("GET", "/transactions", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 without claims, got %d", rec.Code)
+	}
+}
+
+func TestTicketFromQuery(t *testing.T) {
+	logger := newTestLogger()
+	manager := newTestJWTManager(t)
+	token, _, err := manager.Issue(auth.NewClaims("user-002", "user@example.com", auth.RoleUser), 0)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	tickets := auth.NewStreamTickets()
+	issue := func() string {
+		ticket, _, err := tickets.Issue(&auth.Claims{UserID: "user-002", Scope: auth.ScopeInsightsRead})
+		if err != nil {
+			t.Fatalf("Issue failed: %v", err)
+		}
+		return ticket
+	}
+	used := issue()
+
+	var query string
+	handler := TicketFromQuery(tickets, logger, "/alerts/stream")(AuthMiddleware(manager, auth.NewMemoryRevocationList(), auth.NewMemoryAPIKeyStore(), logger)(
+		RequireScope(auth.ScopeInsightsRead, logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			query = r.URL.RawQuery
+			whoAmI(w, r)
+		}))))
+
+	tests := []struct {
+		name string
+		path string
+		want int
+	}{
+		{"stream path", "/alerts/stream?lastEventId=7&ticket=" + used, http.StatusOK},
+		{"used ticket", "/alerts/stream?ticket=" + used, http.StatusUnauthorized},
+		{"other path", "/alerts?ticket=" + issue(), http.StatusUnauthorized},
+		{"unknown ticket", "/alerts/stream?ticket=nope", http.StatusUnauthorized},
+		{"access token in the query", "/alerts/stream?access_token=" + token, http.StatusUnauthorized},
+		{"access token as a ticket", "/alerts/stream?ticket=" + token, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+		if rec.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.want, rec.Code)
+		}
+		if rec.Code == http.StatusOK && (rec.Body.String() != "user-002" || query != "lastEventId=7") {
+			t.Errorf("%s: expected user-002 without the ticket in the query, got %q and %q", tt.name, rec.Body.String(), query)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/rs/cors"
+)
+
+// NewCORS creates a new CORS middleware with appropriate settings. Browsers
+// may call from allowedOrigins, or from any origin when none are given.
+func NewCORS(allowedOrigins ...string) *cors.Cors {
+	if len(allowedOrigins) == 0 {
+		allowedOrigins = []string{"*"} // In production, specify exact origins
+	}
+	return cors.New(cors.Options{
+		AllowedOrigins: allowedOrigins,
+		AllowedMethods: []string{
+			"GET",
+			"POST",
+			"PUT",
+			"PATCH",
+			"DELETE",
+			"OPTIONS",
+		},
+		AllowedHeaders: []string{
+			"Accept",
+			"Authorization",
+			"Content-Type",
+			"X-CSRF-Token",
+			"X-Request-ID",
+			"X-User-ID",
+		},
+		ExposedHeaders: []string{
+			"Link",
+			"X-Request-ID",
+		},
+		AllowCredentials: true,
+		MaxAge:           300, // 5 minutes
+	})
+}
+
+// ParseOrigins splits a comma-separated list of origins, such as the
+// CORS_ALLOWED_ORIGINS setting, dropping empty entries and trailing slashes
+func ParseOrigins(list string) []string {
+	var origins []string
+	for _, origin := range strings.Split(list, ",") {
+		origin = strings.TrimSuffix(strings.TrimSpace(origin), "/")
+		if origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
+// OriginAllowed reports whether the page a request comes from may open a
+// connection that CORS does not guard, such as a WebSocket. A page may
+// connect to its own host and to the services from allowedOrigins, but "*"
+// does not let every site in. Requests without an Origin header do not come
+// from a page and are allowed.
+func OriginAllowed(r *http.Request, allowedOrigins []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range allowedOrigins {
+		if allowed != "*" && strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
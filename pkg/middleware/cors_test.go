@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParseOrigins(t *testing.T) {
+	got := ParseOrigins(" https://app.example.com/, ,http://localhost:3000")
+	if want := []string{"https://app.example.com", "http://localhost:3000"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if got := ParseOrigins(""); got != nil {
+		t.Errorf("Expected no origins, got %v", got)
+	}
+}
+
+func TestOriginAllowed(t *testing.T) {
+	allowed := []string{"https://app.example.com"}
+	tests := []struct {
+		name    string
+		origin  string
+		allowed []string
+		want    bool
+	}{
+		{"no origin", "", allowed, true},
+		{"listed origin", "https://app.example.com", allowed, true},
+		{"same host", "http://insights.example.com", allowed, true},
+		{"other site", "https://evil.example.net", allowed, false},
+		{"other scheme of a listed origin", "http://app.example.com", allowed, false},
+		{"wildcard", "https://evil.example.net", []string{"*"}, false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "http://insights.example.com/alerts/ws", nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		if got := OriginAllowed(req, tt.allowed); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
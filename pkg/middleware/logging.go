@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/CB-AccountStack/AccountStack/pkg/requestid"
 	"github.com/sirupsen/logrus"
 )
 
@@ -29,7 +30,21 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 	return rw.ResponseWriter.Write(b)
 }
 
-// LoggingMiddleware logs HTTP requests and responses
+// Flush passes through to the underlying writer so streamed responses are not held back
+func (rw *responseWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, to hijack
+// the connection or change its deadlines
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// LoggingMiddleware logs HTTP requests and responses, with the request ID
+// when requestid.Middleware runs before it
 func LoggingMiddleware(logger *logrus.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -53,6 +68,7 @@ func LoggingMiddleware(logger *logrus.Logger) func(http.Handler) http.Handler {
 				"duration":   duration.String(),
 				"remote":     r.RemoteAddr,
 				"user_agent": r.UserAgent(),
+				"request_id": requestid.Get(r),
 			}).Info("HTTP request")
 		})
 	}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/CB-AccountStack/AccountStack/pkg/requestid"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestLoggingMiddleware(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	handler := LoggingMiddleware(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		w.(http.Flusher).Flush()
+	}))
+
+	rec := httptest.NewRecorder()
+	requestid.Middleware(handler).ServeHTTP(rec, httptest.NewRequest("GET", "/accounts", nil))
+
+	if !rec.Flushed {
+		t.Error("Expected the flush to reach the underlying writer")
+	}
+	entry := hook.LastEntry()
+	if entry == nil || entry.Level != logrus.InfoLevel {
+		t.Fatalf("Expected the request to be logged, got %v", entry)
+	}
+	if entry.Data["status"] != http.StatusTeapot || entry.Data["path"] != "/accounts" || entry.Data["request_id"] != rec.Header().Get(requestid.Header) {
+		t.Errorf("Unexpected log fields %v", entry.Data)
+	}
+}
+
+func TestRealIPMiddleware(t *testing.T) {
+	tests := []struct {
+		name      string
+		forwarded string
+		want      string
+	}{
+		{"no proxy", "", "192.0.2.1"},
+		{"last hop is used", "203.0.113.9, 198.51.100.7", "198.51.100.7"},
+		{"invalid address is ignored", "not-an-ip", "192.0.2.1"},
+	}
+	for _, tt := range tests {
+		var got string
+		handler := RealIPMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = ClientIP(r)
+		}))
+		req := httptest.NewRequest("GET", "/login", nil)
+		if tt.forwarded != "" {
+			req.Header.Set("X-Forwarded-For", tt.forwarded)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		if got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.want, got)
+		}
+	}
+}
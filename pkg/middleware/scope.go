@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/CB-AccountStack/AccountStack/pkg/problem"
+	"github.com/sirupsen/logrus"
+)
+
+// RequireScope rejects requests whose access token was not granted scope, and
+// requests that were not authenticated as a user. It must run after
+// AuthMiddleware.
+func RequireScope(scope string, logger *logrus.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims := GetClaims(r)
+			if claims == nil || claims.UserID == "" || !claims.HasScope(scope) {
+				fields := logrus.Fields{"scope": scope, "path": r.URL.Path}
+				if claims != nil {
+					fields["userId"] = claims.UserID
+				}
+				logger.WithFields(fields).Warn("Token lacks the required scope")
+				problem.Write(w, r, http.StatusForbidden, "The credentials used were not granted the "+scope+" scope")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
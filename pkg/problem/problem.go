@@ -0,0 +1,80 @@
+// Package problem writes error responses as RFC 7807 problem details
+// (application/problem+json), the one error format every service returns.
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/CB-AccountStack/AccountStack/pkg/requestid"
+)
+
+// ContentType is the media type of problem responses
+const ContentType = "application/problem+json"
+
+// DefaultType is used for problems whose meaning is fully described by the
+// HTTP status, as RFC 7807 recommends
+const DefaultType = "about:blank"
+
+// Details is an RFC 7807 problem details object. Instance identifies the
+// occurrence by its request ID rather than echoing the path, so responses for
+// resources a user may not see do not differ from those for missing ones.
+// RequestID is an extension member matching the X-Request-ID response header.
+type Details struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	RequestID string `json:"requestId,omitempty"`
+}
+
+// New returns the problem details for a response to r with the given status.
+// detail explains this occurrence to people.
+func New(r *http.Request, status int, detail string) *Details {
+	return &Details{
+		Type:      DefaultType,
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  instance(requestid.Get(r)),
+		RequestID: requestid.Get(r),
+	}
+}
+
+// instance returns the URN of the request with the given ID, or "" without one
+func instance(requestID string) string {
+	if requestID == "" {
+		return ""
+	}
+	return "urn:accountstack:request:" + url.PathEscape(requestID)
+}
+
+// Write answers r with a problem response with the given status
+func Write(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	New(r, status, detail).Write(w)
+}
+
+// Write sends the problem as the response
+func (d *Details) Write(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(d.Status)
+	json.NewEncoder(w).Encode(d)
+}
+
+// NotFoundHandler answers requests for paths no route matches
+func NotFoundHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Write(w, r, http.StatusNotFound, "No endpoint matches this path")
+	})
+}
+
+// MethodNotAllowedHandler answers requests for a known path with a method it
+// does not support
+func MethodNotAllowedHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Write(w, r, http.StatusMethodNotAllowed, r.Method+" is not supported on this path")
+	})
+}
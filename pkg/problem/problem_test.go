@@ -0,0 +1,60 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/CB-AccountStack/AccountStack/pkg/requestid"
+)
+
+func TestWrite(t *testing.T) {
+	handler := requestid.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Write(w, r, http.StatusNotFound, "Account not found")
+	}))
+	req := httptest.NewRequest("GET", "/accounts/acc-404", nil)
+	req.Header.Set(requestid.Header, "req-1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != ContentType {
+		t.Errorf("Expected a problem response, got %q", ct)
+	}
+	var body Details
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode body: %v", err)
+	}
+	want := Details{
+		Type:      DefaultType,
+		Title:     "Not Found",
+		Status:    http.StatusNotFound,
+		Detail:    "Account not found",
+		Instance:  "urn:accountstack:request:req-1",
+		RequestID: "req-1",
+	}
+	if body != want {
+		t.Errorf("Expected %+v, got %+v", want, body)
+	}
+}
+
+func TestRouteHandlers(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.Handler
+		want    int
+	}{
+		{"unknown path", NotFoundHandler(), http.StatusNotFound},
+		{"unsupported method", MethodNotAllowedHandler(), http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		tt.handler.ServeHTTP(rec, httptest.NewRequest("PATCH", "/nowhere", nil))
+		if rec.Code != tt.want || rec.Header().Get("Content-Type") != ContentType {
+			t.Errorf("%s: expected a %d problem response, got %d %q", tt.name, tt.want, rec.Code, rec.Header().Get("Content-Type"))
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// Package requestid gives every request an ID that is returned in the
+// X-Request-ID header, logged and included in error responses, so a failure
+// a client reports can be found in the logs.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// Header carries the request ID, in both directions
+const Header = "X-Request-ID"
+
+// maxLength caps request IDs taken from clients, so they cannot flood the logs
+const maxLength = 64
+
+type contextKey struct{}
+
+// Middleware assigns each request an ID. An X-Request-ID sent by the client or
+// a proxy in front of the service is kept, so one ID can follow a request
+// across services; otherwise a random one is generated.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(Header)
+		if !valid(id) {
+			id = newID()
+		}
+		w.Header().Set(Header, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, id)))
+	})
+}
+
+// Get returns the ID of the request, or "" when Middleware did not run
+func Get(r *http.Request) string {
+	id, _ := r.Context().Value(contextKey{}).(string)
+	return id
+}
+
+// valid reports whether a client-supplied ID is short and printable ASCII
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,44 @@
+package requestid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{"generated when missing", "", false},
+		{"kept from the client", "req-42", true},
+		{"replaced when too long", strings.Repeat("a", maxLength+1), false},
+		{"replaced when not printable", "req\n42", false},
+	}
+	for _, tt := range tests {
+		var got string
+		handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = Get(r)
+		}))
+		req := httptest.NewRequest("GET", "/accounts", nil)
+		if tt.incoming != "" {
+			req.Header.Set(Header, tt.incoming)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if got == "" || rec.Header().Get(Header) != got {
+			t.Errorf("%s: expected the header to carry the request ID %q, got %q", tt.name, got, rec.Header().Get(Header))
+		}
+		if (got == tt.incoming) != tt.keep {
+			t.Errorf("%s: unexpected request ID %q", tt.name, got)
+		}
+	}
+
+	if id := Get(httptest.NewRequest("GET", "/accounts", nil)); id != "" {
+		t.Errorf("Expected no request ID without the middleware, got %q", id)
+	}
+}
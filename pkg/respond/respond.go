@@ -0,0 +1,16 @@
+// Package respond writes the JSON responses shared by every service. Errors
+// are written with the problem package.
+package respond
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// JSON writes v as the JSON body of a response with the given status. The
+// returned error is from encoding v, after the status has been sent.
+func JSON(w http.ResponseWriter, status int, v interface{}) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	return json.NewEncoder(w).Encode(v)
+}
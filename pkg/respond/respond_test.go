@@ -0,0 +1,23 @@
+package respond
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if err := JSON(rec, http.StatusCreated, map[string]string{"id": "acc-001"}); err != nil {
+		t.Fatalf("JSON failed: %v", err)
+	}
+	if rec.Code != http.StatusCreated || rec.Body.String() != "{\"id\":\"acc-001\"}\n" {
+		t.Errorf("Unexpected response %d %q", rec.Code, rec.Body.String())
+	}
+
+	// Values that cannot be encoded are reported after the status is sent
+	rec = httptest.NewRecorder()
+	if err := JSON(rec, http.StatusOK, func() {}); err == nil {
+		t.Error("Expected an encoding error")
+	}
+}